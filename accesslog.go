@@ -0,0 +1,321 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Access log defaults; WEB_ACCESS_LOG_MAX_MB and WEB_ACCESS_LOG_BACKUPS
+// override them.
+const (
+	defaultAccessLogMaxMB   = 100
+	defaultAccessLogBackups = 5
+)
+
+// accessEntry collects what handlers learn about a request for its access log
+// line: the signed-in user (set by the sign-in middleware) and, for searches,
+// what was searched for.
+type accessEntry struct {
+	mu     sync.Mutex
+	user   string
+	search string
+}
+
+type accessEntryKey struct{}
+
+// setAccessUser records who made the request, if it is being access-logged.
+func setAccessUser(ctx context.Context, user string) {
+	if e, ok := ctx.Value(accessEntryKey{}).(*accessEntry); ok {
+		e.mu.Lock()
+		e.user = user
+		e.mu.Unlock()
+	}
+}
+
+// setAccessSearch records what a search looked for, if the request is being
+// access-logged.
+func setAccessSearch(ctx context.Context, search string) {
+	if e, ok := ctx.Value(accessEntryKey{}).(*accessEntry); ok {
+		e.mu.Lock()
+		e.search = search
+		e.mu.Unlock()
+	}
+}
+
+// describeSearch is the access log note of a web search, e.g.
+// "mac=00:11:22:33:44:55 networks=N_1,N_2".
+func describeSearch(mac, ip, hostname string, networkIDs []string) string {
+	var parts []string
+	for _, p := range [][2]string{{"mac", mac}, {"ip", ip}, {"hostname", hostname}, {"networks", strings.Join(networkIDs, ",")}} {
+		if p[1] != "" {
+			parts = append(parts, p[0]+"="+p[1])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// accessLog writes one line per web request in the Apache/NGINX combined
+// format, apart from the application log. Searches add what was searched for
+// as a final quoted field, which combined-format parsers ignore.
+type accessLog struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// wrap logs every request once next has answered it.
+func (l *accessLog) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := &accessEntry{}
+		rec := &statusRecorder{ResponseWriter: w}
+		start := l.now()
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, e)))
+		e.mu.Lock()
+		user, search := e.user, e.search
+		e.mu.Unlock()
+		l.write(r, rec, user, search, start)
+	})
+}
+
+func (l *accessLog) write(r *http.Request, rec *statusRecorder, user, search string, start time.Time) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	size := "-"
+	if rec.bytes > 0 {
+		size = fmt.Sprint(rec.bytes)
+	}
+	line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s \"%s\" \"%s\"",
+		host, logField(user), start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method, quoteField(redactQuery(r.URL)), r.Proto, status, size,
+		quoteField(firstNonEmpty(r.Referer(), "-")), quoteField(firstNonEmpty(r.UserAgent(), "-")))
+	if search != "" {
+		line += " \"" + quoteField(search) + "\""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = io.WriteString(l.w, line+"\n")
+}
+
+// secretQueryParams are redacted from logged URLs.
+var secretQueryParams = []string{"apikey", "api_key", "key", "token", "access_token", "code", "state"}
+
+// redactQuery returns the request URI with secret query parameters such as
+// apiKey replaced, so the access log never holds credentials.
+func redactQuery(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.RequestURI()
+	}
+	q := u.Query()
+	for name := range q {
+		for _, secret := range secretQueryParams {
+			if strings.EqualFold(name, secret) {
+				q.Set(name, "REDACTED")
+			}
+		}
+	}
+	return u.EscapedPath() + "?" + q.Encode()
+}
+
+// logField is an unquoted access log field: "-" when empty, with spaces and
+// quotes replaced so the line still splits into the usual fields.
+func logField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '"' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// quoteField escapes s for a double-quoted access log field.
+func quoteField(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < ' ' || r == 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// statusRecorder captures the status and size of a response. It passes
+// Hijack and Flush through, so WebSockets keep working behind it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(p)
+	s.bytes += int64(n)
+	return n, err
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	if s.status == 0 {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// rotatingFile is an append-only log file that is renamed to path.1 (and
+// older copies shifted up to path.<backups>) once it would grow past maxBytes.
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	backups  int
+	f        *os.File
+	size     int64
+}
+
+// openRotatingFile opens path for appending, creating it if needed.
+func openRotatingFile(path string, maxBytes int64, backups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxBytes: maxBytes, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.f, r.size = f, fi.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.maxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the backups, keeping at most r.backups, and starts a new file.
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	if r.backups < 1 {
+		_ = os.Remove(r.path)
+	} else {
+		_ = os.Remove(fmt.Sprintf("%s.%d", r.path, r.backups))
+		for i := r.backups - 1; i >= 1; i-- {
+			_ = os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return err
+		}
+	}
+	return r.open()
+}
+
+// close syncs and closes the log file; standard output is left open.
+func (l *accessLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if f, ok := l.w.(*rotatingFile); ok {
+		return f.Close()
+	}
+	return nil
+}
+
+// Close syncs the current file to disk and closes it.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.f.Sync()
+	return r.f.Close()
+}
+
+// newAccessLog opens the access log target: "-" is standard output, "" and
+// "off" disable access logging (nil), anything else is a file rotated at
+// maxMB megabytes with backups old copies kept.
+func newAccessLog(target string, maxMB, backups int) (*accessLog, error) {
+	switch strings.TrimSpace(target) {
+	case "", "off":
+		return nil, nil
+	case "-":
+		return &accessLog{w: os.Stdout, now: time.Now}, nil
+	}
+	f, err := openRotatingFile(target, int64(maxMB)<<20, backups)
+	if err != nil {
+		return nil, err
+	}
+	return &accessLog{w: f, now: time.Now}, nil
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLogLine(t *testing.T) {
+	var buf bytes.Buffer
+	l := &accessLog{w: &buf, now: func() time.Time { return time.Date(2026, 3, 1, 9, 5, 0, 0, time.FixedZone("", 3600)) }}
+	h := l.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setAccessUser(r.Context(), "Ada Lovelace")
+		if r.URL.Path == "/api/resolve" {
+			setAccessSearch(r.Context(), describeSearch("00:11:22:33:44:55", "", "", []string{"N_1", "N_2"}))
+		}
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest("POST", "/api/resolve", nil)
+	req.RemoteAddr = "10.20.3.4:51000"
+	req.Header.Set("User-Agent", `curl/8 "evil"`)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	want := `10.20.3.4 - Ada_Lovelace [01/Mar/2026:09:05:00 +0100] "POST /api/resolve HTTP/1.1" 200 5 "-" "curl/8 \"evil\"" "mac=00:11:22:33:44:55 networks=N_1,N_2"` + "\n"
+	if buf.String() != want {
+		t.Errorf("access log line:\n got %q\nwant %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	h = l.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	req = httptest.NewRequest("GET", "/api/networks?orgId=1&apiKey=0123456789abcdef", nil)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if line := buf.String(); strings.Contains(line, "0123456789abcdef") || !strings.Contains(line, `"GET /api/networks?apiKey=REDACTED&orgId=1 HTTP/1.1" 403 5 `) || !strings.Contains(line, " - - [") {
+		t.Errorf("access log line = %q", line)
+	}
+}
+
+func TestAccessLogKeepsWebSockets(t *testing.T) {
+	l := &accessLog{w: &bytes.Buffer{}, now: time.Now}
+	srv := httptest.NewServer(l.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Hijacker); !ok {
+			http.Error(w, "no hijacker", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status %d: the access log hides http.Hijacker", resp.StatusCode)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	f, err := openRotatingFile(path, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := fmt.Fprintf(f, "line %02d %s\n", i, strings.Repeat("x", 30)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		fi, err := os.Stat(name)
+		if err != nil || fi.Size() > 100 {
+			t.Errorf("%s: %v, %v", name, fi, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("more backups than configured were kept")
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "line 09") {
+		t.Errorf("current file = %q, want the newest lines", data)
+	}
+
+	if l, err := newAccessLog("off", 1, 1); l != nil || err != nil {
+		t.Errorf(`newAccessLog("off") = %v, %v`, l, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// parseAllowCIDRs parses a comma-separated list of networks such as
+// "10.20.0.0/16,192.0.2.7"; a bare address is a single host. "" yields nil,
+// which allows every source.
+func parseAllowCIDRs(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not an IP address or CIDR network", part)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR network", part)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// sourceAllowList restricts the web server to clients in its networks. The
+// loopback addresses are always allowed so the server host itself keeps
+// working. Only the connection's address counts: X-Forwarded-For is ignored,
+// since any client can send it.
+type sourceAllowList struct {
+	nets []*net.IPNet
+	now  func() time.Time
+
+	mu     sync.Mutex
+	logged map[string]time.Time // source → last rejection logged
+}
+
+// rejectLogInterval limits rejection log lines to one per source and interval,
+// so a scanner cannot flood the log.
+const rejectLogInterval = time.Minute
+
+func (a *sourceAllowList) allowed(remoteAddr string) (string, bool) {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host, false
+	}
+	if ip.IsLoopback() {
+		return host, true
+	}
+	for _, n := range a.nets {
+		if n.Contains(ip) {
+			return host, true
+		}
+	}
+	return host, false
+}
+
+// wrap answers 403 to sources outside the list and logs the attempt.
+func (a *sourceAllowList) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		src, ok := a.allowed(r.RemoteAddr)
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if a.shouldLog(src) {
+			newWebLogger().Warnf("Rejected %s %s from %s: not in --allow-cidr", r.Method, r.URL.Path, src)
+		}
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	})
+}
+
+func (a *sourceAllowList) shouldLog(src string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	if last, ok := a.logged[src]; ok && now.Sub(last) < rejectLogInterval {
+		return false
+	}
+	if a.logged == nil || len(a.logged) > 10000 {
+		a.logged = map[string]time.Time{}
+	}
+	a.logged[src] = now
+	return true
+}
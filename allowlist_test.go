@@ -0,0 +1,74 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseAllowCIDRs(t *testing.T) {
+	nets, err := parseAllowCIDRs(" 10.20.0.0/16, 192.0.2.7 ,2001:db8::/32,")
+	if err != nil || len(nets) != 3 || nets[1].String() != "192.0.2.7/32" {
+		t.Fatalf("parseAllowCIDRs() = %v, %v", nets, err)
+	}
+	if nets, err := parseAllowCIDRs(""); nets != nil || err != nil {
+		t.Errorf(`parseAllowCIDRs("") = %v, %v`, nets, err)
+	}
+	for _, bad := range []string{"10.0.0.0/33", "jump-host", "10.0.0"} {
+		if _, err := parseAllowCIDRs(bad); err == nil {
+			t.Errorf("parseAllowCIDRs(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSourceAllowList(t *testing.T) {
+	nets, _ := parseAllowCIDRs("10.20.0.0/16,2001:db8::/32")
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	a := &sourceAllowList{nets: nets, now: func() time.Time { return now }}
+	h := a.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for addr, want := range map[string]int{
+		"10.20.3.4:51000":      http.StatusOK,
+		"[::ffff:10.20.3.4]:1": http.StatusOK,
+		"[2001:db8::5]:443":    http.StatusOK,
+		"127.0.0.1:40000":      http.StatusOK,
+		"[::1]:40000":          http.StatusOK,
+		"10.21.0.1:51000":      http.StatusForbidden,
+		"[2001:db9::5]:443":    http.StatusForbidden,
+		"not-an-address":       http.StatusForbidden,
+		"192.0.2.1:51000":      http.StatusForbidden,
+	} {
+		req := httptest.NewRequest("GET", "/api/networks", nil)
+		req.RemoteAddr = addr
+		req.Header.Set("X-Forwarded-For", "10.20.0.1") // never trusted
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: status %d, want %d", addr, rec.Code, want)
+		}
+	}
+
+	if !a.shouldLog("192.0.2.9") || a.shouldLog("192.0.2.9") {
+		t.Error("a second rejection within the interval should not be logged")
+	}
+	now = now.Add(rejectLogInterval)
+	if !a.shouldLog("192.0.2.9") {
+		t.Error("a rejection after the interval should be logged again")
+	}
+}
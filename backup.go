@@ -0,0 +1,514 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/history"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// backupFormat is the archive layout version recorded in the manifest. Restore
+// refuses archives written by a newer layout.
+const backupFormat = 1
+
+// Members of a backup archive. Only the manifest is mandatory.
+const (
+	backupManifestName = "manifest.json"
+	backupConfigName   = "config.env"
+	backupHistoryName  = "history.json"
+	backupUIStateName  = "ui-state.json"
+)
+
+// maxBackupMember bounds how much of one archive member restore reads into memory.
+const maxBackupMember = 512 << 20
+
+// encPrefix marks a config value encrypted with the backup passphrase.
+const encPrefix = "enc:v1:"
+
+// backupKDFIterations is the PBKDF2-HMAC-SHA256 work factor for the
+// passphrase; a variable so tests can lower it.
+var backupKDFIterations = 600000
+
+// backupManifest describes an archive: who wrote it, when, and the salt that
+// turns the passphrase into the key for the encrypted config values.
+type backupManifest struct {
+	Format  int       `json:"format"`
+	Created time.Time `json:"created"`
+	Version string    `json:"version"`
+	Host    string    `json:"host,omitempty"`
+	Salt    string    `json:"salt,omitempty"`
+	Files   []string  `json:"files"`
+}
+
+// backupSources are the state locations a backup reads and a restore writes.
+type backupSources struct {
+	envFile     string
+	historyFile string // "" when history recording is off
+	uiStateFile string
+}
+
+// runBackupCommand implements "backup create|restore" and returns the exit
+// code. Secrets in the config (API keys, tokens, passwords and database URLs
+// with credentials) are encrypted with a key derived from the passphrase in
+// $BACKUP_PASSPHRASE, or left out with --no-secrets.
+func runBackupCommand(w io.Writer, args []string, envFile string, getenv func(string) string) int {
+	if len(args) == 0 || (args[0] != "create" && args[0] != "restore") {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: Find-Meraki-Ports-With-MAC backup create [--output FILE] [--no-secrets]")
+		_, _ = fmt.Fprintln(os.Stderr, "       Find-Meraki-Ports-With-MAC backup restore FILE [--dry-run] [--force]")
+		return 2
+	}
+	fs := flag.NewFlagSet("backup "+args[0], flag.ContinueOnError)
+	fs.StringVar(&envFile, "env", envFile, "Config file to back up or restore")
+	historyFlag := fs.String("history-file", getenv("HISTORY_FILE"), "History file path or database URL")
+	passEnvFlag := fs.String("passphrase-env", "BACKUP_PASSPHRASE", "Environment variable holding the passphrase for secrets")
+	outputFlag := fs.String("output", "", "create: archive to write (default find-mac-backup-<date>.tar.gz)")
+	noSecretsFlag := fs.Bool("no-secrets", false, "create: leave secrets out instead of encrypting them")
+	dryRunFlag := fs.Bool("dry-run", false, "restore: list what would be restored without writing anything")
+	forceFlag := fs.Bool("force", false, "restore: overwrite an existing config and UI state")
+	// Allow the archive name before the flags: "backup restore FILE --force".
+	rest := args[1:]
+	var archive string
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		archive, rest = rest[0], rest[1:]
+	}
+	if err := fs.Parse(rest); err != nil {
+		return 2
+	}
+	if archive == "" {
+		archive = fs.Arg(0)
+	}
+	src := backupSources{envFile: envFile, historyFile: resolveHistoryFile(*historyFlag), uiStateFile: webUIState.path}
+	passphrase := getenv(*passEnvFlag)
+
+	if args[0] == "create" {
+		if passphrase == "" && !*noSecretsFlag {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: set %s to encrypt the secrets in the backup, or use --no-secrets\n", *passEnvFlag)
+			return 2
+		}
+		if *noSecretsFlag {
+			passphrase = ""
+		}
+		path := *outputFlag
+		if path == "" {
+			path = "find-mac-backup-" + time.Now().Format("20060102-150405") + ".tar.gz"
+		}
+		m, err := createBackup(path, src, passphrase, time.Now())
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		_, _ = fmt.Fprintf(w, "Wrote %s (%s)\n", path, strings.Join(m.Files[1:], ", "))
+		return 0
+	}
+
+	if archive == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "ERROR: backup restore needs the archive to restore")
+		return 2
+	}
+	if err := restoreBackup(w, archive, src, passphrase, *dryRunFlag, *forceFlag); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// createBackup writes the state in src to an archive at path and returns its
+// manifest. With an empty passphrase secrets are omitted from the config.
+func createBackup(path string, src backupSources, passphrase string, now time.Time) (backupManifest, error) {
+	host, _ := os.Hostname()
+	m := backupManifest{Format: backupFormat, Created: now.UTC(), Version: Version, Host: host, Files: []string{backupManifestName}}
+	members := map[string][]byte{}
+
+	if data, err := os.ReadFile(src.envFile); err == nil {
+		var seal func(string) (string, error)
+		if passphrase != "" {
+			salt := make([]byte, 16)
+			if _, err := rand.Read(salt); err != nil {
+				return m, err
+			}
+			m.Salt = base64.StdEncoding.EncodeToString(salt)
+			seal = sealer(backupKey(passphrase, salt))
+		}
+		if members[backupConfigName], err = transformSecrets(data, seal); err != nil {
+			return m, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return m, err
+	}
+
+	if src.historyFile != "" {
+		store, err := history.Open(src.historyFile)
+		if err != nil {
+			return m, fmt.Errorf("history: %v", err)
+		}
+		sightings := store.Sightings()
+		_ = store.Close()
+		if len(sightings) > 0 {
+			// Same layout as the JSON history file: network → MAC → sighting.
+			networks := map[string]map[string]history.Sighting{}
+			for _, sg := range sightings {
+				if networks[sg.Network] == nil {
+					networks[sg.Network] = map[string]history.Sighting{}
+				}
+				networks[sg.Network][sg.MAC] = sg
+			}
+			if members[backupHistoryName], err = json.MarshalIndent(networks, "", "  "); err != nil {
+				return m, err
+			}
+		}
+	}
+
+	if data, err := os.ReadFile(src.uiStateFile); err == nil {
+		members[backupUIStateName] = data
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return m, err
+	}
+
+	for _, name := range []string{backupConfigName, backupHistoryName, backupUIStateName} {
+		if _, ok := members[name]; ok {
+			m.Files = append(m.Files, name)
+		}
+	}
+	if len(m.Files) == 1 {
+		return m, errors.New("nothing to back up: no config, history or UI state found")
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return m, err
+	}
+	members[backupManifestName] = manifest
+
+	// The archive can hold client history, so it is created owner-only.
+	f, err := output.CreatePrivateAtomic(path)
+	if err != nil {
+		return m, err
+	}
+	defer f.Abort()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, name := range m.Files {
+		data := members[name]
+		hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return m, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return m, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return m, err
+	}
+	if err := gz.Close(); err != nil {
+		return m, err
+	}
+	return m, f.Commit()
+}
+
+// restoreBackup restores the archive at path into src. Everything is read and
+// decrypted before anything is written, so a wrong passphrase or a damaged
+// archive changes nothing. History is merged into the current history; the
+// config and UI state replace existing files only with force.
+func restoreBackup(w io.Writer, path string, src backupSources, passphrase string, dryRun, force bool) error {
+	m, members, err := readBackup(path)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "Backup of %s taken %s by version %s\n", firstNonEmpty(m.Host, "unknown host"), m.Created.Local().Format("2006-01-02 15:04"), m.Version)
+
+	config, hasConfig := members[backupConfigName]
+	if hasConfig {
+		var open func(string) (string, error)
+		if m.Salt != "" {
+			if passphrase == "" {
+				return errors.New("the backup holds encrypted secrets; set BACKUP_PASSPHRASE (or the --passphrase-env variable)")
+			}
+			salt, err := base64.StdEncoding.DecodeString(m.Salt)
+			if err != nil {
+				return fmt.Errorf("manifest salt: %v", err)
+			}
+			open = opener(backupKey(passphrase, salt))
+		}
+		if config, err = transformSecrets(config, open); err != nil {
+			return err
+		}
+	}
+	var sightings []history.Sighting
+	if data, ok := members[backupHistoryName]; ok {
+		var networks map[string]map[string]history.Sighting
+		if err := json.Unmarshal(data, &networks); err != nil {
+			return fmt.Errorf("%s: %v", backupHistoryName, err)
+		}
+		for network, macs := range networks {
+			for mac, sg := range macs {
+				sg.Network, sg.MAC = network, mac
+				sightings = append(sightings, sg)
+			}
+		}
+	}
+	uiState, hasUIState := members[backupUIStateName]
+
+	var conflicts []string
+	if hasConfig && hasSettings(src.envFile) {
+		conflicts = append(conflicts, src.envFile)
+	}
+	if _, err := os.Stat(src.uiStateFile); hasUIState && err == nil {
+		conflicts = append(conflicts, src.uiStateFile)
+	}
+	if len(sightings) > 0 && src.historyFile == "" {
+		return errors.New("the backup holds history but recording is disabled (HISTORY_FILE=off); pass --history-file")
+	}
+
+	if dryRun {
+		if hasConfig {
+			_, _ = fmt.Fprintf(w, "  config      → %s\n", src.envFile)
+		}
+		if len(sightings) > 0 {
+			_, _ = fmt.Fprintf(w, "  history     → %s (merge %d sighting(s))\n", src.historyFile, len(sightings))
+		}
+		if hasUIState {
+			_, _ = fmt.Fprintf(w, "  UI state    → %s\n", src.uiStateFile)
+		}
+		if len(conflicts) > 0 && !force {
+			_, _ = fmt.Fprintf(w, "Would need --force to replace: %s\n", strings.Join(conflicts, ", "))
+		}
+		_, _ = fmt.Fprintln(w, "Dry run: nothing written.")
+		return nil
+	}
+	if len(conflicts) > 0 && !force {
+		return fmt.Errorf("%s already exist(s); use --force to replace, or --dry-run to preview", strings.Join(conflicts, ", "))
+	}
+
+	if hasConfig {
+		if err := writeFileAtomic(src.envFile, config); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "Restored config to %s\n", src.envFile)
+	}
+	if len(sightings) > 0 {
+		store, err := history.Open(src.historyFile)
+		if err != nil {
+			return fmt.Errorf("history: %v", err)
+		}
+		n := store.Merge(sightings)
+		err = store.Save()
+		_ = store.Close()
+		if err != nil {
+			return fmt.Errorf("history: %v", err)
+		}
+		_, _ = fmt.Fprintf(w, "Merged %d of %d sighting(s) into %s\n", n, len(sightings), src.historyFile)
+	}
+	if hasUIState {
+		if err := writeFileAtomic(src.uiStateFile, uiState); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "Restored UI state to %s\n", src.uiStateFile)
+	}
+	return nil
+}
+
+// readBackup reads every member of the archive at path and checks its manifest.
+func readBackup(path string) (backupManifest, map[string][]byte, error) {
+	var m backupManifest
+	f, err := os.Open(path)
+	if err != nil {
+		return m, nil, err
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return m, nil, fmt.Errorf("%s: not a backup archive: %v", path, err)
+	}
+	members := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return m, nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if hdr.Size > maxBackupMember {
+			return m, nil, fmt.Errorf("%s: member %s is too large", path, hdr.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return m, nil, fmt.Errorf("%s: %v", path, err)
+		}
+		members[hdr.Name] = data
+	}
+	data, ok := members[backupManifestName]
+	if !ok {
+		return m, nil, fmt.Errorf("%s: no %s; not a backup archive", path, backupManifestName)
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, nil, fmt.Errorf("%s: %v", backupManifestName, err)
+	}
+	if m.Format > backupFormat {
+		return m, nil, fmt.Errorf("%s was written by a newer version (format %d, this build reads up to %d)", path, m.Format, backupFormat)
+	}
+	return m, members, nil
+}
+
+// writeFileAtomic replaces path with data; the file is only readable by its owner.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := output.CreatePrivateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// transformSecrets rewrites the values of secret settings in a .env file with
+// fn, leaving comments and other settings untouched. A nil fn on backup drops
+// the secret values; on restore it is only an error if a value is encrypted.
+func transformSecrets(env []byte, fn func(string) (string, error)) ([]byte, error) {
+	var b bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(env))
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		key, value, ok := strings.Cut(line, "=")
+		name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(key), "export "))
+		trimmed := strings.TrimSpace(line)
+		if ok && !strings.HasPrefix(trimmed, "#") && value != "" && (isSecretSetting(name, value) || strings.HasPrefix(value, encPrefix)) {
+			switch {
+			case fn != nil:
+				v, err := fn(value)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", name, err)
+				}
+				line = key + "=" + v
+			case strings.HasPrefix(value, encPrefix):
+				return nil, fmt.Errorf("%s is encrypted but the backup has no salt", name)
+			default:
+				line = "# " + key + "= (omitted from backup)"
+			}
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), sc.Err()
+}
+
+// isSecretSetting reports whether a .env setting holds a credential. Database
+// URLs such as HISTORY_FILE count when they carry a password.
+func isSecretSetting(name, value string) bool {
+	upper := strings.ToUpper(name)
+	for _, s := range []string{"KEY", "SECRET", "TOKEN", "PASSWORD", "PASSPHRASE"} {
+		if strings.Contains(upper, s) {
+			return true
+		}
+	}
+	if u, err := url.Parse(strings.Trim(strings.TrimSpace(value), `"'`)); err == nil && u.User != nil {
+		_, ok := u.User.Password()
+		return ok
+	}
+	return false
+}
+
+// sealer returns a function that encrypts a value with AES-256-GCM under key.
+func sealer(key []byte) func(string) (string, error) {
+	return func(plain string) (string, error) {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return "", err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		return encPrefix + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plain), nil)), nil
+	}
+}
+
+// opener returns the inverse of sealer. Values without encPrefix pass through.
+func opener(key []byte) func(string) (string, error) {
+	return func(value string) (string, error) {
+		if !strings.HasPrefix(value, encPrefix) {
+			return value, nil
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encPrefix))
+		if err != nil {
+			return "", err
+		}
+		gcm, err := newGCM(key)
+		if err != nil {
+			return "", err
+		}
+		if len(data) < gcm.NonceSize() {
+			return "", errors.New("encrypted value is truncated")
+		}
+		plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+		if err != nil {
+			return "", errors.New("wrong passphrase or damaged backup")
+		}
+		return string(plain), nil
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// backupKey derives a 32-byte key from passphrase with PBKDF2-HMAC-SHA256
+// (RFC 8018), which needs only one output block at this length.
+func backupKey(passphrase string, salt []byte) []byte {
+	prf := hmac.New(sha256.New, []byte(passphrase))
+	prf.Write(salt)
+	_ = binary.Write(prf, binary.BigEndian, uint32(1))
+	u := prf.Sum(nil)
+	key := append([]byte(nil), u...)
+	for i := 1; i < backupKDFIterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
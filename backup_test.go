@@ -0,0 +1,177 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/history"
+
+	"github.com/joho/godotenv"
+)
+
+func TestBackupKey(t *testing.T) {
+	// PBKDF2-HMAC-SHA256 reference value for P="passwd", S="salt", c=4096.
+	defer func(n int) { backupKDFIterations = n }(backupKDFIterations)
+	backupKDFIterations = 4096
+	got := hex.EncodeToString(backupKey("passwd", []byte("salt")))
+	if want := "21943fd5b7a10905c38fad60157ff498e1e81df1e03254325682a74dca3b2be8"; got != want {
+		t.Errorf("backupKey() = %s, want %s", got, want)
+	}
+}
+
+func TestTransformSecrets(t *testing.T) {
+	env := "# comment with KEY=x\nMERAKI_API_KEY=abc123\nMERAKI_ORG=\"Acme Key Co\"\n" +
+		"HISTORY_FILE=postgres://find:s3cret@db/find\nWEBHOOK_TOKEN=\nexport SMTP_PASSWORD='p w'\n"
+	dropped, err := transformSecrets([]byte(env), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# comment with KEY=x\n# MERAKI_API_KEY= (omitted from backup)\nMERAKI_ORG=\"Acme Key Co\"\n" +
+		"# HISTORY_FILE= (omitted from backup)\nWEBHOOK_TOKEN=\n# export SMTP_PASSWORD= (omitted from backup)\n"
+	if string(dropped) != want {
+		t.Errorf("without a passphrase got\n%s\nwant\n%s", dropped, want)
+	}
+
+	key := bytes.Repeat([]byte{7}, 32)
+	sealed, err := transformSecrets([]byte(env), sealer(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"abc123", "s3cret", "p w"} {
+		if strings.Contains(string(sealed), secret) {
+			t.Errorf("sealed config still contains %q:\n%s", secret, sealed)
+		}
+	}
+	opened, err := transformSecrets(sealed, opener(key))
+	if err != nil || string(opened) != env {
+		t.Errorf("round trip = %q, %v; want the original", opened, err)
+	}
+	if _, err := transformSecrets(sealed, opener(bytes.Repeat([]byte{8}, 32))); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("wrong key error = %v", err)
+	}
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	defer func(n int) { backupKDFIterations = n }(backupKDFIterations)
+	backupKDFIterations = 10
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	// The old host.
+	oldDir := t.TempDir()
+	old := backupSources{
+		envFile:     filepath.Join(oldDir, ".env.find-mac"),
+		historyFile: filepath.Join(oldDir, "history.json"),
+		uiStateFile: filepath.Join(oldDir, "ui-state.json"),
+	}
+	if err := os.WriteFile(old.envFile, []byte("MERAKI_API_KEY=abc123\nMERAKI_ORG=Acme\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(old.uiStateFile, []byte(`{"abc":{"org":"O1"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, _ := history.Open(old.historyFile)
+	store.Observe("HQ", "aa:aa:aa:aa:aa:aa", t0, "sw1", "3")
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(oldDir, "backup.tar.gz")
+	m, err := createBackup(archive, old, "hunter2", t0)
+	if err != nil {
+		t.Fatalf("createBackup() error: %v", err)
+	}
+	if len(m.Files) != 4 || m.Salt == "" {
+		t.Errorf("manifest = %+v, want config, history and UI state with a salt", m)
+	}
+	raw, _ := os.ReadFile(archive)
+	if bytes.Contains(raw, []byte("abc123")) {
+		t.Error("archive contains the API key in the clear")
+	}
+
+	// The new host already has some history and a config of its own.
+	newDir := t.TempDir()
+	dst := backupSources{
+		envFile:     filepath.Join(newDir, ".env.find-mac"),
+		historyFile: filepath.Join(newDir, "history.json"),
+		uiStateFile: filepath.Join(newDir, "ui-state.json"),
+	}
+	_ = os.WriteFile(dst.envFile, []byte("MERAKI_ORG=Other\n"), 0600)
+	store, _ = history.Open(dst.historyFile)
+	store.Observe("HQ", "bb:bb:bb:bb:bb:bb", t0, "sw9", "1")
+	_ = store.Save()
+
+	var out bytes.Buffer
+	if err := restoreBackup(&out, archive, dst, "wrong", false, true); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("restore with a wrong passphrase = %v", err)
+	}
+	if err := restoreBackup(&out, archive, dst, "hunter2", false, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("restore over an existing config without --force = %v", err)
+	}
+	if err := restoreBackup(&out, archive, dst, "hunter2", true, false); err != nil {
+		t.Errorf("dry run error: %v", err)
+	}
+	if env, _ := godotenv.Read(dst.envFile); env["MERAKI_ORG"] != "Other" {
+		t.Fatalf("failed restores changed the config: %v", env)
+	}
+
+	out.Reset()
+	if err := restoreBackup(&out, archive, dst, "hunter2", false, true); err != nil {
+		t.Fatalf("restore error: %v\n%s", err, out.String())
+	}
+	env, _ := godotenv.Read(dst.envFile)
+	if env["MERAKI_API_KEY"] != "abc123" || env["MERAKI_ORG"] != "Acme" {
+		t.Errorf("restored config = %v", env)
+	}
+	if data, _ := os.ReadFile(dst.uiStateFile); string(data) != `{"abc":{"org":"O1"}}` {
+		t.Errorf("restored UI state = %s", data)
+	}
+	store, _ = history.Open(dst.historyFile)
+	if got := store.Sightings(); len(got) != 2 {
+		t.Errorf("history after restore = %+v, want both hosts' sightings", got)
+	}
+}
+
+func TestRunBackupCommandNeedsPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	defer func(s *uiStateStore) { webUIState = s }(webUIState)
+	webUIState = &uiStateStore{path: filepath.Join(dir, "ui-state.json")}
+	envFile := filepath.Join(dir, ".env")
+	_ = os.WriteFile(envFile, []byte("MERAKI_API_KEY=abc\n"), 0600)
+	getenv := func(k string) string {
+		if k == "HISTORY_FILE" {
+			return "off"
+		}
+		return ""
+	}
+	out := filepath.Join(dir, "b.tar.gz")
+	if code := runBackupCommand(&bytes.Buffer{}, []string{"create", "--output", out}, envFile, getenv); code != 2 {
+		t.Errorf("create without a passphrase = %d, want 2", code)
+	}
+	if code := runBackupCommand(&bytes.Buffer{}, []string{"create", "--output", out, "--no-secrets"}, envFile, getenv); code != 0 {
+		t.Fatalf("create --no-secrets = %d, want 0", code)
+	}
+	_, members, err := readBackup(out)
+	if err != nil || strings.Contains(string(members[backupConfigName]), "abc") {
+		t.Errorf("--no-secrets archive config = %q, %v", members[backupConfigName], err)
+	}
+}
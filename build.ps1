@@ -3,9 +3,12 @@
 # Usage:
 #   .\build.ps1            - run tests + lint, then build .\findmac.exe
 #   .\build.ps1 -package   - same as above, then also build static binaries for all platforms in .\bin
+#   .\build.ps1 -msi       - same as the first, then also build the Windows installer in .\bin
+#                            (needs the WiX Toolset v4+: dotnet tool install --global wix)
 
 param(
-    [switch]$package
+    [switch]$package,
+    [switch]$msi
 )
 
 $ErrorActionPreference = "Stop"
@@ -79,6 +82,34 @@ if ($LASTEXITCODE -eq 0) {
     exit 1
 }
 
+if ($msi) {
+    Write-Host ""
+    if (!(Get-Command wix -ErrorAction SilentlyContinue)) {
+        Write-Host "wix not found; install the WiX Toolset with 'dotnet tool install --global wix'" -ForegroundColor Red
+        exit 1
+    }
+    if (!(Test-Path -Path $OutputDir)) {
+        New-Item -ItemType Directory -Path $OutputDir | Out-Null
+    }
+    $exe = "$OutputDir/$AppName-windows-amd64.exe"
+    $msiName = "$OutputDir/$AppName-$Version-x64.msi"
+    Write-Host "Building $msiName..." -ForegroundColor Cyan
+    $env:GOOS = "windows"
+    $env:GOARCH = "amd64"
+    $env:CGO_ENABLED = "0"
+    go build -ldflags $ldflags -o $exe .
+    if ($LASTEXITCODE -ne 0) {
+        Write-Host "  Failed to build $exe" -ForegroundColor Red
+        exit 1
+    }
+    wix build installer\windows\findmac.wxs -arch x64 -d Version=$Version -d Exe=$exe -o $msiName
+    if ($LASTEXITCODE -ne 0) {
+        Write-Host "  Failed to build $msiName" -ForegroundColor Red
+        exit 1
+    }
+    Write-Host "  $msiName" -ForegroundColor Green
+}
+
 if (-not $package) {
     Write-Host ""
     Write-Host "Done. Run '.\build.ps1 -package' to also build all platform binaries in .\bin." -ForegroundColor Cyan
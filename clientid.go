@@ -0,0 +1,86 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// lookupClientID finds a client by its Meraki client ID in each of the given networks
+// using the client detail endpoint. No MAC matching or MAC table polling is done —
+// the client record already names the switch and port it was last seen on.
+// Networks where the client ID does not exist are skipped.
+func lookupClientID(ctx context.Context, client *meraki.MerakiClient, org meraki.Organization, networks []meraki.Network, clientID string, log *logger.Logger) []output.ResultRow {
+	var results []output.ResultRow
+	resultsIndex := make(map[string]struct{})
+	for _, net := range networks {
+		c, err := client.GetNetworkClient(ctx, net.ID, clientID)
+		if err != nil {
+			log.Debugf("Client %s not found in network %s: %v", clientID, net.Name, err)
+			continue
+		}
+		serial := strings.TrimSpace(c.RecentDeviceSerial)
+		if serial == "" {
+			log.Debugf("Client %s in network %s has no recent device", clientID, net.Name)
+			continue
+		}
+		normMAC, err := macaddr.NormalizeExactMac(c.MAC)
+		if err != nil {
+			continue
+		}
+		log.Debugf("Client %s is %s on %s in network %s", clientID, macaddr.FormatMacColon(normMAC), firstNonEmpty(c.RecentDeviceName, serial), net.Name)
+
+		port := firstNonEmpty(c.SwitchportName, c.Switchport, c.Port, "unknown")
+		aggrMembers := resolveAggrPorts(ctx, client, serial, port, map[string]map[string][]string{})
+		vlan, portMode := enrichPortInfoWithMembers(ctx, client, serial, port, aggrMembers, 0, "")
+		ip := c.IP
+		hn, hnFrom := meraki.ClientHostname(*c), srcClientDetail
+		if hn == "" && ip != "" {
+			if hn, hnFrom = meraki.LookupHostOverride(ip, org.Name, net.Name), srcHostOverride; hn == "" {
+				hn, _ = meraki.ResolveHostname(ip)
+				hnFrom = srcReverseDNS
+			}
+		}
+		row := output.ResultRow{
+			OrgName:      org.Name,
+			NetworkName:  net.Name,
+			SwitchName:   firstNonEmpty(c.RecentDeviceName, serial),
+			SwitchSerial: serial,
+			Port:         port,
+			AggrPorts:    aggrMembers,
+			MAC:          macaddr.FormatMacColon(normMAC),
+			IP:           ip,
+			Hostname:     hn,
+			LastSeen:     c.LastSeen,
+			VLAN:         vlan,
+			PortMode:     portMode,
+			IsUplink:     isPortUplink(port, aggrMembers, client.GetDeviceUplinkPorts(ctx, serial)),
+			Source:       output.SourceClientID,
+		}
+		detail := fmt.Sprintf("GET /networks/%s/clients/%s", net.ID, clientID)
+		row.Explain = explainRow(row, output.FieldSource{Source: srcClientDetail, Detail: detail},
+			explainPortInfo(serial, port, 0, "", vlan, portMode, srcClientDetail), hostSources(ip, srcClientDetail, "", hn, hnFrom), srcClientDetail)
+		addResult(resultsIndex, &results, row)
+	}
+	return results
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+// narrowByClientSearch asks the organization-wide client search where each of
+// the exact macs has been seen and returns only those of the selected networks,
+// so a --network ALL search skips the switches of every other network.
+// The live MAC table can know a device the client list does not (yet), so the
+// selection is returned unchanged when any MAC is not found, the search fails,
+// or none of the networks it names are selected.
+func narrowByClientSearch(ctx context.Context, client *meraki.MerakiClient, orgID string, selected []meraki.Network, macs []string, log *logger.Logger) []meraki.Network {
+	if len(selected) < 2 || len(macs) == 0 {
+		return selected
+	}
+	seenIn := make(map[string]bool)
+	for _, mac := range macs {
+		recs, err := client.GetOrganizationClientsSearch(ctx, orgID, mac)
+		if (err == nil && len(recs) == 0) || errors.Is(err, meraki.ErrNotFound) {
+			log.Debugf("Client search: %s was never seen as a client; scanning all %d networks", mac, len(selected))
+			return selected
+		}
+		if err != nil {
+			log.Debugf("Client search for %s failed: %v; scanning all %d networks", mac, err, len(selected))
+			return selected
+		}
+		for _, rec := range recs {
+			seenIn[rec.Network.ID] = true
+		}
+	}
+	var narrowed []meraki.Network
+	for _, n := range selected {
+		if seenIn[n.ID] {
+			narrowed = append(narrowed, n)
+		}
+	}
+	if len(narrowed) == 0 {
+		log.Debugf("Client search found no selected network; scanning all %d networks", len(selected))
+		return selected
+	}
+	log.Infof("Client search: scanning %d of %d networks where the MAC was seen (--full-scan to scan all)", len(narrowed), len(selected))
+	return narrowed
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+func TestNarrowByClientSearch(t *testing.T) {
+	seen := map[string]string{
+		"aa:bb:cc:00:00:01": `[{"network":{"id":"N2"}}]`,
+		"aa:bb:cc:00:00:02": `[{"network":{"id":"N3"}},{"network":{"id":"N_OTHER"}}]`,
+		"aa:bb:cc:00:00:03": `[{"network":{"id":"N_OTHER"}}]`,
+		"aa:bb:cc:00:00:04": `[]`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recs, ok := seen[r.URL.Query().Get("mac")]
+		if !ok {
+			http.Error(w, `{"errors":["Client not found"]}`, http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"records":%s}`, recs)
+	}))
+	defer srv.Close()
+
+	client := meraki.NewClient("key", srv.URL, 1)
+	log := logger.NewWriter(io.Discard, logger.LevelError)
+	selected := []meraki.Network{{ID: "N1"}, {ID: "N2"}, {ID: "N3"}}
+	ids := func(nets []meraki.Network) string {
+		s := ""
+		for _, n := range nets {
+			s += n.ID + " "
+		}
+		return s
+	}
+
+	tests := []struct {
+		name string
+		macs []string
+		want string
+	}{
+		{"one network", []string{"aa:bb:cc:00:00:01"}, "N2 "},
+		{"union of several MACs", []string{"aa:bb:cc:00:00:01", "aa:bb:cc:00:00:02"}, "N2 N3 "},
+		{"only unselected networks", []string{"aa:bb:cc:00:00:03"}, "N1 N2 N3 "},
+		{"no records", []string{"aa:bb:cc:00:00:04"}, "N1 N2 N3 "},
+		{"unknown MAC", []string{"aa:bb:cc:00:00:01", "aa:bb:cc:00:00:99"}, "N1 N2 N3 "},
+	}
+	for _, tt := range tests {
+		if got := ids(narrowByClientSearch(context.Background(), client, "O1", selected, tt.macs, log)); got != tt.want {
+			t.Errorf("%s: networks = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := narrowByClientSearch(context.Background(), client, "O1", selected[:1], []string{"aa:bb:cc:00:00:01"}, log); ids(got) != "N1 " {
+		t.Errorf("single selected network was narrowed to %q", ids(got))
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"sort"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+// historyProductTypes are the event logs --history reads, in the order their
+// events are listed when two occur at the same time.
+var historyProductTypes = []string{"wireless", "switch", "appliance"}
+
+// connectionEvent is one event-log entry about a client, such as a wireless
+// association, an 802.1X authentication on a switch port or a DHCP lease.
+type connectionEvent struct {
+	At          time.Time
+	ProductType string
+	Device      string // device name, or serial when unnamed
+	Where       string // "SSID Corp", "port 14" or "" when the event does not say
+	Type        string
+	Description string
+	Moved       bool // the device or port/SSID differs from the previous located event
+}
+
+// location identifies where the client was during the event; "" when unknown.
+func (e connectionEvent) location() string {
+	if e.Device == "" {
+		return ""
+	}
+	if e.Where == "" {
+		return e.Device
+	}
+	return e.Device + " " + e.Where
+}
+
+// eventProductTypes returns the product types whose event logs are read for
+// network. Networks listed without their product types get every log.
+func eventProductTypes(network meraki.Network) []string {
+	if len(network.ProductTypes) == 0 {
+		return historyProductTypes
+	}
+	var out []string
+	for _, pt := range historyProductTypes {
+		if slices.Contains(network.ProductTypes, pt) {
+			out = append(out, pt)
+		}
+	}
+	return out
+}
+
+// eventWhere describes the SSID or switch port an event names, if any.
+func eventWhere(ev meraki.NetworkEvent) string {
+	if ev.SsidName != "" {
+		return "SSID " + ev.SsidName
+	}
+	if ev.SsidNumber != nil {
+		return fmt.Sprintf("SSID #%d", *ev.SsidNumber)
+	}
+	switch port := ev.EventData["port"].(type) {
+	case string:
+		if port != "" {
+			return "port " + port
+		}
+	case float64:
+		return fmt.Sprintf("port %d", int(port))
+	}
+	return ""
+}
+
+// buildConnectionHistory collects the events logged for mac in network since
+// the given time, oldest first, and flags each one where the client turned up
+// on a different device, port or SSID than in the event before.
+func buildConnectionHistory(ctx context.Context, client *meraki.MerakiClient, network meraki.Network, mac string, since time.Time, log *logger.Logger) []connectionEvent {
+	var events []connectionEvent
+	for _, pt := range eventProductTypes(network) {
+		page, err := client.GetNetworkEventsSince(ctx, network.ID, url.Values{
+			"productType": []string{pt},
+			"clientMac":   []string{mac},
+		}, since)
+		if err != nil {
+			log.Debugf("%s events for %s in %s: %v", pt, mac, network.Name, err)
+			continue
+		}
+		for _, ev := range page {
+			at, err := time.Parse(time.RFC3339, ev.OccurredAt)
+			if err != nil {
+				log.Debugf("Event %q in %s: bad occurredAt %q", ev.Type, network.Name, ev.OccurredAt)
+				continue
+			}
+			events = append(events, connectionEvent{
+				At:          at,
+				ProductType: pt,
+				Device:      firstNonEmpty(ev.DeviceName, ev.DeviceSerial),
+				Where:       eventWhere(ev),
+				Type:        ev.Type,
+				Description: ev.Description,
+			})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+
+	last := ""
+	for i := range events {
+		loc := events[i].location()
+		if loc == "" {
+			continue
+		}
+		events[i].Moved = last != "" && loc != last
+		last = loc
+	}
+	return events
+}
+
+// writeConnectionHistory writes the events of one network as a timeline.
+func writeConnectionHistory(w io.Writer, networkName, mac, span string, events []connectionEvent) {
+	_, _ = fmt.Fprintf(w, "Connection history for %s in %s (last %s):\n", mac, networkName, span)
+	for _, e := range events {
+		note := ""
+		if e.Moved {
+			note = "  <- moved"
+		}
+		_, _ = fmt.Fprintf(w, "  %s  %-9s  %-28s  %-20s  %s%s\n",
+			e.At.Local().Format("2006-01-02 15:04"), e.ProductType, firstNonEmpty(e.location(), "-"), e.Type, e.Description, note)
+	}
+}
+
+// reportConnectionHistory writes the connection history of mac in every network
+// that logged events for it within span (e.g. "7d") before now.
+func reportConnectionHistory(ctx context.Context, w io.Writer, client *meraki.MerakiClient, networks []meraki.Network, mac, span string, window time.Duration, log *logger.Logger) {
+	since := time.Now().Add(-window)
+	found := false
+	for _, network := range networks {
+		if ctx.Err() != nil {
+			break
+		}
+		events := buildConnectionHistory(ctx, client, network, mac, since, log)
+		if len(events) == 0 {
+			continue
+		}
+		if found {
+			_, _ = fmt.Fprintln(w)
+		}
+		found = true
+		writeConnectionHistory(w, network.Name, mac, span, events)
+	}
+	if !found {
+		_, _ = fmt.Fprintf(w, "No connection events for %s in the last %s.\n", mac, span)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+func TestBuildConnectionHistory_MarksMoves(t *testing.T) {
+	var products []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/networks/N1/events" || q.Get("clientMac") != "aa:bb:cc:00:00:01" || q.Get("startingAfter") == "" {
+			http.NotFound(w, r)
+			return
+		}
+		products = append(products, q.Get("productType"))
+		switch q.Get("productType") {
+		case "wireless":
+			_, _ = w.Write([]byte(`{"events":[
+				{"occurredAt":"2026-10-01T09:00:00Z","type":"association","description":"802.11 association","deviceName":"AP-Lobby","ssidName":"Corp"},
+				{"occurredAt":"2026-10-01T12:00:00Z","type":"association","description":"802.11 association","deviceName":"AP-Lab","ssidName":"Corp"}]}`))
+		case "switch":
+			_, _ = w.Write([]byte(`{"events":[
+				{"occurredAt":"2026-10-01T10:00:00Z","type":"8021x_auth","description":"802.1X authentication","deviceSerial":"Q2AA-0001","eventData":{"port":"14"}},
+				{"occurredAt":"2026-10-01T11:00:00Z","type":"8021x_auth","description":"802.1X authentication","deviceSerial":"Q2AA-0001","eventData":{"port":14}}]}`))
+		default:
+			http.Error(w, `{"errors":["no appliance"]}`, http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	network := meraki.Network{ID: "N1", Name: "HQ", ProductTypes: []string{"switch", "wireless", "camera"}}
+	events := buildConnectionHistory(context.Background(), meraki.NewClient("key", srv.URL, 1), network, "aa:bb:cc:00:00:01", time.Now().Add(-24*time.Hour), logger.NewWriter(io.Discard, logger.LevelError))
+
+	if strings.Join(products, ",") != "wireless,switch" {
+		t.Errorf("product types queried = %v, want wireless,switch", products)
+	}
+	var locs []string
+	var moved []bool
+	for _, e := range events {
+		locs = append(locs, e.location())
+		moved = append(moved, e.Moved)
+	}
+	wantLocs := []string{"AP-Lobby SSID Corp", "Q2AA-0001 port 14", "Q2AA-0001 port 14", "AP-Lab SSID Corp"}
+	if strings.Join(locs, "|") != strings.Join(wantLocs, "|") {
+		t.Fatalf("locations = %q, want %q", locs, wantLocs)
+	}
+	if want := []bool{false, true, false, true}; !slices.Equal(moved, want) {
+		t.Errorf("moved = %v, want %v", moved, want)
+	}
+
+	var buf bytes.Buffer
+	writeConnectionHistory(&buf, "HQ", "aa:bb:cc:00:00:01", "1d", events)
+	for _, want := range []string{"Connection history for aa:bb:cc:00:00:01 in HQ (last 1d):", "AP-Lab SSID Corp", "<- moved"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writeConnectionHistory() missing %q\nfull:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestReportConnectionHistory_NoEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"events":[]}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	reportConnectionHistory(context.Background(), &buf, meraki.NewClient("key", srv.URL, 1), []meraki.Network{{ID: "N1", Name: "HQ"}}, "aa:bb:cc:00:00:01", "7d", 7*24*time.Hour, logger.NewWriter(io.Discard, logger.LevelError))
+	if got := buf.String(); got != "No connection events for aa:bb:cc:00:00:01 in the last 7d.\n" {
+		t.Errorf("output = %q", got)
+	}
+}
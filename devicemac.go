@@ -0,0 +1,72 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// findDeviceMACs explains an empty MAC search whose MAC belongs to a Meraki
+// device itself: the management MAC of a switch, AP or appliance never shows up
+// as a client, so it is looked up in the organization's device uplink address
+// listing instead. One row per matching device in networks is returned, with
+// the uplink address as IP and a Note naming the device.
+func findDeviceMACs(ctx context.Context, client *meraki.MerakiClient, org meraki.Organization, networks []meraki.Network, matcher func(string) bool, log *logger.Logger) []output.ResultRow {
+	devices, err := client.GetOrganizationDeviceUplinkAddresses(ctx, org.ID)
+	if err != nil {
+		log.Debugf("Device uplink addresses: %v", err)
+		return nil
+	}
+	netNames := make(map[string]string, len(networks))
+	for _, n := range networks {
+		netNames[n.ID] = n.Name
+	}
+
+	var rows []output.ResultRow
+	for _, d := range devices {
+		netName, selected := netNames[d.Network.ID]
+		if !selected {
+			continue
+		}
+		norm, err := macaddr.NormalizeExactMac(d.MAC)
+		if err != nil || !matcher(norm) {
+			continue
+		}
+		name := firstNonEmpty(d.Name, d.Serial)
+		rows = append(rows, output.ResultRow{
+			OrgName:      org.Name,
+			NetworkName:  netName,
+			SwitchName:   name,
+			SwitchSerial: d.Serial,
+			MAC:          macaddr.FormatMacColon(norm),
+			IP:           d.UplinkAddress(),
+			Note:         fmt.Sprintf("management MAC of %s %s", firstNonEmpty(d.ProductType, "device"), name),
+			Source:       output.SourceDevice,
+		})
+		row := &rows[len(rows)-1]
+		row.Explain = []output.FieldSource{{Field: "MAC", Source: srcDeviceUplinks, Detail: fmt.Sprintf("GET /organizations/%s/devices/uplinks/addresses/byDevice", org.ID)}}
+		if row.IP != "" {
+			row.Explain = append(row.Explain, output.FieldSource{Field: "IP", Source: srcDeviceUplinks})
+		}
+	}
+	return rows
+}
@@ -0,0 +1,56 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+func TestFindDeviceMACs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/organizations/O1/devices/uplinks/addresses/byDevice" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"serial":"Q2SW","name":"idf-2","mac":"0c:8d:db:00:00:01","productType":"switch","network":{"id":"N1"},
+			 "uplinks":[{"interface":"man1","addresses":[{"protocol":"ipv6","address":"fe80::1"},{"protocol":"ipv4","address":"10.0.0.8"}]}]},
+			{"serial":"Q2OT","name":"other","mac":"0c:8d:db:00:00:01","productType":"switch","network":{"id":"N9"}},
+			{"serial":"Q2AP","name":"ap-1","mac":"0c:8d:db:00:00:02","productType":"wireless","network":{"id":"N1"}}]`))
+	}))
+	defer srv.Close()
+
+	client := meraki.NewClient("key", srv.URL, 1)
+	matcher, _, _, _ := macaddr.BuildMacMatcher("0c:8d:db:00:00:01")
+	rows := findDeviceMACs(context.Background(), client, meraki.Organization{ID: "O1", Name: "Acme"},
+		[]meraki.Network{{ID: "N1", Name: "HQ"}}, matcher, logger.NewWriter(io.Discard, logger.LevelError))
+
+	if len(rows) != 1 {
+		t.Fatalf("findDeviceMACs() = %d rows, want 1 (other network excluded): %+v", len(rows), rows)
+	}
+	r := rows[0]
+	if r.SwitchSerial != "Q2SW" || r.NetworkName != "HQ" || r.IP != "10.0.0.8" || r.Note != "management MAC of switch idf-2" {
+		t.Errorf("findDeviceMACs() row = %+v", r)
+	}
+}
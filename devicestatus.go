@@ -0,0 +1,89 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// deviceStatuses fills in whether the switch (or access point or appliance)
+// of each result is online, from the organization's device status listing,
+// so an empty search can be told apart from one whose switch is down. The
+// listing is fetched once, on first use.
+type deviceStatuses struct {
+	ctx        context.Context
+	client     *meraki.MerakiClient
+	orgID      string
+	networkIDs []string                       // networks to list; nil for the whole organization
+	bySerial   map[string]meraki.DeviceStatus // nil until loaded
+}
+
+func newDeviceStatuses(ctx context.Context, client *meraki.MerakiClient, orgID string, networkIDs []string) *deviceStatuses {
+	return &deviceStatuses{ctx: ctx, client: client, orgID: orgID, networkIDs: networkIDs}
+}
+
+// fill sets row's DeviceStatus and LastReported. Rows of devices missing from
+// the listing, or when it cannot be read, are left alone.
+func (d *deviceStatuses) fill(row *output.ResultRow) {
+	st, ok := d.status(row.SwitchSerial)
+	if !ok {
+		return
+	}
+	row.DeviceStatus, row.LastReported = st.Status, st.LastReportedAt
+	if row.Explain != nil {
+		row.Explain = setFieldSource(row.Explain, output.FieldSource{Field: "DeviceStatus", Source: srcDeviceStatuses, Detail: "GET /organizations/" + d.orgID + "/devices/statuses"})
+	}
+}
+
+// status returns the listed status of the device with serial.
+func (d *deviceStatuses) status(serial string) (meraki.DeviceStatus, bool) {
+	if serial == "" {
+		return meraki.DeviceStatus{}, false
+	}
+	if d.bySerial == nil {
+		d.bySerial = make(map[string]meraki.DeviceStatus)
+		statuses, _ := d.client.GetOrganizationDeviceStatuses(d.ctx, d.orgID, d.networkIDs)
+		for _, s := range statuses {
+			d.bySerial[s.Serial] = s
+		}
+	}
+	st, ok := d.bySerial[serial]
+	return st, ok
+}
+
+// offlineWarnings reports the devices to be searched in network that the
+// dashboard lists as offline or dormant, since nothing behind them can be
+// found.
+func (d *deviceStatuses) offlineWarnings(network string, devices []meraki.Device) []output.Warning {
+	var out []output.Warning
+	for _, dev := range devices {
+		st, ok := d.status(dev.Serial)
+		if !ok || (st.Status != "offline" && st.Status != "dormant") {
+			continue
+		}
+		out = append(out, output.Warning{
+			Kind:    output.WarningOfflineDevice,
+			Message: fmt.Sprintf("%s in %s is %s (last reported %s); clients behind it cannot be found", firstNonEmpty(dev.Name, dev.Serial), network, st.Status, firstNonEmpty(st.LastReportedAt, "never")),
+			Network: network,
+			Device:  dev.Serial,
+		})
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestDeviceStatuses(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/organizations/O1/devices/statuses" || r.URL.Query().Get("networkIds[]") != "N1" {
+			http.NotFound(w, r)
+			return
+		}
+		calls++
+		_, _ = w.Write([]byte(`[
+			{"serial":"Q2AA-0001","name":"sw-core","status":"online","lastReportedAt":"2026-10-18T08:00:00Z"},
+			{"serial":"Q2AA-0002","name":"sw-closet","status":"offline","lastReportedAt":"2026-10-17T22:15:00Z"}]`))
+	}))
+	defer srv.Close()
+
+	d := newDeviceStatuses(context.Background(), meraki.NewClient("key", srv.URL, 1), "O1", []string{"N1"})
+	row := output.ResultRow{SwitchSerial: "Q2AA-0001", Port: "3", Explain: []output.FieldSource{}}
+	d.fill(&row)
+	if row.DeviceStatus != "online" || row.LastReported != "2026-10-18T08:00:00Z" {
+		t.Errorf("fill() = %q, %q; want online at 08:00", row.DeviceStatus, row.LastReported)
+	}
+	if len(row.Explain) != 1 || row.Explain[0].Field != "DeviceStatus" || row.Explain[0].Source != srcDeviceStatuses {
+		t.Errorf("Explain = %+v, want the device status source", row.Explain)
+	}
+	unknown := output.ResultRow{SwitchSerial: "Q2AA-0009"}
+	d.fill(&unknown)
+	if unknown.DeviceStatus != "" {
+		t.Errorf("unlisted device got status %q", unknown.DeviceStatus)
+	}
+
+	warnings := d.offlineWarnings("HQ", []meraki.Device{{Serial: "Q2AA-0001"}, {Serial: "Q2AA-0002", Name: "sw-closet"}})
+	if len(warnings) != 1 || warnings[0].Kind != output.WarningOfflineDevice || warnings[0].Device != "Q2AA-0002" {
+		t.Fatalf("offlineWarnings() = %+v, want sw-closet", warnings)
+	}
+	if want := "sw-closet in HQ is offline (last reported 2026-10-17T22:15:00Z); clients behind it cannot be found"; warnings[0].Message != want {
+		t.Errorf("message = %q, want %q", warnings[0].Message, want)
+	}
+	if calls != 1 {
+		t.Errorf("statuses fetched %d times, want once", calls)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/config"
+	"Find-Meraki-Ports-With-MAC/pkg/filters"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// nonSwitchResult builds the location part of a result for a client found on
+// an access point or security appliance (--device-types) rather than a switch:
+// the device takes the switch's place, a wireless client's port is "wireless"
+// with its SSID reported, and an appliance client keeps the appliance port if
+// known. ok is false when the row fails a filter. Without a port
+// configuration to read, the VLAN and port mode are unknown, so --vlan and
+// --port-mode exclude these rows, as does --entry-type.
+func nonSwitchResult(dev meraki.Device, devType, normMAC, rawPort, ssid string, cfg config.Config) (row output.ResultRow, ok bool) {
+	port := firstNonEmpty(rawPort, "unknown")
+	if devType == "wireless" {
+		port = "wireless"
+	} else {
+		ssid = ""
+	}
+	if !filters.MatchesPortFilter(port, cfg.PortFilter) || !filters.MatchesVLANFilter(0, cfg.VLANFilter) ||
+		!filters.MatchesPortModeFilter("", cfg.PortMode) || !filters.MatchesEntryTypeFilter("", cfg.EntryType) {
+		return output.ResultRow{}, false
+	}
+	return output.ResultRow{
+		SwitchName:   firstNonEmpty(dev.Name, dev.Serial),
+		SwitchSerial: dev.Serial,
+		Port:         port,
+		MAC:          macaddr.FormatMacColon(normMAC),
+		SSID:         ssid,
+	}, true
+}
+
+// branchAppliances returns the security appliances searched when
+// --device-types is not given: those of a network without switches, such as a
+// small branch where every device hangs off an MX LAN port.
+func branchAppliances(devices []meraki.Device) []meraki.Device {
+	if len(filters.FilterSwitches(devices)) > 0 {
+		return nil
+	}
+	return filters.FilterDevicesByType(devices, []string{"appliance"})
+}
+
+// applianceArpResults returns a result for each MAC in an appliance's live ARP
+// table (normalized MAC → IP) that matcher accepts and found does not hold:
+// devices behind the appliance that have dropped off its client list. Their
+// LAN port is unknown. Rows are ordered by MAC.
+func applianceArpResults(dev meraki.Device, arp map[string]string, matcher func(string) bool, found map[string]bool, cfg config.Config) []output.ResultRow {
+	var rows []output.ResultRow
+	for normMAC, ip := range arp {
+		if found[normMAC] || !matcher(normMAC) {
+			continue
+		}
+		row, ok := nonSwitchResult(dev, "appliance", normMAC, "", "", cfg)
+		if !ok {
+			continue
+		}
+		row.IP, row.Source = ip, output.SourceArpTable
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].MAC < rows[j].MAC })
+	return rows
+}
+
+// isWirelessClient reports whether a network client was last seen on an
+// access point: its recent device is one or, when that device is not among
+// those listed, the clients API calls the connection wireless.
+func isWirelessClient(c meraki.NetworkClient, dev meraki.Device) bool {
+	if dev.Serial != "" {
+		return filters.DeviceType(dev) == "wireless"
+	}
+	return strings.EqualFold(c.RecentConnection, "wireless")
+}
+
+// wirelessRadios fills in the band and signal strength of wireless results
+// from each client's signal quality history, asking once per client.
+type wirelessRadios struct {
+	ctx    context.Context
+	client *meraki.MerakiClient
+	seen   map[string]wirelessRadio // networkID/clientID → radio
+}
+
+type wirelessRadio struct {
+	band string
+	rssi int
+}
+
+func newWirelessRadios(ctx context.Context, client *meraki.MerakiClient) *wirelessRadios {
+	return &wirelessRadios{ctx: ctx, client: client, seen: make(map[string]wirelessRadio)}
+}
+
+// fill sets row's Band and RSSI for the client with the given Meraki client
+// ID. Rows without a client ID, or whose client was not heard in the last
+// hour, are left alone.
+func (w *wirelessRadios) fill(row *output.ResultRow, networkID, clientID string) {
+	if clientID == "" {
+		return
+	}
+	key := networkID + "/" + clientID
+	radio, ok := w.seen[key]
+	if !ok {
+		if band, rssi, found := w.client.GetWirelessClientRadio(w.ctx, networkID, clientID); found {
+			radio = wirelessRadio{band: band + " GHz", rssi: rssi}
+		}
+		w.seen[key] = radio
+	}
+	if radio.band == "" {
+		return
+	}
+	row.Band, row.RSSI = radio.band, radio.rssi
+	if row.Explain != nil {
+		row.Explain = setFieldSource(row.Explain, output.FieldSource{Field: "Band", Source: srcSignalQuality, Detail: "GET /networks/" + networkID + "/wireless/signalQualityHistory"})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/config"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestNonSwitchResult(t *testing.T) {
+	ap := meraki.Device{Serial: "Q2MR-0001", Name: "ap-lobby", ProductType: "wireless"}
+	mx := meraki.Device{Serial: "Q2MX-0001", ProductType: "appliance"}
+
+	row, ok := nonSwitchResult(ap, "wireless", "aabbcc000001", "", "Corp", config.Config{})
+	if !ok || row.SwitchName != "ap-lobby" || row.Port != "wireless" || row.SSID != "Corp" || row.MAC != "aa:bb:cc:00:00:01" {
+		t.Errorf("nonSwitchResult(AP) = %+v, %v", row, ok)
+	}
+	row, ok = nonSwitchResult(mx, "appliance", "aabbcc000001", "3", "Corp", config.Config{})
+	if !ok || row.SwitchName != "Q2MX-0001" || row.Port != "3" || row.SSID != "" {
+		t.Errorf("nonSwitchResult(MX) = %+v, %v", row, ok)
+	}
+
+	for _, cfg := range []config.Config{{PortFilter: "5"}, {VLANFilter: 10}, {PortMode: "access"}, {EntryType: "dynamic"}} {
+		if _, ok := nonSwitchResult(ap, "wireless", "aabbcc000001", "", "Corp", cfg); ok {
+			t.Errorf("nonSwitchResult(AP) with %+v kept the row", cfg)
+		}
+	}
+	if _, ok := nonSwitchResult(ap, "wireless", "aabbcc000001", "", "Corp", config.Config{PortFilter: "wireless"}); !ok {
+		t.Error("nonSwitchResult(AP) with --port wireless dropped the row")
+	}
+}
+
+func TestBranchAppliances(t *testing.T) {
+	mx := meraki.Device{Serial: "Q2MX-0001", Model: "MX68"}
+	ap := meraki.Device{Serial: "Q2MR-0001", Model: "MR36"}
+	sw := meraki.Device{Serial: "Q2SW-0001", Model: "MS120-8"}
+	if got := branchAppliances([]meraki.Device{mx, ap}); len(got) != 1 || got[0].Serial != "Q2MX-0001" {
+		t.Errorf("branchAppliances(switchless) = %+v, want the MX", got)
+	}
+	if got := branchAppliances([]meraki.Device{mx, sw}); got != nil {
+		t.Errorf("branchAppliances(with switch) = %+v, want nil", got)
+	}
+}
+
+func TestApplianceArpResults(t *testing.T) {
+	mx := meraki.Device{Serial: "Q2MX-0001", Name: "mx-branch", Model: "MX68"}
+	arp := map[string]string{
+		"aabbcc000002": "10.0.0.2",
+		"aabbcc000001": "10.0.0.1",
+		"aabbcc000003": "10.0.0.3", // already in the client list
+		"ddeeff000001": "10.0.0.9", // not searched for
+	}
+	matcher := func(mac string) bool { return mac[:6] == "aabbcc" }
+	rows := applianceArpResults(mx, arp, matcher, map[string]bool{"aabbcc000003": true}, config.Config{})
+	if len(rows) != 2 {
+		t.Fatalf("applianceArpResults() = %d rows, want 2: %+v", len(rows), rows)
+	}
+	want := output.ResultRow{SwitchName: "mx-branch", SwitchSerial: "Q2MX-0001", Port: "unknown", MAC: "aa:bb:cc:00:00:01", IP: "10.0.0.1", Source: output.SourceArpTable}
+	if rows[0].SwitchName != want.SwitchName || rows[0].SwitchSerial != want.SwitchSerial || rows[0].Port != want.Port ||
+		rows[0].MAC != want.MAC || rows[0].IP != want.IP || rows[0].Source != want.Source {
+		t.Errorf("rows[0] = %+v, want %+v", rows[0], want)
+	}
+	if rows[1].MAC != "aa:bb:cc:00:00:02" {
+		t.Errorf("rows[1].MAC = %s, want rows ordered by MAC", rows[1].MAC)
+	}
+	if rows := applianceArpResults(mx, arp, matcher, nil, config.Config{VLANFilter: 10}); len(rows) != 0 {
+		t.Errorf("--vlan should exclude ARP rows, got %+v", rows)
+	}
+}
+
+func TestIsWirelessClient(t *testing.T) {
+	ap := meraki.Device{Serial: "Q2MR-0001", Model: "MR46"}
+	sw := meraki.Device{Serial: "Q2SW-0001", Model: "MS120-8"}
+	wireless := meraki.NetworkClient{RecentConnection: "Wireless"}
+	tests := []struct {
+		c    meraki.NetworkClient
+		dev  meraki.Device
+		want bool
+	}{
+		{meraki.NetworkClient{}, ap, true},
+		{wireless, sw, false},
+		{wireless, meraki.Device{}, true},
+		{meraki.NetworkClient{RecentConnection: "Wired"}, meraki.Device{}, false},
+	}
+	for _, tt := range tests {
+		if got := isWirelessClient(tt.c, tt.dev); got != tt.want {
+			t.Errorf("isWirelessClient(%+v, %s) = %v, want %v", tt.c, tt.dev.Model, got, tt.want)
+		}
+	}
+}
+
+func TestWirelessRadiosFill(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("band") == "5" && r.URL.Query().Get("clientId") == "k1" {
+			_, _ = w.Write([]byte(`[{"endTs":"2026-10-18T09:00:00Z","rssi":-61,"snr":30}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	radios := newWirelessRadios(context.Background(), meraki.NewClient("key", srv.URL, 1))
+	row := output.ResultRow{Port: "wireless", SSID: "Corp", Explain: []output.FieldSource{{Field: "Port"}}}
+	radios.fill(&row, "N1", "k1")
+	if row.Band != "5 GHz" || row.RSSI != -61 {
+		t.Errorf("fill() = band %q, RSSI %d; want 5 GHz, -61", row.Band, row.RSSI)
+	}
+	if e := row.Explain[len(row.Explain)-1]; e.Field != "Band" || e.Source != srcSignalQuality {
+		t.Errorf("fill() explain = %+v", row.Explain)
+	}
+	again := output.ResultRow{Port: "wireless"}
+	radios.fill(&again, "N1", "k1")
+	if again.Band != "5 GHz" || calls != len(meraki.WirelessBands) {
+		t.Errorf("second fill() = %q after %d calls, want cached 5 GHz after %d", again.Band, calls, len(meraki.WirelessBands))
+	}
+	quiet := output.ResultRow{Port: "wireless"}
+	radios.fill(&quiet, "N1", "k2")
+	if radios.fill(&quiet, "N1", ""); quiet.Band != "" || quiet.RSSI != 0 {
+		t.Errorf("fill(client not heard) = %+v, want no band", quiet)
+	}
+}
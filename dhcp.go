@@ -0,0 +1,202 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/filters"
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// dhcpServerInfo describes which DHCP server answers a client's subnet and where
+// that server is attached to the switching fabric.
+type dhcpServerInfo struct {
+	ClientIP   string
+	NetworkID  string
+	VLAN       string
+	Subnet     string
+	Handling   string // appliance dhcpHandling value
+	ServerIP   string
+	ServerMAC  string // normalized (no separators); empty when unknown
+	ServerName string
+	Sightings  []dhcpSighting // switch ports where ServerMAC was seen
+}
+
+// dhcpSighting is one switch port on which the DHCP server's MAC was seen.
+type dhcpSighting struct {
+	SwitchName   string
+	SwitchSerial string
+	Port         string
+}
+
+// locateDHCPServer finds the appliance VLAN containing clientIP, works out which
+// server answers DHCP for it (the MX itself or a relay target) and then finds the
+// switch ports where that server's MAC is learned, first from network clients and
+// then from live MAC tables.
+func locateDHCPServer(ctx context.Context, client *meraki.MerakiClient, network meraki.Network, clientIP string, macTablePoll int, log *logger.Logger) (*dhcpServerInfo, error) {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return nil, fmt.Errorf("client IP %q is not valid", clientIP)
+	}
+	vlans, err := client.GetApplianceVLANs(ctx, network.ID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read appliance VLANs for %s: %v", network.Name, err)
+	}
+	var vlan *meraki.ApplianceVLAN
+	for i := range vlans {
+		if _, subnet, err := net.ParseCIDR(vlans[i].Subnet); err == nil && subnet.Contains(ip) {
+			vlan = &vlans[i]
+			break
+		}
+	}
+	if vlan == nil {
+		return nil, fmt.Errorf("no appliance VLAN in %s contains %s", network.Name, clientIP)
+	}
+
+	info := &dhcpServerInfo{
+		ClientIP:  clientIP,
+		NetworkID: network.ID,
+		VLAN:      fmt.Sprint(vlan.ID),
+		Subnet:    vlan.Subnet,
+		Handling:  vlan.DhcpHandling,
+	}
+
+	devices, err := client.GetDevices(ctx, network.ID)
+	if err != nil {
+		return info, fmt.Errorf("cannot list devices for %s: %v", network.Name, err)
+	}
+	networkClients, _ := client.GetNetworkClients(ctx, network.ID)
+
+	switch {
+	case strings.HasPrefix(vlan.DhcpHandling, "Run"):
+		info.ServerIP = vlan.ApplianceIP
+		for _, d := range devices {
+			if d.ProductType == "appliance" || strings.HasPrefix(strings.ToUpper(d.Model), "MX") {
+				info.ServerName = firstNonEmpty(d.Name, d.Serial)
+				info.ServerMAC, _ = macaddr.NormalizeExactMac(d.MAC)
+				break
+			}
+		}
+	case strings.HasPrefix(vlan.DhcpHandling, "Relay"):
+		if len(vlan.DhcpRelayServerIPs) > 0 {
+			info.ServerIP = vlan.DhcpRelayServerIPs[0]
+		}
+		for _, nc := range networkClients {
+			if info.ServerIP != "" && nc.IP == info.ServerIP {
+				info.ServerName = meraki.ClientHostname(nc)
+				info.ServerMAC, _ = macaddr.NormalizeExactMac(nc.MAC)
+				break
+			}
+		}
+	default:
+		// "Do not respond to DHCP requests" — some other server answers; nothing to locate.
+		return info, nil
+	}
+	if info.ServerMAC == "" {
+		return info, nil
+	}
+	log.Debugf("DHCP server for %s is %s (%s); locating on switches", clientIP, firstNonEmpty(info.ServerName, info.ServerIP), macaddr.FormatMacColon(info.ServerMAC))
+
+	// Cheapest first: the network clients list already names switch and port.
+	for _, nc := range networkClients {
+		if norm, err := macaddr.NormalizeExactMac(nc.MAC); err == nil && norm == info.ServerMAC && nc.RecentDeviceSerial != "" {
+			port := firstNonEmpty(nc.SwitchportName, nc.Switchport, nc.Port)
+			if port != "" {
+				info.Sightings = append(info.Sightings, dhcpSighting{
+					SwitchName:   firstNonEmpty(nc.RecentDeviceName, nc.RecentDeviceSerial),
+					SwitchSerial: nc.RecentDeviceSerial,
+					Port:         port,
+				})
+				return info, nil
+			}
+		}
+	}
+
+	for _, dev := range filters.FilterSwitches(devices) {
+		for _, entry := range pollMacTable(ctx, client, dev.Serial, macTablePoll) {
+			macStr, _ := entry["mac"].(string)
+			if norm, err := macaddr.NormalizeExactMac(macStr); err != nil || norm != info.ServerMAC {
+				continue
+			}
+			port, _ := parseAggrPort(firstNonEmpty(macTableEntryPort(entry), "unknown"))
+			info.Sightings = append(info.Sightings, dhcpSighting{
+				SwitchName:   firstNonEmpty(dev.Name, dev.Serial),
+				SwitchSerial: dev.Serial,
+				Port:         port,
+			})
+		}
+	}
+	return info, nil
+}
+
+// writeDHCPServerInfo writes a human-readable DHCP server summary.
+func writeDHCPServerInfo(w io.Writer, info *dhcpServerInfo) {
+	_, _ = fmt.Fprintf(w, "DHCP server for %s (VLAN %s, %s):\n", info.ClientIP, info.VLAN, info.Subnet)
+	_, _ = fmt.Fprintf(w, "  Handling: %s\n", firstNonEmpty(info.Handling, "unknown"))
+	if info.ServerIP == "" && info.ServerMAC == "" {
+		_, _ = fmt.Fprintln(w, "  Server:   not served by the appliance")
+		return
+	}
+	server := firstNonEmpty(info.ServerName, info.ServerIP)
+	if info.ServerMAC != "" {
+		server += " [" + macaddr.FormatMacColon(info.ServerMAC) + "]"
+	}
+	_, _ = fmt.Fprintf(w, "  Server:   %s (%s)\n", server, firstNonEmpty(info.ServerIP, "no IP"))
+	if len(info.Sightings) == 0 {
+		_, _ = fmt.Fprintln(w, "  Seen on:  (not found in any switch MAC table)")
+		return
+	}
+	for _, s := range info.Sightings {
+		_, _ = fmt.Fprintf(w, "  Seen on:  %s (%s) port %s\n", s.SwitchName, s.SwitchSerial, s.Port)
+	}
+}
+
+// reportDHCPServers locates the DHCP server for each distinct client IP in rows
+// and writes a summary to w. Rows without an IP are skipped.
+func reportDHCPServers(ctx context.Context, w io.Writer, client *meraki.MerakiClient, networks []meraki.Network, rows []output.ResultRow, macTablePoll int, log *logger.Logger) {
+	byName := make(map[string]meraki.Network, len(networks))
+	for _, n := range networks {
+		byName[n.Name] = n
+	}
+	seen := make(map[string]struct{})
+	for _, r := range rows {
+		network, ok := byName[r.NetworkName]
+		key := r.NetworkName + "|" + r.IP
+		if r.IP == "" || !ok {
+			continue
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		info, err := locateDHCPServer(ctx, client, network, r.IP, macTablePoll, log)
+		if err != nil {
+			log.Warnf("DHCP server lookup for %s: %v", r.IP, err)
+			if info == nil {
+				continue
+			}
+		}
+		writeDHCPServerInfo(w, info)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+func TestLocateDHCPServer_Relay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/networks/N1/appliance/vlans":
+			_, _ = w.Write([]byte(`[{"id":10,"subnet":"10.0.10.0/24","applianceIp":"10.0.10.1","dhcpHandling":"Relay DHCP to another server","dhcpRelayServerIps":["10.0.1.5"]}]`))
+		case "/networks/N1/devices":
+			_, _ = w.Write([]byte(`[]`))
+		case "/networks/N1/clients":
+			_, _ = w.Write([]byte(`[{"mac":"aa:bb:cc:00:00:05","ip":"10.0.1.5","hostname":"dhcp01","recentDeviceSerial":"Q2SW","recentDeviceName":"core","switchport":"12"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := meraki.NewClient("key", srv.URL, 1)
+	info, err := locateDHCPServer(context.Background(), client, meraki.Network{ID: "N1", Name: "HQ"}, "10.0.10.42", 1, logger.NewWriter(io.Discard, logger.LevelError))
+	if err != nil {
+		t.Fatalf("locateDHCPServer() error: %v", err)
+	}
+	if info.ServerIP != "10.0.1.5" || info.ServerName != "dhcp01" || info.VLAN != "10" {
+		t.Errorf("locateDHCPServer() = %+v", info)
+	}
+	if len(info.Sightings) != 1 || info.Sightings[0].Port != "12" || info.Sightings[0].SwitchSerial != "Q2SW" {
+		t.Errorf("Sightings = %+v, want core port 12", info.Sightings)
+	}
+
+	var buf bytes.Buffer
+	writeDHCPServerInfo(&buf, info)
+	for _, want := range []string{"VLAN 10", "Relay DHCP", "dhcp01", "aa:bb:cc:00:00:05", "core (Q2SW) port 12"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writeDHCPServerInfo() missing %q\nfull:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestLocateDHCPServer_NoMatchingVLAN(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"id":1,"subnet":"192.168.1.0/24","dhcpHandling":"Run a DHCP server"}]`))
+	}))
+	defer srv.Close()
+
+	client := meraki.NewClient("key", srv.URL, 1)
+	if _, err := locateDHCPServer(context.Background(), client, meraki.Network{ID: "N1", Name: "HQ"}, "10.9.9.9", 1, logger.NewWriter(io.Discard, logger.LevelError)); err == nil {
+		t.Error("locateDHCPServer() for IP outside every VLAN should return an error")
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// dhcpLeases fills in the DHCP hostname and lease expiry of each result, so a
+// client has a name even when reverse DNS has none. The hostname is the one
+// the client sent with its DHCP request, from the network clients list, or
+// else its reservation name. The lease comes from the DHCP scope serving the
+// result's IP: an appliance VLAN or, failing that, a routed interface on the
+// result's switch. Each network's VLANs and each switch's interfaces are read
+// once.
+type dhcpLeases struct {
+	ctx      context.Context
+	client   *meraki.MerakiClient
+	networks map[string]*leaseNetwork // network name → clients and appliance scopes
+	switches map[string][]dhcpScope   // serial → scopes of its routed interfaces
+}
+
+// leaseNetwork is what dhcpLeases knows about one network.
+type leaseNetwork struct {
+	id      string
+	clients map[string]string // normalized MAC → DHCP hostname
+	scopes  []dhcpScope       // appliance VLANs; nil until loaded
+	loaded  bool
+}
+
+// dhcpScope is a subnet that an appliance VLAN or switch interface serves or
+// relays DHCP for.
+type dhcpScope struct {
+	subnet    *net.IPNet
+	serves    bool              // the device runs the DHCP server itself rather than relaying
+	leaseTime time.Duration     // 0 when unknown
+	reserved  map[string]string // normalized MAC → reservation name
+	detail    string            // API path, for --explain
+}
+
+func newDHCPLeases(ctx context.Context, client *meraki.MerakiClient) *dhcpLeases {
+	return &dhcpLeases{ctx: ctx, client: client, networks: make(map[string]*leaseNetwork), switches: make(map[string][]dhcpScope)}
+}
+
+// addNetwork records the DHCP hostnames in a network's clients list. Rows of
+// networks that were never added are left alone.
+func (l *dhcpLeases) addNetwork(network meraki.Network, clients []meraki.NetworkClient) {
+	nw := &leaseNetwork{id: network.ID, clients: make(map[string]string)}
+	for _, c := range clients {
+		if norm, err := macaddr.NormalizeExactMac(c.MAC); err == nil && c.DhcpHostname != "" {
+			nw.clients[norm] = c.DhcpHostname
+		}
+	}
+	l.networks[network.Name] = nw
+}
+
+// fill sets row's DHCPHostname and LeaseExpiry, and its Hostname when that is
+// still empty. A reserved client's lease is "reserved"; otherwise it expires
+// at the latest one lease time after the client was last seen, which is only
+// known for scopes the appliance or switch serves itself. Uplink rows and
+// device management MACs are left alone.
+func (l *dhcpLeases) fill(row *output.ResultRow) {
+	nw := l.networks[row.NetworkName]
+	if nw == nil || row.IsUplink || row.Source == output.SourceDevice {
+		return
+	}
+	normMAC, err := macaddr.NormalizeExactMac(row.MAC)
+	if err != nil {
+		return
+	}
+	hostname, from := nw.clients[normMAC], srcNetworkClients
+	scope := l.scope(nw, row, normMAC)
+	if scope != nil {
+		if name, ok := scope.reserved[normMAC]; ok {
+			row.LeaseExpiry = "reserved"
+			if hostname == "" {
+				hostname, from = name, srcDHCPScope
+			}
+		} else if seen, ok := output.ParseLastSeen(row.LastSeen); ok && scope.serves && scope.leaseTime > 0 {
+			row.LeaseExpiry = seen.Add(scope.leaseTime).UTC().Format(time.RFC3339)
+		}
+	}
+	row.DHCPHostname = hostname
+	if row.Hostname == "" && hostname != "" {
+		row.Hostname = hostname
+		if row.Explain != nil {
+			row.Explain = setFieldSource(row.Explain, output.FieldSource{Field: "Hostname", Source: from, Detail: "DHCP hostname"})
+		}
+	}
+	if row.Explain != nil && row.LeaseExpiry != "" {
+		row.Explain = setFieldSource(row.Explain, output.FieldSource{Field: "LeaseExpiry", Source: srcDHCPScope, Detail: scope.detail})
+	}
+}
+
+// scope returns the DHCP scope that serves row's IP or, for a row without an
+// IP, the one holding a reservation for its MAC; nil when there is none.
+func (l *dhcpLeases) scope(nw *leaseNetwork, row *output.ResultRow, normMAC string) *dhcpScope {
+	if !nw.loaded {
+		nw.loaded = true
+		vlans, _ := l.client.GetApplianceVLANs(l.ctx, nw.id)
+		for _, v := range vlans {
+			if sc, ok := applianceScope(v, nw.id); ok {
+				nw.scopes = append(nw.scopes, sc)
+			}
+		}
+	}
+	scopes := nw.scopes
+	if row.SwitchSerial != "" && row.Port != "wireless" {
+		scopes = append(scopes[:len(scopes):len(scopes)], l.switchScopes(row.SwitchSerial)...)
+	}
+	return findScope(scopes, row.IP, normMAC)
+}
+
+// switchScopes returns the DHCP scopes of a switch's routed interfaces,
+// reading them on first use. A switch that does not route has none.
+func (l *dhcpLeases) switchScopes(serial string) []dhcpScope {
+	if scopes, ok := l.switches[serial]; ok {
+		return scopes
+	}
+	var scopes []dhcpScope
+	ifaces, _ := l.client.GetSwitchRoutingInterfaces(l.ctx, serial)
+	for _, iface := range ifaces {
+		_, subnet, err := net.ParseCIDR(iface.Subnet)
+		if err != nil {
+			continue
+		}
+		dhcp, err := l.client.GetSwitchInterfaceDHCP(l.ctx, serial, iface.InterfaceID)
+		if err != nil || dhcp.DhcpMode == "dhcpDisabled" {
+			continue
+		}
+		sc := dhcpScope{
+			subnet:   subnet,
+			serves:   dhcp.DhcpMode == "dhcpServer",
+			reserved: make(map[string]string),
+			detail:   "GET /devices/" + serial + "/switch/routing/interfaces/" + iface.InterfaceID + "/dhcp",
+		}
+		sc.leaseTime, _ = meraki.ParseLeaseTime(dhcp.DhcpLeaseTime)
+		for _, fa := range dhcp.FixedIPAssignments {
+			if norm, err := macaddr.NormalizeExactMac(fa.MAC); err == nil {
+				sc.reserved[norm] = fa.Name
+			}
+		}
+		scopes = append(scopes, sc)
+	}
+	l.switches[serial] = scopes
+	return scopes
+}
+
+// applianceScope converts an appliance VLAN to a DHCP scope. ok is false for
+// VLANs without a valid subnet or that do not answer DHCP.
+func applianceScope(v meraki.ApplianceVLAN, networkID string) (dhcpScope, bool) {
+	_, subnet, err := net.ParseCIDR(v.Subnet)
+	if err != nil || strings.HasPrefix(v.DhcpHandling, "Do not") {
+		return dhcpScope{}, false
+	}
+	sc := dhcpScope{
+		subnet:   subnet,
+		serves:   v.DhcpHandling == "" || strings.HasPrefix(v.DhcpHandling, "Run"),
+		reserved: make(map[string]string),
+		detail:   "GET /networks/" + networkID + "/appliance/vlans",
+	}
+	sc.leaseTime, _ = meraki.ParseLeaseTime(v.DhcpLeaseTime)
+	for mac, fa := range v.FixedIPAssignments {
+		if norm, err := macaddr.NormalizeExactMac(mac); err == nil {
+			sc.reserved[norm] = fa.Name
+		}
+	}
+	return sc, true
+}
+
+// findScope returns the scope whose subnet holds ip or, when ip is empty or in
+// none of them, the first that reserves normMAC.
+func findScope(scopes []dhcpScope, ip, normMAC string) *dhcpScope {
+	if addr := net.ParseIP(ip); addr != nil {
+		for i := range scopes {
+			if scopes[i].subnet.Contains(addr) {
+				return &scopes[i]
+			}
+		}
+	}
+	for i := range scopes {
+		if _, ok := scopes[i].reserved[normMAC]; ok {
+			return &scopes[i]
+		}
+	}
+	return nil
+}
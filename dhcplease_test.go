@@ -0,0 +1,95 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestDHCPLeasesFill(t *testing.T) {
+	calls := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.URL.Path]++
+		switch r.URL.Path {
+		case "/networks/N1/appliance/vlans":
+			_, _ = w.Write([]byte(`[
+				{"id":10,"subnet":"10.0.10.0/24","dhcpHandling":"Run a DHCP server","dhcpLeaseTime":"1 day",
+				 "fixedIpAssignments":{"aa:bb:cc:00:00:02":{"ip":"10.0.10.20","name":"printer-2"}}},
+				{"id":20,"subnet":"10.0.20.0/24","dhcpHandling":"Relay DHCP to another server","dhcpRelayServerIps":["10.9.9.9"]},
+				{"id":30,"subnet":"10.0.30.0/24","dhcpHandling":"Do not respond to DHCP requests"}]`))
+		case "/devices/Q2SW/switch/routing/interfaces":
+			_, _ = w.Write([]byte(`[{"interfaceId":"I1","subnet":"10.0.40.0/24","vlanId":40}]`))
+		case "/devices/Q2SW/switch/routing/interfaces/I1/dhcp":
+			_, _ = w.Write([]byte(`{"dhcpMode":"dhcpServer","dhcpLeaseTime":"4 hours",
+				"fixedIpAssignments":[{"name":"door-1","mac":"aa:bb:cc:00:00:09","ip":"10.0.40.9"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	l := newDHCPLeases(context.Background(), meraki.NewClient("key", srv.URL, 1))
+	l.addNetwork(meraki.Network{ID: "N1", Name: "HQ"}, []meraki.NetworkClient{
+		{MAC: "aa:bb:cc:00:00:01", DhcpHostname: "laptop-1"},
+		{MAC: "aa:bb:cc:00:00:02"},
+	})
+	const seen = "2026-03-02T14:00:00Z"
+	tests := []struct {
+		name                      string
+		row                       output.ResultRow
+		dhcpHost, lease, hostname string
+		explained                 bool
+	}{
+		{"served lease", output.ResultRow{NetworkName: "HQ", SwitchSerial: "Q2SW", Port: "3", MAC: "aa:bb:cc:00:00:01", IP: "10.0.10.5", LastSeen: seen},
+			"laptop-1", "2026-03-03T14:00:00Z", "laptop-1", true},
+		{"reservation", output.ResultRow{NetworkName: "HQ", SwitchSerial: "Q2SW", Port: "4", MAC: "aa:bb:cc:00:00:02", Hostname: "prn2.example.com", LastSeen: seen},
+			"printer-2", "reserved", "prn2.example.com", true},
+		{"relayed subnet", output.ResultRow{NetworkName: "HQ", SwitchSerial: "Q2SW", Port: "5", MAC: "aa:bb:cc:00:00:03", IP: "10.0.20.5", LastSeen: seen},
+			"", "", "", false},
+		{"switch interface", output.ResultRow{NetworkName: "HQ", SwitchSerial: "Q2SW", Port: "6", MAC: "aa:bb:cc:00:00:04", IP: "10.0.40.7", LastSeen: seen},
+			"", "2026-03-02T18:00:00Z", "", true},
+		{"switch reservation", output.ResultRow{NetworkName: "HQ", SwitchSerial: "Q2SW", Port: "7", MAC: "aa:bb:cc:00:00:09", IP: "10.0.40.9"},
+			"door-1", "reserved", "door-1", true},
+		{"uplink", output.ResultRow{NetworkName: "HQ", SwitchSerial: "Q2SW", Port: "49", MAC: "aa:bb:cc:00:00:01", IP: "10.0.10.5", IsUplink: true},
+			"", "", "", false},
+		{"unknown network", output.ResultRow{NetworkName: "Branch", MAC: "aa:bb:cc:00:00:01", IP: "10.0.10.5"},
+			"", "", "", false},
+	}
+	for _, tt := range tests {
+		row := tt.row
+		row.Explain = []output.FieldSource{}
+		l.fill(&row)
+		if row.DHCPHostname != tt.dhcpHost || row.LeaseExpiry != tt.lease || row.Hostname != tt.hostname {
+			t.Errorf("%s: fill() = dhcp %q lease %q hostname %q, want %q %q %q", tt.name, row.DHCPHostname, row.LeaseExpiry, row.Hostname, tt.dhcpHost, tt.lease, tt.hostname)
+		}
+		var hasLease bool
+		for _, fs := range row.Explain {
+			hasLease = hasLease || fs.Field == "LeaseExpiry"
+		}
+		if hasLease != tt.explained {
+			t.Errorf("%s: LeaseExpiry explained = %v, want %v", tt.name, hasLease, tt.explained)
+		}
+	}
+	if calls["/networks/N1/appliance/vlans"] != 1 || calls["/devices/Q2SW/switch/routing/interfaces"] != 1 {
+		t.Errorf("calls = %v, want VLANs and interfaces read once", calls)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// Provenance source names shown by --explain and the web detail view.
+const (
+	srcLiveMacTable   = "live MAC table"
+	srcLiveArpTable   = "live ARP table"
+	srcNetworkClients = "network clients"
+	srcDeviceClients  = "device clients"
+	srcClientDetail   = "client detail"
+	srcPortConfig     = "switch port config"
+	srcPortStatuses   = "LLDP/CDP port statuses"
+	srcLLDPNeighbors  = "LLDP/CDP neighbors"
+	srcIPLookup       = "IP-to-MAC resolution"
+	srcHostOverride   = "HOST_OVERRIDES"
+	srcReverseDNS     = "reverse DNS"
+	srcDeviceUplinks  = "device uplink addresses"
+	srcSignalQuality  = "wireless signal quality"
+	srcDHCPScope      = "DHCP scope"
+	srcDeviceStatuses = "device statuses"
+	srcDeviceList     = "device list"
+)
+
+// macTableDetail describes a live MAC table job for --explain.
+func macTableDetail(jobID string, at time.Time) string {
+	if jobID == "" {
+		return "at " + at.Format("15:04:05")
+	}
+	return fmt.Sprintf("job %s at %s", jobID, at.Format("15:04:05"))
+}
+
+// explainPortInfo attributes VLAN and port mode. enrichPortInfoWithMembers
+// starts from the values the port source reported (tableVLAN, tableMode) and
+// overrides them from the switch port configuration, so a value that differs
+// from the source's came from the port config.
+func explainPortInfo(serial, port string, tableVLAN int, tableMode string, vlan int, mode string, from string) []output.FieldSource {
+	cfgDetail := fmt.Sprintf("GET /devices/%s/switch/ports (port %s)", serial, port)
+	var out []output.FieldSource
+	if vlan > 0 {
+		fs := output.FieldSource{Field: "VLAN", Source: from}
+		if vlan != tableVLAN {
+			fs = output.FieldSource{Field: "VLAN", Source: srcPortConfig, Detail: cfgDetail}
+		}
+		out = append(out, fs)
+	}
+	if mode != "" {
+		fs := output.FieldSource{Field: "PortMode", Source: from}
+		if mode != tableMode {
+			fs = output.FieldSource{Field: "PortMode", Source: srcPortConfig, Detail: cfgDetail}
+		}
+		out = append(out, fs)
+	}
+	return out
+}
+
+// explainRow assembles a row's provenance in display order: port, VLAN and
+// mode, IP and hostname, last seen, then uplink status.
+func explainRow(row output.ResultRow, port output.FieldSource, portInfo, host []output.FieldSource, lastSeenFrom string) []output.FieldSource {
+	port.Field = "Port"
+	out := []output.FieldSource{port}
+	out = append(out, portInfo...)
+	out = append(out, host...)
+	if row.LastSeen != "" {
+		out = append(out, output.FieldSource{Field: "LastSeen", Source: lastSeenFrom})
+	}
+	if row.IsUplink {
+		out = append(out, output.FieldSource{Field: "Uplink", Source: srcPortStatuses, Detail: fmt.Sprintf("GET /devices/%s/switch/ports/statuses", row.SwitchSerial)})
+	}
+	return out
+}
+
+// setFieldSource replaces the entry for fs.Field in sources, or appends fs.
+func setFieldSource(sources []output.FieldSource, fs output.FieldSource) []output.FieldSource {
+	for i := range sources {
+		if sources[i].Field == fs.Field {
+			sources[i] = fs
+			return sources
+		}
+	}
+	return append(sources, fs)
+}
+
+// hostSources attributes IP and hostname: ipFrom/hnFrom name the source of
+// each (empty when the value is empty).
+func hostSources(ip, ipFrom, ipDetail, hn, hnFrom string) []output.FieldSource {
+	var out []output.FieldSource
+	if ip != "" {
+		out = append(out, output.FieldSource{Field: "IP", Source: ipFrom, Detail: ipDetail})
+	}
+	if hn != "" {
+		out = append(out, output.FieldSource{Field: "Hostname", Source: hnFrom})
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestExplainPortInfo(t *testing.T) {
+	cfg := "GET /devices/Q2/switch/ports (port 5)"
+	tests := []struct {
+		name      string
+		tableVLAN int
+		tableMode string
+		vlan      int
+		mode      string
+		want      []output.FieldSource
+	}{
+		{"from source", 10, "access", 10, "access", []output.FieldSource{
+			{Field: "VLAN", Source: srcLiveMacTable},
+			{Field: "PortMode", Source: srcLiveMacTable},
+		}},
+		{"overridden by port config", 1, "", 20, "trunk", []output.FieldSource{
+			{Field: "VLAN", Source: srcPortConfig, Detail: cfg},
+			{Field: "PortMode", Source: srcPortConfig, Detail: cfg},
+		}},
+		{"unknown", 0, "", 0, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := explainPortInfo("Q2", "5", tt.tableVLAN, tt.tableMode, tt.vlan, tt.mode, srcLiveMacTable)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("explainPortInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplainRow(t *testing.T) {
+	row := output.ResultRow{SwitchSerial: "Q2", Port: "49", LastSeen: "2025-01-01T00:00:00Z", IsUplink: true}
+	port := output.FieldSource{Source: srcNetworkClients}
+	host := hostSources("10.0.0.5", srcNetworkClients, "", "cam-1", srcReverseDNS)
+	got := explainRow(row, port, nil, host, srcNetworkClients)
+	want := []output.FieldSource{
+		{Field: "Port", Source: srcNetworkClients},
+		{Field: "IP", Source: srcNetworkClients},
+		{Field: "Hostname", Source: srcReverseDNS},
+		{Field: "LastSeen", Source: srcNetworkClients},
+		{Field: "Uplink", Source: srcPortStatuses, Detail: "GET /devices/Q2/switch/ports/statuses"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("explainRow() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSetFieldSource(t *testing.T) {
+	sources := []output.FieldSource{{Field: "Port", Source: srcLiveMacTable}, {Field: "LastSeen", Source: srcLiveMacTable}}
+	sources = setFieldSource(sources, output.FieldSource{Field: "LastSeen", Source: srcDeviceClients})
+	sources = setFieldSource(sources, output.FieldSource{Field: "IP", Source: srcNetworkClients})
+	want := []output.FieldSource{
+		{Field: "Port", Source: srcLiveMacTable},
+		{Field: "LastSeen", Source: srcDeviceClients},
+		{Field: "IP", Source: srcNetworkClients},
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("setFieldSource() = %+v, want %+v", sources, want)
+	}
+}
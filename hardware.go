@@ -0,0 +1,52 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// deviceHardware fills in the model and firmware of the switch or device each
+// result was found on, so a report doubles as an audit of where old firmware
+// still runs. It reads the device lists the search fetches anyway.
+type deviceHardware struct {
+	devices map[string]meraki.Device // serial → device
+}
+
+func newDeviceHardware() *deviceHardware {
+	return &deviceHardware{devices: make(map[string]meraki.Device)}
+}
+
+// add records devices, typically those of one network.
+func (h *deviceHardware) add(devices []meraki.Device) {
+	for _, dev := range devices {
+		h.devices[dev.Serial] = dev
+	}
+}
+
+// fill sets row's model and firmware. Rows on a device that was not added are
+// left alone.
+func (h *deviceHardware) fill(row *output.ResultRow) {
+	dev, ok := h.devices[row.SwitchSerial]
+	if !ok {
+		return
+	}
+	row.Model, row.Firmware = dev.Model, dev.Firmware
+	if row.Explain != nil && row.Firmware != "" {
+		row.Explain = setFieldSource(row.Explain, output.FieldSource{Field: "Firmware", Source: srcDeviceList})
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestDeviceHardwareFill(t *testing.T) {
+	h := newDeviceHardware()
+	h.add([]meraki.Device{
+		{Serial: "Q2AA-0001", Model: "MS250-48FP", Firmware: "switch-16-7"},
+		{Serial: "Q2AA-0002", Model: "MX68"},
+	})
+
+	row := output.ResultRow{SwitchSerial: "Q2AA-0001", Port: "3", Explain: []output.FieldSource{}}
+	h.fill(&row)
+	if row.Model != "MS250-48FP" || row.Firmware != "switch-16-7" {
+		t.Errorf("fill() = %q %q, want MS250-48FP switch-16-7", row.Model, row.Firmware)
+	}
+	if len(row.Explain) != 1 || row.Explain[0].Field != "Firmware" || row.Explain[0].Source != srcDeviceList {
+		t.Errorf("Explain = %+v, want the device list source", row.Explain)
+	}
+	mx := output.ResultRow{SwitchSerial: "Q2AA-0002", Explain: []output.FieldSource{}}
+	if h.fill(&mx); mx.Model != "MX68" || mx.Firmware != "" || len(mx.Explain) != 0 {
+		t.Errorf("fill(MX without firmware) = %+v", mx)
+	}
+	unknown := output.ResultRow{SwitchSerial: "Q2AA-0009"}
+	if h.fill(&unknown); unknown.Model != "" {
+		t.Errorf("unlisted device got model %q", unknown.Model)
+	}
+}
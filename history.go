@@ -0,0 +1,359 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/history"
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// firstSeenNote is appended to the Note of results whose MAC is new to the network.
+const firstSeenNote = "first seen in network"
+
+// resolveHistoryFile returns the history location for setting (a file path or
+// a sqlite://, bolt:// or postgres:// URL), or "" when recording is disabled
+// with "off". The default is described at defaultHistoryLocation.
+func resolveHistoryFile(setting string) string {
+	switch {
+	case strings.EqualFold(setting, "off"):
+		return ""
+	case setting != "":
+		return setting
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return defaultHistoryLocation(home, func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	})
+}
+
+// defaultHistoryLocation is the SQLite database ~/.find-mac-history.db. A JSON
+// history written by an older version is kept in use until it is removed, so
+// upgrading never starts the first-seen history over.
+func defaultHistoryLocation(home string, exists func(string) bool) string {
+	legacy := filepath.Join(home, ".find-mac-history.json")
+	db := filepath.Join(home, ".find-mac-history.db")
+	if exists(legacy) && !exists(db) {
+		return legacy
+	}
+	return "sqlite://" + db
+}
+
+// openHistory opens the history store, or returns nil (recording disabled) when
+// location is empty or the history cannot be read.
+func openHistory(location string, log *logger.Logger) *history.Store {
+	if location == "" {
+		return nil
+	}
+	store, err := history.Open(location)
+	if err != nil {
+		log.Warnf("History disabled: %v", err)
+		return nil
+	}
+	return store
+}
+
+// observeResult records row in store and sets row.FirstSeen when the MAC is new
+// to the network. A nil store is a no-op.
+func observeResult(store *history.Store, row *output.ResultRow, at time.Time) {
+	if store == nil || row.IsUplink {
+		return
+	}
+	row.FirstSeen = store.Observe(row.NetworkName, row.MAC, at, row.SwitchName, row.Port)
+}
+
+// webHistoryMu serialises web searches' use of the history, which each search
+// loads, updates and saves as a whole.
+var webHistoryMu sync.Mutex
+
+// recordWebSightings records the rows of a web search in the history at
+// location and returns, per row, a note on where the MAC was last seen when
+// that is none of the places this search found it. Nothing is recorded and
+// every note is empty when location is empty or the history cannot be opened.
+func recordWebSightings(location string, rows []output.ResultRow, at time.Time, log *logger.Logger) []string {
+	notes := make([]string, len(rows))
+	if location == "" {
+		return notes
+	}
+	webHistoryMu.Lock()
+	defer webHistoryMu.Unlock()
+	store := openHistory(location, log)
+	if store == nil {
+		return notes
+	}
+	defer func() { _ = store.Close() }()
+
+	// Look up every MAC before recording anything, so a MAC found on
+	// several ports is compared against the previous search, not this one.
+	prev := make(map[string]history.Sighting)
+	found := make(map[string]bool)
+	for _, row := range rows {
+		key := row.NetworkName + "|" + strings.ToLower(row.MAC)
+		if sg, ok := store.Lookup(row.NetworkName, row.MAC); ok && sg.LastSeen.Before(at) {
+			prev[key] = sg
+		}
+		found[key+"|"+row.SwitchName+"|"+row.Port] = true
+	}
+	for i := range rows {
+		key := rows[i].NetworkName + "|" + strings.ToLower(rows[i].MAC)
+		if sg, ok := prev[key]; ok && !rows[i].IsUplink && sg.Switch != "" && !found[key+"|"+sg.Switch+"|"+sg.Port] {
+			notes[i] = movedNote(sg, at)
+		}
+		observeResult(store, &rows[i], at)
+	}
+	if err := store.Save(); err != nil {
+		log.Warnf("Saving history: %v", err)
+	}
+	return notes
+}
+
+// movedNote describes the earlier sighting sg of a MAC that has since moved,
+// e.g. "moved since last seen here: was SwitchB/port 14 on May 3". The year
+// is added when the sighting is from an earlier year than now.
+func movedNote(sg history.Sighting, now time.Time) string {
+	where := sg.Switch
+	switch sg.Port {
+	case "":
+	case "wireless":
+		where += " (wireless)"
+	default:
+		where += "/port " + sg.Port
+	}
+	last := sg.LastSeen.In(now.Location())
+	layout := "Jan 2"
+	if last.Year() != now.Year() {
+		layout = "Jan 2, 2006"
+	}
+	return fmt.Sprintf("moved since last seen here: was %s on %s", where, last.Format(layout))
+}
+
+// joinNotes combines two result annotations, skipping empty ones.
+func joinNotes(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + "; " + b
+}
+
+// rowNote returns the first-seen part of a result's note.
+func rowNote(row output.ResultRow) string {
+	if row.FirstSeen {
+		return firstSeenNote
+	}
+	return ""
+}
+
+// runReportCommand implements "report <name> [flags]" and returns the exit code.
+// The only report so far is new-devices, which lists MACs first seen within
+// --since (default 7d) according to the history file.
+func runReportCommand(w io.Writer, args []string, historySetting string) int {
+	if len(args) == 0 || args[0] != "new-devices" {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: Find-Meraki-Ports-With-MAC report new-devices [--since 7d] [--network NAME]")
+		return 2
+	}
+	fs := flag.NewFlagSet("report new-devices", flag.ContinueOnError)
+	sinceFlag := fs.String("since", "7d", "Look-back window, e.g. 7d, 24h")
+	networkFlag := fs.String("network", "", "Only list devices in this network")
+	historyFlag := fs.String("history-file", historySetting, "History file path or database URL")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	window, err := history.ParseSince(*sinceFlag)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: --since: %v\n", err)
+		return 2
+	}
+	path := resolveHistoryFile(*historyFlag)
+	if path == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "ERROR: history recording is disabled (HISTORY_FILE=off)")
+		return 1
+	}
+	store, err := history.Open(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	defer func() { _ = store.Close() }()
+	writeNewDevices(w, store.FirstSeenSince(time.Now().Add(-window)), *networkFlag)
+	return 0
+}
+
+// historyPruneInterval is how often the web server prunes the history.
+const historyPruneInterval = 24 * time.Hour
+
+// historyRetention parses a HISTORY_RETENTION setting already validated by
+// config.Load; "" and "0" keep sightings forever.
+func historyRetention(setting string) time.Duration {
+	d, _ := history.ParseSince(setting)
+	return d
+}
+
+// pruneHistory drops the sightings last seen more than retention before now.
+// Searches call it before saving, so the history stays bounded even without
+// the web server's background job. A zero retention keeps everything.
+func pruneHistory(store *history.Store, retention time.Duration, now time.Time, log *logger.Logger) {
+	if store == nil || retention <= 0 {
+		return
+	}
+	n, err := store.Prune(now.Add(-retention))
+	if err != nil {
+		log.Warnf("Pruning history: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Debugf("Pruned %d history sighting(s) older than %s", n, retention)
+	}
+}
+
+// startHistoryPruner prunes the history at location now and then every
+// historyPruneInterval in the background, for long-running web servers that
+// share a history database with scheduled searches.
+func startHistoryPruner(location string, retention time.Duration, log *logger.Logger) {
+	if location == "" || retention <= 0 {
+		return
+	}
+	prune := func() {
+		store, err := history.Open(location)
+		if err != nil {
+			log.Warnf("Pruning history: %v", err)
+			return
+		}
+		pruneHistory(store, retention, time.Now(), log)
+		_ = store.Close()
+	}
+	go func() {
+		for {
+			prune()
+			time.Sleep(historyPruneInterval)
+		}
+	}()
+}
+
+// runHistoryCommand implements "history <action> [flags]" and returns the exit
+// code. migrate upgrades a SQL history database to this build's schema; with
+// --dry-run it only lists the pending steps. Opening a database upgrades it
+// anyway, so migrate is for checking before a daemon upgrade. prune deletes
+// sightings older than --retention (default HISTORY_RETENTION).
+func runHistoryCommand(w io.Writer, args []string, getenv func(string) string) int {
+	if len(args) == 0 || (args[0] != "migrate" && args[0] != "prune") {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: Find-Meraki-Ports-With-MAC history migrate [--dry-run] [--history-file URL]")
+		_, _ = fmt.Fprintln(os.Stderr, "       Find-Meraki-Ports-With-MAC history prune [--retention 180d] [--dry-run] [--history-file URL]")
+		return 2
+	}
+	fs := flag.NewFlagSet("history "+args[0], flag.ContinueOnError)
+	dryRunFlag := fs.Bool("dry-run", false, "Report what would change without changing anything")
+	historyFlag := fs.String("history-file", getenv("HISTORY_FILE"), "History file path or database URL")
+	retentionFlag := fs.String("retention", getenv("HISTORY_RETENTION"), "prune: delete sightings last seen longer ago than this, e.g. 180d")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	path := resolveHistoryFile(*historyFlag)
+	if path == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "ERROR: history recording is disabled (HISTORY_FILE=off)")
+		return 1
+	}
+	if args[0] == "prune" {
+		return runHistoryPrune(w, path, *retentionFlag, *dryRunFlag)
+	}
+	plan, err := history.Migrate(path, *dryRunFlag)
+	if errors.Is(err, history.ErrNoSchema) {
+		_, _ = fmt.Fprintln(w, "History is a JSON or BoltDB file; nothing to migrate.")
+		return 0
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	writeMigrationPlan(w, plan, *dryRunFlag)
+	return 0
+}
+
+// runHistoryPrune implements "history prune".
+func runHistoryPrune(w io.Writer, path, retentionSetting string, dryRun bool) int {
+	retention, err := history.ParseSince(retentionSetting)
+	if err != nil || retention <= 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "ERROR: --retention (or HISTORY_RETENTION) must be a duration such as 180d")
+		return 2
+	}
+	store, err := history.Open(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	defer func() { _ = store.Close() }()
+	cutoff := time.Now().Add(-retention)
+	if dryRun {
+		_, _ = fmt.Fprintf(w, "Dry run: %d sighting(s) last seen before %s would be pruned.\n", store.Stale(cutoff), cutoff.Local().Format("2006-01-02 15:04"))
+		return 0
+	}
+	n, err := store.Prune(cutoff)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintf(w, "Pruned %d sighting(s) last seen before %s.\n", n, cutoff.Local().Format("2006-01-02 15:04"))
+	return 0
+}
+
+// writeMigrationPlan reports the schema version and the steps that ran (or
+// would run with dryRun).
+func writeMigrationPlan(w io.Writer, plan history.MigrationPlan, dryRun bool) {
+	_, _ = fmt.Fprintf(w, "History schema: v%d (latest v%d)\n", plan.Current, plan.Latest)
+	for _, m := range plan.Pending {
+		_, _ = fmt.Fprintf(w, "  v%-3d %s\n", m.Version, m.Description)
+	}
+	switch {
+	case len(plan.Pending) == 0:
+		_, _ = fmt.Fprintln(w, "Up to date.")
+	case dryRun:
+		_, _ = fmt.Fprintf(w, "Dry run: %d migration(s) pending; nothing changed.\n", len(plan.Pending))
+	default:
+		_, _ = fmt.Fprintf(w, "Applied %d migration(s); schema is now v%d.\n", len(plan.Pending), plan.Latest)
+	}
+}
+
+// writeNewDevices writes the newcomer feed as an aligned table, optionally
+// limited to one network (case-insensitive).
+func writeNewDevices(w io.Writer, sightings []history.Sighting, network string) {
+	_, _ = fmt.Fprintf(w, "%-20s  %-20s  %-17s  %-20s  %s\n", "FirstSeen", "Network", "MAC", "Switch", "Port")
+	n := 0
+	for _, sg := range sightings {
+		if network != "" && !strings.EqualFold(sg.Network, network) {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%-20s  %-20s  %-17s  %-20s  %s\n",
+			sg.FirstSeen.Local().Format("2006-01-02 15:04:05"), sg.Network, sg.MAC, sg.Switch, sg.Port)
+		n++
+	}
+	_, _ = fmt.Fprintf(w, "%d new device(s)\n", n)
+}
@@ -0,0 +1,240 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/history"
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestObserveResult(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	store, err := history.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	row := output.ResultRow{NetworkName: "HQ", SwitchName: "sw1", Port: "3", MAC: "00:11:22:33:44:55"}
+	observeResult(store, &row, now)
+	if !row.FirstSeen || rowNote(row) != firstSeenNote {
+		t.Errorf("first observation: FirstSeen=%v note=%q", row.FirstSeen, rowNote(row))
+	}
+	observeResult(store, &row, now)
+	if row.FirstSeen {
+		t.Error("second observation should not be first seen")
+	}
+	uplink := output.ResultRow{NetworkName: "HQ", MAC: "00:11:22:33:44:66", IsUplink: true}
+	observeResult(store, &uplink, now)
+	if uplink.FirstSeen {
+		t.Error("uplink rows should not be recorded")
+	}
+	observeResult(nil, &row, now) // nil store is a no-op
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if code := runReportCommand(&buf, []string{"new-devices", "--since", "1d", "--history-file", path}, ""); code != 0 {
+		t.Fatalf("runReportCommand() = %d", code)
+	}
+	if !strings.Contains(buf.String(), "00:11:22:33:44:55") || !strings.Contains(buf.String(), "1 new device(s)") {
+		t.Errorf("report output:\n%s", buf.String())
+	}
+	buf.Reset()
+	runReportCommand(&buf, []string{"new-devices", "--network", "Branch", "--history-file", path}, "")
+	if !strings.Contains(buf.String(), "0 new device(s)") {
+		t.Errorf("network filter output:\n%s", buf.String())
+	}
+	if code := runReportCommand(&buf, []string{"unknown"}, ""); code != 2 {
+		t.Errorf("unknown report exit code = %d, want 2", code)
+	}
+}
+
+func TestJoinNotes(t *testing.T) {
+	tests := []struct{ a, b, want string }{
+		{"", "", ""},
+		{"x", "", "x"},
+		{"", "y", "y"},
+		{"x", "y", "x; y"},
+	}
+	for _, tt := range tests {
+		if got := joinNotes(tt.a, tt.b); got != tt.want {
+			t.Errorf("joinNotes(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRecordWebSightings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	log := logger.NewWriter(io.Discard, logger.LevelError)
+	may3 := time.Date(2026, 5, 3, 10, 0, 0, 0, time.Local)
+	first := []output.ResultRow{
+		{NetworkName: "HQ", SwitchName: "SwitchB", Port: "14", MAC: "00:11:22:33:44:55"},
+		{NetworkName: "HQ", SwitchName: "SwitchA", Port: "2", MAC: "00:11:22:33:44:66"},
+	}
+	if notes := recordWebSightings(path, first, may3, log); notes[0] != "" || notes[1] != "" {
+		t.Errorf("first search notes = %q, want none", notes)
+	}
+
+	later := may3.Add(48 * time.Hour)
+	second := []output.ResultRow{
+		{NetworkName: "HQ", SwitchName: "SwitchC", Port: "3", MAC: "00:11:22:33:44:55"},
+		{NetworkName: "HQ", SwitchName: "SwitchA", Port: "2", MAC: "00:11:22:33:44:66"},
+		{NetworkName: "HQ", SwitchName: "SwitchC", Port: "3", MAC: "00:11:22:33:44:66"},
+		{NetworkName: "HQ", SwitchName: "Core", Port: "49", MAC: "00:11:22:33:44:55", IsUplink: true},
+	}
+	notes := recordWebSightings(path, second, later, log)
+	want := []string{"moved since last seen here: was SwitchB/port 14 on May 3", "", "", ""}
+	for i := range want {
+		if notes[i] != want[i] {
+			t.Errorf("notes[%d] = %q, want %q", i, notes[i], want[i])
+		}
+	}
+	if notes := recordWebSightings(path, second[:1], later.Add(time.Hour), log); notes[0] != "" {
+		t.Errorf("unmoved MAC note = %q, want none", notes[0])
+	}
+	if notes := recordWebSightings("", second, later, log); len(notes) != len(second) || notes[0] != "" {
+		t.Errorf("history off: notes = %q", notes)
+	}
+}
+
+func TestMovedNote(t *testing.T) {
+	now := time.Date(2026, 5, 10, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		sg   history.Sighting
+		want string
+	}{
+		{history.Sighting{Switch: "sw1", Port: "7", LastSeen: now.AddDate(0, 0, -3)}, "moved since last seen here: was sw1/port 7 on May 7"},
+		{history.Sighting{Switch: "ap-lobby", Port: "wireless", LastSeen: now.AddDate(0, 0, -1)}, "moved since last seen here: was ap-lobby (wireless) on May 9"},
+		{history.Sighting{Switch: "mx", LastSeen: time.Date(2025, 12, 30, 9, 0, 0, 0, time.UTC)}, "moved since last seen here: was mx on Dec 30, 2025"},
+	}
+	for _, tt := range tests {
+		if got := movedNote(tt.sg, now); got != tt.want {
+			t.Errorf("movedNote(%+v) = %q, want %q", tt.sg, got, tt.want)
+		}
+	}
+}
+
+func TestResolveHistoryFile(t *testing.T) {
+	if got := resolveHistoryFile("off"); got != "" {
+		t.Errorf("resolveHistoryFile(off) = %q, want empty", got)
+	}
+	if got := resolveHistoryFile("/tmp/h.json"); got != "/tmp/h.json" {
+		t.Errorf("resolveHistoryFile(path) = %q", got)
+	}
+	if got := resolveHistoryFile(""); !strings.HasSuffix(got, ".find-mac-history.json") && !strings.HasSuffix(got, ".find-mac-history.db") {
+		t.Errorf("resolveHistoryFile(\"\") = %q", got)
+	}
+}
+
+func TestDefaultHistoryLocation(t *testing.T) {
+	home := filepath.Join("home", "ops")
+	legacy, db := filepath.Join(home, ".find-mac-history.json"), filepath.Join(home, ".find-mac-history.db")
+	tests := []struct {
+		name   string
+		exists []string
+		want   string
+	}{
+		{name: "new install", want: "sqlite://" + db},
+		{name: "upgrade keeps the JSON history", exists: []string{legacy}, want: legacy},
+		{name: "database already created", exists: []string{legacy, db}, want: "sqlite://" + db},
+	}
+	for _, tt := range tests {
+		exists := func(path string) bool { return slices.Contains(tt.exists, path) }
+		if got := defaultHistoryLocation(home, exists); got != tt.want {
+			t.Errorf("%s: defaultHistoryLocation() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunHistoryCommand(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	if code := runHistoryCommand(&out, []string{"migrate", "--dry-run"}, func(k string) string { return map[string]string{"HISTORY_FILE": filepath.Join(dir, "h.json")}[k] }); code != 0 || !strings.Contains(out.String(), "nothing to migrate") {
+		t.Errorf("migrate on a JSON file = %d, %q", code, out.String())
+	}
+	if code := runHistoryCommand(&out, nil, func(string) string { return "" }); code != 2 {
+		t.Errorf("no action: exit %d, want 2", code)
+	}
+	if code := runHistoryCommand(&out, []string{"migrate"}, func(string) string { return "off" }); code != 1 {
+		t.Errorf("history off: exit %d, want 1", code)
+	}
+}
+
+func TestHistoryPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "h.json")
+	store, err := history.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	store.Observe("HQ", "aa:aa:aa:aa:aa:aa", now.Add(-200*24*time.Hour), "sw1", "1")
+	store.Observe("HQ", "bb:bb:bb:bb:bb:bb", now.Add(-time.Hour), "sw1", "2")
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	env := func(k string) string {
+		return map[string]string{"HISTORY_FILE": path, "HISTORY_RETENTION": "180d"}[k]
+	}
+
+	var out bytes.Buffer
+	if code := runHistoryCommand(&out, []string{"prune", "--dry-run"}, env); code != 0 || !strings.Contains(out.String(), "Dry run: 1 sighting(s)") {
+		t.Errorf("prune --dry-run = %d, %q", code, out.String())
+	}
+	out.Reset()
+	if code := runHistoryCommand(&out, []string{"prune"}, env); code != 0 || !strings.Contains(out.String(), "Pruned 1 sighting(s)") {
+		t.Errorf("prune = %d, %q", code, out.String())
+	}
+	if code := runHistoryCommand(&out, []string{"prune", "--retention", "soon"}, env); code != 2 {
+		t.Errorf("bad --retention: exit %d, want 2", code)
+	}
+	if code := runHistoryCommand(&out, []string{"prune"}, func(k string) string { return map[string]string{"HISTORY_FILE": path}[k] }); code != 2 {
+		t.Errorf("no retention: exit %d, want 2", code)
+	}
+
+	reloaded, _ := history.Open(path)
+	if got := reloaded.FirstSeenSince(time.Time{}); len(got) != 1 || got[0].MAC != "bb:bb:bb:bb:bb:bb" {
+		t.Errorf("after prune: %+v", got)
+	}
+	// pruneHistory is a no-op without a retention.
+	pruneHistory(reloaded, 0, now.Add(365*24*time.Hour), logger.NewWriter(io.Discard, logger.LevelError))
+	if len(reloaded.FirstSeenSince(time.Time{})) != 1 {
+		t.Error("pruneHistory with zero retention removed sightings")
+	}
+}
+
+func TestWriteMigrationPlan(t *testing.T) {
+	plan := history.MigrationPlan{Current: 1, Latest: 2, Pending: []history.Migration{{Version: 2, Description: "index mac_history by last_seen"}}}
+	var out bytes.Buffer
+	writeMigrationPlan(&out, plan, true)
+	if got := out.String(); !strings.Contains(got, "v1 (latest v2)") || !strings.Contains(got, "v2   index mac_history") || !strings.Contains(got, "nothing changed") {
+		t.Errorf("dry run plan:\n%s", got)
+	}
+	out.Reset()
+	writeMigrationPlan(&out, history.MigrationPlan{Current: 2, Latest: 2}, false)
+	if !strings.Contains(out.String(), "Up to date.") {
+		t.Errorf("current plan:\n%s", out.String())
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+// resolveHostnameMACs forward-resolves name and resolves each address to a
+// client MAC with ResolveIPToMAC, so helpdesk staff can search by machine name.
+// A host with several addresses (dual-stack, multi-homed) yields every MAC that
+// could be found; addresses that are not Meraki clients are logged and skipped.
+// It fails only when DNS fails or no address maps to a client.
+func resolveHostnameMACs(ctx context.Context, client *meraki.MerakiClient, orgID string, networks []meraki.Network, name string, log *logger.Logger) ([]string, error) {
+	ips, err := meraki.LookupHostIPs(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve hostname %s: %v", name, err)
+	}
+	log.Debugf("Hostname %s resolves to %s", name, strings.Join(ips, ", "))
+
+	var macs []string
+	seen := make(map[string]bool)
+	for _, ip := range ips {
+		mac, _, _, err := client.ResolveIPToMAC(ctx, orgID, networks, ip)
+		if err != nil {
+			log.Debugf("Hostname %s: %s: %v", name, ip, err)
+			continue
+		}
+		log.Debugf("Resolved %s (%s) to MAC %s", name, ip, mac)
+		if key := strings.ToLower(mac); !seen[key] {
+			seen[key] = true
+			macs = append(macs, mac)
+		}
+	}
+	if len(macs) == 0 {
+		return nil, fmt.Errorf("hostname %s (%s) not found in any network", name, strings.Join(ips, ", "))
+	}
+	return macs, nil
+}
@@ -0,0 +1,50 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+func TestResolveHostnameMACs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"mac":"aa:bb:cc:00:00:01","ip":"192.0.2.10"}]`))
+	}))
+	defer srv.Close()
+
+	client := meraki.NewClient("key", srv.URL, 1)
+	networks := []meraki.Network{{ID: "N1", Name: "HQ"}}
+	log := logger.NewWriter(io.Discard, logger.LevelError)
+
+	// An IP literal resolves to itself, so no DNS server is needed.
+	macs, err := resolveHostnameMACs(context.Background(), client, "O1", networks, "192.0.2.10", log)
+	if err != nil || len(macs) != 1 || macs[0] != "aa:bb:cc:00:00:01" {
+		t.Errorf("resolveHostnameMACs() = %v, %v; want [aa:bb:cc:00:00:01]", macs, err)
+	}
+
+	_, err = resolveHostnameMACs(context.Background(), client, "O1", networks, "192.0.2.99", log)
+	if err == nil || !strings.Contains(err.Error(), "not found in any network") {
+		t.Errorf("resolveHostnameMACs(unknown client) error = %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// maxIdentifySwitches caps how many switches --identify-switch blinks, so a
+// broad wildcard search does not light up a whole building.
+const maxIdentifySwitches = 5
+
+// identifyTargets returns the distinct switches (serial → name) where a result
+// was learned on an edge port. Uplink sightings are skipped: they only show the
+// path towards the client, not the switch it is plugged into.
+func identifyTargets(rows []output.ResultRow) (serials []string, names map[string]string) {
+	names = make(map[string]string)
+	for _, r := range rows {
+		if r.IsUplink || r.SwitchSerial == "" {
+			continue
+		}
+		if _, dup := names[r.SwitchSerial]; dup {
+			continue
+		}
+		names[r.SwitchSerial] = firstNonEmpty(r.SwitchName, r.SwitchSerial)
+		serials = append(serials, r.SwitchSerial)
+	}
+	return serials, names
+}
+
+// identifySwitches blinks the LEDs of each switch that identifyTargets picks
+// from rows and reports progress to w.
+func identifySwitches(ctx context.Context, w io.Writer, client *meraki.MerakiClient, rows []output.ResultRow, log *logger.Logger) {
+	serials, names := identifyTargets(rows)
+	if len(serials) == 0 {
+		_, _ = fmt.Fprintln(w, "Identify: no edge-port result to identify (uplink sightings are not blinked)")
+		return
+	}
+	if len(serials) > maxIdentifySwitches {
+		log.Warnf("Identify: %d switches matched; blinking only the first %d", len(serials), maxIdentifySwitches)
+		serials = serials[:maxIdentifySwitches]
+	}
+	for _, serial := range serials {
+		d, err := client.BlinkLEDs(ctx, serial, meraki.DefaultBlinkDuration)
+		if err != nil {
+			log.Warnf("Identify %s (%s): %v", names[serial], serial, err)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "Identify: blinking LEDs on %s (%s) for %s\n", names[serial], serial, d)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/config"
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestIdentifySwitches(t *testing.T) {
+	var blinked []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		blinked = append(blinked, r.URL.Path)
+		_, _ = w.Write([]byte(`{"duration":20,"period":160,"duty":50}`))
+	}))
+	defer srv.Close()
+
+	rows := []output.ResultRow{
+		{SwitchName: "access-1", SwitchSerial: "Q2AA", Port: "12"},
+		{SwitchName: "access-1", SwitchSerial: "Q2AA", Port: "13"},
+		{SwitchName: "core", SwitchSerial: "Q2CC", Port: "49", IsUplink: true},
+	}
+	var buf bytes.Buffer
+	client := meraki.NewClient("key", srv.URL, 1)
+	identifySwitches(context.Background(), &buf, client, rows, logger.NewWriter(io.Discard, logger.LevelError))
+
+	if len(blinked) != 1 || blinked[0] != "/devices/Q2AA/blinkLeds" {
+		t.Errorf("blinked %v, want only /devices/Q2AA/blinkLeds", blinked)
+	}
+	if !strings.Contains(buf.String(), "access-1 (Q2AA) for 20s") {
+		t.Errorf("output = %q", buf.String())
+	}
+
+	buf.Reset()
+	identifySwitches(context.Background(), &buf, client, rows[2:], logger.NewWriter(io.Discard, logger.LevelError))
+	if !strings.Contains(buf.String(), "no edge-port result") {
+		t.Errorf("uplink-only output = %q", buf.String())
+	}
+}
+
+// Web handlers build their clients with the server's settings, so
+// MERAKI_BASE_URL and the retry policy apply to them too.
+func TestHandleIdentifyUsesServerSettings(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"duration":20}`))
+	}))
+	defer srv.Close()
+	saved := webClientConfig
+	defer func() { webClientConfig = saved }()
+	webClientConfig = config.Config{BaseURL: srv.URL, MaxRetries: 2, RateLimit: 10}
+
+	rec := httptest.NewRecorder()
+	handleIdentify(rec, httptest.NewRequest("POST", "/api/identify", strings.NewReader(`{"serial":"Q2AA","apiKey":"key"}`)))
+	if hits != 2 || !strings.Contains(rec.Body.String(), `"seconds":20`) {
+		t.Errorf("hits = %d, body = %s; want a retried blink against MERAKI_BASE_URL", hits, rec.Body.String())
+	}
+}
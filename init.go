@@ -0,0 +1,231 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// outputFormats are the OUTPUT_FORMAT values init offers, in config.Load's order.
+var outputFormats = []string{"csv", "text", "html", "jsonl", "xlsx", "yaml", "terraform-external"}
+
+// initSettings are the answers init writes to the config file.
+type initSettings struct {
+	APIKey       string
+	OrgName      string
+	OrgID        string
+	Network      string
+	OutputFormat string
+	BaseURL      string
+}
+
+// runInitCommand implements "init": it asks for the API key (checking it
+// against the Dashboard until one works), the default organization and
+// network, and the output format, then writes them to envFile as a commented
+// config file. Current values from the file are offered as defaults. It
+// returns the exit code.
+func runInitCommand(in io.Reader, out io.Writer, args []string, envFile string, getenv func(string) string) int {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	fs.String("env", envFile, "Config file to write")
+	forceFlag := fs.Bool("force", false, "Overwrite an existing config file without asking")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	p := newPrompter(in, out)
+	ctx := context.Background()
+	fail := func(err error) int {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+
+	if hasSettings(envFile) && !*forceFlag {
+		answer, err := p.ask(fmt.Sprintf("%s already has settings; overwrite it? (y/N)", envFile), "n")
+		if err != nil {
+			return fail(err)
+		}
+		if !strings.HasPrefix(strings.ToLower(answer), "y") {
+			_, _ = fmt.Fprintln(out, "Nothing written.")
+			return 0
+		}
+	}
+
+	s := initSettings{BaseURL: getenv("MERAKI_BASE_URL")}
+	current := strings.TrimSpace(getenv("MERAKI_API_KEY"))
+	var orgs []meraki.Organization
+	var client *meraki.MerakiClient
+	for {
+		question := "Meraki API key"
+		if current != "" {
+			question += " (Enter keeps the current key)"
+		}
+		key, err := p.ask(question, "")
+		if err != nil {
+			return fail(err)
+		}
+		if key == "" {
+			key = current
+		}
+		if key == "" {
+			continue
+		}
+		client = meraki.NewClient(key, s.BaseURL, 0)
+		if orgs, err = client.GetOrganizations(ctx); err != nil {
+			if errors.Is(err, meraki.ErrAuth) {
+				_, _ = fmt.Fprintf(out, "  key rejected: %v\n", err)
+			} else {
+				_, _ = fmt.Fprintf(out, "  could not check the key: %v\n", err)
+			}
+			continue
+		}
+		s.APIKey = key
+		_, _ = fmt.Fprintf(out, "  key OK: %d organization(s)\n", len(orgs))
+		break
+	}
+
+	var org meraki.Organization
+	switch len(orgs) {
+	case 0:
+	case 1:
+		org = orgs[0]
+		_, _ = fmt.Fprintf(out, "Organization: %s\n", org.Name)
+	default:
+		names := make([]string, len(orgs))
+		for i, o := range orgs {
+			names[i] = o.Name
+		}
+		name, err := p.choose("Default organization", names, knownOr(getenv("MERAKI_ORG"), names, ""))
+		if err != nil {
+			return fail(err)
+		}
+		org, _ = selectOrganization(name, orgs)
+	}
+	s.OrgName, s.OrgID = org.Name, org.ID
+
+	s.Network = "ALL"
+	if org.ID != "" {
+		networks, err := client.GetNetworks(ctx, org.ID)
+		if err != nil {
+			return fail(err)
+		}
+		if len(networks) > 1 {
+			names := make([]string, len(networks))
+			for i, n := range networks {
+				names[i] = n.Name
+			}
+			if s.Network, err = p.choose("Default network (number, name or ALL)", names, knownOr(getenv("MERAKI_NETWORK"), names, "ALL"), "ALL"); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	format, err := p.choose("Default output format", outputFormats, knownOr(getenv("OUTPUT_FORMAT"), outputFormats, "csv"))
+	if err != nil {
+		return fail(err)
+	}
+	s.OutputFormat = format
+
+	if err := writeInitConfig(envFile, s); err != nil {
+		return fail(err)
+	}
+	_, _ = fmt.Fprintf(out, "Wrote %s. Try: Find-Meraki-Ports-With-MAC --mac <mac>\n", envFile)
+	return 0
+}
+
+// hasSettings reports whether the file at path assigns any variable, i.e. is
+// more than the commented stub created on first run.
+func hasSettings(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") && strings.Contains(line, "=") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeInitConfig writes s to path atomically as a commented .env file, with
+// the less common settings included as commented-out examples. The file
+// holds the API key, so it is only readable by its owner.
+func writeInitConfig(path string, s initSettings) error {
+	var b strings.Builder
+	line := func(format string, a ...interface{}) { _, _ = fmt.Fprintf(&b, format+"\n", a...) }
+	line("# Find-Meraki-Ports-With-MAC configuration, written by \"init\".")
+	line("# Command-line flags override these values. Re-run init to change them.")
+	line("")
+	line("# Dashboard API key (Organization > API & webhooks).")
+	line("MERAKI_API_KEY=%s", s.APIKey)
+	line("")
+	if s.OrgName != "" {
+		line("# Default organization (--org). MERAKI_ORG_ID (--org-id) skips the name lookup.")
+		line("MERAKI_ORG=%s", envQuote(s.OrgName))
+		line("# MERAKI_ORG_ID=%s", s.OrgID)
+		line("")
+	}
+	line("# Default network name, comma-separated names or glob patterns, or ALL (--network).")
+	line("MERAKI_NETWORK=%s", envQuote(s.Network))
+	line("")
+	line("# Default output format: %s (--output-format).", strings.Join(outputFormats, " | "))
+	line("OUTPUT_FORMAT=%s", s.OutputFormat)
+	line("")
+	if s.BaseURL != "" {
+		line("# Dashboard API base URL.")
+		line("MERAKI_BASE_URL=%s", s.BaseURL)
+	} else {
+		line("# Dashboard API base URL, e.g. for the China or Canada dashboards.")
+		line("# MERAKI_BASE_URL=https://api.meraki.com/api/v1")
+	}
+	line("")
+	line("# Other settings; see --help for the full list.")
+	line("# LOG_LEVEL=WARNING")
+	line("# EXCLUDE_SWITCHES=core-1,core-2")
+	line("# HISTORY_FILE=off")
+	line("# WEB_PORT=8080")
+
+	f, err := output.CreatePrivateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if _, err := io.WriteString(f, b.String()); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// envQuote double-quotes a .env value that has spaces, quotes or a '#', so
+// godotenv reads it back unchanged.
+func envQuote(v string) string {
+	if !strings.ContainsAny(v, " \t\"'#\\") {
+		return v
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joho/godotenv"
+)
+
+func TestRunInitCommand(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Cisco-Meraki-API-Key") != "good" {
+			http.Error(w, `{"errors":["Invalid API key"]}`, http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/organizations":
+			_, _ = w.Write([]byte(`[{"id":"O1","name":"Acme"},{"id":"O2","name":"Acme Labs"}]`))
+		case "/organizations/O2/networks":
+			_, _ = w.Write([]byte(`[{"id":"N1","name":"HQ"},{"id":"N2","name":"Branch #2"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), ".env.find-mac")
+	if err := os.WriteFile(path, []byte("# MERAKI_API_KEY=your-api-key-here\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"MERAKI_BASE_URL": srv.URL}
+	// A rejected key is asked again; "labs" and "branch" complete to unique names.
+	in := strings.NewReader("bad\ngood\nlabs\nbranch\njsonl\n")
+	var out bytes.Buffer
+	if code := runInitCommand(in, &out, nil, path, func(k string) string { return env[k] }); code != 0 {
+		t.Fatalf("init exit %d; output:\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "key rejected") {
+		t.Errorf("output does not report the rejected key:\n%s", out.String())
+	}
+
+	got, err := godotenv.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"MERAKI_API_KEY": "good", "MERAKI_ORG": "Acme Labs", "MERAKI_NETWORK": "Branch #2", "OUTPUT_FORMAT": "jsonl", "MERAKI_BASE_URL": srv.URL}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("config file mode = %v, %v; want 0600", fi.Mode().Perm(), err)
+	}
+
+	// The file now has settings: declining the overwrite leaves it alone.
+	before, _ := os.ReadFile(path)
+	env["MERAKI_API_KEY"] = "good"
+	if code := runInitCommand(strings.NewReader("\n"), &out, nil, path, func(k string) string { return env[k] }); code != 0 {
+		t.Fatalf("init (declined) exit %d", code)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Error("declined overwrite changed the file")
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/config"
+	"Find-Meraki-Ports-With-MAC/pkg/filters"
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// defaultInventoryMaxAge is how long a cached inventory snapshot is served
+// before it is rebuilt from the Dashboard API.
+const defaultInventoryMaxAge = 15 * time.Minute
+
+// inventorySnapshot is an org-wide switch, port and client inventory captured
+// in one pass, so automation reads a consistent document instead of querying
+// Meraki itself.
+type inventorySnapshot struct {
+	GeneratedAt time.Time          `json:"generatedAt"`
+	OrgID       string             `json:"orgId"`
+	Networks    []inventoryNetwork `json:"networks"`
+}
+
+type inventoryNetwork struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Switches []inventorySwitch `json:"switches"`
+}
+
+type inventorySwitch struct {
+	Serial  string            `json:"serial"`
+	Name    string            `json:"name"`
+	Model   string            `json:"model"`
+	Ports   []inventoryPort   `json:"ports"`
+	Clients []inventoryClient `json:"clients"`
+}
+
+type inventoryPort struct {
+	PortID  string `json:"portId"`
+	Name    string `json:"name,omitempty"`
+	Enabled bool   `json:"enabled"`
+	Type    string `json:"type,omitempty"`
+	VLAN    int    `json:"vlan,omitempty"`
+}
+
+type inventoryClient struct {
+	MAC      string `json:"mac"`
+	IP       string `json:"ip,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	Port     string `json:"port,omitempty"`
+	LastSeen string `json:"lastSeen,omitempty"`
+}
+
+// buildInventory fetches every switch, its port configuration and its recent
+// wired clients in orgID. A switch whose ports cannot be read is kept without
+// ports so one failing device does not void the snapshot.
+func buildInventory(ctx context.Context, client *meraki.MerakiClient, orgID string, now time.Time, log *logger.Logger) (*inventorySnapshot, error) {
+	networks, err := client.GetNetworks(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	snap := &inventorySnapshot{GeneratedAt: now.UTC(), OrgID: orgID, Networks: []inventoryNetwork{}}
+	for _, net := range networks {
+		devices, err := client.GetDevices(ctx, net.ID)
+		if err != nil {
+			return nil, fmt.Errorf("network %s: %w", net.Name, err)
+		}
+		switches := filters.FilterSwitches(devices)
+		if len(switches) == 0 {
+			continue
+		}
+
+		clientsBySerial := make(map[string][]inventoryClient)
+		if ncs, err := client.GetNetworkClients(ctx, net.ID); err != nil {
+			log.Warnf("Inventory: clients unavailable for %s: %v", net.Name, err)
+		} else {
+			for _, nc := range ncs {
+				serial := strings.ToUpper(strings.TrimSpace(nc.RecentDeviceSerial))
+				norm, err := macaddr.NormalizeExactMac(nc.MAC)
+				if serial == "" || err != nil {
+					continue
+				}
+				clientsBySerial[serial] = append(clientsBySerial[serial], inventoryClient{
+					MAC:      macaddr.FormatMacColon(norm),
+					IP:       nc.IP,
+					Hostname: meraki.ClientHostname(nc),
+					Port:     firstNonEmpty(nc.SwitchportName, nc.Switchport, nc.Port),
+					LastSeen: nc.LastSeen,
+				})
+			}
+		}
+
+		in := inventoryNetwork{ID: net.ID, Name: net.Name}
+		for _, dev := range switches {
+			sw := inventorySwitch{
+				Serial:  dev.Serial,
+				Name:    firstNonEmpty(dev.Name, dev.Serial),
+				Model:   dev.Model,
+				Ports:   []inventoryPort{},
+				Clients: clientsBySerial[strings.ToUpper(dev.Serial)],
+			}
+			if sw.Clients == nil {
+				sw.Clients = []inventoryClient{}
+			}
+			ports, err := client.GetSwitchPorts(ctx, dev.Serial)
+			if err != nil {
+				log.Warnf("Inventory: ports unavailable for %s: %v", sw.Name, err)
+			}
+			for _, p := range ports {
+				sw.Ports = append(sw.Ports, inventoryPort{PortID: p.PortID, Name: p.Name, Enabled: p.Enabled, Type: p.Type, VLAN: p.Vlan})
+			}
+			in.Switches = append(in.Switches, sw)
+		}
+		snap.Networks = append(snap.Networks, in)
+	}
+	return snap, nil
+}
+
+// inventoryMu serializes snapshot rebuilds so concurrent requests for a stale
+// inventory trigger one rebuild, not one each.
+var inventoryMu sync.Mutex
+
+// inventoryCachePath returns the cache file for orgID in the user cache
+// directory, or the working directory when there is none.
+func inventoryCachePath(orgID string) string {
+	name := "find-mac-inventory-" + filepath.Base(orgID) + ".json"
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// loadInventory is a read-through cache: it returns the snapshot cached at
+// path when it is for orgID and younger than maxAge, and otherwise (or with
+// refresh) builds a fresh one and writes it back. cached reports which happened.
+func loadInventory(ctx context.Context, client *meraki.MerakiClient, orgID, path string, maxAge time.Duration, refresh bool, log *logger.Logger) (snap *inventorySnapshot, cached bool, err error) {
+	inventoryMu.Lock()
+	defer inventoryMu.Unlock()
+
+	now := time.Now()
+	if !refresh {
+		if snap, err := readInventory(path); err == nil && snap.OrgID == orgID && now.Sub(snap.GeneratedAt) < maxAge {
+			return snap, true, nil
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warnf("Inventory cache %s unreadable; rebuilding: %v", path, err)
+		}
+	}
+	snap, err = buildInventory(ctx, client, orgID, now, log)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := writeInventory(path, snap); err != nil {
+		log.Warnf("Inventory cache %s not written: %v", path, err)
+	}
+	return snap, false, nil
+}
+
+func readInventory(path string) (*inventorySnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snap inventorySnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// writeInventory replaces the cache file atomically so readers never see a
+// partial snapshot.
+func writeInventory(path string, snap *inventorySnapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	f, err := output.CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// runInventoryCommand implements "inventory [flags]": it writes the org's
+// inventory snapshot as JSON to w, served from the cache while it is fresh.
+// It returns the exit code.
+func runInventoryCommand(w io.Writer, args []string, getenv func(string) string) int {
+	fs := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	orgFlag := fs.String("org", getenv("MERAKI_ORG"), "Organization name (optional if only one org is accessible)")
+	orgIDFlag := fs.String("org-id", getenv("MERAKI_ORG_ID"), "Organization ID (skips the organization lookup)")
+	maxAgeFlag := fs.Duration("max-age", defaultInventoryMaxAge, "Serve the cached snapshot while it is younger than this")
+	refreshFlag := fs.Bool("refresh", false, "Rebuild the snapshot even if the cached one is fresh")
+	cacheFlag := fs.String("cache-file", "", "Snapshot cache file (default in the user cache directory)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	apiKey := strings.TrimSpace(getenv("MERAKI_API_KEY"))
+	if apiKey == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "ERROR: MERAKI_API_KEY is required")
+		return 1
+	}
+	log := logger.NewWriter(os.Stderr, logger.LevelWarning)
+	// Load only fills in the API client settings here; the search options it
+	// validates do not apply to this command.
+	cfg, _ := config.Load(config.Flags{}, getenv)
+	client := newAPIClient(cfg, apiKey, log)
+	ctx := context.Background()
+
+	orgID := strings.TrimSpace(*orgIDFlag)
+	if orgID == "" {
+		orgs, err := client.GetOrganizations(ctx)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		name := *orgFlag
+		if len(orgs) == 1 {
+			name = orgs[0].Name
+		}
+		org, err := selectOrganization(name, orgs)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		orgID = org.ID
+	}
+
+	path := firstNonEmpty(*cacheFlag, inventoryCachePath(orgID))
+	snap, _, err := loadInventory(ctx, client, orgID, path, *maxAgeFlag, *refreshFlag, log)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(snap); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// handleGetInventory serves the org's inventory snapshot from the cache,
+// rebuilding it when older than maxAge (seconds, default 900) or when refresh=1.
+// Query params: orgId (required), apiKey, maxAge, refresh.
+// The X-Inventory-Cache header reports "hit" or "miss".
+func handleGetInventory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	q := r.URL.Query()
+	orgID := q.Get("orgId")
+	apiKey := requestAPIKey(q.Get("apiKey"))
+	if apiKey == "" {
+		apiKey = webAPIKey
+	}
+	if orgID == "" || apiKey == "" {
+		http.Error(w, `{"error":"orgId and apiKey are required"}`, http.StatusBadRequest)
+		return
+	}
+	maxAge := defaultInventoryMaxAge
+	if s := q.Get("maxAge"); s != "" {
+		secs, err := strconv.Atoi(s)
+		if err != nil || secs < 0 {
+			http.Error(w, `{"error":"maxAge must be a number of seconds"}`, http.StatusBadRequest)
+			return
+		}
+		maxAge = time.Duration(secs) * time.Second
+	}
+
+	client := newWebClient(apiKey, orgID, newWebLogger())
+	snap, cached, err := loadInventory(r.Context(), client, orgID, inventoryCachePath(orgID), maxAge, q.Get("refresh") == "1", newWebLogger())
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		writeJSON(w, map[string]string{"error": fmt.Sprintf("Failed to build inventory: %v", err)})
+		return
+	}
+	if cached {
+		w.Header().Set("X-Inventory-Cache", "hit")
+	} else {
+		w.Header().Set("X-Inventory-Cache", "miss")
+	}
+	writeJSON(w, snap)
+}
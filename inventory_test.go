@@ -0,0 +1,95 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+func TestLoadInventory(t *testing.T) {
+	var networkCalls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/organizations/O1/networks":
+			networkCalls.Add(1)
+			_, _ = w.Write([]byte(`[{"id":"N1","name":"HQ"},{"id":"N2","name":"Wireless only"}]`))
+		case "/networks/N1/devices":
+			_, _ = w.Write([]byte(`[{"serial":"Q2AA-0001","name":"idf-1","model":"MS120","productType":"switch"},
+				{"serial":"Q2MR-0001","name":"ap-1","model":"MR44","productType":"wireless"}]`))
+		case "/networks/N2/devices":
+			_, _ = w.Write([]byte(`[{"serial":"Q2MR-0002","model":"MR44","productType":"wireless"}]`))
+		case "/networks/N1/clients":
+			_, _ = w.Write([]byte(`[{"mac":"AA:BB:CC:00:00:01","ip":"10.0.0.5","hostname":"printer-1","switchport":"3","recentDeviceSerial":"Q2AA-0001"},
+				{"mac":"aa:bb:cc:00:00:09","recentDeviceSerial":"Q2MR-0001"}]`))
+		case "/devices/Q2AA-0001/switch/ports":
+			_, _ = w.Write([]byte(`[{"portId":"3","name":"Printer","enabled":true,"type":"access","vlan":10}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := meraki.NewClient("key", srv.URL, 1)
+	log := logger.NewWriter(io.Discard, logger.LevelError)
+	path := filepath.Join(t.TempDir(), "inventory.json")
+	ctx := context.Background()
+
+	snap, cached, err := loadInventory(ctx, client, "O1", path, time.Hour, false, log)
+	if err != nil {
+		t.Fatalf("loadInventory() error: %v", err)
+	}
+	if cached {
+		t.Error("first loadInventory() should build, not hit the cache")
+	}
+	if len(snap.Networks) != 1 || len(snap.Networks[0].Switches) != 1 {
+		t.Fatalf("snapshot = %+v, want one network with one switch", snap)
+	}
+	sw := snap.Networks[0].Switches[0]
+	if sw.Serial != "Q2AA-0001" || len(sw.Ports) != 1 || sw.Ports[0].VLAN != 10 {
+		t.Errorf("switch = %+v", sw)
+	}
+	if len(sw.Clients) != 1 || sw.Clients[0].MAC != "aa:bb:cc:00:00:01" || sw.Clients[0].Port != "3" || sw.Clients[0].Hostname != "printer-1" {
+		t.Errorf("clients = %+v, want the printer on port 3", sw.Clients)
+	}
+
+	again, cached, err := loadInventory(ctx, client, "O1", path, time.Hour, false, log)
+	if err != nil || !cached || !again.GeneratedAt.Equal(snap.GeneratedAt) {
+		t.Errorf("second loadInventory() = cached %v, err %v; want the cached snapshot", cached, err)
+	}
+	if n := networkCalls.Load(); n != 1 {
+		t.Errorf("GetNetworks called %d times, want 1 (served from cache)", n)
+	}
+
+	if _, cached, _ := loadInventory(ctx, client, "O1", path, time.Hour, true, log); cached {
+		t.Error("loadInventory(refresh) should rebuild")
+	}
+	if _, cached, _ := loadInventory(ctx, client, "O1", path, 0, false, log); cached {
+		t.Error("loadInventory() with an expired snapshot should rebuild")
+	}
+	if _, cached, _ := loadInventory(ctx, client, "O2", path, time.Hour, false, log); cached {
+		t.Error("loadInventory() for another org should not serve this org's snapshot")
+	}
+}
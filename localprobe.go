@@ -0,0 +1,159 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+)
+
+const (
+	// maxProbeHosts caps how many addresses of a subnet --local-probe pings.
+	maxProbeHosts = 4096
+	// localProbeSettle is how long to wait after probing so the switches can
+	// learn the MACs before their tables are read.
+	localProbeSettle = 2 * time.Second
+	// probePort is the UDP discard port. Nothing needs to listen on it: the
+	// datagram only exists to make this host resolve the address.
+	probePort = 9
+)
+
+// localProbe implements --local-probe: it sends one UDP datagram to every
+// address of target (an IP or a CIDR subnet) that is on a subnet attached to
+// this host. Before the datagram can leave, the OS has to resolve the address
+// with ARP (IPv4) or neighbor discovery (IPv6), and the device's reply makes
+// the switches relearn its MAC even if it has been idle. No raw sockets are
+// used, so no elevated privileges are needed. Failures are logged, not fatal.
+func localProbe(target string, log *logger.Logger) {
+	local, err := localPrefixes()
+	if err != nil {
+		log.Warnf("--local-probe: listing interfaces: %v", err)
+		return
+	}
+	ips, err := probeTargets(target, local)
+	if err != nil {
+		log.Warnf("--local-probe: %v; skipping", err)
+		return
+	}
+	sent := 0
+	for _, ip := range ips {
+		conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: ip, Port: probePort})
+		if err != nil {
+			log.Debugf("--local-probe: %s: %v", ip, err)
+			continue
+		}
+		if _, err := conn.Write([]byte{0}); err == nil {
+			sent++
+		}
+		_ = conn.Close()
+	}
+	log.Infof("Local probe: sent %d of %d address(es) in %s; waiting %s for switches to learn them", sent, len(ips), target, localProbeSettle)
+	time.Sleep(localProbeSettle)
+}
+
+// localPrefixes returns the subnets of this host's interfaces.
+func localPrefixes() ([]*net.IPNet, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var out []*net.IPNet
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && !n.IP.IsLoopback() {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+// probeTargets returns the addresses of target (an IP or a CIDR subnet) to
+// probe, given this host's interface subnets. An address must be on one of
+// them, since ARP and neighbor discovery do not cross routers; a subnet must
+// lie entirely within one and hold at most maxProbeHosts addresses. The
+// network and broadcast addresses of IPv4 subnets are left out.
+func probeTargets(target string, local []*net.IPNet) ([]net.IP, error) {
+	if !isSubnet(target) {
+		ip := net.ParseIP(target)
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not an IP address", target)
+		}
+		if !onLink(ip, local) {
+			return nil, fmt.Errorf("%s is not on a subnet attached to this host", ip)
+		}
+		return []net.IP{ip}, nil
+	}
+	_, subnet, err := net.ParseCIDR(target)
+	if err != nil {
+		return nil, err
+	}
+	ones, bits := subnet.Mask.Size()
+	if bits-ones > 12 { // 2^12 = maxProbeHosts
+		return nil, fmt.Errorf("%s has more than %d addresses", subnet, maxProbeHosts)
+	}
+	if !onLink(subnet.IP, local) || !onLink(lastIP(subnet), local) {
+		return nil, fmt.Errorf("%s is not within a subnet attached to this host", subnet)
+	}
+	var ips []net.IP
+	for ip := cloneIP(subnet.IP); subnet.Contains(ip); ip = nextIP(ip) {
+		ips = append(ips, cloneIP(ip))
+	}
+	if subnet.IP.To4() != nil && bits-ones >= 2 {
+		ips = ips[1 : len(ips)-1]
+	}
+	return ips, nil
+}
+
+// onLink reports whether ip is inside one of the local subnets.
+func onLink(ip net.IP, local []*net.IPNet) bool {
+	for _, n := range local {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastIP returns the highest address of subnet.
+func lastIP(subnet *net.IPNet) net.IP {
+	ip := cloneIP(subnet.IP)
+	for i := range ip {
+		ip[i] |= ^subnet.Mask[len(subnet.Mask)-len(ip)+i]
+	}
+	return ip
+}
+
+// nextIP returns ip+1; it wraps to all zeroes past the last address.
+func nextIP(ip net.IP) net.IP {
+	next := cloneIP(ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// cloneIP returns a copy of ip in its shortest form (4 bytes for IPv4).
+func cloneIP(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	return append(net.IP(nil), ip...)
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestProbeTargets(t *testing.T) {
+	var local []*net.IPNet
+	for _, c := range []string{"10.20.30.7/24", "2001:db8:1::5/64"} {
+		ip, n, _ := net.ParseCIDR(c)
+		n.IP = ip
+		local = append(local, n)
+	}
+
+	tests := []struct {
+		target  string
+		want    []string // first and last address, and the count below
+		count   int
+		wantErr string
+	}{
+		{target: "10.20.30.99", want: []string{"10.20.30.99", "10.20.30.99"}, count: 1},
+		{target: "2001:db8:1::42", want: []string{"2001:db8:1::42", "2001:db8:1::42"}, count: 1},
+		{target: "10.20.31.99", wantErr: "not on a subnet"},
+		{target: "10.20.30.0/24", want: []string{"10.20.30.1", "10.20.30.254"}, count: 254},
+		{target: "10.20.30.128/30", want: []string{"10.20.30.129", "10.20.30.130"}, count: 2},
+		{target: "10.20.30.8/31", want: []string{"10.20.30.8", "10.20.30.9"}, count: 2},
+		{target: "2001:db8:1::/126", want: []string{"2001:db8:1::", "2001:db8:1::3"}, count: 4},
+		{target: "10.20.0.0/16", wantErr: "more than 4096"},
+		{target: "10.20.30.0/23", wantErr: "not within a subnet"},
+		{target: "pc-1", wantErr: "not an IP address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, err := probeTargets(tt.target, local)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("probeTargets() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("probeTargets() error: %v", err)
+			}
+			if len(got) != tt.count || got[0].String() != tt.want[0] || got[len(got)-1].String() != tt.want[1] {
+				t.Errorf("probeTargets() = %d addresses %v…%v, want %d %v", len(got), got[0], got[len(got)-1], tt.count, tt.want)
+			}
+		})
+	}
+}
@@ -22,44 +22,35 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/template"
 	"time"
 
+	"Find-Meraki-Ports-With-MAC/pkg/config"
 	"Find-Meraki-Ports-With-MAC/pkg/filters"
+	"Find-Meraki-Ports-With-MAC/pkg/history"
 	"Find-Meraki-Ports-With-MAC/pkg/logger"
 	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
 	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/oui"
 	"Find-Meraki-Ports-With-MAC/pkg/output"
+	"Find-Meraki-Ports-With-MAC/pkg/query"
 
 	"path/filepath"
+	"runtime"
 
 	"github.com/joho/godotenv"
 )
 
-// Config holds all configuration options from environment variables and command-line flags.
-type Config struct {
-	APIKey       string // Meraki Dashboard API key
-	OrgName      string // Organization name filter
-	OrgID        string // Organization ID (used by web path for direct lookup)
-	NetworkName  string // Network name filter or "ALL"
-	OutputFormat string // Output format: csv, text, or html
-	BaseURL      string // Meraki API base URL
-	MaxRetries   int    // Maximum number of API request retries on 429
-	MacTablePoll int    // MAC table lookup poll attempts (2s each)
-	DNSServers   string // Comma-separated alternate DNS servers for PTR lookups
-	LogFile      string // Path to log file
-	LogLevel     string // Log level: DEBUG, INFO, WARNING, ERROR
-	Verbose      bool   // Enable verbose output
-	SwitchFilter string // Switch name filter
-	PortFilter   string // Port filter
-	TestFull     bool   // Display complete MAC forwarding table
-	IPAddress    string // IP address to resolve
-	MACAddress   string // MAC address or pattern to look up
-}
-
 // Version information injected at build time via ldflags.
 // Build with: go build -ldflags "-X main.Version=1.0.0 -X main.Commit=<git-sha> -X main.BuildTime=<timestamp>"
 const (
@@ -67,20 +58,29 @@ const (
 )
 
 var (
-	Version          = "dev"     // Version set at build time
-	Commit           = "unknown" // Git commit SHA set at build time
-	BuildTime        = "unknown" // Build timestamp set at build time
-	GoVersion        = "go1.21"  // Go version (can be updated at build time)
-	webAPIKey        string      // API key pre-loaded from .env for the web interface
-	webPresetMAC     string      // pre-filled MAC from CLI --mac
-	webPresetIP      string      // pre-filled IP from CLI --ip
-	webPresetOrgName string      // pre-selected org name from CLI --org
-	webPresetNetwork string      // pre-selected network name from CLI --network
-	webTestDataMode  bool        // --test-data: serve sanitised demo data, no API calls
+	Version          = "dev"       // Version set at build time
+	Commit           = "unknown"   // Git commit SHA set at build time
+	BuildTime        = "unknown"   // Build timestamp set at build time
+	GoVersion        = "go1.21"    // Go version (can be updated at build time)
+	webAPIKey        string        // API key pre-loaded from .env for the web interface
+	webPresetMAC     string        // pre-filled MAC from CLI --mac
+	webPresetIP      string        // pre-filled IP from CLI --ip
+	webPresetOrgName string        // pre-selected org name from CLI --org
+	webPresetNetwork string        // pre-selected network name from CLI --network
+	webTestDataMode  bool          // --test-data: serve sanitised demo data, no API calls
+	webNotify        bool          // --notify: desktop notification when a long search completes
+	webMaxResults    int           // --max-results: rows a search returns at most
+	webHistoryFile   string        // history location web searches record to and diff against; "" when off
+	webInstanceID    string        // identifies this web server instance to the browser (see instanceID)
+	webPublicURL     string        // --public-url: base URL of shared links such as QR codes
+	webLANURL        string        // LAN address of the web server (see lanBaseURL); "" when it only listens on loopback
+	webClientConfig  config.Config // API client settings of web requests (rate limit, retries, cache); see newWebClient
+	webUIState       = &uiStateStore{path: defaultUIStateFile()}
+	webTokens        = &tokenStore{path: defaultTokensFile()}
 )
 
 // resolveEnvFile resolves the .env file path to use.
-// Priority: --env flag > default (~/.env.find-mac).
+// Priority: --env flag > default (see defaultEnvFile).
 // If the resolved file does not exist it is created as an empty file so the
 // user has a clear location to populate.
 func resolveEnvFile() string {
@@ -101,24 +101,39 @@ func resolveEnvFile() string {
 		}
 	}
 
-	// Default: ~/.env.find-mac
 	home, err := os.UserHomeDir()
 	if err != nil {
 		// Fallback to current directory if home cannot be determined
 		return ".env.find-mac"
 	}
-	return filepath.Join(home, ".env.find-mac")
+	return defaultEnvFile(runtime.GOOS, home, os.Getenv, func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	})
+}
+
+// defaultEnvFile is ~/.env.find-mac, except on Windows, where it lives in the
+// %APPDATA%\Find-Meraki-Ports-With-MAC directory the installer sets up. A
+// %USERPROFILE%\.env.find-mac from an earlier version is still used if present.
+func defaultEnvFile(goos, home string, getenv func(string) string, exists func(string) bool) string {
+	legacy := filepath.Join(home, ".env.find-mac")
+	if goos != "windows" || getenv("APPDATA") == "" || exists(legacy) {
+		return legacy
+	}
+	return filepath.Join(getenv("APPDATA"), "Find-Meraki-Ports-With-MAC", ".env.find-mac")
 }
 
 func main() {
 	envFile := resolveEnvFile()
 
 	// Create the env file if it does not exist so the user has a ready-made
-	// location to add their settings (home directory on all platforms).
+	// location to add their settings.
 	if _, err := os.Stat(envFile); os.IsNotExist(err) {
+		_ = os.MkdirAll(filepath.Dir(envFile), 0700)
 		if f, err := os.OpenFile(envFile, os.O_CREATE|os.O_WRONLY, 0600); err == nil {
 			_, _ = fmt.Fprintf(f, "# Find-Meraki-Ports-With-MAC configuration\n")
-			_, _ = fmt.Fprintf(f, "# Edit this file to set your defaults.\n")
+			_, _ = fmt.Fprintf(f, "# Edit this file to set your defaults, or run\n")
+			_, _ = fmt.Fprintf(f, "# \"Find-Meraki-Ports-With-MAC init\" to fill it in interactively.\n")
 			_, _ = fmt.Fprintf(f, "#\n")
 			_, _ = fmt.Fprintf(f, "# MERAKI_API_KEY=your-api-key-here\n")
 			_, _ = fmt.Fprintf(f, "# MERAKI_ORG=My Organization\n")
@@ -130,60 +145,198 @@ func main() {
 
 	_ = godotenv.Load(envFile)
 
+	// Subcommands create API clients before flags are parsed; --proxy and the
+	// TLS flags below override this for searches.
+	if err := meraki.SetProxy(os.Getenv("MERAKI_PROXY")); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: MERAKI_PROXY ignored: %v\n", err)
+	}
+	envCfg, _ := config.Load(config.Flags{}, os.Getenv)
+	meraki.SetUserAgent(userAgent(envCfg))
+	if err := meraki.SetTLS(tlsOptions(envCfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: API TLS settings ignored: %v\n", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "init" {
+		os.Exit(runInitCommand(os.Stdin, os.Stderr, os.Args[2:], envFile, os.Getenv))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		os.Exit(runReportCommand(os.Stdout, os.Args[2:], os.Getenv("HISTORY_FILE")))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		os.Exit(runHistoryCommand(os.Stdout, os.Args[2:], os.Getenv))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backup" {
+		os.Exit(runBackupCommand(os.Stdout, os.Args[2:], envFile, os.Getenv))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		os.Exit(runTokenCommand(os.Stdout, os.Args[2:], os.Getenv))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "inventory" {
+		os.Exit(runInventoryCommand(os.Stdout, os.Args[2:], os.Getenv))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "merge" {
+		os.Exit(runMergeCommand(os.Stdout, os.Args[2:], os.Getenv))
+	}
+	os.Args = findArgs(os.Args)
+
 	envFlag := flag.String("env", envFile, "Path to .env config file")
 	_ = envFlag // consumed by pre-scan above; registered so --help shows it
 
-	macFlag := flag.String("mac", "", "MAC address or pattern")
+	var macFlag stringList
+	flag.Var(&macFlag, "mac", "MAC address or pattern; repeat or comma-separate to search several in one scan")
+	clientIDFlag := flag.String("client-id", "", "Meraki client ID to look up (e.g. k74272e)")
+	serialFlag := flag.String("serial", "", "List every client on the switch with this serial (live MAC table + client history)")
+	queryFlag := flag.String("query", "", "Search expression, e.g. \"vlan=30 AND vendor~'Axis' AND network='HQ'\"")
 	ipFlag := flag.String("ip", "", "IP address to resolve to MAC")
+	macRangeFlag := flag.String("mac-range", "", "Inclusive MAC range to look up, e.g. 00:11:22:33:44:00-00:11:22:33:44:ff")
+	hostnameFlag := flag.String("hostname", "", "DNS name to resolve to IP(s) and then to MAC")
+	vendorFlag := flag.String("vendor", "", "Vendor name; finds every MAC in the vendor's OUI blocks (e.g. Axis)")
+	ouiFileFlag := flag.String("oui-file", "", "IEEE oui.csv to use instead of the built-in OUI registry")
 	networkFlag := flag.String("network", "", "Network name or ALL")
 	orgFlag := flag.String("org", "", "Organization name")
-	outputFlag := flag.String("output-format", "", "Output format: csv, text, html")
+	orgIDFlag := flag.String("org-id", "", "Organization ID (skips the organization lookup)")
+	networkIDFlag := flag.String("network-id", "", "Comma-separated network IDs (skips the network lookup)")
+	outputFlag := flag.String("output-format", "", "Output format: csv, text, html, jsonl, xlsx, yaml, terraform-external")
+	outputFileFlag := flag.String("output-file", "", "Write results to this file (atomically, via temp file + rename); - means stdout")
+	csvDelimiterFlag := flag.String("csv-delimiter", "", "CSV delimiter: comma, semicolon, tab, pipe or a single character")
+	csvBOMFlag := flag.Bool("csv-bom", false, "Prefix CSV output with a UTF-8 BOM (for Excel)")
+	csvQuoteAllFlag := flag.Bool("csv-quote-all", false, "Quote every CSV field")
+	csvCRLFFlag := flag.Bool("csv-crlf", false, "End CSV lines with CRLF (Windows line endings)")
+	maxResultsFlag := flag.Int("max-results", 0, "Cap the output at this many result rows, with a truncation warning; -1 for no limit (default: 10000)")
+	colorFlag := flag.String("color", "auto", "Colorize text output: auto, always, never")
+	columnsFlag := flag.String("columns", "", "Comma-separated columns for csv/text/html/xlsx output, e.g. switch,port,mac,vlan")
+	outputTemplateFlag := flag.String("output-template", "", "Render each result with a Go text/template (file path or inline template); overrides --output-format")
 	listOrgsFlag := flag.Bool("list-orgs", false, "List organizations the API key can access and exit")
 	listNetworksFlag := flag.Bool("list-networks", false, "List networks per organization and exit")
 	testAPIFlag := flag.Bool("test-api", false, "Validate API key and exit")
-	testFullTableFlag := flag.Bool("test-full-table", false, "Display all MAC addresses in forwarding table (filtered by --switch/--port)")
+	testFullTableFlag := flag.Bool("test-full-table", false, "Display all MAC addresses in forwarding table (filtered by --switch/--port/--vlan)")
 	verboseFlag := flag.Bool("verbose", false, "Send DEBUG logs to console (overrides --log-level and --log-file)")
 	switchFlag := flag.String("switch", "", "Filter by switch name (case-insensitive substring match)")
-	portFlag := flag.String("port", "", "Filter by port name/number")
+	deviceTagFlag := flag.String("device-tag", "", "Comma-separated Dashboard device tags; only search switches with one of them")
+	modelFlag := flag.String("model", "", "Comma-separated switch model prefixes to search, e.g. MS120 or C9300")
+	shardFlag := flag.String("shard", "", "Search only share i of n of the switches, e.g. 2/8, to split a sweep between runs")
+	portFlag := flag.String("port", "", "Filter by port number, range or list (e.g. 3, 1-12, Gi1/0/1-12)")
+	vlanFlag := flag.Int("vlan", 0, "Only report clients on this VLAN")
+	portModeFlag := flag.String("port-mode", "", "Only report clients on access or trunk ports")
+	deviceTypesFlag := flag.String("device-types", "", "Comma-separated device types to search: switch, wireless, appliance (default switch)")
+	entryTypeFlag := flag.String("entry-type", "", "Only report static or dynamic live MAC table entries")
+	excludeSwitchFlag := flag.String("exclude-switch", "", "Comma-separated switch names (substring) or serials to skip")
+	excludePortFlag := flag.String("exclude-port", "", "Comma-separated port IDs to leave out of the results")
 	logFileFlag := flag.String("log-file", "", "Log file path")
 	logLevelFlag := flag.String("log-level", "", "Log level: DEBUG, INFO, WARNING, ERROR")
 	versionFlag := flag.Bool("version", false, "Show version and exit")
 	helpFlag := flag.Bool("help", false, "Show help")
 	interactiveFlag := flag.Bool("interactive", false, "Launch web interface mode")
 	retryFlag := flag.Int("retry", 0, "Maximum API retry attempts on rate limit (default: 6)")
+	retryStatusesFlag := flag.String("retry-statuses", "", "Comma-separated HTTP statuses to retry (default: 429,502,503,504)")
+	timeoutFlag := flag.String("timeout", "", "Abort the search after this long, e.g. 10m, and write the results found so far (default: no limit)")
+	retryElapsedFlag := flag.String("retry-max-elapsed", "", "Stop retrying a request once it has taken this long, e.g. 2m (default: no limit)")
+	rateLimitFlag := flag.Int("rate-limit", 0, "Sustained Meraki API requests per second, shared by all workers (default: 10)")
 	macPollFlag := flag.Int("mac-table-poll", 0, "MAC table lookup poll attempts, 2s each (default: 15)")
+	proxyFlag := flag.String("proxy", "", "Proxy for Dashboard API requests (http://host:port, socks5://host:port, or direct); default HTTPS_PROXY")
+	caFileFlag := flag.String("ca-file", "", "PEM bundle of extra root CAs for Dashboard API requests (e.g. an SSL-inspecting proxy's CA)")
+	tlsMinFlag := flag.String("tls-min-version", "", "Minimum TLS version for Dashboard API requests: 1.2 or 1.3 (default: 1.2)")
+	insecureFlag := flag.Bool("insecure-skip-verify", false, "Do not verify the Dashboard API certificate (lab use only)")
+	userAgentFlag := flag.String("user-agent-suffix", "", "Text appended to the Dashboard API User-Agent, e.g. your team or tool name")
+	requestIDFlag := flag.String("request-id", "", "X-Request-Id prefix for this run's API calls, e.g. a ticket number (default: random)")
+	cacheTTLFlag := flag.String("cache-ttl", "", "Cache organization, network and device listings on disk this long, e.g. 1h or 1d (default: off)")
+	refreshFlag := flag.Bool("refresh", false, "Ignore cached listings for this run and fetch them afresh")
 	dnsServersFlag := flag.String("dns-servers", "", "Comma-separated DNS servers for PTR lookups (e.g. 192.168.1.1,192.168.1.2)")
+	switchModelsFlag := flag.String("switch-models", "", "Comma-separated extra model prefixes to search as switches (e.g. CW91,MS990)")
 	webPortFlag := flag.String("web-port", "", "Port for web server (default: 8080)")
 	webHostFlag := flag.String("web-host", "", "Host for web server (default: localhost)")
+	accessLogFlag := flag.String("access-log", "", "Web access log in combined format: a file (rotated), - for stdout, or off (default: off)")
+	drainTimeoutFlag := flag.Duration("drain-timeout", 0, "How long the web server waits for running searches on SIGINT/SIGTERM (default: 5m)")
+	publicURLFlag := flag.String("public-url", "", "Base URL other devices reach the web server on, used in QR code links (default: the server's LAN address)")
+	allowCIDRFlag := flag.String("allow-cidr", "", "Comma-separated networks allowed to reach the web server, e.g. 10.20.0.0/16 (default: any; loopback is always allowed)")
 	testDataFlag := flag.Bool("test-data", false, "Launch web interface with sanitised demo data (no API key required)")
+	dhcpServerFlag := flag.Bool("dhcp-server", false, "Also report which DHCP server answers each found client's subnet and where it is attached")
+	hideUplinksFlag := flag.Bool("hide-uplinks", false, "Drop a MAC's hits on ports facing another switch (LLDP/CDP) when it was also found on an access port")
+	mapVirtualFlag := flag.Bool("map-virtual-macs", false, "Map VRRP virtual MACs to the network's MX warm-spare pair")
+	importPortNamesFlag := flag.String("import-port-names", "", "Apply switch port names from a serial,port,name CSV file")
+	dryRunFlag := flag.Bool("dry-run", false, "With --import-port-names, preview the changes without applying them")
+	historyRetentionFlag := flag.String("history-retention", "", "Prune history sightings last seen longer ago than this, e.g. 180d (default: keep forever)")
+	historyFileFlag := flag.String("history-file", "", "First-seen history database URL or JSON file (default sqlite://~/.find-mac-history.db, \"off\" to disable)")
+	anonymizeFlag := flag.Bool("anonymize", false, "Replace client MACs, IPs and hostnames in all output with per-run HMAC tokens")
+	portSecurityFlag := flag.Bool("port-security-report", false, "Report unrestricted access ports carrying more than one client")
+	verifyFlag := flag.String("verify", "", "Verify that the critical MACs in a mac,switch,port[,vlan[,name]] CSV are where expected")
+	verifyIntervalFlag := flag.String("verify-interval", "", "With --verify, re-verify this often (e.g. 15m) and alert on changes (default: once)")
+	preflightFlag := flag.Bool("preflight", false, "Before scanning, summarize the dashboard status of the switches in scope, skip offline ones and ask to continue")
+	identifySwitchFlag := flag.Bool("identify-switch", false, "Blink the LEDs of the switch(es) where the client was found")
+	connHistoryFlag := flag.String("history", "", "List the --mac client's logged connection events over this timespan, e.g. 7d, instead of searching")
+	roamingFlag := flag.Bool("roaming", false, "Also report recent wireless AP/SSID associations for each wired result")
+	qrFlag := flag.Bool("qr", false, "Also print a QR code of the results to stderr for sharing to a phone")
+	explainFlag := flag.Bool("explain", false, "Also print to stderr which API source supplied each field of every result")
+	notifyFlag := flag.Bool("notify", false, "Desktop notification when a long web search completes (interactive mode) or --verify alerts")
+	wakeFlag := flag.Bool("wake", false, "If nothing is found, ping the target's last known IP from the network's MX and retry the lookup once")
+	fullScanFlag := flag.Bool("full-scan", false, "With an exact --mac, scan every selected network instead of only those the organization client search has seen it in")
+	localProbeFlag := flag.Bool("local-probe", false, "With --ip, ARP/ND-probe the address from this host first so idle devices reappear in MAC tables")
+	emitOpenAPIFlag := flag.Bool("emit-openapi", false, "Print the OpenAPI spec of the web API and exit")
 	flag.Usage = func() {
 		printUsage(os.Stdout)
 	}
 	flag.Parse()
 
-	cfg := Config{
-		APIKey:       strings.TrimSpace(os.Getenv("MERAKI_API_KEY")),
-		OrgName:      strings.TrimSpace(firstNonEmpty(*orgFlag, os.Getenv("MERAKI_ORG"))),
-		NetworkName:  strings.TrimSpace(firstNonEmpty(*networkFlag, os.Getenv("MERAKI_NETWORK"))),
-		OutputFormat: strings.TrimSpace(firstNonEmpty(*outputFlag, os.Getenv("OUTPUT_FORMAT"))),
-		BaseURL:      strings.TrimSpace(firstNonEmpty(os.Getenv("MERAKI_BASE_URL"), "https://api.meraki.com/api/v1")),
-		MaxRetries:   firstNonZeroInt(*retryFlag, parseIntEnv("MERAKI_RETRIES"), 6),
-		MacTablePoll: firstNonZeroInt(*macPollFlag, parseIntEnv("MERAKI_MAC_POLL"), 15),
-		DNSServers:   strings.TrimSpace(firstNonEmpty(*dnsServersFlag, os.Getenv("DNS_SERVERS"))),
-		LogFile:      strings.TrimSpace(firstNonEmpty(*logFileFlag, os.Getenv("LOG_FILE"), "Find-Meraki-Ports-With-MAC.log")),
-		LogLevel:     strings.TrimSpace(firstNonEmpty(*logLevelFlag, os.Getenv("LOG_LEVEL"), "DEBUG")),
-		Verbose:      *verboseFlag,
-		SwitchFilter: strings.TrimSpace(*switchFlag),
-		PortFilter:   strings.TrimSpace(*portFlag),
-		TestFull:     *testFullTableFlag,
-		IPAddress:    strings.TrimSpace(*ipFlag),
-		MACAddress:   strings.TrimSpace(*macFlag),
-	}
-
-	// If verbose flag is set, override log level to DEBUG and send logs to console
-	if *verboseFlag {
-		cfg.LogLevel = "DEBUG"
-		cfg.LogFile = "" // Empty log file sends logs to console only
+	cfg, cfgErr := config.Load(config.Flags{
+		Org:           *orgFlag,
+		OrgID:         *orgIDFlag,
+		Network:       *networkFlag,
+		NetworkID:     *networkIDFlag,
+		OutputFormat:  *outputFlag,
+		Retry:         *retryFlag,
+		RateLimit:     *rateLimitFlag,
+		RetryStatuses: *retryStatusesFlag,
+		RetryElapsed:  *retryElapsedFlag,
+		Timeout:       *timeoutFlag,
+		VerifyFile:    *verifyFlag,
+		VerifyEvery:   *verifyIntervalFlag,
+		MacTablePoll:  *macPollFlag,
+		DNSServers:    *dnsServersFlag,
+		Proxy:         *proxyFlag,
+		CAFile:        *caFileFlag,
+		TLSMinVersion: *tlsMinFlag,
+		TLSInsecure:   *insecureFlag,
+		UserAgent:     *userAgentFlag,
+		RequestID:     *requestIDFlag,
+		CacheTTL:      *cacheTTLFlag,
+		Refresh:       *refreshFlag,
+		SwitchModels:  *switchModelsFlag,
+		LogFile:       *logFileFlag,
+		LogLevel:      *logLevelFlag,
+		Verbose:       *verboseFlag,
+		Switch:        *switchFlag,
+		DeviceTag:     *deviceTagFlag,
+		Shard:         *shardFlag,
+		Model:         *modelFlag,
+		Port:          *portFlag,
+		VLAN:          *vlanFlag,
+		PortMode:      *portModeFlag,
+		EntryType:     *entryTypeFlag,
+		DeviceTypes:   *deviceTypesFlag,
+		ExcludeSwitch: *excludeSwitchFlag,
+		ExcludePort:   *excludePortFlag,
+		TestFull:      *testFullTableFlag,
+		IP:            *ipFlag,
+		MAC:           macFlag.String(),
+		Hostname:      *hostnameFlag,
+		Vendor:        *vendorFlag,
+		OUIFile:       *ouiFileFlag,
+		MACRange:      *macRangeFlag,
+		ClientID:      *clientIDFlag,
+		Serial:        *serialFlag,
+		Query:         *queryFlag,
+		Notify:        *notifyFlag,
+		HistoryFile:   *historyFileFlag,
+		HistoryMaxAge: *historyRetentionFlag,
+		CSVDelimiter:  *csvDelimiterFlag,
+		CSVBOM:        *csvBOMFlag,
+		CSVQuoteAll:   *csvQuoteAllFlag,
+		CSVCRLF:       *csvCRLFFlag,
+		MaxResults:    *maxResultsFlag,
+	}, os.Getenv)
+
+	// If verbose flag is set, config.Load has already forced DEBUG to the console
+	if cfg.Verbose {
 		fmt.Printf("DEBUG: Verbose flag set, LogLevel=%s, LogFile='%s'\n", cfg.LogLevel, cfg.LogFile)
 	}
 
@@ -192,9 +345,26 @@ func main() {
 		meraki.SetDNSServers(strings.Split(cfg.DNSServers, ","))
 	}
 
+	// Route API requests through an explicit proxy, or none with "direct".
+	if cfg.Proxy != "" {
+		_ = meraki.SetProxy(cfg.Proxy) // validated by config.Load
+	}
+
+	// Treat extra model families as switches so new hardware is not skipped.
+	if cfg.SwitchModels != "" {
+		filters.SetExtraSwitchModels(strings.Split(cfg.SwitchModels, ","))
+	}
+
+	// Skip noisy core/aggregation switches and uplink ports.
+	if cfg.ExcludeSwitch != "" || cfg.ExcludePort != "" {
+		filters.SetExclusions(strings.Split(cfg.ExcludeSwitch, ","), strings.Split(cfg.ExcludePort, ","))
+	}
+
 	// Configure static IP→hostname overrides (for when internal DNS is unreachable).
 	if v := strings.TrimSpace(os.Getenv("HOST_OVERRIDES")); v != "" {
-		meraki.SetHostOverrides(v)
+		if err := meraki.SetHostOverrides(v); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: HOST_OVERRIDES ignored: %v\n", err)
+		}
 	}
 
 	if *helpFlag {
@@ -207,39 +377,106 @@ func main() {
 		return
 	}
 
+	if *emitOpenAPIFlag {
+		if err := writeOpenAPI(os.Stdout); err != nil {
+			exitWithError(nil, err.Error())
+		}
+		return
+	}
+
+	if cfgErr != nil {
+		exitWithError(nil, cfgErr.Error())
+	}
+	if err := meraki.SetTLS(tlsOptions(cfg)); err != nil {
+		exitWithError(nil, "MERAKI_CA_FILE: "+err.Error())
+	}
+	if cfg.TLSInsecure {
+		fmt.Fprintln(os.Stderr, "WARNING: Dashboard API certificates are not verified (--insecure-skip-verify)")
+	}
+	meraki.SetUserAgent(userAgent(cfg))
+
+	emitOpts := emitOptions{QR: *qrFlag, Explain: *explainFlag, Warnings: &output.Warnings{}}
+	var err error
+	if emitOpts.Template, err = loadRowTemplate(*outputTemplateFlag); err != nil {
+		exitWithError(nil, "--output-template: "+err.Error())
+	}
+	if emitOpts.Columns, err = output.ParseColumns(*columnsFlag); err != nil {
+		exitWithError(nil, "--columns: "+err.Error())
+	}
+	emitOpts.CSV = output.CSVOptions{BOM: cfg.CSVBOM, QuoteAll: cfg.CSVQuoteAll, CRLF: cfg.CSVCRLF}
+	if emitOpts.CSV.Delimiter, err = output.ParseCSVDelimiter(cfg.CSVDelimiter); err != nil {
+		exitWithError(nil, "--csv-delimiter: "+err.Error())
+	}
+	colorMode, err := output.ParseColorMode(*colorFlag)
+	if err != nil {
+		exitWithError(nil, "--color: "+err.Error())
+	}
+
 	// Handle interactive mode
 	if *interactiveFlag || *testDataFlag {
 		webTestDataMode = *testDataFlag
 		webPort := firstNonEmpty(*webPortFlag, os.Getenv("WEB_PORT"), "8080")
 		webHost := firstNonEmpty(*webHostFlag, os.Getenv("WEB_HOST"), "localhost")
-		startWebServer(cfg, webHost, webPort)
+		opts := webOptions{Host: webHost, Port: webPort}
+		if opts.Allow, err = parseAllowCIDRs(firstNonEmpty(*allowCIDRFlag, os.Getenv("WEB_ALLOW_CIDR"))); err != nil {
+			exitWithError(nil, "--allow-cidr: "+err.Error())
+		}
+		if opts.PublicURL, err = parsePublicURL(firstNonEmpty(*publicURLFlag, os.Getenv("WEB_PUBLIC_URL"))); err != nil {
+			exitWithError(nil, "--public-url: "+err.Error())
+		}
+		opts.AccessLog, err = newAccessLog(firstNonEmpty(*accessLogFlag, os.Getenv("WEB_ACCESS_LOG")),
+			firstNonZeroInt(parseIntEnv("WEB_ACCESS_LOG_MAX_MB"), defaultAccessLogMaxMB),
+			firstNonZeroInt(parseIntEnv("WEB_ACCESS_LOG_BACKUPS"), defaultAccessLogBackups))
+		if err != nil {
+			exitWithError(nil, "--access-log: "+err.Error())
+		}
+		opts.DrainTimeout = *drainTimeoutFlag
+		if opts.DrainTimeout == 0 {
+			if opts.DrainTimeout, err = time.ParseDuration(firstNonEmpty(os.Getenv("WEB_DRAIN_TIMEOUT"), defaultDrainTimeout.String())); err != nil {
+				exitWithError(nil, "WEB_DRAIN_TIMEOUT: "+err.Error())
+			}
+		}
+		// Started by the Windows service control manager, the server runs
+		// until the service is stopped and has no browser to open.
+		if isWindowsService() {
+			opts.NoBrowser = true
+			runService(func() { startWebServer(cfg, opts) })
+			return
+		}
+		startWebServer(cfg, opts)
 		return
 	}
 
 	log := logger.New(cfg.LogFile, logger.ParseLogLevel(cfg.LogLevel))
 
+	// With --anonymize, result rows are replaced before they are written and the
+	// free-text reports go through a scrubbing writer, all keyed by one per-run
+	// secret so the same client maps to the same token everywhere.
+	var anon *output.Anonymizer
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if *anonymizeFlag {
+		if anon, err = output.NewAnonymizer(); err != nil {
+			exitWithError(log, err.Error())
+		}
+		stdout, stderr = anon.Writer(os.Stdout), anon.Writer(os.Stderr)
+	}
+
 	if cfg.APIKey == "" {
 		exitWithError(log, "MERAKI_API_KEY is required — set it in "+envFile+" or as an environment variable")
 	}
+	networkGiven := cfg.NetworkName != ""
 	if cfg.NetworkName == "" {
 		cfg.NetworkName = "ALL"
 	}
-	if cfg.OutputFormat == "" {
-		cfg.OutputFormat = "csv"
-	}
-
-	cfg.OutputFormat = strings.ToLower(cfg.OutputFormat)
-	if cfg.OutputFormat != "csv" && cfg.OutputFormat != "text" && cfg.OutputFormat != "html" {
-		exitWithError(log, "--output-format must be one of: csv, text, html")
-	}
-
-	client := meraki.NewClient(cfg.APIKey, cfg.BaseURL, cfg.MaxRetries)
-	ctx := context.Background()
+	client := newAPIClient(cfg, cfg.APIKey, log)
+	requestID := firstNonEmpty(cfg.RequestID, meraki.NewRequestID())
+	log.Infof("API calls are sent with X-Request-Id %s-<n>", requestID)
+	ctx := meraki.WithRequestID(context.Background(), requestID)
 
 	if *testAPIFlag {
 		orgs, err := client.GetOrganizations(ctx)
 		if err != nil {
-			exitWithError(log, err.Error())
+			exitWithError(log, describeAPIError(err))
 		}
 		_, _ = fmt.Fprintf(os.Stdout, "API OK: %d organizations found\n", len(orgs))
 		return
@@ -248,7 +485,7 @@ func main() {
 	if *listOrgsFlag {
 		orgs, err := client.GetOrganizations(ctx)
 		if err != nil {
-			exitWithError(log, err.Error())
+			exitWithError(log, describeAPIError(err))
 		}
 		writeOrganizations(os.Stdout, orgs)
 		return
@@ -257,7 +494,7 @@ func main() {
 	if *listNetworksFlag {
 		orgs, err := client.GetOrganizations(ctx)
 		if err != nil {
-			exitWithError(log, err.Error())
+			exitWithError(log, describeAPIError(err))
 		}
 		if cfg.OrgName != "" {
 			org, err := selectOrganization(cfg.OrgName, orgs)
@@ -285,54 +522,230 @@ func main() {
 		log.Debugf("Test full table mode enabled")
 	}
 
-	// Validate mutual exclusivity of --ip and --mac
-	if cfg.IPAddress != "" && strings.TrimSpace(*macFlag) != "" {
-		exitWithError(log, "--ip and --mac are mutually exclusive")
+	// Started from a terminal without a target, ask for it (and below for the
+	// organization and network) instead of failing.
+	var prompt *prompter
+	var promptMemory *promptCache
+	if cfg.IPAddress == "" && cfg.MACAddress == "" && cfg.MACRange == "" && cfg.Hostname == "" && cfg.Vendor == "" && cfg.ClientID == "" && cfg.Serial == "" && cfg.Query == "" {
+		if !cfg.TestFull && !*portSecurityFlag && *importPortNamesFlag == "" && cfg.VerifyFile == "" {
+			if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+				exitWithError(log, "--ip, --mac, --mac-range, --hostname, --vendor, --client-id, --serial or --query is required (or use --interactive to launch the web interface)")
+			}
+			prompt = newPrompter(os.Stdin, os.Stderr)
+			promptMemory = loadPromptCache(promptCachePath())
+			var target string
+			for target == "" {
+				if target, err = prompt.ask("MAC, IP, subnet or hostname to find", ""); err != nil {
+					exitWithError(log, err.Error())
+				}
+			}
+			switch classifyTarget(target) {
+			case "ip":
+				cfg.IPAddress = target
+			case "mac":
+				cfg.MACAddress = target
+			default:
+				cfg.Hostname = target
+			}
+		}
 	}
 
-	if cfg.IPAddress == "" && strings.TrimSpace(*macFlag) == "" {
-		if !cfg.TestFull {
-			exitWithError(log, "--ip or --mac is required (or use --interactive to launch the web interface)")
+	// --history reads the event log for one client instead of searching.
+	var historyMAC string
+	var historyWindow time.Duration
+	if *connHistoryFlag != "" {
+		if historyWindow, err = history.ParseSince(*connHistoryFlag); err != nil || historyWindow == 0 {
+			exitWithError(log, fmt.Sprintf("--history: invalid timespan %q (use e.g. 24h or 7d)", *connHistoryFlag))
 		}
+		norm, err := macaddr.NormalizeExactMac(cfg.MACAddress)
+		if err != nil {
+			exitWithError(log, "--history needs a single exact --mac")
+		}
+		historyMAC = macaddr.FormatMacColon(norm)
 	}
 
-	// Get organizations first
-	orgs, err := client.GetOrganizations(ctx)
-	if err != nil {
-		exitWithError(log, err.Error())
+	// --org-id skips the organization lookup entirely; the name is unknown, so
+	// the ID stands in for it in results.
+	org := meraki.Organization{ID: cfg.OrgID, Name: cfg.OrgID}
+	if cfg.OrgID == "" {
+		orgs, err := client.GetOrganizations(ctx)
+		if err != nil {
+			exitWithError(log, describeAPIError(err))
+		}
+
+		// Handle single organization auto-selection.
+		// When the API key is scoped to exactly one org, use it unconditionally.
+		// If an org name was specified but doesn't match, log a warning and continue.
+		if len(orgs) == 1 {
+			if cfg.OrgName != "" && cfg.OrgName != orgs[0].Name {
+				log.Debugf("Org name %q not matched; auto-selecting only available organization: %s", cfg.OrgName, orgs[0].Name)
+			}
+			cfg.OrgName = orgs[0].Name
+			log.Debugf("Auto-selected single organization: %s", cfg.OrgName)
+		}
+		if prompt != nil && cfg.OrgName == "" {
+			names := make([]string, len(orgs))
+			for i, o := range orgs {
+				names[i] = o.Name
+			}
+			if cfg.OrgName, err = prompt.choose("Organization", names, knownOr(promptMemory.Org, names, "")); err != nil {
+				exitWithError(log, err.Error())
+			}
+			promptMemory.Org = cfg.OrgName
+			promptMemory.save(promptCachePath())
+		}
+
+		org, err = selectOrganization(cfg.OrgName, orgs)
+		if err != nil {
+			exitWithError(log, err.Error())
+		}
 	}
+	log.Debugf("Organization: %s", org.Name)
 
-	// Handle single organization auto-selection.
-	// When the API key is scoped to exactly one org, use it unconditionally.
-	// If an org name was specified but doesn't match, log a warning and continue.
-	if len(orgs) == 1 {
-		if cfg.OrgName != "" && cfg.OrgName != orgs[0].Name {
-			log.Debugf("Org name %q not matched; auto-selecting only available organization: %s", cfg.OrgName, orgs[0].Name)
+	if *importPortNamesFlag != "" {
+		f, err := os.Open(*importPortNamesFlag)
+		if err != nil {
+			exitWithError(log, err.Error())
 		}
-		cfg.OrgName = orgs[0].Name
-		log.Debugf("Auto-selected single organization: %s", cfg.OrgName)
+		defer func() { _ = f.Close() }()
+		if err := importPortNames(ctx, os.Stdout, client, org.ID, f, *dryRunFlag, log); err != nil {
+			exitWithError(log, err.Error())
+		}
+		return
 	}
 
-	org, err := selectOrganization(cfg.OrgName, orgs)
-	if err != nil {
-		exitWithError(log, err.Error())
+	// A query's network= condition picks the networks unless --network did.
+	var plan *queryPlan
+	if cfg.Query != "" {
+		q, err := query.Parse(cfg.Query)
+		if err != nil {
+			exitWithError(log, "--query: "+err.Error())
+		}
+		reg, err := vendorRegistry(cfg.OUIFile)
+		if err != nil {
+			exitWithError(log, fmt.Sprintf("Failed to load OUI registry: %v", err))
+		}
+		if plan, err = planQuery(q, reg); err != nil {
+			exitWithError(log, "--query: "+err.Error())
+		}
+	}
+	networkName := cfg.NetworkName
+	if plan != nil && plan.network != "" && strings.EqualFold(networkName, "ALL") {
+		networkName = plan.network
 	}
-	log.Debugf("Organization: %s", org.Name)
 
-	networks, err := client.GetNetworks(ctx, org.ID)
-	if err != nil {
-		exitWithError(log, err.Error())
+	selectedNetworks := networksByID(cfg.NetworkID)
+	if len(selectedNetworks) == 0 {
+		networks, err := client.GetNetworks(ctx, org.ID)
+		if err != nil {
+			exitWithError(log, describeAPIError(err))
+		}
+		if prompt != nil && !networkGiven && len(networks) > 1 {
+			names := make([]string, len(networks))
+			for i, n := range networks {
+				names[i] = n.Name
+			}
+			if networkName, err = prompt.choose("Network (number, name or ALL)", names, knownOr(promptMemory.Networks[org.Name], names, "ALL"), "ALL"); err != nil {
+				exitWithError(log, err.Error())
+			}
+			promptMemory.Networks[org.Name] = networkName
+			promptMemory.save(promptCachePath())
+		}
+		selectedNetworks, err = selectNetworks(networkName, networks)
+		if err != nil {
+			exitWithError(log, err.Error())
+		}
 	}
 
-	selectedNetworks, err := selectNetworks(cfg.NetworkName, networks)
-	if err != nil {
-		exitWithError(log, err.Error())
+	if cfg.VerifyFile != "" {
+		f, err := os.Open(cfg.VerifyFile)
+		if err != nil {
+			exitWithError(log, err.Error())
+		}
+		list, err := parseWatchlist(f)
+		_ = f.Close()
+		if err != nil {
+			exitWithError(log, fmt.Sprintf("%s: %v", cfg.VerifyFile, err))
+		}
+		interval, _ := time.ParseDuration(cfg.VerifyEvery) // validated by config.Load; "" verifies once
+		verifyCtx, stop := searchContext(ctx, "")
+		err = runVerify(verifyCtx, stdout, client, selectedNetworks, list, interval, cfg.Timeout, cfg.MacTablePoll, cfg.Notify, log)
+		stop()
+		if err != nil {
+			exitWithError(log, err.Error())
+		}
+		return
+	}
+
+	// From here on --timeout and Ctrl+C stop the scan; prompts above are not timed.
+	ctx, cancel := searchContext(ctx, cfg.Timeout)
+	defer cancel()
+
+	if *portSecurityFlag {
+		reportPortSecurity(ctx, stdout, client, selectedNetworks, cfg.MacTablePoll, log)
+		return
+	}
+
+	if historyMAC != "" {
+		reportConnectionHistory(ctx, stdout, client, selectedNetworks, historyMAC, *connHistoryFlag, historyWindow, log)
+		exitIfAborted(ctx, cfg.Timeout, log)
+		return
+	}
+
+	resultFile := openResultFile(*outputFileFlag, log)
+	emitOpts.Out = os.Stdout
+	if resultFile != nil {
+		emitOpts.Out = resultFile
+	}
+	emitOpts.Color = useColor(colorMode, emitOpts.Out, os.Getenv)
+
+	if cfg.ClientID != "" {
+		if err := emitResults(cfg, anonymizeRows(anon, excludeRows(lookupClientID(ctx, client, org, selectedNetworks, cfg.ClientID, log))), emitOpts, log); err != nil {
+			exitWithError(log, err.Error())
+		}
+		commitResultFile(resultFile, log)
+		exitIfAborted(ctx, cfg.Timeout, log)
+		return
+	}
+
+	if cfg.Serial != "" {
+		rows, err := listSwitchClients(ctx, client, org, selectedNetworks, cfg.Serial, cfg.PortFilter, cfg.MacTablePoll, log)
+		if err != nil {
+			exitWithError(log, err.Error())
+		}
+		rows = slices.DeleteFunc(rows, func(r output.ResultRow) bool { return !filters.MatchesEntryTypeFilter(r.EntryType, cfg.EntryType) })
+		if err := emitResults(cfg, anonymizeRows(anon, excludeRows(rows)), emitOpts, log); err != nil {
+			exitWithError(log, err.Error())
+		}
+		commitResultFile(resultFile, log)
+		exitIfAborted(ctx, cfg.Timeout, log)
+		return
+	}
+
+	// Make an idle device talk before its switch's MAC table is read.
+	if *localProbeFlag {
+		if cfg.IPAddress == "" {
+			log.Warnf("--local-probe only applies to --ip; ignored")
+		} else {
+			localProbe(cfg.IPAddress, log)
+		}
 	}
 
 	matcher := func(string) bool { return true }
 	var resolvedHostname string
 
-	if cfg.IPAddress != "" {
+	if isSubnet(cfg.IPAddress) {
+		// Subnet sweep: locate every client of the prefix in one MAC table scan.
+		macs, err := sweepSubnetMACs(ctx, client, selectedNetworks, cfg.IPAddress, log)
+		if err != nil {
+			exitWithError(log, err.Error())
+		}
+		matcher, _, _, err = macaddr.BuildMultiMacMatcher(strings.Join(macs, ","))
+		if err != nil {
+			exitWithError(log, err.Error())
+		}
+
+	} else if cfg.IPAddress != "" {
 		// IP resolution mode
 		log.Debugf("Resolving IP: %s", cfg.IPAddress)
 
@@ -350,342 +763,882 @@ func main() {
 			exitWithError(log, err.Error())
 		}
 
-	} else if strings.TrimSpace(*macFlag) != "" {
+	} else if cfg.Hostname != "" {
+		// Hostname mode: forward-resolve, then resolve every address like --ip
+		macs, err := resolveHostnameMACs(ctx, client, org.ID, selectedNetworks, cfg.Hostname, log)
+		if err != nil {
+			exitWithError(log, err.Error())
+		}
+		matcher, _, _, err = macaddr.BuildMultiMacMatcher(strings.Join(macs, ","))
+		if err != nil {
+			exitWithError(log, err.Error())
+		}
+
+	} else if cfg.MACRange != "" {
+		// MAC range mode: every address in an allocation block
+		var err error
+		if matcher, err = macaddr.BuildMacRangeMatcher(cfg.MACRange); err != nil {
+			exitWithError(log, err.Error())
+		}
+		log.Debugf("MAC range: %s", cfg.MACRange)
+
+	} else if plan != nil {
+		// Query mode: scan the MACs its required conditions allow; the whole
+		// query filters the results below.
+		matcher = plan.matcher
+		log.Debugf("Query: %s", cfg.Query)
+
+	} else if cfg.Vendor != "" {
+		// Vendor mode: every MAC in the vendor's OUI blocks
+		reg, err := vendorRegistry(cfg.OUIFile)
+		if err != nil {
+			exitWithError(log, fmt.Sprintf("Failed to load OUI registry: %v", err))
+		}
+		var blocks []oui.Assignment
+		if matcher, blocks, err = vendorMatcher(reg, cfg.Vendor); err != nil {
+			exitWithError(log, err.Error())
+		}
+		log.Infof("Vendor %q matches %d OUI block(s) of %s", cfg.Vendor, len(blocks), strings.Join(vendorNames(blocks), "; "))
+
+	} else if cfg.MACAddress != "" {
 		// MAC mode (existing logic)
-		var normalized string
+		// Several comma-separated MACs/patterns are matched as a union in one scan.
+		var normalized []string
 		var isWildcard bool
 		var err error
-		matcher, normalized, isWildcard, err = macaddr.BuildMacMatcher(*macFlag)
+		matcher, normalized, isWildcard, err = macaddr.BuildMultiMacMatcher(cfg.MACAddress)
 		if err != nil {
 			exitWithError(log, err.Error())
 		}
 		if isWildcard {
-			log.Debugf("MAC pattern: %s", strings.TrimSpace(*macFlag))
+			log.Debugf("MAC pattern: %s", cfg.MACAddress)
 		} else {
-			log.Debugf("MAC: %s", normalized)
+			log.Debugf("MAC: %s", strings.Join(normalized, ", "))
+			if !*fullScanFlag {
+				selectedNetworks = narrowByClientSearch(ctx, client, org.ID, selectedNetworks, normalized, log)
+			}
 		}
 	}
 
 	var results []output.ResultRow
 	resultsIndex := make(map[string]struct{})
-	var cliAggrCache map[string]map[string][]string
-	for _, net := range selectedNetworks {
-		log.Debugf("Network: %s", net.Name)
-
-		// Get all devices for this network
-		devices, err := client.GetDevices(ctx, net.ID)
-		if err != nil {
-			exitWithError(log, err.Error())
+	// With jsonl output each new row is written as soon as it is found instead
+	// of being buffered and sorted at the end, so long scans show progress.
+	// Query results are only known after scoring, so they are not streamed.
+	// --hide-uplinks needs every row of a MAC before it can drop any.
+	streaming := cfg.OutputFormat == "jsonl" && emitOpts.Template == nil && plan == nil && !*hideUplinksFlag
+	// Every new row is also recorded in the history file so MACs never seen
+	// before in the network can be flagged.
+	hist := openHistory(resolveHistoryFile(cfg.HistoryFile), log)
+	scanTime := time.Now()
+	uplinks := newNeighborUplinks(ctx, client)
+	statuses := newPortStatuses(ctx, client)
+	radios := newWirelessRadios(ctx, client)
+	leases := newDHCPLeases(ctx, client)
+	// A few networks are listed by ID; for more, the organization-wide
+	// listing is cheaper than a URL naming them all.
+	var statusNetworkIDs []string
+	if len(selectedNetworks) <= 10 {
+		for _, n := range selectedNetworks {
+			statusNetworkIDs = append(statusNetworkIDs, n.ID)
 		}
-
-		// Build device lookup map
-		deviceBySerial := make(map[string]meraki.Device)
-		for _, dev := range devices {
-			deviceBySerial[dev.Serial] = dev
+	}
+	devStatuses := newDeviceStatuses(ctx, client, org.ID, statusNetworkIDs)
+	hardware := newDeviceHardware()
+	recordResult := func(row output.ResultRow) {
+		if isRowExcluded(row) {
+			return
+		}
+		uplinks.mark(&row)
+		statuses.fill(&row)
+		uplinks.fillNeighbor(&row)
+		leases.fill(&row)
+		devStatuses.fill(&row)
+		hardware.fill(&row)
+		if !addResult(resultsIndex, &results, row) {
+			return
+		}
+		added := &results[len(results)-1]
+		observeResult(hist, added, scanTime)
+		// Past --max-results rows are still counted for the truncation
+		// warning but no longer streamed.
+		if !streaming || (cfg.MaxResults > 0 && len(results) > cfg.MaxResults) {
+			return
+		}
+		out := *added
+		out.Note = joinNotes(virtualMACNote(out.MAC), rowNote(out))
+		if anon != nil {
+			out = anon.Row(out)
+		}
+		if err := output.WriteJSONLRow(emitOpts.Out, out); err != nil {
+			exitWithError(log, "writing jsonl output: "+err.Error())
+		}
+	}
+	var deviceTags []string
+	if cfg.DeviceTag != "" {
+		deviceTags = strings.Split(cfg.DeviceTag, ",")
+	}
+	var models []string
+	if cfg.Model != "" {
+		models = strings.Split(cfg.Model, ",")
+	}
+	shard, _ := filters.ParseShard(cfg.Shard) // validated by config.Load
+	// --device-types: nil searches switches only, as before the flag existed.
+	deviceTypes, _ := filters.ParseDeviceTypes(cfg.DeviceTypes) // validated by config.Load
+	otherTypes := slices.DeleteFunc(slices.Clone(deviceTypes), func(t string) bool { return t == "switch" })
+	// searchScope narrows a network's devices to the switches, and the access
+	// points and appliances, that the filters select for searching.
+	searchScope := func(network string, devices []meraki.Device) (switches, others []meraki.Device) {
+		// Filter to switches only, unless --device-types says otherwise
+		switches = filters.FilterSwitches(devices)
+		if deviceTypes != nil && !slices.Contains(deviceTypes, "switch") {
+			switches = nil
 		}
-
-		// Filter to switches only
-		switches := filters.FilterSwitches(devices)
 		switches = filters.FilterSwitchesByName(switches, cfg.SwitchFilter)
-
-		// Fetch topology to identify true uplink ports; failure is non-fatal.
-		// Pre-populate AGGR cache from network-level link aggregations API (reliable source for AGGR/N membership).
-		cliAggrCache = client.GetNetworkLinkAggregations(ctx, net.ID)
-		// Build uplink set using LLDP/CDP per switch (topology API lacks port IDs on this firmware).
-		cliUplinkPortCache := make(map[string]map[string]struct{})
-		cliGetUplinkPorts := func(serial string) map[string]struct{} {
-			if _, ok := cliUplinkPortCache[serial]; !ok {
-				cliUplinkPortCache[serial] = client.GetDeviceUplinkPorts(ctx, serial)
-			}
-			return cliUplinkPortCache[serial]
+		switches = filters.FilterSwitchesByTag(switches, deviceTags)
+		switches = filters.FilterSwitchesByModel(switches, models)
+		if shard.Count > 1 {
+			all := len(switches)
+			switches = filters.FilterSwitchesByShard(switches, shard)
+			log.Debugf("Shard %d/%d: searching %d of %d switches in %s", shard.Index, shard.Count, len(switches), all, network)
 		}
-
-		// Query network-level clients
-		networkClients, err := client.GetNetworkClients(ctx, net.ID)
-		if err != nil {
-			exitWithError(log, err.Error())
+		switches = filters.ExcludeSwitches(switches)
+		// Access points and appliances are narrowed down like the switches.
+		// Without --device-types, the appliances of switchless branches
+		// are searched so their devices are not missed.
+		others = filters.FilterDevicesByType(devices, otherTypes)
+		if deviceTypes == nil {
+			others = branchAppliances(devices)
 		}
-		log.Debugf("Network clients API returned %d clients", len(networkClients))
-
-		// Build MAC→IP/hostname/lastSeen maps for enriching results from live table / device clients.
-		macToIP := make(map[string]string, len(networkClients))
-		macToLastSeen := make(map[string]string, len(networkClients))
-		macToHostname := make(map[string]string, len(networkClients))
-		for _, nc := range networkClients {
-			norm, err2 := macaddr.NormalizeExactMac(nc.MAC)
-			if err2 != nil {
-				continue
-			}
-			if nc.IP != "" {
-				macToIP[norm] = nc.IP
-			}
-			if nc.LastSeen != "" {
-				if existing := macToLastSeen[norm]; existing == "" || nc.LastSeen > existing {
-					macToLastSeen[norm] = nc.LastSeen
+		others = filters.FilterSwitchesByName(others, cfg.SwitchFilter)
+		others = filters.FilterSwitchesByTag(others, deviceTags)
+		others = filters.FilterSwitchesByModel(others, models)
+		others = filters.ExcludeSwitches(filters.FilterSwitchesByShard(others, shard))
+		return switches, others
+	}
+	// --preflight checks the dashboard status of everything in scope first,
+	// and may stop here; the device lists it reads are not fetched again.
+	var pre *preflight
+	networkDevices := make(map[string][]meraki.Device)
+	// Several networks are listed in one organization-wide request rather
+	// than one per network; if that fails, each is listed on its own.
+	if len(selectedNetworks) > 1 {
+		if byNetwork, err := devicesByNetwork(ctx, client, org.ID, selectedNetworks, statusNetworkIDs); err == nil {
+			networkDevices = byNetwork
+		} else if ctx.Err() == nil {
+			log.Warnf("Listing organization devices failed, listing each network instead: %v", err)
+		}
+	}
+	if *preflightFlag {
+		pre = newPreflight()
+		for _, net := range selectedNetworks {
+			devices, ok := networkDevices[net.ID]
+			if !ok {
+				var err error
+				if devices, err = client.GetDevices(ctx, net.ID); err != nil {
+					if ctx.Err() != nil {
+						break
+					}
+					exitWithError(log, err.Error())
 				}
+				networkDevices[net.ID] = devices
 			}
-			if hn := meraki.ClientHostname(nc); hn != "" {
-				macToHostname[norm] = hn
+			switches, others := searchScope(net.Name, devices)
+			pre.add(net.Name, append(switches, others...), devStatuses)
+		}
+		pre.write(stderr)
+		if isTerminal(os.Stdin) && isTerminal(os.Stderr) && ctx.Err() == nil {
+			if prompt == nil {
+				prompt = newPrompter(os.Stdin, os.Stderr)
+			}
+			if !confirmScan(prompt, resultFile) {
+				return
 			}
 		}
-
-		// ipAndHostname returns the IP (and reverse-DNS hostname in MAC mode) for a
-		// normalized MAC. If not found in macToIP, performs a live ARP table lookup
-		// on the switch (serial) where the MAC was found, caching results per switch.
-		// In IP mode the hostname is already in resolvedHostname.
-		serialArpCache := make(map[string]map[string]string)
-		ipAndHostname := func(normMAC, knownIP, serial string) (string, string) {
-			ip := knownIP
-			if ip == "" {
-				ip = macToIP[normMAC]
+	}
+	var cliAggrCache map[string]map[string][]string
+	// scanNetworks reads every selected network once; --wake may run it again.
+	scanNetworks := func() {
+		for _, net := range selectedNetworks {
+			if ctx.Err() != nil {
+				return
 			}
-			// Fallback: live ARP table lookup on the specific switch
-			if ip == "" && serial != "" {
-				if _, cached := serialArpCache[serial]; !cached {
-					serialArpCache[serial] = client.FetchArpMap(ctx, serial, cfg.MacTablePoll)
+			log.Debugf("Network: %s", net.Name)
+
+			// Get all devices for this network
+			devices, ok := networkDevices[net.ID]
+			if !ok {
+				var err error
+				if devices, err = client.GetDevices(ctx, net.ID); err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					exitWithError(log, err.Error())
 				}
-				ip = serialArpCache[serial][normMAC]
 			}
-			hn := resolvedHostname // pre-set in IP mode
-			if hn == "" {
-				hn = macToHostname[normMAC]
+
+			// Build device lookup map
+			deviceBySerial := make(map[string]meraki.Device)
+			for _, dev := range devices {
+				deviceBySerial[dev.Serial] = dev
 			}
-			if hn == "" && ip != "" {
-				if hn = meraki.LookupHostOverride(ip, org.Name, net.Name); hn == "" {
-					hn, _ = meraki.ResolveHostname(ip)
-				}
+			hardware.add(devices)
+			for _, w := range unsupportedDeviceWarnings(net.Name, devices) {
+				emitOpts.Warnings.Add(w)
 			}
-			return ip, hn
-		}
 
-		for _, c := range networkClients {
-			normMAC, err := macaddr.NormalizeExactMac(c.MAC)
+			switches, others := searchScope(net.Name, devices)
+			if pre != nil {
+				switches, others = pre.without(switches), pre.without(others)
+			}
+			for _, w := range devStatuses.offlineWarnings(net.Name, append(slices.Clone(switches), others...)) {
+				emitOpts.Warnings.Add(w)
+			}
+
+			// Fetch topology to identify true uplink ports; failure is non-fatal.
+			// Pre-populate AGGR cache from network-level link aggregations API (reliable source for AGGR/N membership).
+			cliAggrCache = client.GetNetworkLinkAggregations(ctx, net.ID)
+			// Build uplink set from the switch port statuses (topology API lacks port IDs on this firmware).
+			cliGetUplinkPorts := statuses.uplinks
+
+			// Query network-level clients
+			networkClients, err := client.GetNetworkClients(ctx, net.ID)
 			if err != nil {
-				continue
+				if ctx.Err() != nil {
+					return
+				}
+				exitWithError(log, err.Error())
 			}
-			if matcher(normMAC) {
-				serial := strings.TrimSpace(c.RecentDeviceSerial)
-				if serial == "" {
+			log.Debugf("Network clients API returned %d clients", len(networkClients))
+			leases.addNetwork(net, networkClients)
+
+			// Build MAC→IP/hostname/lastSeen maps for enriching results from live table / device clients.
+			macToIP := make(map[string]string, len(networkClients))
+			macToLastSeen := make(map[string]string, len(networkClients))
+			macToHostname := make(map[string]string, len(networkClients))
+			macToSSID := make(map[string]string)
+			macToClientID := make(map[string]string) // wireless clients only, for their band and RSSI
+			for _, nc := range networkClients {
+				norm, err2 := macaddr.NormalizeExactMac(nc.MAC)
+				if err2 != nil {
 					continue
 				}
-
-				dev := deviceBySerial[serial]
-				switchName := firstNonEmpty(dev.Name, c.RecentDeviceName, serial)
-
-				if !filters.MatchesSwitchFilter(switchName, cfg.SwitchFilter) {
-					if cfg.Verbose {
-						log.Debugf("Network client %s filtered out by switch filter (switch=%s, filter=%s)",
-							macaddr.FormatMacColon(normMAC), switchName, cfg.SwitchFilter)
+				if nc.IP != "" {
+					macToIP[norm] = nc.IP
+				}
+				if nc.LastSeen != "" {
+					if existing := macToLastSeen[norm]; existing == "" || nc.LastSeen > existing {
+						macToLastSeen[norm] = nc.LastSeen
 					}
-					continue
 				}
-
-				port := firstNonEmpty(c.SwitchportName, c.Switchport, c.Port, "unknown")
-				if !filters.MatchesPortFilter(port, cfg.PortFilter) {
-					continue
+				if hn := meraki.ClientHostname(nc); hn != "" {
+					macToHostname[norm] = hn
 				}
-
-				if cfg.Verbose {
-					log.Debugf("Adding network client %s on %s port %s", macaddr.FormatMacColon(normMAC), switchName, port)
+				if nc.SSID != "" {
+					macToSSID[norm] = nc.SSID
+					macToClientID[norm] = nc.ID
 				}
-
-				aggrMembers := resolveAggrPorts(ctx, client, serial, port, cliAggrCache)
-				vlan, portMode := enrichPortInfoWithMembers(ctx, client, serial, port, aggrMembers, 0, "")
-
-				ip, hn := ipAndHostname(normMAC, c.IP, serial)
-				addResult(resultsIndex, &results, output.ResultRow{
-					OrgName:      org.Name,
-					NetworkName:  net.Name,
-					SwitchName:   switchName,
-					SwitchSerial: serial,
-					Port:         port,
-					AggrPorts:    aggrMembers,
-					MAC:          macaddr.FormatMacColon(normMAC),
-					IP:           ip,
-					Hostname:     hn,
-					LastSeen:     firstNonEmpty(c.LastSeen, macToLastSeen[normMAC]),
-					VLAN:         vlan,
-					PortMode:     portMode,
-					IsUplink:     isPortUplink(port, aggrMembers, cliGetUplinkPorts(serial)),
-				})
 			}
-		}
 
-		// Query device-level clients for each switch
-		for _, dev := range switches {
-			log.Debugf("Querying switch: %s (%s)", firstNonEmpty(dev.Name, dev.Serial), dev.Serial)
+			// ipAndHostname returns the IP (and reverse-DNS hostname in MAC mode) for a
+			// normalized MAC. If not found in macToIP, performs a live ARP table lookup
+			// on the switch (serial) where the MAC was found, caching results per switch.
+			// In IP mode the hostname is already in resolvedHostname.
+			serialArpCache := make(map[string]map[string]string)
+			// ipAndHostname also returns where each value came from, for --explain.
+			ipAndHostname := func(normMAC, knownIP, serial string) (string, string, []output.FieldSource) {
+				ip, ipFrom, ipDetail := knownIP, srcNetworkClients, ""
+				if ip == "" {
+					ip = macToIP[normMAC]
+				}
+				// Fallback: live ARP table lookup on the specific switch
+				if ip == "" && serial != "" {
+					if _, cached := serialArpCache[serial]; !cached {
+						serialArpCache[serial] = client.FetchArpMap(ctx, serial, cfg.MacTablePoll)
+					}
+					ip, ipFrom, ipDetail = serialArpCache[serial][normMAC], srcLiveArpTable, serial
+				}
+				hn, hnFrom := resolvedHostname, srcIPLookup // pre-set in IP mode
+				if hn == "" {
+					hn, hnFrom = macToHostname[normMAC], srcNetworkClients
+				}
+				if hn == "" && ip != "" {
+					if hn, hnFrom = meraki.LookupHostOverride(ip, org.Name, net.Name), srcHostOverride; hn == "" {
+						hn, _ = meraki.ResolveHostname(ip)
+						hnFrom = srcReverseDNS
+					}
+				}
+				return ip, hn, hostSources(ip, ipFrom, ipDetail, hn, hnFrom)
+			}
 
-			// Try live tools MAC table lookup first (works for all switches including Catalyst)
-			macTableID, err := client.CreateMacTableLookup(ctx, dev.Serial)
-			if err == nil && macTableID != "" {
-				if cfg.Verbose {
-					log.Debugf("Created MAC table lookup job %s for %s", macTableID, dev.Serial)
+			for _, c := range networkClients {
+				normMAC, err := macaddr.NormalizeExactMac(c.MAC)
+				if err != nil {
+					continue
 				}
+				if matcher(normMAC) {
+					serial := strings.TrimSpace(c.RecentDeviceSerial)
+					if serial == "" {
+						continue
+					}
 
-				// Poll for results (max 30 seconds)
-				var macEntries []map[string]interface{}
-				var status string
-				for attempt := 0; attempt < cfg.MacTablePoll; attempt++ {
-					time.Sleep(2 * time.Second)
-					macEntries, status, err = client.GetMacTableLookup(ctx, dev.Serial, macTableID)
-					if err != nil {
+					dev := deviceBySerial[serial]
+					switchName := firstNonEmpty(dev.Name, c.RecentDeviceName, serial)
+
+					if !filters.MatchesSwitchFilter(switchName, cfg.SwitchFilter) {
 						if cfg.Verbose {
-							log.Debugf("Error getting MAC table lookup for %s (%s) in network %s: %v",
-								firstNonEmpty(dev.Name, dev.Serial), dev.Serial, net.Name, err)
+							log.Debugf("Network client %s filtered out by switch filter (switch=%s, filter=%s)",
+								macaddr.FormatMacColon(normMAC), switchName, cfg.SwitchFilter)
 						}
-						break
+						continue
 					}
-					if status == "complete" {
-						break
+
+					if !filters.HasAnyTag(dev, deviceTags) || !filters.MatchesModel(dev.Model, models) || !shard.Contains(serial) {
+						continue
+					}
+					devType := filters.DeviceType(dev)
+					if isWirelessClient(c, dev) {
+						devType = "wireless"
 					}
+					if deviceTypes != nil && !slices.Contains(deviceTypes, devType) {
+						continue
+					}
+					// A wireless or appliance client is placed on its access
+					// point or appliance even in a switch-only search, instead
+					// of on a switch port that does not exist.
+					if devType == "wireless" || devType == "appliance" || (deviceTypes != nil && devType != "switch") {
+						row, ok := nonSwitchResult(dev, devType, normMAC, firstNonEmpty(c.SwitchportName, c.Switchport, c.Port), c.SSID, cfg)
+						if !ok {
+							continue
+						}
+						row.OrgName, row.NetworkName, row.SwitchName = org.Name, net.Name, switchName
+						row.LastSeen = firstNonEmpty(c.LastSeen, macToLastSeen[normMAC])
+						ip, hn, hostWhy := ipAndHostname(normMAC, c.IP, "")
+						row.IP, row.Hostname, row.Source = ip, hn, output.SourceNetworkClients
+						row.Explain = explainRow(row, output.FieldSource{Source: srcNetworkClients, Detail: "recent device " + serial}, nil, hostWhy, srcNetworkClients)
+						if devType == "wireless" {
+							radios.fill(&row, net.ID, c.ID)
+						}
+						recordResult(row)
+						continue
+					}
+
+					port := firstNonEmpty(c.SwitchportName, c.Switchport, c.Port, "unknown")
+					if !filters.MatchesPortFilter(port, cfg.PortFilter) {
+						continue
+					}
+
 					if cfg.Verbose {
-						log.Debugf("MAC table lookup status for %s (%s) in network %s: %s (attempt %d/%d)",
-							firstNonEmpty(dev.Name, dev.Serial), dev.Serial, net.Name, status, attempt+1, cfg.MacTablePoll)
+						log.Debugf("Adding network client %s on %s port %s", macaddr.FormatMacColon(normMAC), switchName, port)
+					}
+
+					aggrMembers := resolveAggrPorts(ctx, client, serial, port, cliAggrCache)
+					vlan, portMode := enrichPortInfoWithMembers(ctx, client, serial, port, aggrMembers, 0, "")
+					if !filters.MatchesVLANFilter(vlan, cfg.VLANFilter) || !filters.MatchesPortModeFilter(portMode, cfg.PortMode) || !filters.MatchesEntryTypeFilter("", cfg.EntryType) {
+						continue
 					}
+
+					ip, hn, hostWhy := ipAndHostname(normMAC, c.IP, serial)
+					row := output.ResultRow{
+						OrgName:      org.Name,
+						NetworkName:  net.Name,
+						SwitchName:   switchName,
+						SwitchSerial: serial,
+						Port:         port,
+						AggrPorts:    aggrMembers,
+						MAC:          macaddr.FormatMacColon(normMAC),
+						IP:           ip,
+						Hostname:     hn,
+						LastSeen:     firstNonEmpty(c.LastSeen, macToLastSeen[normMAC]),
+						VLAN:         vlan,
+						PortMode:     portMode,
+						IsUplink:     isPortUplink(port, aggrMembers, cliGetUplinkPorts(serial)),
+						Source:       output.SourceNetworkClients,
+					}
+					row.Explain = explainRow(row, output.FieldSource{Source: srcNetworkClients, Detail: "recent device " + serial},
+						explainPortInfo(serial, port, 0, "", vlan, portMode, srcNetworkClients), hostWhy, srcNetworkClients)
+					recordResult(row)
 				}
+			}
 
-				if status == "complete" && len(macEntries) > 0 {
-					log.Debugf("Live MAC table returned %d entries for %s", len(macEntries), firstNonEmpty(dev.Name, dev.Serial))
+			// Access points and appliances have no MAC table to read; their
+			// client lists show who is associated or attached. An appliance's
+			// live ARP table also fills in IPs and adds the quiet devices
+			// that have dropped off its client list.
+			for _, dev := range others {
+				if ctx.Err() != nil {
+					return
+				}
+				devType := filters.DeviceType(dev)
+				log.Debugf("Querying %s: %s (%s)", devType, firstNonEmpty(dev.Name, dev.Serial), dev.Serial)
+				arpSerial := ""
+				if devType == "appliance" {
+					arpSerial = dev.Serial
+				}
+				clients, err := client.GetDeviceClients(ctx, dev.Serial)
+				if err != nil {
+					log.Debugf("Failed to get device clients for %s: %v", dev.Serial, err)
+				}
+				found := make(map[string]bool)
+				for _, c := range clients {
+					normMAC, err := macaddr.NormalizeExactMac(c.MAC)
+					if err != nil || !matcher(normMAC) {
+						continue
+					}
+					found[normMAC] = true
+					row, ok := nonSwitchResult(dev, devType, normMAC, firstNonEmpty(c.SwitchportName, c.Switchport, c.Port), macToSSID[normMAC], cfg)
+					if !ok {
+						continue
+					}
+					row.OrgName, row.NetworkName = org.Name, net.Name
+					row.LastSeen = firstNonEmpty(c.LastSeen, macToLastSeen[normMAC])
+					ip, hn, hostWhy := ipAndHostname(normMAC, "", arpSerial)
+					row.IP, row.Hostname, row.Source = ip, hn, output.SourceDeviceClients
+					row.Explain = explainRow(row, output.FieldSource{Source: srcDeviceClients, Detail: "GET /devices/" + dev.Serial + "/clients"}, nil, hostWhy, srcDeviceClients)
+					if row.Port == "wireless" {
+						radios.fill(&row, net.ID, macToClientID[normMAC])
+					}
+					recordResult(row)
+				}
+				if arpSerial == "" || ctx.Err() != nil {
+					continue
+				}
+				if _, cached := serialArpCache[arpSerial]; !cached {
+					serialArpCache[arpSerial] = client.FetchArpMap(ctx, arpSerial, cfg.MacTablePoll)
+				}
+				for _, row := range applianceArpResults(dev, serialArpCache[arpSerial], matcher, found, cfg) {
+					normMAC, _ := macaddr.NormalizeExactMac(row.MAC)
+					row.OrgName, row.NetworkName = org.Name, net.Name
+					row.LastSeen = macToLastSeen[normMAC]
+					_, hn, hostWhy := ipAndHostname(normMAC, row.IP, "")
+					row.Hostname = hn
+					row.Explain = setFieldSource(explainRow(row, output.FieldSource{Source: srcLiveArpTable, Detail: arpSerial}, nil, hostWhy, srcNetworkClients),
+						output.FieldSource{Field: "IP", Source: srcLiveArpTable, Detail: arpSerial})
+					recordResult(row)
+				}
+			}
 
-					foundInTable := false
-					for _, entry := range macEntries {
-						macStr, _ := entry["mac"].(string)
-						if macStr == "" {
-							continue
-						}
+			// Query device-level clients for each switch
+			for _, dev := range switches {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Debugf("Querying switch: %s (%s)", firstNonEmpty(dev.Name, dev.Serial), dev.Serial)
+
+				// Try live tools MAC table lookup first (works for all switches including Catalyst)
+				tableRead := false
+				macTableID, err := client.CreateMacTableLookup(ctx, dev.Serial)
+				if err == nil && macTableID != "" {
+					if cfg.Verbose {
+						log.Debugf("Created MAC table lookup job %s for %s", macTableID, dev.Serial)
+					}
 
-						normMAC, err := macaddr.NormalizeExactMac(macStr)
+					// Poll for results (max 30 seconds)
+					var macEntries []map[string]interface{}
+					var status string
+					for attempt := 0; attempt < cfg.MacTablePoll; attempt++ {
+						if err = meraki.SleepContext(ctx, 2*time.Second); err != nil {
+							break
+						}
+						macEntries, status, err = client.GetMacTableLookup(ctx, dev.Serial, macTableID)
 						if err != nil {
-							continue
+							if cfg.Verbose {
+								log.Debugf("Error getting MAC table lookup for %s (%s) in network %s: %v",
+									firstNonEmpty(dev.Name, dev.Serial), dev.Serial, net.Name, err)
+							}
+							break
 						}
+						if status == "complete" {
+							break
+						}
+						if cfg.Verbose {
+							log.Debugf("MAC table lookup status for %s (%s) in network %s: %s (attempt %d/%d)",
+								firstNonEmpty(dev.Name, dev.Serial), dev.Serial, net.Name, status, attempt+1, cfg.MacTablePoll)
+						}
+					}
 
-						if matcher(normMAC) {
-							// Try different field names for port
-							portID, _ := entry["portId"].(string)
-							if portID == "" {
-								portID, _ = entry["port"].(string)
-							}
-							if portID == "" {
-								portID, _ = entry["interface"].(string)
-							}
-							vlan, _ := entry["vlan"].(float64)
-							portMode, _ := entry["type"].(string) // "access" or "trunk"
+					tableRead = status == "complete"
+					if status == "complete" && len(macEntries) > 0 {
+						log.Debugf("Live MAC table returned %d entries for %s", len(macEntries), firstNonEmpty(dev.Name, dev.Serial))
+						tableAt := time.Now()
 
-							if cfg.Verbose && portID == "" {
-								log.Debugf("MAC entry fields: %+v", entry)
-							}
-
-							// Normalize AGGR raw strings (e.g. "AGGR/0=serial/49,...") to clean ID
-							cleanPortID, aggrMembers := parseAggrPort(firstNonEmpty(portID, "unknown"))
-							port := cleanPortID
-							if !filters.MatchesPortFilter(port, cfg.PortFilter) {
+						foundInTable := false
+						for _, entry := range macEntries {
+							macStr, _ := entry["mac"].(string)
+							if macStr == "" {
 								continue
 							}
 
-							// If not already parsed from the raw string, try API/cache lookup
-							if aggrMembers == nil {
-								aggrMembers = resolveAggrPorts(ctx, client, dev.Serial, port, cliAggrCache)
+							normMAC, err := macaddr.NormalizeExactMac(macStr)
+							if err != nil {
+								continue
 							}
 
-							// Enrich with switch port API (authoritative VLAN + mode); for AGGR use first member
-							richVLAN, richMode := enrichPortInfoWithMembers(ctx, client, dev.Serial, port, aggrMembers, int(vlan), portMode)
-
-							if cfg.Verbose {
-								log.Debugf("Found MAC %s on %s port %s (VLAN %d, mode=%s) via live lookup",
-									macaddr.FormatMacColon(normMAC), firstNonEmpty(dev.Name, dev.Serial), port, richVLAN, richMode)
+							if matcher(normMAC) {
+								// Try different field names for port
+								portID, _ := entry["portId"].(string)
+								if portID == "" {
+									portID, _ = entry["port"].(string)
+								}
+								if portID == "" {
+									portID, _ = entry["interface"].(string)
+								}
+								vlan, _ := entry["vlan"].(float64)
+								portMode := macTableEntryMode(entry) // "access" or "trunk"
+								entryType := macTableEntryType(entry)
+
+								if cfg.Verbose && portID == "" {
+									log.Debugf("MAC entry fields: %+v", entry)
+								}
+
+								// Normalize AGGR raw strings (e.g. "AGGR/0=serial/49,...") to clean ID
+								cleanPortID, aggrMembers := parseAggrPort(firstNonEmpty(portID, "unknown"))
+								port := cleanPortID
+								if !filters.MatchesPortFilter(port, cfg.PortFilter) {
+									continue
+								}
+
+								// If not already parsed from the raw string, try API/cache lookup
+								if aggrMembers == nil {
+									aggrMembers = resolveAggrPorts(ctx, client, dev.Serial, port, cliAggrCache)
+								}
+
+								// Enrich with switch port API (authoritative VLAN + mode); for AGGR use first member
+								richVLAN, richMode := enrichPortInfoWithMembers(ctx, client, dev.Serial, port, aggrMembers, int(vlan), portMode)
+								// On a trunk the table entry carries the VLAN the MAC was
+								// learned on, the port API only the native VLAN; either counts.
+								if !filters.MatchesVLANFilter(richVLAN, cfg.VLANFilter) && !filters.MatchesVLANFilter(int(vlan), cfg.VLANFilter) {
+									continue
+								}
+								if !filters.MatchesPortModeFilter(richMode, cfg.PortMode) || !filters.MatchesEntryTypeFilter(entryType, cfg.EntryType) {
+									continue
+								}
+
+								if cfg.Verbose {
+									log.Debugf("Found MAC %s on %s port %s (VLAN %d, mode=%s) via live lookup",
+										macaddr.FormatMacColon(normMAC), firstNonEmpty(dev.Name, dev.Serial), port, richVLAN, richMode)
+								}
+
+								ip, hn, hostWhy := ipAndHostname(normMAC, "", dev.Serial)
+								_, isUplink := cliGetUplinkPorts(dev.Serial)[port]
+								row := output.ResultRow{
+									OrgName:      org.Name,
+									NetworkName:  net.Name,
+									SwitchName:   firstNonEmpty(dev.Name, dev.Serial),
+									SwitchSerial: dev.Serial,
+									Port:         port,
+									AggrPorts:    aggrMembers,
+									MAC:          macaddr.FormatMacColon(normMAC),
+									IP:           ip,
+									Hostname:     hn,
+									LastSeen:     macToLastSeen[normMAC],
+									VLAN:         richVLAN,
+									PortMode:     richMode,
+									EntryType:    entryType,
+									IsUplink:     isUplink,
+									Source:       output.SourceMacTable,
+								}
+								row.Explain = explainRow(row, output.FieldSource{Source: srcLiveMacTable, Detail: macTableDetail(macTableID, tableAt)},
+									explainPortInfo(dev.Serial, port, int(vlan), portMode, richVLAN, richMode, srcLiveMacTable), hostWhy, srcNetworkClients)
+								recordResult(row)
+								foundInTable = true
 							}
-
-							ip, hn := ipAndHostname(normMAC, "", dev.Serial)
-							_, isUplink := cliGetUplinkPorts(dev.Serial)[port]
-							addResult(resultsIndex, &results, output.ResultRow{
-								OrgName:      org.Name,
-								NetworkName:  net.Name,
-								SwitchName:   firstNonEmpty(dev.Name, dev.Serial),
-								SwitchSerial: dev.Serial,
-								Port:         port,
-								AggrPorts:    aggrMembers,
-								MAC:          macaddr.FormatMacColon(normMAC),
-								IP:           ip,
-								Hostname:     hn,
-								LastSeen:     macToLastSeen[normMAC],
-								VLAN:         richVLAN,
-								PortMode:     richMode,
-								IsUplink:     isUplink,
-							})
-							foundInTable = true
 						}
-					}
-					// Only skip device-clients fallback if the target MAC was actually found in the table.
-					// If the table had entries but our MAC wasn't present (device temporarily inactive),
-					// fall through so device clients history can still surface the result.
-					if foundInTable {
-						continue // Skip device clients API
+						// Only skip device-clients fallback if the target MAC was actually found in the table.
+						// If the table had entries but our MAC wasn't present (device temporarily inactive),
+						// fall through so device clients history can still surface the result.
+						if foundInTable {
+							continue // Skip device clients API
+						}
 					}
 				}
-			}
 
-			// Fallback to device clients API
-			clients, err := client.GetDeviceClients(ctx, dev.Serial)
-			if err != nil {
-				if cfg.Verbose {
-					log.Warnf("Failed to get device clients for %s: %v", dev.Serial, err)
-				}
-				continue
-			}
-
-			log.Debugf("Device clients API returned %d clients for %s", len(clients), firstNonEmpty(dev.Name, dev.Serial))
-
-			for _, c := range clients {
-				normMAC, err := macaddr.NormalizeExactMac(c.MAC)
+				// Fallback to device clients API
+				clients, err := client.GetDeviceClients(ctx, dev.Serial)
 				if err != nil {
+					if cfg.Verbose {
+						log.Warnf("Failed to get device clients for %s: %v", dev.Serial, err)
+					}
+					if ctx.Err() == nil {
+						emitOpts.Warnings.Add(skippedSwitchWarning(net.Name, dev, err))
+					}
 					continue
 				}
-				if matcher(normMAC) {
-					port := firstNonEmpty(c.SwitchportName, c.Switchport, c.Port, "unknown")
-					if !filters.MatchesPortFilter(port, cfg.PortFilter) {
+				if !tableRead {
+					emitOpts.Warnings.Add(staleSwitchWarning(net.Name, dev))
+				}
+
+				log.Debugf("Device clients API returned %d clients for %s", len(clients), firstNonEmpty(dev.Name, dev.Serial))
+
+				for _, c := range clients {
+					normMAC, err := macaddr.NormalizeExactMac(c.MAC)
+					if err != nil {
 						continue
 					}
-					aggrMembers2 := resolveAggrPorts(ctx, client, dev.Serial, port, cliAggrCache)
-					vlan, portMode := enrichPortInfoWithMembers(ctx, client, dev.Serial, port, aggrMembers2, 0, "")
-					ip, hn := ipAndHostname(normMAC, "", dev.Serial)
-					addResult(resultsIndex, &results, output.ResultRow{
-						OrgName:      org.Name,
-						NetworkName:  net.Name,
-						SwitchName:   firstNonEmpty(dev.Name, dev.Serial),
-						SwitchSerial: dev.Serial,
-						Port:         port,
-						AggrPorts:    aggrMembers2,
-						MAC:          macaddr.FormatMacColon(normMAC),
-						IP:           ip,
-						Hostname:     hn,
-						LastSeen:     c.LastSeen,
-						VLAN:         vlan,
-						PortMode:     portMode,
-						IsUplink:     isPortUplink(port, aggrMembers2, cliGetUplinkPorts(dev.Serial)),
-					})
+					if matcher(normMAC) {
+						port := firstNonEmpty(c.SwitchportName, c.Switchport, c.Port, "unknown")
+						if !filters.MatchesPortFilter(port, cfg.PortFilter) {
+							continue
+						}
+						aggrMembers2 := resolveAggrPorts(ctx, client, dev.Serial, port, cliAggrCache)
+						vlan, portMode := enrichPortInfoWithMembers(ctx, client, dev.Serial, port, aggrMembers2, 0, "")
+						if !filters.MatchesVLANFilter(vlan, cfg.VLANFilter) || !filters.MatchesPortModeFilter(portMode, cfg.PortMode) || !filters.MatchesEntryTypeFilter("", cfg.EntryType) {
+							continue
+						}
+						ip, hn, hostWhy := ipAndHostname(normMAC, "", dev.Serial)
+						row := output.ResultRow{
+							OrgName:      org.Name,
+							NetworkName:  net.Name,
+							SwitchName:   firstNonEmpty(dev.Name, dev.Serial),
+							SwitchSerial: dev.Serial,
+							Port:         port,
+							AggrPorts:    aggrMembers2,
+							MAC:          macaddr.FormatMacColon(normMAC),
+							IP:           ip,
+							Hostname:     hn,
+							LastSeen:     c.LastSeen,
+							VLAN:         vlan,
+							PortMode:     portMode,
+							IsUplink:     isPortUplink(port, aggrMembers2, cliGetUplinkPorts(dev.Serial)),
+							Source:       output.SourceDeviceClients,
+						}
+						row.Explain = explainRow(row, output.FieldSource{Source: srcDeviceClients, Detail: "GET /devices/" + dev.Serial + "/clients"},
+							explainPortInfo(dev.Serial, port, 0, "", vlan, portMode, srcDeviceClients), hostWhy, srcDeviceClients)
+						recordResult(row)
+					}
 				}
 			}
 		}
 	}
+	scanNetworks()
+
+	// Nothing found: have the MX ping the target so its switch relearns the
+	// MAC, then look once more.
+	if len(results) == 0 && *wakeFlag && ctx.Err() == nil {
+		if wakeMAC, wakeIP, ok := wakeTarget(cfg.MACAddress, cfg.IPAddress); !ok {
+			log.Warnf("--wake needs a single --ip or exact --mac; ignored")
+		} else if wakeClient(ctx, client, selectedNetworks, wakeMAC, wakeIP, cfg.MacTablePoll, log) {
+			log.Infof("--wake: retrying the lookup")
+			scanNetworks()
+		}
+	}
 
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].NetworkName == results[j].NetworkName {
-			if results[i].SwitchName == results[j].SwitchName {
-				return results[i].Port < results[j].Port
-			}
-			return results[i].SwitchName < results[j].SwitchName
+	// A MAC that is no client may be a Meraki device's own management MAC.
+	// Those are on no switch port, so --vlan, --port-mode and --entry-type rule them out.
+	if len(results) == 0 && ctx.Err() == nil && cfg.VLANFilter == 0 && cfg.PortMode == "" && cfg.EntryType == "" && (cfg.MACAddress != "" || cfg.MACRange != "" || cfg.Vendor != "") {
+		for _, row := range findDeviceMACs(ctx, client, org, selectedNetworks, matcher, log) {
+			log.Infof("%s is the %s", row.MAC, row.Note)
+			recordResult(row)
 		}
-		return results[i].NetworkName < results[j].NetworkName
-	})
+	}
 
-	switch cfg.OutputFormat {
-	case "csv":
-		output.WriteCSV(os.Stdout, results)
-	case "text":
-		output.WriteText(os.Stdout, results)
-	case "html":
-		output.WriteHTML(os.Stdout, results)
+	if plan != nil {
+		results = plan.filter(results)
 	}
+	if *hideUplinksFlag {
+		results = hideUplinkRows(results)
+	}
+
+	annotateVirtualMACs(ctx, client, selectedNetworks, results, *mapVirtualFlag)
+	for i := range results {
+		results[i].Note = joinNotes(results[i].Note, rowNote(results[i]))
+	}
+	if hist != nil {
+		pruneHistory(hist, historyRetention(cfg.HistoryMaxAge), scanTime, log)
+		if err := hist.Save(); err != nil {
+			log.Warnf("Saving history: %v", err)
+		}
+		_ = hist.Close()
+	}
+	emitOpts.Streamed = streaming
+	if err := emitResults(cfg, anonymizeRows(anon, results), emitOpts, log); err != nil {
+		exitWithError(log, err.Error())
+	}
+	commitResultFile(resultFile, log)
+	exitIfAborted(ctx, cfg.Timeout, log)
+
+	if *dhcpServerFlag {
+		reportDHCPServers(ctx, stderr, client, selectedNetworks, results, cfg.MacTablePoll, log)
+	}
+	if *roamingFlag {
+		reportRoamingHistory(ctx, stderr, client, selectedNetworks, results, log)
+	}
+	if *identifySwitchFlag {
+		identifySwitches(ctx, stderr, client, results, log)
+	}
+}
+
+// loadRowTemplate parses the --output-template value. An existing file path is
+// read as the template; anything else is used as the template text itself.
+// An empty value returns a nil template.
+func loadRowTemplate(value string) (*template.Template, error) {
+	if value == "" {
+		return nil, nil
+	}
+	text := value
+	if fi, err := os.Stat(value); err == nil && !fi.IsDir() {
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	return output.ParseRowTemplate(text)
+}
+
+// anonymizeRows returns rows unchanged when anon is nil, otherwise an anonymized
+// copy so the originals can still drive follow-up API lookups.
+func anonymizeRows(anon *output.Anonymizer, rows []output.ResultRow) []output.ResultRow {
+	if anon == nil {
+		return rows
+	}
+	scrubbed := make([]output.ResultRow, len(rows))
+	copy(scrubbed, rows)
+	anon.Rows(scrubbed)
+	return scrubbed
+}
+
+// stringList is a flag.Value that may be given more than once; the values are
+// kept in order and String joins them with commas.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// useColor decides whether text output to w is colorized. "always" and "never"
+// are absolute; "auto" colors only a terminal, and only when NO_COLOR is unset
+// and TERM is not "dumb".
+func useColor(mode string, w io.Writer, getenv func(string) string) bool {
+	switch mode {
+	case output.ColorAlways:
+		return true
+	case output.ColorNever:
+		return false
+	}
+	if getenv("NO_COLOR") != "" || getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// emitOptions controls how emitResults writes rows.
+type emitOptions struct {
+	Out      io.Writer          // destination for results; nil means stdout
+	Streamed bool               // rows were already written as JSON Lines while scanning
+	Template *template.Template // --output-template; replaces the configured format
+	Columns  []output.Column    // --columns for the tabular formats; nil means defaults
+	CSV      output.CSVOptions  // CSV dialect (delimiter, BOM, quoting)
+	QR       bool               // also write a QR code of the rows to stderr
+	Explain  bool               // also write each row's field provenance to stderr
+	Color    bool               // ANSI colors in text output (see useColor)
+	Warnings *output.Warnings   // run-level warnings written after the rows; may be nil
+}
+
+// emitResults scores rows (see output.ScoreRows), sorts them with sortResults,
+// keeps the best cfg.MaxResults and writes them to opts.Out in the configured
+// format, followed by the run's warnings, including one when rows were dropped
+// (see output.WriteWarnings). terraform-external reports only the best row and
+// is never truncated. With opts.Explain the
+// field provenance and with opts.QR a QR code of the rows are also written to
+// stderr so redirected CSV/HTML output stays machine-readable. A failed write
+// (full disk, closed pipe) is returned so the run does not report success with
+// truncated output.
+func emitResults(cfg config.Config, results []output.ResultRow, opts emitOptions, log *logger.Logger) error {
+	output.ScoreRows(results, time.Now())
+	sortResults(results)
+	var truncated *output.Truncation
+	if cfg.OutputFormat != "terraform-external" {
+		if results, truncated = output.Truncate(results, cfg.MaxResults); truncated != nil {
+			log.Warnf("%s, or raise --max-results", truncated.Warning())
+		}
+	}
+	warnings := opts.Warnings.List()
+	if truncated != nil {
+		warnings = append(warnings, truncated.AsWarning())
+	}
+
+	w := opts.Out
+	if w == nil {
+		w = os.Stdout
+	}
+	var err error
+	switch {
+	case opts.Template != nil:
+		err = output.WriteTemplate(w, opts.Template, results)
+	case cfg.OutputFormat == "csv":
+		err = output.WriteCSVWithOptions(w, results, opts.CSV, opts.Columns...)
+	case cfg.OutputFormat == "text" && opts.Color:
+		err = output.WriteColorText(w, results, opts.Columns...)
+	case cfg.OutputFormat == "text":
+		err = output.WriteText(w, results, opts.Columns...)
+	case cfg.OutputFormat == "html":
+		err = output.WriteHTML(w, results, opts.Columns...)
+	case cfg.OutputFormat == "xlsx":
+		err = output.WriteXLSXWithFooter(w, results, output.WarningsFooter(warnings), opts.Columns...)
+	case cfg.OutputFormat == "yaml":
+		err = output.WriteYAML(w, results)
+	case cfg.OutputFormat == "terraform-external":
+		err = output.WriteTerraformExternal(w, results)
+	case cfg.OutputFormat == "jsonl":
+		if !opts.Streamed {
+			err = output.WriteJSONL(w, results)
+		}
+	}
+	switch {
+	case err != nil || opts.Template != nil:
+	case cfg.OutputFormat == "csv":
+		err = output.WriteCSVWarnings(w, opts.CSV, warnings)
+	case cfg.OutputFormat == "jsonl":
+		// The {"truncated":true} object predates the warnings list; keep it
+		// for consumers that look for it.
+		if err = output.WriteTruncation(w, cfg.OutputFormat, truncated); err == nil {
+			err = output.WriteWarnings(w, cfg.OutputFormat, warnings)
+		}
+	default:
+		err = output.WriteWarnings(w, cfg.OutputFormat, warnings)
+	}
+	if err != nil {
+		return fmt.Errorf("writing output: %v", err)
+	}
+
+	if opts.Explain {
+		if err := output.WriteExplain(os.Stderr, results); err != nil {
+			log.Warnf("explain: %v", err)
+		}
+	}
+	if opts.QR && len(results) > 0 {
+		if err := output.WriteQR(os.Stderr, output.QRPayload(results)); err != nil {
+			log.Warnf("QR code: %v", err)
+		}
+	}
+	return nil
+}
+
+// sortResults orders rows by descending confidence so the most trustworthy
+// port comes first, then by network, switch and port.
+func sortResults(results []output.ResultRow) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch {
+		case a.Confidence != b.Confidence:
+			return a.Confidence > b.Confidence
+		case a.NetworkName != b.NetworkName:
+			return a.NetworkName < b.NetworkName
+		case a.SwitchName != b.SwitchName:
+			return a.SwitchName < b.SwitchName
+		}
+		return a.Port < b.Port
+	})
 }
 
 // ── Utility helpers ───────────────────────────────────────────────────────────
@@ -721,9 +1674,163 @@ func parseIntEnv(key string) int {
 	return 0
 }
 
+// exitCleanups run before exitWithError terminates the process, since os.Exit
+// skips deferred calls.
+var exitCleanups []func()
+
+// openResultFile starts an atomic --output-file, or returns nil when results go
+// to stdout (empty path or "-"). The temp file is removed if the run exits with
+// an error, so a previous good file at path is never replaced by a partial one.
+func openResultFile(path string, log *logger.Logger) *output.AtomicFile {
+	if path == "" || path == "-" {
+		return nil
+	}
+	f, err := output.CreateAtomic(path)
+	if err != nil {
+		exitWithError(log, "--output-file: "+err.Error())
+	}
+	exitCleanups = append(exitCleanups, f.Abort)
+	return f
+}
+
+// commitResultFile moves a completed --output-file into place; nil is a no-op.
+func commitResultFile(f *output.AtomicFile, log *logger.Logger) {
+	if f == nil {
+		return
+	}
+	if err := f.Commit(); err != nil {
+		exitWithError(log, "--output-file: "+err.Error())
+	}
+}
+
+// describeAPIError adds a hint for the error classes a user can act on.
+func describeAPIError(err error) string {
+	switch {
+	case errors.Is(err, meraki.ErrAuth):
+		return err.Error() + " (check MERAKI_API_KEY and that API access is enabled for the organization)"
+	case errors.Is(err, meraki.ErrRateLimited):
+		return err.Error() + " (rate limited; raise --retry or try again later)"
+	case errors.Is(err, meraki.ErrServer):
+		return err.Error() + " (Dashboard API outage; try again later)"
+	}
+	return err.Error()
+}
+
+// retryPolicy builds the client retry policy from the validated cfg. Unset
+// settings keep the client defaults.
+func retryPolicy(cfg config.Config) meraki.RetryPolicy {
+	p := meraki.RetryPolicy{MaxAttempts: cfg.MaxRetries}
+	p.Statuses, _ = config.ParseStatusList(cfg.RetryStatuses)
+	p.MaxElapsed, _ = time.ParseDuration(cfg.RetryElapsed)
+	return p
+}
+
+// newAPIClient returns a client for apiKey with the base URL, rate limit,
+// retry policy and listing cache of cfg, logging to log.
+func newAPIClient(cfg config.Config, apiKey string, log *logger.Logger) *meraki.MerakiClient {
+	client := meraki.NewClient(apiKey, cfg.BaseURL, cfg.MaxRetries)
+	client.SetWarnFunc(log.Warnf)
+	client.SetDebugFunc(log.Debugf)
+	client.SetRateLimit(cfg.RateLimit)
+	client.SetRetryPolicy(retryPolicy(cfg))
+	client.SetCache(responseCache(cfg, log))
+	return client
+}
+
+// newWebClient is newAPIClient for a web request, with the server's settings.
+// Every web request draws from the shared bucket of orgID, or of the API key
+// while the organization is not known, so concurrent users stay within the
+// organization's budget together.
+func newWebClient(apiKey, orgID string, log *logger.Logger) *meraki.MerakiClient {
+	client := newAPIClient(webClientConfig, apiKey, log)
+	client.ShareOrgRateLimit(orgID)
+	return client
+}
+
+// tlsOptions returns the Dashboard API TLS settings of cfg.
+func tlsOptions(cfg config.Config) meraki.TLSOptions {
+	return meraki.TLSOptions{CAFile: cfg.CAFile, MinVersion: cfg.TLSMinVersion, InsecureSkipVerify: cfg.TLSInsecure}
+}
+
+// responseCache is the listing cache configured by cfg, or nil when
+// MERAKI_CACHE_TTL is unset or zero. MERAKI_CACHE_DIR is a directory, or a
+// sqlite://, bolt:// or postgres:// URL to keep the cache in a database that
+// several installations share.
+func responseCache(cfg config.Config, log *logger.Logger) *meraki.ResponseCache {
+	ttl, _ := history.ParseSince(cfg.CacheTTL)
+	if ttl <= 0 {
+		return nil
+	}
+	c := &meraki.ResponseCache{Dir: firstNonEmpty(cfg.CacheDir, meraki.DefaultCacheDir()), TTL: ttl, Refresh: cfg.Refresh}
+	if history.IsDatabase(cfg.CacheDir) {
+		store, err := openCacheStore(cfg.CacheDir)
+		if err != nil {
+			log.Warnf("Listing cache disabled: %v", err)
+			return nil
+		}
+		c.Store = store
+	}
+	return c
+}
+
+// cacheStores holds the database cache stores opened by openCacheStore, by
+// location, so concurrent web searches share one connection pool.
+var cacheStores sync.Map
+
+func openCacheStore(location string) (history.CacheStore, error) {
+	if s, ok := cacheStores.Load(location); ok {
+		return s.(history.CacheStore), nil
+	}
+	s, err := history.OpenCache(location)
+	if err != nil {
+		return nil, err
+	}
+	if prev, loaded := cacheStores.LoadOrStore(location, s); loaded {
+		_ = s.Close()
+		return prev.(history.CacheStore), nil
+	}
+	return s, nil
+}
+
+// userAgent is the Dashboard API User-Agent: this tool and version, then the
+// configured suffix.
+func userAgent(cfg config.Config) string {
+	return strings.TrimSpace(meraki.DefaultUserAgent + "/" + Version + " " + cfg.UserAgent)
+}
+
+// searchContext derives the search context from ctx: Ctrl+C and SIGTERM
+// cancel it, and so does timeout ("10m") when set. The scan then stops at the
+// next API call or poll and the rows found so far are written. Once the
+// context is done the signals are released, so a second Ctrl+C exits at once.
+func searchContext(ctx context.Context, timeout string) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	cancel := stop
+	if d, err := time.ParseDuration(timeout); err == nil && d > 0 { // validated by config.Load
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, d)
+		cancel = func() { cancelTimeout(); stop() }
+	}
+	context.AfterFunc(ctx, stop)
+	return ctx, cancel
+}
+
+// exitIfAborted exits with status 1 when ctx was cancelled by --timeout or
+// Ctrl+C, after the partial results have been written.
+func exitIfAborted(ctx context.Context, timeout string, log *logger.Logger) {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		exitWithError(log, fmt.Sprintf("Search aborted after --timeout %s; the results written are partial", timeout))
+	case ctx.Err() != nil:
+		exitWithError(log, "Search interrupted; the results written are partial")
+	}
+}
+
 // exitWithError logs an error message and exits the program with status code 1.
 // If log is nil, the error is written to stderr instead.
 func exitWithError(log *logger.Logger, msg string) {
+	for _, cleanup := range exitCleanups {
+		cleanup()
+	}
 	if log != nil {
 		log.Errorf(msg)
 	} else {
@@ -752,30 +1859,94 @@ func selectOrganization(name string, orgs []meraki.Organization) (meraki.Organiz
 
 // selectNetworks filters networks by name.
 // If name is "ALL" (case-insensitive), returns all networks.
-// Otherwise returns a single network matching the name, or an error if not found.
+// Otherwise name is a comma-separated list of network names and glob patterns
+// ("Branch-1,Branch-2", "Branch-*"), matched case-insensitively. Networks are
+// returned in their original order without duplicates; a name or pattern that
+// matches nothing is an error so typos don't silently shrink the scan.
 func selectNetworks(name string, networks []meraki.Network) ([]meraki.Network, error) {
-	if strings.ToUpper(name) == "ALL" {
+	if strings.ToUpper(strings.TrimSpace(name)) == "ALL" {
 		return networks, nil
 	}
+	selected := make(map[string]bool)
+	for _, term := range strings.Split(name, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		pattern := strings.ToLower(term)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid network pattern %q: %w", term, err)
+		}
+		found := false
+		for _, net := range networks {
+			if ok, _ := path.Match(pattern, strings.ToLower(net.Name)); ok {
+				selected[net.ID] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("network %q not found", term)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("network %q not found", name)
+	}
+	var out []meraki.Network
 	for _, net := range networks {
-		if strings.EqualFold(net.Name, name) {
-			return []meraki.Network{net}, nil
+		if selected[net.ID] {
+			out = append(out, net)
+		}
+	}
+	return out, nil
+}
+
+// isRowExcluded reports whether row is on a switch or port excluded with
+// --exclude-switch or --exclude-port.
+func isRowExcluded(row output.ResultRow) bool {
+	return filters.IsSwitchExcluded(row.SwitchName, row.SwitchSerial) || filters.IsPortExcluded(row.Port)
+}
+
+// excludeRows drops the rows isRowExcluded rejects.
+func excludeRows(rows []output.ResultRow) []output.ResultRow {
+	kept := rows[:0]
+	for _, row := range rows {
+		if !isRowExcluded(row) {
+			kept = append(kept, row)
 		}
 	}
-	return nil, fmt.Errorf("network %q not found", name)
+	return kept
 }
 
-// addResult adds a result row to the results slice if it's not a duplicate.
+// networksByID returns a network for each ID in the comma-separated ids, for
+// --network-id, without a GetNetworks round trip. The names are unknown, so
+// the IDs stand in for them. An empty ids returns nil.
+func networksByID(ids string) []meraki.Network {
+	var out []meraki.Network
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, meraki.Network{ID: id, Name: id})
+	}
+	return out
+}
+
+// addResult adds a result row to the results slice if it's not a duplicate and
+// reports whether it was added.
 // Deduplication is based on switch serial, port, MAC address, and last seen timestamp.
-func addResult(index map[string]struct{}, rows *[]output.ResultRow, row output.ResultRow) {
+func addResult(index map[string]struct{}, rows *[]output.ResultRow, row output.ResultRow) bool {
 	// Key on serial+port+MAC only (not LastSeen) so network-clients and MAC-table
 	// results for the same port don't both appear as separate rows.
 	key := fmt.Sprintf("%s|%s|%s", row.SwitchSerial, row.Port, row.MAC)
 	if _, exists := index[key]; exists {
-		return
+		return false
 	}
 	index[key] = struct{}{}
 	*rows = append(*rows, row)
+	return true
 }
 
 // ── CLI output helpers ────────────────────────────────────────────────────────
@@ -789,31 +1960,110 @@ func printUsage(w *os.File) {
 	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --mac 00:11:22:33:44:55 --network ALL --org \"My Org\" --output-format csv")
 	_, _ = fmt.Fprintln(w, "")
 	_, _ = fmt.Fprintln(w, "Flags:")
-	_, _ = fmt.Fprintln(w, "  --ip <address>              IP address to resolve to MAC (mutually exclusive with --mac)")
-	_, _ = fmt.Fprintln(w, "  --mac <mac|pattern>         MAC address or wildcard pattern (required unless using list/test flags)")
+	_, _ = fmt.Fprintln(w, "  --ip <address|cidr>         IP address to resolve to MAC, or a subnet (10.20.30.0/24) to sweep (mutually exclusive with --mac)")
+	_, _ = fmt.Fprintln(w, "  --mac <mac|pattern>         MAC address or wildcard pattern (required unless using list/test flags;")
+	_, _ = fmt.Fprintln(w, "                              in a terminal, a missing target is prompted for)")
+	_, _ = fmt.Fprintln(w, "                              Repeat or comma-separate to find several in one scan: --mac aa:..,bb:..")
+	_, _ = fmt.Fprintln(w, "  --mac-range <first-last>    Inclusive MAC range, e.g. 00:11:22:33:44:00-00:11:22:33:44:ff")
+	_, _ = fmt.Fprintln(w, "  --vendor <name>             Every MAC in the vendor's OUI blocks, e.g. \"Axis\" (cameras) or \"Brother\"")
+	_, _ = fmt.Fprintln(w, "  --oui-file <path>           IEEE oui.csv replacing the built-in OUI registry")
+	_, _ = fmt.Fprintln(w, "  --hostname <name>           DNS name to resolve to IP(s) and then to the switch port (honours --dns-servers)")
+	_, _ = fmt.Fprintln(w, "  --client-id <id>            Meraki client ID from a dashboard URL or webhook (e.g. k74272e)")
+	_, _ = fmt.Fprintln(w, "  --serial <serial>           List every client on one switch (live MAC table merged with client history)")
+	_, _ = fmt.Fprintln(w, "  --query <expr>              Compound search, also written as: find \"<expr>\" (see README)")
+	_, _ = fmt.Fprintln(w, "                              e.g. \"vlan=30 AND vendor~'Axis' AND NOT mode=trunk\"")
 	_, _ = fmt.Fprintln(w, "  --network <name|ALL>        Network name or ALL (default from .env)")
+	_, _ = fmt.Fprintln(w, "                              Comma-separate names or use globs for a subset: --network \"Branch-*,HQ\"")
 	_, _ = fmt.Fprintln(w, "  --org <name>                Organization name (optional if only one org accessible)")
-	_, _ = fmt.Fprintln(w, "  --output-format <csv|text|html>  Output format (default from .env)")
+	_, _ = fmt.Fprintln(w, "  --org-id <id>               Organization ID; skips the organization lookup (overrides --org)")
+	_, _ = fmt.Fprintln(w, "  --network-id <id,...>       Network ID(s); skips the network lookup (overrides --network)")
+	_, _ = fmt.Fprintln(w, "  --output-format <csv|text|html|jsonl|xlsx|yaml|terraform-external>  Output format (default from .env)")
+	_, _ = fmt.Fprintln(w, "  --output-file <path>        Write results to a file atomically (- for stdout)")
+	_, _ = fmt.Fprintln(w, "  --csv-delimiter <name>      CSV delimiter: comma (default), semicolon, tab, pipe")
+	_, _ = fmt.Fprintln(w, "  --csv-bom                   Prefix CSV with a UTF-8 BOM so Excel reads accents correctly")
+	_, _ = fmt.Fprintln(w, "  --max-results <n>           Cap output at n rows with a truncation warning; -1 for no limit (default: 10000)")
+	_, _ = fmt.Fprintln(w, "  --csv-quote-all             Quote every CSV field")
+	_, _ = fmt.Fprintln(w, "  --csv-crlf                  End CSV lines with CRLF, as Excel on Windows expects")
+	_, _ = fmt.Fprintln(w, "  --color <auto|always|never> Colorize text output (auto: only on a terminal, honours NO_COLOR)")
+	_, _ = fmt.Fprintln(w, "  --columns <list>            Columns for csv/text/html/xlsx: "+strings.Join(output.ColumnKeys(), ","))
+	_, _ = fmt.Fprintln(w, "  --output-template <file|text>  Go text/template per result, e.g. '{{.SwitchName}} {{.Port}} {{.MAC}}'")
+	_, _ = fmt.Fprintln(w, "  --dhcp-server               Report the DHCP server (MX or relay target) for each found client and its switch port")
+	_, _ = fmt.Fprintln(w, "  --identify-switch           Blink the LEDs (20s) of the switch where the client is plugged in")
+	_, _ = fmt.Fprintln(w, "  --hide-uplinks              Drop hits on ports facing another switch (LLDP/CDP) when the MAC was also")
+	_, _ = fmt.Fprintln(w, "                              found on an access port")
+	_, _ = fmt.Fprintln(w, "  --map-virtual-macs          Map VRRP virtual MACs to the MX warm-spare pair")
+	_, _ = fmt.Fprintln(w, "  --import-port-names <file>  Rename switch ports from a serial,port,name CSV (via action batches)")
+	_, _ = fmt.Fprintln(w, "  --dry-run                   With --import-port-names, only preview the changes")
+	_, _ = fmt.Fprintln(w, "  --history-file <url|path>   First-seen history: sqlite://, bolt:// or postgres:// URL, or a JSON file")
+	_, _ = fmt.Fprintln(w, "                              (default sqlite://~/.find-mac-history.db, off to disable; postgres needs -tags postgres)")
+	_, _ = fmt.Fprintln(w, "  --history-retention <age>   Prune history sightings last seen longer ago, e.g. 180d (default: keep forever)")
+	_, _ = fmt.Fprintln(w, "  --anonymize                 Hash client MACs/IPs/hostnames in all output (log files are not scrubbed)")
+	_, _ = fmt.Fprintln(w, "  --port-security-report      Report access ports without MAC restrictions that carry several clients")
+	_, _ = fmt.Fprintln(w, "  --verify <file>             Check that the critical MACs in a mac,switch,port[,vlan[,name]] CSV are on")
+	_, _ = fmt.Fprintln(w, "                              their expected switch/port/VLAN; exits 1 on any deviation")
+	_, _ = fmt.Fprintln(w, "  --verify-interval <dur>     With --verify, keep re-checking this often (e.g. 15m) and alert on changes")
+	_, _ = fmt.Fprintln(w, "  --preflight                 Summarize online/offline switches in scope, skip offline ones, confirm before scanning")
+	_, _ = fmt.Fprintln(w, "  --history <timespan>        List the --mac client's connection events (e.g. 7d) and where it moved")
+	_, _ = fmt.Fprintln(w, "  --roaming                   Report recent wireless AP/SSID associations for wired results")
+	_, _ = fmt.Fprintln(w, "  --qr                        Also print a QR code of the results to stderr")
+	_, _ = fmt.Fprintln(w, "  --explain                   Also print to stderr which API call supplied each field of every result")
+	_, _ = fmt.Fprintln(w, "  --local-probe               With --ip on this host's own subnet, ping the address (or subnet) first so")
+	_, _ = fmt.Fprintln(w, "                              idle devices answer ARP/ND and reappear in the switch MAC tables")
+	_, _ = fmt.Fprintln(w, "  --wake                      If nothing is found for a single --ip or --mac, ping its last known IP from")
+	_, _ = fmt.Fprintln(w, "                              the network's MX (live tools) and retry the lookup once")
+	_, _ = fmt.Fprintln(w, "  --full-scan                 With an exact --mac, scan every selected network, not only those the")
+	_, _ = fmt.Fprintln(w, "                              organization client search has seen the MAC in")
 	_, _ = fmt.Fprintln(w, "  --list-orgs                 List organizations and exit")
 	_, _ = fmt.Fprintln(w, "  --list-networks             List networks per organization and exit")
 	_, _ = fmt.Fprintln(w, "  --test-api                  Validate API key and exit")
 	_, _ = fmt.Fprintln(w, "  --test-full-table           Display all MACs in forwarding table (filters apply)")
 	_, _ = fmt.Fprintln(w, "  --switch <name>             Filter by switch name (case-insensitive substring)")
-	_, _ = fmt.Fprintln(w, "  --port <number>             Filter by port name/number")
+	_, _ = fmt.Fprintln(w, "  --device-tag <tag,...>      Only search switches carrying one of these Dashboard tags")
+	_, _ = fmt.Fprintln(w, "  --model <prefix,...>        Only search these switch models, e.g. MS120 or C9300")
+	_, _ = fmt.Fprintln(w, "  --shard <i/n>               Search only share i of n of the switches (split a sweep between runs)")
+	_, _ = fmt.Fprintln(w, "  --port <ports>              Filter by port number, range or list: 3, 1-12, Gi1/0/1-12, 1-12,48")
+	_, _ = fmt.Fprintln(w, "  --vlan <id>                 Only report clients on this VLAN (checked after port lookup)")
+	_, _ = fmt.Fprintln(w, "  --port-mode <access|trunk>  Only report clients on access (or trunk) ports")
+	_, _ = fmt.Fprintln(w, "  --entry-type <static|dynamic> Only report static (incl. sticky) or dynamic live MAC table entries")
+	_, _ = fmt.Fprintln(w, "  --device-types <t,...>      Device types to search: switch, wireless, appliance (default switch);")
+	_, _ = fmt.Fprintln(w, "                              AP hits report the AP and SSID instead of a switch port;")
+	_, _ = fmt.Fprintln(w, "                              switchless networks always search their appliances")
+	_, _ = fmt.Fprintln(w, "  --exclude-switch <list>     Skip switches by name (substring) or serial, e.g. \"core,dist\"")
+	_, _ = fmt.Fprintln(w, "  --exclude-port <list>       Leave these port IDs out of the results, e.g. \"49,50,AGGR/1\"")
 	_, _ = fmt.Fprintln(w, "  --verbose                   Send DEBUG logs to console (overrides --log-level and --log-file)")
 	_, _ = fmt.Fprintln(w, "  --log-file <filename>        Log file path (default from .env)")
 	_, _ = fmt.Fprintln(w, "  --log-level <DEBUG|INFO|WARNING|ERROR>  Log level (default from .env)")
 	_, _ = fmt.Fprintln(w, "  --retry <n>                 Max API retry attempts on rate limit (default: 6)")
+	_, _ = fmt.Fprintln(w, "  --retry-statuses <list>     HTTP statuses to retry (default: 429,502,503,504)")
+	_, _ = fmt.Fprintln(w, "  --timeout <dur>             Abort the search after this long, e.g. 10m, and write what was found")
+	_, _ = fmt.Fprintln(w, "  --retry-max-elapsed <dur>   Stop retrying a request after this long, e.g. 2m (default: no limit)")
+	_, _ = fmt.Fprintln(w, "  --rate-limit <n>            Sustained API requests per second across all workers (default: 10)")
 	_, _ = fmt.Fprintln(w, "  --mac-table-poll <n>        MAC table lookup poll attempts, 2s each (default: 15)")
 	_, _ = fmt.Fprintln(w, "  --dns-servers <addr,...>    Comma-separated DNS servers for PTR lookups")
+	_, _ = fmt.Fprintln(w, "  --proxy <url>               API proxy: http://host:port, socks5://host:port or direct (default: HTTPS_PROXY)")
+	_, _ = fmt.Fprintln(w, "  --ca-file <file>            Extra root CAs (PEM) for API requests, e.g. an SSL-inspecting proxy's CA")
+	_, _ = fmt.Fprintln(w, "  --tls-min-version <v>       Minimum TLS version for API requests: 1.2 or 1.3 (default: 1.2)")
+	_, _ = fmt.Fprintln(w, "  --insecure-skip-verify      Do not verify the API certificate (lab use only)")
+	_, _ = fmt.Fprintln(w, "  --user-agent-suffix <text>  Appended to the API User-Agent (Dashboard API analytics)")
+	_, _ = fmt.Fprintln(w, "  --request-id <id>           X-Request-Id prefix of this run's API calls (default: random, logged)")
+	_, _ = fmt.Fprintln(w, "  --cache-ttl <dur>           Cache org/network/device listings on disk this long, e.g. 1h (default: off)")
+	_, _ = fmt.Fprintln(w, "  --refresh                   Ignore cached listings for this run and fetch them afresh")
+	_, _ = fmt.Fprintln(w, "  --switch-models <m,...>     Extra model prefixes to search as switches (e.g. CW91)")
 	_, _ = fmt.Fprintln(w, "  --interactive               Launch interactive web interface")
 	_, _ = fmt.Fprintln(w, "  --web-port <port>           Web server port (default: 8080)")
 	_, _ = fmt.Fprintln(w, "  --web-host <host>           Web server host (default: localhost)")
+	_, _ = fmt.Fprintln(w, "  --allow-cidr <net,...>      Source networks allowed to reach the web server (default: any)")
+	_, _ = fmt.Fprintln(w, "  --public-url <url>          Base URL of QR code links, e.g. https://findmac.example.com (default: LAN address)")
+	_, _ = fmt.Fprintln(w, "  --access-log <file|->       Web access log in combined format, rotated at WEB_ACCESS_LOG_MAX_MB (default: off)")
+	_, _ = fmt.Fprintln(w, "  --drain-timeout <dur>       Wait this long for running web searches on shutdown (default: 5m)")
+	_, _ = fmt.Fprintln(w, "  --notify                    Desktop notification when a long web search completes or --verify alerts")
+	_, _ = fmt.Fprintln(w, "  --emit-openapi              Print the OpenAPI spec of the web API and exit")
 	_, _ = fmt.Fprintln(w, "  --env <filepath>            Path to .env config file")
 	_, _ = fmt.Fprintln(w, "                                Default: ~/.env.find-mac  (macOS/Linux)")
-	_, _ = fmt.Fprintln(w, "                                         $env:USERPROFILE\\.env.find-mac  (Windows)")
+	_, _ = fmt.Fprintln(w, "                                         $env:APPDATA\\Find-Meraki-Ports-With-MAC\\.env.find-mac  (Windows)")
 	_, _ = fmt.Fprintln(w, "                                The file is created automatically with commented")
-	_, _ = fmt.Fprintln(w, "                                stubs if it does not exist.")
+	_, _ = fmt.Fprintln(w, "                                stubs if it does not exist; \"init\" fills it in")
+	_, _ = fmt.Fprintln(w, "                                interactively.")
 	_, _ = fmt.Fprintln(w, "                                Use --env to point to a network share, project")
 	_, _ = fmt.Fprintln(w, "                                directory, or any other location.")
 	_, _ = fmt.Fprintln(w, "  --version                   Show version and exit")
@@ -823,25 +2073,67 @@ func printUsage(w *os.File) {
 	_, _ = fmt.Fprintln(w, "  MERAKI_API_KEY     Meraki Dashboard API key (required)")
 	_, _ = fmt.Fprintln(w, "  MERAKI_ORG         Default org name")
 	_, _ = fmt.Fprintln(w, "  MERAKI_NETWORK     Default network name or ALL")
-	_, _ = fmt.Fprintln(w, "  OUTPUT_FORMAT      csv | text | html")
+	_, _ = fmt.Fprintln(w, "  MERAKI_ORG_ID      Default organization ID (same as --org-id)")
+	_, _ = fmt.Fprintln(w, "  MERAKI_NETWORK_ID  Default network ID(s) (same as --network-id)")
+	_, _ = fmt.Fprintln(w, "  OUTPUT_FORMAT      csv | text | html | jsonl | xlsx | yaml")
 	_, _ = fmt.Fprintln(w, "  MERAKI_BASE_URL    API base URL (default https://api.meraki.com/api/v1)")
 	_, _ = fmt.Fprintln(w, "  MERAKI_RETRIES     Max API retry attempts on rate limit (default 6)")
+	_, _ = fmt.Fprintln(w, "  MERAKI_RETRY_STATUSES HTTP statuses to retry (default 429,502,503,504)")
+	_, _ = fmt.Fprintln(w, "  MERAKI_RETRY_MAX_ELAPSED Stop retrying a request after this long (default no limit)")
+	_, _ = fmt.Fprintln(w, "  MERAKI_RATE_LIMIT  Sustained API requests per second (default 10)")
 	_, _ = fmt.Fprintln(w, "  MERAKI_MAC_POLL    MAC table lookup poll attempts, 2s each (default 15)")
 	_, _ = fmt.Fprintln(w, "  DNS_SERVERS        Comma-separated DNS servers for PTR lookups")
+	_, _ = fmt.Fprintln(w, "  MERAKI_PROXY       API proxy URL or direct (default HTTPS_PROXY / HTTP_PROXY / NO_PROXY)")
+	_, _ = fmt.Fprintln(w, "  MERAKI_CA_FILE     Extra root CAs (PEM) for API requests")
+	_, _ = fmt.Fprintln(w, "  MERAKI_TLS_MIN_VERSION Minimum TLS version for API requests (1.2 or 1.3)")
+	_, _ = fmt.Fprintln(w, "  MERAKI_TLS_INSECURE_SKIP_VERIFY true to skip API certificate verification (lab use only)")
+	_, _ = fmt.Fprintln(w, "  MERAKI_USER_AGENT_SUFFIX Text appended to the API User-Agent")
+	_, _ = fmt.Fprintln(w, "  MERAKI_REQUEST_ID  X-Request-Id prefix of API calls (default random per run)")
+	_, _ = fmt.Fprintln(w, "  MERAKI_CACHE_TTL   Cache org/network/device listings this long (default off)")
+	_, _ = fmt.Fprintln(w, "  MERAKI_CACHE_DIR   Listing cache directory or sqlite:// / bolt:// / postgres:// URL (default: user cache directory)")
+	_, _ = fmt.Fprintln(w, "  EXCLUDE_SWITCHES   Comma-separated switch names or serials to skip")
+	_, _ = fmt.Fprintln(w, "  EXCLUDE_PORTS      Comma-separated port IDs to leave out of results")
+	_, _ = fmt.Fprintln(w, "  EXTRA_SWITCH_MODELS Comma-separated extra model prefixes to search as switches")
+	_, _ = fmt.Fprintln(w, "  OUI_FILE           IEEE oui.csv replacing the built-in OUI registry (--vendor)")
 	_, _ = fmt.Fprintln(w, "  LOG_FILE           Log file path (default Find-Meraki-Ports-With-MAC.log)")
 	_, _ = fmt.Fprintln(w, "  LOG_LEVEL          DEBUG | INFO | WARNING | ERROR")
+	_, _ = fmt.Fprintln(w, "  NOTIFY             true to enable desktop notifications in web mode")
+	_, _ = fmt.Fprintln(w, "  CSV_DELIMITER      comma | semicolon | tab | pipe")
+	_, _ = fmt.Fprintln(w, "  CSV_BOM            true to prefix CSV output with a UTF-8 BOM")
+	_, _ = fmt.Fprintln(w, "  CSV_QUOTE_ALL      true to quote every CSV field")
+	_, _ = fmt.Fprintln(w, "  CSV_CRLF           true to end CSV lines with CRLF")
+	_, _ = fmt.Fprintln(w, "  HISTORY_FILE       First-seen history file path or database URL, or off")
 	_, _ = fmt.Fprintln(w, "")
 	_, _ = fmt.Fprintln(w, "Examples:")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe init")
 	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --ip 192.168.1.100 --network ALL")
 	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --mac 00:11:22:33:44:55 --network ALL")
 	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --mac 08:f1:b3:6f:9c:* --output-format text")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --mac 08:f1:b3:6f:9c:* --output-format xlsx --output-file results.xlsx")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --mac 08:f1:b3:6f:9c:* --output-template \"{{.SwitchSerial}},{{.Port}},{{.Hostname}}\"")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --client-id k74272e --network HQ")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --serial Q2XX-XXXX-XXXX --port 12 --output-format text")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --port-security-report --network HQ")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --import-port-names ports.csv --dry-run")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe find \"vlan=30 AND vendor~'Axis' AND network='HQ'\"")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe report new-devices --since 7d")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe history migrate --dry-run")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe history prune --retention 180d --dry-run")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe backup create --output find-mac.tar.gz   (secrets encrypted with $BACKUP_PASSPHRASE)")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe token create --name \"nightly export\" --scope export --expires 30d   (web API token, needs SSO)")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe backup restore find-mac.tar.gz --dry-run")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe inventory --org-id 123456 --max-age 15m > inventory.json")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe merge shard1.jsonl shard2.jsonl --output combined.csv")
 	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --test-full-table --network City --switch ccc9300xa")
 	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --test-full-table --network City --switch ccc9300xa --port 3")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --test-full-table --network City --vlan 120")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --test-full-table --network City --switch ccc9300xa --port Gi1/0/1-12")
 	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --list-orgs")
 	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --list-networks --org \"My Org\"")
 	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --test-api")
 	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --interactive")
 	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --interactive --web-port 9090")
+	_, _ = fmt.Fprintln(w, "  Find-Meraki-Ports-With-MAC.exe --emit-openapi > openapi.yaml")
 }
 
 // writeOrganizations writes a formatted list of organizations to the specified file.
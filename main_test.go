@@ -17,11 +17,23 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"Find-Meraki-Ports-With-MAC/pkg/config"
+	"Find-Meraki-Ports-With-MAC/pkg/filters"
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
 	"Find-Meraki-Ports-With-MAC/pkg/meraki"
 	"Find-Meraki-Ports-With-MAC/pkg/output"
 )
@@ -108,6 +120,8 @@ func TestSelectNetworks(t *testing.T) {
 		{ID: "net1", Name: "Network 1"},
 		{ID: "net2", Name: "Network 2"},
 		{ID: "net3", Name: "Network 3"},
+		{ID: "net4", Name: "Branch-1"},
+		{ID: "net5", Name: "Branch-2"},
 	}
 
 	tests := []struct {
@@ -119,7 +133,7 @@ func TestSelectNetworks(t *testing.T) {
 		{
 			name:        "ALL networks",
 			networkName: "ALL",
-			wantCount:   3,
+			wantCount:   5,
 			wantErr:     false,
 		},
 		{
@@ -128,6 +142,42 @@ func TestSelectNetworks(t *testing.T) {
 			wantCount:   1,
 			wantErr:     false,
 		},
+		{
+			name:        "comma-separated list",
+			networkName: "Network 1, branch-2",
+			wantCount:   2,
+			wantErr:     false,
+		},
+		{
+			name:        "glob pattern",
+			networkName: "Branch-*",
+			wantCount:   2,
+			wantErr:     false,
+		},
+		{
+			name:        "overlapping terms are deduplicated",
+			networkName: "Branch-*,Branch-1,Network ?",
+			wantCount:   5,
+			wantErr:     false,
+		},
+		{
+			name:        "one term not found",
+			networkName: "Network 1,Nowhere",
+			wantCount:   0,
+			wantErr:     true,
+		},
+		{
+			name:        "pattern matches nothing",
+			networkName: "Lab-*",
+			wantCount:   0,
+			wantErr:     true,
+		},
+		{
+			name:        "malformed pattern",
+			networkName: "Branch-[",
+			wantCount:   0,
+			wantErr:     true,
+		},
 		{
 			name:        "not found",
 			networkName: "Non-existent",
@@ -150,6 +200,37 @@ func TestSelectNetworks(t *testing.T) {
 	}
 }
 
+func TestExcludeRows(t *testing.T) {
+	filters.SetExclusions([]string{"core"}, []string{"49"})
+	t.Cleanup(func() { filters.SetExclusions(nil, nil) })
+
+	rows := []output.ResultRow{
+		{SwitchName: "idf-1", Port: "3", MAC: "00:00:00:00:00:01"},
+		{SwitchName: "core-1", Port: "3", MAC: "00:00:00:00:00:02"},
+		{SwitchName: "idf-1", Port: "49", MAC: "00:00:00:00:00:03"},
+	}
+	got := excludeRows(rows)
+	if len(got) != 1 || got[0].MAC != "00:00:00:00:00:01" {
+		t.Errorf("excludeRows() = %+v, want only the idf-1 port 3 row", got)
+	}
+}
+
+func TestNetworksByID(t *testing.T) {
+	tests := []struct {
+		ids  string
+		want []meraki.Network
+	}{
+		{"", nil},
+		{"L_1", []meraki.Network{{ID: "L_1", Name: "L_1"}}},
+		{" L_1, N_2,,L_1 ", []meraki.Network{{ID: "L_1", Name: "L_1"}, {ID: "N_2", Name: "N_2"}}},
+	}
+	for _, tt := range tests {
+		if got := networksByID(tt.ids); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("networksByID(%q) = %+v, want %+v", tt.ids, got, tt.want)
+		}
+	}
+}
+
 func TestAddResult(t *testing.T) {
 	index := make(map[string]struct{})
 	var results []output.ResultRow
@@ -313,6 +394,9 @@ func TestParseIntEnv(t *testing.T) {
 func TestResolveEnvFile_Default(t *testing.T) {
 	// With no --env in os.Args (test runner args won't contain it) the default
 	// should be ~/.env.find-mac
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows defaults to %APPDATA%; see TestDefaultEnvFile")
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		t.Skip("cannot determine home dir:", err)
@@ -346,6 +430,33 @@ func TestResolveEnvFile_FlagSpace(t *testing.T) {
 	}
 }
 
+func TestDefaultEnvFile(t *testing.T) {
+	home := filepath.Join("home", "kent")
+	appData := filepath.Join("home", "kent", "AppData", "Roaming")
+	getenv := func(key string) string {
+		if key == "APPDATA" {
+			return appData
+		}
+		return ""
+	}
+	none := func(string) bool { return false }
+	legacy := filepath.Join(home, ".env.find-mac")
+
+	if got := defaultEnvFile("linux", home, getenv, none); got != legacy {
+		t.Errorf("linux: got %q, want %q", got, legacy)
+	}
+	want := filepath.Join(appData, "Find-Meraki-Ports-With-MAC", ".env.find-mac")
+	if got := defaultEnvFile("windows", home, getenv, none); got != want {
+		t.Errorf("windows: got %q, want %q", got, want)
+	}
+	if got := defaultEnvFile("windows", home, getenv, func(p string) bool { return p == legacy }); got != legacy {
+		t.Errorf("windows with an existing home file: got %q, want %q", got, legacy)
+	}
+	if got := defaultEnvFile("windows", home, func(string) string { return "" }, none); got != legacy {
+		t.Errorf("windows without APPDATA: got %q, want %q", got, legacy)
+	}
+}
+
 // ── printVersion ─────────────────────────────────────────────────────────────
 
 func TestPrintVersion(t *testing.T) {
@@ -472,3 +583,366 @@ func TestLookupOUI_ShortMAC(t *testing.T) {
 		t.Errorf("lookupOUI(\"AA:BB\") = %q, want \"\" (too short)", got)
 	}
 }
+
+// ── Desktop notifications ─────────────────────────────────────────────────────
+
+func TestNotifyQuoting(t *testing.T) {
+	if got := psQuote("it's"); got != "it''s" {
+		t.Errorf("psQuote() = %q, want %q", got, "it''s")
+	}
+	if got := appleQuote(`say "hi" \ bye`); got != `"say \"hi\" \\ bye"` {
+		t.Errorf("appleQuote() = %q", got)
+	}
+}
+
+func TestVirtualMACNote(t *testing.T) {
+	tests := []struct {
+		mac  string
+		want string
+	}{
+		{"00:00:5e:00:01:0a", "VRRP virtual MAC (VRID 10)"},
+		{"00:00:0c:07:ac:01", "HSRP virtual MAC (group 1)"},
+		{"aa:bb:cc:dd:ee:ff", ""},
+		{"not-a-mac", ""},
+	}
+	for _, tt := range tests {
+		if got := virtualMACNote(tt.mac); got != tt.want {
+			t.Errorf("virtualMACNote(%q) = %q, want %q", tt.mac, got, tt.want)
+		}
+	}
+}
+
+func TestAnonymizeRows(t *testing.T) {
+	rows := []output.ResultRow{{MAC: "00:11:22:33:44:55", Hostname: "pc1"}}
+	if got := anonymizeRows(nil, rows); &got[0] != &rows[0] {
+		t.Error("anonymizeRows(nil) should return rows unchanged")
+	}
+	got := anonymizeRows(output.NewAnonymizerWithKey([]byte("k")), rows)
+	if got[0].MAC == rows[0].MAC || got[0].Hostname == rows[0].Hostname {
+		t.Errorf("anonymizeRows() = %+v, want scrubbed copy", got[0])
+	}
+	if rows[0].MAC != "00:11:22:33:44:55" {
+		t.Error("anonymizeRows() modified the original rows")
+	}
+}
+
+func TestLoadRowTemplate(t *testing.T) {
+	if tmpl, err := loadRowTemplate(""); tmpl != nil || err != nil {
+		t.Errorf("loadRowTemplate(\"\") = %v, %v; want nil, nil", tmpl, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "row.tmpl")
+	if err := os.WriteFile(path, []byte("FILE {{.MAC}}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for value, want := range map[string]string{path: "FILE aa\n", "INLINE {{.MAC}}": "INLINE aa\n"} {
+		tmpl, err := loadRowTemplate(value)
+		if err != nil {
+			t.Fatalf("loadRowTemplate(%q) error: %v", value, err)
+		}
+		var buf bytes.Buffer
+		_ = output.WriteTemplate(&buf, tmpl, []output.ResultRow{{MAC: "aa"}})
+		if buf.String() != want {
+			t.Errorf("loadRowTemplate(%q) rendered %q, want %q", value, buf.String(), want)
+		}
+	}
+}
+
+func TestSortResults(t *testing.T) {
+	rows := []output.ResultRow{
+		{NetworkName: "A", SwitchName: "core", Port: "49", Confidence: 40},
+		{NetworkName: "B", SwitchName: "edge", Port: "3", Confidence: 95},
+		{NetworkName: "A", SwitchName: "dist", Port: "1", Confidence: 40},
+	}
+	sortResults(rows)
+	var got []string
+	for _, r := range rows {
+		got = append(got, r.SwitchName)
+	}
+	if want := "edge,core,dist"; strings.Join(got, ",") != want {
+		t.Errorf("sortResults() order = %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestEmitResults_WritesToOut(t *testing.T) {
+	rows := []output.ResultRow{
+		{NetworkName: "B", SwitchName: "sw1", Port: "1", MAC: "00:11:22:33:44:66"},
+		{NetworkName: "A", SwitchName: "sw1", Port: "1", MAC: "00:11:22:33:44:55"},
+	}
+	cols, _ := output.ParseColumns("network,mac")
+	var buf bytes.Buffer
+	log := logger.NewWriter(io.Discard, logger.LevelError)
+	if err := emitResults(config.Config{OutputFormat: "csv"}, rows, emitOptions{Out: &buf, Columns: cols}, log); err != nil {
+		t.Fatalf("emitResults() error: %v", err)
+	}
+	if want := "Network,MAC\nA,00:11:22:33:44:55\nB,00:11:22:33:44:66\n"; buf.String() != want {
+		t.Errorf("emitResults() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestEmitResults_Truncates(t *testing.T) {
+	rows := []output.ResultRow{
+		{NetworkName: "B", SwitchName: "sw1", Port: "1", MAC: "00:11:22:33:44:66"},
+		{NetworkName: "A", SwitchName: "sw1", Port: "1", MAC: "00:11:22:33:44:55"},
+		{NetworkName: "C", SwitchName: "sw1", Port: "1", MAC: "00:11:22:33:44:77"},
+	}
+	cols, _ := output.ParseColumns("network,mac")
+	var buf, logs bytes.Buffer
+	log := logger.NewWriter(&logs, logger.LevelWarning)
+	if err := emitResults(config.Config{OutputFormat: "csv", MaxResults: 2}, rows, emitOptions{Out: &buf, Columns: cols}, log); err != nil {
+		t.Fatalf("emitResults() error: %v", err)
+	}
+	want := "Network,MAC\nA,00:11:22:33:44:55\nB,00:11:22:33:44:66\n# WARNING: results truncated: showing the first 2 of 3 matches; refine your pattern to see the rest\n"
+	if buf.String() != want {
+		t.Errorf("emitResults() = %q, want %q", buf.String(), want)
+	}
+	if !strings.Contains(logs.String(), "raise --max-results") {
+		t.Errorf("log = %q, want a truncation warning", logs.String())
+	}
+
+	buf.Reset()
+	if err := emitResults(config.Config{OutputFormat: "terraform-external", MaxResults: 2}, rows, emitOptions{Out: &buf}, log); err != nil {
+		t.Fatalf("emitResults() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"matches":"3"`) {
+		t.Errorf("terraform-external output = %s, want all 3 matches counted", buf.String())
+	}
+}
+
+func TestEmitResults_Warnings(t *testing.T) {
+	rows := []output.ResultRow{{NetworkName: "HQ", SwitchName: "sw1", Port: "1", MAC: "00:11:22:33:44:55"}}
+	ws := &output.Warnings{}
+	ws.Add(output.Warning{Kind: output.WarningSkippedSwitch, Message: "switch sw2 in HQ was skipped: 404", Network: "HQ", Device: "Q2AA-0002"})
+	log := logger.NewWriter(io.Discard, logger.LevelError)
+
+	var buf bytes.Buffer
+	if err := emitResults(config.Config{OutputFormat: "text"}, rows, emitOptions{Out: &buf, Warnings: ws}, log); err != nil {
+		t.Fatalf("emitResults() error: %v", err)
+	}
+	if !strings.HasSuffix(buf.String(), "WARNING: switch sw2 in HQ was skipped: 404\n") {
+		t.Errorf("text output = %q, want the warning last", buf.String())
+	}
+
+	buf.Reset()
+	if err := emitResults(config.Config{OutputFormat: "jsonl"}, rows, emitOptions{Out: &buf, Warnings: ws}, log); err != nil {
+		t.Fatalf("emitResults() error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], `{"warnings":[{"kind":"skipped-switch"`) {
+		t.Errorf("jsonl output = %q, want the row then a warnings object", buf.String())
+	}
+}
+
+func TestPageBounds(t *testing.T) {
+	tests := []struct {
+		total, page, size     int
+		start, end, wantServe int
+	}{
+		{2500, 1, 0, 0, 2500, 1},
+		{2500, 1, 100, 0, 100, 1},
+		{2500, 3, 100, 200, 300, 3},
+		{2500, 25, 100, 2400, 2500, 25},
+		{2500, 99, 100, 2400, 2500, 25},
+		{250, 3, 100, 200, 250, 3},
+		{250, 0, 100, 0, 100, 1},
+		{0, 4, 100, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		start, end, served := pageBounds(tt.total, tt.page, tt.size)
+		if start != tt.start || end != tt.end || served != tt.wantServe {
+			t.Errorf("pageBounds(%d, %d, %d) = %d, %d, %d; want %d, %d, %d",
+				tt.total, tt.page, tt.size, start, end, served, tt.start, tt.end, tt.wantServe)
+		}
+	}
+}
+
+func TestWriteCachedJSON_ETagRevalidation(t *testing.T) {
+	body := map[string]string{"a": "b"}
+	rec := httptest.NewRecorder()
+	writeCachedJSON(rec, httptest.NewRequest(http.MethodGet, "/api/networks", nil), body, time.Minute)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" || rec.Body.String() != "{\"a\":\"b\"}\n" {
+		t.Fatalf("first response = %d, ETag %q, body %q", rec.Code, etag, rec.Body.String())
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "private, max-age=60" {
+		t.Errorf("Cache-Control = %q, want private, max-age=60", cc)
+	}
+
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/networks", nil)
+		req.Header.Set("If-None-Match", inm)
+		rec = httptest.NewRecorder()
+		writeCachedJSON(rec, req, body, time.Minute)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %q: got %d with %d body bytes, want 304 and no body", inm, rec.Code, rec.Body.Len())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/networks", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	writeCachedJSON(rec, req, map[string]string{"a": "changed"}, time.Minute)
+	if rec.Code != http.StatusOK {
+		t.Errorf("changed body with stale ETag: got %d, want 200", rec.Code)
+	}
+}
+
+func TestRetryPolicyFromConfig(t *testing.T) {
+	p := retryPolicy(config.Config{MaxRetries: 4, RetryStatuses: "429, 500", RetryElapsed: "90s"})
+	if p.MaxAttempts != 4 || len(p.Statuses) != 2 || p.Statuses[1] != 500 || p.MaxElapsed != 90*time.Second {
+		t.Errorf("retryPolicy() = %+v", p)
+	}
+	if p := retryPolicy(config.Config{MaxRetries: 6}); p.Statuses != nil || p.MaxElapsed != 0 {
+		t.Errorf("unset settings should keep the client defaults, got %+v", p)
+	}
+}
+
+func TestDescribeAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":["Invalid API key"]}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := meraki.NewClient("key", srv.URL, 1).GetOrganizations(context.Background())
+	if got := describeAPIError(err); !strings.Contains(got, "401") || !strings.Contains(got, "MERAKI_API_KEY") {
+		t.Errorf("describeAPIError(401) = %q", got)
+	}
+	if got := describeAPIError(errors.New("dial tcp: timeout")); got != "dial tcp: timeout" {
+		t.Errorf("describeAPIError(plain) = %q", got)
+	}
+}
+
+func TestHandleResolveRejectsBadHostOverrides(t *testing.T) {
+	body := `{"apiKey":"k","networkId":"N_1","mac":"aa:bb:cc:dd:ee:ff","hostOverrides":{"ip":"10.0.0.1"}}`
+	rec := httptest.NewRecorder()
+	handleResolve(rec, httptest.NewRequest(http.MethodPost, "/api/resolve", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("got %d, want 400", rec.Code)
+	}
+}
+
+func TestStaticETag(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	etag := staticETag(dir, "app.js")
+	if etag == "" || etag[0] != '"' {
+		t.Errorf("staticETag(app.js) = %q, want a quoted tag", etag)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("xy"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if staticETag(dir, "app.js") == etag {
+		t.Error("staticETag did not change after the file changed")
+	}
+	for _, name := range []string{"missing.js", "", "../" + filepath.Base(dir)} {
+		if got := staticETag(dir, name); got != "" {
+			t.Errorf("staticETag(%q) = %q, want empty", name, got)
+		}
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	var seen string
+	h := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = meraki.RequestIDFrom(detachedContext(r))
+	}))
+	for in, keep := range map[string]bool{"INC0042": true, "": false, "bad id\r\n": false} {
+		req := httptest.NewRequest("GET", "/api/networks", nil)
+		if in != "" {
+			req.Header.Set("X-Request-Id", in)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		got := rec.Header().Get("X-Request-Id")
+		if got != seen || (keep && got != in) || (!keep && !meraki.ValidRequestID(got)) {
+			t.Errorf("X-Request-Id %q: echoed %q, context %q", in, got, seen)
+		}
+	}
+	if got := userAgent(config.Config{UserAgent: "ServiceDesk"}); got != meraki.DefaultUserAgent+"/"+Version+" ServiceDesk" {
+		t.Errorf("userAgent() = %q", got)
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	var buf bytes.Buffer
+	tests := []struct {
+		mode string
+		w    io.Writer
+		env  map[string]string
+		want bool
+	}{
+		{output.ColorAlways, &buf, nil, true},
+		{output.ColorNever, os.Stdout, nil, false},
+		{output.ColorAuto, &buf, nil, false},
+		{output.ColorAuto, os.Stdout, map[string]string{"NO_COLOR": "1"}, false},
+		{output.ColorAuto, os.Stdout, map[string]string{"TERM": "dumb"}, false},
+	}
+	for _, tt := range tests {
+		if got := useColor(tt.mode, tt.w, env(tt.env)); got != tt.want {
+			t.Errorf("useColor(%q, %T, %v) = %v, want %v", tt.mode, tt.w, tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestStringList(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var macs stringList
+	fs.Var(&macs, "mac", "")
+	if err := fs.Parse([]string{"--mac", "aa:bb:cc:dd:ee:ff", "--mac", "11:22:33:44:55:66,77:88:99:aa:bb:cc"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "aa:bb:cc:dd:ee:ff,11:22:33:44:55:66,77:88:99:aa:bb:cc"; macs.String() != want {
+		t.Errorf("stringList = %q, want %q", macs.String(), want)
+	}
+}
+
+func TestSearchContext(t *testing.T) {
+	ctx, cancel := searchContext(context.Background(), "20ms")
+	defer cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("search context did not time out")
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("ctx.Err() = %v, want DeadlineExceeded", ctx.Err())
+	}
+
+	ctx, cancel = searchContext(context.Background(), "")
+	if ctx.Err() != nil {
+		t.Errorf("without --timeout ctx.Err() = %v, want nil", ctx.Err())
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Error("cancel() did not cancel the search context")
+	}
+}
+
+func TestPollMacTable_StopsWhenCancelled(t *testing.T) {
+	defer func(d time.Duration) { macTablePollInterval = d }(macTablePollInterval)
+	macTablePollInterval = time.Hour
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/devices/Q2AA-0001/liveTools/macTable" {
+			_, _ = w.Write([]byte(`{"macTableId":"T1"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"running"}`))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if entries := pollMacTable(ctx, meraki.NewClient("key", srv.URL, 1), "Q2AA-0001", 15); entries != nil {
+		t.Errorf("pollMacTable() = %v, want nil", entries)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("pollMacTable() took %s after cancellation, want it to stop at once", elapsed)
+	}
+}
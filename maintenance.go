@@ -0,0 +1,207 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDrainTimeout = 5 * time.Minute
+	maxMaintenanceNote  = 200
+)
+
+// maintenanceMode drains the web server for an upgrade: while it is on, new
+// searches and live-tool jobs are refused with 503 and a notice, and the ones
+// already running are left to finish. Everything else stays available so the
+// UI can show the notice and an admin can watch the drain.
+type maintenanceMode struct {
+	mu      sync.Mutex
+	enabled bool
+	since   time.Time
+	reason  string
+	running int
+	idle    chan struct{} // closed when running drops to zero; nil if nobody waits
+	now     func() time.Time
+}
+
+var webMaintenance = &maintenanceMode{now: time.Now}
+
+// maintenanceStatus is the JSON view of the maintenance state.
+type maintenanceStatus struct {
+	Enabled bool       `json:"enabled"`
+	Since   *time.Time `json:"since,omitempty"`
+	Reason  string     `json:"reason,omitempty"`
+	Running int        `json:"running"`
+	Drained bool       `json:"drained"`
+}
+
+// isJob reports whether r starts a search or a live-tool job, the requests
+// maintenance mode refuses and waits for.
+func isJob(r *http.Request) bool {
+	return r.Method == http.MethodPost && (r.URL.Path == "/api/resolve" || r.URL.Path == "/api/identify")
+}
+
+// begin registers a new job, or returns false while in maintenance.
+func (m *maintenanceMode) begin() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.enabled {
+		return false
+	}
+	m.running++
+	return true
+}
+
+// done ends a job started with begin.
+func (m *maintenanceMode) done() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running--
+	if m.running == 0 && m.idle != nil {
+		close(m.idle)
+		m.idle = nil
+	}
+}
+
+// set turns maintenance mode on or off. Turning it on again keeps the
+// original start time but updates the reason.
+func (m *maintenanceMode) set(enabled bool, reason string) maintenanceStatus {
+	m.mu.Lock()
+	if enabled && !m.enabled {
+		m.since = m.now()
+	}
+	m.enabled = enabled
+	m.reason = ""
+	if enabled {
+		m.reason = reason
+	}
+	m.mu.Unlock()
+	return m.status()
+}
+
+func (m *maintenanceMode) status() maintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := maintenanceStatus{Enabled: m.enabled, Reason: m.reason, Running: m.running}
+	if m.enabled {
+		since := m.since
+		s.Since, s.Drained = &since, m.running == 0
+	}
+	return s
+}
+
+// wait blocks until no job is running or ctx ends.
+func (m *maintenanceMode) wait(ctx context.Context) error {
+	m.mu.Lock()
+	if m.running == 0 {
+		m.mu.Unlock()
+		return nil
+	}
+	if m.idle == nil {
+		m.idle = make(chan struct{})
+	}
+	idle := m.idle
+	m.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notice is the message refused requests get.
+func (m *maintenanceMode) notice() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	msg := "The server is in maintenance mode and not accepting new searches"
+	if m.reason != "" {
+		msg += " (" + m.reason + ")"
+	}
+	return msg + ". Please try again in a few minutes."
+}
+
+// wrap refuses new jobs while in maintenance and counts the running ones.
+func (m *maintenanceMode) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isJob(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !m.begin() {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "120")
+			writeJSONError(w, http.StatusServiceUnavailable, m.notice())
+			return
+		}
+		defer m.done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleMaintenance reports the maintenance state (GET) or changes it (PUT
+// with {"enabled": true, "reason": "..."}). Poll GET until drained is true
+// before stopping the server.
+func handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == http.MethodGet {
+		writeJSON(w, webMaintenance.status())
+		return
+	}
+	var req struct {
+		Enabled *bool  `json:"enabled"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4<<10)).Decode(&req); err != nil || req.Enabled == nil {
+		http.Error(w, `{"error": "Invalid request body: enabled is required"}`, http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if len(reason) > maxMaintenanceNote {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("reason is longer than %d bytes", maxMaintenanceNote))
+		return
+	}
+	s := webMaintenance.set(*req.Enabled, reason)
+	if s.Enabled {
+		newWebLogger().Infof("Maintenance mode on (%d job(s) running)", s.Running)
+	} else {
+		newWebLogger().Infof("Maintenance mode off")
+	}
+	writeJSON(w, s)
+}
+
+// drainWebServer enters maintenance mode and waits up to timeout for running
+// jobs, which save their history as they finish, then closes the access log.
+// It reports whether every job finished.
+func drainWebServer(timeout time.Duration, accessLog *accessLog) bool {
+	webMaintenance.set(true, "server is shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	drained := webMaintenance.wait(ctx) == nil
+	if accessLog != nil {
+		_ = accessLog.close()
+	}
+	return drained
+}
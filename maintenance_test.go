@@ -0,0 +1,118 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceModeDrains(t *testing.T) {
+	m := &maintenanceMode{now: time.Now}
+	started, release := make(chan struct{}), make(chan struct{})
+	h := m.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/resolve" {
+			close(started)
+			<-release
+		}
+	}))
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader("{}")))
+		return rec
+	}
+
+	finished := make(chan int)
+	go func() { finished <- serve("POST", "/api/resolve").Code }()
+	<-started
+	m.set(true, "upgrade to 2.4")
+
+	rec := serve("POST", "/api/identify")
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "upgrade to 2.4") || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("new job during maintenance = %d %q, want 503 with the reason and Retry-After", rec.Code, rec.Body.String())
+	}
+	if code := serve("GET", "/api/networks").Code; code != http.StatusOK {
+		t.Errorf("GET /api/networks during maintenance = %d, want 200", code)
+	}
+	if s := m.status(); !s.Enabled || s.Running != 1 || s.Drained || s.Since == nil {
+		t.Errorf("status while draining = %+v", s)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.wait(ctx); err == nil {
+		t.Error("wait() returned before the running job finished")
+	}
+
+	close(release)
+	if code := <-finished; code != http.StatusOK {
+		t.Errorf("running job = %d, want it to finish with 200", code)
+	}
+	if err := m.wait(context.Background()); err != nil {
+		t.Errorf("wait() after the job finished = %v", err)
+	}
+	if s := m.status(); !s.Drained || s.Running != 0 {
+		t.Errorf("status after drain = %+v, want drained", s)
+	}
+
+	m.set(false, "")
+	if s := m.status(); s.Enabled || s.Since != nil || s.Reason != "" {
+		t.Errorf("status after turning off = %+v", s)
+	}
+}
+
+func TestHandleMaintenance(t *testing.T) {
+	defer webMaintenance.set(false, "")
+	for _, tt := range []struct {
+		body        string
+		wantCode    int
+		wantEnabled bool
+	}{
+		{`{"reason":"no enabled field"}`, http.StatusBadRequest, false},
+		{`{"enabled":true,"reason":"` + strings.Repeat("x", maxMaintenanceNote+1) + `"}`, http.StatusBadRequest, false},
+		{`{"enabled":true,"reason":" patching "}`, http.StatusOK, true},
+		{`{"enabled":false}`, http.StatusOK, false},
+	} {
+		rec := httptest.NewRecorder()
+		handleMaintenance(rec, httptest.NewRequest("PUT", "/api/maintenance", strings.NewReader(tt.body)))
+		if rec.Code != tt.wantCode {
+			t.Errorf("PUT %s = %d, want %d", tt.body, rec.Code, tt.wantCode)
+			continue
+		}
+		rec = httptest.NewRecorder()
+		handleMaintenance(rec, httptest.NewRequest("GET", "/api/maintenance", nil))
+		var s maintenanceStatus
+		if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil || s.Enabled != tt.wantEnabled {
+			t.Errorf("after PUT %s: GET = %s, %v; want enabled=%v", tt.body, rec.Body.String(), err, tt.wantEnabled)
+		}
+		if s.Enabled && s.Reason != "patching" {
+			t.Errorf("reason = %q, want it trimmed", s.Reason)
+		}
+	}
+}
+
+func TestMaintenanceNeedsAdmin(t *testing.T) {
+	for _, method := range []string{"GET", "PUT"} {
+		r := httptest.NewRequest(method, "/api/maintenance", nil)
+		if requiredRole(r) != roleAdmin || requiredScope(r) != scopeAdmin {
+			t.Errorf("%s /api/maintenance: role %v, scope %v; want admin", method, requiredRole(r), requiredScope(r))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/config"
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// formatByExt maps --output file extensions to output formats for merge.
+var formatByExt = map[string]string{
+	".csv":   "csv",
+	".txt":   "text",
+	".html":  "html",
+	".htm":   "html",
+	".jsonl": "jsonl",
+	".json":  "jsonl",
+	".xlsx":  "xlsx",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+}
+
+// runMergeCommand implements "merge FILE... [--output PATH] [--output-format F]":
+// it reads jsonl/yaml result files from separate runs (e.g. --shard), merges
+// them with output.MergeRows and writes the combined results. Flags may
+// follow the file names. It returns the exit code.
+func runMergeCommand(w io.Writer, args []string, getenv func(string) string) int {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	outFlag := fs.String("output", "", "Write the merged results to this file (format from its extension)")
+	formatFlag := fs.String("output-format", "", "Output format (default: from --output's extension, else OUTPUT_FORMAT, else csv)")
+	var files []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(files) == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: Find-Meraki-Ports-With-MAC merge results1.jsonl results2.jsonl [--output combined.csv]")
+		return 2
+	}
+
+	format := strings.ToLower(firstNonEmpty(*formatFlag, formatByExt[strings.ToLower(filepath.Ext(*outFlag))], getenv("OUTPUT_FORMAT"), config.DefaultOutputFormat))
+	switch format {
+	case "csv", "text", "html", "jsonl", "xlsx", "yaml", "terraform-external":
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: unknown output format %q\n", format)
+		return 2
+	}
+
+	var rows []output.ResultRow
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		read, err := output.ReadResults(f)
+		_ = f.Close()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: %s: %v\n", name, err)
+			return 1
+		}
+		rows = append(rows, read...)
+	}
+	merged := output.MergeRows(rows)
+
+	opts := emitOptions{Out: w}
+	var file *output.AtomicFile
+	if *outFlag != "" && *outFlag != "-" {
+		var err error
+		if file, err = output.CreateAtomic(*outFlag); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: --output: %v\n", err)
+			return 1
+		}
+		defer file.Abort()
+		opts.Out = file
+	}
+	log := logger.NewWriter(os.Stderr, logger.LevelWarning)
+	if err := emitResults(config.Config{OutputFormat: format}, merged, opts, log); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	if file != nil {
+		if err := file.Commit(); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: --output: %v\n", err)
+			return 1
+		}
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Merged %d row(s) from %d file(s) into %d result(s)\n", len(rows), len(files), len(merged))
+	return 0
+}
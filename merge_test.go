@@ -0,0 +1,89 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunMergeCommand(t *testing.T) {
+	dir := t.TempDir()
+	shard1 := filepath.Join(dir, "shard1.jsonl")
+	shard2 := filepath.Join(dir, "shard2.yaml")
+	if err := os.WriteFile(shard1, []byte(
+		`{"org":"Org","network":"HQ","switch":"sw1","serial":"S1","port":"3","mac":"00:11:22:33:44:55","uplink":false,"source":"network-clients","ip":"10.0.0.5"}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(shard2, []byte(`- org: Org
+  network: HQ
+  switch: sw1
+  serial: S1
+  port: "3"
+  mac: "00:11:22:33:44:55"
+  vlan: 30
+  source: mac-table
+- org: Org
+  network: HQ
+  switch: sw9
+  serial: S9
+  port: "12"
+  mac: "00:11:22:33:44:66"
+  source: device-clients
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	noEnv := func(string) string { return "" }
+
+	// Flags may follow the file names; the format comes from --output's extension.
+	out := filepath.Join(dir, "combined.csv")
+	if code := runMergeCommand(&bytes.Buffer{}, []string{shard1, shard2, "--output", out}, noEnv); code != 0 {
+		t.Fatalf("merge exit code = %d", code)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("combined.csv has %d lines, want header + 2:\n%s", len(lines), data)
+	}
+	if !strings.Contains(lines[1], "sw1") || !strings.Contains(lines[1], "10.0.0.5") {
+		t.Errorf("merged row = %s, want sw1 with the IP from shard 1", lines[1])
+	}
+
+	var buf bytes.Buffer
+	if code := runMergeCommand(&buf, []string{"--output-format", "jsonl", shard1, shard2}, noEnv); code != 0 {
+		t.Fatalf("merge exit code = %d", code)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("jsonl output has %d rows, want 2:\n%s", n, buf.String())
+	}
+	first := strings.SplitN(buf.String(), "\n", 2)[0]
+	if !strings.Contains(first, `"ip":"10.0.0.5"`) || !strings.Contains(first, `"vlan":30`) || !strings.Contains(first, `"source":"mac-table"`) {
+		t.Errorf("merged row = %s, want the mac-table row with the IP from shard 1", first)
+	}
+
+	if code := runMergeCommand(&bytes.Buffer{}, nil, noEnv); code != 2 {
+		t.Errorf("merge without files exit code = %d, want 2", code)
+	}
+	if code := runMergeCommand(&bytes.Buffer{}, []string{filepath.Join(dir, "missing.jsonl")}, noEnv); code != 1 {
+		t.Errorf("merge of a missing file exit code = %d, want 1", code)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// notifyMinDuration is how long a search must run before a desktop notification
+// is worth sending; quick lookups finish while the user is still watching.
+const notifyMinDuration = 10 * time.Second
+
+// powerShellAppID is the AppUserModelID Windows registers for PowerShell. Toasts
+// are only shown for an AUMID with a Start Menu entry, so they are sent as
+// PowerShell rather than under a made-up name that Windows silently drops.
+const powerShellAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// notifySearchComplete fires a desktop notification summarising a finished search
+// when --notify is enabled and the search ran for at least notifyMinDuration.
+func notifySearchComplete(query string, results int, elapsed time.Duration) {
+	if !webNotify || elapsed < notifyMinDuration {
+		return
+	}
+	msg := fmt.Sprintf("%s: %d result(s) in %s", query, results, elapsed.Round(time.Second))
+	sendDesktopNotification("Find Meraki Ports", msg)
+}
+
+// sendDesktopNotification shows a native OS notification. Failures are ignored —
+// notifications are a convenience and must never interrupt a search.
+func sendDesktopNotification(title, message string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		script := fmt.Sprintf(`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null; `+
+			`$t = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02); `+
+			`$x = $t.GetElementsByTagName('text'); $x.Item(0).AppendChild($t.CreateTextNode('%s')) | Out-Null; $x.Item(1).AppendChild($t.CreateTextNode('%s')) | Out-Null; `+
+			`[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('%s').Show([Windows.UI.Notifications.ToastNotification]::new($t))`,
+			psQuote(title), psQuote(message), psQuote(powerShellAppID))
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleQuote(message), appleQuote(title))
+		cmd = exec.Command("osascript", "-e", script)
+	default: // linux and others
+		cmd = exec.Command("notify-send", title, message)
+	}
+	if err := cmd.Start(); err != nil {
+		return
+	}
+	go func() { _ = cmd.Wait() }() // reap the helper so it does not linger as a zombie
+}
+
+// psQuote escapes a string for use inside a single-quoted PowerShell literal.
+func psQuote(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
+
+// appleQuote returns s as a double-quoted AppleScript string literal.
+func appleQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
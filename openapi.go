@@ -0,0 +1,43 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	_ "embed"
+	"io"
+	"net/http"
+)
+
+// openAPISpec documents the --interactive JSON API. Generate a client from it
+// with "make python-client", or fetch it from a running server at
+// /api/openapi.yaml. Keep it in step with registerAPIRoutes;
+// TestOpenAPICoversRoutes fails when a route is missing.
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// writeOpenAPI writes the embedded OpenAPI spec to w (for --emit-openapi).
+func writeOpenAPI(w io.Writer) error {
+	_, err := w.Write(openAPISpec)
+	return err
+}
+
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	if err := writeOpenAPI(w); err != nil {
+		newWebLogger().Warnf("Writing OpenAPI spec: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+// devicesByNetwork lists the devices of networks with one organization-wide
+// request instead of one per network, restricted to networkIDs when any are
+// given. Every network gets an entry, empty if it has no devices, so the
+// caller does not list it again; devices of other networks are dropped.
+func devicesByNetwork(ctx context.Context, client *meraki.MerakiClient, orgID string, networks []meraki.Network, networkIDs []string) (map[string][]meraki.Device, error) {
+	devices, err := client.GetOrganizationDevices(ctx, orgID, networkIDs)
+	if err != nil {
+		return nil, err
+	}
+	byNetwork := make(map[string][]meraki.Device, len(networks))
+	for _, n := range networks {
+		byNetwork[n.ID] = []meraki.Device{}
+	}
+	for _, dev := range devices {
+		if list, ok := byNetwork[dev.NetworkID]; ok {
+			byNetwork[dev.NetworkID] = append(list, dev)
+		}
+	}
+	return byNetwork, nil
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+func TestDevicesByNetwork(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/organizations/O1/devices" {
+			http.NotFound(w, r)
+			return
+		}
+		calls++
+		_, _ = w.Write([]byte(`[
+			{"serial":"Q2AA-0001","name":"sw-core","model":"MS250-48","productType":"switch","networkId":"N1"},
+			{"serial":"Q2AA-0002","name":"ap-lobby","model":"MR46","productType":"wireless","networkId":"N1"},
+			{"serial":"Q2AA-0003","name":"sw-other","model":"MS120-8","productType":"switch","networkId":"N9"}]`))
+	}))
+	defer srv.Close()
+
+	networks := []meraki.Network{{ID: "N1", Name: "HQ"}, {ID: "N2", Name: "Branch"}}
+	got, err := devicesByNetwork(context.Background(), meraki.NewClient("key", srv.URL, 1), "O1", networks, nil)
+	if err != nil {
+		t.Fatalf("devicesByNetwork() error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("requests = %d, want 1", calls)
+	}
+	if len(got["N1"]) != 2 || got["N1"][0].Serial != "Q2AA-0001" {
+		t.Errorf("N1 devices = %+v, want sw-core and ap-lobby", got["N1"])
+	}
+	if devices, ok := got["N2"]; !ok || len(devices) != 0 {
+		t.Errorf("N2 devices = %+v (listed %v), want an empty entry", devices, ok)
+	}
+	if _, ok := got["N9"]; ok {
+		t.Error("devices of unselected network N9 should be dropped")
+	}
+}
@@ -21,6 +21,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
 )
 
 // ouiCache stores OUI prefix → vendor name to avoid duplicate API calls.
@@ -45,7 +47,7 @@ func lookupOUI(mac string) string {
 		return cached.(string)
 	}
 
-	client := &http.Client{Timeout: 4 * time.Second}
+	client := &http.Client{Timeout: 4 * time.Second, Transport: meraki.Transport()}
 	resp, err := client.Get("https://api.macvendors.com/" + oui)
 	if err != nil {
 		ouiCache.Store(oui, "")
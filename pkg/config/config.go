@@ -0,0 +1,458 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package config loads command-line flags, environment variables and the .env
+// file into a single validated Config struct.
+//
+// Precedence for every setting is: flag > environment (including values loaded
+// from the .env file) > built-in default.
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/filters"
+	"Find-Meraki-Ports-With-MAC/pkg/history"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/query"
+)
+
+// Default values applied when neither a flag nor an environment variable is set.
+const (
+	DefaultBaseURL      = "https://api.meraki.com/api/v1"
+	DefaultOutputFormat = "csv"
+	DefaultMaxRetries   = 6
+	DefaultRateLimit    = 10
+	DefaultMacTablePoll = 15
+	DefaultMaxResults   = 10000
+	DefaultLogFile      = "Find-Meraki-Ports-With-MAC.log"
+	DefaultLogLevel     = "DEBUG"
+)
+
+// Config holds all configuration options from environment variables and command-line flags.
+type Config struct {
+	APIKey        string // Meraki Dashboard API key
+	OrgName       string // Organization name filter
+	OrgID         string // Organization ID; skips the organization name lookup
+	NetworkName   string // Network name filter or "ALL"
+	NetworkID     string // Comma-separated network IDs; skip the network name lookup
+	OutputFormat  string // Output format: csv, text, html, jsonl, xlsx, yaml, or terraform-external
+	BaseURL       string // Meraki API base URL
+	MaxRetries    int    // Maximum number of API request retries on 429
+	RateLimit     int    // Sustained Meraki API requests per second (token bucket)
+	RetryStatuses string // Comma-separated HTTP statuses to retry ("" = 429,502,503,504)
+	RetryElapsed  string // Give up retrying once a request has taken this long ("90s"); "" = no limit
+	MacTablePoll  int    // MAC table lookup poll attempts (2s each)
+	DNSServers    string // Comma-separated alternate DNS servers for PTR lookups
+	Proxy         string // Dashboard API proxy URL, "direct", or "" for HTTPS_PROXY/HTTP_PROXY
+	CAFile        string // PEM bundle trusted for the Dashboard API in addition to the system roots
+	TLSMinVersion string // Minimum TLS version for the Dashboard API ("1.2", "1.3"); "" is Go's default
+	TLSInsecure   bool   // Skip Dashboard API certificate verification (lab use only)
+	UserAgent     string // Appended to the Dashboard API User-Agent, e.g. "ServiceDesk"
+	RequestID     string // X-Request-Id prefix of this run's API calls; "" means random
+	CacheTTL      string // Keep organization/network/device listings on disk this long ("1h", "1d"); "" or "0" disables
+	CacheDir      string // Response cache directory; "" means the user cache directory
+	Refresh       bool   // Bypass cached responses for this run (and store fresh ones)
+	SwitchModels  string // Comma-separated extra model prefixes always searched as switches
+	LogFile       string // Path to log file
+	LogLevel      string // Log level: DEBUG, INFO, WARNING, ERROR
+	Verbose       bool   // Enable verbose output
+	SwitchFilter  string // Switch name filter
+	DeviceTag     string // Comma-separated Dashboard device tags; only switches carrying one are searched
+	Shard         string // "i/n": search only the i-th of n deterministic shares of the switches
+	Model         string // Comma-separated model prefixes (e.g. MS120, C9300); only those switches are searched
+	PortFilter    string // Port filter
+	VLANFilter    int    // Only report clients on this VLAN; 0 means any
+	PortMode      string // Only report clients on "access" or "trunk" ports; "" means either
+	EntryType     string // Only report "static" or "dynamic" live MAC table entries; "" means any result
+	DeviceTypes   string // Comma-separated device types to search (switch, wireless, appliance); "" means switches
+	ExcludeSwitch string // Comma-separated switch names/serials skipped from scanning and output
+	ExcludePort   string // Comma-separated port IDs dropped from output
+	TestFull      bool   // Display complete MAC forwarding table
+	IPAddress     string // IP address to resolve
+	MACAddress    string // MAC address or pattern to look up
+	MACRange      string // inclusive MAC range "first-last" to look up
+	Hostname      string // DNS name forward-resolved to IP(s) and then looked up like IPAddress
+	Vendor        string // vendor name whose OUI blocks are looked up
+	OUIFile       string // IEEE oui.csv replacing the embedded OUI registry; "" means embedded
+	ClientID      string // Meraki client ID to look up directly (bypasses the MAC matcher)
+	Serial        string // switch serial whose clients are all listed (no search term)
+	Query         string // search expression such as "vlan=30 AND vendor~'Axis'" (see pkg/query)
+	Notify        bool   // Fire a desktop notification when a long web search completes
+	HistoryFile   string // First-seen history file; "off" disables recording, "" means the default location
+	HistoryMaxAge string // Prune history sightings last seen longer ago than this ("180d"); "" or "0" keeps them forever
+	CSVDelimiter  string // CSV field separator name or character ("comma", "semicolon", "tab", …)
+	CSVBOM        bool   // Prefix CSV output with a UTF-8 BOM for Excel
+	CSVQuoteAll   bool   // Quote every CSV field
+	CSVCRLF       bool   // End CSV lines with CRLF
+	MaxResults    int    // Cap on result rows; the rest are dropped with a warning. Negative means no limit
+	Timeout       string // Abort the search after this long ("10m") and write the partial results; "" = no limit
+	VerifyFile    string // Watchlist CSV of critical MACs and their expected switch/port/VLAN; "" = no verification
+	VerifyEvery   string // Re-verify the watchlist this often ("15m"); "" = verify once and exit
+}
+
+// Flags holds the raw values parsed from the command line.
+// Zero values mean "not set on the command line" so the environment or default applies.
+type Flags struct {
+	Org           string
+	OrgID         string
+	Network       string
+	NetworkID     string
+	OutputFormat  string
+	Retry         int
+	RateLimit     int
+	RetryStatuses string
+	RetryElapsed  string
+	MacTablePoll  int
+	DNSServers    string
+	Proxy         string
+	CAFile        string
+	TLSMinVersion string
+	TLSInsecure   bool
+	UserAgent     string
+	RequestID     string
+	CacheTTL      string
+	Refresh       bool
+	SwitchModels  string
+	LogFile       string
+	LogLevel      string
+	Verbose       bool
+	Switch        string
+	DeviceTag     string
+	Shard         string
+	Model         string
+	Port          string
+	VLAN          int
+	PortMode      string
+	EntryType     string
+	DeviceTypes   string
+	ExcludeSwitch string
+	ExcludePort   string
+	TestFull      bool
+	IP            string
+	MAC           string
+	MACRange      string
+	Hostname      string
+	Vendor        string
+	OUIFile       string
+	ClientID      string
+	Serial        string
+	Query         string
+	Notify        bool
+	HistoryFile   string
+	HistoryMaxAge string
+	CSVDelimiter  string
+	CSVBOM        bool
+	CSVQuoteAll   bool
+	CSVCRLF       bool
+	MaxResults    int
+	Timeout       string
+	VerifyFile    string
+	VerifyEvery   string
+}
+
+// ValidationError aggregates every problem found while loading a Config so the
+// user can fix them all in one pass instead of one per run.
+type ValidationError struct {
+	Problems []string
+}
+
+// Error joins all problems into a single multi-line message.
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return "invalid configuration: " + e.Problems[0]
+	}
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// add records a formatted problem.
+func (e *ValidationError) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// orNil returns e if any problems were recorded, otherwise nil.
+func (e *ValidationError) orNil() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Load merges flags, environment variables and defaults into a Config and validates it.
+// getenv is normally os.Getenv; it is a parameter so tests can supply a fixed environment.
+// The returned Config is fully populated even when err is non-nil.
+func Load(f Flags, getenv func(string) string) (Config, error) {
+	verr := &ValidationError{}
+
+	cfg := Config{
+		APIKey:        strings.TrimSpace(getenv("MERAKI_API_KEY")),
+		OrgName:       strings.TrimSpace(firstNonEmpty(f.Org, getenv("MERAKI_ORG"))),
+		OrgID:         strings.TrimSpace(firstNonEmpty(f.OrgID, getenv("MERAKI_ORG_ID"))),
+		NetworkName:   strings.TrimSpace(firstNonEmpty(f.Network, getenv("MERAKI_NETWORK"))),
+		NetworkID:     strings.TrimSpace(firstNonEmpty(f.NetworkID, getenv("MERAKI_NETWORK_ID"))),
+		OutputFormat:  strings.ToLower(strings.TrimSpace(firstNonEmpty(f.OutputFormat, getenv("OUTPUT_FORMAT"), DefaultOutputFormat))),
+		BaseURL:       strings.TrimSpace(firstNonEmpty(getenv("MERAKI_BASE_URL"), DefaultBaseURL)),
+		MaxRetries:    firstNonZeroInt(f.Retry, intEnv(verr, getenv, "MERAKI_RETRIES"), DefaultMaxRetries),
+		RateLimit:     firstNonZeroInt(f.RateLimit, intEnv(verr, getenv, "MERAKI_RATE_LIMIT"), DefaultRateLimit),
+		RetryStatuses: strings.TrimSpace(firstNonEmpty(f.RetryStatuses, getenv("MERAKI_RETRY_STATUSES"))),
+		RetryElapsed:  strings.TrimSpace(firstNonEmpty(f.RetryElapsed, getenv("MERAKI_RETRY_MAX_ELAPSED"))),
+		MacTablePoll:  firstNonZeroInt(f.MacTablePoll, intEnv(verr, getenv, "MERAKI_MAC_POLL"), DefaultMacTablePoll),
+		DNSServers:    strings.TrimSpace(firstNonEmpty(f.DNSServers, getenv("DNS_SERVERS"))),
+		Proxy:         strings.TrimSpace(firstNonEmpty(f.Proxy, getenv("MERAKI_PROXY"))),
+		CAFile:        strings.TrimSpace(firstNonEmpty(f.CAFile, getenv("MERAKI_CA_FILE"))),
+		TLSMinVersion: strings.TrimSpace(firstNonEmpty(f.TLSMinVersion, getenv("MERAKI_TLS_MIN_VERSION"))),
+		TLSInsecure:   f.TLSInsecure || boolEnv(getenv, "MERAKI_TLS_INSECURE_SKIP_VERIFY"),
+		UserAgent:     strings.TrimSpace(firstNonEmpty(f.UserAgent, getenv("MERAKI_USER_AGENT_SUFFIX"))),
+		RequestID:     strings.TrimSpace(firstNonEmpty(f.RequestID, getenv("MERAKI_REQUEST_ID"))),
+		CacheTTL:      strings.TrimSpace(firstNonEmpty(f.CacheTTL, getenv("MERAKI_CACHE_TTL"))),
+		CacheDir:      strings.TrimSpace(getenv("MERAKI_CACHE_DIR")),
+		Refresh:       f.Refresh,
+		SwitchModels:  strings.TrimSpace(firstNonEmpty(f.SwitchModels, getenv("EXTRA_SWITCH_MODELS"))),
+		LogFile:       strings.TrimSpace(firstNonEmpty(f.LogFile, getenv("LOG_FILE"), DefaultLogFile)),
+		LogLevel:      strings.ToUpper(strings.TrimSpace(firstNonEmpty(f.LogLevel, getenv("LOG_LEVEL"), DefaultLogLevel))),
+		Verbose:       f.Verbose,
+		SwitchFilter:  strings.TrimSpace(f.Switch),
+		DeviceTag:     strings.TrimSpace(f.DeviceTag),
+		Shard:         strings.TrimSpace(f.Shard),
+		Model:         strings.TrimSpace(f.Model),
+		PortFilter:    strings.TrimSpace(f.Port),
+		VLANFilter:    f.VLAN,
+		PortMode:      strings.ToLower(strings.TrimSpace(f.PortMode)),
+		EntryType:     strings.ToLower(strings.TrimSpace(f.EntryType)),
+		DeviceTypes:   strings.TrimSpace(f.DeviceTypes),
+		ExcludeSwitch: strings.TrimSpace(firstNonEmpty(f.ExcludeSwitch, getenv("EXCLUDE_SWITCHES"))),
+		ExcludePort:   strings.TrimSpace(firstNonEmpty(f.ExcludePort, getenv("EXCLUDE_PORTS"))),
+		TestFull:      f.TestFull,
+		IPAddress:     strings.TrimSpace(f.IP),
+		MACAddress:    strings.TrimSpace(f.MAC),
+		Hostname:      strings.TrimSpace(f.Hostname),
+		MACRange:      strings.TrimSpace(f.MACRange),
+		Vendor:        strings.TrimSpace(f.Vendor),
+		OUIFile:       strings.TrimSpace(firstNonEmpty(f.OUIFile, getenv("OUI_FILE"))),
+		ClientID:      strings.TrimSpace(f.ClientID),
+		Serial:        strings.TrimSpace(f.Serial),
+		Query:         strings.TrimSpace(f.Query),
+		Notify:        f.Notify || boolEnv(getenv, "NOTIFY"),
+		HistoryFile:   strings.TrimSpace(firstNonEmpty(f.HistoryFile, getenv("HISTORY_FILE"))),
+		HistoryMaxAge: strings.TrimSpace(firstNonEmpty(f.HistoryMaxAge, getenv("HISTORY_RETENTION"))),
+		CSVDelimiter:  firstNonEmpty(f.CSVDelimiter, getenv("CSV_DELIMITER")),
+		CSVBOM:        f.CSVBOM || boolEnv(getenv, "CSV_BOM"),
+		CSVQuoteAll:   f.CSVQuoteAll || boolEnv(getenv, "CSV_QUOTE_ALL"),
+		CSVCRLF:       f.CSVCRLF || boolEnv(getenv, "CSV_CRLF"),
+		MaxResults:    firstNonZeroInt(f.MaxResults, intEnv(verr, getenv, "MAX_RESULTS"), DefaultMaxResults),
+		Timeout:       strings.TrimSpace(firstNonEmpty(f.Timeout, getenv("SEARCH_TIMEOUT"))),
+		VerifyFile:    strings.TrimSpace(firstNonEmpty(f.VerifyFile, getenv("VERIFY_FILE"))),
+		VerifyEvery:   strings.TrimSpace(firstNonEmpty(f.VerifyEvery, getenv("VERIFY_INTERVAL"))),
+	}
+
+	// Verbose sends DEBUG logs to the console only.
+	if cfg.Verbose {
+		cfg.LogLevel = "DEBUG"
+		cfg.LogFile = ""
+	}
+
+	cfg.validate(verr)
+	return cfg, verr.orNil()
+}
+
+// Validate checks ranges, enumerations and mutually exclusive options.
+// It returns a *ValidationError listing every problem, or nil.
+func (c Config) Validate() error {
+	verr := &ValidationError{}
+	c.validate(verr)
+	return verr.orNil()
+}
+
+func (c Config) validate(verr *ValidationError) {
+	switch c.OutputFormat {
+	case "csv", "text", "html", "jsonl", "xlsx", "yaml", "terraform-external":
+	default:
+		verr.add("OUTPUT_FORMAT must be one of: csv, text, html, jsonl, xlsx, yaml, terraform-external (got %q)", c.OutputFormat)
+	}
+	switch strings.ToUpper(c.LogLevel) {
+	case "DEBUG", "INFO", "WARNING", "WARN", "ERROR":
+	default:
+		verr.add("LOG_LEVEL must be one of: DEBUG, INFO, WARNING, ERROR (got %q)", c.LogLevel)
+	}
+	if c.MaxRetries < 1 || c.MaxRetries > 20 {
+		verr.add("MERAKI_RETRIES must be 1–20 (got %d)", c.MaxRetries)
+	}
+	if c.RateLimit < 1 || c.RateLimit > 100 {
+		verr.add("MERAKI_RATE_LIMIT must be 1–100 requests per second (got %d)", c.RateLimit)
+	}
+	if _, err := ParseStatusList(c.RetryStatuses); err != nil {
+		verr.add("MERAKI_RETRY_STATUSES: %v", err)
+	}
+	if c.Timeout != "" {
+		if d, err := time.ParseDuration(c.Timeout); err != nil || d <= 0 {
+			verr.add("SEARCH_TIMEOUT must be a positive duration such as 10m (got %q)", c.Timeout)
+		}
+	}
+	if c.VerifyEvery != "" {
+		if d, err := time.ParseDuration(c.VerifyEvery); err != nil || d < time.Minute {
+			verr.add("VERIFY_INTERVAL must be a duration of at least 1m such as 15m (got %q)", c.VerifyEvery)
+		} else if c.VerifyFile == "" {
+			verr.add("VERIFY_INTERVAL needs a watchlist (--verify or VERIFY_FILE)")
+		}
+	}
+	if c.RetryElapsed != "" {
+		if d, err := time.ParseDuration(c.RetryElapsed); err != nil || d < 0 {
+			verr.add("MERAKI_RETRY_MAX_ELAPSED must be a duration such as 90s or 5m (got %q)", c.RetryElapsed)
+		}
+	}
+	if _, err := meraki.ParseProxy(c.Proxy); err != nil {
+		verr.add("MERAKI_PROXY: %v", err)
+	}
+	if _, err := meraki.ParseTLSVersion(c.TLSMinVersion); err != nil {
+		verr.add("MERAKI_TLS_MIN_VERSION: %v", err)
+	}
+	if c.TLSInsecure && c.CAFile != "" {
+		verr.add("MERAKI_CA_FILE and MERAKI_TLS_INSECURE_SKIP_VERIFY are mutually exclusive")
+	}
+	if !meraki.ValidUserAgentPart(c.UserAgent) {
+		verr.add("MERAKI_USER_AGENT_SUFFIX must be printable ASCII of at most 100 characters")
+	}
+	if c.RequestID != "" && !meraki.ValidRequestID(c.RequestID) {
+		verr.add("MERAKI_REQUEST_ID must be 1–64 letters, digits, '.', '_', ':' or '-' (got %q)", c.RequestID)
+	}
+	if c.CacheTTL != "" {
+		if _, err := history.ParseSince(c.CacheTTL); err != nil {
+			verr.add("MERAKI_CACHE_TTL must be a duration such as 1h or 1d (got %q)", c.CacheTTL)
+		}
+	}
+	if c.MacTablePoll < 1 || c.MacTablePoll > 60 {
+		verr.add("MERAKI_MAC_POLL must be 1–60 (got %d)", c.MacTablePoll)
+	}
+	if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		verr.add("MERAKI_BASE_URL must be an http(s) URL (got %q)", c.BaseURL)
+	}
+	if c.VLANFilter < 0 || c.VLANFilter > 4094 {
+		verr.add("--vlan must be 1–4094 (got %d)", c.VLANFilter)
+	}
+	if _, err := filters.ParseShard(c.Shard); err != nil {
+		verr.add("--shard: %v", err)
+	}
+	if _, err := filters.ParseDeviceTypes(c.DeviceTypes); err != nil {
+		verr.add("--device-types: %v", err)
+	}
+	switch c.PortMode {
+	case "", "access", "trunk":
+	default:
+		verr.add("--port-mode must be access or trunk (got %q)", c.PortMode)
+	}
+	switch c.EntryType {
+	case "", "static", "dynamic":
+	default:
+		verr.add("--entry-type must be static or dynamic (got %q)", c.EntryType)
+	}
+	if c.HistoryMaxAge != "" {
+		if _, err := history.ParseSince(c.HistoryMaxAge); err != nil {
+			verr.add("--history-retention: %v (use e.g. 180d)", err)
+		}
+	}
+	if c.IPAddress != "" && net.ParseIP(c.IPAddress) == nil {
+		if _, _, err := net.ParseCIDR(c.IPAddress); err != nil {
+			verr.add("--ip %q is not a valid IP address or CIDR subnet", c.IPAddress)
+		}
+	}
+	if c.MACRange != "" {
+		if _, _, err := macaddr.ParseMacRange(c.MACRange); err != nil {
+			verr.add("--mac-range: %v", err)
+		}
+	}
+	if c.Query != "" {
+		if _, err := query.Parse(c.Query); err != nil {
+			verr.add("--query: %v", err)
+		}
+	}
+	lookups := 0
+	for _, v := range []string{c.IPAddress, c.MACAddress, c.MACRange, c.Hostname, c.Vendor, c.ClientID, c.Serial, c.Query} {
+		if v != "" {
+			lookups++
+		}
+	}
+	if lookups > 1 {
+		verr.add("--ip, --mac, --mac-range, --hostname, --vendor, --client-id, --serial and --query are mutually exclusive")
+	}
+}
+
+// firstNonEmpty returns the first value that is not empty or whitespace-only.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ParseStatusList parses a comma-separated list of HTTP error statuses such as
+// "429,503". An empty list yields nil.
+func ParseStatusList(s string) ([]int, error) {
+	var out []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 400 || code > 599 {
+			return nil, fmt.Errorf("%q is not an HTTP error status (400–599)", part)
+		}
+		out = append(out, code)
+	}
+	return out, nil
+}
+
+// firstNonZeroInt returns the first non-zero int from the provided values.
+func firstNonZeroInt(values ...int) int {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// intEnv reads an integer environment variable. Unset yields 0; a value that is
+// set but not an integer is recorded as a problem and also yields 0.
+func intEnv(verr *ValidationError, getenv func(string) string, key string) int {
+	v := strings.TrimSpace(getenv(key))
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		verr.add("%s must be an integer (got %q)", key, v)
+		return 0
+	}
+	return n
+}
+
+// boolEnv reports whether an environment variable is set to a truthy value
+// (1, true, yes, on; case-insensitive).
+func boolEnv(getenv func(string) string, key string) bool {
+	switch strings.ToLower(strings.TrimSpace(getenv(key))) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// envMap returns a getenv func backed by a fixed map.
+func envMap(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(Flags{}, envMap(nil))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if cfg.OutputFormat != DefaultOutputFormat {
+		t.Errorf("OutputFormat = %q, want %q", cfg.OutputFormat, DefaultOutputFormat)
+	}
+	if cfg.MaxRetries != DefaultMaxRetries {
+		t.Errorf("MaxRetries = %d, want %d", cfg.MaxRetries, DefaultMaxRetries)
+	}
+	if cfg.RateLimit != DefaultRateLimit {
+		t.Errorf("RateLimit = %d, want %d", cfg.RateLimit, DefaultRateLimit)
+	}
+	if cfg.MacTablePoll != DefaultMacTablePoll {
+		t.Errorf("MacTablePoll = %d, want %d", cfg.MacTablePoll, DefaultMacTablePoll)
+	}
+	if cfg.MaxResults != DefaultMaxResults {
+		t.Errorf("MaxResults = %d, want %d", cfg.MaxResults, DefaultMaxResults)
+	}
+	if cfg.BaseURL != DefaultBaseURL {
+		t.Errorf("BaseURL = %q, want %q", cfg.BaseURL, DefaultBaseURL)
+	}
+}
+
+func TestLoad_FlagOverridesEnv(t *testing.T) {
+	cfg, err := Load(Flags{Org: "FlagOrg", MacTablePoll: 5, OutputFormat: "TEXT"}, envMap(map[string]string{
+		"MERAKI_ORG":      "EnvOrg",
+		"MERAKI_MAC_POLL": "30",
+		"OUTPUT_FORMAT":   "html",
+	}))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if cfg.OrgName != "FlagOrg" {
+		t.Errorf("OrgName = %q, want FlagOrg", cfg.OrgName)
+	}
+	if cfg.MacTablePoll != 5 {
+		t.Errorf("MacTablePoll = %d, want 5", cfg.MacTablePoll)
+	}
+	if cfg.OutputFormat != "text" {
+		t.Errorf("OutputFormat = %q, want text (lower-cased)", cfg.OutputFormat)
+	}
+}
+
+func TestLoad_IDs(t *testing.T) {
+	cfg, err := Load(Flags{NetworkID: " L_1,N_2 "}, envMap(map[string]string{
+		"MERAKI_ORG_ID":     "123456",
+		"MERAKI_NETWORK_ID": "N_9",
+	}))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if cfg.OrgID != "123456" || cfg.NetworkID != "L_1,N_2" {
+		t.Errorf("OrgID=%q NetworkID=%q, want 123456 from env and L_1,N_2 from the flag", cfg.OrgID, cfg.NetworkID)
+	}
+}
+
+func TestLoad_Exclusions(t *testing.T) {
+	cfg, _ := Load(Flags{ExcludePort: "49,50"}, envMap(map[string]string{
+		"EXCLUDE_SWITCHES": "core",
+		"EXCLUDE_PORTS":    "1",
+	}))
+	if cfg.ExcludeSwitch != "core" || cfg.ExcludePort != "49,50" {
+		t.Errorf("ExcludeSwitch=%q ExcludePort=%q, want core from env and 49,50 from the flag", cfg.ExcludeSwitch, cfg.ExcludePort)
+	}
+}
+
+func TestLoad_VerboseForcesConsoleDebug(t *testing.T) {
+	cfg, _ := Load(Flags{Verbose: true, LogLevel: "ERROR", LogFile: "x.log"}, envMap(nil))
+	if cfg.LogLevel != "DEBUG" || cfg.LogFile != "" {
+		t.Errorf("verbose: LogLevel=%q LogFile=%q, want DEBUG and empty", cfg.LogLevel, cfg.LogFile)
+	}
+}
+
+func TestLoad_AggregatesProblems(t *testing.T) {
+	_, err := Load(Flags{IP: "10.0.0.1", MAC: "00:11:22:33:44:55"}, envMap(map[string]string{
+		"MERAKI_MAC_POLL": "99",
+		"MERAKI_RETRIES":  "lots",
+		"OUTPUT_FORMAT":   "pdf",
+	}))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() error = %v, want *ValidationError", err)
+	}
+	want := []string{
+		"MERAKI_RETRIES must be an integer",
+		"OUTPUT_FORMAT must be one of",
+		"MERAKI_MAC_POLL must be 1–60",
+		"mutually exclusive",
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("error missing %q\nfull:\n%s", w, err)
+		}
+	}
+	if len(verr.Problems) != len(want) {
+		t.Errorf("got %d problems, want %d: %v", len(verr.Problems), len(want), verr.Problems)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	base := Config{OutputFormat: "csv", LogLevel: "INFO", MaxRetries: 6, RateLimit: 10, MacTablePoll: 15, BaseURL: DefaultBaseURL}
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"poll zero", func(c *Config) { c.MacTablePoll = 0 }, "MERAKI_MAC_POLL"},
+		{"retries too high", func(c *Config) { c.MaxRetries = 50 }, "MERAKI_RETRIES"},
+		{"rate limit too high", func(c *Config) { c.RateLimit = 500 }, "MERAKI_RATE_LIMIT"},
+		{"retry status not an error", func(c *Config) { c.RetryStatuses = "429,200" }, "MERAKI_RETRY_STATUSES"},
+		{"proxy scheme", func(c *Config) { c.Proxy = "ftp://proxy:21" }, "MERAKI_PROXY"},
+		{"user agent control character", func(c *Config) { c.UserAgent = "Ops\r\nX-Evil: 1" }, "MERAKI_USER_AGENT_SUFFIX"},
+		{"request id", func(c *Config) { c.RequestID = "INC 0042" }, "MERAKI_REQUEST_ID"},
+		{"cache ttl", func(c *Config) { c.CacheTTL = "a while" }, "MERAKI_CACHE_TTL"},
+		{"tls version", func(c *Config) { c.TLSMinVersion = "1.4" }, "MERAKI_TLS_MIN_VERSION"},
+		{"ca file and insecure", func(c *Config) { c.CAFile = "corp.pem"; c.TLSInsecure = true }, "mutually exclusive"},
+		{"retry elapsed not a duration", func(c *Config) { c.RetryElapsed = "5 minutes" }, "MERAKI_RETRY_MAX_ELAPSED"},
+		{"timeout not a duration", func(c *Config) { c.Timeout = "ten minutes" }, "SEARCH_TIMEOUT"},
+		{"timeout zero", func(c *Config) { c.Timeout = "0s" }, "SEARCH_TIMEOUT"},
+		{"verify interval too short", func(c *Config) { c.VerifyFile = "critical.csv"; c.VerifyEvery = "30s" }, "VERIFY_INTERVAL"},
+		{"verify interval without watchlist", func(c *Config) { c.VerifyEvery = "15m" }, "needs a watchlist"},
+		{"bad log level", func(c *Config) { c.LogLevel = "TRACE" }, "LOG_LEVEL"},
+		{"bad base url", func(c *Config) { c.BaseURL = "api.meraki.com" }, "MERAKI_BASE_URL"},
+		{"bad ip", func(c *Config) { c.IPAddress = "10.0.0" }, "not a valid IP"},
+		{"bad cidr", func(c *Config) { c.IPAddress = "10.0.0.0/33" }, "not a valid IP"},
+		{"cidr", func(c *Config) { c.IPAddress = "10.20.30.0/24" }, ""},
+		{"bad mac range", func(c *Config) { c.MACRange = "00:11:22:33:44:ff-00:11:22:33:44:00" }, "--mac-range"},
+		{"hostname and mac", func(c *Config) { c.Hostname = "pc-1"; c.MACAddress = "00:11:22:33:44:55" }, "mutually exclusive"},
+		{"vendor and ip", func(c *Config) { c.Vendor = "Axis"; c.IPAddress = "10.0.0.1" }, "mutually exclusive"},
+		{"serial and mac", func(c *Config) { c.Serial = "Q2XX-0001"; c.MACAddress = "00:11:22:33:44:55" }, "mutually exclusive"},
+		{"query", func(c *Config) { c.Query = "vlan=30 AND vendor~'Axis'" }, ""},
+		{"bad query", func(c *Config) { c.Query = "vlan=30 AND" }, "--query"},
+		{"vlan", func(c *Config) { c.VLANFilter = 120 }, ""},
+		{"vlan too high", func(c *Config) { c.VLANFilter = 4095 }, "--vlan"},
+		{"port mode", func(c *Config) { c.PortMode = "access" }, ""},
+		{"bad port mode", func(c *Config) { c.PortMode = "routed" }, "--port-mode"},
+		{"entry type", func(c *Config) { c.EntryType = "static" }, ""},
+		{"bad entry type", func(c *Config) { c.EntryType = "sticky" }, "--entry-type"},
+		{"history retention", func(c *Config) { c.HistoryMaxAge = "180d" }, ""},
+		{"proxy", func(c *Config) { c.Proxy = "proxy.corp:3128" }, ""},
+		{"correlation", func(c *Config) { c.UserAgent = "ServiceDesk/2.1"; c.RequestID = "INC0042" }, ""},
+		{"cache", func(c *Config) { c.CacheTTL = "1d"; c.Refresh = true }, ""},
+		{"tls options", func(c *Config) { c.CAFile = "corp.pem"; c.TLSMinVersion = "TLS1.3" }, ""},
+		{"retry policy", func(c *Config) { c.RetryStatuses = " 429, 500 "; c.RetryElapsed = "2m" }, ""},
+		{"timeout", func(c *Config) { c.Timeout = "10m" }, ""},
+		{"bad history retention", func(c *Config) { c.HistoryMaxAge = "six months" }, "--history-retention"},
+		{"device types", func(c *Config) { c.DeviceTypes = "switch,wireless" }, ""},
+		{"bad device type", func(c *Config) { c.DeviceTypes = "switch,camera" }, "--device-types"},
+		{"shard", func(c *Config) { c.Shard = "2/8" }, ""},
+		{"bad shard", func(c *Config) { c.Shard = "9/8" }, "--shard"},
+		{"query and mac", func(c *Config) { c.Query = "vlan=30"; c.MACAddress = "00:11:22:33:44:55" }, "mutually exclusive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := base
+			tt.mutate(&c)
+			err := c.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -17,33 +17,134 @@
 package filters
 
 import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
 	"strings"
 
 	"Find-Meraki-Ports-With-MAC/pkg/meraki"
 )
 
+// switchModelPrefixes are the model families treated as switches when the
+// Dashboard omits productType (older inventory responses and some templates).
+var switchModelPrefixes = []string{
+	"MS", // Meraki MS, including MS130R outdoor
+	"C9", // Catalyst 9000 series
+	"GS", // Meraki Go GS switches
+}
+
+// extraSwitchModels holds user-configured model prefixes that are always
+// treated as switches, so new hardware can be searched before it is known here.
+var extraSwitchModels []string
+
+// SetExtraSwitchModels configures additional model prefixes (case-insensitive)
+// that FilterSwitches accepts regardless of productType.
+// Pass nil or an empty slice to clear the list.
+func SetExtraSwitchModels(models []string) {
+	cleaned := make([]string, 0, len(models))
+	for _, m := range models {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if m != "" {
+			cleaned = append(cleaned, m)
+		}
+	}
+	extraSwitchModels = cleaned
+}
+
 // FilterSwitches returns only devices that are switches.
 // A device is considered a switch if:
-// - productType is "switch", OR
-// - model starts with "MS" (Meraki switches), OR
-// - model starts with "C9" (Catalyst 9000 series)
+// - its model matches a prefix configured with SetExtraSwitchModels, OR
+// - productType is "switch" (MS, Catalyst and Meraki Go GS alike), OR
+// - productType is empty and the model starts with a known switch family
 func FilterSwitches(devices []meraki.Device) []meraki.Device {
 	var switches []meraki.Device
 	for _, d := range devices {
-		if d.ProductType == "switch" {
+		if IsSwitch(d) {
 			switches = append(switches, d)
-			continue
 		}
-		if strings.HasPrefix(strings.ToUpper(d.Model), "MS") {
-			switches = append(switches, d)
+	}
+	return switches
+}
+
+// IsSwitch reports whether a single device is treated as a switch by FilterSwitches.
+func IsSwitch(d meraki.Device) bool {
+	model := strings.ToUpper(strings.TrimSpace(d.Model))
+	if hasAnyPrefix(model, extraSwitchModels) {
+		return true
+	}
+	switch strings.ToLower(d.ProductType) {
+	case "switch":
+		return true
+	case "":
+		return hasAnyPrefix(model, switchModelPrefixes)
+	}
+	return false
+}
+
+// DeviceTypes are the device types --device-types accepts, in the order
+// they are documented.
+var DeviceTypes = []string{"switch", "wireless", "appliance"}
+
+// DeviceType classifies a device for --device-types: "switch" (see IsSwitch),
+// "wireless" for access points (productType wireless, or an MR/CW model when
+// productType is missing), "appliance" for security appliances (productType
+// appliance, or an MX/Z model), or otherwise its lower-cased productType.
+func DeviceType(d meraki.Device) string {
+	if IsSwitch(d) {
+		return "switch"
+	}
+	pt := strings.ToLower(d.ProductType)
+	if pt != "" {
+		return pt
+	}
+	model := strings.ToUpper(strings.TrimSpace(d.Model))
+	switch {
+	case hasAnyPrefix(model, []string{"MR", "CW"}):
+		return "wireless"
+	case hasAnyPrefix(model, []string{"MX", "Z"}):
+		return "appliance"
+	}
+	return ""
+}
+
+// ParseDeviceTypes parses a comma-separated --device-types value. It returns
+// nil for an empty value and an error naming the first unknown type.
+func ParseDeviceTypes(s string) ([]string, error) {
+	var types []string
+	for _, t := range strings.Split(s, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
 			continue
 		}
-		if strings.HasPrefix(strings.ToUpper(d.Model), "C9") {
-			switches = append(switches, d)
-			continue
+		if !slices.Contains(DeviceTypes, t) {
+			return nil, fmt.Errorf("unknown device type %q (want %s)", t, strings.Join(DeviceTypes, ", "))
+		}
+		if !slices.Contains(types, t) {
+			types = append(types, t)
 		}
 	}
-	return switches
+	return types, nil
+}
+
+// FilterDevicesByType returns the devices whose DeviceType is one of types.
+func FilterDevicesByType(devices []meraki.Device, types []string) []meraki.Device {
+	var out []meraki.Device
+	for _, d := range devices {
+		if slices.Contains(types, DeviceType(d)) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
 }
 
 // FilterSwitchesByName filters devices by a case-insensitive substring match on the name.
@@ -60,16 +161,299 @@ func FilterSwitchesByName(devices []meraki.Device, filter string) []meraki.Devic
 	return filtered
 }
 
+// FilterSwitchesByTag keeps devices carrying at least one of the Dashboard
+// tags (case-insensitive exact match). An empty tags list keeps every device.
+func FilterSwitchesByTag(devices []meraki.Device, tags []string) []meraki.Device {
+	tags = cleanLower(tags)
+	if len(tags) == 0 {
+		return devices
+	}
+	var filtered []meraki.Device
+	for _, d := range devices {
+		if HasAnyTag(d, tags) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// HasAnyTag reports whether d carries one of tags (case-insensitive). An empty
+// tags list matches every device.
+func HasAnyTag(d meraki.Device, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, t := range d.Tags {
+		t = strings.TrimSpace(t)
+		for _, want := range tags {
+			if strings.EqualFold(t, strings.TrimSpace(want)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FilterSwitchesByModel keeps devices whose model starts with one of the
+// prefixes (case-insensitive), e.g. "MS120" or "C9300". An empty list keeps
+// every device.
+func FilterSwitchesByModel(devices []meraki.Device, models []string) []meraki.Device {
+	if len(cleanLower(models)) == 0 {
+		return devices
+	}
+	var filtered []meraki.Device
+	for _, d := range devices {
+		if MatchesModel(d.Model, models) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// MatchesModel reports whether model starts with one of the prefixes
+// (case-insensitive). An empty list matches every model.
+func MatchesModel(model string, models []string) bool {
+	prefixes := cleanLower(models)
+	if len(prefixes) == 0 {
+		return true
+	}
+	return hasAnyPrefix(strings.ToLower(strings.TrimSpace(model)), prefixes)
+}
+
+// Shard selects a deterministic share of the switches, so several runs can
+// split one large sweep between them. Index is 1-based; the zero value (or a
+// Count of 1) selects every switch.
+type Shard struct {
+	Index, Count int
+}
+
+// ParseShard parses "i/n" (e.g. "2/8"), with 1 <= i <= n. An empty string
+// returns the zero Shard.
+func ParseShard(s string) (Shard, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Shard{}, nil
+	}
+	i, n, ok := strings.Cut(s, "/")
+	index, err1 := strconv.Atoi(strings.TrimSpace(i))
+	count, err2 := strconv.Atoi(strings.TrimSpace(n))
+	if !ok || err1 != nil || err2 != nil || count < 1 || index < 1 || index > count {
+		return Shard{}, fmt.Errorf("shard %q must be i/n with 1 <= i <= n, e.g. 2/8", s)
+	}
+	return Shard{Index: index, Count: count}, nil
+}
+
+// Contains reports whether the switch with this serial belongs to the shard.
+// Switches are assigned by a hash of the serial, not by list position, so the
+// split does not shift when switches are added, removed or reordered.
+func (s Shard) Contains(serial string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strings.ToUpper(strings.TrimSpace(serial))))
+	return int(h.Sum32()%uint32(s.Count)) == s.Index-1
+}
+
+// FilterSwitchesByShard keeps the devices that belong to shard.
+func FilterSwitchesByShard(devices []meraki.Device, shard Shard) []meraki.Device {
+	if shard.Count <= 1 {
+		return devices
+	}
+	var filtered []meraki.Device
+	for _, d := range devices {
+		if shard.Contains(d.Serial) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
 // MatchesSwitchFilter checks if a switch name matches the filter (case-insensitive substring).
 func MatchesSwitchFilter(name, filter string) bool {
 	return strings.Contains(strings.ToLower(name), strings.ToLower(filter))
 }
 
-// MatchesPortFilter checks if a port matches the filter.
-// The filter can be an exact match or a substring match.
+// excludedSwitches and excludedPorts hold the user's --exclude-switch and
+// --exclude-port lists, lower-cased.
+var excludedSwitches, excludedPorts []string
+
+// SetExclusions configures the switches and ports skipped from scanning and
+// output. A switch pattern matches a serial exactly or a name as a substring;
+// a port pattern matches a port ID exactly ("49", "AGGR/1"). Matching is
+// case-insensitive. Pass nil slices to clear the lists.
+func SetExclusions(switches, ports []string) {
+	excludedSwitches, excludedPorts = cleanLower(switches), cleanLower(ports)
+}
+
+func cleanLower(values []string) []string {
+	cleaned := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v != "" {
+			cleaned = append(cleaned, v)
+		}
+	}
+	return cleaned
+}
+
+// ExcludeSwitches drops devices excluded with SetExclusions.
+func ExcludeSwitches(devices []meraki.Device) []meraki.Device {
+	if len(excludedSwitches) == 0 {
+		return devices
+	}
+	var kept []meraki.Device
+	for _, d := range devices {
+		if !IsSwitchExcluded(d.Name, d.Serial) {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// IsSwitchExcluded reports whether the switch with this name or serial was
+// excluded with SetExclusions.
+func IsSwitchExcluded(name, serial string) bool {
+	name, serial = strings.ToLower(name), strings.ToLower(serial)
+	for _, p := range excludedSwitches {
+		if (serial != "" && serial == p) || (name != "" && strings.Contains(name, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPortExcluded reports whether port was excluded with SetExclusions.
+func IsPortExcluded(port string) bool {
+	port = strings.ToLower(strings.TrimSpace(port))
+	for _, p := range excludedPorts {
+		if port == p {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesPortFilter checks if a port matches the filter. An empty filter
+// matches every port. The filter is a comma-separated list of terms; a port
+// matching any term matches. Numeric terms compare port numbers, not text, so
+// "3" matches 3, "Gi1/0/3" and "port-3" but not 13 or 23:
+//
+//	3           port number 3 on any module
+//	1-12        port numbers 1 through 12
+//	Gi1/0/1-12  ports 1-12 on module 1/0 ("Gi" also matches "GigabitEthernet")
+//	AGGR/1      link aggregate 1
+//
+// A term without a trailing number (e.g. "uplink") matches as a
+// case-insensitive substring of the port name.
 func MatchesPortFilter(port, filter string) bool {
-	if port == filter {
+	if strings.TrimSpace(filter) == "" {
+		return true
+	}
+	for _, term := range strings.Split(filter, ",") {
+		if term = strings.TrimSpace(term); term != "" && matchesPortTerm(port, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPortTerm matches port against one MatchesPortFilter term.
+func matchesPortTerm(port, term string) bool {
+	if strings.EqualFold(strings.TrimSpace(port), term) {
 		return true
 	}
-	return strings.Contains(port, filter)
+	// "1-12" is a range; "port-3" is a name.
+	spec, hi := term, -1
+	if i := strings.LastIndexByte(term, '-'); i > 0 && term[i-1] >= '0' && term[i-1] <= '9' {
+		if n, err := strconv.Atoi(term[i+1:]); err == nil {
+			spec, hi = term[:i], n
+		}
+	}
+	tPrefix, tNums, ok := splitPortID(spec)
+	if !ok {
+		return strings.Contains(strings.ToLower(port), strings.ToLower(term))
+	}
+	lo := tNums[len(tNums)-1]
+	if hi < 0 {
+		hi = lo
+	}
+	lo, hi = min(lo, hi), max(lo, hi)
+
+	pPrefix, pNums, ok := splitPortID(port)
+	if !ok {
+		return false
+	}
+	if n := pNums[len(pNums)-1]; n < lo || n > hi {
+		return false
+	}
+	// A term with a module ("1/0/3") must match the port's module exactly.
+	if len(tNums) > 1 && !slices.Equal(tNums[:len(tNums)-1], pNums[:len(pNums)-1]) {
+		return false
+	}
+	// Interface names may be abbreviated: "Gi" matches "GigabitEthernet".
+	tPrefix, pPrefix = strings.ToLower(tPrefix), strings.ToLower(pPrefix)
+	return tPrefix == "" || strings.HasPrefix(pPrefix, tPrefix) || (pPrefix != "" && strings.HasPrefix(tPrefix, pPrefix))
+}
+
+// SamePort reports whether two port IDs name the same port, comparing them
+// the way MatchesPortFilter compares a port with a single-port term: "5",
+// "Gi1/0/5" and "GigabitEthernet1/0/5" are the same port, "Gi1/0/5" and
+// "Gi2/0/5" are not. IDs that do not end in a number compare as text.
+func SamePort(a, b string) bool {
+	if strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b)) {
+		return true
+	}
+	aPrefix, aNums, aOK := splitPortID(a)
+	bPrefix, bNums, bOK := splitPortID(b)
+	if !aOK || !bOK || aNums[len(aNums)-1] != bNums[len(bNums)-1] {
+		return false
+	}
+	// Modules only have to agree when both IDs carry one.
+	if len(aNums) > 1 && len(bNums) > 1 && !slices.Equal(aNums[:len(aNums)-1], bNums[:len(bNums)-1]) {
+		return false
+	}
+	aPrefix, bPrefix = strings.ToLower(aPrefix), strings.ToLower(bPrefix)
+	return aPrefix == "" || bPrefix == "" || strings.HasPrefix(aPrefix, bPrefix) || strings.HasPrefix(bPrefix, aPrefix)
+}
+
+// splitPortID splits a port ID such as "Gi1/0/3", "AGGR/1" or "12" into its
+// non-numeric prefix and its slash-separated numbers. ok is false when the ID
+// does not end in numbers.
+func splitPortID(id string) (prefix string, nums []int, ok bool) {
+	id = strings.TrimSpace(id)
+	i := strings.IndexAny(id, "0123456789")
+	if i < 0 {
+		return "", nil, false
+	}
+	prefix = strings.TrimRight(id[:i], "/- ")
+	for _, part := range strings.Split(id[i:], "/") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return "", nil, false
+		}
+		nums = append(nums, n)
+	}
+	return prefix, nums, true
+}
+
+// MatchesVLANFilter checks if a port's VLAN matches the filter.
+// A filter of 0 (unset) matches every VLAN.
+func MatchesVLANFilter(vlan, filter int) bool {
+	return filter <= 0 || vlan == filter
+}
+
+// MatchesPortModeFilter checks if a port's mode ("access" or "trunk") matches
+// the filter. An empty filter matches every port; a port whose mode is unknown
+// matches only the empty filter.
+func MatchesPortModeFilter(mode, filter string) bool {
+	return filter == "" || strings.EqualFold(mode, filter)
+}
+
+// MatchesEntryTypeFilter checks if a MAC table entry's type ("static" or
+// "dynamic") matches the filter. An empty filter matches every entry; an entry
+// of unknown type, including every result not read from a live MAC table,
+// matches only the empty filter.
+func MatchesEntryTypeFilter(entryType, filter string) bool {
+	return filter == "" || strings.EqualFold(entryType, filter)
 }
@@ -16,6 +16,9 @@
 package filters
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
 
 	"Find-Meraki-Ports-With-MAC/pkg/meraki"
@@ -43,6 +46,39 @@ func TestFilterSwitches(t *testing.T) {
 	}
 }
 
+func TestIsSwitch(t *testing.T) {
+	tests := []struct {
+		name   string
+		device meraki.Device
+		extra  []string
+		want   bool
+	}{
+		{"ms by productType", meraki.Device{Model: "MS250-48", ProductType: "switch"}, nil, true},
+		{"ms130r outdoor", meraki.Device{Model: "MS130R-8P", ProductType: "switch"}, nil, true},
+		{"meraki go gs", meraki.Device{Model: "GS110-8P", ProductType: "switch"}, nil, true},
+		{"catalyst", meraki.Device{Model: "C9300-48P", ProductType: "switch"}, nil, true},
+		{"unknown model with switch productType", meraki.Device{Model: "XS999", ProductType: "switch"}, nil, true},
+		{"productType case", meraki.Device{Model: "MS120", ProductType: "Switch"}, nil, true},
+		{"no productType, ms model", meraki.Device{Model: "MS120-8"}, nil, true},
+		{"no productType, gs model", meraki.Device{Model: "GS110-24"}, nil, true},
+		{"no productType, ap model", meraki.Device{Model: "MR44"}, nil, false},
+		{"ms-looking wireless", meraki.Device{Model: "MS-AP", ProductType: "wireless"}, nil, false},
+		{"appliance", meraki.Device{Model: "MX84", ProductType: "appliance"}, nil, false},
+		{"extra model", meraki.Device{Model: "CW9166", ProductType: "wireless"}, []string{" cw91 "}, true},
+		{"extra model no match", meraki.Device{Model: "MR44", ProductType: "wireless"}, []string{"CW91"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetExtraSwitchModels(tt.extra)
+			defer SetExtraSwitchModels(nil)
+			if got := IsSwitch(tt.device); got != tt.want {
+				t.Errorf("IsSwitch(%+v) = %v, want %v", tt.device, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFilterSwitchesByName(t *testing.T) {
 	devices := []meraki.Device{
 		{Serial: "S1", Name: "core-switch-1", Model: "MS250"},
@@ -87,6 +123,126 @@ func TestFilterSwitchesByName(t *testing.T) {
 	}
 }
 
+func TestFilterSwitchesByTag(t *testing.T) {
+	devices := []meraki.Device{
+		{Serial: "S1", Name: "idf-1", Tags: []string{"wiring-closet", "floor-1"}},
+		{Serial: "S2", Name: "idf-2", Tags: []string{"Wiring-Closet"}},
+		{Serial: "S3", Name: "core", Tags: []string{"core"}},
+		{Serial: "S4", Name: "lab"},
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{name: "no tags", tags: nil, want: []string{"S1", "S2", "S3", "S4"}},
+		{name: "case insensitive", tags: []string{"wiring-closet"}, want: []string{"S1", "S2"}},
+		{name: "any of", tags: []string{" core ", "floor-1"}, want: []string{"S1", "S3"}},
+		{name: "no match", tags: []string{"closet"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range FilterSwitchesByTag(devices, tt.tags) {
+				got = append(got, d.Serial)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("FilterSwitchesByTag(%q) = %v, want %v", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterSwitchesByModel(t *testing.T) {
+	devices := []meraki.Device{
+		{Serial: "S1", Model: "MS120-8LP"},
+		{Serial: "S2", Model: "MS225-48"},
+		{Serial: "S3", Model: "C9300-48P-M"},
+		{Serial: "S4", Model: "C9300X-24Y-M"},
+	}
+
+	tests := []struct {
+		name   string
+		models []string
+		want   []string
+	}{
+		{name: "no models", models: nil, want: []string{"S1", "S2", "S3", "S4"}},
+		{name: "blank", models: []string{" "}, want: []string{"S1", "S2", "S3", "S4"}},
+		{name: "exact family", models: []string{"MS120"}, want: []string{"S1"}},
+		{name: "catalyst only", models: []string{"c9300"}, want: []string{"S3", "S4"}},
+		{name: "ms only", models: []string{"MS"}, want: []string{"S1", "S2"}},
+		{name: "several", models: []string{"MS225", "C9300X"}, want: []string{"S2", "S4"}},
+		{name: "no match", models: []string{"GS110"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range FilterSwitchesByModel(devices, tt.models) {
+				got = append(got, d.Serial)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("FilterSwitchesByModel(%q) = %v, want %v", tt.models, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseShard(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Shard
+		wantErr bool
+	}{
+		{in: "", want: Shard{}},
+		{in: "2/8", want: Shard{Index: 2, Count: 8}},
+		{in: " 1 / 1 ", want: Shard{Index: 1, Count: 1}},
+		{in: "0/8", wantErr: true},
+		{in: "9/8", wantErr: true},
+		{in: "2", wantErr: true},
+		{in: "a/b", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseShard(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseShard(%q) = %+v, %v; want %+v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestShardPartition(t *testing.T) {
+	var devices []meraki.Device
+	for i := 0; i < 200; i++ {
+		devices = append(devices, meraki.Device{Serial: fmt.Sprintf("Q2XX-%04d-ABCD", i)})
+	}
+	const n = 8
+	seen := make(map[string]int)
+	for i := 1; i <= n; i++ {
+		shard := Shard{Index: i, Count: n}
+		part := FilterSwitchesByShard(devices, shard)
+		if len(part) == 0 {
+			t.Errorf("shard %d/%d is empty", i, n)
+		}
+		for _, d := range part {
+			seen[d.Serial]++
+		}
+		// Assignment depends only on the serial, not on case or list order.
+		if !shard.Contains(strings.ToLower(part[0].Serial)) {
+			t.Errorf("shard %d/%d: serial case changed the assignment", i, n)
+		}
+	}
+	for _, d := range devices {
+		if seen[d.Serial] != 1 {
+			t.Errorf("%s is in %d shards, want 1", d.Serial, seen[d.Serial])
+		}
+	}
+	if got := FilterSwitchesByShard(devices, Shard{}); len(got) != len(devices) {
+		t.Errorf("zero Shard kept %d of %d", len(got), len(devices))
+	}
+}
+
 func TestMatchesSwitchFilter(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -122,6 +278,27 @@ func TestMatchesPortFilter(t *testing.T) {
 		{port: "Gi1/0/3", filter: "3", want: true},
 		{port: "port-3", filter: "3", want: true},
 		{port: "4", filter: "3", want: false},
+		{port: "13", filter: "3", want: false},
+		{port: "23", filter: "3", want: false},
+		{port: "Gi1/0/13", filter: "3", want: false},
+		{port: "7", filter: "", want: true},
+		{port: "1", filter: "1-12", want: true},
+		{port: "12", filter: "1-12", want: true},
+		{port: "13", filter: "1-12", want: false},
+		{port: "Gi1/0/5", filter: "1-12", want: true},
+		{port: "Gi1/0/5", filter: "Gi1/0/1-12", want: true},
+		{port: "GigabitEthernet1/0/5", filter: "Gi1/0/1-12", want: true},
+		{port: "Gi2/0/5", filter: "Gi1/0/1-12", want: false},
+		{port: "Te1/1/2", filter: "Gi1/1/1-4", want: false},
+		{port: "5", filter: "Gi1/0/1-12", want: false},
+		{port: "12", filter: "12-1", want: true},
+		{port: "48", filter: "1-12, 48", want: true},
+		{port: "AGGR/1", filter: "AGGR/1", want: true},
+		{port: "AGGR/10", filter: "AGGR/1", want: false},
+		{port: "port-3", filter: "port-3", want: true},
+		{port: "1_MA-MOD-4X10G_1", filter: "1_ma-mod-4x10g_1", want: true},
+		{port: "unknown", filter: "3", want: false},
+		{port: "uplink-49", filter: "uplink", want: true},
 	}
 
 	for _, tt := range tests {
@@ -133,3 +310,161 @@ func TestMatchesPortFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestSamePort(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "5", b: "5", want: true},
+		{a: "Gi1/0/5", b: "5", want: true},
+		{a: "5", b: "Gi1/0/5", want: true},
+		{a: "GigabitEthernet1/0/5", b: "gi1/0/5", want: true},
+		{a: "Gi1/0/5", b: "Gi2/0/5", want: false},
+		{a: "Gi1/0/5", b: "Te1/0/5", want: false},
+		{a: "Gi1/0/5", b: "15", want: false},
+		{a: "AGGR/1", b: "aggr/1", want: true},
+		{a: "AGGR/1", b: "AGGR/2", want: false},
+		{a: "uplink", b: "UPLINK", want: true},
+		{a: "uplink", b: "5", want: false},
+	}
+	for _, tt := range tests {
+		if got := SamePort(tt.a, tt.b); got != tt.want {
+			t.Errorf("SamePort(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesVLANFilter(t *testing.T) {
+	tests := []struct {
+		vlan   int
+		filter int
+		want   bool
+	}{
+		{vlan: 120, filter: 0, want: true},
+		{vlan: 0, filter: 0, want: true},
+		{vlan: 120, filter: 120, want: true},
+		{vlan: 12, filter: 120, want: false},
+		{vlan: 0, filter: 120, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesVLANFilter(tt.vlan, tt.filter); got != tt.want {
+			t.Errorf("MatchesVLANFilter(%d, %d) = %v, want %v", tt.vlan, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesPortModeFilter(t *testing.T) {
+	tests := []struct {
+		mode   string
+		filter string
+		want   bool
+	}{
+		{mode: "trunk", filter: "", want: true},
+		{mode: "", filter: "", want: true},
+		{mode: "access", filter: "access", want: true},
+		{mode: "Trunk", filter: "trunk", want: true},
+		{mode: "trunk", filter: "access", want: false},
+		{mode: "", filter: "access", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesPortModeFilter(tt.mode, tt.filter); got != tt.want {
+			t.Errorf("MatchesPortModeFilter(%q, %q) = %v, want %v", tt.mode, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestDeviceType(t *testing.T) {
+	tests := []struct {
+		device meraki.Device
+		want   string
+	}{
+		{meraki.Device{Model: "MS120-8", ProductType: "switch"}, "switch"},
+		{meraki.Device{Model: "MS120-8"}, "switch"},
+		{meraki.Device{Model: "MR44", ProductType: "wireless"}, "wireless"},
+		{meraki.Device{Model: "CW9166"}, "wireless"},
+		{meraki.Device{Model: "MX68", ProductType: "Appliance"}, "appliance"},
+		{meraki.Device{Model: "Z4"}, "appliance"},
+		{meraki.Device{Model: "MV12", ProductType: "camera"}, "camera"},
+		{meraki.Device{Model: "XYZ"}, ""},
+	}
+	for _, tt := range tests {
+		if got := DeviceType(tt.device); got != tt.want {
+			t.Errorf("DeviceType(%+v) = %q, want %q", tt.device, got, tt.want)
+		}
+	}
+
+	devices := []meraki.Device{{Serial: "S", ProductType: "switch"}, {Serial: "W", ProductType: "wireless"}, {Serial: "A", ProductType: "appliance"}}
+	if got := FilterDevicesByType(devices, []string{"wireless", "appliance"}); len(got) != 2 || got[0].Serial != "W" || got[1].Serial != "A" {
+		t.Errorf("FilterDevicesByType(wireless,appliance) = %+v", got)
+	}
+}
+
+func TestParseDeviceTypes(t *testing.T) {
+	got, err := ParseDeviceTypes(" Switch, wireless,,switch ")
+	if err != nil || !slices.Equal(got, []string{"switch", "wireless"}) {
+		t.Errorf("ParseDeviceTypes() = %v, %v; want [switch wireless]", got, err)
+	}
+	if got, err := ParseDeviceTypes(""); got != nil || err != nil {
+		t.Errorf("ParseDeviceTypes(\"\") = %v, %v; want nil, nil", got, err)
+	}
+	if _, err := ParseDeviceTypes("switch,camera"); err == nil {
+		t.Error("ParseDeviceTypes(camera) succeeded, want error")
+	}
+}
+
+func TestMatchesEntryTypeFilter(t *testing.T) {
+	tests := []struct {
+		entryType string
+		filter    string
+		want      bool
+	}{
+		{entryType: "static", filter: "", want: true},
+		{entryType: "", filter: "", want: true},
+		{entryType: "static", filter: "static", want: true},
+		{entryType: "dynamic", filter: "static", want: false},
+		{entryType: "", filter: "dynamic", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesEntryTypeFilter(tt.entryType, tt.filter); got != tt.want {
+			t.Errorf("MatchesEntryTypeFilter(%q, %q) = %v, want %v", tt.entryType, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestExclusions(t *testing.T) {
+	SetExclusions([]string{" Core ", "Q2XX-0001", ""}, []string{"49", "aggr/1"})
+	t.Cleanup(func() { SetExclusions(nil, nil) })
+
+	devices := []meraki.Device{
+		{Serial: "Q2XX-0001", Name: "idf-2"},
+		{Serial: "Q2XX-0002", Name: "HQ-CORE-1"},
+		{Serial: "Q2XX-0003", Name: "idf-3"},
+	}
+	if got := ExcludeSwitches(devices); len(got) != 1 || got[0].Serial != "Q2XX-0003" {
+		t.Errorf("ExcludeSwitches() = %+v, want only Q2XX-0003", got)
+	}
+
+	ports := []struct {
+		port string
+		want bool
+	}{
+		{"49", true},
+		{"AGGR/1", true},
+		{"4", false},
+		{"149", false},
+	}
+	for _, tt := range ports {
+		if got := IsPortExcluded(tt.port); got != tt.want {
+			t.Errorf("IsPortExcluded(%q) = %v, want %v", tt.port, got, tt.want)
+		}
+	}
+
+	SetExclusions(nil, nil)
+	if got := ExcludeSwitches(devices); len(got) != 3 {
+		t.Errorf("ExcludeSwitches() after clearing = %d devices, want 3", len(got))
+	}
+}
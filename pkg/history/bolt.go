@@ -0,0 +1,181 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltOpenTimeout is how long opening a BoltDB file waits for another process
+// to close it: BoltDB lets only one process use a file at a time, so the file
+// is only held open for each read or write.
+const boltOpenTimeout = 10 * time.Second
+
+// boltHistoryBucket holds one sighting per network + NUL + MAC key, encoded as
+// in the JSON file backend.
+var boltHistoryBucket = []byte("mac_history")
+
+// boltFile is a BoltDB file that is opened for each transaction.
+type boltFile string
+
+// openBoltFile creates the BoltDB file at path if needed, so a bad path fails
+// on open like the other backends.
+func openBoltFile(path string) (boltFile, error) {
+	f := boltFile(path)
+	return f, f.view(func(*bolt.Tx) error { return nil })
+}
+
+func (f boltFile) open() (*bolt.DB, error) {
+	// Owner-only, since it holds client history.
+	db, err := bolt.Open(string(f), 0o600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("history database %s: %w", string(f), err)
+	}
+	return db, nil
+}
+
+func (f boltFile) view(fn func(*bolt.Tx) error) error {
+	db, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+	return db.View(fn)
+}
+
+func (f boltFile) update(fn func(*bolt.Tx) error) error {
+	db, err := f.open()
+	if err != nil {
+		return err
+	}
+	if err := db.Update(fn); err != nil {
+		_ = db.Close()
+		return err
+	}
+	return db.Close()
+}
+
+// boltBackend stores the history in a BoltDB file: a single local file like
+// the JSON backend, but updated in place rather than rewritten on every Save.
+type boltBackend struct {
+	file boltFile
+}
+
+func openBolt(path string) (Backend, error) {
+	f, err := openBoltFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &boltBackend{file: f}, nil
+}
+
+func boltKey(network, mac string) []byte {
+	return []byte(network + "\x00" + mac)
+}
+
+func (b *boltBackend) Load() ([]Sighting, error) {
+	var out []Sighting
+	err := b.file.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltHistoryBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var sg Sighting
+			if err := json.Unmarshal(v, &sg); err != nil {
+				return fmt.Errorf("history database: %q: %v", k, err)
+			}
+			sg.Network, sg.MAC, _ = strings.Cut(string(k), "\x00")
+			out = append(out, sg)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// Save merges the changed sightings into the stored ones the way the SQL
+// upsert does, so a sighting written by another run since Load is not lost.
+func (b *boltBackend) Save(_, changed []Sighting) error {
+	return b.file.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(boltHistoryBucket)
+		if err != nil {
+			return err
+		}
+		for _, sg := range changed {
+			key := boltKey(sg.Network, sg.MAC)
+			var stored Sighting
+			if v := bucket.Get(key); v != nil && json.Unmarshal(v, &stored) == nil {
+				sg = mergeSighting(stored, sg)
+			}
+			data, err := json.Marshal(sg)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// mergeSighting combines a stored sighting with a newer write of it: the
+// earliest first sighting wins and the latest last sighting carries the
+// switch and port.
+func mergeSighting(stored, sg Sighting) Sighting {
+	if stored.FirstSeen.Before(sg.FirstSeen) {
+		sg.FirstSeen = stored.FirstSeen
+	}
+	if stored.LastSeen.After(sg.LastSeen) {
+		sg.LastSeen, sg.Switch, sg.Port = stored.LastSeen, stored.Switch, stored.Port
+	}
+	return sg
+}
+
+// Prune deletes by last seen, like the SQL backend.
+func (b *boltBackend) Prune(cutoff time.Time, _ []Sighting) error {
+	return b.file.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltHistoryBucket)
+		if bucket == nil {
+			return nil
+		}
+		// Deleting while iterating skips keys, so collect them first.
+		var stale [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var sg Sighting
+			if json.Unmarshal(v, &sg) == nil && sg.LastSeen.Before(cutoff) {
+				stale = append(stale, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *boltBackend) Close() error { return nil }
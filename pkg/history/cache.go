@@ -0,0 +1,126 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package history
+
+import (
+	"database/sql"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// CacheStore keeps opaque values by key in a history database. The API
+// response cache stores its entries in one (see meraki.CacheStore), so
+// installations that share a history database share their inventory cache too.
+type CacheStore interface {
+	// Get returns the value stored under key; ok is false when there is none
+	// or it cannot be read.
+	Get(key string) (value []byte, ok bool)
+	Put(key string, value []byte) error
+	Close() error
+}
+
+// OpenCache opens the cache of the sqlite://, bolt:// or postgres:// database
+// at location, creating it if needed. File paths are refused: a cache that is
+// not shared is kept as files in a directory instead.
+func OpenCache(location string) (CacheStore, error) {
+	driver, dsn, tag, err := sqlTarget(location)
+	switch {
+	case err != nil:
+		return nil, err
+	case driver == "":
+		return nil, fmt.Errorf("%s is not a sqlite://, bolt:// or postgres:// URL", location)
+	case driver == "bolt":
+		f, err := openBoltFile(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return &boltCache{file: f}, nil
+	}
+	db, err := openSQLDB(driver, dsn, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlCache{db: db}, nil
+}
+
+// The cache values are JSON documents, so TEXT suits both SQLite and PostgreSQL.
+const (
+	sqlCreateCache = `CREATE TABLE IF NOT EXISTS api_cache (
+	cache_key TEXT NOT NULL PRIMARY KEY,
+	value     TEXT NOT NULL
+)`
+	sqlCacheGet = `SELECT value FROM api_cache WHERE cache_key = $1`
+	sqlCachePut = `INSERT INTO api_cache (cache_key, value) VALUES ($1, $2)
+ON CONFLICT (cache_key) DO UPDATE SET value = excluded.value`
+)
+
+// sqlCache stores the cache in the api_cache table.
+type sqlCache struct {
+	db *sql.DB
+}
+
+func (c *sqlCache) Get(key string) ([]byte, bool) {
+	var value string
+	if err := c.db.QueryRow(sqlCacheGet, key).Scan(&value); err != nil {
+		return nil, false
+	}
+	return []byte(value), true
+}
+
+func (c *sqlCache) Put(key string, value []byte) error {
+	if _, err := c.db.Exec(sqlCachePut, key, string(value)); err != nil {
+		return fmt.Errorf("cache database: %w", err)
+	}
+	return nil
+}
+
+func (c *sqlCache) Close() error { return c.db.Close() }
+
+// boltCache stores the cache in the api_cache bucket.
+type boltCache struct {
+	file boltFile
+}
+
+var boltCacheBucket = []byte("api_cache")
+
+func (c *boltCache) Get(key string) ([]byte, bool) {
+	var value []byte
+	_ = c.file.view(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(boltCacheBucket); b != nil {
+			// The slice is only valid inside the transaction.
+			value = append([]byte(nil), b.Get([]byte(key))...)
+		}
+		return nil
+	})
+	return value, len(value) > 0
+}
+
+func (c *boltCache) Put(key string, value []byte) error {
+	err := c.file.update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(boltCacheBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), value)
+	})
+	if err != nil {
+		return fmt.Errorf("cache database: %w", err)
+	}
+	return nil
+}
+
+func (c *boltCache) Close() error { return nil }
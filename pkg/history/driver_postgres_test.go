@@ -0,0 +1,59 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+//go:build postgres
+
+package history
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+)
+
+// TestPostgresBackend runs the backend and cache tests against the database
+// in $HISTORY_TEST_POSTGRES_URL (make test-postgres). Its tables are dropped
+// first, so point it at a scratch database.
+func TestPostgresBackend(t *testing.T) {
+	location := os.Getenv("HISTORY_TEST_POSTGRES_URL")
+	if location == "" {
+		t.Skip("HISTORY_TEST_POSTGRES_URL is not set")
+	}
+	db, err := sql.Open("postgres", location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DROP TABLE IF EXISTS mac_history, api_cache, history_schema`); err != nil {
+		t.Fatalf("resetting the test database: %v", err)
+	}
+	_ = db.Close()
+
+	testBackend(t, location)
+
+	c, err := OpenCache(location)
+	if err != nil {
+		t.Fatalf("OpenCache() error: %v", err)
+	}
+	defer func() { _ = c.Close() }()
+	if err := c.Put("k", []byte(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("k", []byte(`{"v":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := c.Get("k"); !ok || string(v) != `{"v":2}` {
+		t.Errorf("Get() = %s, %v; want the second Put", v, ok)
+	}
+}
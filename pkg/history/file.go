@@ -0,0 +1,94 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileBackend stores the history as one JSON object keyed by network name and
+// then MAC address.
+type fileBackend struct {
+	path string
+}
+
+func (b *fileBackend) Load() ([]Sighting, error) {
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var networks map[string]map[string]Sighting
+	if err := json.Unmarshal(data, &networks); err != nil {
+		return nil, fmt.Errorf("history file %s: %v", b.path, err)
+	}
+	var out []Sighting
+	for network, macs := range networks {
+		for mac, sg := range macs {
+			sg.Network, sg.MAC = network, mac
+			out = append(out, sg)
+		}
+	}
+	return out, nil
+}
+
+// Save rewrites the whole file. It is replaced atomically so an interrupted
+// run cannot truncate the history.
+func (b *fileBackend) Save(all, _ []Sighting) error {
+	networks := make(map[string]map[string]Sighting)
+	for _, sg := range all {
+		if networks[sg.Network] == nil {
+			networks[sg.Network] = make(map[string]Sighting)
+		}
+		networks[sg.Network][sg.MAC] = sg
+	}
+	data, err := json.MarshalIndent(networks, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), ".history-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), b.path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Prune rewrites the file with the remaining sightings.
+func (b *fileBackend) Prune(_ time.Time, kept []Sighting) error {
+	return b.Save(kept, nil)
+}
+
+func (b *fileBackend) Close() error { return nil }
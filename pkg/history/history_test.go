@@ -0,0 +1,471 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package history
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_ObserveAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() missing file error: %v", err)
+	}
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	if !s.Observe("HQ", "AA:BB:CC:DD:EE:FF", t0, "sw1", "3") {
+		t.Error("first Observe() should report a new MAC")
+	}
+	if s.Observe("HQ", "aa:bb:cc:dd:ee:ff", t0.Add(time.Hour), "sw2", "7") {
+		t.Error("second Observe() in same network should not be new")
+	}
+	if !s.Observe("Branch", "aa:bb:cc:dd:ee:ff", t0, "sw9", "1") {
+		t.Error("Observe() in another network should be new")
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	s2, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	got := s2.FirstSeenSince(t0)
+	if len(got) != 2 {
+		t.Fatalf("FirstSeenSince() = %d sightings, want 2", len(got))
+	}
+	for _, sg := range got {
+		if sg.Network == "HQ" && (sg.Switch != "sw2" || sg.Port != "7" || !sg.FirstSeen.Equal(t0)) {
+			t.Errorf("HQ sighting = %+v, want last seen on sw2/7 and first seen %v", sg, t0)
+		}
+	}
+	if got := s2.FirstSeenSince(t0.Add(time.Minute)); len(got) != 0 {
+		t.Errorf("FirstSeenSince(later) = %v, want none", got)
+	}
+	if sg, ok := s2.Lookup("HQ", "AA:BB:CC:DD:EE:FF"); !ok || sg.Switch != "sw2" || !sg.LastSeen.Equal(t0.Add(time.Hour)) {
+		t.Errorf("Lookup(HQ) = %+v, %v; want the sw2 sighting", sg, ok)
+	}
+	if _, ok := s2.Lookup("Lab", "aa:bb:cc:dd:ee:ff"); ok {
+		t.Error("Lookup() in an unknown network should miss")
+	}
+}
+
+// memBackend is an in-memory Backend that records what each Save received.
+type memBackend struct {
+	loaded []Sighting
+	saved  [][]Sighting
+	pruned []time.Time
+	kept   []Sighting
+	closed bool
+}
+
+func (b *memBackend) Load() ([]Sighting, error) { return b.loaded, nil }
+
+func (b *memBackend) Save(_, changed []Sighting) error {
+	b.saved = append(b.saved, changed)
+	return nil
+}
+
+func (b *memBackend) Prune(cutoff time.Time, kept []Sighting) error {
+	b.pruned = append(b.pruned, cutoff)
+	b.kept = kept
+	return nil
+}
+
+func (b *memBackend) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestStore_SavesOnlyChanged(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	b := &memBackend{loaded: []Sighting{
+		{Network: "HQ", MAC: "aa:aa:aa:aa:aa:aa", FirstSeen: t0, LastSeen: t0},
+		{Network: "HQ", MAC: "bb:bb:bb:bb:bb:bb", FirstSeen: t0, LastSeen: t0},
+	}}
+	s, err := NewStore(b)
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+	if s.Observe("HQ", "AA:AA:AA:AA:AA:AA", t0.Add(-time.Hour), "sw1", "1") {
+		t.Error("Observe() of a loaded MAC should not be new")
+	}
+	if err := s.Save(); err != nil || len(b.saved) != 0 {
+		t.Fatalf("Save() with only an older sighting wrote %v (err %v), want nothing", b.saved, err)
+	}
+	s.Observe("HQ", "bb:bb:bb:bb:bb:bb", t0.Add(time.Hour), "sw2", "7")
+	s.Observe("Branch", "cc:cc:cc:cc:cc:cc", t0, "sw3", "2")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if len(b.saved) != 1 || len(b.saved[0]) != 2 {
+		t.Fatalf("Save() wrote %v, want the 2 changed sightings", b.saved)
+	}
+	for _, sg := range b.saved[0] {
+		if sg.MAC == "aa:aa:aa:aa:aa:aa" {
+			t.Errorf("Save() wrote unchanged sighting %+v", sg)
+		}
+	}
+	if err := s.Save(); err != nil || len(b.saved) != 1 {
+		t.Errorf("second Save() wrote again: %v (err %v)", b.saved, err)
+	}
+	if err := s.Close(); err != nil || !b.closed {
+		t.Errorf("Close() = %v, backend closed = %v", err, b.closed)
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	b := &memBackend{loaded: []Sighting{
+		{Network: "HQ", MAC: "aa:aa:aa:aa:aa:aa", FirstSeen: t0, LastSeen: t0},
+		{Network: "HQ", MAC: "bb:bb:bb:bb:bb:bb", FirstSeen: t0, LastSeen: t0.Add(48 * time.Hour)},
+		{Network: "Lab", MAC: "cc:cc:cc:cc:cc:cc", FirstSeen: t0, LastSeen: t0},
+	}}
+	s, err := NewStore(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cutoff := t0.Add(24 * time.Hour)
+	if got := s.Stale(cutoff); got != 2 {
+		t.Errorf("Stale() = %d, want 2", got)
+	}
+	n, err := s.Prune(cutoff)
+	if err != nil || n != 2 {
+		t.Fatalf("Prune() = %d, %v; want 2", n, err)
+	}
+	if len(b.pruned) != 1 || !b.pruned[0].Equal(cutoff) || len(b.kept) != 1 || b.kept[0].MAC != "bb:bb:bb:bb:bb:bb" {
+		t.Errorf("backend Prune got cutoff %v, kept %+v", b.pruned, b.kept)
+	}
+	if !s.Observe("HQ", "aa:aa:aa:aa:aa:aa", t0.Add(72*time.Hour), "sw1", "1") {
+		t.Error("a pruned MAC seen again should be reported as new")
+	}
+	if n, _ := s.Prune(cutoff); n != 0 || len(b.pruned) != 1 {
+		t.Errorf("second Prune() = %d with %d backend calls; want nothing pruned and no backend call", n, len(b.pruned))
+	}
+
+	// The file backend rewrites itself without the pruned sightings.
+	path := filepath.Join(t.TempDir(), "history.json")
+	fs, _ := Open(path)
+	fs.Observe("HQ", "aa:aa:aa:aa:aa:aa", t0, "sw1", "1")
+	fs.Observe("HQ", "bb:bb:bb:bb:bb:bb", t0.Add(48*time.Hour), "sw1", "2")
+	if err := fs.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := fs.Prune(cutoff); n != 1 || err != nil {
+		t.Fatalf("file Prune() = %d, %v", n, err)
+	}
+	reloaded, _ := Open(path)
+	if got := reloaded.FirstSeenSince(t0); len(got) != 1 || got[0].MAC != "bb:bb:bb:bb:bb:bb" {
+		t.Errorf("after prune the file holds %+v", got)
+	}
+}
+
+func TestStore_Merge(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	b := &memBackend{loaded: []Sighting{
+		{Network: "HQ", MAC: "aa:aa:aa:aa:aa:aa", FirstSeen: t0, LastSeen: t0, Switch: "sw1", Port: "1"},
+		{Network: "HQ", MAC: "bb:bb:bb:bb:bb:bb", FirstSeen: t0, LastSeen: t0},
+	}}
+	s, err := NewStore(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := s.Merge([]Sighting{
+		{Network: "HQ", MAC: "AA:AA:AA:AA:AA:AA", FirstSeen: t0.Add(-time.Hour), LastSeen: t0.Add(time.Hour), Switch: "sw2", Port: "9"},
+		{Network: "HQ", MAC: "bb:bb:bb:bb:bb:bb", FirstSeen: t0.Add(time.Hour), LastSeen: t0.Add(-time.Hour)},
+		{Network: "Lab", MAC: "cc:cc:cc:cc:cc:cc", FirstSeen: t0, LastSeen: t0},
+	})
+	if n != 2 {
+		t.Errorf("Merge() = %d, want 2 (one widened, one added)", n)
+	}
+	got := s.Sightings()
+	if len(got) != 3 || got[0].MAC != "aa:aa:aa:aa:aa:aa" || got[2].Network != "Lab" {
+		t.Fatalf("Sightings() = %+v", got)
+	}
+	if a := got[0]; !a.FirstSeen.Equal(t0.Add(-time.Hour)) || !a.LastSeen.Equal(t0.Add(time.Hour)) || a.Switch != "sw2" {
+		t.Errorf("merged sighting = %+v, want the wider window and the later location", a)
+	}
+	if b := got[1]; !b.FirstSeen.Equal(t0) || !b.LastSeen.Equal(t0) {
+		t.Errorf("a narrower backup entry changed %+v", b)
+	}
+	if err := s.Save(); err != nil || len(b.saved) != 1 || len(b.saved[0]) != 2 {
+		t.Errorf("Save() after Merge wrote %v (err %v), want the 2 changed sightings", b.saved, err)
+	}
+}
+
+func TestOpenBackend(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		location string
+		want     string // backend type
+		wantErr  string
+	}{
+		{location: "/tmp/history.json", want: "*history.fileBackend"},
+		{location: `C:\Users\me\history.json`, want: "*history.fileBackend"},
+		{location: "sqlite://" + filepath.Join(dir, "history.db"), want: "*history.sqlBackend"},
+		{location: "bolt://" + filepath.Join(dir, "history.bolt"), want: "*history.boltBackend"},
+		{location: "redis://localhost", wantErr: "unknown history backend"},
+		{location: "postgres://localhost/find", wantErr: "-tags postgres"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			if strings.HasPrefix(tt.location, "postgres") && slices.Contains(sql.Drivers(), "postgres") {
+				t.Skip("PostgreSQL driver is compiled in")
+			}
+			b, err := OpenBackend(tt.location)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("OpenBackend() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OpenBackend() error: %v", err)
+			}
+			defer b.Close()
+			if got := fmt.Sprintf("%T", b); got != tt.want {
+				t.Errorf("OpenBackend() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// testBackend runs the same save, reload, concurrent-writer and prune checks
+// against the store at location.
+func testBackend(t *testing.T, location string) {
+	t.Helper()
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	open := func() *Store {
+		t.Helper()
+		s, err := Open(location)
+		if err != nil {
+			t.Fatalf("Open(%s) error: %v", location, err)
+		}
+		return s
+	}
+
+	a := open()
+	a.Observe("HQ", "aa:aa:aa:aa:aa:aa", t0, "sw1", "1")
+	a.Observe("HQ", "bb:bb:bb:bb:bb:bb", t0.Add(48*time.Hour), "sw1", "2")
+	if err := a.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	_ = a.Close()
+
+	// Two stores loaded from the same history both write aa: the earliest
+	// first sighting and the latest location win, whoever saves last.
+	b, c := open(), open()
+	b.Observe("HQ", "aa:aa:aa:aa:aa:aa", t0.Add(2*time.Hour), "sw3", "3")
+	c.Merge([]Sighting{{Network: "HQ", MAC: "aa:aa:aa:aa:aa:aa", FirstSeen: t0.Add(-time.Hour), LastSeen: t0.Add(time.Hour), Switch: "sw2", Port: "2"}})
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = b.Close(), c.Close()
+
+	d := open()
+	sg, ok := d.Lookup("HQ", "AA:AA:AA:AA:AA:AA")
+	if !ok || !sg.FirstSeen.Equal(t0.Add(-time.Hour)) || !sg.LastSeen.Equal(t0.Add(2*time.Hour)) || sg.Switch != "sw3" {
+		t.Errorf("after two writers aa = %+v, %v; want first seen %v and last seen on sw3", sg, ok, t0.Add(-time.Hour))
+	}
+	if n, err := d.Prune(t0.Add(24 * time.Hour)); n != 1 || err != nil {
+		t.Fatalf("Prune() = %d, %v; want 1", n, err)
+	}
+	_ = d.Close()
+	if got := open().Sightings(); len(got) != 1 || got[0].MAC != "bb:bb:bb:bb:bb:bb" {
+		t.Errorf("after prune the history holds %+v", got)
+	}
+}
+
+func TestBackends(t *testing.T) {
+	for _, scheme := range []string{"sqlite", "bolt"} {
+		t.Run(scheme, func(t *testing.T) {
+			testBackend(t, scheme+"://"+filepath.Join(t.TempDir(), "history.db"))
+		})
+	}
+}
+
+func TestOpenCache(t *testing.T) {
+	if _, err := OpenCache(filepath.Join(t.TempDir(), "cache")); err == nil {
+		t.Error("OpenCache(path) succeeded, want an error")
+	}
+	for _, scheme := range []string{"sqlite", "bolt"} {
+		t.Run(scheme, func(t *testing.T) {
+			location := scheme + "://" + filepath.Join(t.TempDir(), "history.db")
+			c, err := OpenCache(location)
+			if err != nil {
+				t.Fatalf("OpenCache() error: %v", err)
+			}
+			if _, ok := c.Get("k"); ok {
+				t.Error("Get() on an empty cache hit")
+			}
+			if err := c.Put("k", []byte(`{"v":1}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Put("k", []byte(`{"v":2}`)); err != nil {
+				t.Fatal(err)
+			}
+			if v, ok := c.Get("k"); !ok || string(v) != `{"v":2}` {
+				t.Errorf("Get() = %s, %v; want the second Put", v, ok)
+			}
+			_ = c.Close()
+
+			// The cache lives beside the history in the same database.
+			s, err := Open(location)
+			if err != nil {
+				t.Fatalf("Open() on a cache database: %v", err)
+			}
+			_ = s.Close()
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"7d", 7 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"xd", 0, true},
+		{"-1h", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSince(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSince(%q) = %v, %v; want %v, err=%v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// fakeSchema is an in-memory schemaDB. failAt makes that version's apply fail;
+// with raced set, another process "applies" it first.
+type fakeSchema struct {
+	applied []int
+	failAt  int
+	raced   bool
+}
+
+func (f *fakeSchema) version() (int, error) {
+	if len(f.applied) == 0 {
+		return 0, nil
+	}
+	return slices.Max(f.applied), nil
+}
+
+func (f *fakeSchema) apply(m Migration) error {
+	if m.Version == f.failAt {
+		if f.raced {
+			f.applied = append(f.applied, m.Version)
+		}
+		return errors.New("UNIQUE constraint failed")
+	}
+	f.applied = append(f.applied, m.Version)
+	return nil
+}
+
+func TestMigrateSchema(t *testing.T) {
+	latest := migrations[len(migrations)-1].Version
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migrations[%d].Version = %d; versions must be 1, 2, 3, ...", i, m.Version)
+		}
+	}
+
+	db := &fakeSchema{}
+	plan, err := migrateSchema(db, true)
+	if err != nil || plan.Current != 0 || plan.Latest != latest || len(plan.Pending) != latest || len(db.applied) != 0 {
+		t.Fatalf("dry run = %+v, %v, applied %v; want every step pending and nothing applied", plan, err, db.applied)
+	}
+	if _, err := migrateSchema(db, false); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := db.version(); v != latest {
+		t.Errorf("after migrate version = %d, want %d", v, latest)
+	}
+	if plan, err := migrateSchema(db, false); err != nil || len(plan.Pending) != 0 {
+		t.Errorf("second migrate = %+v, %v; want nothing pending", plan, err)
+	}
+
+	// Only the steps after the applied version run.
+	db = &fakeSchema{applied: []int{1}}
+	if plan, _ := migrateSchema(db, false); len(plan.Pending) != latest-1 || plan.Pending[0].Version != 2 {
+		t.Errorf("from v1 pending = %+v", plan.Pending)
+	}
+
+	if _, err := migrateSchema(&fakeSchema{applied: []int{latest + 1}}, false); err == nil || !strings.Contains(err.Error(), "newer than this build") {
+		t.Errorf("newer schema error = %v", err)
+	}
+	if _, err := migrateSchema(&fakeSchema{failAt: 1}, false); err == nil || !strings.Contains(err.Error(), "migration v1") {
+		t.Errorf("failed step error = %v", err)
+	}
+	if _, err := migrateSchema(&fakeSchema{failAt: 1, raced: true}, false); err != nil {
+		t.Errorf("step applied concurrently by another process: %v", err)
+	}
+}
+
+func TestMigrateFileBackend(t *testing.T) {
+	for _, location := range []string{filepath.Join(t.TempDir(), "history.json"), "bolt://" + filepath.Join(t.TempDir(), "history.bolt")} {
+		if _, err := Migrate(location, true); !errors.Is(err, ErrNoSchema) {
+			t.Errorf("Migrate(%s) error = %v, want ErrNoSchema", location, err)
+		}
+	}
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	location := "sqlite://" + filepath.Join(t.TempDir(), "history.db")
+	latest := migrations[len(migrations)-1].Version
+	if plan, err := Migrate(location, true); err != nil || len(plan.Pending) != latest {
+		t.Fatalf("dry run = %+v, %v; want every step pending", plan, err)
+	}
+	if _, err := Migrate(location, false); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err := Migrate(location, true); err != nil || plan.Current != latest || len(plan.Pending) != 0 {
+		t.Errorf("after migrate = %+v, %v; want v%d and nothing pending", plan, err, latest)
+	}
+}
+
+func TestSQLTarget(t *testing.T) {
+	tests := []struct {
+		location, driver, dsn string
+	}{
+		{"/var/lib/history.json", "", ""},
+		{"sqlite:///var/lib/h.db", "sqlite", "/var/lib/h.db?_pragma=busy_timeout(5000)"},
+		{"sqlite:///var/lib/h.db?_pragma=journal_mode(WAL)", "sqlite", "/var/lib/h.db?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"},
+		{"sqlite:///var/lib/h.db?_pragma=busy_timeout(100)", "sqlite", "/var/lib/h.db?_pragma=busy_timeout(100)"},
+		{"bolt:///var/lib/h.bolt", "bolt", "/var/lib/h.bolt"},
+		{"postgres://u:p@db/find", "postgres", "postgres://u:p@db/find"},
+	}
+	for _, tt := range tests {
+		driver, dsn, _, err := sqlTarget(tt.location)
+		if err != nil || driver != tt.driver || dsn != tt.dsn {
+			t.Errorf("sqlTarget(%q) = %q, %q, %v; want %q, %q", tt.location, driver, dsn, err, tt.driver, tt.dsn)
+		}
+	}
+}
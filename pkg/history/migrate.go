@@ -0,0 +1,164 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package history
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoSchema is returned by Migrate for the JSON file and BoltDB backends,
+// which have no schema to upgrade.
+var ErrNoSchema = errors.New("JSON and BoltDB history files have no schema; migrations apply to sqlite:// and postgres:// history databases")
+
+// Migration is one step of the SQL schema. Steps are never edited once
+// released: a change to the schema is always a new step at the end of
+// migrations, so every database upgrades through the same sequence.
+type Migration struct {
+	Version     int
+	Description string
+	Statements  []string
+}
+
+// migrations is the SQL schema history. Version 1 is the table that existed
+// before migrations were tracked; its IF NOT EXISTS makes it a no-op there.
+var migrations = []Migration{
+	{1, "create mac_history table", []string{sqlCreateTable}},
+	{2, "index mac_history by last_seen", []string{
+		`CREATE INDEX IF NOT EXISTS mac_history_last_seen ON mac_history (last_seen)`,
+	}},
+	{3, "create api_cache table", []string{sqlCreateCache}},
+}
+
+// MigrationPlan describes a history database's schema relative to this build.
+type MigrationPlan struct {
+	Current int         // applied version; 0 for a new or pre-migration database
+	Latest  int         // newest version this build knows
+	Pending []Migration // steps from Current to Latest, in order
+}
+
+// schemaDB is the part of a database the migrator needs, so the upgrade logic
+// can be tested without a SQL driver.
+type schemaDB interface {
+	// version returns the highest applied migration, without changing anything.
+	version() (int, error)
+	// apply runs m and records it, atomically.
+	apply(m Migration) error
+}
+
+// migrateSchema upgrades db to the latest version, or only reports what would
+// run when dryRun is set. A database written by a newer build is refused
+// rather than used, since this build cannot know what its schema means.
+func migrateSchema(db schemaDB, dryRun bool) (MigrationPlan, error) {
+	current, err := db.version()
+	if err != nil {
+		return MigrationPlan{}, fmt.Errorf("history database: reading schema version: %w", err)
+	}
+	plan := MigrationPlan{Current: current, Latest: migrations[len(migrations)-1].Version}
+	if current > plan.Latest {
+		return plan, fmt.Errorf("history database schema v%d is newer than this build supports (v%d); upgrade Find-Meraki-Ports-With-MAC", current, plan.Latest)
+	}
+	for _, m := range migrations {
+		if m.Version > current {
+			plan.Pending = append(plan.Pending, m)
+		}
+	}
+	if dryRun {
+		return plan, nil
+	}
+	for _, m := range plan.Pending {
+		if err := db.apply(m); err != nil {
+			// Another process sharing the database may have applied it first.
+			if v, verr := db.version(); verr == nil && v >= m.Version {
+				continue
+			}
+			return plan, fmt.Errorf("history database: migration v%d (%s): %w", m.Version, m.Description, err)
+		}
+	}
+	return plan, nil
+}
+
+// Migrate upgrades the history database at location to the latest schema, or
+// with dryRun only reports the pending steps. SQL stores are also upgraded
+// automatically when opened; Migrate lets an operator check first.
+func Migrate(location string, dryRun bool) (MigrationPlan, error) {
+	driver, dsn, tag, err := sqlTarget(location)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	if driver == "" || driver == "bolt" {
+		return MigrationPlan{}, ErrNoSchema
+	}
+	db, err := connectSQL(driver, dsn, tag)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	defer func() { _ = db.Close() }()
+	return migrateSchema(sqlSchema{db}, dryRun)
+}
+
+const (
+	sqlCreateMigrations = `CREATE TABLE IF NOT EXISTS history_schema (
+	version     INTEGER PRIMARY KEY,
+	description TEXT   NOT NULL,
+	applied_at  BIGINT NOT NULL
+)`
+	sqlSchemaVersion = `SELECT COALESCE(MAX(version), 0) FROM history_schema`
+	sqlRecordVersion = `INSERT INTO history_schema (version, description, applied_at) VALUES ($1, $2, $3)`
+)
+
+// sqlSchema tracks applied migrations in the history_schema table. SQLite and
+// PostgreSQL both roll back DDL with the transaction, so a failed step leaves
+// the database as it was.
+type sqlSchema struct {
+	db *sql.DB
+}
+
+// version creates the bookkeeping table inside a transaction that is always
+// rolled back, so a dry run leaves a new database untouched.
+func (s sqlSchema) version() (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(sqlCreateMigrations); err != nil {
+		return 0, err
+	}
+	var v int
+	err = tx.QueryRow(sqlSchemaVersion).Scan(&v)
+	return v, err
+}
+
+func (s sqlSchema) apply(m Migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range append([]string{sqlCreateMigrations}, m.Statements...) {
+		if _, err := tx.Exec(stmt); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.Exec(sqlRecordVersion, m.Version, m.Description, time.Now().UnixNano()); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
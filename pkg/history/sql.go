@@ -0,0 +1,131 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// The schema and upsert are plain SQL understood by both SQLite (3.24+) and
+// PostgreSQL. Times are Unix nanoseconds so comparisons need no date functions.
+// Sightings from several installations merge: the earliest first_seen wins and
+// the latest last_seen carries the switch and port.
+const (
+	sqlCreateTable = `CREATE TABLE IF NOT EXISTS mac_history (
+	network     TEXT   NOT NULL,
+	mac         TEXT   NOT NULL,
+	first_seen  BIGINT NOT NULL,
+	last_seen   BIGINT NOT NULL,
+	switch_name TEXT   NOT NULL DEFAULT '',
+	port        TEXT   NOT NULL DEFAULT '',
+	PRIMARY KEY (network, mac)
+)`
+	sqlPrune  = `DELETE FROM mac_history WHERE last_seen < $1`
+	sqlSelect = `SELECT network, mac, first_seen, last_seen, switch_name, port FROM mac_history`
+	sqlUpsert = `INSERT INTO mac_history (network, mac, first_seen, last_seen, switch_name, port)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (network, mac) DO UPDATE SET
+	first_seen  = CASE WHEN excluded.first_seen < mac_history.first_seen THEN excluded.first_seen ELSE mac_history.first_seen END,
+	switch_name = CASE WHEN excluded.last_seen > mac_history.last_seen THEN excluded.switch_name ELSE mac_history.switch_name END,
+	port        = CASE WHEN excluded.last_seen > mac_history.last_seen THEN excluded.port ELSE mac_history.port END,
+	last_seen   = CASE WHEN excluded.last_seen > mac_history.last_seen THEN excluded.last_seen ELSE mac_history.last_seen END`
+)
+
+// sqlBackend stores the history in the mac_history table of a SQL database.
+type sqlBackend struct {
+	db *sql.DB
+}
+
+// openSQL connects with the named database/sql driver and upgrades the schema
+// to the latest version (see migrations). tag is the build tag that links the
+// driver in.
+func openSQL(driver, dsn, tag string) (Backend, error) {
+	db, err := openSQLDB(driver, dsn, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlBackend{db: db}, nil
+}
+
+// openSQLDB connects and migrates; see openSQL.
+func openSQLDB(driver, dsn, tag string) (*sql.DB, error) {
+	db, err := connectSQL(driver, dsn, tag)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrateSchema(sqlSchema{db}, false); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// connectSQL opens a database with the named driver, failing with a rebuild
+// hint when the driver is not compiled in.
+func connectSQL(driver, dsn, tag string) (*sql.DB, error) {
+	if !slices.Contains(sql.Drivers(), driver) {
+		return nil, fmt.Errorf("history backend %s is not compiled in; rebuild with -tags %s", driver, tag)
+	}
+	return sql.Open(driver, dsn)
+}
+
+func (b *sqlBackend) Load() ([]Sighting, error) {
+	rows, err := b.db.Query(sqlSelect)
+	if err != nil {
+		return nil, fmt.Errorf("history database: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []Sighting
+	for rows.Next() {
+		var sg Sighting
+		var first, last int64
+		if err := rows.Scan(&sg.Network, &sg.MAC, &first, &last, &sg.Switch, &sg.Port); err != nil {
+			return nil, fmt.Errorf("history database: %w", err)
+		}
+		sg.FirstSeen, sg.LastSeen = time.Unix(0, first), time.Unix(0, last)
+		out = append(out, sg)
+	}
+	return out, rows.Err()
+}
+
+// Save upserts only the changed sightings in one transaction.
+func (b *sqlBackend) Save(_, changed []Sighting) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, sg := range changed {
+		if _, err := tx.Exec(sqlUpsert, sg.Network, sg.MAC, sg.FirstSeen.UnixNano(), sg.LastSeen.UnixNano(), sg.Switch, sg.Port); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("history database: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Prune deletes by last_seen, so rows written by other installations since
+// this store was loaded are pruned too.
+func (b *sqlBackend) Prune(cutoff time.Time, _ []Sighting) error {
+	if _, err := b.db.Exec(sqlPrune, cutoff.UnixNano()); err != nil {
+		return fmt.Errorf("history database: %w", err)
+	}
+	return nil
+}
+
+func (b *sqlBackend) Close() error { return b.db.Close() }
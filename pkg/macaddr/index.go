@@ -0,0 +1,232 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package macaddr
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// nibbleMasks is a compiled MAC pattern: bit v of mask i is set when the i-th
+// hex digit of a matching MAC may have value v.
+type nibbleMasks [12]uint16
+
+// match reports whether the 48-bit MAC v satisfies every nibble mask.
+func (m *nibbleMasks) match(v uint64) bool {
+	for i := range m {
+		if m[i]&(1<<((v>>(44-4*i))&0xF)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fixedOUI reports whether the first six nibbles each allow a single value,
+// so the pattern can be filed under its OUI.
+func (m *nibbleMasks) fixedOUI() bool {
+	for i := 0; i < 6; i++ {
+		if bits.OnesCount16(m[i]) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// oui returns the OUI of a pattern for which fixedOUI is true.
+func (m *nibbleMasks) oui() uint64 {
+	var v uint64
+	for i := 0; i < 6; i++ {
+		v = v<<4 | uint64(bits.TrailingZeros16(m[i]))
+	}
+	return v
+}
+
+// PatternIndex matches MACs against a set of exact addresses and wildcard
+// patterns without running a regular expression per entry. Patterns are
+// compiled to per-nibble bitmasks and those with a fixed OUI are indexed by
+// it, so a lookup parses the MAC once and only checks the patterns sharing
+// its OUI plus any whose OUI is itself wildcarded. Build it once per search
+// and share it across switches; it is safe for concurrent Match calls once
+// populated.
+type PatternIndex struct {
+	exact map[uint64]struct{}
+	byOUI map[uint64][]nibbleMasks
+	rest  []nibbleMasks
+}
+
+// NewPatternIndex returns an empty index.
+func NewPatternIndex() *PatternIndex {
+	return &PatternIndex{exact: make(map[uint64]struct{}), byOUI: make(map[uint64][]nibbleMasks)}
+}
+
+// AddExact adds a single MAC address in any format NormalizeExactMac accepts.
+func (x *PatternIndex) AddExact(mac string) error {
+	normalized, err := NormalizeExactMac(mac)
+	if err != nil {
+		return err
+	}
+	v, _ := parseMAC(normalized)
+	x.exact[v] = struct{}{}
+	return nil
+}
+
+// AddPattern adds a wildcard pattern already passed through
+// NormalizePatternInput, e.g. "0011223344*" or "0011223344[1-4][0-F]".
+func (x *PatternIndex) AddPattern(clean string) error {
+	m, err := compilePattern(clean)
+	if err != nil {
+		return err
+	}
+	if m.fixedOUI() {
+		key := m.oui()
+		x.byOUI[key] = append(x.byOUI[key], m)
+		return nil
+	}
+	x.rest = append(x.rest, m)
+	return nil
+}
+
+// Match reports whether mac equals an exact entry or matches a pattern.
+// MACs that do not parse never match.
+func (x *PatternIndex) Match(mac string) bool {
+	v, ok := parseMAC(mac)
+	if !ok {
+		return false
+	}
+	if _, ok := x.exact[v]; ok {
+		return true
+	}
+	for i := range x.byOUI[v>>24] {
+		if x.byOUI[v>>24][i].match(v) {
+			return true
+		}
+	}
+	for i := range x.rest {
+		if x.rest[i].match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMAC returns a MAC as a 48-bit integer without allocating, accepting
+// the separators NormalizeExactMac strips.
+func parseMAC(mac string) (uint64, bool) {
+	var v uint64
+	n := 0
+	for i := 0; i < len(mac); i++ {
+		c := mac[i]
+		if c == ':' || c == '.' || c == '-' {
+			continue
+		}
+		d, ok := hexValue(c)
+		if !ok || n == 12 {
+			return 0, false
+		}
+		v = v<<4 | uint64(d)
+		n++
+	}
+	return v, n == 12
+}
+
+// compilePattern turns a normalized pattern into nibble masks. It accepts the
+// same syntax as BuildMacRegex and reports the same errors.
+func compilePattern(clean string) (nibbleMasks, error) {
+	var m nibbleMasks
+	n := 0
+	set := func(mask uint16) {
+		if n < len(m) {
+			m[n] = mask
+		}
+		n++
+	}
+	i := 0
+	for i < len(clean) {
+		switch clean[i] {
+		case '[':
+			end := strings.Index(clean[i:], "]")
+			if end == -1 {
+				return m, errors.New("unmatched bracket in MAC pattern")
+			}
+			token := clean[i : i+end+1]
+			sanitized, err := sanitizeBracket(token)
+			if err != nil {
+				return m, err
+			}
+			mask, err := bracketMask(sanitized[1 : len(sanitized)-1])
+			if err != nil {
+				return m, fmt.Errorf("invalid bracket pattern: %s", token)
+			}
+			set(mask)
+			i += end + 1
+		case '*':
+			set(0xFFFF)
+			set(0xFFFF)
+			i++
+		default:
+			d, ok := hexValue(clean[i])
+			if !ok {
+				return m, fmt.Errorf("invalid MAC pattern: %s", clean)
+			}
+			set(1 << d)
+			i++
+		}
+	}
+	if n != 12 {
+		return m, fmt.Errorf("invalid MAC pattern length (need 12 nibbles): %s", clean)
+	}
+	return m, nil
+}
+
+// bracketMask returns the values allowed by the inside of a sanitized bracket
+// such as "1-4" or "0-F". As in a regex character class, a '-' that does not
+// sit between two digits stands for itself and so matches no hex digit.
+func bracketMask(inner string) (uint16, error) {
+	var mask uint16
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '-' {
+			continue
+		}
+		lo, _ := hexValue(inner[i])
+		hi := lo
+		if i+2 < len(inner) && inner[i+1] == '-' {
+			hi, _ = hexValue(inner[i+2])
+			if hi < lo {
+				return 0, errors.New("reversed range")
+			}
+			i += 2
+		}
+		for d := lo; d <= hi; d++ {
+			mask |= 1 << d
+		}
+	}
+	return mask, nil
+}
+
+// hexValue returns the value of a hexadecimal digit.
+func hexValue(c byte) (uint8, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
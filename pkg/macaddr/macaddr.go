@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -96,8 +97,7 @@ func BuildMacMatcher(input string) (func(string) bool, string, bool, error) {
 		return nil, "", false, errors.New("MAC pattern cannot be empty")
 	}
 
-	hasWildcard := strings.Contains(input, "*") || strings.Contains(input, "[")
-	if !hasWildcard {
+	if !isMacPattern(input) {
 		normalized, err := NormalizeExactMac(input)
 		if err != nil {
 			return nil, "", false, err
@@ -108,18 +108,112 @@ func BuildMacMatcher(input string) (func(string) bool, string, bool, error) {
 		}, displayMac, false, nil
 	}
 
-	clean := NormalizePatternInput(input)
-	re, err := BuildMacRegex(clean)
-	if err != nil {
+	idx := NewPatternIndex()
+	if err := idx.AddPattern(NormalizePatternInput(input)); err != nil {
 		return nil, "", false, err
 	}
+	return idx.Match, input, true, nil
+}
+
+// isMacPattern reports whether input uses wildcard or bracket syntax rather
+// than naming a single MAC.
+func isMacPattern(input string) bool {
+	return strings.ContainsAny(input, "*[")
+}
+
+// SplitMacList splits a comma-separated list of MACs or patterns, trimming
+// whitespace and dropping empty entries.
+func SplitMacList(input string) []string {
+	var out []string
+	for _, part := range strings.Split(input, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// BuildMultiMacMatcher is BuildMacMatcher for a comma-separated list of MACs
+// and patterns. The matcher accepts a MAC when any entry matches, so several
+// addresses are found in a single scan. It returns the display form of each
+// entry and whether any entry is a pattern. An invalid entry is reported by
+// value so the user knows which one to fix. All entries share one
+// PatternIndex, so the cost per MAC does not grow with the number of
+// patterns that have distinct OUIs.
+func BuildMultiMacMatcher(input string) (func(string) bool, []string, bool, error) {
+	entries := SplitMacList(input)
+	if len(entries) == 0 {
+		return nil, nil, false, errors.New("MAC pattern cannot be empty")
+	}
+	if len(entries) == 1 {
+		m, display, isPattern, err := BuildMacMatcher(entries[0])
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return m, []string{display}, isPattern, nil
+	}
+
+	idx := NewPatternIndex()
+	displays := make([]string, 0, len(entries))
+	anyPattern := false
+	for _, entry := range entries {
+		display := entry
+		var err error
+		if isMacPattern(entry) {
+			anyPattern = true
+			err = idx.AddPattern(NormalizePatternInput(entry))
+		} else if err = idx.AddExact(entry); err == nil {
+			normalized, _ := NormalizeExactMac(entry)
+			display = FormatMacColon(normalized)
+		}
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("%q: %w", entry, err)
+		}
+		displays = append(displays, display)
+	}
+	return idx.Match, displays, anyPattern, nil
+}
+
+// ParseMacRange parses an inclusive range "first-last" such as
+// "00:11:22:33:44:00-00:11:22:33:44:ff" and returns both ends as 48-bit
+// integers. Either end may use any format NormalizeExactMac accepts, including
+// dash separators, so every '-' is tried as the split point.
+func ParseMacRange(input string) (first, last uint64, err error) {
+	input = strings.TrimSpace(input)
+	for i := 0; i < len(input); i++ {
+		if input[i] != '-' {
+			continue
+		}
+		lo, errLo := NormalizeExactMac(strings.TrimSpace(input[:i]))
+		hi, errHi := NormalizeExactMac(strings.TrimSpace(input[i+1:]))
+		if errLo != nil || errHi != nil {
+			continue
+		}
+		first, _ = strconv.ParseUint(lo, 16, 64)
+		last, _ = strconv.ParseUint(hi, 16, 64)
+		if first > last {
+			return 0, 0, fmt.Errorf("MAC range %s: start is after end", input)
+		}
+		return first, last, nil
+	}
+	return 0, 0, fmt.Errorf("invalid MAC range %q (want first-last, e.g. 00:11:22:33:44:00-00:11:22:33:44:ff)", input)
+}
+
+// BuildMacRangeMatcher returns a matcher for every MAC in the inclusive range
+// accepted by ParseMacRange, e.g. an allocation block from an asset system.
+func BuildMacRangeMatcher(input string) (func(string) bool, error) {
+	first, last, err := ParseMacRange(input)
+	if err != nil {
+		return nil, err
+	}
 	return func(mac string) bool {
-		normalized, err := NormalizeExactMac(mac)
+		norm, err := NormalizeExactMac(mac)
 		if err != nil {
 			return false
 		}
-		return re.MatchString(strings.ToUpper(normalized))
-	}, input, true, nil
+		v, _ := strconv.ParseUint(norm, 16, 64)
+		return v >= first && v <= last
+	}, nil
 }
 
 // BuildMacRegex builds a regex pattern from a normalized MAC pattern string.
@@ -16,6 +16,9 @@
 package macaddr
 
 import (
+	"fmt"
+	"math/rand"
+	"strings"
 	"testing"
 )
 
@@ -205,6 +208,114 @@ func TestBuildMacMatcher(t *testing.T) {
 	}
 }
 
+func TestBuildMultiMacMatcher(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		testMACs    map[string]bool
+		wantDisplay int
+		wantPattern bool
+		wantErr     bool
+	}{
+		{
+			name:        "single MAC",
+			input:       "00:11:22:33:44:55",
+			testMACs:    map[string]bool{"001122334455": true, "001122334456": false},
+			wantDisplay: 1,
+		},
+		{
+			name:  "union of exact MACs",
+			input: "00:11:22:33:44:55, AA-BB-CC-DD-EE-FF,",
+			testMACs: map[string]bool{
+				"001122334455": true,
+				"aabbccddeeff": true,
+				"001122334456": false,
+			},
+			wantDisplay: 2,
+		},
+		{
+			name:  "exact MAC and pattern",
+			input: "00:11:22:33:44:55,aa:bb:cc:dd:ee:*",
+			testMACs: map[string]bool{
+				"001122334455": true,
+				"aabbccddee01": true,
+				"aabbccddef01": false,
+			},
+			wantDisplay: 2,
+			wantPattern: true,
+		},
+		{name: "one invalid entry", input: "00:11:22:33:44:55,zz", wantErr: true},
+		{name: "only separators", input: " , ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, displays, isPattern, err := BuildMultiMacMatcher(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildMultiMacMatcher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(displays) != tt.wantDisplay || isPattern != tt.wantPattern {
+				t.Errorf("BuildMultiMacMatcher() displays = %v, isPattern = %v; want %d entries, %v", displays, isPattern, tt.wantDisplay, tt.wantPattern)
+			}
+			for mac, shouldMatch := range tt.testMACs {
+				if matcher(mac) != shouldMatch {
+					t.Errorf("matcher(%q) = %v, want %v", mac, !shouldMatch, shouldMatch)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildMacRangeMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		testMACs map[string]bool
+		wantErr  bool
+	}{
+		{
+			name:  "last byte block",
+			input: "00:11:22:33:44:00-00:11:22:33:44:ff",
+			testMACs: map[string]bool{
+				"001122334400": true,
+				"0011223344ff": true,
+				"001122334380": false,
+				"001122334500": false,
+			},
+		},
+		{
+			name:  "dash separated ends crossing a byte boundary",
+			input: "00-11-22-33-44-f0 - 00-11-22-33-45-0f",
+			testMACs: map[string]bool{
+				"0011223344f0": true,
+				"001122334500": true,
+				"00112233450f": true,
+				"001122334510": false,
+			},
+		},
+		{name: "single address", input: "aa:bb:cc:dd:ee:ff-aa:bb:cc:dd:ee:ff", testMACs: map[string]bool{"aabbccddeeff": true, "aabbccddeefe": false}},
+		{name: "reversed", input: "00:11:22:33:44:ff-00:11:22:33:44:00", wantErr: true},
+		{name: "not a range", input: "00:11:22:33:44:55", wantErr: true},
+		{name: "bad end", input: "00:11:22:33:44:00-zz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := BuildMacRangeMatcher(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildMacRangeMatcher(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			for mac, want := range tt.testMACs {
+				if got := matcher(mac); got != want {
+					t.Errorf("matcher(%q) = %v, want %v", mac, got, want)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkNormalizeExactMac(b *testing.B) {
 	mac := "00:11:22:33:44:55"
 	for i := 0; i < b.N; i++ {
@@ -218,3 +329,122 @@ func BenchmarkBuildMacRegex(b *testing.B) {
 		_, _ = BuildMacRegex(pattern)
 	}
 }
+
+func TestPatternIndex_AgreesWithRegex(t *testing.T) {
+	patterns := []string{
+		"0011223344*",
+		"001122***",
+		"*1122334455",
+		"0011223344[1-4][0-F]",
+		"00112233[A-C]F*",
+		"0011223344[135]F",
+		"0011223344[1-]F",
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, p := range patterns {
+		re, err := BuildMacRegex(p)
+		if err != nil {
+			t.Fatalf("BuildMacRegex(%q): %v", p, err)
+		}
+		idx := NewPatternIndex()
+		if err := idx.AddPattern(p); err != nil {
+			t.Fatalf("AddPattern(%q): %v", p, err)
+		}
+		for i := 0; i < 5000; i++ {
+			// Keep most MACs near the patterns so both outcomes are exercised.
+			mac := fmt.Sprintf("0011%02x33%04x", rng.Intn(4)+0x21, rng.Intn(0x10000))
+			if i%2 == 0 {
+				mac = fmt.Sprintf("%012x", rng.Int63n(1<<48))
+			}
+			if got, want := idx.Match(mac), re.MatchString(strings.ToUpper(mac)); got != want {
+				t.Fatalf("pattern %q, mac %s: index = %v, regex = %v", p, mac, got, want)
+			}
+		}
+	}
+}
+
+func TestPatternIndex(t *testing.T) {
+	idx := NewPatternIndex()
+	if err := idx.AddExact("aa:bb:cc:dd:ee:ff"); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"001122***", "*0000000001"} {
+		if err := idx.AddPattern(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := map[string]bool{
+		"aabbccddeeff":      true,
+		"AA-BB-CC-DD-EE-FF": true,
+		"0011229abcde":      true,
+		"ff0000000001":      true,
+		"001123000000":      false,
+		"aabbccddeefe":      false,
+		"0011229abcd":       false,
+		"0011229abcdef":     false,
+		"zz1122334455":      false,
+	}
+	for mac, want := range tests {
+		if got := idx.Match(mac); got != want {
+			t.Errorf("Match(%q) = %v, want %v", mac, got, want)
+		}
+	}
+
+	for _, bad := range []string{"0011223344**", "0011223344[F-0]F", "0011223344[1-4F", "00112233445G", "0011223344[]F"} {
+		if err := NewPatternIndex().AddPattern(bad); err == nil {
+			t.Errorf("AddPattern(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+// benchMACs is a synthetic org-wide table for the matcher benchmarks.
+func benchMACs() []string {
+	rng := rand.New(rand.NewSource(1))
+	macs := make([]string, 10000)
+	for i := range macs {
+		macs[i] = fmt.Sprintf("%012x", rng.Int63n(1<<48))
+	}
+	return macs
+}
+
+func BenchmarkMatchRegex(b *testing.B) {
+	re, _ := BuildMacRegex("0011223344*")
+	macs := benchMACs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mac := macs[i%len(macs)]
+		normalized, _ := NormalizeExactMac(mac)
+		_ = re.MatchString(strings.ToUpper(normalized))
+	}
+}
+
+func BenchmarkMatchPatternIndex(b *testing.B) {
+	m, _, _, _ := BuildMultiMacMatcher("00:11:22:33:44:*,aa:bb:cc:*:*:*,00:00:5e:00:01:*")
+	macs := benchMACs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = m(macs[i%len(macs)])
+	}
+}
+
+func TestVirtualMAC(t *testing.T) {
+	tests := []struct {
+		mac       string
+		wantKind  string
+		wantGroup int
+		wantOK    bool
+	}{
+		{"00005e000101", VirtualVRRP, 1, true},
+		{"00005E0002FF", VirtualVRRP, 255, true},
+		{"00000c07ac0a", VirtualHSRP, 10, true},
+		{"00000c9ff123", VirtualHSRP, 0x123, true},
+		{"001122334455", VirtualNone, 0, false},
+		{"short", VirtualNone, 0, false},
+	}
+	for _, tt := range tests {
+		kind, group, ok := VirtualMAC(tt.mac)
+		if kind != tt.wantKind || group != tt.wantGroup || ok != tt.wantOK {
+			t.Errorf("VirtualMAC(%q) = (%q, %d, %v), want (%q, %d, %v)", tt.mac, kind, group, ok, tt.wantKind, tt.wantGroup, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package macaddr
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Virtual MAC kinds returned by VirtualMAC.
+const (
+	VirtualVRRP  = "VRRP" // RFC 5798 IPv4/IPv6 virtual router (MX warm spare, MS virtual router)
+	VirtualHSRP  = "HSRP" // Cisco HSRP v1/v2 (Catalyst)
+	VirtualNone  = ""
+	vrrpV4Prefix = "00005e0001"
+	vrrpV6Prefix = "00005e0002"
+	hsrpV1Prefix = "00000c07ac"
+	hsrpV2Prefix = "00000c9ff"
+)
+
+// VirtualMAC reports whether a normalized 12-character MAC is a first-hop
+// redundancy virtual MAC rather than a real NIC. It returns the protocol kind
+// and the virtual router / group ID encoded in the address.
+//
+// MX warm-spare pairs and MS virtual router interfaces both use VRRP, so their
+// shared gateway MAC appears on whichever switch port faces the active unit.
+func VirtualMAC(norm string) (kind string, group int, ok bool) {
+	norm = strings.ToLower(norm)
+	if len(norm) != 12 {
+		return VirtualNone, 0, false
+	}
+	parse := func(hex string) int {
+		n, _ := strconv.ParseInt(hex, 16, 32)
+		return int(n)
+	}
+	switch {
+	case strings.HasPrefix(norm, vrrpV4Prefix), strings.HasPrefix(norm, vrrpV6Prefix):
+		return VirtualVRRP, parse(norm[10:]), true
+	case strings.HasPrefix(norm, hsrpV1Prefix):
+		return VirtualHSRP, parse(norm[10:]), true
+	case strings.HasPrefix(norm, hsrpV2Prefix):
+		return VirtualHSRP, parse(norm[9:]), true
+	}
+	return VirtualNone, 0, false
+}
@@ -0,0 +1,167 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package meraki
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// cacheablePaths are the slow-changing listings a ResponseCache keeps,
+// relative to the API base URL.
+var cacheablePaths = regexp.MustCompile(`^/(organizations|organizations/[^/]+/networks|networks/[^/]+/devices)$`)
+
+// ResponseCache keeps successful responses of the organization, network and
+// device listings on disk or in a CacheStore, so repeated runs do not refetch
+// the inventory.
+// Entries are keyed by API key and URL (each page separately) and are used
+// until they are TTL old.
+type ResponseCache struct {
+	Dir     string        // entries are files here unless Store is set
+	Store   CacheStore    // where entries are kept; nil means files in Dir
+	TTL     time.Duration // how long an entry is used
+	Refresh bool          // fetch everything afresh, then store it as usual
+
+	now func() time.Time // nil means time.Now
+}
+
+// CacheStore keeps ResponseCache entries by key. history.OpenCache returns one
+// in a SQLite, BoltDB or PostgreSQL database, so several installations can
+// share the cache.
+type CacheStore interface {
+	// Get returns the value stored under key; ok is false when there is none.
+	Get(key string) (value []byte, ok bool)
+	Put(key string, value []byte) error
+}
+
+// dirStore is the default CacheStore: one file per entry in a directory.
+type dirStore string
+
+func (d dirStore) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(filepath.Join(string(d), key+".json"))
+	return data, err == nil
+}
+
+// Put replaces the entry's file atomically, so a concurrent run never reads
+// half an entry.
+func (d dirStore) Put(key string, value []byte) error {
+	if err := os.MkdirAll(string(d), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(string(d), ".entry-*")
+	if err != nil {
+		return err
+	}
+	_, werr := tmp.Write(value)
+	if cerr := tmp.Close(); werr != nil || cerr != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Join(werr, cerr)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(string(d), key+".json")); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// DefaultCacheDir is find-mac-api-cache in the user cache directory, or in
+// the working directory when there is none.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "find-mac-api-cache"
+	}
+	return filepath.Join(dir, "find-mac-api-cache")
+}
+
+// SetCache makes m use c for the listings it covers; nil turns caching off.
+func (m *MerakiClient) SetCache(c *ResponseCache) {
+	m.cache = c
+}
+
+// cacheEntry is the on-disk form of one cached page.
+type cacheEntry struct {
+	URL    string          `json:"url"`
+	Stored time.Time       `json:"stored"`
+	Next   string          `json:"next,omitempty"`
+	Body   json.RawMessage `json:"body"`
+}
+
+func (c *ResponseCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// covers reports whether fullURL is one of the cached listings under baseURL.
+func (c *ResponseCache) covers(baseURL, fullURL string) bool {
+	if c == nil || c.TTL <= 0 || !strings.HasPrefix(fullURL, baseURL+"/") {
+		return false
+	}
+	u, err := url.Parse(strings.TrimPrefix(fullURL, baseURL))
+	return err == nil && cacheablePaths.MatchString(u.Path)
+}
+
+func (c *ResponseCache) store() CacheStore {
+	if c.Store != nil {
+		return c.Store
+	}
+	return dirStore(c.Dir)
+}
+
+// key is the store key of the entry for apiKey and fullURL. The API key is
+// hashed in, so one user's inventory is never served to another key.
+func (c *ResponseCache) key(apiKey, fullURL string) string {
+	sum := sha256.Sum256([]byte(apiKey + "\x00" + fullURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns a fresh cached page.
+func (c *ResponseCache) get(apiKey, fullURL string) ([]byte, string, bool) {
+	if c.Refresh {
+		return nil, "", false
+	}
+	data, ok := c.store().Get(c.key(apiKey, fullURL))
+	if !ok {
+		return nil, "", false
+	}
+	var e cacheEntry
+	if json.Unmarshal(data, &e) != nil || e.URL != fullURL || c.clock().Sub(e.Stored) >= c.TTL {
+		return nil, "", false
+	}
+	return e.Body, e.Next, true
+}
+
+// put stores a page. Failures are ignored: the cache only saves requests.
+func (c *ResponseCache) put(apiKey, fullURL string, body []byte, next string) {
+	if !json.Valid(body) {
+		return
+	}
+	data, err := json.Marshal(cacheEntry{URL: fullURL, Stored: c.clock().UTC(), Next: next, Body: body})
+	if err != nil {
+		return
+	}
+	_ = c.store().Put(c.key(apiKey, fullURL), data)
+}
@@ -19,6 +19,7 @@
 package meraki
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -30,6 +31,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -41,17 +44,22 @@ type Organization struct {
 
 // Network represents a Meraki network.
 type Network struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	ProductTypes []string `json:"productTypes,omitempty"`
 }
 
 // Device represents a Meraki device (switch, access point, etc.).
 type Device struct {
-	Serial      string `json:"serial"`
-	Name        string `json:"name"`
-	Model       string `json:"model"`
-	ProductType string `json:"productType"`
-	NetworkID   string `json:"networkId"`
+	Serial      string   `json:"serial"`
+	Name        string   `json:"name"`
+	Model       string   `json:"model"`
+	ProductType string   `json:"productType"`
+	NetworkID   string   `json:"networkId"`
+	Firmware    string   `json:"firmware"` // e.g. "switch-17-10-4", or "Not running configured version"
+	MAC         string   `json:"mac"`
+	LanIP       string   `json:"lanIp"`
+	Tags        []string `json:"tags"`
 }
 
 // Client represents a client connected to a device.
@@ -65,6 +73,7 @@ type Client struct {
 
 // NetworkClient represents a client at the network level.
 type NetworkClient struct {
+	ID                 string `json:"id"`
 	MAC                string `json:"mac"`
 	Switchport         string `json:"switchport"`
 	SwitchportName     string `json:"switchportName"`
@@ -77,33 +86,101 @@ type NetworkClient struct {
 	Description        string `json:"description"`
 	DhcpHostname       string `json:"dhcpHostname"`
 	Notes              string `json:"notes"`
+	SSID               string `json:"ssid"`                   // last SSID for wireless clients
+	RecentConnection   string `json:"recentDeviceConnection"` // "Wired" or "Wireless"
+	FirstSeen          string `json:"firstSeen"`
 }
 
 // MerakiClient is an HTTP client wrapper for the Meraki Dashboard API.
 type MerakiClient struct {
-	apiKey     string
-	baseURL    string
-	maxRetries int
-	client     *http.Client
+	apiKey    string
+	baseURL   string
+	client    *http.Client
+	retry     RetryPolicy                                      // see SetRetryPolicy
+	sleep     func(ctx context.Context, d time.Duration) error // waits between retries; replaced in tests
+	jitter    func(d time.Duration) time.Duration              // randomises computed backoff; replaced in tests
+	warnf     func(format string, args ...interface{})         // schema drift warnings; see SetWarnFunc
+	warned    sync.Map                                         // warnOnce keys already reported
+	portCache sync.Map                                         // serial → map[portID]SwitchPort; see GetSwitchPort
+	limiter   *rateLimiter                                     // caps the request rate; see SetRateLimit
+	debugf    func(format string, args ...interface{})         // per-call log; see SetDebugFunc
+	cache     *ResponseCache                                   // on-disk listing cache; see SetCache
+
+	requestID  string        // X-Request-Id prefix when the context has none
+	requestSeq atomic.Uint64 // numbers the calls; see WithRequestID
+}
+
+// maxPages caps how many pages getAllPages follows for one listing. At the
+// largest page sizes this is far beyond any real organization, so hitting it
+// means the Link headers are looping.
+const maxPages = 1000
+
+// maxRetryDelay caps the wait between retries, whether computed or requested
+// by a Retry-After header.
+const maxRetryDelay = 60 * time.Second
+
+// SleepContext waits for d or until ctx is done, whichever comes first, and
+// returns ctx.Err() in the latter case. Poll loops use it so a cancelled
+// search stops waiting at once.
+func SleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// retryDelay returns how long to wait before retry number attempt (0-based).
+// A Retry-After header (delta-seconds or HTTP-date) wins; otherwise the delay
+// doubles from one second. Both are capped at maxRetryDelay.
+func retryDelay(retryAfter string, attempt int, now time.Time) time.Duration {
+	d := maxRetryDelay
+	if attempt < 6 {
+		d = time.Second << uint(attempt)
+	}
+	if retryAfter = strings.TrimSpace(retryAfter); retryAfter != "" {
+		if secs, err := strconv.Atoi(retryAfter); err == nil && secs >= 0 {
+			d = time.Duration(secs) * time.Second
+		} else if at, err := http.ParseTime(retryAfter); err == nil {
+			d = at.Sub(now)
+			if d < 0 {
+				d = 0
+			}
+		}
+	}
+	if d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	return d
 }
 
-// NewClient creates a new Meraki API client.
-// maxRetries controls how many times a 429 response is retried; 0 uses the default of 6.
+// NewClient creates a new Meraki API client with DefaultRetryPolicy.
+// maxRetries overrides how many attempts are made when a request fails
+// transiently; 0 uses the default of 6. See SetRetryPolicy for the rest.
 func NewClient(apiKey, baseURL string, maxRetries int) *MerakiClient {
 	if baseURL == "" {
 		baseURL = "https://api.meraki.com/api/v1"
 	}
 	baseURL = strings.TrimRight(baseURL, "/")
-	if maxRetries <= 0 {
-		maxRetries = 6
+	retry := DefaultRetryPolicy()
+	if maxRetries > 0 {
+		retry.MaxAttempts = maxRetries
 	}
 	return &MerakiClient{
-		apiKey:     apiKey,
-		baseURL:    baseURL,
-		maxRetries: maxRetries,
+		apiKey:  apiKey,
+		baseURL: baseURL,
+		retry:   retry,
 		client: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout:   60 * time.Second,
+			Transport: Transport(), // see SetProxy
 		},
+		sleep:     SleepContext,
+		jitter:    equalJitter,
+		limiter:   newRateLimiter(DefaultRateLimit),
+		requestID: NewRequestID(),
 	}
 }
 
@@ -113,14 +190,7 @@ func (m *MerakiClient) GetOrganizations(ctx context.Context) ([]Organization, er
 	if err != nil {
 		return nil, err
 	}
-	orgs := make([]Organization, 0, len(raws))
-	for _, r := range raws {
-		var o Organization
-		if err := json.Unmarshal(r, &o); err == nil {
-			orgs = append(orgs, o)
-		}
-	}
-	return orgs, nil
+	return decodeItems[Organization](m, "GET /organizations", raws, "id", "name"), nil
 }
 
 // GetNetworks retrieves all networks for a given organization.
@@ -130,14 +200,7 @@ func (m *MerakiClient) GetNetworks(ctx context.Context, orgID string) ([]Network
 	if err != nil {
 		return nil, err
 	}
-	nets := make([]Network, 0, len(raws))
-	for _, r := range raws {
-		var n Network
-		if err := json.Unmarshal(r, &n); err == nil {
-			nets = append(nets, n)
-		}
-	}
-	return nets, nil
+	return decodeItems[Network](m, "GET /organizations/{id}/networks", raws, "id", "name"), nil
 }
 
 // GetDevices retrieves all devices in a network.
@@ -147,14 +210,96 @@ func (m *MerakiClient) GetDevices(ctx context.Context, networkID string) ([]Devi
 	if err != nil {
 		return nil, err
 	}
-	devs := make([]Device, 0, len(raws))
-	for _, r := range raws {
-		var d Device
-		if err := json.Unmarshal(r, &d); err == nil {
-			devs = append(devs, d)
+	return decodeItems[Device](m, "GET /networks/{id}/devices", raws, "serial", "model"), nil
+}
+
+// GetOrganizationDevices lists the devices of an organization in one paginated
+// listing, or only those in networkIDs when any are given. Each device carries
+// its NetworkID, so callers can group them without a request per network.
+func (m *MerakiClient) GetOrganizationDevices(ctx context.Context, orgID string, networkIDs []string) ([]Device, error) {
+	path := fmt.Sprintf("/organizations/%s/devices", orgID)
+	params := url.Values{"perPage": []string{"1000"}}
+	if len(networkIDs) > 0 {
+		params["networkIds[]"] = networkIDs
+	}
+	raws, err := m.getAllPages(ctx, path, params)
+	if err != nil {
+		return nil, err
+	}
+	return decodeItems[Device](m, "GET /organizations/{id}/devices", raws, "serial", "networkId"), nil
+}
+
+// DeviceUplinkAddresses is one device from the organization-wide uplink address
+// listing. MAC is the device's own (management) MAC address.
+type DeviceUplinkAddresses struct {
+	Serial      string `json:"serial"`
+	Name        string `json:"name"`
+	MAC         string `json:"mac"`
+	ProductType string `json:"productType"`
+	Network     struct {
+		ID string `json:"id"`
+	} `json:"network"`
+	Uplinks []struct {
+		Interface string `json:"interface"`
+		Addresses []struct {
+			Protocol string `json:"protocol"`
+			Address  string `json:"address"`
+		} `json:"addresses"`
+	} `json:"uplinks"`
+}
+
+// UplinkAddress returns the first IPv4 uplink address of the device, or the
+// first address of any protocol, or "".
+func (d DeviceUplinkAddresses) UplinkAddress() string {
+	first := ""
+	for _, u := range d.Uplinks {
+		for _, a := range u.Addresses {
+			if a.Protocol == "ipv4" && a.Address != "" {
+				return a.Address
+			}
+			if first == "" {
+				first = a.Address
+			}
 		}
 	}
-	return devs, nil
+	return first
+}
+
+// GetOrganizationDeviceUplinkAddresses lists the uplink (management) MAC and
+// addresses of every device in an organization.
+func (m *MerakiClient) GetOrganizationDeviceUplinkAddresses(ctx context.Context, orgID string) ([]DeviceUplinkAddresses, error) {
+	path := fmt.Sprintf("/organizations/%s/devices/uplinks/addresses/byDevice", orgID)
+	raws, err := m.getAllPages(ctx, path, url.Values{"perPage": []string{"1000"}})
+	if err != nil {
+		return nil, err
+	}
+	return decodeItems[DeviceUplinkAddresses](m, "GET /organizations/{id}/devices/uplinks/addresses/byDevice", raws, "serial", "mac"), nil
+}
+
+// DeviceStatus is one device from the organization-wide device status listing.
+type DeviceStatus struct {
+	Serial         string `json:"serial"`
+	Name           string `json:"name"`
+	NetworkID      string `json:"networkId"`
+	ProductType    string `json:"productType"`
+	Model          string `json:"model"`
+	Status         string `json:"status"`         // "online", "alerting", "offline" or "dormant"
+	LastReportedAt string `json:"lastReportedAt"` // RFC 3339; empty if the device never reported
+}
+
+// GetOrganizationDeviceStatuses lists the dashboard status of the devices in
+// an organization, or only of those in networkIDs when any are given.
+func (m *MerakiClient) GetOrganizationDeviceStatuses(ctx context.Context, orgID string, networkIDs []string) ([]DeviceStatus, error) {
+	path := fmt.Sprintf("/organizations/%s/devices/statuses", orgID)
+	params := url.Values{"perPage": []string{"1000"}}
+	if len(networkIDs) > 0 {
+		params["networkIds[]"] = networkIDs
+	}
+	raws, err := m.getAllPages(ctx, path, params)
+	if err != nil {
+		return nil, err
+	}
+	return decodeItems[DeviceStatus](m, "GET /organizations/{id}/devices/statuses", raws, "serial", "status"), nil
 }
 
 // GetDeviceClients retrieves clients connected to a specific device.
@@ -169,14 +314,7 @@ func (m *MerakiClient) GetDeviceClients(ctx context.Context, serial string) ([]C
 	if err != nil {
 		return nil, err
 	}
-	clients := make([]Client, 0, len(raws))
-	for _, r := range raws {
-		var c Client
-		if err := json.Unmarshal(r, &c); err == nil {
-			clients = append(clients, c)
-		}
-	}
-	return clients, nil
+	return decodeItems[Client](m, "GET /devices/{serial}/clients", raws, "mac"), nil
 }
 
 // GetNetworkClients retrieves all clients across a network.
@@ -191,14 +329,166 @@ func (m *MerakiClient) GetNetworkClients(ctx context.Context, networkID string)
 	if err != nil {
 		return nil, err
 	}
-	clients := make([]NetworkClient, 0, len(raws))
-	for _, r := range raws {
-		var c NetworkClient
-		if err := json.Unmarshal(r, &c); err == nil {
-			clients = append(clients, c)
+	return decodeItems[NetworkClient](m, "GET /networks/{id}/clients", raws, "mac", "recentDeviceSerial"), nil
+}
+
+// EachNetworkClientPage is the streaming form of GetNetworkClients: fn is
+// called with the decoded clients of each page as it arrives. An error from fn
+// stops the listing and is returned.
+func (m *MerakiClient) EachNetworkClientPage(ctx context.Context, networkID string, fn func([]NetworkClient) error) error {
+	path := fmt.Sprintf("/networks/%s/clients", networkID)
+	params := url.Values{
+		"perPage":  []string{"1000"},
+		"timespan": []string{"2592000"}, // 30 days
+	}
+	return m.Pages(ctx, path, params, func(body []byte) error {
+		var raws []json.RawMessage
+		if err := json.Unmarshal(body, &raws); err != nil {
+			return err
+		}
+		return fn(decodeItems[NetworkClient](m, "GET /networks/{id}/clients", raws, "mac", "recentDeviceSerial"))
+	})
+}
+
+// GetNetworkClient retrieves a single client by its Meraki client ID (e.g. "k74272e"),
+// the identifier that appears in dashboard URLs and webhook payloads.
+// Returns an error wrapping the API status when the client is not in the network.
+func (m *MerakiClient) GetNetworkClient(ctx context.Context, networkID, clientID string) (*NetworkClient, error) {
+	path := fmt.Sprintf("/networks/%s/clients/%s", networkID, url.PathEscape(clientID))
+	body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, nil))
+	if err != nil {
+		return nil, err
+	}
+	var c NetworkClient
+	if err := json.Unmarshal(body, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ClientSearchRecord is one network in which an organization-wide client
+// search has seen a MAC.
+type ClientSearchRecord struct {
+	Network struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"network"`
+	ClientID           string `json:"clientId"`
+	IP                 string `json:"ip"`
+	Description        string `json:"description"`
+	Switchport         string `json:"switchport"`
+	RecentDeviceSerial string `json:"recentDeviceSerial"`
+	RecentDeviceName   string `json:"recentDeviceName"`
+}
+
+// GetOrganizationClientsSearch returns every network in the organization where
+// the client with the given exact MAC has been seen. The response is a single
+// object whose records are paginated, so getAllPages does not apply.
+// The API answers 404 when the MAC was never seen as a client.
+func (m *MerakiClient) GetOrganizationClientsSearch(ctx context.Context, orgID, mac string) ([]ClientSearchRecord, error) {
+	path := fmt.Sprintf("/organizations/%s/clients/search", orgID)
+	fullURL := m.buildURL(path, url.Values{"mac": []string{mac}, "perPage": []string{"5"}})
+	var all []ClientSearchRecord
+	seen := make(map[string]struct{})
+	for pages := 1; ; pages++ {
+		if _, dup := seen[fullURL]; dup || pages > maxPages {
+			return nil, fmt.Errorf("pagination on %s did not terminate after %d pages", path, pages-1)
+		}
+		seen[fullURL] = struct{}{}
+
+		body, next, err := m.doRequest(ctx, "GET", fullURL)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Records []ClientSearchRecord `json:"records"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Records...)
+		if next == "" {
+			return all, nil
+		}
+		fullURL = next
+	}
+}
+
+// NetworkEvent is a single entry from the network event log.
+type NetworkEvent struct {
+	OccurredAt        string                 `json:"occurredAt"`
+	Type              string                 `json:"type"`
+	Description       string                 `json:"description"`
+	Category          string                 `json:"category"`
+	ClientID          string                 `json:"clientId"`
+	ClientDescription string                 `json:"clientDescription"`
+	ClientMac         string                 `json:"clientMac"`
+	DeviceSerial      string                 `json:"deviceSerial"`
+	DeviceName        string                 `json:"deviceName"`
+	SsidNumber        *int                   `json:"ssidNumber"`
+	SsidName          string                 `json:"ssidName"`
+	EventData         map[string]interface{} `json:"eventData"`
+}
+
+// GetNetworkEvents retrieves one page of the network event log, newest first.
+// params are passed through (e.g. productType, clientMac, includedEventTypes[], perPage).
+// The events endpoint wraps results in an object, so it is not paged via getAllPages.
+func (m *MerakiClient) GetNetworkEvents(ctx context.Context, networkID string, params url.Values) ([]NetworkEvent, error) {
+	path := fmt.Sprintf("/networks/%s/events", networkID)
+	body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, params))
+	if err != nil {
+		return nil, err
+	}
+	var page struct {
+		Events []NetworkEvent `json:"events"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, err
+	}
+	return page.Events, nil
+}
+
+// eventPageLimit caps how many pages GetNetworkEventsSince follows, so a busy
+// network's log cannot turn one lookup into hundreds of requests.
+const eventPageLimit = 20
+
+// GetNetworkEventsSince retrieves the network events that occurred at or after
+// since, oldest first. params are passed through as for GetNetworkEvents. The
+// log is paged forwards from since using the pageEndAt marker of each response;
+// at most eventPageLimit pages of perPage events are read.
+func (m *MerakiClient) GetNetworkEventsSince(ctx context.Context, networkID string, params url.Values, since time.Time) ([]NetworkEvent, error) {
+	path := fmt.Sprintf("/networks/%s/events", networkID)
+	q := url.Values{}
+	for k, v := range params {
+		q[k] = v
+	}
+	if q.Get("perPage") == "" {
+		q.Set("perPage", "1000")
+	}
+	perPage, _ := strconv.Atoi(q.Get("perPage"))
+	q.Set("startingAfter", since.UTC().Format(time.RFC3339))
+
+	var all []NetworkEvent
+	for i := 0; i < eventPageLimit; i++ {
+		body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, q))
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			PageEndAt string         `json:"pageEndAt"`
+			Events    []NetworkEvent `json:"events"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
 		}
+		all = append(all, page.Events...)
+		if len(page.Events) < perPage || page.PageEndAt == "" || page.PageEndAt == q.Get("startingAfter") {
+			break
+		}
+		q.Set("startingAfter", page.PageEndAt)
 	}
-	return clients, nil
+	sort.SliceStable(all, func(i, j int) bool { return all[i].OccurredAt < all[j].OccurredAt })
+	return all, nil
 }
 
 // CreateMacTableLookup initiates a live MAC table lookup on a device.
@@ -315,7 +605,9 @@ func (m *MerakiClient) FetchArpMap(ctx context.Context, serial string, maxPoll i
 		return result
 	}
 	for i := 0; i < maxPoll; i++ {
-		time.Sleep(2 * time.Second)
+		if SleepContext(ctx, 2*time.Second) != nil {
+			return result
+		}
 		entries, status, err := m.GetArpTableLookup(ctx, serial, arpID)
 		if err != nil || status == "failed" {
 			return result
@@ -342,26 +634,52 @@ func (m *MerakiClient) FetchArpMap(ctx context.Context, serial string, maxPoll i
 	return result
 }
 
-// getAllPages handles pagination for API endpoints that return arrays.
-// It follows the Link header with rel="next" until all pages are retrieved.
-func (m *MerakiClient) getAllPages(ctx context.Context, path string, params url.Values) ([]json.RawMessage, error) {
+// Pages streams a paginated listing one page at a time. It follows the Link
+// header with rel="next", calling fn with the raw JSON array of each page, and
+// fails instead of looping forever when a next link repeats or maxPages is hit.
+// An error returned by fn stops the iteration and is returned as is, so
+// callers can filter very large listings (50k+ clients) without holding every
+// page in memory.
+func (m *MerakiClient) Pages(ctx context.Context, path string, params url.Values, fn func(page []byte) error) error {
 	fullURL := m.buildURL(path, params)
-	var all []json.RawMessage
-	for {
+	seen := make(map[string]struct{})
+	for pages := 1; ; pages++ {
+		if _, dup := seen[fullURL]; dup {
+			return fmt.Errorf("pagination loop on %s: page %d links back to %s (malformed Link header?)", path, pages, fullURL)
+		}
+		if pages > maxPages {
+			return fmt.Errorf("pagination on %s exceeded %d pages; aborting", path, maxPages)
+		}
+		seen[fullURL] = struct{}{}
+
 		body, next, err := m.doRequest(ctx, "GET", fullURL)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		var page []json.RawMessage
-		if err := json.Unmarshal(body, &page); err != nil {
-			return nil, err
+		if err := fn(body); err != nil {
+			return err
 		}
-		all = append(all, page...)
 		if next == "" {
-			break
+			return nil
 		}
 		fullURL = next
 	}
+}
+
+// getAllPages collects every item of a paginated listing via Pages.
+func (m *MerakiClient) getAllPages(ctx context.Context, path string, params url.Values) ([]json.RawMessage, error) {
+	var all []json.RawMessage
+	err := m.Pages(ctx, path, params, func(body []byte) error {
+		var page []json.RawMessage
+		if err := json.Unmarshal(body, &page); err != nil {
+			return err
+		}
+		all = append(all, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return all, nil
 }
 
@@ -378,44 +696,105 @@ func (m *MerakiClient) buildURL(path string, params url.Values) string {
 }
 
 // doRequest executes an HTTP request with retry logic and rate limit handling.
-// It automatically retries on 429 (Too Many Requests) with exponential backoff.
-// Returns the response body, next page URL (from Link header), and any error.
+// Responses with a status in the client's RetryPolicy (429, 502, 503 and 504
+// by default) are retried, honouring Retry-After and otherwise backing off
+// exponentially with jitter; so are transient network errors on idempotent
+// requests. Listings covered by SetCache are served from the cache while
+// fresh. Returns the response body, next page URL (from Link header), and any
+// error.
 func (m *MerakiClient) doRequest(ctx context.Context, method, fullURL string) ([]byte, string, error) {
-	for attempt := 0; attempt < m.maxRetries; attempt++ {
-		req, err := http.NewRequestWithContext(ctx, method, fullURL, nil)
+	if method != http.MethodGet || !m.cache.covers(m.baseURL, fullURL) {
+		return m.doRequestBody(ctx, method, fullURL, nil)
+	}
+	if body, next, ok := m.cache.get(m.apiKey, fullURL); ok {
+		if u, err := url.Parse(fullURL); err == nil && m.debugf != nil {
+			m.debugf("API GET %s: from cache", u.Path)
+		}
+		return body, next, nil
+	}
+	body, next, err := m.doRequestBody(ctx, method, fullURL, nil)
+	if err == nil {
+		m.cache.put(m.apiKey, fullURL, body, next)
+	}
+	return body, next, err
+}
+
+// doRequestBody is doRequest with an optional JSON request body.
+func (m *MerakiClient) doRequestBody(ctx context.Context, method, fullURL string, payload []byte) ([]byte, string, error) {
+	p := m.retry
+	start := time.Now()
+	requestID := m.nextRequestID(ctx)
+	for attempt := 0; ; attempt++ {
+		last := attempt == p.MaxAttempts-1
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+		if err := m.limiter.wait(ctx); err != nil {
+			return nil, "", err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, fullURL, reqBody)
 		if err != nil {
 			return nil, "", err
 		}
 		req.Header.Set("X-Cisco-Meraki-API-Key", m.apiKey)
 		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", currentUserAgent())
+		req.Header.Set("X-Request-Id", requestID)
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
 
+		sent := time.Now()
 		resp, err := m.client.Do(req)
 		if err != nil {
-			return nil, "", err
+			m.logCall(req, requestID, attempt, sent, err.Error())
+			if last || !idempotent(method) || !isTransientNetError(ctx, err) {
+				return nil, "", err
+			}
+			d := m.jitter(retryDelay("", attempt, time.Now()))
+			if !p.withinBudget(start, d) {
+				return nil, "", err
+			}
+			if err := m.sleep(ctx, d); err != nil {
+				return nil, "", err
+			}
+			continue
 		}
 		body, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
+		m.logCall(req, requestID, attempt, sent, resp.Status)
 
-		if resp.StatusCode == http.StatusTooManyRequests {
+		if p.retryableStatus(method, resp.StatusCode) {
 			retryAfter := resp.Header.Get("Retry-After")
-			if retryAfter != "" {
-				if seconds, err := time.ParseDuration(retryAfter + "s"); err == nil {
-					time.Sleep(seconds)
-					continue
-				}
+			d := retryDelay(retryAfter, attempt, time.Now())
+			if retryAfter == "" {
+				d = m.jitter(d)
+			}
+			if last || !p.withinBudget(start, d) {
+				return nil, "", newAPIError(resp.StatusCode, strings.TrimSpace(string(body)), attempt+1, d)
+			}
+			if err := m.sleep(ctx, d); err != nil {
+				return nil, "", err
 			}
-			time.Sleep(time.Second * time.Duration(1+attempt))
 			continue
 		}
 
 		if resp.StatusCode >= 300 {
-			return nil, "", fmt.Errorf("meraki API error %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+			return nil, "", newAPIError(resp.StatusCode, strings.TrimSpace(string(body)), attempt+1, 0)
 		}
 
 		next := parseLinkNext(resp.Header.Get("Link"))
 		return body, next, nil
 	}
-	return nil, "", errors.New("meraki API request failed after retries")
+}
+
+// logCall reports one attempt of an API call through the debug callback.
+func (m *MerakiClient) logCall(req *http.Request, requestID string, attempt int, sent time.Time, outcome string) {
+	if m.debugf == nil {
+		return
+	}
+	m.debugf("API %s %s: %s in %s (X-Request-Id %s, attempt %d)", req.Method, req.URL.Path, outcome, time.Since(sent).Round(time.Millisecond), requestID, attempt+1)
 }
 
 // customDNSServers holds optional user-supplied DNS server addresses (host:port).
@@ -426,6 +805,10 @@ var customDNSServers []string
 // Use "*" as a wildcard for either part. Set via SetHostOverrides.
 var hostOverrides map[string]map[string]string
 
+// hostNamesMu guards customDNSServers and hostOverrides. Both are replaced
+// wholesale, never mutated in place, so a HostNames snapshot can share them.
+var hostNamesMu sync.RWMutex
+
 // hostOverrideEntry is the JSON shape for a single HOST_OVERRIDES entry.
 type hostOverrideEntry struct {
 	Org      string `json:"org"`
@@ -443,18 +826,32 @@ type hostOverrideEntry struct {
 // Example:
 //
 //	[{"org":"Acme Corp","net":"HQ","ip":"192.168.1.1","hostname":"gateway"},{"org":"*","net":"*","ip":"10.0.0.1","hostname":"core"}]
-func SetHostOverrides(raw string) {
-	raw = strings.TrimSpace(raw)
-	if raw == "" {
-		hostOverrides = nil
-		return
-	}
+//
+// Invalid JSON clears the overrides and returns an error so the caller can warn.
+func SetHostOverrides(raw string) error {
+	m, err := parseHostOverrides(nil, raw)
+	hostNamesMu.Lock()
+	hostOverrides = m
+	hostNamesMu.Unlock()
+	return err
+}
+
+// parseHostOverrides layers the entries in raw over a copy of base. base is
+// never modified. A nil map is returned when the result has no entries.
+func parseHostOverrides(base map[string]map[string]string, raw string) (map[string]map[string]string, error) {
 	var entries []hostOverrideEntry
-	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
-		hostOverrides = nil
-		return
+	if raw = strings.TrimSpace(raw); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+			return nil, fmt.Errorf("invalid host overrides: %w", err)
+		}
+	}
+	m := make(map[string]map[string]string, len(base))
+	for scope, ips := range base {
+		m[scope] = make(map[string]string, len(ips))
+		for ip, hn := range ips {
+			m[scope][ip] = hn
+		}
 	}
-	m := make(map[string]map[string]string)
 	for _, e := range entries {
 		org := strings.TrimSpace(e.Org)
 		net := strings.TrimSpace(e.Net)
@@ -475,38 +872,17 @@ func SetHostOverrides(raw string) {
 		}
 		m[scope][ip] = hn
 	}
-	if len(m) > 0 {
-		hostOverrides = m
-	} else {
-		hostOverrides = nil
+	if len(m) == 0 {
+		return nil, nil
 	}
+	return m, nil
 }
 
 // LookupHostOverride returns the static hostname override for the given IP
-// within the specified org and network. Lookup priority:
-//  1. orgName/netName  (exact match)
-//  2. orgName/*        (org matches, any network)
-//  3. */netName        (any org, network matches)
-//  4. */*              (global fallback / backward-compat bare entries)
-//
-// Returns "" if no override is found.
+// within the specified org and network. See HostNames.Override for the lookup
+// priority. Returns "" if no override is found.
 func LookupHostOverride(ip, orgName, netName string) string {
-	if hostOverrides == nil {
-		return ""
-	}
-	for _, key := range []string{
-		orgName + "/" + netName,
-		orgName + "/*",
-		"*/" + netName,
-		"*/*",
-	} {
-		if m, ok := hostOverrides[key]; ok {
-			if hn, ok := m[ip]; ok {
-				return hn
-			}
-		}
-	}
-	return ""
+	return CurrentHostNames().Override(ip, orgName, netName)
 }
 
 // SetDNSServers configures one or more DNS servers for reverse hostname lookups.
@@ -524,12 +900,82 @@ func SetDNSServers(servers []string) {
 		}
 		cleaned = append(cleaned, s)
 	}
+	hostNamesMu.Lock()
 	customDNSServers = cleaned
+	hostNamesMu.Unlock()
 }
 
 // ResolveHostname performs reverse DNS lookup on an IP address.
 // Returns the hostname or empty string if lookup fails.
 func ResolveHostname(ip string) (string, error) {
+	return CurrentHostNames().Resolve(ip)
+}
+
+// LookupHostIPs forward-resolves a DNS name using the HostNames carried by ctx,
+// falling back to the servers configured with SetDNSServers (or the system
+// resolver). See HostNames.LookupIPs.
+func LookupHostIPs(ctx context.Context, name string) ([]string, error) {
+	return HostNamesFrom(ctx).LookupIPs(ctx, name)
+}
+
+// HostNames is an immutable snapshot of the host overrides and DNS servers
+// used to name IPs. The process-wide settings suit the CLI; the web server
+// derives one per request with WithOverrides so concurrent searches with
+// different org contexts never see each other's overrides.
+type HostNames struct {
+	overrides  map[string]map[string]string
+	dnsServers []string
+}
+
+// CurrentHostNames snapshots the process-wide settings from SetHostOverrides
+// and SetDNSServers.
+func CurrentHostNames() *HostNames {
+	hostNamesMu.RLock()
+	defer hostNamesMu.RUnlock()
+	return &HostNames{overrides: hostOverrides, dnsServers: customDNSServers}
+}
+
+// WithOverrides returns a copy of h with the HOST_OVERRIDES-format entries in
+// raw layered on top; entries in raw win for the same scope and IP. h itself
+// is unchanged.
+func (h *HostNames) WithOverrides(raw string) (*HostNames, error) {
+	m, err := parseHostOverrides(h.overrides, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &HostNames{overrides: m, dnsServers: h.dnsServers}, nil
+}
+
+// Override returns the static hostname override for ip within the given org
+// and network. Lookup priority:
+//  1. orgName/netName  (exact match)
+//  2. orgName/*        (org matches, any network)
+//  3. */netName        (any org, network matches)
+//  4. */*              (global fallback / backward-compat bare entries)
+//
+// Returns "" if no override is found.
+func (h *HostNames) Override(ip, orgName, netName string) string {
+	if h.overrides == nil {
+		return ""
+	}
+	for _, key := range []string{
+		orgName + "/" + netName,
+		orgName + "/*",
+		"*/" + netName,
+		"*/*",
+	} {
+		if m, ok := h.overrides[key]; ok {
+			if hn, ok := m[ip]; ok {
+				return hn
+			}
+		}
+	}
+	return ""
+}
+
+// Resolve performs reverse DNS lookup on an IP address.
+// Returns the hostname or empty string if lookup fails.
+func (h *HostNames) Resolve(ip string) (string, error) {
 	if ip == "" {
 		return "", nil
 	}
@@ -538,9 +984,52 @@ func ResolveHostname(ip string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// Perform reverse DNS lookup
+	names, err := h.resolver().LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return "", err
+	}
+
+	// Return the first name, trim trailing dot
+	return strings.TrimSuffix(names[0], "."), nil
+}
+
+// LookupIPs forward-resolves a DNS name. IPv4 addresses are listed first since
+// Meraki client records are keyed by IPv4; duplicates are removed.
+func (h *HostNames) LookupIPs(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	addrs, err := h.resolver().LookupIPAddr(ctx, strings.TrimSuffix(strings.TrimSpace(name), "."))
+	if err != nil {
+		return nil, err
+	}
+	var v4, v6 []string
+	seen := make(map[string]bool)
+	for _, a := range addrs {
+		s := a.IP.String()
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		if a.IP.To4() != nil {
+			v4 = append(v4, s)
+		} else {
+			v6 = append(v6, s)
+		}
+	}
+	if len(v4)+len(v6) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", name)
+	}
+	return append(v4, v6...), nil
+}
+
+// resolver returns a resolver that queries h's DNS servers, or
+// net.DefaultResolver when none are configured.
+func (h *HostNames) resolver() *net.Resolver {
 	resolver := net.DefaultResolver
-	if len(customDNSServers) > 0 {
-		servers := customDNSServers // capture for closure
+	if len(h.dnsServers) > 0 {
+		servers := h.dnsServers // capture for closure
 		resolver = &net.Resolver{
 			PreferGo: true,
 			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
@@ -558,15 +1047,24 @@ func ResolveHostname(ip string) (string, error) {
 			},
 		}
 	}
+	return resolver
+}
 
-	// Perform reverse DNS lookup
-	names, err := resolver.LookupAddr(ctx, ip)
-	if err != nil || len(names) == 0 {
-		return "", err
-	}
+type hostNamesKey struct{}
 
-	// Return the first name, trim trailing dot
-	return strings.TrimSuffix(names[0], "."), nil
+// WithHostNames returns a context carrying h for ResolveIPToMAC,
+// LookupHostIPs and HostNamesFrom.
+func WithHostNames(ctx context.Context, h *HostNames) context.Context {
+	return context.WithValue(ctx, hostNamesKey{}, h)
+}
+
+// HostNamesFrom returns the HostNames carried by ctx, or the process-wide
+// settings when ctx has none.
+func HostNamesFrom(ctx context.Context) *HostNames {
+	if h, ok := ctx.Value(hostNamesKey{}).(*HostNames); ok && h != nil {
+		return h
+	}
+	return CurrentHostNames()
 }
 
 // isUUIDLike returns true if s matches the xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx pattern.
@@ -614,19 +1112,178 @@ type SwitchPort struct {
 	VoiceVlan int         `json:"voiceVlan"` // voice VLAN (ignored here)
 }
 
-// GetSwitchPort retrieves the configuration for a single switch port.
-// portID is the port number/name as a string (e.g. "24", "1").
+// GetSwitchPort returns the configuration of one switch port. portID is the
+// port number/name as a string (e.g. "24", "1"). The first call for a switch
+// fetches all of its ports with one GetSwitchPorts request and builds a port
+// map that answers every later call for that switch, so enriching hundreds of
+// results on a busy switch costs one API call instead of one per result. A
+// switch without the ports endpoint (404) is remembered as having no ports.
 func (m *MerakiClient) GetSwitchPort(ctx context.Context, serial, portID string) (*SwitchPort, error) {
-	path := fmt.Sprintf("/devices/%s/switch/ports/%s", serial, portID)
+	cached, ok := m.portCache.Load(serial)
+	if !ok {
+		ports, err := m.GetSwitchPorts(ctx, serial)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		byID := make(map[string]SwitchPort, len(ports))
+		for _, p := range ports {
+			byID[p.PortID] = SwitchPort{Number: p.PortID, Name: p.Name, Type: p.Type, Vlan: p.Vlan}
+		}
+		cached, _ = m.portCache.LoadOrStore(serial, byID)
+	}
+	sp, ok := cached.(map[string]SwitchPort)[portID]
+	if !ok {
+		return nil, fmt.Errorf("switch %s has no port %s", serial, portID)
+	}
+	return &sp, nil
+}
+
+// ResetPortCache forgets the port maps built by GetSwitchPort, so long-running
+// loops such as --verify-interval see port changes made since the last pass.
+func (m *MerakiClient) ResetPortCache() {
+	m.portCache.Range(func(key, _ any) bool {
+		m.portCache.Delete(key)
+		return true
+	})
+}
+
+// SwitchPortConfig holds the access-control settings of a switch port.
+type SwitchPortConfig struct {
+	PortID                  string   `json:"portId"`
+	Name                    string   `json:"name"`
+	Enabled                 bool     `json:"enabled"`
+	Type                    string   `json:"type"` // "access" or "trunk"
+	Vlan                    int      `json:"vlan"`
+	AccessPolicyType        string   `json:"accessPolicyType"` // "Open", "Custom access policy", "MAC allow list", "Sticky MAC allow list"
+	MacAllowList            []string `json:"macAllowList"`
+	StickyMacAllowList      []string `json:"stickyMacAllowList"`
+	StickyMacAllowListLimit int      `json:"stickyMacAllowListLimit"`
+}
+
+// GetSwitchPorts retrieves the configuration of every port on a switch.
+func (m *MerakiClient) GetSwitchPorts(ctx context.Context, serial string) ([]SwitchPortConfig, error) {
+	path := fmt.Sprintf("/devices/%s/switch/ports", serial)
 	body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, nil))
 	if err != nil {
 		return nil, err
 	}
-	var sp SwitchPort
-	if err := json.Unmarshal(body, &sp); err != nil {
+	var ports []SwitchPortConfig
+	if err := json.Unmarshal(body, &ports); err != nil {
 		return nil, err
 	}
-	return &sp, nil
+	return ports, nil
+}
+
+// ActionBatchMaxActions is the largest number of actions an asynchronous
+// action batch may contain.
+const ActionBatchMaxActions = 100
+
+// ActionBatchAction is a single write operation inside an action batch.
+type ActionBatchAction struct {
+	Resource  string                 `json:"resource"`  // e.g. "/devices/Q2XX-XXXX-XXXX/switch/ports/1"
+	Operation string                 `json:"operation"` // "create", "update" or "destroy"
+	Body      map[string]interface{} `json:"body"`
+}
+
+// CreateActionBatch submits actions as one confirmed, asynchronous action batch
+// and returns the batch ID.
+func (m *MerakiClient) CreateActionBatch(ctx context.Context, orgID string, actions []ActionBatchAction) (string, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"confirmed":   true,
+		"synchronous": false,
+		"actions":     actions,
+	})
+	if err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("/organizations/%s/actionBatches", orgID)
+	body, _, err := m.doRequestBody(ctx, "POST", m.buildURL(path, nil), payload)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// DefaultBlinkDuration is how long BlinkLEDs blinks a device when no duration is given.
+const DefaultBlinkDuration = 20 * time.Second
+
+// BlinkLEDs starts the "blink LEDs" live tool on a device so it can be picked
+// out physically in a rack. duration is rounded to whole seconds and limited
+// to the API's 5–120 s range; zero means DefaultBlinkDuration. It returns the
+// duration the dashboard accepted.
+func (m *MerakiClient) BlinkLEDs(ctx context.Context, serial string, duration time.Duration) (time.Duration, error) {
+	if duration == 0 {
+		duration = DefaultBlinkDuration
+	}
+	seconds := min(max(int(duration.Round(time.Second)/time.Second), 5), 120)
+	payload, err := json.Marshal(map[string]int{"duration": seconds, "period": 160, "duty": 50})
+	if err != nil {
+		return 0, err
+	}
+	path := fmt.Sprintf("/devices/%s/blinkLeds", serial)
+	body, _, err := m.doRequestBody(ctx, "POST", m.buildURL(path, nil), payload)
+	if err != nil {
+		return 0, err
+	}
+	var resp struct {
+		Duration int `json:"duration"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, err
+	}
+	return time.Duration(resp.Duration) * time.Second, nil
+}
+
+// CreatePing starts the "ping" live tool on a device (typically the network's
+// MX), sending count pings (1–5) to target. It returns the pingId used to poll
+// GetPing.
+func (m *MerakiClient) CreatePing(ctx context.Context, serial, target string, count int) (string, error) {
+	payload, err := json.Marshal(map[string]interface{}{"target": target, "count": min(max(count, 1), 5)})
+	if err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("/devices/%s/liveTools/ping", serial)
+	body, _, err := m.doRequestBody(ctx, "POST", m.buildURL(path, nil), payload)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		PingID string `json:"pingId"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+	if resp.PingID == "" {
+		return "", fmt.Errorf("no pingId in response")
+	}
+	return resp.PingID, nil
+}
+
+// GetPing polls a ping started with CreatePing. It returns the job status
+// ("new", "ready", "running", "complete" or "failed") and, once complete, how
+// many replies came back.
+func (m *MerakiClient) GetPing(ctx context.Context, serial, pingID string) (status string, received int, err error) {
+	path := fmt.Sprintf("/devices/%s/liveTools/ping/%s", serial, pingID)
+	body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, nil))
+	if err != nil {
+		return "", 0, err
+	}
+	var resp struct {
+		Status  string `json:"status"`
+		Results struct {
+			Received int `json:"received"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", 0, err
+	}
+	return resp.Status, resp.Results.Received, nil
 }
 
 // SwitchPortFull holds the full port detail needed to resolve link-aggregation membership.
@@ -829,34 +1486,271 @@ func (m *MerakiClient) GetNetworkLinkAggregations(ctx context.Context, networkID
 	return result
 }
 
+// ApplianceVLAN represents a VLAN configured on an MX security appliance.
+type ApplianceVLAN struct {
+	ID                 interface{} `json:"id"` // int on most firmware, string on some
+	Name               string      `json:"name"`
+	Subnet             string      `json:"subnet"`
+	ApplianceIP        string      `json:"applianceIp"`
+	DhcpHandling       string      `json:"dhcpHandling"` // "Run a DHCP server", "Relay DHCP to another server", "Do not respond to DHCP requests"
+	DhcpRelayServerIPs []string    `json:"dhcpRelayServerIps"`
+	DhcpLeaseTime      string      `json:"dhcpLeaseTime"` // e.g. "1 day"; see ParseLeaseTime
+	// FixedIPAssignments are the VLAN's DHCP reservations, keyed by client MAC.
+	FixedIPAssignments map[string]FixedIPAssignment `json:"fixedIpAssignments"`
+}
+
+// FixedIPAssignment is a DHCP reservation on an appliance VLAN or a switch
+// layer 3 interface.
+type FixedIPAssignment struct {
+	MAC  string `json:"mac,omitempty"` // switch interfaces only; appliance VLANs key reservations by MAC
+	IP   string `json:"ip"`
+	Name string `json:"name"`
+}
+
+// GetApplianceVLANs retrieves the VLANs (with DHCP handling) configured on the
+// network's MX appliance. Returns an error for networks without an appliance or
+// with VLANs disabled.
+func (m *MerakiClient) GetApplianceVLANs(ctx context.Context, networkID string) ([]ApplianceVLAN, error) {
+	path := fmt.Sprintf("/networks/%s/appliance/vlans", networkID)
+	body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, nil))
+	if err != nil {
+		return nil, err
+	}
+	var vlans []ApplianceVLAN
+	if err := json.Unmarshal(body, &vlans); err != nil {
+		return nil, err
+	}
+	return vlans, nil
+}
+
+// SwitchRoutingInterface is a layer 3 interface on a switch.
+type SwitchRoutingInterface struct {
+	InterfaceID string `json:"interfaceId"`
+	Name        string `json:"name"`
+	Subnet      string `json:"subnet"`
+	InterfaceIP string `json:"interfaceIp"`
+	VLANID      int    `json:"vlanId"`
+}
+
+// GetSwitchRoutingInterfaces retrieves the layer 3 interfaces of a switch. A
+// switch that does not route has none.
+func (m *MerakiClient) GetSwitchRoutingInterfaces(ctx context.Context, serial string) ([]SwitchRoutingInterface, error) {
+	path := fmt.Sprintf("/devices/%s/switch/routing/interfaces", serial)
+	body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, nil))
+	if err != nil {
+		return nil, err
+	}
+	var ifaces []SwitchRoutingInterface
+	if err := json.Unmarshal(body, &ifaces); err != nil {
+		return nil, err
+	}
+	return ifaces, nil
+}
+
+// SwitchInterfaceDHCP is the DHCP configuration of a switch layer 3 interface.
+type SwitchInterfaceDHCP struct {
+	DhcpMode           string              `json:"dhcpMode"`      // "dhcpDisabled", "dhcpRelay" or "dhcpServer"
+	DhcpLeaseTime      string              `json:"dhcpLeaseTime"` // e.g. "1 day"; see ParseLeaseTime
+	FixedIPAssignments []FixedIPAssignment `json:"fixedIpAssignments"`
+}
+
+// GetSwitchInterfaceDHCP retrieves the DHCP settings of one layer 3 interface
+// of a switch.
+func (m *MerakiClient) GetSwitchInterfaceDHCP(ctx context.Context, serial, interfaceID string) (*SwitchInterfaceDHCP, error) {
+	path := fmt.Sprintf("/devices/%s/switch/routing/interfaces/%s/dhcp", serial, interfaceID)
+	body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, nil))
+	if err != nil {
+		return nil, err
+	}
+	var dhcp SwitchInterfaceDHCP
+	if err := json.Unmarshal(body, &dhcp); err != nil {
+		return nil, err
+	}
+	return &dhcp, nil
+}
+
+// ParseLeaseTime parses a DHCP lease time as the Dashboard API reports it,
+// e.g. "30 minutes", "1 hour", "12 hours", "1 day" or "1 week".
+func ParseLeaseTime(s string) (time.Duration, bool) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) != 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	units := map[string]time.Duration{"minute": time.Minute, "hour": time.Hour, "day": 24 * time.Hour, "week": 7 * 24 * time.Hour}
+	unit, ok := units[strings.TrimSuffix(fields[1], "s")]
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// WarmSpare describes an MX warm-spare (high availability) pair.
+type WarmSpare struct {
+	Enabled       bool   `json:"enabled"`
+	PrimarySerial string `json:"primarySerial"`
+	SpareSerial   string `json:"spareSerial"`
+	UplinkMode    string `json:"uplinkMode"` // "virtual" when the pair shares VRRP virtual IPs
+}
+
+// GetApplianceWarmSpare retrieves the MX warm-spare configuration for a network.
+func (m *MerakiClient) GetApplianceWarmSpare(ctx context.Context, networkID string) (*WarmSpare, error) {
+	path := fmt.Sprintf("/networks/%s/appliance/warmSpare", networkID)
+	body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, nil))
+	if err != nil {
+		return nil, err
+	}
+	var ws WarmSpare
+	if err := json.Unmarshal(body, &ws); err != nil {
+		return nil, err
+	}
+	return &ws, nil
+}
+
 // LLDPCDPData holds the LLDP/CDP neighbor data for a device.
 type LLDPCDPData struct {
-	// Ports maps port ID string → map of protocol ("lldp"/"cdp") → neighbor info
-	Ports     map[string]map[string]interface{} `json:"ports"`
-	SourceMac string                            `json:"sourceMac"`
+	Ports     map[string]LLDPCDPPort `json:"ports"` // port ID → neighbors
+	SourceMac string                 `json:"sourceMac"`
 }
 
-// GetDeviceUplinkPorts returns the set of port IDs on the given switch that are
-// marked as uplinks by the Meraki platform, using the switch port statuses API
-// (/devices/{serial}/switch/ports/statuses). This mirrors exactly what the
-// Meraki console shows as "uplink" — including ports connected to ISP routers,
-// core switches, and other upstream devices regardless of whether they speak
-// LLDP or CDP.
-// Returns an empty set (never nil) on error.
-func (m *MerakiClient) GetDeviceUplinkPorts(ctx context.Context, serial string) map[string]struct{} {
-	path := fmt.Sprintf("/devices/%s/switch/ports/statuses", serial)
+// LLDPCDPPort is the LLDP and CDP neighbor seen on one port; either may be nil.
+type LLDPCDPPort struct {
+	LLDP *PortNeighbor `json:"lldp"`
+	CDP  *PortNeighbor `json:"cdp"`
+}
+
+// GetDeviceLLDPCDP retrieves the LLDP and CDP neighbors seen on each port of a
+// device (/devices/{serial}/lldpCdp).
+func (m *MerakiClient) GetDeviceLLDPCDP(ctx context.Context, serial string) (*LLDPCDPData, error) {
+	path := fmt.Sprintf("/devices/%s/lldpCdp", serial)
 	body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, nil))
 	if err != nil {
-		return map[string]struct{}{}
+		return nil, err
 	}
-	var ports []struct {
-		PortID   string `json:"portId"`
-		IsUplink bool   `json:"isUplink"`
+	var data LLDPCDPData
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
 	}
+	return &data, nil
+}
+
+// GetSwitchNeighborPorts returns the ports of a switch whose LLDP or CDP
+// neighbor is itself a switch, mapped to the neighbor's name. A MAC learned on
+// such a port was learned through the neighbor, so it is not where the client
+// is plugged in. Phones and other hosts that bridge a PC are not switches.
+// Returns an empty map (never nil) on error.
+func (m *MerakiClient) GetSwitchNeighborPorts(ctx context.Context, serial string) map[string]string {
+	data, err := m.GetDeviceLLDPCDP(ctx, serial)
+	if err != nil {
+		return make(map[string]string)
+	}
+	return data.SwitchNeighborPorts()
+}
+
+// SwitchNeighborPorts returns the ports whose LLDP or CDP neighbor is a
+// switch, mapped to the neighbor's name (see GetSwitchNeighborPorts).
+func (d *LLDPCDPData) SwitchNeighborPorts() map[string]string {
+	out := make(map[string]string)
+	for portID, p := range d.Ports {
+		switch {
+		case p.LLDP != nil && NeighborIsSwitch(p.LLDP.SystemCapabilities):
+			out[portID] = p.LLDP.Name()
+		case p.CDP != nil && NeighborIsSwitch(p.CDP.Capabilities):
+			out[portID] = p.CDP.Name()
+		}
+	}
+	return out
+}
+
+// NeighborIsSwitch reports whether LLDP system capabilities or CDP
+// capabilities ("Router, Switch, IGMP", "Bridge, Telephone", ...) describe a
+// switch: one advertising "Switch" or an IEEE "Bridge" that is not also a
+// phone or access point. CDP's "Trans-Bridge" (access points) does not count.
+func NeighborIsSwitch(capabilities string) bool {
+	isSwitch := false
+	for _, c := range strings.FieldsFunc(strings.ToLower(capabilities), func(r rune) bool { return r == ',' || r == ';' }) {
+		switch c = strings.TrimSpace(c); {
+		case strings.Contains(c, "phone"), strings.Contains(c, "access point"), strings.Contains(c, "wlan"):
+			return false
+		case c == "switch", c == "bridge":
+			isSwitch = true
+		}
+	}
+	return isSwitch
+}
+
+// SwitchPortStatus is the live state of one switch port as reported by
+// /devices/{serial}/switch/ports/statuses.
+type SwitchPortStatus struct {
+	PortID   string        `json:"portId"`
+	Enabled  bool          `json:"enabled"`
+	Status   string        `json:"status"` // "Connected", "Disconnected" or "Disabled"
+	IsUplink bool          `json:"isUplink"`
+	Speed    string        `json:"speed"`  // e.g. "1 Gbps"; empty while the link is down
+	Duplex   string        `json:"duplex"` // "full" or "half"; empty while the link is down
+	CDP      *PortNeighbor `json:"cdp"`
+	LLDP     *PortNeighbor `json:"lldp"`
+}
+
+// PortNeighbor is the CDP or LLDP neighbor heard on a switch port.
+type PortNeighbor struct {
+	SystemName         string `json:"systemName"`
+	SystemDescription  string `json:"systemDescription"`  // LLDP only, e.g. "Cisco IP Phone CP-8845"
+	SystemCapabilities string `json:"systemCapabilities"` // LLDP only
+	DeviceID           string `json:"deviceId"`           // CDP only
+	Platform           string `json:"platform"`           // CDP only, e.g. "Cisco IP Phone 8845"
+	Capabilities       string `json:"capabilities"`       // CDP only
+	PortID             string `json:"portId"`
+}
+
+// Name returns the neighbor's system name, or its CDP device ID.
+func (n *PortNeighbor) Name() string {
+	if n.SystemName != "" {
+		return n.SystemName
+	}
+	return n.DeviceID
+}
+
+// PlatformName returns what the neighbor says it is: the CDP platform, or the first
+// line of the LLDP system description.
+func (n *PortNeighbor) PlatformName() string {
+	if n.Platform != "" {
+		return n.Platform
+	}
+	first, _, _ := strings.Cut(n.SystemDescription, "\n")
+	return strings.TrimSpace(first)
+}
+
+// GetSwitchPortStatuses retrieves the link state, speed, duplex and CDP/LLDP
+// neighbor of every port on a switch.
+func (m *MerakiClient) GetSwitchPortStatuses(ctx context.Context, serial string) ([]SwitchPortStatus, error) {
+	path := fmt.Sprintf("/devices/%s/switch/ports/statuses", serial)
+	body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, nil))
+	if err != nil {
+		return nil, err
+	}
+	var ports []SwitchPortStatus
 	if err := json.Unmarshal(body, &ports); err != nil {
-		return map[string]struct{}{}
+		return nil, err
 	}
+	return ports, nil
+}
+
+// GetDeviceUplinkPorts returns the set of port IDs on the given switch that are
+// marked as uplinks by the Meraki platform, using GetSwitchPortStatuses. This
+// mirrors exactly what the Meraki console shows as "uplink" — including ports
+// connected to ISP routers, core switches, and other upstream devices
+// regardless of whether they speak LLDP or CDP.
+// Returns an empty set (never nil) on error.
+func (m *MerakiClient) GetDeviceUplinkPorts(ctx context.Context, serial string) map[string]struct{} {
 	uplinks := make(map[string]struct{})
+	ports, err := m.GetSwitchPortStatuses(ctx, serial)
+	if err != nil {
+		return uplinks
+	}
 	for _, p := range ports {
 		if p.IsUplink {
 			uplinks[p.PortID] = struct{}{}
@@ -865,11 +1759,69 @@ func (m *MerakiClient) GetDeviceUplinkPorts(ctx context.Context, serial string)
 	return uplinks
 }
 
+// SignalQuality is one interval of a wireless client's signal history.
+type SignalQuality struct {
+	StartTs string `json:"startTs"`
+	EndTs   string `json:"endTs"`
+	RSSI    *int   `json:"rssi"` // dBm; nil when the client was not heard in the interval
+	SNR     *int   `json:"snr"`
+}
+
+// WirelessBands are the radio bands signal history can be filtered by.
+var WirelessBands = []string{"2.4", "5", "6"}
+
+// GetWirelessSignalQuality retrieves the last hour of a wireless client's
+// signal quality in 5-minute intervals, on one band ("2.4", "5" or "6") or on
+// all bands when band is empty (/networks/{networkId}/wireless/signalQualityHistory).
+func (m *MerakiClient) GetWirelessSignalQuality(ctx context.Context, networkID, clientID, band string) ([]SignalQuality, error) {
+	path := fmt.Sprintf("/networks/%s/wireless/signalQualityHistory", networkID)
+	params := url.Values{
+		"clientId":   []string{clientID},
+		"timespan":   []string{"3600"},
+		"resolution": []string{"300"},
+	}
+	if band != "" {
+		params.Set("band", band)
+	}
+	body, _, err := m.doRequest(ctx, "GET", m.buildURL(path, params))
+	if err != nil {
+		return nil, err
+	}
+	var history []SignalQuality
+	if err := json.Unmarshal(body, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// GetWirelessClientRadio returns the band a wireless client was most recently
+// heard on ("2.4", "5" or "6") and its RSSI in dBm at the time, by asking for
+// each band's signal history. ok is false when the client was not heard in
+// the last hour or the network has no wireless history.
+func (m *MerakiClient) GetWirelessClientRadio(ctx context.Context, networkID, clientID string) (band string, rssi int, ok bool) {
+	var latest string
+	for _, b := range WirelessBands {
+		history, err := m.GetWirelessSignalQuality(ctx, networkID, clientID, b)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) || ctx.Err() != nil {
+				return "", 0, false
+			}
+			continue
+		}
+		for _, q := range history {
+			if q.RSSI != nil && q.EndTs > latest {
+				latest, band, rssi, ok = q.EndTs, b, *q.RSSI, true
+			}
+		}
+	}
+	return band, rssi, ok
+}
+
 // ResolveIPToMAC resolves an IP address to MAC address by querying Meraki clients API.
 // Searches across multiple networks and returns the MAC, network ID, and hostname.
 func (c *MerakiClient) ResolveIPToMAC(ctx context.Context, orgID string, networks []Network, ip string) (mac string, networkID string, hostname string, err error) {
 	// First, attempt hostname resolution
-	hostname, _ = ResolveHostname(ip) // Ignore error, hostname is optional
+	hostname, _ = HostNamesFrom(ctx).Resolve(ip) // Ignore error, hostname is optional
 
 	// Search through each network for the IP
 	for _, network := range networks {
@@ -888,7 +1840,7 @@ func (c *MerakiClient) ResolveIPToMAC(ctx context.Context, orgID string, network
 		}
 	}
 
-	return "", "", hostname, errors.New("IP address not found in any network")
+	return "", "", hostname, ErrIPNotFound
 }
 
 // parseLinkNext extracts the next page URL from a Link header.
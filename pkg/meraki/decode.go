@@ -0,0 +1,97 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package meraki
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// maxDriftSample caps how much of an offending item is quoted in a warning.
+const maxDriftSample = 200
+
+// SetWarnFunc installs a callback for non-fatal problems such as API schema
+// drift. Without one, such problems are silently ignored.
+func (m *MerakiClient) SetWarnFunc(f func(format string, args ...interface{})) {
+	m.warnf = f
+}
+
+// warnOnce reports a problem through the warn callback, at most once per key
+// for the lifetime of the client so a drifted field does not flood the log.
+func (m *MerakiClient) warnOnce(key, format string, args ...interface{}) {
+	if m.warnf == nil {
+		return
+	}
+	if _, seen := m.warned.LoadOrStore(key, struct{}{}); seen {
+		return
+	}
+	m.warnf(format, args...)
+}
+
+// decodeItems unmarshals each raw page item into T. Items that fail to decode
+// are dropped as before, but the count and a sample are now reported, as is any
+// important field that is absent from every item (usually a renamed field).
+// endpoint labels the warnings, e.g. "GET /networks/{id}/clients".
+func decodeItems[T any](m *MerakiClient, endpoint string, raws []json.RawMessage, important ...string) []T {
+	items := make([]T, 0, len(raws))
+	missing := make(map[string]int, len(important))
+	failed := 0
+	var firstErr error
+	var sample json.RawMessage
+	for _, r := range raws {
+		var item T
+		if err := json.Unmarshal(r, &item); err != nil {
+			if failed == 0 {
+				firstErr, sample = err, r
+			}
+			failed++
+			continue
+		}
+		items = append(items, item)
+		if len(important) == 0 {
+			continue
+		}
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(r, &fields) != nil {
+			continue
+		}
+		for _, k := range important {
+			if _, ok := fields[k]; !ok {
+				missing[k]++
+			}
+		}
+	}
+
+	if failed > 0 {
+		m.warnOnce(endpoint+"|decode", "%s: dropped %d of %d items that failed to decode (%v); sample: %s",
+			endpoint, failed, len(raws), firstErr, truncateSample(sample))
+	}
+	for _, k := range important {
+		if len(items) > 0 && missing[k] == len(items) {
+			m.warnOnce(endpoint+"|"+k, "%s: field %q missing from all %d items; the API schema may have changed",
+				endpoint, k, len(items))
+		}
+	}
+	return items
+}
+
+// truncateSample returns raw as a string of at most maxDriftSample bytes.
+func truncateSample(raw json.RawMessage) string {
+	if len(raw) <= maxDriftSample {
+		return string(raw)
+	}
+	return fmt.Sprintf("%s… (%d bytes)", raw[:maxDriftSample], len(raw))
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package meraki
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinels for errors.Is. Each typed error below matches its own sentinel, so
+// callers can write errors.Is(err, meraki.ErrNotFound) without errors.As.
+var (
+	ErrRateLimited = errors.New("meraki API rate limit")
+	ErrAuth        = errors.New("meraki API authentication failed")
+	ErrNotFound    = errors.New("meraki API resource not found")
+	ErrServer      = errors.New("meraki API server error")
+)
+
+// ErrIPNotFound is returned by ResolveIPToMAC when no client of the searched
+// networks has the IP.
+var ErrIPNotFound = errors.New("IP address not found in any network")
+
+// APIError is an unsuccessful Dashboard API response. Responses that fit one
+// of the classes below are returned as that type, which unwraps to the
+// *APIError; other statuses (400, 409, ...) are returned as *APIError itself.
+type APIError struct {
+	StatusCode int
+	Body       string // trimmed response body, usually {"errors":[...]}
+	Attempts   int    // requests made; more than 1 when retries ran out
+}
+
+func (e *APIError) Error() string {
+	if e.Attempts > 1 {
+		return fmt.Sprintf("meraki API error %d after %d attempts: %s", e.StatusCode, e.Attempts, e.Body)
+	}
+	return fmt.Sprintf("meraki API error %d: %s", e.StatusCode, e.Body)
+}
+
+// RateLimitError is a 429 that was still returned after every retry.
+type RateLimitError struct {
+	*APIError
+	RetryAfter time.Duration // the last delay the API asked for
+}
+
+func (e *RateLimitError) Unwrap() error        { return e.APIError }
+func (e *RateLimitError) Is(target error) bool { return target == ErrRateLimited }
+
+// AuthError is a 401 or 403: the API key is invalid, or lacks access to the
+// organization or the write permission the call needs.
+type AuthError struct{ *APIError }
+
+func (e *AuthError) Unwrap() error        { return e.APIError }
+func (e *AuthError) Is(target error) bool { return target == ErrAuth }
+
+// NotFoundError is a 404: the organization, network, device or client does
+// not exist or is not visible to the API key.
+type NotFoundError struct{ *APIError }
+
+func (e *NotFoundError) Unwrap() error        { return e.APIError }
+func (e *NotFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// ServerError is a 5xx, including a 503 that persisted through every retry.
+type ServerError struct{ *APIError }
+
+func (e *ServerError) Unwrap() error        { return e.APIError }
+func (e *ServerError) Is(target error) bool { return target == ErrServer }
+
+// newAPIError classifies an unsuccessful response.
+func newAPIError(status int, body string, attempts int, retryAfter time.Duration) error {
+	base := &APIError{StatusCode: status, Body: body, Attempts: attempts}
+	switch {
+	case status == http.StatusTooManyRequests:
+		return &RateLimitError{APIError: base, RetryAfter: retryAfter}
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return &AuthError{base}
+	case status == http.StatusNotFound:
+		return &NotFoundError{base}
+	case status >= 500:
+		return &ServerError{base}
+	}
+	return base
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package meraki
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockStep is one scripted response from mockAPI.
+type mockStep struct {
+	Status     int    // HTTP status; 0 means 200
+	RetryAfter string // Retry-After header value, if any
+	Body       string
+	Drop       bool // close the connection without answering
+}
+
+// mockAPI is a Dashboard API stand-in that answers each request to a path with
+// the next scripted step, repeating the last step once the script runs out.
+type mockAPI struct {
+	*httptest.Server
+	mu      sync.Mutex
+	scripts map[string][]mockStep
+	hits    map[string]int
+	headers []http.Header // of every request, in order
+}
+
+// newMockAPI starts a mock server; it is closed when the test ends.
+func newMockAPI(t *testing.T) *mockAPI {
+	t.Helper()
+	m := &mockAPI{scripts: make(map[string][]mockStep), hits: make(map[string]int)}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serve))
+	t.Cleanup(m.Close)
+	return m
+}
+
+// script programs the responses for path.
+func (m *mockAPI) script(path string, steps ...mockStep) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scripts[path] = steps
+}
+
+// hitCount returns how many requests path has received.
+func (m *mockAPI) hitCount(path string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.hits[path]
+}
+
+// requestHeaders returns the headers of every request received so far.
+func (m *mockAPI) requestHeaders() []http.Header {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]http.Header(nil), m.headers...)
+}
+
+func (m *mockAPI) serve(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	steps := m.scripts[r.URL.Path]
+	n := m.hits[r.URL.Path]
+	m.hits[r.URL.Path]++
+	m.headers = append(m.headers, r.Header.Clone())
+	m.mu.Unlock()
+
+	if len(steps) == 0 {
+		http.Error(w, `{"errors":["Not found"]}`, http.StatusNotFound)
+		return
+	}
+	if n >= len(steps) {
+		n = len(steps) - 1
+	}
+	step := steps[n]
+	if step.Drop {
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			_ = conn.Close()
+		}
+		return
+	}
+	if step.RetryAfter != "" {
+		w.Header().Set("Retry-After", step.RetryAfter)
+	}
+	if step.Status != 0 {
+		w.WriteHeader(step.Status)
+	}
+	_, _ = w.Write([]byte(step.Body))
+}
+
+// recordSleeps replaces the client's retry sleep with one that records the
+// requested delays and returns immediately. Jitter is turned off so the
+// delays are predictable.
+func recordSleeps(c *MerakiClient) *[]time.Duration {
+	c.jitter = func(d time.Duration) time.Duration { return d }
+	var delays []time.Duration
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return &delays
+}
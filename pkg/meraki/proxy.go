@@ -0,0 +1,119 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package meraki
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// apiTransport is the transport of clients created by NewClient; nil means
+// http.DefaultTransport, which takes its proxy from HTTPS_PROXY, HTTP_PROXY
+// and NO_PROXY. It is rebuilt from the proxy and TLS settings whenever
+// SetProxy or SetTLS changes one of them.
+var (
+	transportMu  sync.RWMutex
+	proxySet     bool     // SetProxy was given a non-empty setting
+	proxyURL     *url.URL // nil with proxySet means direct
+	apiTLS       *tls.Config
+	apiTransport http.RoundTripper
+)
+
+// rebuildTransport recomputes apiTransport; the caller holds transportMu.
+func rebuildTransport() {
+	if !proxySet && apiTLS == nil {
+		apiTransport = nil
+		return
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if proxySet {
+		t.Proxy = nil
+		if proxyURL != nil {
+			t.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+	if apiTLS != nil {
+		t.TLSClientConfig = apiTLS.Clone()
+	}
+	apiTransport = t
+}
+
+// ParseProxy validates a proxy setting as accepted by SetProxy and returns
+// its URL; "" and "direct" return nil.
+func ParseProxy(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.EqualFold(raw, "direct") {
+		return nil, nil
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https or socks5)", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", raw)
+	}
+	return u, nil
+}
+
+// SetProxy chooses how clients created afterwards reach the Dashboard API:
+//
+//   - "" — the environment's HTTPS_PROXY / HTTP_PROXY / NO_PROXY (Go's default)
+//   - "direct" — no proxy, whatever the environment says
+//   - http://[user:pass@]host:port, https://… or socks5://host:port — that
+//     proxy for every request; a bare host:port means http://
+//
+// DNS lookups for hostnames and PTR records never go through the proxy.
+func SetProxy(raw string) error {
+	u, err := ParseProxy(raw)
+	if err != nil {
+		return err
+	}
+	transportMu.Lock()
+	proxySet, proxyURL = strings.TrimSpace(raw) != "", u
+	rebuildTransport()
+	transportMu.Unlock()
+	return nil
+}
+
+// Transport returns the transport chosen by SetProxy and SetTLS, for other
+// outbound HTTP such as the online vendor lookup; nil means
+// http.DefaultTransport.
+func Transport() http.RoundTripper {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	return apiTransport
+}
+
+// RedactProxy returns a proxy setting with any password replaced, for logging.
+func RedactProxy(raw string) string {
+	u, err := ParseProxy(raw)
+	if err != nil || u == nil {
+		return strings.TrimSpace(raw)
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,106 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package meraki
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// DefaultRateLimit is the Dashboard API's documented budget of requests per
+// second per organization.
+const DefaultRateLimit = 10
+
+// rateLimiter is a token bucket shared by every goroutine using it. It holds
+// up to burst tokens, refilled at rate per second; a request that finds the
+// bucket empty reserves a future token and waits for it, so concurrent callers
+// are spread out instead of all failing with 429 at once.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time // when tokens was last brought up to date
+}
+
+func newRateLimiter(perSecond int) *rateLimiter {
+	return &rateLimiter{rate: float64(perSecond), burst: float64(perSecond), tokens: float64(perSecond)}
+}
+
+// reserve takes a token at now and returns how long the caller must wait
+// before using it.
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// wait blocks until the caller may send a request, or ctx is done. A nil
+// limiter never waits.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	if d := l.reserve(time.Now()); d > 0 {
+		return SleepContext(ctx, d)
+	}
+	return ctx.Err()
+}
+
+// orgLimiters holds the buckets shared through ShareOrgRateLimit, keyed by
+// organization ID (or "key:" and a hash of the API key), so every client
+// working on one organization draws from the same budget.
+var orgLimiters sync.Map
+
+// SetRateLimit caps the client's sustained request rate at perSecond, with a
+// burst of the same size. 0 or less removes the cap and relies on 429 retries.
+func (m *MerakiClient) SetRateLimit(perSecond int) {
+	if perSecond <= 0 {
+		m.limiter = nil
+		return
+	}
+	m.limiter = newRateLimiter(perSecond)
+}
+
+// ShareOrgRateLimit makes the client use the process-wide bucket for orgID,
+// so concurrent clients (one per web request, say) stay within the
+// organization's budget together. The bucket is created at the client's
+// current rate the first time an organization is seen. An empty orgID shares
+// the bucket of the client's API key instead, for calls made before the
+// organization is known.
+func (m *MerakiClient) ShareOrgRateLimit(orgID string) {
+	if m.limiter == nil {
+		return
+	}
+	key := orgID
+	if key == "" {
+		sum := sha256.Sum256([]byte(m.apiKey))
+		key = "key:" + hex.EncodeToString(sum[:])
+	}
+	l, _ := orgLimiters.LoadOrStore(key, newRateLimiter(int(m.limiter.rate)))
+	m.limiter = l.(*rateLimiter)
+}
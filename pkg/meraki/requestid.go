@@ -0,0 +1,119 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package meraki
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultUserAgent identifies this tool to the Dashboard API, whose analytics
+// group calls by User-Agent.
+const DefaultUserAgent = "Find-Meraki-Ports-With-MAC"
+
+var (
+	userAgentMu sync.RWMutex
+	userAgent   = DefaultUserAgent
+)
+
+// SetUserAgent sets the User-Agent of every API request; "" restores
+// DefaultUserAgent.
+func SetUserAgent(ua string) {
+	if ua = strings.TrimSpace(ua); ua == "" {
+		ua = DefaultUserAgent
+	}
+	userAgentMu.Lock()
+	userAgent = ua
+	userAgentMu.Unlock()
+}
+
+func currentUserAgent() string {
+	userAgentMu.RLock()
+	defer userAgentMu.RUnlock()
+	return userAgent
+}
+
+// ValidUserAgentPart reports whether s may go into a User-Agent header:
+// printable ASCII, at most 100 characters.
+func ValidUserAgentPart(s string) bool {
+	if len(s) > 100 {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidRequestID reports whether id is usable as a correlation ID: 1–64
+// letters, digits, '.', '_', ':' or '-'.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("._:-", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewRequestID returns a random 12-character correlation ID.
+func NewRequestID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx whose API calls are sent with
+// X-Request-Id "<id>-<n>", n counting the calls of each client, so a run or a
+// web request can be traced through the Dashboard's API analytics and the log.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the ID set by WithRequestID, or "".
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// nextRequestID is the X-Request-Id of m's next call made with ctx. Without
+// an ID in ctx the client's own random one is used.
+func (m *MerakiClient) nextRequestID(ctx context.Context) string {
+	base := RequestIDFrom(ctx)
+	if base == "" {
+		base = m.requestID
+	}
+	return fmt.Sprintf("%s-%d", base, m.requestSeq.Add(1))
+}
+
+// SetDebugFunc installs a callback that logs every API call with its status,
+// duration and X-Request-Id. Without one, calls are not logged.
+func (m *MerakiClient) SetDebugFunc(f func(format string, args ...interface{})) {
+	m.debugf = f
+}
@@ -0,0 +1,118 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package meraki
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy decides which failed requests are retried and for how long.
+// Waits follow the server's Retry-After when given, and otherwise back off
+// exponentially from one second with jitter, so clients that failed together
+// do not all retry in the same instant.
+type RetryPolicy struct {
+	MaxAttempts int           // requests per call, including the first
+	MaxElapsed  time.Duration // give up rather than wait past this much time since the first attempt; 0 = no limit
+	Statuses    []int         // response codes worth retrying
+}
+
+// DefaultRetryPolicy retries rate limiting (429) and the gateway errors
+// Meraki's front end returns during incidents (502, 503, 504), six attempts
+// in all, without an elapsed-time limit.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 6,
+		Statuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// SetRetryPolicy replaces the client's retry policy. MaxAttempts below 1
+// keeps the current value; a nil Statuses keeps the current list.
+func (m *MerakiClient) SetRetryPolicy(p RetryPolicy) {
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = m.retry.MaxAttempts
+	}
+	if p.Statuses == nil {
+		p.Statuses = m.retry.Statuses
+	}
+	m.retry = p
+}
+
+// retryableStatus reports whether the policy retries a response with code to
+// a method request. A gateway error does not mean a write failed, so requests
+// that are not idempotent are only re-sent after 429 and 503, which Meraki
+// returns without acting on the request.
+func (p RetryPolicy) retryableStatus(method string, code int) bool {
+	if !idempotent(method) && code != http.StatusTooManyRequests && code != http.StatusServiceUnavailable {
+		return false
+	}
+	for _, s := range p.Statuses {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
+
+// withinBudget reports whether waiting d more still ends within MaxElapsed of start.
+func (p RetryPolicy) withinBudget(start time.Time, d time.Duration) bool {
+	return p.MaxElapsed <= 0 || time.Since(start)+d <= p.MaxElapsed
+}
+
+// isTransientNetError reports whether a transport error is worth retrying:
+// timeouts, refused or reset connections, and connections closed mid-response.
+// A cancelled or expired ctx is never transient.
+func isTransientNetError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// idempotent reports whether a request with method can be sent again after a
+// transport error without risking a repeated side effect.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// equalJitter spreads a computed backoff over [d/2, d).
+func equalJitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
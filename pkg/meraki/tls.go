@@ -0,0 +1,97 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package meraki
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSOptions adjusts how clients created by NewClient verify the Dashboard
+// API, for SSL-inspecting proxies and lab environments. The zero value keeps
+// Go's defaults: system roots and TLS 1.2 or later.
+type TLSOptions struct {
+	CAFile             string // PEM bundle trusted in addition to the system roots
+	MinVersion         string // "1.0"–"1.3"; "" keeps the default
+	InsecureSkipVerify bool   // accept any certificate; never for production use
+}
+
+// ParseTLSVersion converts "1.2" or "TLS1.2" to a crypto/tls version; ""
+// returns 0 (the default).
+func ParseTLSVersion(s string) (uint16, error) {
+	v := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "TLS")
+	switch strings.TrimSpace(v) {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unknown TLS version %q (use 1.0, 1.1, 1.2 or 1.3)", s)
+}
+
+// LoadCAFile reads a PEM bundle and returns the system roots plus its
+// certificates.
+func LoadCAFile(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// SetTLS applies o to clients created afterwards and to Transport. It
+// combines with SetProxy; the zero TLSOptions restores the defaults. On error
+// the previous settings are kept.
+func SetTLS(o TLSOptions) error {
+	minVersion, err := ParseTLSVersion(o.MinVersion)
+	if err != nil {
+		return err
+	}
+	if o.InsecureSkipVerify && o.CAFile != "" {
+		return errors.New("a CA file and insecure skip-verify cannot be combined")
+	}
+	var cfg *tls.Config
+	if o.CAFile != "" || minVersion != 0 || o.InsecureSkipVerify {
+		cfg = &tls.Config{MinVersion: minVersion, InsecureSkipVerify: o.InsecureSkipVerify}
+		if o.CAFile != "" {
+			if cfg.RootCAs, err = LoadCAFile(o.CAFile); err != nil {
+				return fmt.Errorf("CA file: %w", err)
+			}
+		}
+	}
+	transportMu.Lock()
+	apiTLS = cfg
+	rebuildTransport()
+	transportMu.Unlock()
+	return nil
+}
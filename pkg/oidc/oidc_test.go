@@ -0,0 +1,213 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a minimal OpenID provider signing with an RSA and an EC key.
+type fakeProvider struct {
+	*httptest.Server
+	rsaKey    *rsa.PrivateKey
+	ecKey     *ecdsa.PrivateKey
+	jwksHits  int
+	lastToken url.Values
+	idToken   string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	f := &fakeProvider{rsaKey: rk, ecKey: ek}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.URL,
+			"authorization_endpoint": f.URL + "/authorize",
+			"token_endpoint":         f.URL + "/token",
+			"jwks_uri":               f.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		f.jwksHits++
+		b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "r1", "use": "sig", "n": b64(rk.N.Bytes()), "e": b64(big.NewInt(int64(rk.E)).Bytes())},
+			{"kty": "EC", "kid": "e1", "crv": "P-256", "x": b64(ek.X.FillBytes(make([]byte, 32))), "y": b64(ek.Y.FillBytes(make([]byte, 32)))},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		f.lastToken = r.PostForm
+		if r.PostForm.Get("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"code expired"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": f.idToken, "token_type": "Bearer"})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// sign builds a JWS over claims with the given algorithm and key ID.
+func (f *fakeProvider) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	enc := func(v interface{}) string {
+		b, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := enc(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch alg {
+	case "RS256":
+		sig, _ = rsa.SignPKCS1v15(rand.Reader, f.rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		r, s, _ := ecdsa.Sign(rand.Reader, f.ecKey, digest[:])
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestProviderFlow(t *testing.T) {
+	f := newFakeProvider(t)
+	ctx := context.Background()
+	cfg := Config{Issuer: f.URL, ClientID: "app", ClientSecret: "s3cret", RedirectURL: "https://find-mac.corp/auth/callback", Scopes: []string{"profile", "email"}}
+	p, err := Discover(ctx, cfg, f.Client())
+	if err != nil {
+		t.Fatalf("Discover() error: %v", err)
+	}
+
+	u, _ := url.Parse(p.AuthCodeURL("st", "nc", "verifier"))
+	q := u.Query()
+	sum := sha256.Sum256([]byte("verifier"))
+	if u.Path != "/authorize" || q.Get("state") != "st" || q.Get("nonce") != "nc" || q.Get("scope") != "openid profile email" ||
+		q.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(sum[:]) || q.Get("code_challenge_method") != "S256" {
+		t.Errorf("AuthCodeURL() = %s", u)
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": f.URL, "aud": "app", "sub": "u1", "nonce": "nc", "name": "Ada",
+		"exp": now.Add(time.Hour).Unix(), "iat": now.Unix(), "groups": []string{"net-admins", "staff"},
+	}
+	f.idToken = f.sign(t, "RS256", "r1", claims)
+	raw, err := p.Exchange(ctx, "good-code", "verifier")
+	if err != nil || raw != f.idToken {
+		t.Fatalf("Exchange() = %q, %v", raw, err)
+	}
+	if f.lastToken.Get("code_verifier") != "verifier" || f.lastToken.Get("client_secret") != "s3cret" {
+		t.Errorf("token request = %v", f.lastToken)
+	}
+	if _, err := p.Exchange(ctx, "bad-code", "verifier"); err == nil || !strings.Contains(err.Error(), "code expired") {
+		t.Errorf("Exchange(bad code) error = %v", err)
+	}
+
+	got, err := p.Verify(ctx, raw, "nc", now)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if got.String("sub") != "u1" || strings.Join(got.Strings("groups"), ",") != "net-admins,staff" {
+		t.Errorf("claims = %v", got)
+	}
+	if _, err := p.Verify(ctx, f.sign(t, "ES256", "e1", claims), "nc", now); err != nil {
+		t.Errorf("Verify(ES256) error: %v", err)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	f := newFakeProvider(t)
+	ctx := context.Background()
+	p, err := Discover(ctx, Config{Issuer: f.URL, ClientID: "app"}, f.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{"iss": f.URL, "aud": "app", "sub": "u1", "nonce": "nc", "exp": now.Add(time.Hour).Unix()}
+	}
+	with := func(k string, v interface{}) map[string]interface{} {
+		c := valid()
+		c[k] = v
+		return c
+	}
+	good := f.sign(t, "RS256", "r1", valid())
+	parts := strings.Split(good, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"`+f.URL+`","aud":"app","sub":"admin","nonce":"nc","exp":9999999999}`)) + "." + parts[2]
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"r1"}`)) + "." + parts[1] + "."
+
+	tests := []struct {
+		name, token, want string
+	}{
+		{"tampered claims", tampered, "signature is invalid"},
+		{"alg none", none, "not supported"},
+		{"RSA key with an EC algorithm", f.sign(t, "ES256", "r1", valid()), "does not match"},
+		{"wrong issuer", f.sign(t, "RS256", "r1", with("iss", "https://evil")), "issuer"},
+		{"wrong audience", f.sign(t, "RS256", "r1", with("aud", []string{"other", "app2"})), "not for this client"},
+		{"other authorized party", f.sign(t, "RS256", "r1", with("aud", []string{"app", "other"})), "authorized party"},
+		{"expired", f.sign(t, "RS256", "r1", with("exp", now.Add(-time.Hour).Unix())), "expired"},
+		{"future iat", f.sign(t, "RS256", "r1", with("iat", now.Add(time.Hour).Unix())), "future"},
+		{"nonce", f.sign(t, "RS256", "r1", with("nonce", "other")), "nonce"},
+		{"garbage", "abc", "not a JWS"},
+	}
+	for _, tt := range tests {
+		if _, err := p.Verify(ctx, tt.token, "nc", now); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Verify() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	// Unknown key IDs refetch the key set, but at most once a minute.
+	hits := f.jwksHits
+	unknown := f.sign(t, "RS256", "rotated", valid())
+	for i := 0; i < 5; i++ {
+		_, _ = p.Verify(ctx, unknown, "nc", now)
+	}
+	if f.jwksHits-hits != 0 {
+		t.Errorf("unknown kid refetched keys %d times within a minute", f.jwksHits-hits)
+	}
+	if _, _ = p.Verify(ctx, unknown, "nc", now.Add(2*time.Minute)); f.jwksHits-hits != 1 {
+		t.Errorf("unknown kid after a minute refetched %d times, want 1", f.jwksHits-hits)
+	}
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	f := newFakeProvider(t)
+	if _, err := Discover(context.Background(), Config{Issuer: f.URL + "/"}, f.Client()); err == nil {
+		t.Error("Discover() accepted a provider reporting a different issuer")
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package oui maps vendor names to their IEEE MAC address assignments (OUIs)
+// so searches can find every device made by one vendor.
+//
+// A registry in the IEEE CSV format is compiled into the binary. The checked-in
+// file covers common camera, printer, phone and infrastructure vendors; run
+// `make oui` to replace it with the full IEEE MA-L/MA-M/MA-S registry before
+// building, or load a downloaded copy at run time with Load.
+package oui
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed oui.csv
+var embeddedCSV []byte
+
+// Assignment is one block of MAC addresses assigned to an organization.
+type Assignment struct {
+	Prefix string // lowercase hex without separators: 6 (MA-L), 7 (MA-M) or 9 (MA-S) digits
+	Vendor string // organization name as registered
+}
+
+// Registry is a parsed OUI database.
+type Registry struct {
+	entries  []Assignment
+	byPrefix map[string]string // prefix → vendor
+}
+
+var (
+	embeddedOnce sync.Once
+	embeddedReg  *Registry
+)
+
+// Embedded returns the registry compiled into the binary.
+func Embedded() *Registry {
+	embeddedOnce.Do(func() {
+		reg, err := Parse(bytes.NewReader(embeddedCSV))
+		if err != nil {
+			panic("oui: embedded registry: " + err.Error())
+		}
+		embeddedReg = reg
+	})
+	return embeddedReg
+}
+
+// Load reads a registry from an IEEE CSV file such as
+// https://standards-oui.ieee.org/oui/oui.csv.
+func Load(path string) (*Registry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	reg, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return reg, nil
+}
+
+// Parse reads the IEEE CSV format: Registry,Assignment,Organization Name,...
+// The header row and rows with a malformed assignment are skipped.
+func Parse(r io.Reader) (*Registry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	reg := &Registry{byPrefix: make(map[string]string)}
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) < 3 {
+			continue
+		}
+		prefix := strings.ToLower(strings.TrimSpace(rec[1]))
+		vendor := strings.TrimSpace(rec[2])
+		if !isHexPrefix(prefix) || vendor == "" {
+			continue
+		}
+		reg.entries = append(reg.entries, Assignment{Prefix: prefix, Vendor: vendor})
+		reg.byPrefix[prefix] = vendor
+	}
+	if len(reg.entries) == 0 {
+		return nil, errors.New("no OUI assignments found")
+	}
+	return reg, nil
+}
+
+// Len returns the number of assignments in the registry.
+func (r *Registry) Len() int {
+	return len(r.entries)
+}
+
+// FindVendor returns every assignment whose organization name contains name as
+// a case-insensitive word prefix ("axis" matches "Axis Communications AB" but
+// not "Praxis"), sorted by prefix.
+func (r *Registry) FindVendor(name string) []Assignment {
+	query := strings.ToLower(strings.TrimSpace(name))
+	if query == "" {
+		return nil
+	}
+	var out []Assignment
+	for _, a := range r.entries {
+		if containsWord(strings.ToLower(a.Vendor), query) {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Prefix < out[j].Prefix })
+	return out
+}
+
+// Lookup returns the organization a MAC address is assigned to, preferring the
+// most specific (MA-S, then MA-M, then MA-L) block, or "" when it is unknown.
+// The MAC may use any separators.
+func (r *Registry) Lookup(mac string) string {
+	var hex strings.Builder
+	for _, c := range strings.ToLower(mac) {
+		if strings.ContainsRune("0123456789abcdef", c) {
+			hex.WriteRune(c)
+		}
+	}
+	digits := hex.String()
+	for _, n := range []int{9, 7, 6} {
+		if len(digits) >= n {
+			if v, ok := r.byPrefix[digits[:n]]; ok {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// containsWord reports whether query occurs in s starting at a word boundary.
+func containsWord(s, query string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], query)
+		if j < 0 {
+			return false
+		}
+		at := i + j
+		if at == 0 {
+			return true
+		}
+		prev := rune(s[at-1])
+		if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+			return true
+		}
+		i = at + 1
+	}
+}
+
+// isHexPrefix reports whether s is a 6, 7 or 9 digit hex assignment.
+func isHexPrefix(s string) bool {
+	if len(s) != 6 && len(s) != 7 && len(s) != 9 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
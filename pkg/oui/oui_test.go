@@ -0,0 +1,115 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package oui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleCSV = `Registry,Assignment,Organization Name,Organization Address
+MA-L,00408C,Axis Communications AB,Emdalavägen 14 Lund SE 22369
+MA-L,ACCC8E,Axis Communications AB,Emdalavägen 14 Lund SE 22369
+MA-M,70B3D5F,"Praxis Tech, Inc.",Somewhere
+MA-S,8C1F64ABC,Taxis Ltd,Elsewhere
+MA-L,ZZZZZZ,Broken Row,
+MA-L,005056,"VMware, Inc.",3401 Hillview Avenue Palo Alto CA US 94304
+`
+
+func TestFindVendor(t *testing.T) {
+	reg, err := Parse(strings.NewReader(sampleCSV))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if reg.Len() != 5 {
+		t.Errorf("Len() = %d, want 5 (header and malformed row skipped)", reg.Len())
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Axis", []string{"00408c", "accc8e"}},
+		{"  axis communications ", []string{"00408c", "accc8e"}},
+		{"vmware", []string{"005056"}},
+		{"praxis", []string{"70b3d5f"}},
+		{"taxis", []string{"8c1f64abc"}},
+		{"xis", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, a := range reg.FindVendor(tt.query) {
+			got = append(got, a.Prefix)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("FindVendor(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	reg, err := Parse(strings.NewReader(sampleCSV + "MA-L,8C1F64,IEEE Registration Authority,\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	tests := []struct {
+		mac  string
+		want string
+	}{
+		{"00:40:8C:12:34:56", "Axis Communications AB"},
+		{"accc.8e00.0001", "Axis Communications AB"},
+		{"70-B3-D5-F1-23-45", "Praxis Tech, Inc."},
+		{"8c:1f:64:ab:c1:23", "Taxis Ltd"},
+		{"8c:1f:64:00:00:01", "IEEE Registration Authority"},
+		{"11:22:33:44:55:66", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := reg.Lookup(tt.mac); got != tt.want {
+			t.Errorf("Lookup(%q) = %q, want %q", tt.mac, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oui.csv")
+	if err := os.WriteFile(path, []byte(sampleCSV), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := Load(path)
+	if err != nil || reg.Len() != 5 {
+		t.Fatalf("Load() = %v, %v", reg, err)
+	}
+	if err := os.WriteFile(path, []byte("Registry,Assignment,Organization Name\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load() of an empty registry succeeded, want error")
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	reg := Embedded()
+	if reg.Len() == 0 {
+		t.Fatal("embedded registry is empty")
+	}
+	if len(reg.FindVendor("Axis")) == 0 {
+		t.Error("embedded registry has no Axis assignments")
+	}
+}
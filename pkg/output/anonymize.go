@@ -0,0 +1,144 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+)
+
+var (
+	anonMACPattern  = regexp.MustCompile(`(?i)\b[0-9a-f]{2}(?:[:-][0-9a-f]{2}){5}\b|\b[0-9a-f]{4}\.[0-9a-f]{4}\.[0-9a-f]{4}\b`)
+	anonIPv4Pattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+)
+
+// Anonymizer replaces client identities (MACs, IPs and hostnames) with keyed
+// HMAC-SHA256 tokens. The same input always maps to the same token within one
+// Anonymizer, so rows and reports stay correlatable, while a fresh random key per
+// run prevents reversing tokens by hashing candidate values.
+type Anonymizer struct {
+	key       []byte
+	hostnames map[string]string // original hostname → token, for scrubbing free text
+}
+
+// NewAnonymizer returns an Anonymizer with a random per-run key.
+func NewAnonymizer() (*Anonymizer, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return NewAnonymizerWithKey(key), nil
+}
+
+// NewAnonymizerWithKey returns an Anonymizer using key; intended for tests.
+func NewAnonymizerWithKey(key []byte) *Anonymizer {
+	return &Anonymizer{key: key, hostnames: make(map[string]string)}
+}
+
+// sum returns the keyed digest of kind and value.
+func (a *Anonymizer) sum(kind, value string) []byte {
+	h := hmac.New(sha256.New, a.key)
+	_, _ = io.WriteString(h, kind+"\x00"+value)
+	return h.Sum(nil)
+}
+
+// MAC returns a stand-in MAC address. It keeps the colon format and sets the
+// locally-administered bit (02:…) so it can never collide with a vendor OUI.
+// Invalid input is returned unchanged.
+func (a *Anonymizer) MAC(mac string) string {
+	norm, err := macaddr.NormalizeExactMac(mac)
+	if err != nil {
+		return mac
+	}
+	d := a.sum("mac", norm)
+	return fmt.Sprintf("02:%02x:%02x:%02x:%02x:%02x", d[0], d[1], d[2], d[3], d[4])
+}
+
+// IP returns a stand-in token such as "ip-1a2b3c4d", or "" for "".
+func (a *Anonymizer) IP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	return "ip-" + hex.EncodeToString(a.sum("ip", ip)[:4])
+}
+
+// Hostname returns a stand-in token such as "host-1a2b3c4d", or "" for "".
+// The mapping is remembered so Text can scrub the hostname from free text.
+func (a *Anonymizer) Hostname(name string) string {
+	if name == "" {
+		return ""
+	}
+	token := "host-" + hex.EncodeToString(a.sum("host", strings.ToLower(name))[:4])
+	a.hostnames[name] = token
+	return token
+}
+
+// Row returns row with its MAC, IP and hostname replaced.
+func (a *Anonymizer) Row(row ResultRow) ResultRow {
+	row.MAC = a.MAC(row.MAC)
+	row.IP = a.IP(row.IP)
+	row.Hostname = a.Hostname(row.Hostname)
+	return row
+}
+
+// Rows anonymizes rows in place.
+func (a *Anonymizer) Rows(rows []ResultRow) {
+	for i := range rows {
+		rows[i] = a.Row(rows[i])
+	}
+}
+
+// Text scrubs every MAC and IPv4 address in s, plus any hostname previously
+// passed to Hostname (longest first, so "pc1.corp" wins over "pc1").
+func (a *Anonymizer) Text(s string) string {
+	names := make([]string, 0, len(a.hostnames))
+	for name := range a.hostnames {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	for _, name := range names {
+		s = strings.ReplaceAll(s, name, a.hostnames[name])
+	}
+	s = anonMACPattern.ReplaceAllStringFunc(s, a.MAC)
+	return anonIPv4Pattern.ReplaceAllStringFunc(s, a.IP)
+}
+
+// Writer returns a writer that scrubs each write with Text before passing it
+// on to w. Writes are expected to contain whole lines, as fmt.Fprintf calls do.
+func (a *Anonymizer) Writer(w io.Writer) io.Writer {
+	return anonWriter{a: a, w: w}
+}
+
+type anonWriter struct {
+	a *Anonymizer
+	w io.Writer
+}
+
+func (aw anonWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(aw.w, aw.a.Text(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
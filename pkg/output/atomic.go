@@ -0,0 +1,108 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// AtomicFile is a file that only appears at its final path once Commit
+// succeeds. Writes go to a temporary file in the same directory, so a run that
+// fails midway never leaves a truncated file for downstream imports to pick up.
+type AtomicFile struct {
+	f       *os.File
+	path    string
+	private bool
+	done    bool
+}
+
+// CreateAtomic starts writing a new file that will be renamed to path on
+// Commit. The file gets the mode of the file it replaces, or 0666 less the
+// umask when path does not exist yet, like os.Create.
+func CreateAtomic(path string) (*AtomicFile, error) {
+	return createAtomic(path, 0666, false)
+}
+
+// CreatePrivateAtomic is CreateAtomic for files holding secrets or personal
+// data: the result is always readable by its owner only.
+func CreatePrivateAtomic(path string) (*AtomicFile, error) {
+	return createAtomic(path, 0600, true)
+}
+
+func createAtomic(path string, perm os.FileMode, private bool) (*AtomicFile, error) {
+	dir, base := filepath.Dir(path), filepath.Base(path)
+	for {
+		name := filepath.Join(dir, "."+base+"."+strconv.FormatUint(uint64(rand.Uint32()), 10)+".tmp")
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &AtomicFile{f: f, path: path, private: private}, nil
+	}
+}
+
+// Write writes to the temporary file.
+func (a *AtomicFile) Write(p []byte) (int, error) {
+	return a.f.Write(p)
+}
+
+// Commit flushes the temporary file to disk and renames it over path, keeping
+// the mode of the file it replaces.
+func (a *AtomicFile) Commit() error {
+	if a.done {
+		return nil
+	}
+	a.done = true
+	if err := a.f.Sync(); err != nil {
+		_ = a.f.Close()
+		_ = os.Remove(a.f.Name())
+		return err
+	}
+	if err := a.f.Close(); err != nil {
+		_ = os.Remove(a.f.Name())
+		return err
+	}
+	if !a.private {
+		if fi, err := os.Stat(a.path); err == nil {
+			if err := os.Chmod(a.f.Name(), fi.Mode().Perm()); err != nil {
+				_ = os.Remove(a.f.Name())
+				return err
+			}
+		}
+	}
+	if err := os.Rename(a.f.Name(), a.path); err != nil {
+		_ = os.Remove(a.f.Name())
+		return err
+	}
+	return nil
+}
+
+// Abort discards the temporary file; path is left untouched. It is a no-op
+// after Commit, so it is safe to defer.
+func (a *AtomicFile) Abort() {
+	if a.done {
+		return
+	}
+	a.done = true
+	_ = a.f.Close()
+	_ = os.Remove(a.f.Name())
+}
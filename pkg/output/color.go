@@ -0,0 +1,142 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ANSI SGR sequences used by the colorized text writer.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+)
+
+// Color modes accepted by ParseColorMode.
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// ParseColorMode validates a --color value. An empty value means auto.
+func ParseColorMode(mode string) (string, error) {
+	switch m := strings.ToLower(strings.TrimSpace(mode)); m {
+	case "":
+		return ColorAuto, nil
+	case ColorAuto, ColorAlways, ColorNever:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid color mode %q (use auto, always or never)", mode)
+}
+
+// WriteColorText is WriteText with ANSI colors: the header is bold, the MAC
+// column is highlighted, the port and port-mode columns are green for access
+// ports, yellow for trunks and magenta for confirmed uplinks, the confidence
+// column is green, yellow or red by band, and empty fields are shown as a dim "-". Column alignment is computed on the uncolored text.
+func WriteColorText(w io.Writer, rows []ResultRow, cols ...Column) error {
+	ew := &errWriter{w: w}
+	if len(rows) == 0 {
+		ew.println("No results")
+		return ew.err
+	}
+
+	cols = columnsOrDefault(cols, defaultColumnKeys)
+	hdrs := headers(cols)
+	widths := make([]int, len(cols))
+	for i, h := range hdrs {
+		widths[i] = len(h)
+	}
+	values := make([][]string, len(rows))
+	for r, row := range rows {
+		values[r] = rowValues(row, cols)
+		for i, v := range values[r] {
+			if v == "" {
+				v = "-"
+			}
+			widths[i] = max(widths[i], len(v))
+		}
+	}
+
+	separator := ansiDim + strings.Repeat("-", sum(widths)+len(widths)*3-1) + ansiReset
+	ew.println(separator)
+	ew.println(formatColorRow(hdrs, widths, func(int, string) string { return ansiBold }))
+	ew.println(separator)
+	for r, v := range values {
+		row := rows[r]
+		ew.println(formatColorRow(v, widths, func(i int, value string) string {
+			return cellColor(cols[i].Key, row, value)
+		}))
+	}
+	ew.println(separator)
+	return ew.err
+}
+
+// cellColor returns the SGR prefix for one cell, or "" to leave it plain.
+func cellColor(key string, row ResultRow, value string) string {
+	if value == "" {
+		return ansiDim
+	}
+	switch key {
+	case "mac":
+		return ansiBold + ansiCyan
+	case "port", "portmode", "uplink":
+		switch {
+		case row.IsUplink:
+			return ansiMagenta
+		case row.PortMode == "trunk":
+			return ansiYellow
+		case row.PortMode == "access":
+			return ansiGreen
+		}
+	case "confidence":
+		switch {
+		case row.Confidence >= ConfidenceHigh:
+			return ansiBold + ansiGreen
+		case row.Confidence >= ConfidenceLow:
+			return ansiYellow
+		default:
+			return ansiRed
+		}
+	}
+	return ""
+}
+
+// formatColorRow pads each value to its column width and then wraps it in the
+// color chosen by colorOf, so escape sequences do not disturb the alignment.
+func formatColorRow(values []string, widths []int, colorOf func(i int, value string) string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		color := colorOf(i, v)
+		if v == "" {
+			v = "-"
+		}
+		cell := fmt.Sprintf("%-*s", widths[i], v)
+		if color != "" {
+			cell = color + cell + ansiReset
+		}
+		parts[i] = cell
+	}
+	return strings.Join(parts, " | ")
+}
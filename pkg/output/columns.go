@@ -0,0 +1,169 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Column is one selectable output column shared by the tabular writers.
+type Column struct {
+	Key    string                 // name used by --columns, e.g. "mac"
+	Header string                 // CSV/text/XLSX header
+	Label  string                 // HTML header; empty means Header
+	Value  func(ResultRow) string // cell value for a row
+}
+
+// label returns the HTML header for c.
+func (c Column) label() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	return c.Header
+}
+
+// columnRegistry lists every column in its default position.
+var columnRegistry = []Column{
+	{Key: "org", Header: "Org", Value: func(r ResultRow) string { return r.OrgName }},
+	{Key: "network", Header: "Network", Value: func(r ResultRow) string { return r.NetworkName }},
+	{Key: "switch", Header: "Switch", Value: func(r ResultRow) string { return r.SwitchName }},
+	{Key: "serial", Header: "Serial", Value: func(r ResultRow) string { return r.SwitchSerial }},
+	{Key: "port", Header: "Port", Value: func(r ResultRow) string { return r.Port }},
+	{Key: "aggrports", Header: "AggrPorts", Value: aggrPortsStr},
+	{Key: "mac", Header: "MAC", Value: func(r ResultRow) string { return r.MAC }},
+	{Key: "ip", Header: "IP", Value: func(r ResultRow) string { return r.IP }},
+	{Key: "hostname", Header: "Hostname", Value: func(r ResultRow) string { return r.Hostname }},
+	{Key: "vlan", Header: "VLAN", Value: func(r ResultRow) string {
+		if r.VLAN <= 0 {
+			return ""
+		}
+		return strconv.Itoa(r.VLAN)
+	}},
+	{Key: "portmode", Header: "PortMode", Value: func(r ResultRow) string { return r.PortMode }},
+	{Key: "entrytype", Header: "EntryType", Label: "Entry Type", Value: func(r ResultRow) string { return r.EntryType }},
+	{Key: "dhcphostname", Header: "DHCPHostname", Label: "DHCP Hostname", Value: func(r ResultRow) string { return r.DHCPHostname }},
+	{Key: "leaseexpiry", Header: "LeaseExpiry", Label: "Lease Expiry", Value: func(r ResultRow) string { return r.LeaseExpiry }},
+	{Key: "ssid", Header: "SSID", Value: func(r ResultRow) string { return r.SSID }},
+	{Key: "band", Header: "Band", Value: func(r ResultRow) string { return r.Band }},
+	{Key: "rssi", Header: "RSSI", Value: func(r ResultRow) string {
+		if r.RSSI == 0 {
+			return ""
+		}
+		return strconv.Itoa(r.RSSI)
+	}},
+	{Key: "lastseen", Header: "LastSeen", Label: "Last Seen", Value: func(r ResultRow) string { return r.LastSeen }},
+	{Key: "uplink", Header: "Uplink", Value: func(r ResultRow) string {
+		if r.IsUplink {
+			return "yes"
+		}
+		return ""
+	}},
+	{Key: "link", Header: "Link", Value: func(r ResultRow) string { return r.Link }},
+	{Key: "speed", Header: "Speed", Value: func(r ResultRow) string { return r.Speed }},
+	{Key: "duplex", Header: "Duplex", Value: func(r ResultRow) string { return r.Duplex }},
+	{Key: "neighbor", Header: "Neighbor", Value: func(r ResultRow) string { return r.Neighbor }},
+	{Key: "neighborplatform", Header: "NeighborPlatform", Label: "Neighbor Platform", Value: func(r ResultRow) string { return r.NeighborPlatform }},
+	{Key: "devicestatus", Header: "DeviceStatus", Label: "Device Status", Value: func(r ResultRow) string { return r.DeviceStatus }},
+	{Key: "lastreported", Header: "LastReported", Label: "Last Reported", Value: func(r ResultRow) string { return r.LastReported }},
+	{Key: "model", Header: "Model", Value: func(r ResultRow) string { return r.Model }},
+	{Key: "firmware", Header: "Firmware", Value: func(r ResultRow) string { return r.Firmware }},
+	{Key: "note", Header: "Note", Value: func(r ResultRow) string { return r.Note }},
+	{Key: "source", Header: "Source", Value: func(r ResultRow) string { return r.Source }},
+	{Key: "confidence", Header: "Confidence", Value: func(r ResultRow) string {
+		if r.Confidence <= 0 {
+			return ""
+		}
+		return strconv.Itoa(r.Confidence)
+	}},
+}
+
+// defaultColumnKeys are the columns written by CSV, text and HTML when no
+// selection is given.
+var defaultColumnKeys = []string{"org", "network", "switch", "serial", "port", "aggrports", "mac", "ip", "hostname", "lastseen", "uplink", "note", "confidence"}
+
+// ColumnKeys returns every valid --columns key in registry order.
+func ColumnKeys() []string {
+	keys := make([]string, len(columnRegistry))
+	for i, c := range columnRegistry {
+		keys[i] = c.Key
+	}
+	return keys
+}
+
+// ParseColumns resolves a comma-separated list of column keys (case-insensitive)
+// in the order given. An empty spec returns nil, meaning each writer's defaults.
+func ParseColumns(spec string) ([]Column, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	var cols []Column
+	for _, key := range strings.Split(spec, ",") {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		col, ok := lookupColumn(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q (valid: %s)", key, strings.Join(ColumnKeys(), ", "))
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// lookupColumn returns the registered column for key.
+func lookupColumn(key string) (Column, bool) {
+	for _, c := range columnRegistry {
+		if c.Key == key {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// columnsOrDefault returns cols, or the columns named by defaults when cols is empty.
+func columnsOrDefault(cols []Column, defaults []string) []Column {
+	if len(cols) > 0 {
+		return cols
+	}
+	out := make([]Column, 0, len(defaults))
+	for _, key := range defaults {
+		if c, ok := lookupColumn(key); ok {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// headers returns the header of every column.
+func headers(cols []Column) []string {
+	out := make([]string, len(cols))
+	for i, c := range cols {
+		out[i] = c.Header
+	}
+	return out
+}
+
+// rowValues returns the cell values of row for cols.
+func rowValues(row ResultRow, cols []Column) []string {
+	out := make([]string, len(cols))
+	for i, c := range cols {
+		out[i] = c.Value(row)
+	}
+	return out
+}
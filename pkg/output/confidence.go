@@ -0,0 +1,140 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Confidence bands used to highlight scores in the text and HTML writers.
+const (
+	ConfidenceHigh = 75 // at or above: very likely the port the device is plugged into
+	ConfidenceLow  = 40 // below: stale, indirect or ambiguous sighting
+)
+
+// ScoreRows sets Confidence (0–100) on every row. The score adds up four parts:
+//
+//   - freshness (40): how recently the MAC was seen; a live MAC table hit
+//     without a timestamp counts as current
+//   - data source (30): live MAC table and device records beat client history
+//   - port role (20): an access edge port beats a trunk, and an uplink scores 0
+//   - duplicate sightings (10): the MAC on exactly one edge port is unambiguous;
+//     the same MAC on several edge ports, or on an uplink when an edge port is
+//     known, is not
+//
+// Scores are relative to the rows passed in, so call it once on the full result set.
+func ScoreRows(rows []ResultRow, now time.Time) {
+	edges := make(map[string]map[string]struct{})
+	for _, r := range rows {
+		if !isEdgeRow(r) {
+			continue
+		}
+		mac := strings.ToLower(r.MAC)
+		if edges[mac] == nil {
+			edges[mac] = make(map[string]struct{})
+		}
+		edges[mac][r.SwitchSerial+"|"+r.Port] = struct{}{}
+	}
+
+	for i := range rows {
+		r := &rows[i]
+		score := freshnessScore(r.LastSeen, r.Source, now) + sourceScore(r.Source) + portScore(*r)
+		switch n := len(edges[strings.ToLower(r.MAC)]); {
+		case isEdgeRow(*r) && n == 1:
+			score += 10
+		case !isEdgeRow(*r) && n == 0:
+			score += 5
+		}
+		r.Confidence = min(max(score, 1), 100)
+	}
+}
+
+// isEdgeRow reports whether r is a candidate for the device's physical port:
+// a non-uplink switch port that is not a trunk.
+func isEdgeRow(r ResultRow) bool {
+	return r.Port != "" && r.Port != "unknown" && !r.IsUplink && r.PortMode != "trunk"
+}
+
+// freshnessScore grades the age of lastSeen (RFC 3339 or Unix seconds).
+func freshnessScore(lastSeen, source string, now time.Time) int {
+	seen, ok := ParseLastSeen(lastSeen)
+	if !ok {
+		if source == SourceMacTable || source == SourceDevice {
+			return 40
+		}
+		return 10
+	}
+	switch age := now.Sub(seen); {
+	case age <= 15*time.Minute:
+		return 40
+	case age <= time.Hour:
+		return 35
+	case age <= 24*time.Hour:
+		return 25
+	case age <= 7*24*time.Hour:
+		return 12
+	}
+	return 0
+}
+
+// ParseLastSeen parses a LastSeen value: the RFC 3339 timestamps and Unix
+// seconds used by the Dashboard API's lastSeen fields.
+func ParseLastSeen(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil && secs > 0 {
+		return time.Unix(secs, 0), true
+	}
+	return time.Time{}, false
+}
+
+// sourceScore grades how directly the API observed the MAC on the port.
+func sourceScore(source string) int {
+	switch source {
+	case SourceMacTable, SourceDevice:
+		return 30
+	case SourceClientID:
+		return 25
+	case SourceNetworkClients:
+		return 20
+	case SourceDeviceClients:
+		return 15
+	}
+	return 10
+}
+
+// portScore grades the port role: where a MAC is learned on an access port is
+// where the device is; trunks and uplinks only carry its traffic.
+func portScore(r ResultRow) int {
+	switch {
+	case r.Source == SourceDevice:
+		return 20
+	case r.IsUplink, r.Port == "", r.Port == "unknown":
+		return 0
+	case r.PortMode == "access":
+		return 20
+	case r.PortMode == "trunk":
+		return 8
+	}
+	return 12
+}
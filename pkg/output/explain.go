@@ -0,0 +1,72 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FieldSource records which API call or lookup supplied one field of a row.
+type FieldSource struct {
+	Field  string `json:"field"`            // ResultRow field, e.g. "Port" or "VLAN"
+	Source string `json:"source"`           // e.g. "live MAC table", "network clients"
+	Detail string `json:"detail,omitempty"` // job ID, endpoint or time; may be empty
+}
+
+// WriteExplain writes, for every row, which source supplied each of its
+// fields, so conflicting or surprising data can be traced to an API call.
+// Rows without recorded provenance are listed with "(no provenance recorded)".
+func WriteExplain(w io.Writer, rows []ResultRow) error {
+	ew := &errWriter{w: w}
+	for i, row := range rows {
+		title := fmt.Sprintf("[%d] %s on %s port %s", i+1, row.MAC, firstNonBlank(row.SwitchName, row.SwitchSerial, "?"), firstNonBlank(row.Port, "-"))
+		if row.NetworkName != "" {
+			title += " (" + row.NetworkName + ")"
+		}
+		if row.Confidence > 0 {
+			title += fmt.Sprintf(", confidence %d", row.Confidence)
+		}
+		ew.println(title)
+		if len(row.Explain) == 0 {
+			ew.println("    (no provenance recorded)")
+			continue
+		}
+		width := 0
+		for _, fs := range row.Explain {
+			width = max(width, len(fs.Field))
+		}
+		for _, fs := range row.Explain {
+			line := fmt.Sprintf("    %-*s  %s", width, fs.Field, fs.Source)
+			if fs.Detail != "" {
+				line += " (" + fs.Detail + ")"
+			}
+			ew.println(line)
+		}
+	}
+	return ew.err
+}
+
+// firstNonBlank returns the first value that is not empty after trimming.
+func firstNonBlank(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
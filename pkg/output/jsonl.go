@@ -0,0 +1,110 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// exportRow is the structured (JSON Lines / YAML) representation of a ResultRow.
+type exportRow struct {
+	Org        string   `json:"org" yaml:"org"`
+	Network    string   `json:"network" yaml:"network"`
+	Switch     string   `json:"switch" yaml:"switch"`
+	Serial     string   `json:"serial" yaml:"serial"`
+	Port       string   `json:"port" yaml:"port"`
+	AggrPorts  []string `json:"aggrPorts,omitempty" yaml:"aggrPorts,omitempty"`
+	MAC        string   `json:"mac" yaml:"mac"`
+	IP         string   `json:"ip,omitempty" yaml:"ip,omitempty"`
+	Hostname   string   `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	DHCPHost   string   `json:"dhcpHostname,omitempty" yaml:"dhcpHostname,omitempty"`
+	Lease      string   `json:"leaseExpiry,omitempty" yaml:"leaseExpiry,omitempty"`
+	LastSeen   string   `json:"lastSeen,omitempty" yaml:"lastSeen,omitempty"`
+	VLAN       int      `json:"vlan,omitempty" yaml:"vlan,omitempty"`
+	PortMode   string   `json:"portMode,omitempty" yaml:"portMode,omitempty"`
+	EntryType  string   `json:"entryType,omitempty" yaml:"entryType,omitempty"`
+	SSID       string   `json:"ssid,omitempty" yaml:"ssid,omitempty"`
+	Band       string   `json:"band,omitempty" yaml:"band,omitempty"`
+	RSSI       int      `json:"rssi,omitempty" yaml:"rssi,omitempty"`
+	Uplink     bool     `json:"uplink" yaml:"uplink"`
+	Link       string   `json:"link,omitempty" yaml:"link,omitempty"`
+	Speed      string   `json:"speed,omitempty" yaml:"speed,omitempty"`
+	Duplex     string   `json:"duplex,omitempty" yaml:"duplex,omitempty"`
+	Neighbor   string   `json:"neighbor,omitempty" yaml:"neighbor,omitempty"`
+	Platform   string   `json:"neighborPlatform,omitempty" yaml:"neighborPlatform,omitempty"`
+	DevStatus  string   `json:"deviceStatus,omitempty" yaml:"deviceStatus,omitempty"`
+	Reported   string   `json:"lastReported,omitempty" yaml:"lastReported,omitempty"`
+	Model      string   `json:"model,omitempty" yaml:"model,omitempty"`
+	Firmware   string   `json:"firmware,omitempty" yaml:"firmware,omitempty"`
+	Note       string   `json:"note,omitempty" yaml:"note,omitempty"`
+	Source     string   `json:"source,omitempty" yaml:"source,omitempty"`
+	Confidence int      `json:"confidence,omitempty" yaml:"confidence,omitempty"`
+}
+
+// WriteJSONLRow writes a single result as one JSON object followed by a newline,
+// so callers can stream rows as they are found.
+func WriteJSONLRow(w io.Writer, row ResultRow) error {
+	return json.NewEncoder(w).Encode(toExportRow(row))
+}
+
+// toExportRow converts a ResultRow to its structured form.
+func toExportRow(row ResultRow) exportRow {
+	return exportRow{
+		Org:        row.OrgName,
+		Network:    row.NetworkName,
+		Switch:     row.SwitchName,
+		Serial:     row.SwitchSerial,
+		Port:       row.Port,
+		AggrPorts:  row.AggrPorts,
+		MAC:        row.MAC,
+		IP:         row.IP,
+		Hostname:   row.Hostname,
+		DHCPHost:   row.DHCPHostname,
+		Lease:      row.LeaseExpiry,
+		LastSeen:   row.LastSeen,
+		VLAN:       row.VLAN,
+		PortMode:   row.PortMode,
+		EntryType:  row.EntryType,
+		SSID:       row.SSID,
+		Band:       row.Band,
+		RSSI:       row.RSSI,
+		Uplink:     row.IsUplink,
+		Link:       row.Link,
+		Speed:      row.Speed,
+		Duplex:     row.Duplex,
+		Neighbor:   row.Neighbor,
+		Platform:   row.NeighborPlatform,
+		DevStatus:  row.DeviceStatus,
+		Reported:   row.LastReported,
+		Model:      row.Model,
+		Firmware:   row.Firmware,
+		Note:       row.Note,
+		Source:     row.Source,
+		Confidence: row.Confidence,
+	}
+}
+
+// WriteJSONL writes results in JSON Lines (NDJSON) format, one object per line.
+// It stops at and returns the first write error.
+func WriteJSONL(w io.Writer, rows []ResultRow) error {
+	for _, row := range rows {
+		if err := WriteJSONLRow(w, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadResults reads rows written by the jsonl or yaml formats (or a JSON array
+// of the same objects), so results of separate runs can be merged.
+func ReadResults(r io.Reader) ([]ResultRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	var records []exportRow
+	if bytes.HasPrefix(data, []byte("{")) {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for dec.More() {
+			var rec exportRow
+			if err := dec.Decode(&rec); err != nil {
+				return nil, fmt.Errorf("row %d: %v", len(records)+1, err)
+			}
+			records = append(records, rec)
+		}
+	} else if err := yaml.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	rows := make([]ResultRow, len(records))
+	for i, rec := range records {
+		rows[i] = fromExportRow(rec)
+	}
+	return rows, nil
+}
+
+// fromExportRow is the inverse of toExportRow.
+func fromExportRow(rec exportRow) ResultRow {
+	return ResultRow{
+		OrgName:          rec.Org,
+		NetworkName:      rec.Network,
+		SwitchName:       rec.Switch,
+		SwitchSerial:     rec.Serial,
+		Port:             rec.Port,
+		AggrPorts:        rec.AggrPorts,
+		MAC:              rec.MAC,
+		IP:               rec.IP,
+		Hostname:         rec.Hostname,
+		DHCPHostname:     rec.DHCPHost,
+		LeaseExpiry:      rec.Lease,
+		LastSeen:         rec.LastSeen,
+		VLAN:             rec.VLAN,
+		PortMode:         rec.PortMode,
+		EntryType:        rec.EntryType,
+		SSID:             rec.SSID,
+		Band:             rec.Band,
+		RSSI:             rec.RSSI,
+		IsUplink:         rec.Uplink,
+		Link:             rec.Link,
+		Speed:            rec.Speed,
+		Duplex:           rec.Duplex,
+		Neighbor:         rec.Neighbor,
+		NeighborPlatform: rec.Platform,
+		DeviceStatus:     rec.DevStatus,
+		LastReported:     rec.Reported,
+		Model:            rec.Model,
+		Firmware:         rec.Firmware,
+		Note:             rec.Note,
+		Source:           rec.Source,
+		Confidence:       rec.Confidence,
+	}
+}
+
+// MergeRows deduplicates rows from several runs on switch serial, port and MAC,
+// the same key a single run uses. Of two rows for the same port the one from
+// the more direct source wins (a live MAC table lookup beats client history),
+// then the more recently seen one; fields the winner lacks are filled from the
+// loser. Rows keep the order in which their key first appeared.
+func MergeRows(rows []ResultRow) []ResultRow {
+	var merged []ResultRow
+	index := make(map[string]int)
+	for _, row := range rows {
+		key := strings.ToLower(row.SwitchSerial + "|" + row.Port + "|" + row.MAC)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, row)
+			continue
+		}
+		merged[i] = mergeRow(merged[i], row)
+	}
+	return merged
+}
+
+// mergeRow combines two rows for the same switch port and MAC.
+func mergeRow(a, b ResultRow) ResultRow {
+	if prefer(b, a) {
+		a, b = b, a
+	}
+	if a.IP == "" {
+		a.IP = b.IP
+	}
+	if a.Hostname == "" {
+		a.Hostname = b.Hostname
+	}
+	if a.DHCPHostname == "" {
+		a.DHCPHostname = b.DHCPHostname
+	}
+	if a.LeaseExpiry == "" {
+		a.LeaseExpiry = b.LeaseExpiry
+	}
+	if a.LastSeen == "" {
+		a.LastSeen = b.LastSeen
+	}
+	if a.VLAN == 0 {
+		a.VLAN = b.VLAN
+	}
+	if a.PortMode == "" {
+		a.PortMode = b.PortMode
+	}
+	if a.EntryType == "" {
+		a.EntryType = b.EntryType
+	}
+	if a.SSID == "" {
+		a.SSID = b.SSID
+	}
+	if a.Band == "" {
+		a.Band, a.RSSI = b.Band, b.RSSI
+	}
+	if a.Link == "" {
+		a.Link, a.Speed, a.Duplex = b.Link, b.Speed, b.Duplex
+	}
+	if a.Neighbor == "" {
+		a.Neighbor, a.NeighborPlatform = b.Neighbor, b.NeighborPlatform
+	}
+	if a.DeviceStatus == "" {
+		a.DeviceStatus, a.LastReported = b.DeviceStatus, b.LastReported
+	}
+	if a.Model == "" {
+		a.Model, a.Firmware = b.Model, b.Firmware
+	}
+	if a.AggrPorts == nil {
+		a.AggrPorts = b.AggrPorts
+	}
+	if a.Note == "" {
+		a.Note = b.Note
+	}
+	a.IsUplink = a.IsUplink || b.IsUplink
+	return a
+}
+
+// prefer reports whether a is the better observation of the two.
+func prefer(a, b ResultRow) bool {
+	if sa, sb := sourceScore(a.Source), sourceScore(b.Source); sa != sb {
+		return sa > sb
+	}
+	ta, okA := ParseLastSeen(a.LastSeen)
+	tb, okB := ParseLastSeen(b.LastSeen)
+	return okA && (!okB || ta.After(tb))
+}
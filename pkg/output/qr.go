@@ -0,0 +1,69 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// qrRow is the compact per-row shape encoded into a QR code. Field names are kept
+// short so typical results fit in a code a phone camera can read from a screen.
+type qrRow struct {
+	Switch string `json:"sw"`
+	Serial string `json:"sn"`
+	Port   string `json:"p"`
+	MAC    string `json:"m"`
+	IP     string `json:"ip,omitempty"`
+	VLAN   int    `json:"v,omitempty"`
+}
+
+// QRPayload returns the compact JSON encoding of rows used for QR code sharing.
+func QRPayload(rows []ResultRow) string {
+	compact := make([]qrRow, 0, len(rows))
+	for _, r := range rows {
+		compact = append(compact, qrRow{
+			Switch: r.SwitchName,
+			Serial: r.SwitchSerial,
+			Port:   r.Port,
+			MAC:    r.MAC,
+			IP:     r.IP,
+			VLAN:   r.VLAN,
+		})
+	}
+	b, _ := json.Marshal(compact)
+	return string(b)
+}
+
+// WriteQR renders payload as a QR code using Unicode half-block characters so it
+// can be scanned straight from a terminal. Returns an error if the payload is too
+// large to encode.
+func WriteQR(w io.Writer, payload string) error {
+	q, err := qrcode.New(payload, qrcode.Low)
+	if err != nil {
+		return fmt.Errorf("cannot encode QR code (%d bytes): %v", len(payload), err)
+	}
+	_, err = io.WriteString(w, q.ToSmallString(false))
+	return err
+}
+
+// QRPNG renders payload as a size×size pixel PNG image.
+func QRPNG(payload string, size int) ([]byte, error) {
+	return qrcode.Encode(payload, qrcode.Medium, size)
+}
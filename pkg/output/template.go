@@ -0,0 +1,61 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"io"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are available to --output-template templates in addition to the
+// text/template builtins.
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  func(sep string, s []string) string { return strings.Join(s, sep) },
+	"default": func(def, s string) string {
+		if s == "" {
+			return def
+		}
+		return s
+	},
+}
+
+// ParseRowTemplate parses a template that is executed once per ResultRow, e.g.
+// `{{.SwitchName}} port {{.Port}}: {{.MAC}}`. A newline is appended to the
+// template unless it already ends with one, so one-liners produce one line per row.
+func ParseRowTemplate(text string) (*template.Template, error) {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return template.New("row").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// WriteTemplate renders every row through tmpl. Field names are those of
+// ResultRow (OrgName, NetworkName, SwitchName, SwitchSerial, Port, AggrPorts,
+// MAC, IP, Hostname, DHCPHostname, LeaseExpiry, LastSeen, VLAN, PortMode,
+// EntryType, SSID, Band, RSSI, IsUplink, Link, Speed, Duplex, Neighbor,
+// NeighborPlatform, DeviceStatus, LastReported, Model, Firmware, Note,
+// FirstSeen, Source, Confidence).
+func WriteTemplate(w io.Writer, tmpl *template.Template, rows []ResultRow) error {
+	for _, row := range rows {
+		if err := tmpl.Execute(w, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
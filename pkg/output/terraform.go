@@ -0,0 +1,106 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteTerraformExternal writes the best result (rows are expected sorted by
+// confidence) as the flat JSON object of strings that Terraform's "external"
+// data source requires. Every key is always present, so HCL references do not
+// fail when nothing was found: found is "false" and the other values are empty.
+// matches is the number of rows, so a plan can check the answer is unambiguous.
+func WriteTerraformExternal(w io.Writer, rows []ResultRow) error {
+	out := map[string]string{
+		"found":             "false",
+		"matches":           strconv.Itoa(len(rows)),
+		"org":               "",
+		"network":           "",
+		"switch":            "",
+		"serial":            "",
+		"port":              "",
+		"aggr_ports":        "",
+		"mac":               "",
+		"ip":                "",
+		"hostname":          "",
+		"dhcp_hostname":     "",
+		"lease_expiry":      "",
+		"last_seen":         "",
+		"vlan":              "",
+		"port_mode":         "",
+		"entry_type":        "",
+		"ssid":              "",
+		"band":              "",
+		"rssi":              "",
+		"uplink":            "",
+		"link":              "",
+		"speed":             "",
+		"duplex":            "",
+		"neighbor":          "",
+		"neighbor_platform": "",
+		"device_status":     "",
+		"last_reported":     "",
+		"model":             "",
+		"firmware":          "",
+		"source":            "",
+		"confidence":        "",
+	}
+	if len(rows) > 0 {
+		r := rows[0]
+		out["found"] = "true"
+		out["org"] = r.OrgName
+		out["network"] = r.NetworkName
+		out["switch"] = r.SwitchName
+		out["serial"] = r.SwitchSerial
+		out["port"] = r.Port
+		out["aggr_ports"] = strings.Join(r.AggrPorts, ",")
+		out["mac"] = r.MAC
+		out["ip"] = r.IP
+		out["hostname"] = r.Hostname
+		out["dhcp_hostname"] = r.DHCPHostname
+		out["lease_expiry"] = r.LeaseExpiry
+		out["last_seen"] = r.LastSeen
+		if r.VLAN > 0 {
+			out["vlan"] = strconv.Itoa(r.VLAN)
+		}
+		out["port_mode"] = r.PortMode
+		out["entry_type"] = r.EntryType
+		out["ssid"] = r.SSID
+		out["band"] = r.Band
+		if r.RSSI != 0 {
+			out["rssi"] = strconv.Itoa(r.RSSI)
+		}
+		out["uplink"] = strconv.FormatBool(r.IsUplink)
+		out["link"] = r.Link
+		out["speed"] = r.Speed
+		out["duplex"] = r.Duplex
+		out["neighbor"] = r.Neighbor
+		out["neighbor_platform"] = r.NeighborPlatform
+		out["device_status"] = r.DeviceStatus
+		out["last_reported"] = r.LastReported
+		out["model"] = r.Model
+		out["firmware"] = r.Firmware
+		out["source"] = r.Source
+		if r.Confidence > 0 {
+			out["confidence"] = strconv.Itoa(r.Confidence)
+		}
+	}
+	return json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+)
+
+// Truncation records that a result set was cut to its first Shown of Total rows.
+type Truncation struct {
+	Shown int `json:"shown"`
+	Total int `json:"total"`
+}
+
+// Truncate keeps the first limit rows, so rows should already be sorted best
+// first. The Truncation is nil when nothing was dropped; a limit of zero or
+// less keeps every row.
+func Truncate(rows []ResultRow, limit int) ([]ResultRow, *Truncation) {
+	if limit <= 0 || len(rows) <= limit {
+		return rows, nil
+	}
+	return rows[:limit], &Truncation{Shown: limit, Total: len(rows)}
+}
+
+// Warning is the notice shown with truncated results.
+func (t *Truncation) Warning() string {
+	return fmt.Sprintf("results truncated: showing the first %d of %d matches; refine your pattern to see the rest", t.Shown, t.Total)
+}
+
+// WriteTruncation appends the truncation notice to output already written in
+// format: a trailing line for text, a comment line for csv and yaml, a
+// paragraph for html and a final {"truncated":true,...} object for jsonl.
+// Other formats have no place for it and are left alone; xlsx carries it via
+// WriteXLSXWithFooter. A nil t writes nothing. Runs that collect warnings
+// write the notice with the others through WriteWarnings instead.
+func WriteTruncation(w io.Writer, format string, t *Truncation) error {
+	if t == nil {
+		return nil
+	}
+	var err error
+	switch format {
+	case "text":
+		_, err = fmt.Fprintf(w, "WARNING: %s\n", t.Warning())
+	case "csv", "yaml":
+		_, err = fmt.Fprintf(w, "# WARNING: %s\n", t.Warning())
+	case "html":
+		_, err = fmt.Fprintf(w, "<p class=\"warning\"><strong>Warning:</strong> %s</p>\n", html.EscapeString(t.Warning()))
+	case "jsonl":
+		err = json.NewEncoder(w).Encode(struct {
+			Truncated bool `json:"truncated"`
+			*Truncation
+			Warning string `json:"warning"`
+		}{true, t, t.Warning()})
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Kinds of run-level warnings.
+const (
+	WarningSkippedSwitch     = "skipped-switch"     // a switch could not be searched at all
+	WarningSkippedNetwork    = "skipped-network"    // a network could not be searched at all
+	WarningStaleData         = "stale-data"         // a switch was searched from client history only
+	WarningUnsupportedDevice = "unsupported-device" // a device of unknown type was left out
+	WarningOfflineDevice     = "offline-device"     // a device to be searched is offline in the dashboard
+	WarningTruncated         = "truncated"          // results were cut at --max-results
+)
+
+// Warning is a problem that did not stop the run but may leave its results
+// incomplete or out of date.
+type Warning struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Network string `json:"network,omitempty"`
+	Device  string `json:"device,omitempty"` // serial of the device concerned
+}
+
+// Warnings collects the warnings of one run, in the order they were added and
+// without duplicates. It is safe for concurrent use. Adding to a nil
+// *Warnings does nothing, so code shared with runs that do not collect
+// warnings can add them unconditionally.
+type Warnings struct {
+	mu   sync.Mutex
+	list []Warning
+}
+
+// Add records w unless an identical warning was already added.
+func (ws *Warnings) Add(w Warning) {
+	if ws == nil {
+		return
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for _, have := range ws.list {
+		if have == w {
+			return
+		}
+	}
+	ws.list = append(ws.list, w)
+}
+
+// List returns a copy of the warnings added so far; nil when there are none.
+func (ws *Warnings) List() []Warning {
+	if ws == nil {
+		return nil
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if len(ws.list) == 0 {
+		return nil
+	}
+	return append([]Warning(nil), ws.list...)
+}
+
+// AsWarning returns the truncation notice as a run-level warning.
+func (t *Truncation) AsWarning() Warning {
+	return Warning{Kind: WarningTruncated, Message: t.Warning()}
+}
+
+// WriteWarnings appends the warnings section to output already written in
+// format: a "WARNING:" line per warning for text, comment lines for csv and
+// yaml, a <section class="warnings"> for html and a final {"warnings":[...]}
+// object for jsonl. Other formats have no place for it and are left alone;
+// xlsx carries the warnings via WriteXLSXWithFooter (see WarningsFooter).
+// Nothing is written when there are no warnings.
+func WriteWarnings(w io.Writer, format string, warnings []Warning) error {
+	if len(warnings) == 0 {
+		return nil
+	}
+	var err error
+	switch format {
+	case "text":
+		for _, wn := range warnings {
+			if _, err = fmt.Fprintf(w, "WARNING: %s\n", wn.Message); err != nil {
+				return err
+			}
+		}
+	case "csv", "yaml":
+		return writeCommentWarnings(w, warnings, "\n")
+	case "html":
+		var b strings.Builder
+		b.WriteString("<section class=\"warnings\">\n<h2>Warnings</h2>\n")
+		for _, wn := range warnings {
+			fmt.Fprintf(&b, "<p class=\"warning\"><strong>Warning:</strong> %s</p>\n", html.EscapeString(wn.Message))
+		}
+		b.WriteString("</section>\n")
+		_, err = io.WriteString(w, b.String())
+	case "jsonl":
+		err = json.NewEncoder(w).Encode(struct {
+			Warnings []Warning `json:"warnings"`
+		}{warnings})
+	}
+	return err
+}
+
+// WriteCSVWarnings is WriteWarnings for CSV written with opts: the comment
+// lines end the way the rows do.
+func WriteCSVWarnings(w io.Writer, opts CSVOptions, warnings []Warning) error {
+	return writeCommentWarnings(w, warnings, opts.newline())
+}
+
+func writeCommentWarnings(w io.Writer, warnings []Warning, newline string) error {
+	for _, wn := range warnings {
+		if _, err := io.WriteString(w, "# WARNING: "+wn.Message+newline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WarningsFooter joins the warnings into the footer text of an xlsx sheet.
+func WarningsFooter(warnings []Warning) string {
+	lines := make([]string, len(warnings))
+	for i, wn := range warnings {
+		lines[i] = "WARNING: " + wn.Message
+	}
+	return strings.Join(lines, "\n")
+}
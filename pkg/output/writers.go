@@ -26,21 +26,51 @@ import (
 
 // ResultRow represents a single row of MAC lookup results.
 type ResultRow struct {
-	OrgName      string
-	NetworkName  string
-	SwitchName   string
-	SwitchSerial string
-	Port         string
-	AggrPorts    []string // member ports when Port is a link-aggregation (AGGR/*) port
-	MAC          string
-	LastSeen     string
-	IP           string
-	Hostname     string
-	VLAN         int
-	PortMode     string // "access", "trunk", or ""
-	IsUplink     bool   // true when port appears in link-layer topology as an inter-device link
+	OrgName          string
+	NetworkName      string
+	SwitchName       string
+	SwitchSerial     string
+	Port             string
+	AggrPorts        []string // member ports when Port is a link-aggregation (AGGR/*) port
+	MAC              string
+	LastSeen         string
+	IP               string
+	Hostname         string
+	DHCPHostname     string // host name the client sent in its DHCP request, or its DHCP reservation name
+	LeaseExpiry      string // when the client's DHCP lease runs out at the latest (RFC 3339), "reserved", or ""
+	VLAN             int
+	PortMode         string        // "access", "trunk", or ""
+	EntryType        string        // live MAC table entry type: "static", "dynamic", or "" when unknown
+	SSID             string        // SSID of a wireless client found on an access point
+	Band             string        // radio band a wireless client was last heard on, e.g. "5 GHz"
+	RSSI             int           // wireless client signal strength in dBm; 0 when unknown
+	IsUplink         bool          // true when port appears in link-layer topology as an inter-device link
+	Link             string        // live port state from the switch: "up", "down", "disabled", or "" when unknown
+	Speed            string        // negotiated link speed such as "1 Gbps"; empty when down or unknown
+	Duplex           string        // "full" or "half"; empty when down or unknown
+	Neighbor         string        // CDP/LLDP neighbor on the port, e.g. "core-sw1 (Gi1/0/48)"
+	NeighborPlatform string        // what the neighbor says it is, e.g. "Cisco IP Phone 8845"; empty when not advertised
+	DeviceStatus     string        // dashboard status of the switch or device: "online", "alerting", "offline", "dormant", or ""
+	LastReported     string        // when the switch or device last reported to the dashboard (RFC 3339), or ""
+	Model            string        // hardware model of the switch or device, e.g. "MS250-48FP"; empty when unknown
+	Firmware         string        // firmware the switch or device runs as the dashboard names it, e.g. "switch-17-10-4"
+	Note             string        // annotation such as a virtual-MAC label; empty for ordinary clients
+	FirstSeen        bool          // MAC had never been observed in this network before (history file)
+	Source           string        // API the row came from: one of the Source* constants, or "" if unknown
+	Confidence       int           // 0–100 trust score set by ScoreRows; 0 means not scored
+	Explain          []FieldSource // where each field came from, for --explain; nil when not recorded
 }
 
+// Data sources recorded in ResultRow.Source, from the most to the least direct.
+const (
+	SourceMacTable       = "mac-table"       // live switch MAC table lookup
+	SourceDevice         = "device"          // the MAC is a Meraki device's own management MAC
+	SourceClientID       = "client-id"       // client detail looked up by Meraki client ID
+	SourceNetworkClients = "network-clients" // network clients API (recent connection)
+	SourceDeviceClients  = "device-clients"  // per-switch device clients history
+	SourceArpTable       = "arp-table"       // security appliance live ARP table; the LAN port is unknown
+)
+
 // aggrPortsStr returns the AggrPorts as a comma-separated string, or empty string if none.
 func aggrPortsStr(row ResultRow) string {
 	if len(row.AggrPorts) == 0 {
@@ -49,95 +79,193 @@ func aggrPortsStr(row ResultRow) string {
 	return strings.Join(row.AggrPorts, ", ")
 }
 
-// WriteCSV writes results in CSV format with headers.
-func WriteCSV(w io.Writer, rows []ResultRow) {
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
+// CSVOptions selects the CSV dialect. The zero value is RFC 4180 with commas.
+type CSVOptions struct {
+	Delimiter rune // field separator; 0 means ','
+	BOM       bool // prefix a UTF-8 byte order mark so Excel detects the encoding
+	QuoteAll  bool // quote every field, not only those that need it
+	CRLF      bool // end lines with \r\n, as Excel on Windows writes them
+}
+
+// newline returns the line ending opts selects.
+func (opts CSVOptions) newline() string {
+	if opts.CRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// ParseCSVDelimiter accepts "comma", "semicolon", "tab", "pipe" or a single
+// character and returns the delimiter rune. An empty name means comma.
+func ParseCSVDelimiter(name string) (rune, error) {
+	switch strings.ToLower(name) {
+	case "", "comma", ",":
+		return ',', nil
+	case "semicolon", ";":
+		return ';', nil
+	case "tab", `\t`, "\t":
+		return '\t', nil
+	case "pipe", "|":
+		return '|', nil
+	}
+	if r := []rune(name); len(r) == 1 && r[0] != '"' && r[0] != '\r' && r[0] != '\n' {
+		return r[0], nil
+	}
+	return 0, fmt.Errorf("invalid CSV delimiter %q (use comma, semicolon, tab, pipe or a single character)", name)
+}
+
+// WriteCSV writes results in CSV format with headers. cols selects and orders
+// the columns; when omitted the default column set is used. It returns the
+// first write error, e.g. a full disk or closed pipe.
+func WriteCSV(w io.Writer, rows []ResultRow, cols ...Column) error {
+	return WriteCSVWithOptions(w, rows, CSVOptions{}, cols...)
+}
+
+// WriteCSVWithOptions is WriteCSV with a configurable dialect.
+func WriteCSVWithOptions(w io.Writer, rows []ResultRow, opts CSVOptions, cols ...Column) error {
+	cols = columnsOrDefault(cols, defaultColumnKeys)
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+	}
+	if opts.BOM {
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return err
+		}
+	}
+	if opts.QuoteAll {
+		return writeCSVQuoted(w, rows, opts, cols)
+	}
 
-	_ = writer.Write([]string{"Org", "Network", "Switch", "Serial", "Port", "AggrPorts", "MAC", "IP", "Hostname", "LastSeen", "Uplink"})
+	writer := csv.NewWriter(w)
+	writer.Comma = opts.Delimiter
+	writer.UseCRLF = opts.CRLF
+	if err := writer.Write(headers(cols)); err != nil {
+		return err
+	}
 	for _, row := range rows {
-		uplinkStr := ""
-		if row.IsUplink {
-			uplinkStr = "yes"
+		if err := writer.Write(rowValues(row, cols)); err != nil {
+			return err
 		}
-		_ = writer.Write([]string{
-			row.OrgName, row.NetworkName, row.SwitchName, row.SwitchSerial,
-			row.Port, aggrPortsStr(row), row.MAC, row.IP, row.Hostname, row.LastSeen, uplinkStr,
-		})
 	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeCSVQuoted writes CSV with every field quoted; encoding/csv only quotes
+// fields that need it. Like encoding/csv, CRLF mode also turns line breaks
+// inside fields into \r\n.
+func writeCSVQuoted(w io.Writer, rows []ResultRow, opts CSVOptions, cols []Column) error {
+	ew := &errWriter{w: w}
+	escape := strings.NewReplacer(`"`, `""`)
+	if opts.CRLF {
+		escape = strings.NewReplacer(`"`, `""`, "\r", "", "\n", "\r\n")
+	}
+	line := func(values []string) {
+		for i, v := range values {
+			values[i] = `"` + escape.Replace(v) + `"`
+		}
+		if ew.err == nil {
+			_, ew.err = io.WriteString(ew.w, strings.Join(values, string(opts.Delimiter))+opts.newline())
+		}
+	}
+	line(headers(cols))
+	for _, row := range rows {
+		line(rowValues(row, cols))
+	}
+	return ew.err
 }
 
 // WriteText writes results in plain text table format with aligned columns.
-func WriteText(w io.Writer, rows []ResultRow) {
+// cols selects and orders the columns; when omitted the default set is used.
+// It returns the first write error.
+func WriteText(w io.Writer, rows []ResultRow, cols ...Column) error {
+	ew := &errWriter{w: w}
 	if len(rows) == 0 {
-		_, _ = fmt.Fprintln(w, "No results")
-		return
+		ew.println("No results")
+		return ew.err
 	}
 
-	headers := []string{"Org", "Network", "Switch", "Serial", "Port", "AggrPorts", "MAC", "IP", "Hostname", "LastSeen", "Uplink"}
-	widths := make([]int, len(headers))
-	for i, h := range headers {
+	cols = columnsOrDefault(cols, defaultColumnKeys)
+	hdrs := headers(cols)
+	widths := make([]int, len(cols))
+	for i, h := range hdrs {
 		widths[i] = len(h)
 	}
-	for _, row := range rows {
-		widths[0] = max(widths[0], len(row.OrgName))
-		widths[1] = max(widths[1], len(row.NetworkName))
-		widths[2] = max(widths[2], len(row.SwitchName))
-		widths[3] = max(widths[3], len(row.SwitchSerial))
-		widths[4] = max(widths[4], len(row.Port))
-		widths[5] = max(widths[5], len(aggrPortsStr(row)))
-		widths[6] = max(widths[6], len(row.MAC))
-		widths[7] = max(widths[7], len(row.IP))
-		widths[8] = max(widths[8], len(row.Hostname))
-		widths[9] = max(widths[9], len(row.LastSeen))
-		// widths[10] is "Uplink"/"yes"/"" — max is len("Uplink")=6
+	values := make([][]string, len(rows))
+	for r, row := range rows {
+		values[r] = rowValues(row, cols)
+		for i, v := range values[r] {
+			widths[i] = max(widths[i], len(v))
+		}
 	}
 
 	separator := strings.Repeat("-", sum(widths)+len(widths)*3-1)
-	_, _ = fmt.Fprintln(w, separator)
-	_, _ = fmt.Fprintln(w, formatRow(headers, widths))
-	_, _ = fmt.Fprintln(w, separator)
-	for _, row := range rows {
-		uplinkStr := ""
-		if row.IsUplink {
-			uplinkStr = "yes"
-		}
-		values := []string{row.OrgName, row.NetworkName, row.SwitchName, row.SwitchSerial, row.Port, aggrPortsStr(row), row.MAC, row.IP, row.Hostname, row.LastSeen, uplinkStr}
-		_, _ = fmt.Fprintln(w, formatRow(values, widths))
+	ew.println(separator)
+	ew.println(formatRow(hdrs, widths))
+	ew.println(separator)
+	for _, v := range values {
+		ew.println(formatRow(v, widths))
 	}
-	_, _ = fmt.Fprintln(w, separator)
+	ew.println(separator)
+	return ew.err
 }
 
-// WriteHTML writes results in HTML table format.
-func WriteHTML(w io.Writer, rows []ResultRow) {
-	_, _ = fmt.Fprintln(w, "<table>")
-	_, _ = fmt.Fprintln(w, "  <thead>")
-	_, _ = fmt.Fprintln(w, "    <tr>")
-	_, _ = fmt.Fprintln(w, "      <th>Org</th><th>Network</th><th>Switch</th><th>Serial</th><th>Port</th><th>AggrPorts</th><th>MAC</th><th>IP</th><th>Hostname</th><th>Last Seen</th><th>Uplink</th>")
-	_, _ = fmt.Fprintln(w, "    </tr>")
-	_, _ = fmt.Fprintln(w, "  </thead>")
-	_, _ = fmt.Fprintln(w, "  <tbody>")
+// WriteHTML writes results in HTML table format. cols selects and orders the
+// columns; when omitted the default set is used. It returns the first write error.
+func WriteHTML(w io.Writer, rows []ResultRow, cols ...Column) error {
+	cols = columnsOrDefault(cols, defaultColumnKeys)
+	var th strings.Builder
+	for _, c := range cols {
+		th.WriteString("<th>" + html.EscapeString(c.label()) + "</th>")
+	}
+	ew := &errWriter{w: w}
+	ew.println("<table>")
+	ew.println("  <thead>")
+	ew.println("    <tr>")
+	ew.println("      " + th.String())
+	ew.println("    </tr>")
+	ew.println("  </thead>")
+	ew.println("  <tbody>")
 	for _, row := range rows {
-		uplinkStr := ""
-		if row.IsUplink {
-			uplinkStr = "yes"
+		var td strings.Builder
+		for i, v := range rowValues(row, cols) {
+			td.WriteString("<td" + htmlCellStyle(cols[i].Key, row) + ">" + html.EscapeString(v) + "</td>")
 		}
-		_, _ = fmt.Fprintf(w, "    <tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
-			html.EscapeString(row.OrgName),
-			html.EscapeString(row.NetworkName),
-			html.EscapeString(row.SwitchName),
-			html.EscapeString(row.SwitchSerial),
-			html.EscapeString(row.Port),
-			html.EscapeString(aggrPortsStr(row)),
-			html.EscapeString(row.MAC),
-			html.EscapeString(row.IP),
-			html.EscapeString(row.Hostname),
-			html.EscapeString(row.LastSeen),
-			html.EscapeString(uplinkStr),
-		)
-	}
-	_, _ = fmt.Fprintln(w, "  </tbody>")
-	_, _ = fmt.Fprintln(w, "</table>")
+		ew.println("    <tr>" + td.String() + "</tr>")
+	}
+	ew.println("  </tbody>")
+	ew.println("</table>")
+	return ew.err
+}
+
+// htmlCellStyle returns an inline style attribute highlighting the confidence
+// band, or "" for every other cell.
+func htmlCellStyle(key string, row ResultRow) string {
+	if key != "confidence" || row.Confidence <= 0 {
+		return ""
+	}
+	switch {
+	case row.Confidence >= ConfidenceHigh:
+		return ` style="background:#dcfce7"`
+	case row.Confidence >= ConfidenceLow:
+		return ` style="background:#fef9c3"`
+	}
+	return ` style="background:#fee2e2"`
+}
+
+// errWriter writes lines until the first error, which it keeps so a writer can
+// check once at the end instead of after every line.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+// println writes s and a newline unless an earlier write failed.
+func (ew *errWriter) println(s string) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintln(ew.w, s)
 }
 
 // formatRow formats a row of values with column widths for text table output.
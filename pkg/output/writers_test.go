@@ -16,9 +16,19 @@
 package output
 
 import (
+	"archive/zip"
 	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestWriteCSV(t *testing.T) {
@@ -122,3 +132,757 @@ func TestWriteHTML(t *testing.T) {
 		t.Error("WriteHTML() missing hostname")
 	}
 }
+
+func TestQRPayload(t *testing.T) {
+	got := QRPayload([]ResultRow{{SwitchName: "sw1", SwitchSerial: "Q2", Port: "3", MAC: "00:11:22:33:44:55", VLAN: 10}})
+	want := `[{"sw":"sw1","sn":"Q2","p":"3","m":"00:11:22:33:44:55","v":10}]`
+	if got != want {
+		t.Errorf("QRPayload() = %s, want %s", got, want)
+	}
+}
+
+func TestWriteQR(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteQR(&buf, "hello"); err != nil {
+		t.Fatalf("WriteQR() error: %v", err)
+	}
+	if !strings.ContainsAny(buf.String(), "█▀▄") {
+		t.Error("WriteQR() output contains no block characters")
+	}
+	if err := WriteQR(&buf, strings.Repeat("x", 5000)); err == nil {
+		t.Error("WriteQR() with oversize payload should return an error")
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	rows := []ResultRow{
+		{OrgName: "Org", NetworkName: "HQ", SwitchName: "sw1", SwitchSerial: "S1", Port: "3", MAC: "00:11:22:33:44:55", VLAN: 10},
+		{OrgName: "Org", NetworkName: "HQ", SwitchName: "sw2", SwitchSerial: "S2", Port: "AGGR/1", AggrPorts: []string{"1", "2"}, MAC: "00:11:22:33:44:66", IsUplink: true},
+	}
+
+	var buf bytes.Buffer
+	WriteJSONL(&buf, rows)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("WriteJSONL() wrote %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	if want := `{"org":"Org","network":"HQ","switch":"sw1","serial":"S1","port":"3","mac":"00:11:22:33:44:55","vlan":10,"uplink":false}`; lines[0] != want {
+		t.Errorf("line 1 = %s\nwant     %s", lines[0], want)
+	}
+	if !strings.Contains(lines[1], `"aggrPorts":["1","2"]`) || !strings.Contains(lines[1], `"uplink":true`) {
+		t.Errorf("line 2 = %s", lines[1])
+	}
+}
+
+func TestWriteTerraformExternal(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []ResultRow
+		want map[string]string
+	}{
+		{
+			name: "best row",
+			rows: []ResultRow{
+				{OrgName: "Org", NetworkName: "HQ", SwitchName: "sw1", SwitchSerial: "S1", Port: "AGGR/1", AggrPorts: []string{"1", "2"}, MAC: "00:11:22:33:44:55", VLAN: 10, PortMode: "access", Confidence: 90},
+				{OrgName: "Org", NetworkName: "HQ", SwitchName: "sw2", SwitchSerial: "S2", Port: "49", MAC: "00:11:22:33:44:55", IsUplink: true},
+			},
+			want: map[string]string{"found": "true", "matches": "2", "switch": "sw1", "port": "AGGR/1", "aggr_ports": "1,2", "vlan": "10", "uplink": "false", "confidence": "90"},
+		},
+		{
+			name: "none",
+			want: map[string]string{"found": "false", "matches": "0", "switch": "", "port": "", "vlan": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteTerraformExternal(&buf, tt.rows); err != nil {
+				t.Fatalf("WriteTerraformExternal() error: %v", err)
+			}
+			var got map[string]string
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("output is not a JSON object of strings: %v\n%s", err, buf.String())
+			}
+			for k, v := range tt.want {
+				if g, ok := got[k]; !ok || g != v {
+					t.Errorf("%s = %q (present %v), want %q", k, g, ok, v)
+				}
+			}
+		})
+	}
+}
+
+func TestReadResults(t *testing.T) {
+	rows := []ResultRow{
+		{OrgName: "Org", NetworkName: "HQ", SwitchName: "sw1", SwitchSerial: "S1", Port: "3", MAC: "00:11:22:33:44:55", VLAN: 10, PortMode: "access", Source: SourceMacTable},
+		{OrgName: "Org", NetworkName: "HQ", SwitchName: "sw2", SwitchSerial: "S2", Port: "AGGR/1", AggrPorts: []string{"1", "2"}, MAC: "00:11:22:33:44:66", IsUplink: true},
+	}
+	writers := map[string]func(io.Writer, []ResultRow) error{
+		"jsonl": WriteJSONL,
+		"yaml":  WriteYAML,
+		"json array": func(w io.Writer, rows []ResultRow) error {
+			recs := make([]exportRow, len(rows))
+			for i, r := range rows {
+				recs[i] = toExportRow(r)
+			}
+			return json.NewEncoder(w).Encode(recs)
+		},
+	}
+	for name, write := range writers {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := write(&buf, rows); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadResults(&buf)
+			if err != nil {
+				t.Fatalf("ReadResults() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, rows) {
+				t.Errorf("ReadResults() =\n%+v\nwant\n%+v", got, rows)
+			}
+		})
+	}
+	if _, err := ReadResults(strings.NewReader(`{"mac":"00:11:22:33:44:55"}` + "\n{oops}\n")); err == nil {
+		t.Error("ReadResults() accepted a malformed jsonl row")
+	}
+}
+
+func TestMergeRows(t *testing.T) {
+	rows := []ResultRow{
+		{SwitchSerial: "S1", Port: "3", MAC: "00:11:22:33:44:55", IP: "10.0.0.5", Hostname: "pc-5", LastSeen: "2026-01-02T10:00:00Z", Source: SourceNetworkClients},
+		{SwitchSerial: "S2", Port: "49", MAC: "00:11:22:33:44:55", IsUplink: true, Source: SourceMacTable},
+		{SwitchSerial: "S1", Port: "3", MAC: "00:11:22:33:44:55", VLAN: 30, PortMode: "access", Source: SourceMacTable},
+		{SwitchSerial: "S3", Port: "7", MAC: "00:11:22:33:44:66", LastSeen: "2026-01-01T00:00:00Z", Source: SourceDeviceClients},
+		{SwitchSerial: "S3", Port: "7", MAC: "00:11:22:33:44:66", LastSeen: "2026-01-03T00:00:00Z", IP: "10.0.0.6", Source: SourceDeviceClients},
+	}
+	got := MergeRows(rows)
+	want := []ResultRow{
+		// The live MAC table row wins; IP, hostname and lastSeen come from client history.
+		{SwitchSerial: "S1", Port: "3", MAC: "00:11:22:33:44:55", IP: "10.0.0.5", Hostname: "pc-5", LastSeen: "2026-01-02T10:00:00Z", VLAN: 30, PortMode: "access", Source: SourceMacTable},
+		{SwitchSerial: "S2", Port: "49", MAC: "00:11:22:33:44:55", IsUplink: true, Source: SourceMacTable},
+		// Same source: the more recently seen row wins.
+		{SwitchSerial: "S3", Port: "7", MAC: "00:11:22:33:44:66", LastSeen: "2026-01-03T00:00:00Z", IP: "10.0.0.6", Source: SourceDeviceClients},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeRows() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	rows := []ResultRow{
+		{OrgName: "Org", NetworkName: "HQ", SwitchName: "sw<1>", SwitchSerial: "S1", Port: "3", MAC: "00:11:22:33:44:55", VLAN: 10},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, rows); err != nil {
+		t.Fatalf("WriteXLSX() error: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("WriteXLSX() did not produce a zip archive: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		if err := xml.Unmarshal(data, new(interface{})); err != nil {
+			t.Errorf("part %s is not well-formed XML: %v", f.Name, err)
+		}
+		parts[f.Name] = string(data)
+	}
+	for _, name := range []string{"[Content_Types].xml", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`state="frozen"`,
+		`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">Org</t>`,
+		`<t xml:space="preserve">00:11:22:33:44:55</t>`,
+		`<c r="J2" s="0"><v>10</v></c>`,
+		`sw&lt;1&gt;`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet missing %s", want)
+		}
+	}
+}
+
+func TestXLSXColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumnName(i); got != want {
+			t.Errorf("xlsxColumnName(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestAnonymizer(t *testing.T) {
+	a := NewAnonymizerWithKey([]byte("test-key"))
+	row := a.Row(ResultRow{SwitchName: "sw1", MAC: "00:11:22:33:44:55", IP: "10.0.0.5", Hostname: "alice-laptop"})
+
+	if row.SwitchName != "sw1" {
+		t.Errorf("Row() changed SwitchName to %q", row.SwitchName)
+	}
+	if !strings.HasPrefix(row.MAC, "02:") || len(row.MAC) != 17 || row.MAC == "00:11:22:33:44:55" {
+		t.Errorf("Row() MAC = %q, want locally administered stand-in", row.MAC)
+	}
+	if got := a.MAC("0011.2233.4455"); got != row.MAC {
+		t.Errorf("MAC() is not format-independent: %q vs %q", got, row.MAC)
+	}
+	if !strings.HasPrefix(row.IP, "ip-") || !strings.HasPrefix(row.Hostname, "host-") {
+		t.Errorf("Row() IP = %q, Hostname = %q", row.IP, row.Hostname)
+	}
+	if other := NewAnonymizerWithKey([]byte("other-key")).MAC("00:11:22:33:44:55"); other == row.MAC {
+		t.Error("different keys should give different tokens")
+	}
+
+	text := a.Text("Roaming history for 00:11:22:33:44:55 (alice-laptop) at 10.0.0.5\n")
+	if strings.Contains(text, "alice") || strings.Contains(text, "00:11:22") || strings.Contains(text, "10.0.0.5") {
+		t.Errorf("Text() leaked identity: %q", text)
+	}
+	if !strings.Contains(text, row.MAC) || !strings.Contains(text, row.Hostname) || !strings.Contains(text, row.IP) {
+		t.Errorf("Text() tokens inconsistent with Row(): %q", text)
+	}
+
+	var buf bytes.Buffer
+	_, _ = io.WriteString(a.Writer(&buf), "client 00:11:22:33:44:55\n")
+	if buf.String() != "client "+row.MAC+"\n" {
+		t.Errorf("Writer() wrote %q", buf.String())
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	rows := []ResultRow{
+		{OrgName: "Org", NetworkName: "HQ", SwitchName: "sw1", SwitchSerial: "S1", Port: "3", MAC: "00:11:22:33:44:55", Hostname: "yes", VLAN: 10},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteYAML(&buf, rows); err != nil {
+		t.Fatalf("WriteYAML() error: %v", err)
+	}
+	want := `- org: Org
+  network: HQ
+  switch: sw1
+  serial: S1
+  port: "3"
+  mac: "00:11:22:33:44:55"
+  hostname: "yes"
+  vlan: 10
+  uplink: false
+`
+	if buf.String() != want {
+		t.Errorf("WriteYAML() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteTemplate(t *testing.T) {
+	rows := []ResultRow{
+		{SwitchName: "sw1", Port: "3", MAC: "00:11:22:33:44:55", Hostname: "pc1"},
+		{SwitchName: "sw2", Port: "AGGR/1", AggrPorts: []string{"1", "2"}, MAC: "00:11:22:33:44:66"},
+	}
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{"one-liner gets newline", `{{.SwitchName}} {{.Port}}`, "sw1 3\nsw2 AGGR/1\n"},
+		{"funcs", `{{upper .MAC}} {{default "-" .Hostname}} {{join "+" .AggrPorts}}` + "\n",
+			"00:11:22:33:44:55 pc1 \n00:11:22:33:44:66 - 1+2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseRowTemplate(tt.tmpl)
+			if err != nil {
+				t.Fatalf("ParseRowTemplate() error: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := WriteTemplate(&buf, tmpl, rows); err != nil {
+				t.Fatalf("WriteTemplate() error: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("WriteTemplate() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseRowTemplate(`{{.MAC`); err == nil {
+		t.Error("ParseRowTemplate() should reject malformed templates")
+	}
+	tmpl, _ := ParseRowTemplate(`{{.NoSuchField}}`)
+	if err := WriteTemplate(io.Discard, tmpl, rows); err == nil {
+		t.Error("WriteTemplate() should fail on unknown fields")
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	cols, err := ParseColumns(" Switch, port ,mac,vlan,")
+	if err != nil {
+		t.Fatalf("ParseColumns() error: %v", err)
+	}
+	if got := strings.Join(headers(cols), ","); got != "Switch,Port,MAC,VLAN" {
+		t.Errorf("ParseColumns() headers = %s", got)
+	}
+	if cols, err := ParseColumns(""); cols != nil || err != nil {
+		t.Errorf("ParseColumns(\"\") = %v, %v; want nil, nil", cols, err)
+	}
+	if _, err := ParseColumns("mac,bogus"); err == nil || !strings.Contains(err.Error(), `"bogus"`) {
+		t.Errorf("ParseColumns(bogus) error = %v", err)
+	}
+}
+
+func TestWriters_SelectedColumns(t *testing.T) {
+	rows := []ResultRow{{SwitchName: "sw1", Port: "3", MAC: "00:11:22:33:44:55", VLAN: 20, OrgName: "Hidden Org"}}
+	cols, _ := ParseColumns("mac,switch,port,vlan")
+
+	var csvBuf, textBuf, htmlBuf bytes.Buffer
+	WriteCSV(&csvBuf, rows, cols...)
+	WriteText(&textBuf, rows, cols...)
+	WriteHTML(&htmlBuf, rows, cols...)
+
+	if want := "MAC,Switch,Port,VLAN\n00:11:22:33:44:55,sw1,3,20\n"; csvBuf.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", csvBuf.String(), want)
+	}
+	if !strings.Contains(textBuf.String(), "MAC               | Switch | Port | VLAN") {
+		t.Errorf("WriteText() header wrong:\n%s", textBuf.String())
+	}
+	if !strings.Contains(htmlBuf.String(), "<th>MAC</th><th>Switch</th><th>Port</th><th>VLAN</th>") {
+		t.Errorf("WriteHTML() header wrong:\n%s", htmlBuf.String())
+	}
+	for name, out := range map[string]string{"csv": csvBuf.String(), "text": textBuf.String(), "html": htmlBuf.String()} {
+		if strings.Contains(out, "Hidden Org") {
+			t.Errorf("%s output contains an unselected column", name)
+		}
+	}
+}
+
+// failWriter fails every write, like a full disk or a closed pipe.
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, io.ErrShortWrite }
+
+func TestWriters_ReturnWriteErrors(t *testing.T) {
+	rows := []ResultRow{{SwitchName: "sw1", Port: "3", MAC: "00:11:22:33:44:55"}}
+	writers := map[string]func(io.Writer) error{
+		"csv":        func(w io.Writer) error { return WriteCSV(w, rows) },
+		"text":       func(w io.Writer) error { return WriteText(w, rows) },
+		"text-empty": func(w io.Writer) error { return WriteText(w, nil) },
+		"html":       func(w io.Writer) error { return WriteHTML(w, rows) },
+		"jsonl":      func(w io.Writer) error { return WriteJSONL(w, rows) },
+		"xlsx":       func(w io.Writer) error { return WriteXLSX(w, rows) },
+		"yaml":       func(w io.Writer) error { return WriteYAML(w, rows) },
+	}
+	for name, write := range writers {
+		if err := write(failWriter{}); err == nil {
+			t.Errorf("%s writer returned nil error on a failing writer", name)
+		}
+		if err := write(io.Discard); err != nil {
+			t.Errorf("%s writer returned %v on a working writer", name, err)
+		}
+	}
+}
+
+func TestAtomicFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.csv")
+	if err := os.WriteFile(path, []byte("previous good run\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	aborted, err := CreateAtomic(path)
+	if err != nil {
+		t.Fatalf("CreateAtomic() error: %v", err)
+	}
+	_, _ = io.WriteString(aborted, "partial")
+	aborted.Abort()
+	if data, _ := os.ReadFile(path); string(data) != "previous good run\n" {
+		t.Errorf("Abort() changed the target: %q", data)
+	}
+
+	f, err := CreateAtomic(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteCSV(f, []ResultRow{{MAC: "00:11:22:33:44:55"}}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "previous good run\n" {
+		t.Error("target changed before Commit()")
+	}
+	if err := f.Commit(); err != nil {
+		t.Fatalf("Commit() error: %v", err)
+	}
+	f.Abort() // no-op after Commit
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "00:11:22:33:44:55") {
+		t.Errorf("Commit() target = %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temp files left behind: %v", entries)
+	}
+}
+
+func TestAtomicFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not meaningful on Windows")
+	}
+	dir := t.TempDir()
+	commit := func(f *AtomicFile, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.WriteString(f, "data\n")
+		if err := f.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mode := func(path string) os.FileMode {
+		t.Helper()
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Mode().Perm()
+	}
+
+	// A new file gets the same mode os.Create would give it.
+	probe := filepath.Join(dir, "probe")
+	if err := os.WriteFile(probe, nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	fresh := filepath.Join(dir, "fresh.csv")
+	commit(CreateAtomic(fresh))
+	if got, want := mode(fresh), mode(probe); got != want {
+		t.Errorf("new file mode = %v, want %v", got, want)
+	}
+
+	existing := filepath.Join(dir, "existing.csv")
+	if err := os.WriteFile(existing, nil, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(existing, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	commit(CreateAtomic(existing))
+	if got := mode(existing); got != 0o640 {
+		t.Errorf("replaced file mode = %v, want -rw-r-----", got)
+	}
+
+	secret := filepath.Join(dir, "secret.env")
+	if err := os.WriteFile(secret, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	commit(CreatePrivateAtomic(secret))
+	if got := mode(secret); got != 0o600 {
+		t.Errorf("private file mode = %v, want -rw-------", got)
+	}
+}
+
+func TestWriteCSVWithOptions(t *testing.T) {
+	rows := []ResultRow{{SwitchName: "sw;1", Port: "3", Hostname: `say "hi"`}}
+	cols, _ := ParseColumns("switch,port,hostname")
+	tests := []struct {
+		name string
+		opts CSVOptions
+		want string
+	}{
+		{"default", CSVOptions{}, "Switch,Port,Hostname\nsw;1,3,\"say \"\"hi\"\"\"\n"},
+		{"semicolon", CSVOptions{Delimiter: ';'}, "Switch;Port;Hostname\n\"sw;1\";3;\"say \"\"hi\"\"\"\n"},
+		{"bom", CSVOptions{BOM: true}, "\uFEFFSwitch,Port,Hostname\nsw;1,3,\"say \"\"hi\"\"\"\n"},
+		{"quote all tab", CSVOptions{Delimiter: '\t', QuoteAll: true}, "\"Switch\"\t\"Port\"\t\"Hostname\"\n\"sw;1\"\t\"3\"\t\"say \"\"hi\"\"\"\n"},
+		{"crlf", CSVOptions{CRLF: true}, "Switch,Port,Hostname\r\nsw;1,3,\"say \"\"hi\"\"\"\r\n"},
+		{"excel europe", CSVOptions{Delimiter: ';', BOM: true, CRLF: true}, "\uFEFFSwitch;Port;Hostname\r\n\"sw;1\";3;\"say \"\"hi\"\"\"\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteCSVWithOptions(&buf, rows, tt.opts, cols...); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got  %q\nwant %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCSVQuotingEdgeCases(t *testing.T) {
+	rows := []ResultRow{{SwitchName: "line1\nline2", Port: " 3 ", Hostname: "", Note: "a,b;c\r\nd"}}
+	cols, _ := ParseColumns("switch,port,hostname,note")
+	tests := []struct {
+		name string
+		opts CSVOptions
+		want string
+	}{
+		{"default", CSVOptions{}, "Switch,Port,Hostname,Note\n\"line1\nline2\",\" 3 \",,\"a,b;c\r\nd\"\n"},
+		{"semicolon", CSVOptions{Delimiter: ';'}, "Switch;Port;Hostname;Note\n\"line1\nline2\";\" 3 \";;\"a,b;c\r\nd\"\n"},
+		{"crlf", CSVOptions{CRLF: true}, "Switch,Port,Hostname,Note\r\n\"line1\r\nline2\",\" 3 \",,\"a,b;c\r\nd\"\r\n"},
+		{"quote all", CSVOptions{QuoteAll: true}, "\"Switch\",\"Port\",\"Hostname\",\"Note\"\n\"line1\nline2\",\" 3 \",\"\",\"a,b;c\r\nd\"\n"},
+		{"quote all crlf", CSVOptions{QuoteAll: true, CRLF: true}, "\"Switch\",\"Port\",\"Hostname\",\"Note\"\r\n\"line1\r\nline2\",\" 3 \",\"\",\"a,b;c\r\nd\"\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteCSVWithOptions(&buf, rows, tt.opts, cols...); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got  %q\nwant %q", buf.String(), tt.want)
+			}
+			// Whatever the dialect, the output reads back as the original values.
+			r := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\uFEFF")))
+			r.Comma = tt.opts.Delimiter
+			if r.Comma == 0 {
+				r.Comma = ','
+			}
+			records, err := r.ReadAll()
+			if err != nil || len(records) != 2 {
+				t.Fatalf("reading back: %v, %d records", err, len(records))
+			}
+			want := []string{"line1\nline2", " 3 ", "", "a,b;c\nd"}
+			for i := range want {
+				if records[1][i] != want[i] {
+					t.Errorf("field %d read back as %q, want %q", i, records[1][i], want[i])
+				}
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSVWarnings(&buf, CSVOptions{CRLF: true}, []Warning{(&Truncation{Shown: 1, Total: 2}).AsWarning()}); err != nil || !strings.HasSuffix(buf.String(), "pattern to see the rest\r\n") {
+		t.Errorf("WriteCSVWarnings(CRLF) = %q, %v", buf.String(), err)
+	}
+}
+
+func TestParseCSVDelimiter(t *testing.T) {
+	for in, want := range map[string]rune{"": ',', "comma": ',', "Semicolon": ';', ";": ';', "tab": '\t', `\t`: '\t', "pipe": '|', "#": '#'} {
+		if got, err := ParseCSVDelimiter(in); err != nil || got != want {
+			t.Errorf("ParseCSVDelimiter(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{`"`, "\n", "colon-ish"} {
+		if _, err := ParseCSVDelimiter(in); err == nil {
+			t.Errorf("ParseCSVDelimiter(%q) should fail", in)
+		}
+	}
+}
+
+func TestWriteColorText(t *testing.T) {
+	rows := []ResultRow{
+		{SwitchName: "sw1", Port: "3", MAC: "aa:bb:cc:dd:ee:ff", PortMode: "access"},
+		{SwitchName: "sw2", Port: "49", MAC: "aa:bb:cc:dd:ee:ff", PortMode: "trunk", IsUplink: true},
+	}
+	cols, _ := ParseColumns("switch,port,mac,hostname")
+	var buf bytes.Buffer
+	if err := WriteColorText(&buf, rows, cols...); err != nil {
+		t.Fatalf("WriteColorText() error: %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		ansiBold + "Switch",
+		ansiGreen + "3   " + ansiReset,
+		ansiMagenta + "49  " + ansiReset,
+		ansiBold + ansiCyan + "aa:bb:cc:dd:ee:ff" + ansiReset,
+		ansiDim + "-       " + ansiReset,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteColorText() missing %q in:\n%q", want, got)
+		}
+	}
+
+	// Stripping the escapes must leave a table aligned like WriteText's.
+	var plain bytes.Buffer
+	_ = WriteText(&plain, rows, cols...)
+	stripped := got
+	for _, seq := range []string{ansiReset, ansiBold, ansiDim, ansiGreen, ansiYellow, ansiMagenta, ansiCyan} {
+		stripped = strings.ReplaceAll(stripped, seq, "")
+	}
+	gotLines, plainLines := strings.Split(stripped, "\n"), strings.Split(plain.String(), "\n")
+	if len(gotLines) != len(plainLines) || len(gotLines[1]) != len(plainLines[1]) {
+		t.Errorf("colored table is misaligned:\n%s\nvs plain:\n%s", stripped, plain.String())
+	}
+}
+
+func TestParseColorMode(t *testing.T) {
+	for in, want := range map[string]string{"": ColorAuto, "AUTO": ColorAuto, "always": ColorAlways, " never ": ColorNever} {
+		if got, err := ParseColorMode(in); err != nil || got != want {
+			t.Errorf("ParseColorMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseColorMode("sometimes"); err == nil {
+		t.Error("ParseColorMode(sometimes) succeeded, want error")
+	}
+}
+
+func TestScoreRows(t *testing.T) {
+	now := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	rows := []ResultRow{
+		// 0: live MAC table, access edge port, only edge sighting
+		{SwitchSerial: "S1", Port: "12", MAC: "aa:aa:aa:aa:aa:aa", PortMode: "access", Source: SourceMacTable},
+		// 1: same MAC seen on an uplink toward the core
+		{SwitchSerial: "S2", Port: "49", MAC: "aa:aa:aa:aa:aa:aa", PortMode: "trunk", IsUplink: true, Source: SourceMacTable},
+		// 2: device-clients history from last week, duplicated on two edge ports
+		{SwitchSerial: "S3", Port: "5", MAC: "bb:bb:bb:bb:bb:bb", PortMode: "access", Source: SourceDeviceClients, LastSeen: "2026-02-25T15:00:00Z"},
+		{SwitchSerial: "S4", Port: "6", MAC: "bb:bb:bb:bb:bb:bb", PortMode: "access", Source: SourceDeviceClients, LastSeen: "2026-02-25T15:00:00Z"},
+		// 4: network clients seen ten minutes ago as a Unix timestamp
+		{SwitchSerial: "S5", Port: "7", MAC: "cc:cc:cc:cc:cc:cc", Source: SourceNetworkClients, LastSeen: "1772463000"},
+		// 5: management MAC of a Meraki device
+		{SwitchSerial: "S6", MAC: "dd:dd:dd:dd:dd:dd", Source: SourceDevice},
+	}
+	ScoreRows(rows, now)
+
+	want := []int{100, 70, 47, 47, 82, 95}
+	for i, w := range want {
+		if rows[i].Confidence != w {
+			t.Errorf("row %d Confidence = %d, want %d", i, rows[i].Confidence, w)
+		}
+	}
+	if rows[0].Confidence < ConfidenceHigh || rows[2].Confidence >= ConfidenceHigh {
+		t.Errorf("bands wrong: edge=%d duplicate=%d", rows[0].Confidence, rows[2].Confidence)
+	}
+}
+
+func TestConfidenceHighlight(t *testing.T) {
+	rows := []ResultRow{{MAC: "aa", Confidence: 90}, {MAC: "bb", Confidence: 50}, {MAC: "cc", Confidence: 10}}
+	cols, _ := ParseColumns("mac,confidence")
+
+	var text bytes.Buffer
+	_ = WriteColorText(&text, rows, cols...)
+	for _, want := range []string{ansiBold + ansiGreen + "90", ansiYellow + "50", ansiRed + "10"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("WriteColorText() missing %q in:\n%q", want, text.String())
+		}
+	}
+
+	var html bytes.Buffer
+	_ = WriteHTML(&html, rows, cols...)
+	for _, want := range []string{`<td style="background:#dcfce7">90</td>`, `<td style="background:#fef9c3">50</td>`, `<td style="background:#fee2e2">10</td>`, "<td>aa</td>"} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("WriteHTML() missing %q", want)
+		}
+	}
+}
+
+func TestWriteExplain(t *testing.T) {
+	rows := []ResultRow{
+		{
+			NetworkName: "HQ", SwitchName: "sw1", Port: "3", MAC: "00:11:22:33:44:55", Confidence: 92,
+			Explain: []FieldSource{
+				{Field: "Port", Source: "live MAC table", Detail: "job L_1 at 10:00:00"},
+				{Field: "VLAN", Source: "switch port config"},
+			},
+		},
+		{SwitchSerial: "Q2", MAC: "00:11:22:33:44:66"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteExplain(&buf, rows); err != nil {
+		t.Fatalf("WriteExplain() error: %v", err)
+	}
+	want := "[1] 00:11:22:33:44:55 on sw1 port 3 (HQ), confidence 92\n" +
+		"    Port  live MAC table (job L_1 at 10:00:00)\n" +
+		"    VLAN  switch port config\n" +
+		"[2] 00:11:22:33:44:66 on Q2 port -\n" +
+		"    (no provenance recorded)\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteExplain() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	rows := []ResultRow{{MAC: "00:11:22:33:44:01"}, {MAC: "00:11:22:33:44:02"}, {MAC: "00:11:22:33:44:03"}}
+	if got, tr := Truncate(rows, 3); len(got) != 3 || tr != nil {
+		t.Errorf("Truncate(3 rows, 3) = %d rows, %v; want all rows and no truncation", len(got), tr)
+	}
+	if got, tr := Truncate(rows, 0); len(got) != 3 || tr != nil {
+		t.Errorf("Truncate(rows, 0) = %d rows, %v; want no limit", len(got), tr)
+	}
+	got, tr := Truncate(rows, 2)
+	if len(got) != 2 || got[1].MAC != "00:11:22:33:44:02" || tr == nil || *tr != (Truncation{Shown: 2, Total: 3}) {
+		t.Fatalf("Truncate(rows, 2) = %v, %+v", got, tr)
+	}
+
+	for format, want := range map[string]string{
+		"text":  "WARNING: results truncated: showing the first 2 of 3 matches; refine your pattern to see the rest\n",
+		"csv":   "# WARNING: results truncated",
+		"yaml":  "# WARNING: results truncated",
+		"html":  `<p class="warning"><strong>Warning:</strong> results truncated`,
+		"jsonl": `{"truncated":true,"shown":2,"total":3,"warning":"results truncated`,
+	} {
+		var buf bytes.Buffer
+		if err := WriteTruncation(&buf, format, tr); err != nil || !strings.HasPrefix(buf.String(), want) {
+			t.Errorf("WriteTruncation(%s) = %q, %v; want prefix %q", format, buf.String(), err, want)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteTruncation(&buf, "terraform-external", tr); err != nil || buf.Len() != 0 {
+		t.Errorf("WriteTruncation(terraform-external) = %q, %v; want nothing", buf.String(), err)
+	}
+	if err := WriteTruncation(&buf, "text", nil); err != nil || buf.Len() != 0 {
+		t.Errorf("WriteTruncation(nil) = %q, %v; want nothing", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := WriteXLSXWithFooter(&buf, got, tr.Warning()); err != nil {
+		t.Fatalf("WriteXLSXWithFooter() error: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, _ := f.Open()
+		sheet, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		if !strings.Contains(string(sheet), `<c r="A5" s="0" t="inlineStr"><is><t xml:space="preserve">results truncated`) {
+			t.Errorf("sheet has no footer below the rows:\n%s", sheet)
+		}
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	var none *Warnings
+	none.Add(Warning{Kind: WarningStaleData, Message: "ignored"})
+	if none.List() != nil {
+		t.Error("nil *Warnings should discard what is added")
+	}
+
+	ws := &Warnings{}
+	stale := Warning{Kind: WarningStaleData, Message: "live MAC table of sw1 in HQ was unavailable", Network: "HQ", Device: "Q2AA-0001"}
+	ws.Add(stale)
+	ws.Add(stale)
+	ws.Add((&Truncation{Shown: 1, Total: 2}).AsWarning())
+	list := ws.List()
+	if len(list) != 2 || list[0] != stale || list[1].Kind != WarningTruncated {
+		t.Fatalf("List() = %+v, want the stale warning once, then the truncation", list)
+	}
+
+	for format, want := range map[string]string{
+		"text":  "WARNING: live MAC table of sw1 in HQ was unavailable\nWARNING: results truncated",
+		"csv":   "# WARNING: live MAC table of sw1 in HQ was unavailable\n# WARNING: results truncated",
+		"yaml":  "# WARNING: live MAC table",
+		"html":  "<section class=\"warnings\">\n<h2>Warnings</h2>\n<p class=\"warning\"><strong>Warning:</strong> live MAC table",
+		"jsonl": `{"warnings":[{"kind":"stale-data","message":"live MAC table of sw1 in HQ was unavailable","network":"HQ","device":"Q2AA-0001"},{"kind":"truncated"`,
+	} {
+		var buf bytes.Buffer
+		if err := WriteWarnings(&buf, format, list); err != nil || !strings.HasPrefix(buf.String(), want) {
+			t.Errorf("WriteWarnings(%s) = %q, %v; want prefix %q", format, buf.String(), err, want)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteWarnings(&buf, "text", nil); err != nil || buf.Len() != 0 {
+		t.Errorf("WriteWarnings(none) = %q, %v; want nothing", buf.String(), err)
+	}
+	if got := WarningsFooter(list); strings.Count(got, "WARNING: ") != 2 || strings.Count(got, "\n") != 1 {
+		t.Errorf("WarningsFooter() = %q, want two lines", got)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// xlsxMaxColWidth caps auto-sized column widths (in characters).
+const xlsxMaxColWidth = 60
+
+// xlsxStatic holds the fixed package parts of a single-sheet workbook.
+// Style 1 (cellXfs index 1) is the bold, shaded header style.
+var xlsxStatic = map[string]string{
+	"[Content_Types].xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`,
+	"_rels/.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`,
+	"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Results" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+	"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`,
+	"xl/styles.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><color rgb="FFFFFFFF"/><name val="Calibri"/></font></fonts><fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill><fill><patternFill patternType="solid"><fgColor rgb="FF1F4E79"/><bgColor indexed="64"/></patternFill></fill></fills><borders count="1"><border/></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/></cellXfs></styleSheet>`,
+}
+
+// xlsxPartOrder keeps the archive layout deterministic.
+var xlsxPartOrder = []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"}
+
+// xlsxDefaultColumnKeys are the workbook columns when no selection is given;
+// unlike CSV they include VLAN and port mode.
+var xlsxDefaultColumnKeys = []string{"org", "network", "switch", "serial", "port", "aggrports", "mac", "ip", "hostname", "vlan", "portmode", "lastseen", "uplink", "note", "confidence"}
+
+// WriteXLSX writes results as an Excel workbook with a bold header row, the
+// header frozen in place and columns sized to their content. Every cell except
+// VLAN is stored as text so MACs and ports keep their exact formatting. cols
+// selects and orders the columns; when omitted the default set is used.
+func WriteXLSX(w io.Writer, rows []ResultRow, cols ...Column) error {
+	return WriteXLSXWithFooter(w, rows, "", cols...)
+}
+
+// WriteXLSXWithFooter is WriteXLSX with footer, such as a truncation warning,
+// written below the rows after a blank line, one row per line of footer. An
+// empty footer adds nothing.
+func WriteXLSXWithFooter(w io.Writer, rows []ResultRow, footer string, cols ...Column) error {
+	cols = columnsOrDefault(cols, xlsxDefaultColumnKeys)
+	header := headers(cols)
+	numericCol := -1
+	widths := make([]int, len(header))
+	for i, c := range cols {
+		widths[i] = utf8.RuneCountInString(c.Header)
+		if c.Key == "vlan" {
+			numericCol = i
+		}
+	}
+	records := make([][]string, len(rows))
+	for r, row := range rows {
+		records[r] = rowValues(row, cols)
+		for i, v := range records[r] {
+			widths[i] = max(widths[i], utf8.RuneCountInString(v))
+		}
+	}
+
+	var sheet strings.Builder
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	sheet.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	sheet.WriteString(`<cols>`)
+	for i, wd := range widths {
+		fmt.Fprintf(&sheet, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, min(wd, xlsxMaxColWidth)+2)
+	}
+	sheet.WriteString(`</cols><sheetData>`)
+	writeXLSXRow(&sheet, 1, header, -1, 1)
+	for r, rec := range records {
+		writeXLSXRow(&sheet, r+2, rec, numericCol, 0)
+	}
+	if footer != "" {
+		for i, line := range strings.Split(footer, "\n") {
+			writeXLSXRow(&sheet, len(records)+3+i, []string{line}, -1, 0)
+		}
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	zw := zip.NewWriter(w)
+	for _, name := range xlsxPartOrder {
+		if err := writeZipPart(zw, name, xlsxStatic[name]); err != nil {
+			return err
+		}
+	}
+	if err := writeZipPart(zw, "xl/worksheets/sheet1.xml", sheet.String()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeXLSXRow appends one <row>. The cell at numericCol is written as a number
+// when it parses as one; all other cells are inline strings with style s.
+func writeXLSXRow(b *strings.Builder, rowNum int, values []string, numericCol, style int) {
+	fmt.Fprintf(b, `<row r="%d">`, rowNum)
+	for i, v := range values {
+		ref := xlsxColumnName(i) + strconv.Itoa(rowNum)
+		if i == numericCol {
+			if _, err := strconv.Atoi(v); err == nil {
+				fmt.Fprintf(b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, v)
+				continue
+			}
+		}
+		fmt.Fprintf(b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+		_ = xml.EscapeText(b, []byte(v))
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+}
+
+// xlsxColumnName converts a zero-based column index to its letter name (0 → A, 26 → AA).
+func xlsxColumnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+// writeZipPart stores one package part in the archive.
+func writeZipPart(zw *zip.Writer, name, content string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, content)
+	return err
+}
@@ -0,0 +1,37 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package output
+
+import (
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WriteYAML writes results as a YAML sequence of mappings using the same keys
+// as the jsonl format, ready for Ansible vars files and similar tooling.
+func WriteYAML(w io.Writer, rows []ResultRow) error {
+	records := make([]exportRow, len(rows))
+	for i, row := range rows {
+		records[i] = toExportRow(row)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(records); err != nil {
+		return err
+	}
+	return enc.Close()
+}
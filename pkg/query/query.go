@@ -0,0 +1,343 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package query parses the small search language used by `find` and --query,
+// e.g. `vlan=30 AND vendor~'Axis' AND network='HQ'`, and evaluates it against
+// result fields.
+//
+// Grammar (keywords are case-insensitive):
+//
+//	expr       = term { "OR" term }
+//	term       = factor { "AND" factor }
+//	factor     = "NOT" factor | "(" expr ")" | comparison
+//	comparison = field op value
+//	op         = "=" | "!=" | "~" | "!~" | "<" | "<=" | ">" | ">="
+//
+// Values are quoted with ' or ", or bare words such as 30 or 00:11:22:*.
+// = and != compare case-insensitively and accept * and ? wildcards; ~ and !~
+// test for a case-insensitive substring; <, <=, > and >= compare numbers.
+package query
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Fields lists the field names a query may use.
+var Fields = []string{
+	"mac", "ip", "hostname", "vendor", "vlan", "network", "switch", "serial",
+	"port", "mode", "uplink", "source", "confidence", "lastseen",
+}
+
+// Op is a comparison operator.
+type Op string
+
+// Comparison operators.
+const (
+	OpEq       Op = "="
+	OpNe       Op = "!="
+	OpContains Op = "~"
+	OpNotMatch Op = "!~"
+	OpLt       Op = "<"
+	OpLe       Op = "<="
+	OpGt       Op = ">"
+	OpGe       Op = ">="
+)
+
+// Condition is one field comparison.
+type Condition struct {
+	Field string // lower-case name from Fields
+	Op    Op
+	Value string
+}
+
+// Query is a parsed search expression.
+type Query struct {
+	root node
+}
+
+// node is an expression tree node; get returns a field's value for a row.
+type node interface {
+	eval(get func(field string) string) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ inner node }
+
+func (n andNode) eval(get func(string) string) bool { return n.left.eval(get) && n.right.eval(get) }
+func (n orNode) eval(get func(string) string) bool  { return n.left.eval(get) || n.right.eval(get) }
+func (n notNode) eval(get func(string) string) bool { return !n.inner.eval(get) }
+
+func (c Condition) eval(get func(string) string) bool {
+	return c.Matches(get(c.Field))
+}
+
+// Matches reports whether a field value satisfies the condition.
+func (c Condition) Matches(value string) bool {
+	v, want := strings.ToLower(strings.TrimSpace(value)), strings.ToLower(c.Value)
+	switch c.Op {
+	case OpEq:
+		return equal(v, want)
+	case OpNe:
+		return !equal(v, want)
+	case OpContains:
+		return strings.Contains(v, want)
+	case OpNotMatch:
+		return !strings.Contains(v, want)
+	}
+	got, err1 := strconv.ParseFloat(v, 64)
+	limit, err2 := strconv.ParseFloat(want, 64)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	switch c.Op {
+	case OpLt:
+		return got < limit
+	case OpLe:
+		return got <= limit
+	case OpGt:
+		return got > limit
+	}
+	return got >= limit
+}
+
+// equal compares numbers numerically, wildcard patterns with path.Match and
+// anything else as strings.
+func equal(v, want string) bool {
+	if a, err := strconv.ParseFloat(v, 64); err == nil {
+		if b, err := strconv.ParseFloat(want, 64); err == nil {
+			return a == b
+		}
+	}
+	if strings.ContainsAny(want, "*?") {
+		ok, _ := path.Match(want, v)
+		return ok
+	}
+	return v == want
+}
+
+// Match evaluates the query; get returns the value of a field for the row
+// being tested.
+func (q *Query) Match(get func(field string) string) bool {
+	return q.root.eval(get)
+}
+
+// Required returns the conditions every match must satisfy: those joined to
+// the top level by AND only. Callers use them to narrow a search before
+// evaluating the whole query on each result.
+func (q *Query) Required() []Condition {
+	var out []Condition
+	var walk func(n node)
+	walk = func(n node) {
+		switch n := n.(type) {
+		case andNode:
+			walk(n.left)
+			walk(n.right)
+		case Condition:
+			out = append(out, n)
+		}
+	}
+	walk(q.root)
+	return out
+}
+
+// Parse parses a query. Errors name the offending token and its position.
+func Parse(input string) (*Query, error) {
+	toks, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return nil, fmt.Errorf("empty query")
+	}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+	}
+	return &Query{root: root}, nil
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokWord && strings.EqualFold(t.text, kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expr() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) term() (node, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) factor() (node, error) {
+	if p.keyword("NOT") {
+		inner, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tokRParen {
+			return nil, fmt.Errorf("missing ) at position %d", c.pos+1)
+		}
+		return inner, nil
+	case tokWord:
+		field := strings.ToLower(t.text)
+		if !knownField(field) {
+			return nil, fmt.Errorf("unknown field %q at position %d (fields: %s)", t.text, t.pos+1, strings.Join(Fields, ", "))
+		}
+		op := p.next()
+		if op.kind != tokOp {
+			return nil, fmt.Errorf("expected an operator after %s at position %d", t.text, op.pos+1)
+		}
+		val := p.next()
+		if val.kind != tokWord && val.kind != tokString {
+			return nil, fmt.Errorf("expected a value after %s%s at position %d", t.text, op.text, val.pos+1)
+		}
+		return Condition{Field: field, Op: Op(op.text), Value: val.text}, nil
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+}
+
+func knownField(f string) bool {
+	for _, k := range Fields {
+		if f == k {
+			return true
+		}
+	}
+	return false
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits input into words, quoted strings, operators and parentheses.
+func lex(input string) ([]token, error) {
+	var toks []token
+	rs := []rune(input)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(rs) && rs[end] != r {
+				end++
+			}
+			if end == len(rs) {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			toks = append(toks, token{tokString, string(rs[i+1 : end]), i})
+			i = end + 1
+		case strings.ContainsRune("=!~<>", r):
+			op := string(r)
+			if i+1 < len(rs) && (r != '=' && r != '~' && rs[i+1] == '=' || r == '!' && rs[i+1] == '~') {
+				op += string(rs[i+1])
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unknown operator %q at position %d", op, i+1)
+			}
+			toks = append(toks, token{tokOp, op, i})
+			i += len(op)
+		default:
+			start := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) && !strings.ContainsRune("()'\"=!~<>", rs[i]) {
+				i++
+			}
+			toks = append(toks, token{tokWord, string(rs[start:i]), start})
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(rs)}), nil
+}
@@ -0,0 +1,107 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr string
+	}{
+		{"", "empty query"},
+		{"vlan=30 AND", "unexpected end"},
+		{"colour=red", `unknown field "colour"`},
+		{"vlan 30", "expected an operator"},
+		{"vlan=", "expected a value"},
+		{"vendor~'Axis", "unterminated string"},
+		{"(vlan=30", "missing )"},
+		{"vlan=30)", `unexpected ")"`},
+		{"vlan==30", "expected a value"},
+		{"vlan ! 30", "unknown operator"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	row := map[string]string{
+		"mac": "00:40:8c:12:34:56", "vlan": "30", "vendor": "Axis Communications AB",
+		"network": "HQ", "port": "12", "mode": "access", "confidence": "82", "uplink": "false",
+	}
+	get := func(f string) string { return row[f] }
+
+	tests := []struct {
+		q    string
+		want bool
+	}{
+		{"vlan=30 AND vendor~'Axis' AND network='HQ'", true},
+		{"VLAN = 30 and Network = hq", true},
+		{"vlan=030", true},
+		{"vlan!=30", false},
+		{"vlan=31 OR vendor~axis", true},
+		{"vlan=31 OR vendor~hikvision", false},
+		{"NOT mode=trunk", true},
+		{"NOT (mode=access AND uplink=false)", false},
+		{"vlan=30 AND (network=Branch OR network=HQ)", true},
+		{`mac="00:40:8c:*"`, true},
+		{"mac=00:40:8d:*", false},
+		{"vendor!~hikvision", true},
+		{"confidence>=75", true},
+		{"confidence<75", false},
+		{"confidence>82 OR confidence<=82", true},
+		{"hostname=''", true},
+		{"hostname~x", false},
+		{"network>5", false},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.q)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.q, err)
+			continue
+		}
+		if got := q.Match(get); got != tt.want {
+			t.Errorf("%q matched = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestRequired(t *testing.T) {
+	q, err := Parse("network='HQ' AND (vlan=30 OR vlan=31) AND NOT mode=trunk AND vendor~Axis")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	want := []Condition{
+		{Field: "network", Op: OpEq, Value: "HQ"},
+		{Field: "vendor", Op: OpContains, Value: "Axis"},
+	}
+	if got := q.Required(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Required() = %+v, want %+v", got, want)
+	}
+
+	q, _ = Parse("vlan=30 OR network=HQ")
+	if got := q.Required(); len(got) != 0 {
+		t.Errorf("Required() of an OR = %+v, want none", got)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+// portNameChange is one port rename from the import CSV.
+type portNameChange struct {
+	Serial  string
+	PortID  string
+	OldName string
+	NewName string
+}
+
+// parsePortNamesCSV reads serial,port,name rows. A header row whose first
+// column is "serial" is skipped; blank serials and ports are rejected with the
+// offending line number.
+func parsePortNamesCSV(r io.Reader) ([]portNameChange, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 3
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var changes []portNameChange
+	for i, rec := range records {
+		serial, port, name := strings.TrimSpace(rec[0]), strings.TrimSpace(rec[1]), strings.TrimSpace(rec[2])
+		if i == 0 && strings.EqualFold(serial, "serial") {
+			continue
+		}
+		if serial == "" || port == "" {
+			return nil, fmt.Errorf("line %d: serial and port are required", i+1)
+		}
+		changes = append(changes, portNameChange{Serial: strings.ToUpper(serial), PortID: port, NewName: name})
+	}
+	return changes, nil
+}
+
+// diffPortNames fills in each change's current name and drops changes that
+// would not alter anything. current maps serial → port ID → name; ports not
+// present there are reported as errors so typos do not silently create no-ops.
+func diffPortNames(changes []portNameChange, current map[string]map[string]string) ([]portNameChange, []error) {
+	var pending []portNameChange
+	var errs []error
+	for _, c := range changes {
+		old, ok := current[c.Serial][c.PortID]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s port %s: no such port", c.Serial, c.PortID))
+			continue
+		}
+		if old == c.NewName {
+			continue
+		}
+		c.OldName = old
+		pending = append(pending, c)
+	}
+	return pending, errs
+}
+
+// writePortNameDiff writes a preview of the pending renames.
+func writePortNameDiff(w io.Writer, changes []portNameChange) {
+	for _, c := range changes {
+		_, _ = fmt.Fprintf(w, "  %s port %s: %q → %q\n", c.Serial, c.PortID, c.OldName, c.NewName)
+	}
+	_, _ = fmt.Fprintf(w, "%d port name change(s)\n", len(changes))
+}
+
+// portNameActions converts changes into action batches of at most
+// meraki.ActionBatchMaxActions actions each.
+func portNameActions(changes []portNameChange) [][]meraki.ActionBatchAction {
+	var batches [][]meraki.ActionBatchAction
+	for start := 0; start < len(changes); start += meraki.ActionBatchMaxActions {
+		end := start + meraki.ActionBatchMaxActions
+		if end > len(changes) {
+			end = len(changes)
+		}
+		batch := make([]meraki.ActionBatchAction, 0, end-start)
+		for _, c := range changes[start:end] {
+			batch = append(batch, meraki.ActionBatchAction{
+				Resource:  fmt.Sprintf("/devices/%s/switch/ports/%s", c.Serial, c.PortID),
+				Operation: "update",
+				Body:      map[string]interface{}{"name": c.NewName},
+			})
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// importPortNames previews the renames in the CSV and, unless dryRun is set,
+// applies them via action batches in orgID. It returns an error if the CSV is
+// invalid, references unknown ports, or a batch is rejected.
+func importPortNames(ctx context.Context, w io.Writer, client *meraki.MerakiClient, orgID string, r io.Reader, dryRun bool, log *logger.Logger) error {
+	changes, err := parsePortNamesCSV(r)
+	if err != nil {
+		return fmt.Errorf("port names CSV: %v", err)
+	}
+
+	current := make(map[string]map[string]string)
+	for _, c := range changes {
+		if _, fetched := current[c.Serial]; fetched {
+			continue
+		}
+		ports, err := client.GetSwitchPorts(ctx, c.Serial)
+		if err != nil {
+			return fmt.Errorf("switch %s: %v", c.Serial, err)
+		}
+		names := make(map[string]string, len(ports))
+		for _, p := range ports {
+			names[p.PortID] = p.Name
+		}
+		current[c.Serial] = names
+	}
+
+	pending, errs := diffPortNames(changes, current)
+	if len(errs) > 0 {
+		for _, e := range errs {
+			_, _ = fmt.Fprintf(w, "  error: %v\n", e)
+		}
+		return fmt.Errorf("%d row(s) reference unknown ports; nothing applied", len(errs))
+	}
+	writePortNameDiff(w, pending)
+	if dryRun || len(pending) == 0 {
+		return nil
+	}
+
+	for i, batch := range portNameActions(pending) {
+		id, err := client.CreateActionBatch(ctx, orgID, batch)
+		if err != nil {
+			return fmt.Errorf("action batch %d: %v", i+1, err)
+		}
+		log.Debugf("Action batch %s submitted with %d action(s)", id, len(batch))
+		_, _ = fmt.Fprintf(w, "Submitted action batch %s (%d change(s))\n", id, len(batch))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePortNamesCSV(t *testing.T) {
+	in := "serial,port,name\nq2aa-bbbb-cccc, 1, Printer\nQ2AA-BBBB-CCCC,2,\"Desk, 4th floor\"\n"
+	got, err := parsePortNamesCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("parsePortNamesCSV() error: %v", err)
+	}
+	if len(got) != 2 || got[0].Serial != "Q2AA-BBBB-CCCC" || got[0].PortID != "1" || got[1].NewName != "Desk, 4th floor" {
+		t.Errorf("parsePortNamesCSV() = %+v", got)
+	}
+	if _, err := parsePortNamesCSV(strings.NewReader("Q2AA-BBBB-CCCC,,x\n")); err == nil {
+		t.Error("missing port should be an error")
+	}
+	if _, err := parsePortNamesCSV(strings.NewReader("Q2AA-BBBB-CCCC,1\n")); err == nil {
+		t.Error("short row should be an error")
+	}
+}
+
+func TestDiffPortNames(t *testing.T) {
+	current := map[string]map[string]string{"S1": {"1": "Old", "2": "Same"}}
+	changes := []portNameChange{
+		{Serial: "S1", PortID: "1", NewName: "New"},
+		{Serial: "S1", PortID: "2", NewName: "Same"},
+		{Serial: "S1", PortID: "9", NewName: "Typo"},
+	}
+	pending, errs := diffPortNames(changes, current)
+	if len(pending) != 1 || pending[0].OldName != "Old" || pending[0].NewName != "New" {
+		t.Errorf("pending = %+v", pending)
+	}
+	if len(errs) != 1 {
+		t.Errorf("errs = %v, want one unknown-port error", errs)
+	}
+
+	var buf bytes.Buffer
+	writePortNameDiff(&buf, pending)
+	if !strings.Contains(buf.String(), `S1 port 1: "Old" → "New"`) {
+		t.Errorf("diff = %q", buf.String())
+	}
+}
+
+func TestPortNameActions_Batches(t *testing.T) {
+	var changes []portNameChange
+	for i := 0; i < 205; i++ {
+		changes = append(changes, portNameChange{Serial: "S1", PortID: fmt.Sprint(i + 1), NewName: "n"})
+	}
+	batches := portNameActions(changes)
+	if len(batches) != 3 || len(batches[0]) != 100 || len(batches[2]) != 5 {
+		t.Fatalf("batch sizes wrong: %d batches", len(batches))
+	}
+	if a := batches[0][0]; a.Resource != "/devices/S1/switch/ports/1" || a.Operation != "update" || a.Body["name"] != "n" {
+		t.Errorf("action = %+v", a)
+	}
+}
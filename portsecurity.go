@@ -0,0 +1,163 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+// portSecurityFinding is an access port without a MAC allow list that carries
+// more than one client.
+type portSecurityFinding struct {
+	NetworkName  string
+	SwitchName   string
+	SwitchSerial string
+	PortID       string
+	PortName     string
+	VLAN         int
+	Policy       string   // current access policy, e.g. "Open"
+	MACs         []string // colon-formatted MACs learned on the port
+}
+
+// isMACRestricted reports whether a port already limits which MACs may use it.
+func isMACRestricted(p meraki.SwitchPortConfig) bool {
+	switch p.AccessPolicyType {
+	case "MAC allow list", "Sticky MAC allow list":
+		return true
+	}
+	return false
+}
+
+// findUnsecuredPorts returns the enabled access ports of one switch that have no
+// MAC restriction but more than one MAC learned. macsByPort maps port ID to the
+// MACs currently in the switch's table; uplink ports are skipped.
+func findUnsecuredPorts(network meraki.Network, sw meraki.Device, ports []meraki.SwitchPortConfig, macsByPort map[string][]string, uplinks map[string]struct{}) []portSecurityFinding {
+	var findings []portSecurityFinding
+	for _, p := range ports {
+		if !p.Enabled || p.Type != "access" || isMACRestricted(p) {
+			continue
+		}
+		if _, up := uplinks[p.PortID]; up {
+			continue
+		}
+		macs := macsByPort[p.PortID]
+		if len(macs) < 2 {
+			continue
+		}
+		findings = append(findings, portSecurityFinding{
+			NetworkName:  network.Name,
+			SwitchName:   sw.Name,
+			SwitchSerial: sw.Serial,
+			PortID:       p.PortID,
+			PortName:     p.Name,
+			VLAN:         p.Vlan,
+			Policy:       firstNonEmpty(p.AccessPolicyType, "Open"),
+			MACs:         macs,
+		})
+	}
+	return findings
+}
+
+// liveMACsByPort polls the switch MAC table and groups the distinct MACs by port.
+// When the live table is unavailable the device clients list is used instead.
+func liveMACsByPort(ctx context.Context, client *meraki.MerakiClient, serial string, maxPoll int) map[string][]string {
+	seen := make(map[string]map[string]struct{})
+	add := func(port, mac string) {
+		norm, err := macaddr.NormalizeExactMac(mac)
+		if port == "" || err != nil {
+			return
+		}
+		if seen[port] == nil {
+			seen[port] = make(map[string]struct{})
+		}
+		seen[port][macaddr.FormatMacColon(norm)] = struct{}{}
+	}
+	if entries := pollMacTable(ctx, client, serial, maxPoll); entries != nil {
+		for _, e := range entries {
+			mac, _ := e["mac"].(string)
+			add(macTableEntryPort(e), mac)
+		}
+	} else if clients, err := client.GetDeviceClients(ctx, serial); err == nil {
+		for _, c := range clients {
+			add(firstNonEmpty(c.Switchport, c.Port), c.MAC)
+		}
+	}
+	byPort := make(map[string][]string, len(seen))
+	for port, macs := range seen {
+		for mac := range macs {
+			byPort[port] = append(byPort[port], mac)
+		}
+		sort.Strings(byPort[port])
+	}
+	return byPort
+}
+
+// writePortSecurityReport writes one recommendation per finding.
+func writePortSecurityReport(w io.Writer, findings []portSecurityFinding) {
+	if len(findings) == 0 {
+		_, _ = fmt.Fprintln(w, "Port security: no unrestricted access ports with multiple clients found")
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Port security: %d unrestricted access port(s) with multiple clients\n", len(findings))
+	for _, f := range findings {
+		_, _ = fmt.Fprintf(w, "  %s / %s port %s%s (VLAN %d, policy %s): %d MACs — %s\n",
+			f.NetworkName, firstNonEmpty(f.SwitchName, f.SwitchSerial), f.PortID, quotedSuffix(f.PortName),
+			f.VLAN, f.Policy, len(f.MACs), strings.Join(f.MACs, ", "))
+		_, _ = fmt.Fprintf(w, "    Recommendation: enable a sticky MAC allow list (limit %d) or 802.1X\n", len(f.MACs))
+	}
+}
+
+// quotedSuffix returns ` "name"` or "" when name is empty.
+func quotedSuffix(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" %q", name)
+}
+
+// reportPortSecurity scans every switch in networks and writes the port security
+// recommendations report.
+func reportPortSecurity(ctx context.Context, w io.Writer, client *meraki.MerakiClient, networks []meraki.Network, maxPoll int, log *logger.Logger) {
+	var findings []portSecurityFinding
+	for _, network := range networks {
+		devices, err := client.GetDevices(ctx, network.ID)
+		if err != nil {
+			log.Debugf("Devices for %s: %v", network.Name, err)
+			continue
+		}
+		for _, d := range devices {
+			if d.ProductType != "switch" && !strings.HasPrefix(strings.ToUpper(d.Model), "MS") {
+				continue
+			}
+			ports, err := client.GetSwitchPorts(ctx, d.Serial)
+			if err != nil {
+				log.Debugf("Switch ports for %s: %v", d.Serial, err)
+				continue
+			}
+			macs := liveMACsByPort(ctx, client, d.Serial, maxPoll)
+			findings = append(findings, findUnsecuredPorts(network, d, ports, macs, client.GetDeviceUplinkPorts(ctx, d.Serial))...)
+		}
+	}
+	writePortSecurityReport(w, findings)
+}
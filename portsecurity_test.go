@@ -0,0 +1,61 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+func TestFindUnsecuredPorts(t *testing.T) {
+	network := meraki.Network{ID: "N_1", Name: "HQ"}
+	sw := meraki.Device{Serial: "Q2SW-0001", Name: "SW-Core"}
+	ports := []meraki.SwitchPortConfig{
+		{PortID: "1", Enabled: true, Type: "access", Vlan: 10, AccessPolicyType: "Open"},
+		{PortID: "2", Enabled: true, Type: "access", AccessPolicyType: "Sticky MAC allow list"},
+		{PortID: "3", Enabled: true, Type: "trunk"},
+		{PortID: "4", Enabled: false, Type: "access"},
+		{PortID: "5", Enabled: true, Type: "access"},
+		{PortID: "6", Enabled: true, Type: "access"},
+		{PortID: "7", Enabled: true, Type: "access", AccessPolicyType: "Custom access policy"},
+	}
+	two := []string{"aa:aa:aa:aa:aa:01", "aa:aa:aa:aa:aa:02"}
+	macs := map[string][]string{
+		"1": two, "2": two, "3": two, "4": two, "5": two,
+		"6": {"aa:aa:aa:aa:aa:03"},
+		"7": two,
+	}
+	uplinks := map[string]struct{}{"5": {}}
+
+	got := findUnsecuredPorts(network, sw, ports, macs, uplinks)
+	if len(got) != 2 || got[0].PortID != "1" || got[1].PortID != "7" {
+		t.Fatalf("findings = %+v, want ports 1 and 7", got)
+	}
+	if got[0].Policy != "Open" || got[0].VLAN != 10 || got[0].SwitchName != "SW-Core" {
+		t.Errorf("finding = %+v", got[0])
+	}
+
+	var buf bytes.Buffer
+	writePortSecurityReport(&buf, got)
+	for _, want := range []string{"2 unrestricted access port(s)", "SW-Core port 1", "sticky MAC allow list (limit 2)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, buf.String())
+		}
+	}
+}
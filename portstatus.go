@@ -0,0 +1,129 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// portStatuses fills in whether each result's port is actually up, at what
+// speed and duplex, and which CDP/LLDP neighbor is on the other end. Each
+// switch's port statuses are fetched once.
+type portStatuses struct {
+	ctx    context.Context
+	client *meraki.MerakiClient
+	ports  map[string]map[string]meraki.SwitchPortStatus // serial → port → status
+}
+
+func newPortStatuses(ctx context.Context, client *meraki.MerakiClient) *portStatuses {
+	return &portStatuses{ctx: ctx, client: client, ports: make(map[string]map[string]meraki.SwitchPortStatus)}
+}
+
+// fill sets row's link state, speed, duplex and neighbor. A link-aggregation
+// port takes them from its first connected member. Rows from access points
+// and device management MACs have no switch port and are left alone, as are
+// rows on switches whose statuses cannot be read.
+func (p *portStatuses) fill(row *output.ResultRow) {
+	if row.SwitchSerial == "" || row.Port == "" || row.Port == "wireless" || row.SSID != "" || row.Source == output.SourceDevice {
+		return
+	}
+	ports := p.load(row.SwitchSerial)
+	st, found := ports[row.Port]
+	for _, m := range row.AggrPorts {
+		if ms, ok := ports[m]; ok && (!found || linkState(st) != "up") {
+			st, found = ms, true
+		}
+	}
+	if !found {
+		return
+	}
+	row.Link = linkState(st)
+	if row.Link == "up" {
+		row.Speed, row.Duplex = st.Speed, st.Duplex
+	}
+	row.Neighbor, row.NeighborPlatform = portNeighbor(st.LLDP, st.CDP)
+	if row.Explain != nil {
+		row.Explain = setFieldSource(row.Explain, output.FieldSource{Field: "Link", Source: srcPortStatuses, Detail: "GET /devices/" + row.SwitchSerial + "/switch/ports/statuses"})
+	}
+}
+
+// uplinks returns the ports the platform marks as uplinks on a switch, in the
+// form isPortUplink expects.
+func (p *portStatuses) uplinks(serial string) map[string]struct{} {
+	out := make(map[string]struct{})
+	for id, s := range p.load(serial) {
+		if s.IsUplink {
+			out[id] = struct{}{}
+		}
+	}
+	return out
+}
+
+// load returns the statuses of a switch's ports by port ID, fetching them on
+// first use. A switch whose statuses cannot be read has none.
+func (p *portStatuses) load(serial string) map[string]meraki.SwitchPortStatus {
+	if ports, ok := p.ports[serial]; ok {
+		return ports
+	}
+	ports := make(map[string]meraki.SwitchPortStatus)
+	statuses, _ := p.client.GetSwitchPortStatuses(p.ctx, serial)
+	for _, s := range statuses {
+		ports[s.PortID] = s
+	}
+	p.ports[serial] = ports
+	return ports
+}
+
+// linkState maps a port status to "up", "down" or "disabled".
+func linkState(s meraki.SwitchPortStatus) string {
+	switch strings.ToLower(s.Status) {
+	case "connected":
+		return "up"
+	case "disconnected":
+		if !s.Enabled {
+			return "disabled"
+		}
+		return "down"
+	case "disabled":
+		return "disabled"
+	}
+	return strings.ToLower(s.Status)
+}
+
+// portNeighbor describes the LLDP neighbor on a port, or failing that the CDP
+// one, as "name (port)", along with the platform it advertises. An LLDP
+// neighbor that sends no system description takes the CDP platform. Both are
+// empty when neither protocol reports a name.
+func portNeighbor(lldp, cdp *meraki.PortNeighbor) (neighbor, platform string) {
+	for _, n := range []*meraki.PortNeighbor{lldp, cdp} {
+		if n == nil || n.Name() == "" {
+			continue
+		}
+		platform = n.PlatformName()
+		if platform == "" && n == lldp && cdp != nil {
+			platform = cdp.PlatformName()
+		}
+		if n.PortID != "" {
+			return n.Name() + " (" + n.PortID + ")", platform
+		}
+		return n.Name(), platform
+	}
+	return "", ""
+}
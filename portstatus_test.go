@@ -0,0 +1,73 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestPortStatusesFill(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/devices/Q2SW/switch/ports/statuses" {
+			http.NotFound(w, r)
+			return
+		}
+		calls++
+		_, _ = w.Write([]byte(`[
+			{"portId":"3","enabled":true,"status":"Connected","speed":"1 Gbps","duplex":"full",
+			 "lldp":{"systemName":"phone-3","portId":"eth0","systemDescription":"Cisco IP Phone CP-8845\nsip88xx.14-1-1"}},
+			{"portId":"4","enabled":true,"status":"Disconnected"},
+			{"portId":"5","enabled":false,"status":"Disconnected"},
+			{"portId":"48","enabled":true,"status":"Disconnected"},
+			{"portId":"49","enabled":true,"status":"Connected","isUplink":true,"speed":"10 Gbps","duplex":"full",
+			 "cdp":{"deviceId":"core-1","portId":"Te1/1/1","platform":"cisco C9300-48P"}}]`))
+	}))
+	defer srv.Close()
+
+	p := newPortStatuses(context.Background(), meraki.NewClient("key", srv.URL, 1))
+	tests := []struct {
+		row                                     output.ResultRow
+		link, speed, duplex, neighbor, platform string
+	}{
+		{output.ResultRow{SwitchSerial: "Q2SW", Port: "3"}, "up", "1 Gbps", "full", "phone-3 (eth0)", "Cisco IP Phone CP-8845"},
+		{output.ResultRow{SwitchSerial: "Q2SW", Port: "4"}, "down", "", "", "", ""},
+		{output.ResultRow{SwitchSerial: "Q2SW", Port: "5"}, "disabled", "", "", "", ""},
+		{output.ResultRow{SwitchSerial: "Q2SW", Port: "AGGR/1", AggrPorts: []string{"48", "49"}}, "up", "10 Gbps", "full", "core-1 (Te1/1/1)", "cisco C9300-48P"},
+		{output.ResultRow{SwitchSerial: "Q2SW", Port: "99"}, "", "", "", "", ""},
+		{output.ResultRow{SwitchSerial: "Q2SW", Port: "3", SSID: "corp"}, "", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		row := tt.row
+		p.fill(&row)
+		if row.Link != tt.link || row.Speed != tt.speed || row.Duplex != tt.duplex || row.Neighbor != tt.neighbor || row.NeighborPlatform != tt.platform {
+			t.Errorf("fill(port %s) = %q %q %q %q %q, want %q %q %q %q %q", tt.row.Port, row.Link, row.Speed, row.Duplex, row.Neighbor, row.NeighborPlatform,
+				tt.link, tt.speed, tt.duplex, tt.neighbor, tt.platform)
+		}
+	}
+	if up := p.uplinks("Q2SW"); len(up) != 1 {
+		t.Errorf("uplinks() = %v, want only port 49", up)
+	}
+	if calls != 1 {
+		t.Errorf("statuses fetched %d times, want once per switch", calls)
+	}
+}
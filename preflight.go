@@ -0,0 +1,132 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// preflightListLimit caps the offline and alerting devices listed by name.
+const preflightListLimit = 10
+
+// preflight is the dashboard status of the devices a scan is about to search,
+// checked up front with --preflight so a scan that cannot succeed can be
+// abandoned before its MAC table lookups time out. Offline and dormant
+// devices are left out of the scan.
+type preflight struct {
+	networks int
+	online   int
+	unknown  int // not in the status listing, or it could not be read
+	offline  []preflightDevice
+	alerting []preflightDevice
+	skip     map[string]bool // serials of the offline and dormant devices
+}
+
+// preflightDevice is a device the summary names.
+type preflightDevice struct {
+	network string
+	dev     meraki.Device
+	status  meraki.DeviceStatus
+}
+
+func newPreflight() *preflight {
+	return &preflight{skip: make(map[string]bool)}
+}
+
+// add counts the devices to be searched in network by their status.
+func (p *preflight) add(network string, devices []meraki.Device, statuses *deviceStatuses) {
+	p.networks++
+	for _, dev := range devices {
+		st, ok := statuses.status(dev.Serial)
+		switch {
+		case !ok:
+			p.unknown++
+		case st.Status == "offline" || st.Status == "dormant":
+			p.offline = append(p.offline, preflightDevice{network, dev, st})
+			p.skip[dev.Serial] = true
+		case st.Status == "alerting":
+			p.alerting = append(p.alerting, preflightDevice{network, dev, st})
+		default:
+			p.online++
+		}
+	}
+}
+
+// total is the number of devices counted.
+func (p *preflight) total() int {
+	return p.online + p.unknown + len(p.offline) + len(p.alerting)
+}
+
+// without returns devices minus the ones the pre-flight check skips.
+func (p *preflight) without(devices []meraki.Device) []meraki.Device {
+	var out []meraki.Device
+	for _, d := range devices {
+		if !p.skip[d.Serial] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// write prints the summary line, e.g. "Pre-flight: 46 devices in 5 networks:
+// 42 online, 3 offline will be skipped, 1 alerting", then the offline and
+// alerting devices by name.
+func (p *preflight) write(w io.Writer) {
+	parts := []string{fmt.Sprintf("%d online", p.online)}
+	if len(p.offline) > 0 {
+		parts = append(parts, fmt.Sprintf("%d offline will be skipped", len(p.offline)))
+	}
+	if len(p.alerting) > 0 {
+		parts = append(parts, fmt.Sprintf("%d alerting", len(p.alerting)))
+	}
+	if p.unknown > 0 {
+		parts = append(parts, fmt.Sprintf("%d unknown", p.unknown))
+	}
+	_, _ = fmt.Fprintf(w, "Pre-flight: %d devices in %d networks: %s\n", p.total(), p.networks, strings.Join(parts, ", "))
+	writePreflightDevices(w, p.offline)
+	writePreflightDevices(w, p.alerting)
+}
+
+// confirmScan asks whether to start the scan after the pre-flight summary. A
+// declined scan ends the run without an error, so it discards the pending
+// --output-file (nil when results go to stdout) itself; exitCleanups only run
+// on exitWithError.
+func confirmScan(prompt *prompter, resultFile *output.AtomicFile) bool {
+	answer, err := prompt.ask("Start the scan? (y/n)", "y")
+	if err == nil && strings.HasPrefix(strings.ToLower(answer), "y") {
+		return true
+	}
+	if resultFile != nil {
+		resultFile.Abort()
+	}
+	_, _ = fmt.Fprintln(prompt.out, "Scan cancelled.")
+	return false
+}
+
+func writePreflightDevices(w io.Writer, devices []preflightDevice) {
+	for i, d := range devices {
+		if i == preflightListLimit {
+			_, _ = fmt.Fprintf(w, "  ... and %d more %s\n", len(devices)-i, d.status.Status)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "  %-9s %s (%s), last reported %s\n", d.status.Status, firstNonEmpty(d.dev.Name, d.dev.Serial), d.network, firstNonEmpty(d.status.LastReportedAt, "never"))
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestPreflight(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"serial":"Q2AA-0001","status":"online"},
+			{"serial":"Q2AA-0002","status":"online"},
+			{"serial":"Q2AA-0003","status":"offline","lastReportedAt":"2026-10-17T22:15:00Z"},
+			{"serial":"Q2AA-0004","status":"alerting","lastReportedAt":"2026-10-18T08:00:00Z"},
+			{"serial":"Q2AA-0005","status":"dormant"}]`))
+	}))
+	defer srv.Close()
+	statuses := newDeviceStatuses(context.Background(), meraki.NewClient("key", srv.URL, 1), "O1", nil)
+
+	p := newPreflight()
+	p.add("HQ", []meraki.Device{{Serial: "Q2AA-0001"}, {Serial: "Q2AA-0003", Name: "sw-closet"}, {Serial: "Q2AA-0004", Name: "sw-core"}}, statuses)
+	p.add("Branch", []meraki.Device{{Serial: "Q2AA-0002"}, {Serial: "Q2AA-0005", Name: "sw-spare"}, {Serial: "Q2AA-0009"}}, statuses)
+
+	var buf bytes.Buffer
+	p.write(&buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"Pre-flight: 6 devices in 2 networks: 2 online, 2 offline will be skipped, 1 alerting, 1 unknown",
+		"  offline   sw-closet (HQ), last reported 2026-10-17T22:15:00Z",
+		"  dormant   sw-spare (Branch), last reported never",
+		"  alerting  sw-core (HQ), last reported 2026-10-18T08:00:00Z",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("write() =\n%s\nwant\n%s", buf.String(), strings.Join(want, "\n"))
+	}
+
+	kept := p.without([]meraki.Device{{Serial: "Q2AA-0003"}, {Serial: "Q2AA-0004"}, {Serial: "Q2AA-0005"}})
+	if len(kept) != 1 || kept[0].Serial != "Q2AA-0004" {
+		t.Errorf("without() = %+v, want only the alerting switch", kept)
+	}
+}
+
+func TestConfirmScanDeclinedDiscardsOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "out.csv")
+	resultFile, err := output.CreateAtomic(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if confirmScan(newPrompter(strings.NewReader("n\n"), &out), resultFile) {
+		t.Fatal("confirmScan() = true for answer n")
+	}
+	if !strings.Contains(out.String(), "Scan cancelled.") {
+		t.Errorf("output = %q", out.String())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("declined scan left %v next to --output-file", entries)
+	}
+
+	if !confirmScan(newPrompter(strings.NewReader("\n"), &out), nil) {
+		t.Error("confirmScan() = false for the default answer")
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+)
+
+// maxListedChoices is how many names choose prints as a numbered list; longer
+// lists are only matched by what the user types.
+const maxListedChoices = 30
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// prompter asks for the parameters a run without --mac/--ip is missing when
+// it is started from a terminal, instead of exiting with an error.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out}
+}
+
+// ask prints question and returns the trimmed answer, or def when the answer
+// is empty. End of input is an error so a closed stdin does not loop.
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		_, _ = fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		_, _ = fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+		_, _ = fmt.Fprintln(p.out)
+		return "", fmt.Errorf("no answer to %q", question)
+	}
+	if line == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+// choose asks for one of names. The answer may be its number in the printed
+// list, the name in any case, or any unique case-insensitive prefix (or, failing
+// that, substring) of one, which is completed to the full name. extra answers
+// (such as "ALL") are accepted verbatim. Ambiguous or unknown answers are asked
+// again.
+func (p *prompter) choose(label string, names []string, def string, extra ...string) (string, error) {
+	if len(names) <= maxListedChoices {
+		for i, n := range names {
+			_, _ = fmt.Fprintf(p.out, "  %2d) %s\n", i+1, n)
+		}
+	}
+	for {
+		answer, err := p.ask(label, def)
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			continue
+		}
+		for _, e := range extra {
+			if strings.EqualFold(answer, e) {
+				return e, nil
+			}
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(names) {
+			return names[n-1], nil
+		}
+		matches := completeName(answer, names)
+		switch len(matches) {
+		case 1:
+			if matches[0] != answer {
+				_, _ = fmt.Fprintf(p.out, "  → %s\n", matches[0])
+			}
+			return matches[0], nil
+		case 0:
+			_, _ = fmt.Fprintf(p.out, "  no match for %q\n", answer)
+		default:
+			_, _ = fmt.Fprintf(p.out, "  %q matches %s; be more specific\n", answer, strings.Join(matches, ", "))
+		}
+	}
+}
+
+// completeName returns the names answer completes to: an exact case-insensitive
+// match, else every name it is a prefix of, else every name containing it.
+func completeName(answer string, names []string) []string {
+	a := strings.ToLower(answer)
+	var prefix, substr []string
+	for _, n := range names {
+		l := strings.ToLower(n)
+		switch {
+		case l == a:
+			return []string{n}
+		case strings.HasPrefix(l, a):
+			prefix = append(prefix, n)
+		case strings.Contains(l, a):
+			substr = append(substr, n)
+		}
+	}
+	if len(prefix) > 0 {
+		return prefix
+	}
+	return substr
+}
+
+// classifyTarget sorts a prompted lookup target into the flag it stands for:
+// "ip" for an address or CIDR subnet, "mac" for a MAC, pattern or list of
+// them, and "hostname" for anything else.
+func classifyTarget(target string) string {
+	if net.ParseIP(target) != nil {
+		return "ip"
+	}
+	if _, _, err := net.ParseCIDR(target); err == nil {
+		return "ip"
+	}
+	if _, _, _, err := macaddr.BuildMultiMacMatcher(target); err == nil {
+		return "mac"
+	}
+	return "hostname"
+}
+
+// promptCache remembers the last organization and, per organization, network
+// picked at a prompt, so they are offered as the default next time.
+type promptCache struct {
+	Org      string            `json:"org"`
+	Networks map[string]string `json:"networks"`
+}
+
+// promptCachePath returns the prompt cache file in the user cache directory,
+// or "" when there is none (the cache is then not used).
+func promptCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "find-mac-prompt.json")
+}
+
+// loadPromptCache reads the cache at path; a missing or unreadable file gives
+// an empty cache.
+func loadPromptCache(path string) *promptCache {
+	c := &promptCache{}
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			_ = json.Unmarshal(data, c)
+		}
+	}
+	if c.Networks == nil {
+		c.Networks = make(map[string]string)
+	}
+	return c
+}
+
+// save writes the cache to path. It is a convenience, so errors are ignored.
+func (c *promptCache) save(path string) {
+	if path == "" {
+		return
+	}
+	if data, err := json.Marshal(c); err == nil {
+		_ = os.WriteFile(path, data, 0o600)
+	}
+}
+
+// knownOr returns name if it is one of names, else fallback; a remembered
+// default that no longer exists is not offered.
+func knownOr(name string, names []string, fallback string) string {
+	for _, n := range names {
+		if name != "" && n == name {
+			return name
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrompterChoose(t *testing.T) {
+	names := []string{"HQ", "Branch-North", "Branch-South", "Lab"}
+	tests := []struct {
+		input string
+		def   string
+		want  string
+	}{
+		{"2\n", "", "Branch-North"},
+		{"hq\n", "", "HQ"},
+		{"branch-s\n", "", "Branch-South"},
+		{"branch\nnorth\n", "", "Branch-North"}, // ambiguous, then a unique substring
+		{"nowhere\nla\n", "", "Lab"},
+		{"\n", "Lab", "Lab"},
+		{"all\n", "", "ALL"},
+		{"9\n4\n", "", "Lab"}, // out of range is not a name either
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		p := newPrompter(strings.NewReader(tt.input), &out)
+		got, err := p.choose("Network", names, tt.def, "ALL")
+		if err != nil || got != tt.want {
+			t.Errorf("choose(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+
+	p := newPrompter(strings.NewReader("branch\n"), &bytes.Buffer{})
+	if got, err := p.choose("Network", names, "", "ALL"); err == nil {
+		t.Errorf("choose() at end of input = %q, want error", got)
+	}
+}
+
+func TestClassifyTarget(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.5":          "ip",
+		"2001:db8::1":       "ip",
+		"10.0.0.0/24":       "ip",
+		"aa:bb:cc:dd:ee:ff": "mac",
+		"aabb.ccdd.eeff":    "mac",
+		"aa:bb:cc:*:*:*":    "mac",
+		"printer-3.example": "hostname",
+	}
+	for in, want := range tests {
+		if got := classifyTarget(in); got != want {
+			t.Errorf("classifyTarget(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPromptCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.json")
+	c := loadPromptCache(path)
+	if c.Org != "" || len(c.Networks) != 0 {
+		t.Fatalf("loadPromptCache(missing) = %+v, want empty", c)
+	}
+	c.Org = "Acme"
+	c.Networks["Acme"] = "HQ"
+	c.save(path)
+	c = loadPromptCache(path)
+	if c.Org != "Acme" || c.Networks["Acme"] != "HQ" {
+		t.Errorf("loadPromptCache() after save = %+v", c)
+	}
+	if got := knownOr("Gone", []string{"HQ"}, "ALL"); got != "ALL" {
+		t.Errorf("knownOr(stale) = %q, want fallback", got)
+	}
+}
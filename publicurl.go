@@ -0,0 +1,65 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// parsePublicURL checks a --public-url value: an absolute http or https URL,
+// returned without its trailing slash so paths can be appended. "" is allowed
+// and means "work it out" (see lanBaseURL).
+func parsePublicURL(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%q is not an http:// or https:// URL", s)
+	}
+	return strings.TrimRight(s, "/"), nil
+}
+
+// lanBaseURL is the address other devices, such as a phone scanning a QR
+// code, can reach the web server on: the --web-host itself, or the first LAN
+// address of the machine when the server listens on every interface. It is ""
+// when the server only listens on loopback, which nothing else can reach.
+func lanBaseURL(host, port string, interfaceAddrs func() ([]net.Addr, error)) string {
+	switch ip := net.ParseIP(host); {
+	case strings.EqualFold(host, "localhost") || (ip != nil && ip.IsLoopback()):
+		return ""
+	case host == "" || (ip != nil && ip.IsUnspecified()):
+		addrs, err := interfaceAddrs()
+		if err != nil {
+			return ""
+		}
+		host = ""
+		for _, a := range addrs {
+			if n, ok := a.(*net.IPNet); ok && n.IP.To4() != nil && n.IP.IsGlobalUnicast() {
+				host = n.IP.String()
+				break
+			}
+		}
+		if host == "" {
+			return ""
+		}
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
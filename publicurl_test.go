@@ -0,0 +1,75 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestParsePublicURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "", want: ""},
+		{in: " https://findmac.example.com/ ", want: "https://findmac.example.com"},
+		{in: "http://10.1.2.3:8080", want: "http://10.1.2.3:8080"},
+		{in: "findmac.example.com", wantErr: true},
+		{in: "ftp://findmac.example.com", wantErr: true},
+		{in: "https://", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePublicURL(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parsePublicURL(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLANBaseURL(t *testing.T) {
+	addrs := func() ([]net.Addr, error) {
+		return []net.Addr{
+			&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+			&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+			&net.IPNet{IP: net.ParseIP("169.254.10.1"), Mask: net.CIDRMask(16, 32)},
+			&net.IPNet{IP: net.ParseIP("10.20.30.40"), Mask: net.CIDRMask(24, 32)},
+		}, nil
+	}
+	noAddrs := func() ([]net.Addr, error) { return nil, errors.New("no interfaces") }
+	tests := []struct {
+		host  string
+		addrs func() ([]net.Addr, error)
+		want  string
+	}{
+		{host: "localhost", addrs: addrs, want: ""},
+		{host: "127.0.0.1", addrs: addrs, want: ""},
+		{host: "::1", addrs: addrs, want: ""},
+		{host: "0.0.0.0", addrs: addrs, want: "http://10.20.30.40:8080"},
+		{host: "::", addrs: addrs, want: "http://10.20.30.40:8080"},
+		{host: "", addrs: addrs, want: "http://10.20.30.40:8080"},
+		{host: "0.0.0.0", addrs: noAddrs, want: ""},
+		{host: "192.0.2.7", addrs: noAddrs, want: "http://192.0.2.7:8080"},
+		{host: "2001:db8::7", addrs: noAddrs, want: "http://[2001:db8::7]:8080"},
+		{host: "findmac.example.com", addrs: noAddrs, want: "http://findmac.example.com:8080"},
+	}
+	for _, tt := range tests {
+		if got := lanBaseURL(tt.host, "8080", tt.addrs); got != tt.want {
+			t.Errorf("lanBaseURL(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/oui"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+	"Find-Meraki-Ports-With-MAC/pkg/query"
+)
+
+// queryPlan is how a --query search runs: the query's required conditions
+// narrow the scan, and the whole query filters the results.
+type queryPlan struct {
+	query   *query.Query
+	vendors *oui.Registry
+	network string            // network= value used to select networks; "" scans the configured ones
+	matcher func(string) bool // normalized MAC → candidate
+}
+
+// planQuery turns required mac= and vendor conditions into a MAC matcher and a
+// required network= condition into a network selection, so a query does not
+// have to scan every MAC of every network.
+func planQuery(q *query.Query, vendors *oui.Registry) (*queryPlan, error) {
+	plan := &queryPlan{query: q, vendors: vendors, matcher: func(string) bool { return true }}
+	for _, c := range q.Required() {
+		var m func(string) bool
+		switch {
+		case c.Field == "mac" && c.Op == query.OpEq:
+			var err error
+			if m, _, _, err = macaddr.BuildMultiMacMatcher(c.Value); err != nil {
+				return nil, err
+			}
+		case c.Field == "vendor":
+			c := c
+			m = func(mac string) bool { return c.Matches(vendors.Lookup(mac)) }
+		case c.Field == "network" && c.Op == query.OpEq && plan.network == "":
+			plan.network = c.Value
+		}
+		if m != nil {
+			prev := plan.matcher
+			plan.matcher = func(mac string) bool { return prev(mac) && m(mac) }
+		}
+	}
+	return plan, nil
+}
+
+// filter scores rows (so confidence can be queried) and keeps those matching
+// the query.
+func (p *queryPlan) filter(rows []output.ResultRow) []output.ResultRow {
+	output.ScoreRows(rows, time.Now())
+	kept := rows[:0]
+	for _, row := range rows {
+		if p.query.Match(p.rowField(row)) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+// rowField returns the getter the query evaluates row with.
+func (p *queryPlan) rowField(row output.ResultRow) func(string) string {
+	return func(field string) string {
+		switch field {
+		case "mac":
+			return row.MAC
+		case "ip":
+			return row.IP
+		case "hostname":
+			return row.Hostname
+		case "vendor":
+			return p.vendors.Lookup(row.MAC)
+		case "vlan":
+			if row.VLAN == 0 {
+				return ""
+			}
+			return strconv.Itoa(row.VLAN)
+		case "network":
+			return row.NetworkName
+		case "switch":
+			return row.SwitchName
+		case "serial":
+			return row.SwitchSerial
+		case "port":
+			return row.Port
+		case "mode":
+			return row.PortMode
+		case "uplink":
+			return strconv.FormatBool(row.IsUplink)
+		case "source":
+			return row.Source
+		case "confidence":
+			return strconv.Itoa(row.Confidence)
+		case "lastseen":
+			return row.LastSeen
+		}
+		return ""
+	}
+}
+
+// findArgs rewrites `find "<query>" [flags]` into `--query "<query>" [flags]`.
+// Other argument lists are returned unchanged.
+func findArgs(args []string) []string {
+	if len(args) < 3 || args[1] != "find" || strings.HasPrefix(args[2], "-") {
+		return args
+	}
+	return append([]string{args[0], "--query", args[2]}, args[3:]...)
+}
@@ -0,0 +1,83 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/oui"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+	"Find-Meraki-Ports-With-MAC/pkg/query"
+)
+
+func TestPlanQuery(t *testing.T) {
+	reg, err := oui.Parse(strings.NewReader("MA-L,00408C,Axis Communications AB,\nMA-L,BCAD28,Hikvision,\n"))
+	if err != nil {
+		t.Fatalf("oui.Parse() error: %v", err)
+	}
+	q, err := query.Parse("vlan=30 AND vendor~'axis' AND network='Branch-*' AND mac=00:40:8c:00:00:*")
+	if err != nil {
+		t.Fatalf("query.Parse() error: %v", err)
+	}
+	plan, err := planQuery(q, reg)
+	if err != nil {
+		t.Fatalf("planQuery() error: %v", err)
+	}
+	if plan.network != "Branch-*" {
+		t.Errorf("network = %q, want Branch-*", plan.network)
+	}
+	for mac, want := range map[string]bool{
+		"00408c000001": true,  // Axis, inside the mac pattern
+		"00408c120001": false, // Axis, outside the mac pattern
+		"bcad28000001": false, // Hikvision
+	} {
+		if got := plan.matcher(mac); got != want {
+			t.Errorf("matcher(%s) = %v, want %v", mac, got, want)
+		}
+	}
+
+	rows := []output.ResultRow{
+		{NetworkName: "Branch-1", Port: "3", PortMode: "access", VLAN: 30, MAC: "00:40:8c:00:00:01", Source: output.SourceMacTable},
+		{NetworkName: "Branch-1", Port: "4", PortMode: "access", VLAN: 40, MAC: "00:40:8c:00:00:02", Source: output.SourceMacTable},
+	}
+	got := plan.filter(rows)
+	if len(got) != 1 || got[0].Port != "3" {
+		t.Errorf("filter() = %+v, want only the VLAN 30 row", got)
+	}
+
+	q, _ = query.Parse("mac=zz")
+	if _, err := planQuery(q, reg); err == nil {
+		t.Error("planQuery() with an invalid mac pattern should fail")
+	}
+}
+
+func TestFindArgs(t *testing.T) {
+	tests := []struct {
+		in, want []string
+	}{
+		{[]string{"findmac", "find", "vlan=30", "--output-format", "text"}, []string{"findmac", "--query", "vlan=30", "--output-format", "text"}},
+		{[]string{"findmac", "find", "--help"}, []string{"findmac", "find", "--help"}},
+		{[]string{"findmac", "--mac", "find"}, []string{"findmac", "--mac", "find"}},
+		{[]string{"findmac"}, []string{"findmac"}},
+	}
+	for _, tt := range tests {
+		if got := findArgs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("findArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
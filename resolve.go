@@ -18,9 +18,11 @@ package main
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
+	"Find-Meraki-Ports-With-MAC/pkg/config"
 	"Find-Meraki-Ports-With-MAC/pkg/filters"
 	"Find-Meraki-Ports-With-MAC/pkg/logger"
 	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
@@ -149,11 +151,87 @@ func isPortUplink(portID string, aggrMembers []string, uplinkSet map[string]stru
 	return false
 }
 
-func resolveDevices(cfg Config, macAddr, ipAddr string) ([]output.ResultRow, error) {
+// macTablePollInterval is the wait between live MAC table polls; tests shorten it.
+var macTablePollInterval = 2 * time.Second
+
+// pollMacTable starts a live MAC table lookup on serial and polls every
+// macTablePollInterval, up to maxPoll attempts, until it completes. Returns nil when the switch does not
+// support live tools or the lookup fails or times out.
+func pollMacTable(ctx context.Context, client *meraki.MerakiClient, serial string, maxPoll int) []map[string]interface{} {
+	macTableID, err := client.CreateMacTableLookup(ctx, serial)
+	if err != nil || macTableID == "" {
+		return nil
+	}
+	for attempt := 0; attempt < maxPoll; attempt++ {
+		if meraki.SleepContext(ctx, macTablePollInterval) != nil {
+			return nil
+		}
+		entries, status, err := client.GetMacTableLookup(ctx, serial, macTableID)
+		if err != nil || status == "failed" {
+			return nil
+		}
+		if status == "complete" {
+			return entries
+		}
+	}
+	return nil
+}
+
+// macTableEntryPort returns the port of a live MAC table entry, trying the field
+// names used by the different switch families.
+func macTableEntryPort(entry map[string]interface{}) string {
+	for _, key := range []string{"portId", "port", "interface"} {
+		if p, _ := entry[key].(string); p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// macTableEntryMode returns the port mode ("access" or "trunk") a live MAC
+// table entry reports in its type field. Some switches put the entry type
+// there instead; that is not a port mode, so it gives "".
+func macTableEntryMode(entry map[string]interface{}) string {
+	t, _ := entry["type"].(string)
+	if normalizeEntryType(t) != "" {
+		return ""
+	}
+	return t
+}
+
+// macTableEntryType returns whether a live MAC table entry is "static"
+// (configured, sticky or port-security) or "dynamic" (learned), or "" when the
+// switch does not say. It is read from entryType or, on switches that report
+// it there, type.
+func macTableEntryType(entry map[string]interface{}) string {
+	for _, key := range []string{"entryType", "type"} {
+		s, _ := entry[key].(string)
+		if t := normalizeEntryType(s); t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// normalizeEntryType maps a switch's entry type wording ("STATIC", "Sticky",
+// "secure-dynamic", "learned", ...) to "static", "dynamic" or "".
+func normalizeEntryType(s string) string {
+	s = strings.ToLower(s)
+	switch {
+	case strings.Contains(s, "static"), strings.Contains(s, "sticky"), strings.Contains(s, "secure"):
+		return "static"
+	case strings.Contains(s, "dynamic"), strings.Contains(s, "learned"):
+		return "dynamic"
+	}
+	return ""
+}
+
+// resolveDevices names IPs with the meraki.HostNames carried by ctx, so each
+// web request sees only its own host overrides.
+func resolveDevices(ctx context.Context, cfg config.Config, macAddr, ipAddr string) ([]output.ResultRow, error) {
 	log := newWebLogger()
 
-	client := meraki.NewClient(cfg.APIKey, cfg.BaseURL, cfg.MaxRetries)
-	ctx := context.Background()
+	client := newWebClient(cfg.APIKey, cfg.OrgID, log)
 
 	var targetOrg *meraki.Organization
 	var targetNetwork *meraki.Network
@@ -162,7 +240,7 @@ func resolveDevices(cfg Config, macAddr, ipAddr string) ([]output.ResultRow, err
 		// Fast path: org ID known, only fetch networks for that org
 		networks, err := client.GetNetworks(ctx, cfg.OrgID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get networks: %v", err)
+			return nil, fmt.Errorf("failed to get networks: %w", err)
 		}
 		for i, net := range networks {
 			if net.ID == cfg.NetworkName {
@@ -187,7 +265,7 @@ func resolveDevices(cfg Config, macAddr, ipAddr string) ([]output.ResultRow, err
 		// Slow path: search all orgs for the network (fallback when orgId not provided)
 		orgs, err := client.GetOrganizations(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get organizations: %v", err)
+			return nil, fmt.Errorf("failed to get organizations: %w", err)
 		}
 		if len(orgs) == 0 {
 			return nil, fmt.Errorf("no organizations found")
@@ -214,6 +292,7 @@ func resolveDevices(cfg Config, macAddr, ipAddr string) ([]output.ResultRow, err
 		}
 	}
 
+	client.ShareOrgRateLimit(targetOrg.ID)
 	log.Infof("Resolving in organization: %s, network: %s", targetOrg.Name, targetNetwork.Name)
 
 	// Build MAC matcher
@@ -226,7 +305,7 @@ func resolveDevices(cfg Config, macAddr, ipAddr string) ([]output.ResultRow, err
 
 		resolvedMAC, _, hostname, err := client.ResolveIPToMAC(ctx, targetOrg.ID, []meraki.Network{*targetNetwork}, ipAddr)
 		if err != nil {
-			return nil, fmt.Errorf("failed to resolve IP %s: %v", ipAddr, err)
+			return nil, fmt.Errorf("failed to resolve IP %s: %w", ipAddr, err)
 		}
 
 		log.Debugf("Resolved IP %s to MAC %s (hostname: %s)", ipAddr, resolvedMAC, hostname)
@@ -248,26 +327,35 @@ func resolveDevices(cfg Config, macAddr, ipAddr string) ([]output.ResultRow, err
 	// Get devices and process results (simplified version)
 	devices, err := client.GetDevices(ctx, targetNetwork.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get devices: %v", err)
+		return nil, fmt.Errorf("failed to get devices: %w", err)
 	}
 
-	switches := filters.FilterSwitches(devices)
-	results, err := processSwitchesForResolution(ctx, client, targetOrg, targetNetwork, switches, matcher, resolvedHostname, cfg.MacTablePoll, log)
+	for _, w := range unsupportedDeviceWarnings(targetNetwork.Name, devices) {
+		runWarnings(ctx).Add(w)
+	}
+	switches := filters.ExcludeSwitches(filters.FilterSwitches(devices))
+	appliances := filters.ExcludeSwitches(branchAppliances(devices))
+	results, err := processSwitchesForResolution(ctx, client, targetOrg, targetNetwork, switches, appliances, matcher, resolvedHostname, cfg.MacTablePoll, log)
 	if err != nil {
 		return nil, err
 	}
+	if len(results) == 0 && ipAddr == "" {
+		results = findDeviceMACs(ctx, client, *targetOrg, []meraki.Network{*targetNetwork}, matcher, log)
+	}
 
-	return results, nil
+	return excludeRows(results), nil
 }
 
-func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClient, org *meraki.Organization, network *meraki.Network, switches []meraki.Device, matcher func(string) bool, hostname string, macTablePoll int, log *logger.Logger) ([]output.ResultRow, error) {
+// processSwitchesForResolution searches the switches of network and, for a
+// switchless branch, its appliances (see branchAppliances).
+func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClient, org *meraki.Organization, network *meraki.Network, switches, appliances []meraki.Device, matcher func(string) bool, hostname string, macTablePoll int, log *logger.Logger) ([]output.ResultRow, error) {
 	var results []output.ResultRow
 	resultsIndex := make(map[string]struct{})
 
 	// Get network clients
 	networkClients, err := client.GetNetworkClients(ctx, network.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get network clients: %v", err)
+		return nil, fmt.Errorf("failed to get network clients: %w", err)
 	}
 
 	log.Debugf("Network clients API returned %d clients", len(networkClients))
@@ -294,8 +382,10 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 		}
 	}
 	serialArpCacheWeb := make(map[string]map[string]string)
-	resolveIP := func(normMAC, knownIP, serial string) (string, string) {
-		ip := knownIP
+	hostNames := meraki.HostNamesFrom(ctx)
+	// resolveIP also returns where each value came from, for the explain view.
+	resolveIP := func(normMAC, knownIP, serial string) (string, string, []output.FieldSource) {
+		ip, ipFrom, ipDetail := knownIP, srcNetworkClients, ""
 		if ip == "" {
 			ip = macToIPWeb[normMAC]
 		}
@@ -303,23 +393,24 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 			if _, cached := serialArpCacheWeb[serial]; !cached {
 				serialArpCacheWeb[serial] = client.FetchArpMap(ctx, serial, macTablePoll)
 			}
-			ip = serialArpCacheWeb[serial][normMAC]
+			ip, ipFrom, ipDetail = serialArpCacheWeb[serial][normMAC], srcLiveArpTable, serial
 		}
-		hn := hostname
+		hn, hnFrom := hostname, srcIPLookup
 		if hn == "" {
-			hn = macToHostnameWeb[normMAC]
+			hn, hnFrom = macToHostnameWeb[normMAC], srcNetworkClients
 		}
 		if hn == "" && ip != "" {
-			if hn = meraki.LookupHostOverride(ip, org.Name, network.Name); hn == "" {
-				hn, _ = meraki.ResolveHostname(ip)
+			if hn, hnFrom = hostNames.Override(ip, org.Name, network.Name), srcHostOverride; hn == "" {
+				hn, _ = hostNames.Resolve(ip)
+				hnFrom = srcReverseDNS
 			}
 		}
-		return ip, hn
+		return ip, hn, hostSources(ip, ipFrom, ipDetail, hn, hnFrom)
 	}
 
 	// Build device lookup map
 	deviceBySerial := make(map[string]meraki.Device)
-	for _, dev := range switches {
+	for _, dev := range append(append([]meraki.Device(nil), switches...), appliances...) {
 		deviceBySerial[dev.Serial] = dev
 	}
 
@@ -329,16 +420,21 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 	// The per-device /switch/ports API does not expose linkAggregationId on this hardware.
 	aggrCache := client.GetNetworkLinkAggregations(ctx, network.ID)
 
-	// Build uplink port set using LLDP/CDP data per switch.
-	// Ports where the neighbor has a Meraki dashboard URL are confirmed inter-device uplinks.
-	// The topology/linkLayer API does not include port IDs on this firmware, so LLDP/CDP is used instead.
-	// Results are cached per serial since we query each switch once.
-	uplinkPortCache := make(map[string]map[string]struct{}) // serial → set of uplink portIDs
-	getUplinkPorts := func(serial string) map[string]struct{} {
-		if _, ok := uplinkPortCache[serial]; !ok {
-			uplinkPortCache[serial] = client.GetDeviceUplinkPorts(ctx, serial)
-		}
-		return uplinkPortCache[serial]
+	// Build uplink port set from the port statuses of each switch, which also
+	// give every result its link state. The topology/linkLayer API does not
+	// include port IDs on this firmware. Statuses are fetched once per switch.
+	statuses := newPortStatuses(ctx, client)
+	neighbors := newNeighborUplinks(ctx, client)
+	getUplinkPorts := statuses.uplinks
+	radios := newWirelessRadios(ctx, client)
+	leases := newDHCPLeases(ctx, client)
+	leases.addNetwork(*network, networkClients)
+	devStatuses := newDeviceStatuses(ctx, client, org.ID, []string{network.ID})
+	hardware := newDeviceHardware()
+	hardware.add(switches)
+	hardware.add(appliances)
+	for _, w := range devStatuses.offlineWarnings(network.Name, append(slices.Clone(switches), appliances...)) {
+		runWarnings(ctx).Add(w)
 	}
 
 	// Process network clients
@@ -356,12 +452,34 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 			dev := deviceBySerial[serial]
 			switchName := firstNonEmpty(dev.Name, c.RecentDeviceName, serial)
 
+			if isWirelessClient(c, dev) {
+				row, _ := nonSwitchResult(meraki.Device{Serial: serial, Name: switchName}, "wireless", normMAC, "", c.SSID, config.Config{})
+				row.OrgName, row.NetworkName = org.Name, network.Name
+				row.LastSeen = firstNonEmpty(c.LastSeen, macToLastSeenWeb[normMAC])
+				ip, hn, hostWhy := resolveIP(normMAC, c.IP, "")
+				row.IP, row.Hostname, row.Source = ip, hn, output.SourceNetworkClients
+				row.Explain = explainRow(row, output.FieldSource{Source: srcNetworkClients, Detail: "recent device " + serial}, nil, hostWhy, srcNetworkClients)
+				radios.fill(&row, network.ID, c.ID)
+				addResult(resultsIndex, &results, row)
+				continue
+			}
+			if filters.DeviceType(dev) == "appliance" {
+				row, _ := nonSwitchResult(dev, "appliance", normMAC, firstNonEmpty(c.SwitchportName, c.Switchport, c.Port), "", config.Config{})
+				row.OrgName, row.NetworkName = org.Name, network.Name
+				row.LastSeen = firstNonEmpty(c.LastSeen, macToLastSeenWeb[normMAC])
+				ip, hn, hostWhy := resolveIP(normMAC, c.IP, serial)
+				row.IP, row.Hostname, row.Source = ip, hn, output.SourceNetworkClients
+				row.Explain = explainRow(row, output.FieldSource{Source: srcNetworkClients, Detail: "recent device " + serial}, nil, hostWhy, srcNetworkClients)
+				addResult(resultsIndex, &results, row)
+				continue
+			}
+
 			port := firstNonEmpty(c.SwitchportName, c.Switchport, c.Port, "unknown")
 			aggrMembers := resolveAggrPorts(ctx, client, serial, port, aggrCache)
 			vlan, portMode := enrichPortInfoWithMembers(ctx, client, serial, port, aggrMembers, 0, "")
-			ip, hn := resolveIP(normMAC, c.IP, serial)
+			ip, hn, hostWhy := resolveIP(normMAC, c.IP, serial)
 
-			addResult(resultsIndex, &results, output.ResultRow{
+			row := output.ResultRow{
 				OrgName:      org.Name,
 				NetworkName:  network.Name,
 				SwitchName:   switchName,
@@ -375,7 +493,11 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 				VLAN:         vlan,
 				PortMode:     portMode,
 				IsUplink:     isPortUplink(port, aggrMembers, getUplinkPorts(serial)),
-			})
+				Source:       output.SourceNetworkClients,
+			}
+			row.Explain = explainRow(row, output.FieldSource{Source: srcNetworkClients, Detail: "recent device " + serial},
+				explainPortInfo(serial, port, 0, "", vlan, portMode, srcNetworkClients), hostWhy, srcNetworkClients)
+			addResult(resultsIndex, &results, row)
 		}
 	}
 
@@ -384,6 +506,7 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 		log.Debugf("Querying switch: %s (%s)", firstNonEmpty(dev.Name, dev.Serial), dev.Serial)
 
 		// Try live MAC table lookup with up to 15 retries (30 seconds)
+		tableRead := false
 		macTableID, err := client.CreateMacTableLookup(ctx, dev.Serial)
 		if err != nil {
 			log.Debugf("MAC table lookup not available for %s: %v", dev.Serial, err)
@@ -394,7 +517,9 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 			var macEntries []map[string]interface{}
 			var status string
 			for attempt := 0; attempt < macTablePoll; attempt++ {
-				time.Sleep(2 * time.Second)
+				if err = meraki.SleepContext(ctx, 2*time.Second); err != nil {
+					break
+				}
 				macEntries, status, err = client.GetMacTableLookup(ctx, dev.Serial, macTableID)
 				if err != nil {
 					break
@@ -405,7 +530,9 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 				log.Debugf("MAC table status for %s: %s (attempt %d/%d)", firstNonEmpty(dev.Name, dev.Serial), status, attempt+1, macTablePoll)
 			}
 
+			tableRead = status == "complete"
 			if status == "complete" && len(macEntries) > 0 {
+				tableAt := time.Now()
 				foundInTable := false
 				for _, entry := range macEntries {
 					macStr, _ := entry["mac"].(string)
@@ -427,15 +554,15 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 						portID, _ = entry["interface"].(string)
 					}
 					vlan, _ := entry["vlan"].(float64)
-					portMode, _ := entry["type"].(string)
+					portMode := macTableEntryMode(entry)
 					// Normalize AGGR raw strings (e.g. "AGGR/0=serial/49,...") to clean ID + member list
 					cleanPortID, aggrMembers := parseAggrPort(firstNonEmpty(portID, "unknown"))
 					if aggrMembers == nil {
 						aggrMembers = resolveAggrPorts(ctx, client, dev.Serial, cleanPortID, aggrCache)
 					}
 					richVLAN, richMode := enrichPortInfoWithMembers(ctx, client, dev.Serial, cleanPortID, aggrMembers, int(vlan), portMode)
-					ip, hn := resolveIP(normMAC, "", dev.Serial)
-					addResult(resultsIndex, &results, output.ResultRow{
+					ip, hn, hostWhy := resolveIP(normMAC, "", dev.Serial)
+					row := output.ResultRow{
 						OrgName:      org.Name,
 						NetworkName:  network.Name,
 						SwitchName:   firstNonEmpty(dev.Name, dev.Serial),
@@ -449,7 +576,11 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 						VLAN:         richVLAN,
 						PortMode:     richMode,
 						IsUplink:     isPortUplink(cleanPortID, aggrMembers, getUplinkPorts(dev.Serial)),
-					})
+						Source:       output.SourceMacTable,
+					}
+					row.Explain = explainRow(row, output.FieldSource{Source: srcLiveMacTable, Detail: macTableDetail(macTableID, tableAt)},
+						explainPortInfo(dev.Serial, cleanPortID, int(vlan), portMode, richVLAN, richMode, srcLiveMacTable), hostWhy, srcNetworkClients)
+					addResult(resultsIndex, &results, row)
 					foundInTable = true
 				}
 				// Only skip device-clients fallback if the target MAC was actually
@@ -467,8 +598,14 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 		clients, err := client.GetDeviceClients(ctx, dev.Serial)
 		if err != nil {
 			log.Debugf("Failed to get device clients for %s: %v", dev.Serial, err)
+			if ctx.Err() == nil {
+				runWarnings(ctx).Add(skippedSwitchWarning(network.Name, dev, err))
+			}
 			continue
 		}
+		if !tableRead {
+			runWarnings(ctx).Add(staleSwitchWarning(network.Name, dev))
+		}
 		for _, c := range clients {
 			normMAC, err := macaddr.NormalizeExactMac(c.MAC)
 			if err != nil || !matcher(normMAC) {
@@ -477,8 +614,8 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 			port := firstNonEmpty(c.SwitchportName, c.Switchport, c.Port, "unknown")
 			aggrMembers3 := resolveAggrPorts(ctx, client, dev.Serial, port, aggrCache)
 			vlan, portMode := enrichPortInfoWithMembers(ctx, client, dev.Serial, port, aggrMembers3, 0, "")
-			ip, hn := resolveIP(normMAC, "", dev.Serial)
-			addResult(resultsIndex, &results, output.ResultRow{
+			ip, hn, hostWhy := resolveIP(normMAC, "", dev.Serial)
+			row := output.ResultRow{
 				OrgName:      org.Name,
 				NetworkName:  network.Name,
 				SwitchName:   firstNonEmpty(dev.Name, dev.Serial),
@@ -492,9 +629,61 @@ func processSwitchesForResolution(ctx context.Context, client *meraki.MerakiClie
 				VLAN:         vlan,
 				PortMode:     portMode,
 				IsUplink:     isPortUplink(port, aggrMembers3, getUplinkPorts(dev.Serial)),
-			})
+				Source:       output.SourceDeviceClients,
+			}
+			row.Explain = explainRow(row, output.FieldSource{Source: srcDeviceClients, Detail: "GET /devices/" + dev.Serial + "/clients"},
+				explainPortInfo(dev.Serial, port, 0, "", vlan, portMode, srcDeviceClients), hostWhy, srcDeviceClients)
+			addResult(resultsIndex, &results, row)
+		}
+	}
+
+	// Appliances have no MAC table: their client lists and live ARP tables
+	// show who is attached.
+	for _, dev := range appliances {
+		log.Debugf("Querying appliance: %s (%s)", firstNonEmpty(dev.Name, dev.Serial), dev.Serial)
+		clients, err := client.GetDeviceClients(ctx, dev.Serial)
+		if err != nil {
+			log.Debugf("Failed to get device clients for %s: %v", dev.Serial, err)
+		}
+		found := make(map[string]bool)
+		for _, c := range clients {
+			normMAC, err := macaddr.NormalizeExactMac(c.MAC)
+			if err != nil || !matcher(normMAC) {
+				continue
+			}
+			found[normMAC] = true
+			row, _ := nonSwitchResult(dev, "appliance", normMAC, firstNonEmpty(c.SwitchportName, c.Switchport, c.Port), "", config.Config{})
+			row.OrgName, row.NetworkName = org.Name, network.Name
+			row.LastSeen = firstNonEmpty(c.LastSeen, macToLastSeenWeb[normMAC])
+			ip, hn, hostWhy := resolveIP(normMAC, "", dev.Serial)
+			row.IP, row.Hostname, row.Source = ip, hn, output.SourceDeviceClients
+			row.Explain = explainRow(row, output.FieldSource{Source: srcDeviceClients, Detail: "GET /devices/" + dev.Serial + "/clients"}, nil, hostWhy, srcDeviceClients)
+			addResult(resultsIndex, &results, row)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if _, cached := serialArpCacheWeb[dev.Serial]; !cached {
+			serialArpCacheWeb[dev.Serial] = client.FetchArpMap(ctx, dev.Serial, macTablePoll)
+		}
+		for _, row := range applianceArpResults(dev, serialArpCacheWeb[dev.Serial], matcher, found, config.Config{}) {
+			normMAC, _ := macaddr.NormalizeExactMac(row.MAC)
+			row.OrgName, row.NetworkName = org.Name, network.Name
+			row.LastSeen = macToLastSeenWeb[normMAC]
+			_, hn, hostWhy := resolveIP(normMAC, row.IP, "")
+			row.Hostname = hn
+			row.Explain = setFieldSource(explainRow(row, output.FieldSource{Source: srcLiveArpTable, Detail: dev.Serial}, nil, hostWhy, srcNetworkClients),
+				output.FieldSource{Field: "IP", Source: srcLiveArpTable, Detail: dev.Serial})
+			addResult(resultsIndex, &results, row)
 		}
 	}
 
+	for i := range results {
+		statuses.fill(&results[i])
+		neighbors.fillNeighbor(&results[i])
+		leases.fill(&results[i])
+		devStatuses.fill(&results[i])
+		hardware.fill(&results[i])
+	}
 	return results, nil
 }
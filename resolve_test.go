@@ -164,3 +164,26 @@ func TestResolveAggrPorts_CacheHit(t *testing.T) {
 		t.Errorf("resolveAggrPorts() cache hit = %v, want [51 52]", result)
 	}
 }
+
+func TestMacTableEntryType(t *testing.T) {
+	tests := []struct {
+		entry              map[string]interface{}
+		wantType, wantMode string
+	}{
+		{map[string]interface{}{"type": "access"}, "", "access"},
+		{map[string]interface{}{"type": "trunk", "entryType": "Dynamic"}, "dynamic", "trunk"},
+		{map[string]interface{}{"type": "STATIC"}, "static", ""},
+		{map[string]interface{}{"entryType": "sticky"}, "static", ""},
+		{map[string]interface{}{"type": "secure-dynamic"}, "static", ""},
+		{map[string]interface{}{"type": "learned"}, "dynamic", ""},
+		{map[string]interface{}{}, "", ""},
+	}
+	for _, tt := range tests {
+		if got := macTableEntryType(tt.entry); got != tt.wantType {
+			t.Errorf("macTableEntryType(%v) = %q, want %q", tt.entry, got, tt.wantType)
+		}
+		if got := macTableEntryMode(tt.entry); got != tt.wantMode {
+			t.Errorf("macTableEntryMode(%v) = %q, want %q", tt.entry, got, tt.wantMode)
+		}
+	}
+}
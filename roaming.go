@@ -0,0 +1,139 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// roamingHistoryLimit caps the wireless associations shown per client.
+const roamingHistoryLimit = 5
+
+// wirelessAssociation is one wireless association of a client to an AP.
+type wirelessAssociation struct {
+	MAC        string // colon-formatted client MAC that associated
+	At         string // event timestamp
+	DeviceName string // AP name
+	SSID       string
+}
+
+// roamingHistory pairs a wired result row with the client's recent wireless associations.
+type roamingHistory struct {
+	Wired    output.ResultRow
+	Wireless []wirelessAssociation
+}
+
+// buildRoamingHistory finds recent wireless associations for a wired result.
+// Dual-homed devices usually use a different MAC for Wi-Fi than for the dock, so
+// besides the row's own MAC, any client in the network with the same hostname is
+// included.
+func buildRoamingHistory(ctx context.Context, client *meraki.MerakiClient, network meraki.Network, networkClients []meraki.NetworkClient, row output.ResultRow, log *logger.Logger) roamingHistory {
+	h := roamingHistory{Wired: row}
+
+	macs := []string{row.MAC}
+	if row.Hostname != "" {
+		for _, nc := range networkClients {
+			if nc.RecentConnection != "Wireless" || !strings.EqualFold(meraki.ClientHostname(nc), row.Hostname) {
+				continue
+			}
+			if norm, err := macaddr.NormalizeExactMac(nc.MAC); err == nil {
+				if mac := macaddr.FormatMacColon(norm); mac != row.MAC {
+					macs = append(macs, mac)
+				}
+			}
+		}
+	}
+
+	for _, mac := range macs {
+		events, err := client.GetNetworkEvents(ctx, network.ID, url.Values{
+			"productType":          []string{"wireless"},
+			"clientMac":            []string{mac},
+			"includedEventTypes[]": []string{"association"},
+			"perPage":              []string{fmt.Sprint(roamingHistoryLimit)},
+		})
+		if err != nil {
+			log.Debugf("Wireless events for %s in %s: %v", mac, network.Name, err)
+			continue
+		}
+		for _, ev := range events {
+			ssid := ev.SsidName
+			if ssid == "" && ev.SsidNumber != nil {
+				ssid = fmt.Sprintf("SSID #%d", *ev.SsidNumber)
+			}
+			h.Wireless = append(h.Wireless, wirelessAssociation{
+				MAC:        mac,
+				At:         ev.OccurredAt,
+				DeviceName: firstNonEmpty(ev.DeviceName, ev.DeviceSerial),
+				SSID:       ssid,
+			})
+		}
+	}
+	return h
+}
+
+// writeRoamingHistory writes the wired location and wireless associations of a client.
+func writeRoamingHistory(w io.Writer, h roamingHistory) {
+	_, _ = fmt.Fprintf(w, "Roaming history for %s%s:\n", h.Wired.MAC, hostnameSuffix(h.Wired.Hostname))
+	_, _ = fmt.Fprintf(w, "  Wired:    %s port %s (last seen %s)\n", h.Wired.SwitchName, h.Wired.Port, firstNonEmpty(h.Wired.LastSeen, "unknown"))
+	if len(h.Wireless) == 0 {
+		_, _ = fmt.Fprintln(w, "  Wireless: no recent associations")
+		return
+	}
+	for _, a := range h.Wireless {
+		_, _ = fmt.Fprintf(w, "  Wireless: %s on %s via %s at %s\n", a.MAC, firstNonEmpty(a.SSID, "unknown SSID"), a.DeviceName, a.At)
+	}
+}
+
+// hostnameSuffix returns " (hostname)" or "" when hostname is empty.
+func hostnameSuffix(hostname string) string {
+	if hostname == "" {
+		return ""
+	}
+	return " (" + hostname + ")"
+}
+
+// reportRoamingHistory writes roaming history for every distinct MAC in rows.
+func reportRoamingHistory(ctx context.Context, w io.Writer, client *meraki.MerakiClient, networks []meraki.Network, rows []output.ResultRow, log *logger.Logger) {
+	byName := make(map[string]meraki.Network, len(networks))
+	for _, n := range networks {
+		byName[n.Name] = n
+	}
+	clientsByNet := make(map[string][]meraki.NetworkClient)
+	seen := make(map[string]struct{})
+	for _, r := range rows {
+		network, ok := byName[r.NetworkName]
+		if !ok || r.IsUplink {
+			continue
+		}
+		if _, dup := seen[r.MAC]; dup {
+			continue
+		}
+		seen[r.MAC] = struct{}{}
+		if _, cached := clientsByNet[network.ID]; !cached {
+			clientsByNet[network.ID], _ = client.GetNetworkClients(ctx, network.ID)
+		}
+		writeRoamingHistory(w, buildRoamingHistory(ctx, client, network, clientsByNet[network.ID], r, log))
+	}
+}
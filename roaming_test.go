@@ -0,0 +1,64 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestBuildRoamingHistory_LinksWirelessMACByHostname(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/networks/N1/events" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("clientMac") == "aa:aa:aa:00:00:02" {
+			_, _ = w.Write([]byte(`{"events":[{"occurredAt":"2026-10-01T09:00:00Z","deviceName":"AP-Lobby","ssidName":"Corp"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"events":[]}`))
+	}))
+	defer srv.Close()
+
+	networkClients := []meraki.NetworkClient{
+		{MAC: "aa:aa:aa:00:00:02", Hostname: "LAPTOP-7", RecentConnection: "Wireless"},
+		{MAC: "aa:aa:aa:00:00:03", Hostname: "other", RecentConnection: "Wireless"},
+	}
+	row := output.ResultRow{MAC: "aa:aa:aa:00:00:01", Hostname: "laptop-7", SwitchName: "sw1", Port: "4"}
+	h := buildRoamingHistory(context.Background(), meraki.NewClient("key", srv.URL, 1), meraki.Network{ID: "N1"}, networkClients, row, logger.NewWriter(io.Discard, logger.LevelError))
+
+	if len(h.Wireless) != 1 || h.Wireless[0].SSID != "Corp" || h.Wireless[0].MAC != "aa:aa:aa:00:00:02" {
+		t.Fatalf("Wireless = %+v, want one Corp association from the Wi-Fi MAC", h.Wireless)
+	}
+
+	var buf bytes.Buffer
+	writeRoamingHistory(&buf, h)
+	for _, want := range []string{"laptop-7", "sw1 port 4", "Corp via AP-Lobby"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writeRoamingHistory() missing %q\nfull:\n%s", want, buf.String())
+		}
+	}
+}
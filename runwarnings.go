@@ -0,0 +1,80 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"Find-Meraki-Ports-With-MAC/pkg/filters"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+type runWarningsKey struct{}
+
+// withRunWarnings returns ctx carrying ws, so a web search can collect the
+// warnings of resolveDevices for its response.
+func withRunWarnings(ctx context.Context, ws *output.Warnings) context.Context {
+	return context.WithValue(ctx, runWarningsKey{}, ws)
+}
+
+// runWarnings returns the warnings collection carried by ctx, or nil, to which
+// adding is a no-op.
+func runWarnings(ctx context.Context) *output.Warnings {
+	ws, _ := ctx.Value(runWarningsKey{}).(*output.Warnings)
+	return ws
+}
+
+// skippedSwitchWarning reports a switch whose MAC table and client list could
+// both not be read, so a device on it cannot have been found.
+func skippedSwitchWarning(network string, dev meraki.Device, err error) output.Warning {
+	return output.Warning{
+		Kind:    output.WarningSkippedSwitch,
+		Message: fmt.Sprintf("switch %s in %s was skipped: %v", firstNonEmpty(dev.Name, dev.Serial), network, err),
+		Network: network,
+		Device:  dev.Serial,
+	}
+}
+
+// staleSwitchWarning reports a switch searched through its client history
+// because its live MAC table could not be read.
+func staleSwitchWarning(network string, dev meraki.Device) output.Warning {
+	return output.Warning{
+		Kind:    output.WarningStaleData,
+		Message: fmt.Sprintf("live MAC table of %s in %s was unavailable; its results come from client history and may be out of date", firstNonEmpty(dev.Name, dev.Serial), network),
+		Network: network,
+		Device:  dev.Serial,
+	}
+}
+
+// unsupportedDeviceWarnings reports the devices of network that have neither a
+// product type nor a model this tool recognizes, and so are never searched.
+func unsupportedDeviceWarnings(network string, devices []meraki.Device) []output.Warning {
+	var out []output.Warning
+	for _, d := range devices {
+		if filters.DeviceType(d) != "" {
+			continue
+		}
+		out = append(out, output.Warning{
+			Kind:    output.WarningUnsupportedDevice,
+			Message: fmt.Sprintf("device %s (model %s) in %s is not searched: unknown device type; add its model with --switch-models if it is a switch", firstNonEmpty(d.Name, d.Serial), firstNonEmpty(d.Model, "unknown"), network),
+			Network: network,
+			Device:  d.Serial,
+		})
+	}
+	return out
+}
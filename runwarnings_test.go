@@ -0,0 +1,84 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestProcessSwitchesForResolution_Warnings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/networks/N1/clients", "/devices/Q2AA-0001/clients":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			// No live tools for either switch, no client list for Q2AA-0002.
+			http.Error(w, `{"errors":["Not found"]}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ws := &output.Warnings{}
+	ctx := withRunWarnings(context.Background(), ws)
+	switches := []meraki.Device{
+		{Serial: "Q2AA-0001", Name: "sw-history", ProductType: "switch"},
+		{Serial: "Q2AA-0002", Name: "sw-dark", ProductType: "switch"},
+	}
+	_, err := processSwitchesForResolution(ctx, meraki.NewClient("key", srv.URL, 1), &meraki.Organization{ID: "O1"}, &meraki.Network{ID: "N1", Name: "HQ"},
+		switches, nil, func(string) bool { return true }, "", 1, logger.NewWriter(io.Discard, logger.LevelError))
+	if err != nil {
+		t.Fatalf("processSwitchesForResolution() error: %v", err)
+	}
+
+	got := ws.List()
+	if len(got) != 2 {
+		t.Fatalf("warnings = %+v, want one stale and one skipped switch", got)
+	}
+	if got[0].Kind != output.WarningStaleData || got[0].Device != "Q2AA-0001" || got[0].Network != "HQ" {
+		t.Errorf("warnings[0] = %+v, want stale data for sw-history", got[0])
+	}
+	if got[1].Kind != output.WarningSkippedSwitch || got[1].Device != "Q2AA-0002" {
+		t.Errorf("warnings[1] = %+v, want sw-dark skipped", got[1])
+	}
+}
+
+func TestUnsupportedDeviceWarnings(t *testing.T) {
+	devices := []meraki.Device{
+		{Serial: "Q2AA-0001", Model: "MS120-8", ProductType: "switch"},
+		{Serial: "Q2AA-0002", Model: "MR46"},
+		{Serial: "Q2AA-0003", Name: "closet-9", Model: "XY900"},
+	}
+	got := unsupportedDeviceWarnings("HQ", devices)
+	if len(got) != 1 || got[0].Device != "Q2AA-0003" || got[0].Kind != output.WarningUnsupportedDevice {
+		t.Fatalf("unsupportedDeviceWarnings() = %+v, want only the XY900", got)
+	}
+}
+
+func TestRunWarnings_NilWithoutCollection(t *testing.T) {
+	// Adding outside a collecting run must be harmless.
+	runWarnings(context.Background()).Add(output.Warning{Kind: output.WarningStaleData})
+	if got := runWarnings(context.Background()).List(); got != nil {
+		t.Errorf("List() = %+v, want nil", got)
+	}
+}
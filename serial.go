@@ -0,0 +1,154 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/filters"
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// findSwitchBySerial returns the device with serial (case-insensitive) and the
+// network it belongs to, searching only the given networks.
+func findSwitchBySerial(ctx context.Context, client *meraki.MerakiClient, networks []meraki.Network, serial string) (meraki.Device, meraki.Network, error) {
+	for _, net := range networks {
+		devices, err := client.GetDevices(ctx, net.ID)
+		if err != nil {
+			return meraki.Device{}, meraki.Network{}, err
+		}
+		for _, d := range devices {
+			if strings.EqualFold(d.Serial, serial) {
+				return d, net, nil
+			}
+		}
+	}
+	return meraki.Device{}, meraki.Network{}, fmt.Errorf("switch %s not found in the selected networks", serial)
+}
+
+// listSwitchClients lists every MAC on one switch with its port, without a
+// search term. Unlike --test-full-table it merges two sources: the live MAC
+// table (what is connected now) and the device clients history (what was
+// connected recently). A MAC seen by both on the same port becomes one
+// mac-table row with the history's last-seen time; history-only MACs are kept
+// as device-clients rows. IP and hostname come from the network clients list.
+func listSwitchClients(ctx context.Context, client *meraki.MerakiClient, org meraki.Organization, networks []meraki.Network, serial, portFilter string, macTablePoll int, log *logger.Logger) ([]output.ResultRow, error) {
+	dev, net, err := findSwitchBySerial(ctx, client, networks, serial)
+	if err != nil {
+		return nil, err
+	}
+	name := firstNonEmpty(dev.Name, dev.Serial)
+	log.Debugf("Listing clients of %s (%s) in network %s", name, dev.Serial, net.Name)
+
+	netClients := make(map[string]meraki.NetworkClient)
+	if ncs, err := client.GetNetworkClients(ctx, net.ID); err != nil {
+		log.Warnf("Network clients unavailable for %s; IPs and hostnames will be missing: %v", net.Name, err)
+	} else {
+		for _, nc := range ncs {
+			if norm, err := macaddr.NormalizeExactMac(nc.MAC); err == nil {
+				netClients[norm] = nc
+			}
+		}
+	}
+	aggrCache := client.GetNetworkLinkAggregations(ctx, net.ID)
+	uplinks := client.GetDeviceUplinkPorts(ctx, dev.Serial)
+
+	var results []output.ResultRow
+	index := make(map[string]struct{})
+	rowAt := make(map[string]int) // serial|port|mac → index in results
+	add := func(normMAC, rawPort string, vlan int, mode, entryType, lastSeen, source string, from output.FieldSource) {
+		port, aggrMembers := parseAggrPort(firstNonEmpty(rawPort, "unknown"))
+		if !filters.MatchesPortFilter(port, portFilter) {
+			return
+		}
+		if aggrMembers == nil {
+			aggrMembers = resolveAggrPorts(ctx, client, dev.Serial, port, aggrCache)
+		}
+		nc := netClients[normMAC]
+		row := output.ResultRow{
+			OrgName:      org.Name,
+			NetworkName:  net.Name,
+			SwitchName:   name,
+			SwitchSerial: dev.Serial,
+			Port:         port,
+			AggrPorts:    aggrMembers,
+			MAC:          macaddr.FormatMacColon(normMAC),
+			IP:           nc.IP,
+			Hostname:     meraki.ClientHostname(nc),
+			LastSeen:     firstNonEmpty(lastSeen, nc.LastSeen),
+			EntryType:    entryType,
+			IsUplink:     isPortUplink(port, aggrMembers, uplinks),
+			Source:       source,
+		}
+		key := fmt.Sprintf("%s|%s|%s", row.SwitchSerial, row.Port, row.MAC)
+		if i, ok := rowAt[key]; ok {
+			// Already in the live table: the history only contributes its timestamp.
+			if lastSeen != "" {
+				results[i].LastSeen = lastSeen
+				results[i].Explain = setFieldSource(results[i].Explain, output.FieldSource{Field: "LastSeen", Source: from.Source})
+			}
+			return
+		}
+		row.VLAN, row.PortMode = enrichPortInfoWithMembers(ctx, client, dev.Serial, port, aggrMembers, vlan, mode)
+		lastSeenFrom := srcNetworkClients
+		if lastSeen != "" {
+			lastSeenFrom = from.Source
+		}
+		row.Explain = explainRow(row, from, explainPortInfo(dev.Serial, port, vlan, mode, row.VLAN, row.PortMode, from.Source),
+			hostSources(row.IP, srcNetworkClients, "", row.Hostname, srcNetworkClients), lastSeenFrom)
+		if addResult(index, &results, row) {
+			rowAt[key] = len(results) - 1
+		}
+	}
+
+	live := pollMacTable(ctx, client, dev.Serial, macTablePoll)
+	liveFrom := output.FieldSource{Source: srcLiveMacTable, Detail: macTableDetail("", time.Now())}
+	log.Debugf("Live MAC table returned %d entries for %s", len(live), name)
+	for _, entry := range live {
+		macStr, _ := entry["mac"].(string)
+		normMAC, err := macaddr.NormalizeExactMac(macStr)
+		if err != nil {
+			continue
+		}
+		vlan, _ := entry["vlan"].(float64)
+		add(normMAC, macTableEntryPort(entry), int(vlan), macTableEntryMode(entry), macTableEntryType(entry), "", output.SourceMacTable, liveFrom)
+	}
+
+	history, err := client.GetDeviceClients(ctx, dev.Serial)
+	if err != nil {
+		log.Warnf("Device clients unavailable for %s: %v", name, err)
+	}
+	log.Debugf("Device clients API returned %d clients for %s", len(history), name)
+	for _, c := range history {
+		normMAC, err := macaddr.NormalizeExactMac(c.MAC)
+		if err != nil {
+			continue
+		}
+		add(normMAC, firstNonEmpty(c.SwitchportName, c.Switchport, c.Port), 0, "", "", c.LastSeen, output.SourceDeviceClients,
+			output.FieldSource{Source: srcDeviceClients, Detail: "GET /devices/" + dev.Serial + "/clients"})
+	}
+
+	if live == nil && err != nil {
+		return nil, fmt.Errorf("no client data for %s: live MAC table unavailable and device clients failed: %v", name, err)
+	}
+	return results, nil
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestListSwitchClients(t *testing.T) {
+	defer func(d time.Duration) { macTablePollInterval = d }(macTablePollInterval)
+	macTablePollInterval = 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/networks/N1/devices":
+			_, _ = w.Write([]byte(`[{"serial":"Q2AA-0001","name":"idf-1","model":"MS120","productType":"switch"}]`))
+		case "/networks/N1/clients":
+			_, _ = w.Write([]byte(`[{"mac":"aa:bb:cc:00:00:01","ip":"10.0.0.5","hostname":"printer-1"}]`))
+		case "/devices/Q2AA-0001/liveTools/macTable":
+			_, _ = w.Write([]byte(`{"macTableId":"T1"}`))
+		case "/devices/Q2AA-0001/liveTools/macTable/T1":
+			_, _ = w.Write([]byte(`{"status":"complete","entries":[
+				{"mac":"aa:bb:cc:00:00:01","portId":"3","vlan":10,"type":"access","entryType":"dynamic"},
+				{"mac":"aa:bb:cc:00:00:02","portId":"49","vlan":1,"type":"STATIC"}]}`))
+		case "/devices/Q2AA-0001/clients":
+			_, _ = w.Write([]byte(`[
+				{"mac":"AA:BB:CC:00:00:01","switchport":"3","lastSeen":"2026-03-02T14:00:00Z"},
+				{"mac":"aa:bb:cc:00:00:03","switchport":"7","lastSeen":"2026-03-01T08:00:00Z"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := meraki.NewClient("key", srv.URL, 1)
+	networks := []meraki.Network{{ID: "N1", Name: "HQ"}}
+	log := logger.NewWriter(io.Discard, logger.LevelError)
+	org := meraki.Organization{ID: "O1", Name: "Acme"}
+
+	rows, err := listSwitchClients(context.Background(), client, org, networks, "q2aa-0001", "", 2, log)
+	if err != nil {
+		t.Fatalf("listSwitchClients() error: %v", err)
+	}
+	got := make(map[string]output.ResultRow)
+	for _, r := range rows {
+		got[r.MAC] = r
+	}
+	if len(rows) != 3 {
+		t.Fatalf("listSwitchClients() = %d rows, want 3 (live and history merged): %+v", len(rows), rows)
+	}
+	if r := got["aa:bb:cc:00:00:01"]; r.Source != output.SourceMacTable || r.LastSeen != "2026-03-02T14:00:00Z" ||
+		r.IP != "10.0.0.5" || r.Hostname != "printer-1" || r.VLAN != 10 || r.SwitchName != "idf-1" || r.EntryType != "dynamic" {
+		t.Errorf("merged row = %+v", r)
+	}
+	if r := got["aa:bb:cc:00:00:02"]; r.EntryType != "static" || r.PortMode == "STATIC" {
+		t.Errorf("static entry row = %+v, want entry type static and no port mode from it", r)
+	}
+	if r := got["aa:bb:cc:00:00:03"]; r.Source != output.SourceDeviceClients || r.Port != "7" || r.EntryType != "" {
+		t.Errorf("history-only row = %+v", r)
+	}
+
+	rows, _ = listSwitchClients(context.Background(), client, org, networks, "Q2AA-0001", "49", 2, log)
+	if len(rows) != 1 || rows[0].MAC != "aa:bb:cc:00:00:02" {
+		t.Errorf("listSwitchClients(port 49) = %+v", rows)
+	}
+
+	if _, err := listSwitchClients(context.Background(), client, org, networks, "Q2ZZ-9999", "", 2, log); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("listSwitchClients(unknown serial) error = %v", err)
+	}
+}
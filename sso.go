@@ -0,0 +1,406 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/oidc"
+
+	"github.com/gorilla/mux"
+)
+
+// webRole is what a signed-in user may do in the web interface. Each role
+// includes the ones before it.
+type webRole int
+
+const (
+	roleNone     webRole = iota
+	roleViewer           // search, browse topology and inventory
+	roleOperator         // also blink switch LEDs
+	roleAdmin            // also the debug and log endpoints
+)
+
+func (r webRole) String() string {
+	switch r {
+	case roleViewer:
+		return "viewer"
+	case roleOperator:
+		return "operator"
+	case roleAdmin:
+		return "admin"
+	}
+	return "none"
+}
+
+// Cookie names and lifetimes.
+const (
+	sessionCookie   = "fmp_session"
+	loginCookie     = "fmp_login"
+	sessionLifetime = 8 * time.Hour
+	loginLifetime   = 10 * time.Minute
+)
+
+// serverKeyToken stands in for the server's Meraki API key in the browser
+// when SSO is on, so signed-in users can search with the key without ever
+// receiving it. Real keys are 40 hex digits and cannot collide with it.
+const serverKeyToken = "server-key"
+
+// webSSO is the sign-in configuration of the web server, or nil when the web
+// interface is open to anyone who can reach it.
+var webSSO *ssoAuth
+
+// ssoAuth signs users in with OpenID Connect and maps their groups to roles.
+type ssoAuth struct {
+	provider    *oidc.Provider
+	groupsClaim string
+	groups      map[webRole][]string // role → groups granting it; "*" is any signed-in user
+	key         []byte               // signs the session and login cookies
+	secure      bool                 // cookies only over HTTPS
+	now         func() time.Time
+}
+
+// webSession is the signed content of the session cookie, or the session an
+// API token acts as (see tokenSession).
+type webSession struct {
+	Subject string     `json:"sub"`
+	Name    string     `json:"name"`
+	Role    webRole    `json:"role"`
+	Scope   tokenScope `json:"-"` // API tokens only
+	Expires int64      `json:"exp"`
+}
+
+// loginState is the signed content of the cookie that carries a sign-in from
+// /auth/login to /auth/callback.
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Next     string `json:"next"`
+	Expires  int64  `json:"exp"`
+}
+
+// newSSOAuth reads the OIDC_* settings and discovers the provider. It returns
+// nil, nil when OIDC_ISSUER is unset. defaultRedirect is this server's
+// /auth/callback URL, used when OIDC_REDIRECT_URL is unset.
+func newSSOAuth(ctx context.Context, getenv func(string) string, defaultRedirect string) (*ssoAuth, error) {
+	issuer := strings.TrimSpace(getenv("OIDC_ISSUER"))
+	if issuer == "" {
+		return nil, nil
+	}
+	cfg := oidc.Config{
+		Issuer:       issuer,
+		ClientID:     strings.TrimSpace(getenv("OIDC_CLIENT_ID")),
+		ClientSecret: strings.TrimSpace(getenv("OIDC_CLIENT_SECRET")),
+		RedirectURL:  firstNonEmpty(strings.TrimSpace(getenv("OIDC_REDIRECT_URL")), defaultRedirect),
+		Scopes:       strings.Fields(firstNonEmpty(getenv("OIDC_SCOPES"), "profile email")),
+	}
+	if cfg.ClientID == "" {
+		return nil, errors.New("OIDC_CLIENT_ID is required with OIDC_ISSUER")
+	}
+	a := &ssoAuth{
+		groupsClaim: firstNonEmpty(strings.TrimSpace(getenv("OIDC_GROUPS_CLAIM")), "groups"),
+		groups:      map[webRole][]string{},
+		secure:      strings.HasPrefix(cfg.RedirectURL, "https://"),
+		now:         time.Now,
+	}
+	for role, env := range map[webRole]string{roleViewer: "OIDC_VIEWER_GROUPS", roleOperator: "OIDC_OPERATOR_GROUPS", roleAdmin: "OIDC_ADMIN_GROUPS"} {
+		for _, g := range strings.Split(getenv(env), ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				a.groups[role] = append(a.groups[role], g)
+			}
+		}
+	}
+	if len(a.groups) == 0 {
+		return nil, errors.New("set OIDC_VIEWER_GROUPS, OIDC_OPERATOR_GROUPS or OIDC_ADMIN_GROUPS (use * to admit every signed-in user)")
+	}
+	if k := getenv("OIDC_SESSION_KEY"); k != "" {
+		if len(k) < 32 {
+			return nil, errors.New("OIDC_SESSION_KEY must be at least 32 characters")
+		}
+		a.key = []byte(k)
+	} else {
+		// Sessions end when the server restarts.
+		a.key = make([]byte, 32)
+		if _, err := rand.Read(a.key); err != nil {
+			return nil, err
+		}
+	}
+	p, err := oidc.Discover(ctx, cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	a.provider = p
+	return a, nil
+}
+
+// roleFor returns the highest role any of groups grants.
+func (a *ssoAuth) roleFor(groups []string) webRole {
+	best := roleNone
+	for role, allowed := range a.groups {
+		for _, g := range allowed {
+			if role > best && (g == "*" || slices.Contains(groups, g)) {
+				best = role
+			}
+		}
+	}
+	return best
+}
+
+// requiredRole is the least role that may make request r.
+func requiredRole(r *http.Request) webRole {
+	switch p := r.URL.Path; {
+	case p == "/api/identify":
+		return roleOperator
+	case p == "/api/logs" || strings.HasPrefix(p, "/ws/") || strings.HasPrefix(p, "/api/debug/") || strings.HasPrefix(p, "/api/tokens") || p == "/api/maintenance":
+		return roleAdmin
+	}
+	return roleViewer
+}
+
+// registerRoutes adds the sign-in endpoints.
+func (a *ssoAuth) registerRoutes(r *mux.Router) {
+	r.HandleFunc("/auth/login", a.handleLogin).Methods("GET")
+	r.HandleFunc("/auth/callback", a.handleCallback).Methods("GET")
+	r.HandleFunc("/auth/logout", a.handleLogout).Methods("GET", "POST")
+}
+
+// wrap requires a session with a sufficient role for everything but the
+// sign-in endpoints and static files. Pages redirect to the sign-in; API
+// calls get 401 so the UI can reload. Operator and admin actions are logged
+// with the user's name.
+func (a *ssoAuth) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/auth/") || strings.HasPrefix(r.URL.Path, "/static/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		s, ok := a.session(r)
+		if !ok {
+			if r.Method == http.MethodGet && r.Header.Get("Authorization") == "" && !strings.HasPrefix(r.URL.Path, "/api/") && !strings.HasPrefix(r.URL.Path, "/ws/") {
+				http.Redirect(w, r, "/auth/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error": "Sign-in required"}`, http.StatusUnauthorized)
+			return
+		}
+		need := requiredRole(r)
+		if s.Role < need {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, fmt.Sprintf(`{"error": "This needs the %s role"}`, need), http.StatusForbidden)
+			return
+		}
+		if s.Scope != scopeNone && s.Scope < requiredScope(r) {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, fmt.Sprintf(`{"error": "This needs a token with the %s scope"}`, requiredScope(r)), http.StatusForbidden)
+			return
+		}
+		setAccessUser(r.Context(), s.Name)
+		if need > roleViewer {
+			newWebLogger().Infof("%s (%s): %s %s", s.Name, s.Role, r.Method, r.URL.Path)
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, s)))
+	})
+}
+
+type sessionContextKey struct{}
+
+// sessionFrom returns the signed-in user of a request passed by wrap.
+func sessionFrom(ctx context.Context) (webSession, bool) {
+	s, ok := ctx.Value(sessionContextKey{}).(webSession)
+	return s, ok
+}
+
+// session returns the valid, unexpired session of r. A request with an
+// Authorization header is judged by its API token alone.
+func (a *ssoAuth) session(r *http.Request) (webSession, bool) {
+	if raw, ok := bearerToken(r); ok {
+		t, ok := webTokens.authenticate(raw)
+		if !ok {
+			return webSession{}, false
+		}
+		return tokenSession(t), true
+	}
+	var s webSession
+	c, err := r.Cookie(sessionCookie)
+	if err != nil || !a.open("session", c.Value, &s) || a.now().Unix() >= s.Expires || s.Role == roleNone {
+		return webSession{}, false
+	}
+	return s, true
+}
+
+// handleLogin starts a sign-in and sends the browser to the provider.
+func (a *ssoAuth) handleLogin(w http.ResponseWriter, r *http.Request) {
+	st := loginState{
+		State:    oidc.RandomString(),
+		Nonce:    oidc.RandomString(),
+		Verifier: oidc.RandomString(),
+		Next:     safeNext(r.URL.Query().Get("next")),
+		Expires:  a.now().Add(loginLifetime).Unix(),
+	}
+	a.setCookie(w, loginCookie, "/auth/", a.seal("login", st), loginLifetime)
+	http.Redirect(w, r, a.provider.AuthCodeURL(st.State, st.Nonce, st.Verifier), http.StatusFound)
+}
+
+// handleCallback completes a sign-in: it checks the state, redeems the code,
+// verifies the ID token and issues the session cookie.
+func (a *ssoAuth) handleCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var st loginState
+	c, err := r.Cookie(loginCookie)
+	if err != nil || !a.open("login", c.Value, &st) || a.now().Unix() >= st.Expires {
+		ssoPage(w, http.StatusBadRequest, "Sign-in expired", "Start again from the <a href=\"/auth/login\">sign-in page</a>.")
+		return
+	}
+	a.setCookie(w, loginCookie, "/auth/", "", -1)
+	if e := q.Get("error"); e != "" {
+		ssoPage(w, http.StatusForbidden, "Sign-in failed", html.EscapeString(strings.TrimSpace(e+": "+q.Get("error_description"))))
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(st.State)) != 1 {
+		ssoPage(w, http.StatusBadRequest, "Sign-in failed", "The sign-in response does not belong to this browser.")
+		return
+	}
+	raw, err := a.provider.Exchange(r.Context(), q.Get("code"), st.Verifier)
+	if err == nil {
+		var claims oidc.Claims
+		if claims, err = a.provider.Verify(r.Context(), raw, st.Nonce, a.now()); err == nil {
+			a.startSession(w, r, claims, st.Next)
+			return
+		}
+	}
+	newWebLogger().Warnf("SSO sign-in failed: %v", err)
+	ssoPage(w, http.StatusForbidden, "Sign-in failed", html.EscapeString(err.Error()))
+}
+
+// startSession maps the user's groups to a role and issues the session.
+func (a *ssoAuth) startSession(w http.ResponseWriter, r *http.Request, claims oidc.Claims, next string) {
+	name := firstNonEmpty(claims.String("name"), claims.String("preferred_username"), claims.String("email"), claims.String("sub"))
+	role := a.roleFor(claims.Strings(a.groupsClaim))
+	if role == roleNone {
+		newWebLogger().Warnf("SSO: %s signed in but is in no group allowed to use the web interface", name)
+		ssoPage(w, http.StatusForbidden, "Access denied", "Your account ("+html.EscapeString(name)+") is not in a group allowed to use Find Meraki Ports.")
+		return
+	}
+	s := webSession{Subject: claims.String("sub"), Name: name, Role: role, Expires: a.now().Add(sessionLifetime).Unix()}
+	a.setCookie(w, sessionCookie, "/", a.seal("session", s), sessionLifetime)
+	newWebLogger().Infof("SSO: %s signed in as %s", name, role)
+	http.Redirect(w, r, next, http.StatusFound)
+}
+
+// handleLogout ends the session. It does not redirect to the sign-in, which
+// would sign a user with a provider session straight back in.
+func (a *ssoAuth) handleLogout(w http.ResponseWriter, r *http.Request) {
+	a.setCookie(w, sessionCookie, "/", "", -1)
+	ssoPage(w, http.StatusOK, "Signed out", "<a href=\"/auth/login\">Sign in again</a>")
+}
+
+// handleMe reports who is signed in, for the UI's user badge.
+func handleMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	s, ok := sessionFrom(r.Context())
+	if webSSO == nil || !ok {
+		writeJSON(w, map[string]interface{}{"sso": false})
+		return
+	}
+	writeJSON(w, map[string]interface{}{"sso": true, "name": s.Name, "role": s.Role.String()})
+}
+
+// browserAPIKey is the API key value the web server hands to the browser.
+func browserAPIKey() string {
+	if webSSO != nil && webAPIKey != "" {
+		return serverKeyToken
+	}
+	return webAPIKey
+}
+
+// requestAPIKey maps an API key sent by the browser back to the real key.
+func requestAPIKey(k string) string {
+	if k == serverKeyToken {
+		return webAPIKey
+	}
+	return k
+}
+
+func (a *ssoAuth) setCookie(w http.ResponseWriter, name, path, value string, maxAge time.Duration) {
+	c := &http.Cookie{Name: name, Value: value, Path: path, HttpOnly: true, Secure: a.secure, SameSite: http.SameSiteLaxMode}
+	if maxAge < 0 {
+		c.MaxAge = -1
+	} else {
+		c.MaxAge = int(maxAge.Seconds())
+	}
+	http.SetCookie(w, c)
+}
+
+// seal encodes v and signs it for purpose, so a login cookie cannot be
+// replayed as a session cookie.
+func (a *ssoAuth) seal(purpose string, v interface{}) string {
+	data, _ := json.Marshal(v)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(a.mac(purpose, payload))
+}
+
+// open verifies a sealed value and decodes it into v.
+func (a *ssoAuth) open(purpose, sealed string, v interface{}) bool {
+	payload, sig, ok := strings.Cut(sealed, ".")
+	if !ok {
+		return false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, a.mac(purpose, payload)) {
+		return false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	return err == nil && json.Unmarshal(data, v) == nil
+}
+
+func (a *ssoAuth) mac(purpose, payload string) []byte {
+	m := hmac.New(sha256.New, a.key)
+	m.Write([]byte(purpose + "\x00" + payload))
+	return m.Sum(nil)
+}
+
+// safeNext keeps a post-sign-in redirect on this server.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") || strings.HasPrefix(next, "/auth/") {
+		return "/"
+	}
+	return next
+}
+
+// ssoPage writes a minimal sign-in status page; body is trusted HTML.
+func ssoPage(w http.ResponseWriter, status int, title, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `<!DOCTYPE html><html lang="en"><head><meta charset="UTF-8"><title>%s</title><link rel="stylesheet" href="/static/css/style.css"></head><body><div class="auth-page"><h1>%s</h1><p>%s</p></div></body></html>`, title, title, body)
+}
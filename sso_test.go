@@ -0,0 +1,192 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSSO returns an ssoAuth with a fixed key and clock and no provider, for
+// exercising sessions and role checks without a sign-in.
+func testSSO(now time.Time) *ssoAuth {
+	return &ssoAuth{
+		groupsClaim: "groups",
+		groups: map[webRole][]string{
+			roleViewer:   {"*"},
+			roleOperator: {"helpdesk"},
+			roleAdmin:    {"net-admins"},
+		},
+		key: []byte(strings.Repeat("k", 32)),
+		now: func() time.Time { return now },
+	}
+}
+
+func TestSSORoleFor(t *testing.T) {
+	a := testSSO(time.Now())
+	tests := []struct {
+		groups []string
+		want   webRole
+	}{
+		{nil, roleViewer},
+		{[]string{"helpdesk"}, roleOperator},
+		{[]string{"helpdesk", "net-admins"}, roleAdmin},
+	}
+	for _, tt := range tests {
+		if got := a.roleFor(tt.groups); got != tt.want {
+			t.Errorf("roleFor(%v) = %v, want %v", tt.groups, got, tt.want)
+		}
+	}
+	a.groups = map[webRole][]string{roleAdmin: {"net-admins"}}
+	if got := a.roleFor([]string{"staff"}); got != roleNone {
+		t.Errorf("without a * group, an unlisted user got %v", got)
+	}
+}
+
+func TestSSOWrap(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	a := testSSO(now)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := sessionFrom(r.Context())
+		_, _ = w.Write([]byte("ok " + s.Name))
+	})
+	h := a.wrap(next)
+	cookie := func(s webSession) *http.Cookie {
+		return &http.Cookie{Name: sessionCookie, Value: a.seal("session", s)}
+	}
+	viewer := cookie(webSession{Name: "Ada", Role: roleViewer, Expires: now.Add(time.Hour).Unix()})
+	admin := cookie(webSession{Name: "Root", Role: roleAdmin, Expires: now.Add(time.Hour).Unix()})
+	expired := cookie(webSession{Name: "Ada", Role: roleAdmin, Expires: now.Add(-time.Minute).Unix()})
+	forged := &http.Cookie{Name: sessionCookie, Value: strings.Replace(viewer.Value, ".", "x.", 1)}
+	// A login cookie signed with the same key must not pass as a session.
+	replayed := &http.Cookie{Name: sessionCookie, Value: a.seal("login", webSession{Name: "Eve", Role: roleAdmin, Expires: now.Add(time.Hour).Unix()})}
+
+	tests := []struct {
+		name, method, path string
+		cookie             *http.Cookie
+		wantStatus         int
+		wantBody           string
+	}{
+		{"page without session redirects", "GET", "/topology?networkId=N1", nil, http.StatusFound, ""},
+		{"API without session", "GET", "/api/networks", nil, http.StatusUnauthorized, "Sign-in required"},
+		{"static files are open", "GET", "/static/css/style.css", nil, http.StatusOK, "ok"},
+		{"auth endpoints are open", "GET", "/auth/login", nil, http.StatusOK, "ok"},
+		{"viewer searches", "POST", "/api/resolve", viewer, http.StatusOK, "ok Ada"},
+		{"viewer cannot blink LEDs", "POST", "/api/identify", viewer, http.StatusForbidden, "operator"},
+		{"viewer cannot debug", "GET", "/api/debug/network", viewer, http.StatusForbidden, "admin"},
+		{"viewer cannot stream logs", "GET", "/ws/logs", viewer, http.StatusForbidden, "admin"},
+		{"admin debugs", "GET", "/api/debug/network", admin, http.StatusOK, "ok Root"},
+		{"expired session", "GET", "/api/networks", expired, http.StatusUnauthorized, ""},
+		{"forged session", "GET", "/api/networks", forged, http.StatusUnauthorized, ""},
+		{"login cookie replayed as session", "GET", "/api/networks", replayed, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.cookie != nil {
+			req.AddCookie(tt.cookie)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("%s: %d %q, want %d containing %q", tt.name, rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/topology?networkId=N1", nil))
+	if loc := rec.Header().Get("Location"); loc != "/auth/login?next=%2Ftopology%3FnetworkId%3DN1" {
+		t.Errorf("redirect = %q", loc)
+	}
+}
+
+func TestSSOCallbackRejectsForeignState(t *testing.T) {
+	now := time.Now()
+	a := testSSO(now)
+	st := loginState{State: "mine", Nonce: "n", Verifier: "v", Next: "/", Expires: now.Add(time.Minute).Unix()}
+	req := httptest.NewRequest("GET", "/auth/callback?code=c&state=theirs", nil)
+	req.AddCookie(&http.Cookie{Name: loginCookie, Value: a.seal("login", st)})
+	rec := httptest.NewRecorder()
+	a.handleCallback(rec, req)
+	if rec.Code != http.StatusBadRequest || strings.Contains(rec.Header().Get("Set-Cookie"), sessionCookie+"=") {
+		t.Errorf("callback with a foreign state = %d, cookies %q", rec.Code, rec.Header().Values("Set-Cookie"))
+	}
+
+	rec = httptest.NewRecorder()
+	a.handleCallback(rec, httptest.NewRequest("GET", "/auth/callback?code=c&state=mine", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("callback without the login cookie = %d, want 400", rec.Code)
+	}
+}
+
+func TestSafeNext(t *testing.T) {
+	for in, want := range map[string]string{
+		"/topology?x=1":      "/topology?x=1",
+		"":                   "/",
+		"https://evil":       "/",
+		"//evil.example/":    "/",
+		"/\\evil.example":    "/",
+		"/auth/logout":       "/",
+		"javascript:alert()": "/",
+	} {
+		if got := safeNext(in); got != want {
+			t.Errorf("safeNext(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSSOHidesServerKey(t *testing.T) {
+	defer func(k string, s *ssoAuth) { webAPIKey, webSSO = k, s }(webAPIKey, webSSO)
+	webAPIKey = "0123456789abcdef0123456789abcdef01234567"
+
+	webSSO = nil
+	if browserAPIKey() != webAPIKey {
+		t.Error("without SSO the browser should get the server key as before")
+	}
+	webSSO = testSSO(time.Now())
+	if browserAPIKey() != serverKeyToken || requestAPIKey(serverKeyToken) != webAPIKey || requestAPIKey("user-key") != "user-key" {
+		t.Error("with SSO the browser should only see the placeholder")
+	}
+	rec := httptest.NewRecorder()
+	handleGetConfig(rec, httptest.NewRequest("GET", "/api/config", nil))
+	if strings.Contains(rec.Body.String(), webAPIKey) {
+		t.Errorf("/api/config leaked the server key: %s", rec.Body.String())
+	}
+}
+
+func TestNewSSOAuthSettings(t *testing.T) {
+	env := func(m map[string]string) func(string) string { return func(k string) string { return m[k] } }
+	ctx := context.Background()
+	if a, err := newSSOAuth(ctx, env(nil), ""); a != nil || err != nil {
+		t.Errorf("without OIDC_ISSUER = %v, %v; want SSO off", a, err)
+	}
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"no client", map[string]string{"OIDC_ISSUER": "https://idp"}, "OIDC_CLIENT_ID"},
+		{"no groups", map[string]string{"OIDC_ISSUER": "https://idp", "OIDC_CLIENT_ID": "app"}, "OIDC_VIEWER_GROUPS"},
+		{"short key", map[string]string{"OIDC_ISSUER": "https://idp", "OIDC_CLIENT_ID": "app", "OIDC_VIEWER_GROUPS": "*", "OIDC_SESSION_KEY": "short"}, "32 characters"},
+	}
+	for _, tt := range tests {
+		if _, err := newSSOAuth(ctx, env(tt.env), ""); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -1,5 +1,8 @@
 // Find-Meraki-Ports-With-MAC - Interactive UI
 
+// Major version of the /api schema this page was written for (see /api/version).
+const UI_API_SCHEMA = '1';
+
 class App {
   constructor() {
     this.apiKey = '';
@@ -8,27 +11,85 @@ class App {
     this.networks = [];
     this.selectedNetwork = null;
     this.results = [];
+    this.truncationWarning = ''; // set when the server capped the results
     this.wsLogs = null;
     this.logFilter = 'DEBUG';
     this._sortCol = null;
     this._sortDir = 1; // 1 = asc, -1 = desc
+    this._page = 1;
+    this._pageSize = 100; // rows rendered per page; 0 = all
     this._preset = { mac: '', ip: '', org: '', network: '' };
     this._autoResolvePending = false;
     this._presetApplied = false;
     this._testDataMode = false;
+    this._instanceId = '';
+    this._features = new Set();
+    this._sso = false;
 
-    this._restorePrefs();
     this._bindEvents();
     this._connectLogSocket();
+    this._checkVersion();
+    this._loadUser();
     this._loadConfig();
   }
 
   // ── Init ──────────────────────────────────────────────────
 
+  // A page cached from before an upgrade may not match the server's API; warn
+  // instead of failing on the first request with a confusing error.
+  async _checkVersion() {
+    try {
+      const res = await fetch('/api/version', { cache: 'no-store' });
+      if (!res.ok) return; // server predates /api/version
+      const info = await res.json();
+      this._features = new Set(info.features || []);
+      const schemas = info.schemaVersions || [];
+      if (!schemas.includes(UI_API_SCHEMA)) {
+        this.toast(`This page expects API v${UI_API_SCHEMA} but server ${info.version} speaks v${schemas.join(', v')}; reload the page.`, 'warn');
+      }
+    } catch (e) {
+      console.warn('Version check failed:', e);
+    }
+  }
+
+  // With single sign-on, show who is signed in and hide what their role cannot
+  // do. A session that expires mid-use turns API calls into 401s; send the
+  // browser back through the sign-in instead of toasting the error.
+  async _loadUser() {
+    try {
+      const res = await fetch('/api/me', { cache: 'no-store' });
+      if (!res.ok) return;
+      const me = await res.json();
+      if (!me.sso) return;
+      this._sso = true;
+      document.getElementById('userBadge').textContent = `${me.name} (${me.role})`;
+      document.getElementById('userBadge').classList.remove('hidden');
+      document.getElementById('signOut').classList.remove('hidden');
+      if (me.role === 'viewer') document.getElementById('identifyBtn').classList.add('hidden');
+      // The live log stream is for admins; stop retrying a socket the server refuses.
+      if (me.role !== 'admin') {
+        this._noLogSocket = true;
+        if (this.wsLogs) this.wsLogs.close();
+      }
+      const fetchWithSession = window.fetch.bind(window);
+      window.fetch = async (...args) => {
+        const r = await fetchWithSession(...args);
+        if (r.status === 401) location.href = '/auth/login?next=' + encodeURIComponent(location.pathname + location.search);
+        return r;
+      };
+    } catch (e) {
+      console.warn('User check failed:', e);
+    }
+  }
+
   async _loadConfig() {
     try {
       const res = await fetch('/api/config');
       const data = await res.json();
+      this._instanceId = data.instanceId || '';
+      this._publicURL = data.publicURL || '';
+      this._lanURL = data.lanURL || '';
+      await this._restorePrefs();
       // Store presets from CLI flags
       this._preset = {
         mac:     data.presetMAC     || '',
@@ -36,6 +97,9 @@ class App {
         org:     data.presetOrg     || '',
         network: data.presetNetwork || ''
       };
+      // Deep-link query parameters (e.g. from a shared QR code) win over CLI presets
+      const q = new URLSearchParams(location.search);
+      ['mac', 'ip', 'org', 'network'].forEach(k => { if (q.get(k)) this._preset[k] = q.get(k); });
       this._testDataMode = !!data.testData;
       this._autoResolvePending = !!(this._preset.mac || this._preset.ip) || this._testDataMode;
       if (data.apiKey) {
@@ -47,6 +111,7 @@ class App {
       }
     } catch (e) {
       console.warn('Config fetch failed:', e);
+      await this._restorePrefs();
     }
     // No key from server - show key input
     document.getElementById('scopeHint').textContent = 'Enter an API key to begin.';
@@ -198,11 +263,28 @@ class App {
     document.getElementById('macInput').addEventListener('keydown', e => { if (e.key === 'Enter') this._resolve(); });
     document.getElementById('ipInput').addEventListener('keydown', e => { if (e.key === 'Enter') this._resolve(); });
 
+    // Quick search palette: Ctrl+K / ⌘K from anywhere, Enter searches, Esc closes
+    document.addEventListener('keydown', e => {
+      if ((e.ctrlKey || e.metaKey) && e.key.toLowerCase() === 'k') {
+        e.preventDefault();
+        this._openPalette();
+      } else if (e.key === 'Escape' && !document.getElementById('palette').classList.contains('hidden')) {
+        this._closePalette();
+      }
+    });
+    const paletteInput = document.getElementById('paletteInput');
+    paletteInput.addEventListener('input', () => this._updatePaletteHint());
+    paletteInput.addEventListener('keydown', e => { if (e.key === 'Enter') this._paletteSearch(); });
+    document.getElementById('palette').addEventListener('click', e => {
+      if (e.target.id === 'palette') this._closePalette();
+    });
+
     // Clear inputs
     document.getElementById('clearBtn').addEventListener('click', () => {
       document.getElementById('macInput').value = '';
       document.getElementById('ipInput').value = '';
       this.results = [];
+      this.truncationWarning = '';
       this._renderResults();
     });
 
@@ -229,10 +311,12 @@ class App {
               + '&hostname='        + encodeURIComponent(hostname);
       window.open(url, '_blank');
     });
+    document.getElementById('identifyBtn').addEventListener('click', () => this._identify());
 
     // Export
     document.getElementById('exportCsvBtn').addEventListener('click', () => this._exportCSV());
     document.getElementById('exportJsonBtn').addEventListener('click', () => this._exportJSON());
+    document.getElementById('qrBtn').addEventListener('click', () => this._showQR());
 
     // Table header sort
     document.getElementById('resultsTable').addEventListener('click', e => {
@@ -245,9 +329,21 @@ class App {
         this._sortCol = col;
         this._sortDir = 1;
       }
+      this._page = 1;
+      this._savePrefs();
       this._renderResults();
     });
 
+    // Result paging — rendering thousands of rows at once freezes the browser
+    document.getElementById('pageSizeSel').addEventListener('change', e => {
+      this._pageSize = parseInt(e.target.value, 10) || 0;
+      this._page = 1;
+      this._savePrefs();
+      this._renderResults();
+    });
+    document.getElementById('pagePrevBtn').addEventListener('click', () => { this._page--; this._renderResults(); });
+    document.getElementById('pageNextBtn').addEventListener('click', () => { this._page++; this._renderResults(); });
+
     // Log controls
     document.getElementById('logLevelSel').addEventListener('change', e => {
       this.logFilter = e.target.value;
@@ -269,19 +365,20 @@ class App {
 
   // ── Resolve ───────────────────────────────────────────────
 
-  async _resolve() {
+  async _resolve(hostname = '') {
     const mac = document.getElementById('macInput').value.trim();
     const ip  = document.getElementById('ipInput').value.trim();
-    if (!mac && !ip && !this._testDataMode) { this.toast('Enter a MAC or IP address', 'warn'); return; }
+    if (!mac && !ip && !hostname && !this._testDataMode) { this.toast('Enter a MAC or IP address', 'warn'); return; }
     if (!this.selectedNetwork && !this._testDataMode) { this.toast('Select a network first', 'warn'); return; }
 
     this._setBusy('resolveBtn', true, 'Resolving…');
     this.results = [];
+    this._page = 1;
     this._renderResults();
 
     const isAll = this.selectedNetwork === 'ALL';
     const payload = {
-      mac, ip, apiKey: this.apiKey,
+      mac, ip, hostname, apiKey: this.apiKey,
       orgId: this.selectedOrg,
       networkId:  isAll ? '' : this.selectedNetwork,
       networkIds: isAll ? this.networks.map(n => n.id) : []
@@ -296,15 +393,18 @@ class App {
       const data = await res.json();
       if (data.error) { this.toast(data.error, 'error'); return; }
       this.results = data.results || [];
+      this.truncationWarning = data.warning || '';
       this._renderResults();
       if (this.results.length === 0) { this.toast('No devices found', 'warn'); }
+      else if (this.truncationWarning) { this.toast(this.truncationWarning, 'warn'); }
       else {
         this.toast(this.results.length + ' result(s) found', 'success');
       }
+      this._toastWarnings(data.warnings || []);
 
       // Determine the effective MAC to use for display/manufacturer lookup
       let effectiveMac = mac;
-      if (ip && !mac && this.results[0] && this.results[0].mac) {
+      if ((ip || hostname) && !mac && this.results[0] && this.results[0].mac) {
         effectiveMac = this.results[0].mac;
         document.getElementById('macInput').value = effectiveMac;
       }
@@ -334,6 +434,82 @@ class App {
     } catch (e) { /* best-effort */ }
   }
 
+  // Blink the LEDs of the selected row's switch (first row if none selected).
+  async _identify() {
+    const r = this.selectedResult || (this.results && this.results[0]);
+    if (!r || !r.deviceSerial) { this.toast('Resolve a device first, then select a row', 'warn'); return; }
+    const name = r.deviceName || r.switchName || r.deviceSerial;
+    if (r.isUplink) this.toast(name + ' only sees this MAC on an uplink — it is not the access switch', 'warn');
+    this._setBusy('identifyBtn', true, 'Blinking…');
+    try {
+      const res = await fetch('/api/identify', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ serial: r.deviceSerial, apiKey: this.apiKey })
+      });
+      const data = await res.json();
+      if (data.error) { this.toast(data.error, 'error'); return; }
+      this.toast('Blinking ' + name + ' for ' + data.seconds + 's', 'success');
+    } catch (e) {
+      this.toast('Identify failed: ' + e.message, 'error');
+    } finally {
+      this._setBusy('identifyBtn', false, 'Identify');
+    }
+  }
+
+  // ── Quick search palette ──────────────────────────────────
+
+  // Classify palette input as a MAC (including wildcard patterns), an IP
+  // address, or otherwise a hostname to forward-resolve on the server.
+  _classifyQuery(q) {
+    if (/^\d{1,3}(\.\d{1,3}){3}$/.test(q)) return 'ip';
+    const hex = q.replace(/[:.\-]/g, '');
+    if (/^[0-9a-f*\[\]]+$/i.test(hex) && (hex.length === 12 || /[*\[]/.test(hex))) return 'mac';
+    if (q.includes(':') && /^[0-9a-f:]+$/i.test(q)) return 'ip'; // IPv6
+    return 'hostname';
+  }
+
+  _scopeLabel() {
+    if (this.selectedNetwork === 'ALL') return 'all networks';
+    const net = this.networks.find(n => n.id === this.selectedNetwork);
+    return net ? net.name : 'no network selected';
+  }
+
+  _openPalette() {
+    const input = document.getElementById('paletteInput');
+    document.getElementById('palette').classList.remove('hidden');
+    input.value = '';
+    this._updatePaletteHint();
+    input.focus();
+  }
+
+  _closePalette() {
+    document.getElementById('palette').classList.add('hidden');
+  }
+
+  _updatePaletteHint() {
+    const q = document.getElementById('paletteInput').value.trim();
+    const hint = document.getElementById('paletteHint');
+    if (!q) { hint.textContent = 'Searches ' + this._scopeLabel() + '. Esc to close.'; return; }
+    const kind = { mac: 'MAC', ip: 'IP', hostname: 'hostname' }[this._classifyQuery(q)];
+    hint.textContent = 'Enter: search ' + kind + ' ' + q + ' in ' + this._scopeLabel();
+  }
+
+  // Run the palette query with the current (last used) org/network scope and
+  // move focus to the results so the keyboard can take over from there.
+  async _paletteSearch() {
+    const q = document.getElementById('paletteInput').value.trim();
+    if (!q) return;
+    this._closePalette();
+    const kind = this._classifyQuery(q);
+    document.getElementById('macInput').value = kind === 'mac' ? q : '';
+    document.getElementById('ipInput').value  = kind === 'ip'  ? q : '';
+    await this._resolve(kind === 'hostname' ? q : '');
+    const wrap = document.getElementById('resultsWrap');
+    wrap.focus();
+    wrap.scrollIntoView({ block: 'start' });
+  }
+
   // ── Sort ──────────────────────────────────────────────────
 
   _colValue(r, col) {
@@ -347,6 +523,7 @@ class App {
       case 'hostname':     return (r.hostname || '').toLowerCase();
       case 'manufacturer': return (r.manufacturer || '').toLowerCase();
       case 'mode':         return r.isUplink ? 'uplink' : (r.portMode || '').toLowerCase();
+      case 'confidence':   return r.confidence || 0;
       default:             return '';
     }
   }
@@ -379,12 +556,74 @@ class App {
       const b = this._colValue(rb, col);
       let cmp;
       if (col === 'ip')   cmp = this._cmpIP(a, b);
+      else if (col === 'confidence') cmp = a - b;
       else if (col === 'port') cmp = this._cmpPort(a, b);
       else                cmp = a < b ? -1 : a > b ? 1 : 0;
       return cmp * dir;
     });
   }
 
+  // Show which API source supplied each field of the selected row.
+  _renderExplain(r) {
+    const el = document.getElementById('explainPanel');
+    const fields = (r && r.explain) || [];
+    if (!fields.length) { el.innerHTML = ''; el.classList.add('hidden'); return; }
+    el.innerHTML =
+      '<div class="explain-title">Where this row came from — ' + this._esc(r.mac || '') + ' on ' +
+        this._esc(r.deviceName || r.deviceSerial || '') + ' port ' + this._esc(r.port || '—') + '</div>' +
+      '<table class="explain-table"><tbody>' + fields.map(f =>
+        '<tr><th>' + this._esc(f.field) + '</th><td>' + this._esc(f.source) +
+        (f.detail ? ' <span class="aggr-members">(' + this._esc(f.detail) + ')</span>' : '') + '</td></tr>'
+      ).join('') + '</tbody></table>';
+    el.classList.remove('hidden');
+  }
+
+  // Colour the server's 0–100 confidence score by band (see output.ConfidenceHigh/Low).
+  _confidenceBadge(score) {
+    if (!score) return '—';
+    const band = score >= 75 ? 'high' : score >= 40 ? 'medium' : 'low';
+    return '<span class="conf-badge conf-' + band + '">' + this._esc(String(score)) + '</span>';
+  }
+
+  // Live port state from the switch: a dot after the port, with speed, duplex
+  // and the CDP/LLDP neighbor in the tooltip.
+  _linkBadge(r) {
+    if (!r.link) return '';
+    const tip = ['Link ' + r.link, [r.speed, r.duplex].filter(Boolean).join(' '), r.neighbor ? 'neighbor ' + r.neighbor : '', r.neighborPlatform || '']
+      .filter(Boolean).join(' · ');
+    return ' <span class="link-badge link-' + this._esc(r.link) + '" title="' + this._esc(tip) + '">' + this._esc(r.link) + '</span>';
+  }
+
+  // CDP/LLDP neighbor under the port, so a phone, AP or small switch between
+  // the port and the client is obvious, with its platform in the tooltip.
+  _neighborLabel(r) {
+    if (!r.neighbor || r.port === 'wireless') return '';
+    const tip = 'CDP/LLDP neighbor' + (r.neighborPlatform ? ' · ' + r.neighborPlatform : '');
+    return '<div class="aggr-members" title="' + this._esc(tip) + '">\u2194 ' + this._esc(r.neighbor) + '</div>';
+  }
+
+  // Dashboard status after the device name, only when it is not online, with
+  // the last report time in the tooltip.
+  _statusBadge(r) {
+    if (!r.deviceStatus || r.deviceStatus === 'online') return '';
+    const tip = 'Dashboard status ' + r.deviceStatus + (r.lastReported ? ' · last reported ' + new Date(r.lastReported).toLocaleString() : '');
+    return ' <span class="link-badge status-' + this._esc(r.deviceStatus) + '" title="' + this._esc(tip) + '">' + this._esc(r.deviceStatus) + '</span>';
+  }
+
+  // Model and firmware of the device as a tooltip on its name.
+  _hardwareTitle(r) {
+    const tip = [r.model, r.firmware].filter(Boolean).join(' · ');
+    return tip ? ' title="' + this._esc(tip) + '"' : '';
+  }
+
+  // DHCP lease after the hostname: "reserved", or when it runs out at the latest.
+  _leaseLabel(r) {
+    if (!r.leaseExpiry) return '';
+    const text = r.leaseExpiry === 'reserved' ? 'reserved' : 'lease until ' + new Date(r.leaseExpiry).toLocaleString();
+    const tip = 'DHCP' + (r.dhcpHostname ? ' hostname ' + r.dhcpHostname : '');
+    return ' <span class="aggr-members" title="' + this._esc(tip) + '">(' + this._esc(text) + ')</span>';
+  }
+
   _updateSortHeaders() {
     document.querySelectorAll('#resultsTable th.sortable').forEach(th => {
       th.classList.remove('sort-asc', 'sort-desc');
@@ -401,27 +640,37 @@ class App {
     const count = document.getElementById('resultsCount');
     const exportBtns = document.getElementById('exportBtns');
     const noteEl = document.getElementById('uplinkNote');
+    const truncEl = document.getElementById('truncatedNote');
+    const pager = document.getElementById('resultsPager');
 
     tbody.innerHTML = '';
     noteEl.innerHTML = ''; noteEl.classList.add('hidden');
+    truncEl.textContent = ''; truncEl.classList.add('hidden');
+    this._renderExplain(null);
     if (!this.results || this.results.length === 0) {
-      tbody.innerHTML = '<tr><td colspan="9" class="no-results">No results — enter a MAC or IP address and click Resolve.</td></tr>';
+      tbody.innerHTML = '<tr><td colspan="10" class="no-results">No results — enter a MAC or IP address and click Resolve.</td></tr>';
       count.textContent = '';
       exportBtns.classList.add('hidden');
+      pager.classList.add('hidden');
       return;
     }
 
     count.textContent = this.results.length + ' result' + (this.results.length !== 1 ? 's' : '');
+    if (this.truncationWarning) {
+      truncEl.textContent = '\u26A0 ' + this.truncationWarning.charAt(0).toUpperCase() + this.truncationWarning.slice(1) + '.';
+      truncEl.classList.remove('hidden');
+    }
     exportBtns.classList.remove('hidden');
     this._updateSortHeaders();
 
     const sorted = this._sortedResults();
+    const pageRows = this._pageSlice(sorted);
     // Re-apply selectedResult after re-render (match by mac+port+serial)
     const prevSel = this.selectedResult;
     this.selectedResult = null;
     let renderedCount = 0;
 
-    sorted.forEach((r, idx) => {
+    pageRows.forEach((r, idx) => {
       // Normalise aggrPorts: Go nil slice → JSON null → ensure it's always array or null
       if (r.aggrPorts !== null && r.aggrPorts !== undefined && !Array.isArray(r.aggrPorts)) {
         r.aggrPorts = null;
@@ -441,24 +690,35 @@ class App {
       const vlanDisplay = (r.vlan != null && r.vlan !== '') ? String(r.vlan) : '—';
       try {
         tr.innerHTML =
-          '<td>' + this._esc(r.deviceName || r.switchName || '—') + '</td>' +
+          '<td' + this._hardwareTitle(r) + '>' + this._esc(r.deviceName || r.switchName || '—') + this._statusBadge(r) + '</td>' +
           '<td>' + this._esc(r.networkName || '—') + '</td>' +
           '<td class="cell-mono">' + this._esc(r.mac || '—') + '</td>' +
           '<td class="cell-mono">' + this._esc(r.ip || '—') + '</td>' +
           '<td>' + (() => {
-            const portLabel = this._esc(r.port || r.portId || '—');
+            let portLabel = this._esc(r.port || r.portId || '—');
             if (r.aggrPorts && r.aggrPorts.length) {
-              return portLabel + ' <span class="aggr-members" title="Member ports">(' + this._esc(r.aggrPorts.join(', ')) + ')</span>';
+              portLabel += ' <span class="aggr-members" title="Member ports">(' + this._esc(r.aggrPorts.join(', ')) + ')</span>';
+            }
+            if (r.port === 'wireless') {
+              const radio = [r.ssid, r.band, r.rssi ? r.rssi + ' dBm' : ''].filter(Boolean).join(' · ');
+              if (radio) portLabel += ' <span class="aggr-members" title="SSID · band · signal">(' + this._esc(radio) + ')</span>';
+            }
+            portLabel += this._linkBadge(r) + this._neighborLabel(r);
+            if (r.moved) {
+              portLabel += '<div class="moved-note" title="From the search history">\u21BB ' + this._esc(r.moved) + '</div>';
             }
             return portLabel;
           })() + '</td>' +
           '<td>' + this._esc(vlanDisplay) + '</td>' +
-          '<td>' + this._esc(r.hostname || '—') + '</td>' +
+          '<td>' + this._esc(r.hostname || '—') +
+            (r.note ? ' <span class="aggr-members" title="Virtual router MAC">(' + this._esc(r.note) + ')</span>' : '') +
+            this._leaseLabel(r) + '</td>' +
           '<td>' + (r.manufacturer ? '<span class="mfr-badge">' + this._esc(r.manufacturer) + '</span>' : '—') + '</td>' +
-          '<td>' + modeCell + '</td>';
+          '<td>' + modeCell + '</td>' +
+          '<td>' + this._confidenceBadge(r.confidence) + '</td>';
       } catch(e) {
         console.error('Row render error for result:', r, e);
-        tr.innerHTML = '<td colspan="9" style="color:red;background:#fee2e2;padding:6px">⚠ Error rendering row (' + this._esc(r.port || '?') + ' / ' + this._esc(r.mac || '?') + '): ' + this._esc(String(e)) + '</td>';
+        tr.innerHTML = '<td colspan="10" style="color:red;background:#fee2e2;padding:6px">⚠ Error rendering row (' + this._esc(r.port || '?') + ' / ' + this._esc(r.mac || '?') + '): ' + this._esc(String(e)) + '</td>';
       }
       if (r.isUplink) tr.classList.add('row-uplink-confirmed');
       renderedCount++;
@@ -467,6 +727,7 @@ class App {
         tbody.querySelectorAll('tr').forEach(t => t.classList.remove('row-selected'));
         tr.classList.add('row-selected');
         this.selectedResult = r;
+        this._renderExplain(r);
       });
 
       // Auto-select: restore previous selection or default to first row
@@ -475,13 +736,19 @@ class App {
       if (isSame || (!prevSel && idx === 0)) {
         tr.classList.add('row-selected');
         this.selectedResult = r;
+        this._renderExplain(r);
       }
 
       tbody.appendChild(tr);
     });
 
     // Update count to match what was actually rendered
-    count.textContent = renderedCount + ' result' + (renderedCount !== 1 ? 's' : '');
+    if (renderedCount < sorted.length) {
+      const first = (this._page - 1) * this._pageSize + 1;
+      count.textContent = first + '–' + (first + renderedCount - 1) + ' of ' + sorted.length + ' results';
+    } else {
+      count.textContent = renderedCount + ' result' + (renderedCount !== 1 ? 's' : '');
+    }
 
     // Note: confirmed uplinks (from topology) get a specific warning.
     // Unconfirmed trunk ports get a softer neutral note.
@@ -503,10 +770,24 @@ class App {
     }
   }
 
+  // Clamp this._page to the available pages and return that page's rows,
+  // updating the pager controls to match.
+  _pageSlice(rows) {
+    const pager = document.getElementById('resultsPager');
+    const size = this._pageSize > 0 ? this._pageSize : rows.length;
+    const pages = Math.max(1, Math.ceil(rows.length / size));
+    this._page = Math.min(Math.max(this._page, 1), pages);
+    pager.classList.toggle('hidden', rows.length <= 50);
+    document.getElementById('pageInfo').textContent = 'Page ' + this._page + ' of ' + pages;
+    document.getElementById('pagePrevBtn').disabled = this._page <= 1;
+    document.getElementById('pageNextBtn').disabled = this._page >= pages;
+    return rows.slice((this._page - 1) * size, this._page * size);
+  }
+
   // ── Export ────────────────────────────────────────────────
 
   _exportCSV() {
-    const header = ['Device','Network','MAC','IP','Port','AggrPorts','VLAN','Hostname','Manufacturer','Mode','Uplink'];
+    const header = ['Device','Network','MAC','IP','Port','AggrPorts','VLAN','Hostname','Manufacturer','Mode','Uplink','Confidence'];
     const rows = this.results.map(r => [
       r.deviceName || r.switchName || '',
       r.networkName || '',
@@ -516,7 +797,8 @@ class App {
       r.vlan || '', r.hostname || '',
       r.manufacturer || '',
       r.portMode || '',
-      r.isUplink ? 'yes' : ''
+      r.isUplink ? 'yes' : '',
+      r.confidence || ''
     ].map(v => '"' + String(v).replace(/"/g, '""') + '"').join(','));
     this._download('meraki-results.csv', [header.join(','), ...rows].join('\r\n'), 'text/csv');
   }
@@ -525,6 +807,36 @@ class App {
     this._download('meraki-results.json', JSON.stringify(this.results, null, 2), 'application/json');
   }
 
+  // Open a QR code encoding a deep link back to this search so a field tech can
+  // re-run it from a phone on the same network.
+  _showQR() {
+    const org = this.orgs.find(o => o.id === this.selectedOrg);
+    const net = this.networks.find(n => n.id === this.selectedNetwork);
+    const q = new URLSearchParams();
+    const mac = document.getElementById('macInput').value.trim();
+    const ip  = document.getElementById('ipInput').value.trim();
+    if (mac) q.set('mac', mac); else if (ip) q.set('ip', ip);
+    if (org) q.set('org', org.name);
+    q.set('network', net ? net.name : 'ALL');
+    // --public-url always wins. Otherwise a page opened on localhost links to
+    // the server's LAN address, since a phone cannot open a localhost link.
+    const loopback = this._isLoopbackHost(location.hostname);
+    let base = this._publicURL || location.origin;
+    if (!this._publicURL && loopback && this._lanURL) {
+      base = this._lanURL;
+      this.toast('\u26A0 This page is open on localhost; the QR code links to ' + base + ' instead', 'warn');
+    } else if (this._isLoopbackHost(new URL(base).hostname)) {
+      this.toast('\u26A0 The QR code links to localhost, which other devices cannot open. Start the server with --public-url or a LAN --web-host', 'warn');
+    }
+    const link = base + '/?' + q.toString();
+    window.open('/api/qr?data=' + encodeURIComponent(link), '_blank');
+  }
+
+  _isLoopbackHost(host) {
+    host = host.replace(/^\[|\]$/g, '').toLowerCase();
+    return host === 'localhost' || host.endsWith('.localhost') || host === '::1' || /^127\./.test(host);
+  }
+
   _download(filename, content, mime) {
     const a = document.createElement('a');
     a.href = URL.createObjectURL(new Blob([content], { type: mime }));
@@ -536,6 +848,7 @@ class App {
   // ── Log WebSocket ─────────────────────────────────────────
 
   _connectLogSocket() {
+    if (this._noLogSocket) return;
     const proto = location.protocol === 'https:' ? 'wss' : 'ws';
     this.wsLogs = new WebSocket(proto + '://' + location.host + '/ws/logs');
     this.wsLogs.onmessage = e => this._appendLog(e.data);
@@ -609,6 +922,16 @@ class App {
     return String(str).replace(/&/g,'&amp;').replace(/</g,'&lt;').replace(/>/g,'&gt;').replace(/"/g,'&quot;');
   }
 
+  // Run-level warnings (skipped switches, stale data, ...) each get a toast;
+  // truncation already has its own above. Past a handful they are summarised
+  // so a wide search does not bury the screen.
+  _toastWarnings(warnings) {
+    const shown = warnings.filter(w => w.kind !== 'truncated');
+    const max = 4;
+    shown.slice(0, max).forEach(w => this.toast('\u26A0 ' + w.message, 'warn'));
+    if (shown.length > max) this.toast('\u26A0 ' + (shown.length - max) + ' more warning(s)', 'warn');
+  }
+
   toast(msg, type = 'info') {
     const el = document.createElement('div');
     el.className = 'toast toast-' + type;
@@ -619,23 +942,54 @@ class App {
 
   // ── Prefs ─────────────────────────────────────────────────
 
+  // UI state is kept in localStorage under a per-instance key, so two servers
+  // on the same origin (e.g. localhost:8080 with different .env files) do not
+  // share selections, and mirrored to /api/ui-state so it can follow the user
+  // to another browser.
+  _prefsKey() {
+    return 'meraki_prefs' + (this._instanceId ? ':' + this._instanceId : '');
+  }
+
   _savePrefs() {
-    localStorage.setItem('meraki_prefs', JSON.stringify({
+    const state = {
       org: this.selectedOrg,
       net: this.selectedNetwork,
-      logFilter: this.logFilter
-    }));
+      logFilter: this.logFilter,
+      pageSize: this._pageSize,
+      sortCol: this._sortCol,
+      sortDir: this._sortDir
+    };
+    const json = JSON.stringify(state);
+    try { localStorage.setItem(this._prefsKey(), json); } catch (e) { /* storage full or disabled */ }
+    fetch('/api/ui-state', {
+      method: 'PUT',
+      headers: { 'Content-Type': 'application/json' },
+      body: json
+    }).catch(() => { /* best-effort */ });
   }
 
-  _restorePrefs() {
+  async _restorePrefs() {
+    let p = null;
     try {
-      const p = JSON.parse(localStorage.getItem('meraki_prefs') || '{}');
-      this._savedOrg     = p.org || null;
-      this._savedNetwork = p.net || null;
-      this.logFilter     = p.logFilter || 'DEBUG';
-      const sel = document.getElementById('logLevelSel');
-      if (sel) sel.value = this.logFilter;
+      // Fall back to the pre-instance key so existing users keep their settings.
+      p = JSON.parse(localStorage.getItem(this._prefsKey()) || localStorage.getItem('meraki_prefs') || 'null');
     } catch (e) {}
+    if (!p) {
+      try {
+        const res = await fetch('/api/ui-state');
+        if (res.ok) p = await res.json();
+      } catch (e) {}
+    }
+    p = p || {};
+    this._savedOrg     = p.org || null;
+    this._savedNetwork = p.net || null;
+    this.logFilter     = p.logFilter || 'DEBUG';
+    if (typeof p.pageSize === 'number') this._pageSize = p.pageSize;
+    if (p.sortCol) { this._sortCol = p.sortCol; this._sortDir = p.sortDir === -1 ? -1 : 1; }
+    const sel = document.getElementById('logLevelSel');
+    if (sel) sel.value = this.logFilter;
+    const ps = document.getElementById('pageSizeSel');
+    if (ps) ps.value = String(this._pageSize);
   }
 }
 
@@ -0,0 +1,72 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+// isSubnet reports whether an --ip value is a CIDR prefix (subnet sweep)
+// rather than a single address.
+func isSubnet(ip string) bool {
+	return strings.Contains(ip, "/")
+}
+
+// sweepSubnetMACs returns the MAC of every client in networks whose IP falls
+// inside cidr. The MACs then drive a single MAC table scan, so a /24 costs one
+// client listing per network instead of one IP resolution per address.
+func sweepSubnetMACs(ctx context.Context, client *meraki.MerakiClient, networks []meraki.Network, cidr string, log *logger.Logger) ([]string, error) {
+	_, prefix, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	var macs []string
+	seen := make(map[string]bool)
+	for _, n := range networks {
+		// Stream the listing page by page: only matching MACs are kept, so a
+		// network with tens of thousands of clients never sits in memory at once.
+		err := client.EachNetworkClientPage(ctx, n.ID, func(clients []meraki.NetworkClient) error {
+			for _, c := range clients {
+				ip := net.ParseIP(c.IP)
+				if ip == nil || !prefix.Contains(ip) {
+					continue
+				}
+				norm, err := macaddr.NormalizeExactMac(c.MAC)
+				if err != nil || seen[norm] {
+					continue
+				}
+				seen[norm] = true
+				macs = append(macs, macaddr.FormatMacColon(norm))
+			}
+			return nil
+		})
+		if err != nil {
+			log.Warnf("Subnet sweep: client listing for network %s incomplete: %v", n.Name, err)
+		}
+	}
+	if len(macs) == 0 {
+		return nil, fmt.Errorf("no clients found in %s", prefix)
+	}
+	log.Infof("Subnet %s: %d client(s) to locate", prefix, len(macs))
+	return macs, nil
+}
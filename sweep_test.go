@@ -0,0 +1,70 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+func TestSweepSubnetMACs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/networks/N1/clients":
+			_, _ = w.Write([]byte(`[
+				{"mac":"aa:bb:cc:00:00:01","ip":"10.20.30.5"},
+				{"mac":"aa:bb:cc:00:00:02","ip":"10.20.31.5"},
+				{"mac":"AA:BB:CC:00:00:03","ip":"10.20.30.200"},
+				{"mac":"aa:bb:cc:00:00:04","ip":""}]`))
+		case "/networks/N2/clients":
+			_, _ = w.Write([]byte(`[{"mac":"aa:bb:cc:00:00:01","ip":"10.20.30.5"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := meraki.NewClient("key", srv.URL, 1)
+	networks := []meraki.Network{{ID: "N1", Name: "HQ"}, {ID: "N2", Name: "Branch"}}
+	log := logger.NewWriter(io.Discard, logger.LevelError)
+
+	macs, err := sweepSubnetMACs(context.Background(), client, networks, "10.20.30.0/24", log)
+	if err != nil {
+		t.Fatalf("sweepSubnetMACs() error: %v", err)
+	}
+	if want := []string{"aa:bb:cc:00:00:01", "aa:bb:cc:00:00:03"}; !reflect.DeepEqual(macs, want) {
+		t.Errorf("sweepSubnetMACs() = %v, want %v", macs, want)
+	}
+
+	if _, err := sweepSubnetMACs(context.Background(), client, networks, "192.168.0.0/16", log); err == nil {
+		t.Error("sweepSubnetMACs() for an empty subnet should return an error")
+	}
+}
+
+func TestIsSubnet(t *testing.T) {
+	for in, want := range map[string]bool{"10.0.0.0/24": true, "10.0.0.1": false, "": false} {
+		if got := isSubnet(in); got != want {
+			t.Errorf("isSubnet(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,497 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/history"
+
+	"github.com/gorilla/mux"
+)
+
+// tokenScope limits what a REST API token may call. Each scope includes the
+// ones below it.
+type tokenScope int
+
+const (
+	scopeNone   tokenScope = iota // not a token; cookie sessions are limited by role only
+	scopeSearch                   // searches and the lookups the search form needs
+	scopeExport                   // + inventory, topology and alert exports
+	scopeAdmin                    // everything an admin may do, including token management
+)
+
+var tokenScopeNames = []string{"", "search", "export", "admin"}
+
+func (s tokenScope) String() string { return tokenScopeNames[s] }
+
+// parseTokenScope parses "search", "export" or "admin".
+func parseTokenScope(s string) (tokenScope, error) {
+	for i, name := range tokenScopeNames {
+		if i > 0 && strings.EqualFold(strings.TrimSpace(s), name) {
+			return tokenScope(i), nil
+		}
+	}
+	return scopeNone, fmt.Errorf("unknown token scope %q (use search, export or admin)", s)
+}
+
+func (s tokenScope) MarshalJSON() ([]byte, error) { return json.Marshal(s.String()) }
+
+func (s *tokenScope) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return err
+	}
+	v, err := parseTokenScope(name)
+	*s = v
+	return err
+}
+
+// requiredScope is the token scope a request needs.
+func requiredScope(r *http.Request) tokenScope {
+	switch p := r.URL.Path; {
+	case strings.HasPrefix(p, "/api/tokens"), p == "/api/identify", p == "/api/logs",
+		strings.HasPrefix(p, "/api/debug/"), strings.HasPrefix(p, "/ws/"),
+		p == "/api/ui-state" && r.Method != http.MethodGet, p == "/api/maintenance":
+		return scopeAdmin
+	case p == "/api/inventory", p == "/api/topology", p == "/topology", p == "/api/alerts", p == "/api/qr":
+		return scopeExport
+	}
+	return scopeSearch
+}
+
+const (
+	tokenPrefix       = "fmp_"
+	defaultTokenTTL   = 90 * 24 * time.Hour
+	maxTokenTTL       = 366 * 24 * time.Hour
+	maxTokenNameBytes = 100
+)
+
+// apiToken is a stored REST API token. Only a SHA-256 hash of the secret is
+// kept; the token itself is shown once, when it is created.
+type apiToken struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name"`
+	Owner   string     `json:"owner"`
+	Scope   tokenScope `json:"scope"`
+	Hash    string     `json:"hash,omitempty"`
+	Created time.Time  `json:"created"`
+	Expires time.Time  `json:"expires"`
+	Revoked *time.Time `json:"revoked,omitempty"`
+}
+
+// status is "active", "expired" or "revoked" at now.
+func (t apiToken) status(now time.Time) string {
+	switch {
+	case t.Revoked != nil:
+		return "revoked"
+	case !now.Before(t.Expires):
+		return "expired"
+	}
+	return "active"
+}
+
+// defaultTokensFile is ~/.find-mac-api-tokens.json, or a file in the working
+// directory when the home directory is unknown.
+func defaultTokensFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".find-mac-api-tokens.json"
+	}
+	return filepath.Join(home, ".find-mac-api-tokens.json")
+}
+
+// tokenStore keeps API tokens in a JSON file. The file is read on every
+// check, so a token revoked from the command line stops working at once.
+type tokenStore struct {
+	mu   sync.Mutex
+	path string
+	now  func() time.Time // nil means time.Now
+}
+
+func (s *tokenStore) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// list returns every token, newest first, without hashes.
+func (s *tokenStore) list() ([]apiToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].Created.After(tokens[j].Created) })
+	for i := range tokens {
+		tokens[i].Hash = ""
+	}
+	return tokens, nil
+}
+
+// create stores a new token and returns it with its secret, which is not
+// recoverable later.
+func (s *tokenStore) create(name, owner string, scope tokenScope, ttl time.Duration) (string, apiToken, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "" || len(name) > maxTokenNameBytes:
+		return "", apiToken{}, fmt.Errorf("a token name of 1–%d characters is required", maxTokenNameBytes)
+	case scope == scopeNone:
+		return "", apiToken{}, errors.New("a token scope is required")
+	case ttl <= 0 || ttl > maxTokenTTL:
+		return "", apiToken{}, errors.New("tokens must expire within 366 days")
+	}
+	id, secret := make([]byte, 6), make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", apiToken{}, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", apiToken{}, err
+	}
+	t := apiToken{ID: hex.EncodeToString(id), Name: name, Owner: owner, Scope: scope, Created: s.clock().UTC().Truncate(time.Second)}
+	t.Expires = t.Created.Add(ttl)
+	raw := tokenPrefix + t.ID + "_" + hex.EncodeToString(secret)
+	t.Hash = hashToken(raw)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load()
+	if err != nil {
+		return "", apiToken{}, err
+	}
+	if err := s.save(append(tokens, t)); err != nil {
+		return "", apiToken{}, err
+	}
+	t.Hash = ""
+	return raw, t, nil
+}
+
+// revoke marks the token with id as revoked. It reports false when there is
+// no such token; revoking twice keeps the first time.
+func (s *tokenStore) revoke(id string) (apiToken, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load()
+	if err != nil {
+		return apiToken{}, false, err
+	}
+	for i := range tokens {
+		if tokens[i].ID != id {
+			continue
+		}
+		if tokens[i].Revoked == nil {
+			now := s.clock().UTC().Truncate(time.Second)
+			tokens[i].Revoked = &now
+			if err := s.save(tokens); err != nil {
+				return apiToken{}, false, err
+			}
+		}
+		t := tokens[i]
+		t.Hash = ""
+		return t, true, nil
+	}
+	return apiToken{}, false, nil
+}
+
+// authenticate returns the active token matching raw.
+func (s *tokenStore) authenticate(raw string) (apiToken, bool) {
+	id, _, ok := strings.Cut(strings.TrimPrefix(raw, tokenPrefix), "_")
+	if !ok || !strings.HasPrefix(raw, tokenPrefix) {
+		return apiToken{}, false
+	}
+	s.mu.Lock()
+	tokens, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		newWebLogger().Warnf("Reading API tokens: %v", err)
+		return apiToken{}, false
+	}
+	want := hashToken(raw)
+	for _, t := range tokens {
+		if t.ID == id && subtle.ConstantTimeCompare([]byte(t.Hash), []byte(want)) == 1 {
+			return t, t.status(s.clock()) == "active"
+		}
+	}
+	return apiToken{}, false
+}
+
+// load reads every token; a missing file is an empty store.
+func (s *tokenStore) load() ([]apiToken, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tokens []apiToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("%s: %v", s.path, err)
+	}
+	return tokens, nil
+}
+
+func (s *tokenStore) save(tokens []apiToken) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// bearerToken returns the credentials of an Authorization header and whether
+// there is one; anything but "Bearer <token>" yields "".
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	if h == "" {
+		return "", false
+	}
+	scheme, raw, _ := strings.Cut(h, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", true
+	}
+	return strings.TrimSpace(raw), true
+}
+
+// tokenSession is the session a valid token acts as. Admin tokens have the
+// admin role; the others may do what a viewer may, further limited by scope.
+func tokenSession(t apiToken) webSession {
+	s := webSession{Subject: "token:" + t.ID, Name: fmt.Sprintf("token %q of %s", t.Name, t.Owner), Role: roleViewer, Scope: t.Scope, Expires: t.Expires.Unix()}
+	if t.Scope == scopeAdmin {
+		s.Role = roleAdmin
+	}
+	return s
+}
+
+// tokenView is a token as shown by the API, with its status at now.
+func tokenView(t apiToken, now time.Time) map[string]interface{} {
+	v := map[string]interface{}{
+		"id": t.ID, "name": t.Name, "owner": t.Owner, "scope": t.Scope.String(),
+		"created": t.Created, "expires": t.Expires, "status": t.status(now),
+	}
+	if t.Revoked != nil {
+		v["revoked"] = *t.Revoked
+	}
+	return v
+}
+
+// handleTokens lists tokens (GET) or creates one (POST). Tokens are only
+// checked when single sign-on is on, so without it there is nothing to manage.
+func handleTokens(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if webSSO == nil {
+		http.Error(w, `{"error": "API tokens need single sign-on (OIDC_ISSUER)"}`, http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodGet {
+		tokens, err := webTokens.list()
+		if err != nil {
+			newWebLogger().Warnf("Reading API tokens: %v", err)
+			http.Error(w, `{"error": "Failed to read API tokens"}`, http.StatusInternalServerError)
+			return
+		}
+		views := []map[string]interface{}{}
+		for _, t := range tokens {
+			views = append(views, tokenView(t, webTokens.clock()))
+		}
+		writeJSON(w, map[string]interface{}{"tokens": views})
+		return
+	}
+
+	var req struct {
+		Name      string `json:"name"`
+		Scope     string `json:"scope"`
+		ExpiresIn string `json:"expiresIn"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4<<10)).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	scope, err := parseTokenScope(req.Scope)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ttl, err := parseTokenTTL(req.ExpiresIn)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s, _ := sessionFrom(r.Context())
+	raw, t, err := webTokens.create(req.Name, s.Name, scope, ttl)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	newWebLogger().Infof("%s created %s API token %s (%q)", s.Name, scope, t.ID, t.Name)
+	v := tokenView(t, webTokens.clock())
+	v["token"] = raw
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, v)
+}
+
+// handleRevokeToken revokes the token named in the path.
+func handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if webSSO == nil {
+		http.Error(w, `{"error": "API tokens need single sign-on (OIDC_ISSUER)"}`, http.StatusNotFound)
+		return
+	}
+	t, ok, err := webTokens.revoke(mux.Vars(r)["id"])
+	if err != nil {
+		newWebLogger().Warnf("Revoking API token: %v", err)
+		http.Error(w, `{"error": "Failed to revoke API token"}`, http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, `{"error": "No such API token"}`, http.StatusNotFound)
+		return
+	}
+	s, _ := sessionFrom(r.Context())
+	newWebLogger().Infof("%s revoked API token %s (%q)", s.Name, t.ID, t.Name)
+	writeJSON(w, tokenView(t, webTokens.clock()))
+}
+
+// writeJSONError writes {"error": msg} with status.
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	writeJSON(w, map[string]string{"error": msg})
+}
+
+// parseTokenTTL parses a token lifetime such as "30d" or "12h"; "" is the
+// 90-day default.
+func parseTokenTTL(s string) (time.Duration, error) {
+	if strings.TrimSpace(s) == "" {
+		return defaultTokenTTL, nil
+	}
+	d, err := history.ParseSince(s)
+	if err != nil || d <= 0 || d > maxTokenTTL {
+		return 0, fmt.Errorf("token lifetime must be a duration such as 30d, at most 366d (got %q)", s)
+	}
+	return d, nil
+}
+
+// runTokenCommand implements "token create|list|revoke", for managing API
+// tokens on the server host without signing in.
+func runTokenCommand(w io.Writer, args []string, getenv func(string) string) int {
+	if len(args) == 0 || (args[0] != "create" && args[0] != "list" && args[0] != "revoke") {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: Find-Meraki-Ports-With-MAC token create --name NAME --scope search|export|admin [--expires 90d]")
+		_, _ = fmt.Fprintln(os.Stderr, "       Find-Meraki-Ports-With-MAC token list")
+		_, _ = fmt.Fprintln(os.Stderr, "       Find-Meraki-Ports-With-MAC token revoke ID")
+		return 2
+	}
+	fs := flag.NewFlagSet("token "+args[0], flag.ContinueOnError)
+	fileFlag := fs.String("tokens-file", firstNonEmpty(getenv("API_TOKENS_FILE"), defaultTokensFile()), "API token file")
+	nameFlag := fs.String("name", "", "create: what the token is for, e.g. \"nightly inventory export\"")
+	scopeFlag := fs.String("scope", "", "create: search, export or admin")
+	expiresFlag := fs.String("expires", "", "create: lifetime such as 30d (default 90d, at most 366d)")
+	// Allow the token ID before the flags: "token revoke ID --tokens-file F".
+	rest := args[1:]
+	var ids []string
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		ids, rest = rest[:1], rest[1:]
+	}
+	if err := fs.Parse(rest); err != nil {
+		return 2
+	}
+	ids = append(ids, fs.Args()...)
+	store := &tokenStore{path: *fileFlag}
+
+	switch args[0] {
+	case "create":
+		// Only the single sign-on middleware checks tokens; without it the web
+		// API is open and a token would do nothing.
+		if getenv("OIDC_ISSUER") == "" {
+			_, _ = fmt.Fprintln(os.Stderr, "ERROR: API tokens need single sign-on; set OIDC_ISSUER for the web server first")
+			return 1
+		}
+		scope, err := parseTokenScope(*scopeFlag)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: --scope: %v\n", err)
+			return 2
+		}
+		ttl, err := parseTokenTTL(*expiresFlag)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: --expires: %v\n", err)
+			return 2
+		}
+		owner := "cli:" + firstNonEmpty(getenv("USER"), getenv("USERNAME"), "unknown")
+		raw, t, err := store.create(*nameFlag, owner, scope, ttl)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		_, _ = fmt.Fprintf(w, "Created %s token %s (%q), expires %s.\n", t.Scope, t.ID, t.Name, t.Expires.Local().Format("2006-01-02 15:04"))
+		_, _ = fmt.Fprintf(w, "Token (shown only once): %s\n", raw)
+	case "list":
+		tokens, err := store.list()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		if len(tokens) == 0 {
+			_, _ = fmt.Fprintln(w, "No API tokens.")
+			return 0
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "ID\tNAME\tSCOPE\tOWNER\tEXPIRES\tSTATUS")
+		now := time.Now()
+		for _, t := range tokens {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Scope, t.Owner, t.Expires.Local().Format("2006-01-02"), t.status(now))
+		}
+		_ = tw.Flush()
+	case "revoke":
+		if len(ids) != 1 {
+			_, _ = fmt.Fprintln(os.Stderr, "ERROR: token revoke needs exactly one token ID (see token list)")
+			return 2
+		}
+		t, ok, err := store.revoke(ids[0])
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		if !ok {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR: no API token %s\n", ids[0])
+			return 1
+		}
+		_, _ = fmt.Fprintf(w, "Revoked token %s (%q).\n", t.ID, t.Name)
+	}
+	return 0
+}
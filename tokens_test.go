@@ -0,0 +1,251 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTokenStore(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	s := &tokenStore{path: filepath.Join(t.TempDir(), "tokens.json"), now: func() time.Time { return now }}
+
+	raw, tok, err := s.create("nightly export", "Ada", scopeExport, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw, tokenPrefix+tok.ID+"_") || tok.Hash != "" {
+		t.Errorf("create() = %q, %+v", raw, tok)
+	}
+	data, _ := os.ReadFile(s.path)
+	if strings.Contains(string(data), strings.TrimPrefix(raw, tokenPrefix+tok.ID+"_")) {
+		t.Error("the token secret was stored in clear")
+	}
+	if got, ok := s.authenticate(raw); !ok || got.Scope != scopeExport || got.Owner != "Ada" {
+		t.Errorf("authenticate() = %+v, %v", got, ok)
+	}
+	for _, bad := range []string{"", raw + "x", tokenPrefix + tok.ID, strings.Replace(raw, tok.ID, "000000000000", 1)} {
+		if _, ok := s.authenticate(bad); ok {
+			t.Errorf("authenticate(%q) accepted", bad)
+		}
+	}
+
+	now = now.Add(25 * time.Hour)
+	if _, ok := s.authenticate(raw); ok {
+		t.Error("an expired token was accepted")
+	}
+
+	raw2, tok2, _ := s.create("search bot", "Ada", scopeSearch, time.Hour)
+	if _, ok, err := s.revoke(tok2.ID); !ok || err != nil {
+		t.Fatalf("revoke() = %v, %v", ok, err)
+	}
+	if _, ok := s.authenticate(raw2); ok {
+		t.Error("a revoked token was accepted")
+	}
+	if _, ok, _ := s.revoke("nope"); ok {
+		t.Error("revoke() of an unknown ID reported success")
+	}
+	list, _ := s.list()
+	if len(list) != 2 || list[0].ID != tok2.ID || list[0].status(now) != "revoked" || list[1].status(now) != "expired" {
+		t.Errorf("list() = %+v", list)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		scope tokenScope
+		ttl   time.Duration
+	}{{"", scopeSearch, time.Hour}, {"x", scopeNone, time.Hour}, {"x", scopeSearch, 0}, {"x", scopeSearch, 400 * 24 * time.Hour}} {
+		if _, _, err := s.create(tt.name, "Ada", tt.scope, tt.ttl); err == nil {
+			t.Errorf("create(%q, %v, %v) should fail", tt.name, tt.scope, tt.ttl)
+		}
+	}
+}
+
+func TestTokenScopes(t *testing.T) {
+	defer func(s *tokenStore) { webTokens = s }(webTokens)
+	now := time.Now()
+	webTokens = &tokenStore{path: filepath.Join(t.TempDir(), "tokens.json")}
+	a := testSSO(now)
+	h := a.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := sessionFrom(r.Context())
+		_, _ = w.Write([]byte("ok " + s.Name))
+	}))
+	search, _, _ := webTokens.create("search bot", "Ada", scopeSearch, time.Hour)
+	export, _, _ := webTokens.create("export bot", "Ada", scopeExport, time.Hour)
+	admin, _, _ := webTokens.create("admin bot", "Ada", scopeAdmin, time.Hour)
+	revoked, rt, _ := webTokens.create("old bot", "Ada", scopeAdmin, time.Hour)
+	_, _, _ = webTokens.revoke(rt.ID)
+
+	tests := []struct {
+		name, method, path, token string
+		wantStatus                int
+	}{
+		{"search token searches", "POST", "/api/resolve", search, http.StatusOK},
+		{"search token cannot export", "GET", "/api/inventory", search, http.StatusForbidden},
+		{"export token exports", "GET", "/api/inventory", export, http.StatusOK},
+		{"export token cannot blink", "POST", "/api/identify", export, http.StatusForbidden},
+		{"export token cannot mint tokens", "POST", "/api/tokens", export, http.StatusForbidden},
+		{"admin token blinks", "POST", "/api/identify", admin, http.StatusOK},
+		{"admin token lists tokens", "GET", "/api/tokens", admin, http.StatusOK},
+		{"revoked token", "POST", "/api/resolve", revoked, http.StatusUnauthorized},
+		{"unknown token on a page", "GET", "/topology", "fmp_0_0", http.StatusUnauthorized},
+		{"basic auth", "GET", "/api/networks", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		} else {
+			req.SetBasicAuth("user", "pass")
+		}
+		// A valid browser session must not lift a bad token.
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: a.seal("session", webSession{Name: "Root", Role: roleAdmin, Expires: now.Add(time.Hour).Unix()})})
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: %d %s, want %d", tt.name, rec.Code, rec.Body.String(), tt.wantStatus)
+		}
+	}
+}
+
+func TestTokenEndpoints(t *testing.T) {
+	defer func(s *tokenStore, sso *ssoAuth) { webTokens, webSSO = s, sso }(webTokens, webSSO)
+	now := time.Now()
+	webTokens = &tokenStore{path: filepath.Join(t.TempDir(), "tokens.json")}
+	webSSO = testSSO(now)
+	r := mux.NewRouter()
+	registerAPIRoutes(r)
+	h := webSSO.wrap(r)
+	admin := &http.Cookie{Name: sessionCookie, Value: webSSO.seal("session", webSession{Name: "Root", Role: roleAdmin, Expires: now.Add(time.Hour).Unix()})}
+	do := func(method, path, body string, c *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if c != nil {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("POST", "/api/tokens", `{"name":"inventory export","scope":"export","expiresIn":"30d"}`, admin)
+	var created struct {
+		ID, Token, Owner, Scope, Status string
+		Expires                         time.Time
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); rec.Code != http.StatusCreated || err != nil {
+		t.Fatalf("create = %d %s", rec.Code, rec.Body.String())
+	}
+	if created.Owner != "Root" || created.Scope != "export" || created.Status != "active" || created.Expires.Sub(now) < 29*24*time.Hour {
+		t.Errorf("created %+v", created)
+	}
+	if rec := do("POST", "/api/tokens", `{"name":"x","scope":"root"}`, admin); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown scope = %d", rec.Code)
+	}
+	viewer := &http.Cookie{Name: sessionCookie, Value: webSSO.seal("session", webSession{Name: "Ada", Role: roleViewer, Expires: now.Add(time.Hour).Unix()})}
+	if rec := do("GET", "/api/tokens", "", viewer); rec.Code != http.StatusForbidden {
+		t.Errorf("viewer listing tokens = %d", rec.Code)
+	}
+
+	rec = do("GET", "/api/tokens", "", admin)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), created.Token) || strings.Contains(rec.Body.String(), "hash") {
+		t.Errorf("list = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("DELETE", "/api/tokens/"+created.ID, "", admin); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"revoked"`) {
+		t.Errorf("revoke = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("DELETE", "/api/tokens/unknown", "", admin); rec.Code != http.StatusNotFound {
+		t.Errorf("revoke unknown = %d", rec.Code)
+	}
+}
+
+func TestRunTokenCommand(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens.json")
+	env := func(k string) string {
+		return map[string]string{"API_TOKENS_FILE": file, "USER": "ops", "OIDC_ISSUER": "https://idp"}[k]
+	}
+	var out bytes.Buffer
+	if code := runTokenCommand(&out, []string{"create", "--name", "cron", "--scope", "search", "--expires", "7d"}, env); code != 0 {
+		t.Fatalf("create exit %d", code)
+	}
+	raw := strings.TrimSpace(out.String()[strings.Index(out.String(), tokenPrefix):])
+	tok, ok := (&tokenStore{path: file}).authenticate(raw)
+	if !ok || tok.Owner != "cli:ops" || tok.Scope != scopeSearch {
+		t.Fatalf("created token %+v, %v from %q", tok, ok, out.String())
+	}
+
+	out.Reset()
+	if code := runTokenCommand(&out, []string{"list"}, env); code != 0 || !strings.Contains(out.String(), tok.ID) || !strings.Contains(out.String(), "active") {
+		t.Errorf("list exit %d:\n%s", code, out.String())
+	}
+	out.Reset()
+	if code := runTokenCommand(&out, []string{"revoke", tok.ID}, env); code != 0 {
+		t.Errorf("revoke exit %d", code)
+	}
+	if _, ok := (&tokenStore{path: file}).authenticate(raw); ok {
+		t.Error("the token still works after revoke")
+	}
+	if code := runTokenCommand(&out, []string{"create", "--name", "x", "--scope", "superuser"}, env); code != 2 {
+		t.Errorf("bad scope exit %d, want 2", code)
+	}
+
+	// Without single sign-on nothing checks tokens, so none is issued.
+	noSSO := filepath.Join(t.TempDir(), "tokens.json")
+	env = func(k string) string { return map[string]string{"API_TOKENS_FILE": noSSO}[k] }
+	out.Reset()
+	if code := runTokenCommand(&out, []string{"create", "--name", "cron", "--scope", "search"}, env); code != 1 || strings.Contains(out.String(), tokenPrefix) {
+		t.Errorf("create without OIDC_ISSUER exit %d, output %q; want 1 and no token", code, out.String())
+	}
+	if _, err := os.Stat(noSSO); !os.IsNotExist(err) {
+		t.Errorf("create without OIDC_ISSUER wrote %s", noSSO)
+	}
+}
+
+func TestRunTokenCommandFlagsAfterID(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens.json")
+	other := filepath.Join(t.TempDir(), "tokens.json")
+	env := func(k string) string {
+		return map[string]string{"API_TOKENS_FILE": other, "USER": "ops"}[k]
+	}
+	store := &tokenStore{path: file}
+	raw, tok, err := store.create("cron", "cli:ops", scopeSearch, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if code := runTokenCommand(&out, []string{"revoke", tok.ID, "--tokens-file", file}, env); code != 0 {
+		t.Fatalf("revoke ID --tokens-file exit %d", code)
+	}
+	if _, ok := store.authenticate(raw); ok {
+		t.Error("the token still works after revoke with --tokens-file after the ID")
+	}
+	if code := runTokenCommand(&out, []string{"revoke", tok.ID, "extra", "--tokens-file", file}, env); code != 2 {
+		t.Errorf("revoke with two IDs exit %d, want 2", code)
+	}
+	if code := runTokenCommand(&out, []string{"revoke", "--tokens-file", file}, env); code != 2 {
+		t.Errorf("revoke without an ID exit %d, want 2", code)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// maxUIStateBytes bounds a stored UI state document; the web UI only saves a
+// handful of selections, so anything larger is a client bug or abuse.
+const maxUIStateBytes = 64 << 10
+
+// instanceID identifies a web server instance across restarts, so browser state
+// saved against one instance (host, listen address, API key) is not applied to
+// another that happens to be served from the same origin. Only a truncated hash
+// of the inputs is exposed.
+func instanceID(hostname, addr, apiKey string, testData bool) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%t", hostname, addr, apiKey, testData)))
+	return hex.EncodeToString(sum[:6])
+}
+
+// defaultUIStateFile is ~/.find-mac-ui-state.json, or a file in the working
+// directory when the home directory is unknown.
+func defaultUIStateFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".find-mac-ui-state.json"
+	}
+	return filepath.Join(home, ".find-mac-ui-state.json")
+}
+
+// uiStateStore persists one opaque JSON object of web UI state per server
+// instance. It backs /api/ui-state so selections survive a browser change once
+// users are authenticated; until then the browser's localStorage is primary.
+type uiStateStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// get returns the state saved for instance, or nil if there is none.
+func (s *uiStateStore) get(instance string) (json.RawMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return states[instance], nil
+}
+
+// put replaces the state saved for instance and rewrites the file atomically.
+func (s *uiStateStore) put(instance string, state json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states, err := s.load()
+	if err != nil {
+		return err
+	}
+	states[instance] = state
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := output.CreatePrivateAtomic(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// load reads every instance's state; a missing file is an empty store.
+func (s *uiStateStore) load() (map[string]json.RawMessage, error) {
+	states := map[string]json.RawMessage{}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("%s: %v", s.path, err)
+	}
+	return states, nil
+}
+
+// handleUIState serves GET (the saved state, or {} when none) and PUT (replace
+// it with the request body, which must be a JSON object) for this instance.
+func handleUIState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+		state, err := webUIState.get(webInstanceID)
+		if err != nil {
+			newWebLogger().Warnf("Reading UI state: %v", err)
+			http.Error(w, `{"error": "Failed to read UI state"}`, http.StatusInternalServerError)
+			return
+		}
+		if state == nil {
+			state = json.RawMessage("{}")
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, state)
+	case http.MethodPut:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxUIStateBytes+1))
+		if err != nil || len(body) > maxUIStateBytes {
+			http.Error(w, `{"error": "UI state too large"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(body, &obj); err != nil {
+			http.Error(w, `{"error": "UI state must be a JSON object"}`, http.StatusBadRequest)
+			return
+		}
+		if err := webUIState.put(webInstanceID, json.RawMessage(body)); err != nil {
+			newWebLogger().Warnf("Saving UI state: %v", err)
+			http.Error(w, `{"error": "Failed to save UI state"}`, http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstanceID(t *testing.T) {
+	a := instanceID("host", "localhost:8080", "key", false)
+	if a != instanceID("host", "localhost:8080", "key", false) {
+		t.Error("instanceID is not stable for the same inputs")
+	}
+	for _, other := range []string{
+		instanceID("host", "localhost:8081", "key", false),
+		instanceID("host", "localhost:8080", "other", false),
+		instanceID("host", "localhost:8080", "key", true),
+	} {
+		if other == a {
+			t.Errorf("instanceID collision: %s", a)
+		}
+	}
+	if strings.Contains(a, "key") || len(a) != 12 {
+		t.Errorf("instanceID = %q, want a 12-char hex hash", a)
+	}
+}
+
+func TestHandleUIState(t *testing.T) {
+	oldStore, oldID := webUIState, webInstanceID
+	defer func() { webUIState, webInstanceID = oldStore, oldID }()
+	webUIState = &uiStateStore{path: filepath.Join(t.TempDir(), "ui.json")}
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handleUIState(rec, httptest.NewRequest(method, "/api/ui-state", strings.NewReader(body)))
+		return rec
+	}
+
+	webInstanceID = "one"
+	if rec := do(http.MethodGet, ""); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "{}" {
+		t.Fatalf("GET empty = %d %q, want 200 {}", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPut, `{"org":"o1","pageSize":250}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT = %d, want 204", rec.Code)
+	}
+	if rec := do(http.MethodGet, ""); !strings.Contains(rec.Body.String(), `"pageSize":250`) {
+		t.Errorf("GET after PUT = %q", rec.Body.String())
+	}
+
+	webInstanceID = "two"
+	if rec := do(http.MethodGet, ""); strings.TrimSpace(rec.Body.String()) != "{}" {
+		t.Errorf("other instance sees %q, want {}", rec.Body.String())
+	}
+
+	for _, bad := range []string{`[1,2]`, `not json`} {
+		if rec := do(http.MethodPut, bad); rec.Code != http.StatusBadRequest {
+			t.Errorf("PUT %q = %d, want 400", bad, rec.Code)
+		}
+	}
+	if rec := do(http.MethodPut, `{"x":"`+strings.Repeat("a", maxUIStateBytes)+`"}`); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized PUT = %d, want 413", rec.Code)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// neighborUplinks flags results found on a port whose LLDP/CDP neighbor is
+// another switch. Such a hit is an echo of the client learned through the
+// neighbor; the access-port hit on the neighbor (or further down) is the one
+// people want. It also names the neighbor of ports whose port statuses do not.
+// Each switch's neighbors are fetched once.
+type neighborUplinks struct {
+	ctx       context.Context
+	client    *meraki.MerakiClient
+	ports     map[string]map[string]string             // serial → port → switch neighbor name
+	neighbors map[string]map[string]meraki.LLDPCDPPort // serial → port → neighbors
+}
+
+func newNeighborUplinks(ctx context.Context, client *meraki.MerakiClient) *neighborUplinks {
+	return &neighborUplinks{
+		ctx:       ctx,
+		client:    client,
+		ports:     make(map[string]map[string]string),
+		neighbors: make(map[string]map[string]meraki.LLDPCDPPort),
+	}
+}
+
+// load fetches a switch's LLDP/CDP neighbors on first use. A switch whose
+// neighbors cannot be read has none.
+func (n *neighborUplinks) load(serial string) {
+	if _, ok := n.ports[serial]; ok {
+		return
+	}
+	n.ports[serial], n.neighbors[serial] = make(map[string]string), nil
+	if data, err := n.client.GetDeviceLLDPCDP(n.ctx, serial); err == nil {
+		n.ports[serial], n.neighbors[serial] = data.SwitchNeighborPorts(), data.Ports
+	}
+}
+
+// mark sets row.IsUplink and notes the neighbor when row's port, or a member
+// of its link aggregate, faces another switch.
+func (n *neighborUplinks) mark(row *output.ResultRow) {
+	if row.SwitchSerial == "" || row.Port == "" || row.Source == output.SourceDevice {
+		return
+	}
+	n.load(row.SwitchSerial)
+	ports := n.ports[row.SwitchSerial]
+	name, found := ports[row.Port]
+	for _, m := range row.AggrPorts {
+		if found {
+			break
+		}
+		name, found = ports[m]
+	}
+	if !found {
+		return
+	}
+	if !row.IsUplink && row.Explain != nil {
+		row.Explain = setFieldSource(row.Explain, output.FieldSource{Field: "Uplink", Source: srcLLDPNeighbors, Detail: "GET /devices/" + row.SwitchSerial + "/lldpCdp"})
+	}
+	row.IsUplink = true
+	row.Note = joinNotes(row.Note, "uplink to "+firstNonEmpty(name, "another switch"))
+}
+
+// fillNeighbor names the LLDP/CDP neighbor on row's port, and the platform it
+// advertises, when the port statuses did not. A link aggregate takes the
+// neighbor of its first member that has one. Wireless and device rows are
+// left alone.
+func (n *neighborUplinks) fillNeighbor(row *output.ResultRow) {
+	if row.Neighbor != "" || row.SwitchSerial == "" || row.Port == "" || row.Port == "wireless" || row.SSID != "" || row.Source == output.SourceDevice {
+		return
+	}
+	n.load(row.SwitchSerial)
+	for _, port := range append([]string{row.Port}, row.AggrPorts...) {
+		p, ok := n.neighbors[row.SwitchSerial][port]
+		if !ok {
+			continue
+		}
+		if row.Neighbor, row.NeighborPlatform = portNeighbor(p.LLDP, p.CDP); row.Neighbor == "" {
+			continue
+		}
+		if row.Explain != nil {
+			row.Explain = setFieldSource(row.Explain, output.FieldSource{Field: "Neighbor", Source: srcLLDPNeighbors, Detail: "GET /devices/" + row.SwitchSerial + "/lldpCdp"})
+		}
+		return
+	}
+}
+
+// hideUplinkRows implements --hide-uplinks: it drops uplink rows of every MAC
+// that was also found on a non-uplink port. A MAC seen only on uplinks keeps
+// them, since they are the only clue to where it is.
+func hideUplinkRows(rows []output.ResultRow) []output.ResultRow {
+	onAccess := make(map[string]bool)
+	for _, r := range rows {
+		if !r.IsUplink {
+			onAccess[strings.ToLower(r.MAC)] = true
+		}
+	}
+	out := rows[:0]
+	for _, r := range rows {
+		if r.IsUplink && onAccess[strings.ToLower(r.MAC)] {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+func TestNeighborUplinksMark(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/devices/Q2SW/lldpCdp" {
+			http.NotFound(w, r)
+			return
+		}
+		calls++
+		_, _ = w.Write([]byte(`{"ports":{"49":{"cdp":{"deviceId":"core-1","capabilities":"Router, Switch, IGMP"}},
+			"7":{"lldp":{"systemName":"phone","systemCapabilities":"Bridge, Telephone"}}}}`))
+	}))
+	defer srv.Close()
+
+	n := newNeighborUplinks(context.Background(), meraki.NewClient("key", srv.URL, 1))
+	trunk := output.ResultRow{SwitchSerial: "Q2SW", Port: "49", MAC: "aa", Explain: []output.FieldSource{{Field: "Port"}}}
+	n.mark(&trunk)
+	if !trunk.IsUplink || trunk.Note != "uplink to core-1" {
+		t.Errorf("mark(port 49) = %+v, want uplink to core-1", trunk)
+	}
+	if e := trunk.Explain[len(trunk.Explain)-1]; e.Field != "Uplink" || e.Source != srcLLDPNeighbors {
+		t.Errorf("mark(port 49) explain = %+v", trunk.Explain)
+	}
+	aggr := output.ResultRow{SwitchSerial: "Q2SW", Port: "AGGR/1", AggrPorts: []string{"48", "49"}, MAC: "aa"}
+	if n.mark(&aggr); !aggr.IsUplink {
+		t.Error("mark(AGGR/1 with member 49) not flagged as uplink")
+	}
+	phone := output.ResultRow{SwitchSerial: "Q2SW", Port: "7", MAC: "aa"}
+	if n.mark(&phone); phone.IsUplink || phone.Note != "" {
+		t.Errorf("mark(port behind a phone) = %+v, want unchanged", phone)
+	}
+	if calls != 1 {
+		t.Errorf("lldpCdp fetched %d times, want once per switch", calls)
+	}
+}
+
+func TestNeighborUplinksFillNeighbor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/devices/Q2SW/lldpCdp" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ports":{
+			"7":{"cdp":{"deviceId":"SEP0011223344","portId":"Port 1","platform":"Cisco IP Phone 8845","capabilities":"Host, Phone"}},
+			"12":{"lldp":{"systemName":"ap-lobby","portId":"eth0","systemDescription":"Meraki MR46 Cloud Managed AP"}}}}`))
+	}))
+	defer srv.Close()
+
+	n := newNeighborUplinks(context.Background(), meraki.NewClient("key", srv.URL, 1))
+	phone := output.ResultRow{SwitchSerial: "Q2SW", Port: "7", Explain: []output.FieldSource{}}
+	n.fillNeighbor(&phone)
+	if phone.Neighbor != "SEP0011223344 (Port 1)" || phone.NeighborPlatform != "Cisco IP Phone 8845" {
+		t.Errorf("fillNeighbor(port 7) = %q %q, want the phone", phone.Neighbor, phone.NeighborPlatform)
+	}
+	if len(phone.Explain) != 1 || phone.Explain[0].Field != "Neighbor" || phone.Explain[0].Source != srcLLDPNeighbors {
+		t.Errorf("fillNeighbor(port 7) explain = %+v", phone.Explain)
+	}
+	aggr := output.ResultRow{SwitchSerial: "Q2SW", Port: "AGGR/2", AggrPorts: []string{"11", "12"}}
+	if n.fillNeighbor(&aggr); aggr.Neighbor != "ap-lobby (eth0)" || aggr.NeighborPlatform != "Meraki MR46 Cloud Managed AP" {
+		t.Errorf("fillNeighbor(AGGR/2) = %q %q, want ap-lobby", aggr.Neighbor, aggr.NeighborPlatform)
+	}
+	known := output.ResultRow{SwitchSerial: "Q2SW", Port: "7", Neighbor: "phone-7 (eth0)"}
+	if n.fillNeighbor(&known); known.Neighbor != "phone-7 (eth0)" || known.NeighborPlatform != "" {
+		t.Errorf("fillNeighbor() replaced the port status neighbor: %+v", known)
+	}
+	bare := output.ResultRow{SwitchSerial: "Q2SW", Port: "3"}
+	if n.fillNeighbor(&bare); bare.Neighbor != "" {
+		t.Errorf("fillNeighbor(port 3) = %q, want none", bare.Neighbor)
+	}
+}
+
+func TestHideUplinkRows(t *testing.T) {
+	rows := []output.ResultRow{
+		{MAC: "aa", Port: "49", IsUplink: true},
+		{MAC: "AA", Port: "3"},
+		{MAC: "bb", Port: "50", IsUplink: true},
+	}
+	got := hideUplinkRows(rows)
+	if len(got) != 2 || got[0].Port != "3" || got[1].MAC != "bb" {
+		t.Errorf("hideUplinkRows() = %+v, want aa's access port and bb's only (uplink) hit", got)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+
+	"Find-Meraki-Ports-With-MAC/pkg/oui"
+)
+
+// vendorRegistry returns the OUI registry: the IEEE CSV at path when set,
+// otherwise the one compiled into the binary.
+func vendorRegistry(path string) (*oui.Registry, error) {
+	if path == "" {
+		return oui.Embedded(), nil
+	}
+	return oui.Load(path)
+}
+
+// vendorMatcher returns a matcher for every MAC in the OUI blocks assigned to
+// vendor, together with those blocks. It fails when the vendor owns none, so
+// a typo does not turn into a slow scan that finds nothing.
+func vendorMatcher(reg *oui.Registry, vendor string) (func(string) bool, []oui.Assignment, error) {
+	blocks := reg.FindVendor(vendor)
+	if len(blocks) == 0 {
+		return nil, nil, fmt.Errorf("no OUI assignments found for vendor %q (%d assignments known; use --oui-file for the full IEEE registry)", vendor, reg.Len())
+	}
+	prefixes := make(map[string]struct{}, len(blocks))
+	for _, b := range blocks {
+		prefixes[b.Prefix] = struct{}{}
+	}
+	// MA-L, MA-M and MA-S blocks are 6, 7 and 9 hex digits long.
+	matcher := func(normMAC string) bool {
+		for _, n := range []int{6, 7, 9} {
+			if len(normMAC) < n {
+				return false
+			}
+			if _, ok := prefixes[normMAC[:n]]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matcher, blocks, nil
+}
+
+// vendorNames returns the distinct organization names of blocks in order.
+func vendorNames(blocks []oui.Assignment) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, b := range blocks {
+		if !seen[b.Vendor] {
+			seen[b.Vendor] = true
+			names = append(names, b.Vendor)
+		}
+	}
+	return names
+}
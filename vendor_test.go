@@ -0,0 +1,67 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"Find-Meraki-Ports-With-MAC/pkg/oui"
+)
+
+func TestVendorMatcher(t *testing.T) {
+	reg, err := oui.Parse(strings.NewReader(`Registry,Assignment,Organization Name,Organization Address
+MA-L,00408C,Axis Communications AB,
+MA-M,70B3D5F,Axis Communications AB,
+MA-L,005056,"VMware, Inc.",
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matcher, blocks, err := vendorMatcher(reg, "axis")
+	if err != nil {
+		t.Fatalf("vendorMatcher() error: %v", err)
+	}
+	if len(blocks) != 2 || strings.Join(vendorNames(blocks), ";") != "Axis Communications AB" {
+		t.Errorf("vendorMatcher() blocks = %v", blocks)
+	}
+	for mac, want := range map[string]bool{
+		"00408c123456": true,
+		"70b3d5f12345": true,
+		"70b3d5012345": false,
+		"005056000001": false,
+		"00408":        false,
+	} {
+		if got := matcher(mac); got != want {
+			t.Errorf("matcher(%q) = %v, want %v", mac, got, want)
+		}
+	}
+
+	if _, _, err := vendorMatcher(reg, "Nonexistent"); err == nil || !strings.Contains(err.Error(), "--oui-file") {
+		t.Errorf("vendorMatcher(unknown) error = %v, want hint about --oui-file", err)
+	}
+}
+
+func TestVendorRegistry(t *testing.T) {
+	reg, err := vendorRegistry("")
+	if err != nil || reg.Len() == 0 {
+		t.Fatalf("vendorRegistry(\"\") = %v, %v; want embedded registry", reg, err)
+	}
+	if _, err := vendorRegistry("/nonexistent/oui.csv"); err == nil {
+		t.Error("vendorRegistry(missing file) succeeded, want error")
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/filters"
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+// criticalDevice is one watchlist entry: a MAC and where it must be plugged in.
+type criticalDevice struct {
+	MAC    string // normalized, no separators
+	Switch string // switch name or serial as written in the watchlist
+	Port   string
+	VLAN   int // 0 means any VLAN
+	Name   string
+}
+
+// label names d in alerts: its watchlist name and MAC, or just the MAC.
+func (d criticalDevice) label() string {
+	if d.Name == "" {
+		return macaddr.FormatMacColon(d.MAC)
+	}
+	return fmt.Sprintf("%s (%s)", d.Name, macaddr.FormatMacColon(d.MAC))
+}
+
+// parseWatchlist reads mac,switch,port[,vlan[,name]] rows. A header row whose
+// first column is "mac" and lines starting with # are skipped; problems are
+// reported with the offending line number.
+func parseWatchlist(r io.Reader) ([]criticalDevice, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+	var list []criticalDevice
+	seen := make(map[string]bool)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		for i := range rec {
+			rec[i] = strings.TrimSpace(rec[i])
+		}
+		if len(list) == 0 && strings.EqualFold(rec[0], "mac") {
+			continue
+		}
+		if len(rec) < 3 || len(rec) > 5 {
+			return nil, fmt.Errorf("line %d: want mac,switch,port[,vlan[,name]]", line)
+		}
+		mac, err := macaddr.NormalizeExactMac(rec[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if rec[1] == "" || rec[2] == "" {
+			return nil, fmt.Errorf("line %d: switch and port are required", line)
+		}
+		if seen[mac] {
+			return nil, fmt.Errorf("line %d: %s is listed twice", line, macaddr.FormatMacColon(mac))
+		}
+		seen[mac] = true
+		d := criticalDevice{MAC: mac, Switch: rec[1], Port: rec[2]}
+		if len(rec) > 3 && rec[3] != "" {
+			if d.VLAN, err = strconv.Atoi(rec[3]); err != nil || d.VLAN < 1 || d.VLAN > 4094 {
+				return nil, fmt.Errorf("line %d: VLAN must be 1-4094 (got %q)", line, rec[3])
+			}
+		}
+		if len(rec) > 4 {
+			d.Name = rec[4]
+		}
+		list = append(list, d)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("watchlist has no entries")
+	}
+	return list, nil
+}
+
+// watchSwitches maps each watchlist switch, by name or serial, to a switch in
+// networks. Every unknown switch is reported so all typos surface at start.
+func watchSwitches(ctx context.Context, client *meraki.MerakiClient, networks []meraki.Network, list []criticalDevice) (map[string]meraki.Device, error) {
+	var switches []meraki.Device
+	for _, net := range networks {
+		devices, err := client.GetDevices(ctx, net.ID)
+		if err != nil {
+			return nil, fmt.Errorf("listing devices of %s: %w", net.Name, err)
+		}
+		switches = append(switches, filters.FilterSwitches(devices)...)
+	}
+	found := make(map[string]meraki.Device)
+	var missing []string
+	for _, d := range list {
+		if _, ok := found[d.Switch]; ok {
+			continue
+		}
+		i := slices.IndexFunc(switches, func(s meraki.Device) bool {
+			return strings.EqualFold(s.Serial, d.Switch) || strings.EqualFold(s.Name, d.Switch)
+		})
+		if i < 0 {
+			missing = append(missing, d.Switch)
+			continue
+		}
+		found[d.Switch] = switches[i]
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("watchlist switch(es) not found in the selected networks: %s", strings.Join(missing, ", "))
+	}
+	return found, nil
+}
+
+// macSighting is where a switch's live MAC table puts a MAC.
+type macSighting struct {
+	Switch string
+	Port   string
+	VLAN   int
+}
+
+// verifyPass reads the live MAC table of every watchlist switch once and
+// returns each device's problem, keyed by MAC; "" means it is where expected.
+// A switch whose table cannot be read leaves its devices unverified, which is
+// a problem too. It returns nil when ctx ends mid-pass.
+func verifyPass(ctx context.Context, client *meraki.MerakiClient, list []criticalDevice, switches map[string]meraki.Device, macTablePoll int, log *logger.Logger) map[string]string {
+	tables := make(map[string]map[string]macSighting) // serial → MAC → sighting
+	keys := make([]string, 0, len(switches))
+	for k := range switches {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sw := switches[k]
+		if _, ok := tables[sw.Serial]; ok {
+			continue
+		}
+		entries := pollMacTable(ctx, client, sw.Serial, macTablePoll)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if entries == nil {
+			log.Warnf("Live MAC table of %s unavailable; its critical devices are unverified", firstNonEmpty(sw.Name, sw.Serial))
+			continue
+		}
+		table := make(map[string]macSighting, len(entries))
+		for _, e := range entries {
+			mac, _ := e["mac"].(string)
+			norm, err := macaddr.NormalizeExactMac(mac)
+			if err != nil {
+				continue
+			}
+			port, _ := parseAggrPort(macTableEntryPort(e))
+			vlan, _ := e["vlan"].(float64)
+			table[norm] = macSighting{Switch: firstNonEmpty(sw.Name, sw.Serial), Port: port, VLAN: int(vlan)}
+		}
+		tables[sw.Serial] = table
+	}
+
+	problems := make(map[string]string, len(list))
+	for _, d := range list {
+		problems[d.MAC] = checkCritical(d, switches[d.Switch], tables)
+	}
+	return problems
+}
+
+// checkCritical compares one device with the MAC tables read this pass.
+func checkCritical(d criticalDevice, sw meraki.Device, tables map[string]map[string]macSighting) string {
+	name := firstNonEmpty(sw.Name, sw.Serial)
+	table, ok := tables[sw.Serial]
+	if !ok {
+		return "unverified: MAC table of " + name + " unavailable"
+	}
+	s, ok := table[d.MAC]
+	if !ok {
+		serials := make([]string, 0, len(tables))
+		for serial := range tables {
+			serials = append(serials, serial)
+		}
+		sort.Strings(serials)
+		for _, serial := range serials {
+			if other, ok := tables[serial][d.MAC]; ok {
+				return fmt.Sprintf("not on %s; seen on %s port %s", name, other.Switch, other.Port)
+			}
+		}
+		return "not on " + name
+	}
+	var problems []string
+	if !filters.SamePort(s.Port, d.Port) {
+		problems = append(problems, fmt.Sprintf("on port %s, expected %s", s.Port, d.Port))
+	}
+	if d.VLAN > 0 && s.VLAN != d.VLAN {
+		problems = append(problems, fmt.Sprintf("on VLAN %d, expected %d", s.VLAN, d.VLAN))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// verifyMonitor turns successive passes into alerts. A device is reported
+// when its problem first appears, when it changes and when it clears, never
+// again while it stays the same, so a long outage raises one alert.
+type verifyMonitor struct {
+	out    io.Writer
+	log    *logger.Logger
+	notify bool
+	last   map[string]string // MAC → problem reported by the previous pass
+	now    func() time.Time
+}
+
+func newVerifyMonitor(out io.Writer, notify bool, log *logger.Logger) *verifyMonitor {
+	return &verifyMonitor{out: out, log: log, notify: notify, last: make(map[string]string), now: time.Now}
+}
+
+// report prints the changes since the previous pass and returns how many
+// devices currently deviate.
+func (m *verifyMonitor) report(list []criticalDevice, problems map[string]string) int {
+	at := m.now().UTC().Format(time.RFC3339)
+	deviations := 0
+	for _, d := range list {
+		problem := problems[d.MAC]
+		if problem != "" {
+			deviations++
+		}
+		prev, seen := m.last[d.MAC]
+		m.last[d.MAC] = problem
+		switch {
+		case problem == prev && seen:
+			continue
+		case problem != "":
+			m.log.Warnf("Critical device %s deviates: %s", d.label(), problem)
+			_, _ = fmt.Fprintf(m.out, "%s DEVIATION %s: %s\n", at, d.label(), problem)
+			if m.notify {
+				sendDesktopNotification("Critical device moved", d.label()+": "+problem)
+			}
+		case seen:
+			m.log.Infof("Critical device %s is back on %s port %s", d.label(), d.Switch, d.Port)
+			_, _ = fmt.Fprintf(m.out, "%s RECOVERED %s: on %s port %s\n", at, d.label(), d.Switch, d.Port)
+		}
+	}
+	m.log.Infof("Verified %d critical device(s): %d deviation(s)", len(list), deviations)
+	return deviations
+}
+
+// runVerify implements --verify: it checks the watchlist once, or every
+// interval until ctx ends when interval is positive. --timeout bounds each
+// pass rather than the whole run. It returns an error when a single pass
+// finds deviations, so cron and monitoring jobs can alert on the exit status.
+func runVerify(ctx context.Context, out io.Writer, client *meraki.MerakiClient, networks []meraki.Network, list []criticalDevice, interval time.Duration, timeout string, macTablePoll int, notify bool, log *logger.Logger) error {
+	switches, err := watchSwitches(ctx, client, networks, list)
+	if err != nil {
+		return err
+	}
+	mon := newVerifyMonitor(out, notify, log)
+	for {
+		client.ResetPortCache() // each pass must see the switches as they are now
+		passCtx, cancel := searchContext(ctx, timeout)
+		problems := verifyPass(passCtx, client, list, switches, macTablePoll, log)
+		cancel()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if problems == nil && interval <= 0 {
+			return fmt.Errorf("verification did not finish within --timeout %s", timeout)
+		}
+		if problems == nil {
+			log.Warnf("Verification pass stopped after --timeout %s; devices are rechecked next pass", timeout)
+		} else if n := mon.report(list, problems); interval <= 0 && n > 0 {
+			return fmt.Errorf("%d of %d critical device(s) are not where expected", n, len(list))
+		}
+		if interval <= 0 || meraki.SleepContext(ctx, interval) != nil {
+			return nil
+		}
+	}
+}
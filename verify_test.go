@@ -0,0 +1,152 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+func TestParseWatchlist(t *testing.T) {
+	list, err := parseWatchlist(strings.NewReader(`mac,switch,port,vlan,name
+# door controllers
+00:11:22:33:44:55, idf-1, 3, 20, door-east
+aa-bb-cc-dd-ee-ff,Q2SW-0002,AGGR/1
+`))
+	if err != nil {
+		t.Fatalf("parseWatchlist() error: %v", err)
+	}
+	if len(list) != 2 || list[0] != (criticalDevice{MAC: "001122334455", Switch: "idf-1", Port: "3", VLAN: 20, Name: "door-east"}) ||
+		list[1] != (criticalDevice{MAC: "aabbccddeeff", Switch: "Q2SW-0002", Port: "AGGR/1"}) {
+		t.Errorf("parseWatchlist() = %+v", list)
+	}
+
+	for name, in := range map[string]string{
+		"bad mac":      "zz,idf-1,3\n",
+		"no port":      "00:11:22:33:44:55,idf-1,\n",
+		"too few":      "00:11:22:33:44:55,idf-1\n",
+		"bad vlan":     "00:11:22:33:44:55,idf-1,3,5000\n",
+		"duplicate":    "00:11:22:33:44:55,idf-1,3\n001122334455,idf-2,4\n",
+		"header only":  "mac,switch,port\n",
+		"empty":        "",
+		"line numbers": "00:11:22:33:44:55,idf-1,3\n\nzz,idf-1,3\n",
+	} {
+		_, err := parseWatchlist(strings.NewReader(in))
+		if err == nil {
+			t.Errorf("%s: parseWatchlist() succeeded, want error", name)
+		}
+		if name == "line numbers" && err != nil && !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("%s: error %q does not name line 3", name, err)
+		}
+	}
+}
+
+func TestVerifyPass(t *testing.T) {
+	defer func(d time.Duration) { macTablePollInterval = d }(macTablePollInterval)
+	macTablePollInterval = 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/networks/N1/devices":
+			_, _ = w.Write([]byte(`[{"serial":"Q2SW-0001","name":"idf-1","model":"MS120","productType":"switch"},
+				{"serial":"Q2SW-0002","name":"idf-2","model":"MS120","productType":"switch"},
+				{"serial":"Q2SW-0003","name":"idf-3","model":"MS120","productType":"switch"}]`))
+		case "/devices/Q2SW-0001/liveTools/macTable", "/devices/Q2SW-0002/liveTools/macTable":
+			_, _ = w.Write([]byte(`{"macTableId":"T1"}`))
+		case "/devices/Q2SW-0001/liveTools/macTable/T1":
+			_, _ = w.Write([]byte(`{"status":"complete","entries":[
+				{"mac":"00:00:00:00:00:01","portId":"3","vlan":20},
+				{"mac":"00:00:00:00:00:02","portId":"4","vlan":30},
+				{"mac":"00:00:00:00:00:06","portId":"Gi1/0/5","vlan":20}]}`))
+		case "/devices/Q2SW-0002/liveTools/macTable/T1":
+			_, _ = w.Write([]byte(`{"status":"complete","entries":[{"mac":"00:00:00:00:00:03","portId":"9","vlan":20}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := meraki.NewClient("key", srv.URL, 1)
+	log := logger.NewWriter(io.Discard, logger.LevelError)
+	list := []criticalDevice{
+		{MAC: "000000000001", Switch: "idf-1", Port: "3", VLAN: 20},
+		{MAC: "000000000002", Switch: "idf-1", Port: "5", VLAN: 20},
+		{MAC: "000000000003", Switch: "Q2SW-0001", Port: "7"},
+		{MAC: "000000000004", Switch: "idf-2", Port: "1"},
+		{MAC: "000000000005", Switch: "idf-3", Port: "1"},
+		{MAC: "000000000006", Switch: "idf-1", Port: "5"},
+	}
+	switches, err := watchSwitches(context.Background(), client, []meraki.Network{{ID: "N1", Name: "HQ"}}, list)
+	if err != nil {
+		t.Fatalf("watchSwitches() error: %v", err)
+	}
+	got := verifyPass(context.Background(), client, list, switches, 2, log)
+	want := map[string]string{
+		"000000000001": "",
+		"000000000002": "on port 4, expected 5; on VLAN 30, expected 20",
+		"000000000003": "not on idf-1; seen on idf-2 port 9",
+		"000000000004": "not on idf-2",
+		"000000000005": "unverified: MAC table of idf-3 unavailable",
+		"000000000006": "",
+	}
+	for mac, w := range want {
+		if got[mac] != w {
+			t.Errorf("problem for %s = %q, want %q", mac, got[mac], w)
+		}
+	}
+
+	if _, err := watchSwitches(context.Background(), client, []meraki.Network{{ID: "N1", Name: "HQ"}},
+		[]criticalDevice{{MAC: "000000000001", Switch: "idf-9", Port: "1"}}); err == nil || !strings.Contains(err.Error(), "idf-9") {
+		t.Errorf("watchSwitches(unknown switch) error = %v, want it named", err)
+	}
+}
+
+func TestVerifyMonitorReport(t *testing.T) {
+	var out bytes.Buffer
+	m := newVerifyMonitor(&out, false, logger.NewWriter(io.Discard, logger.LevelError))
+	m.now = func() time.Time { return time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC) }
+	list := []criticalDevice{{MAC: "000000000001", Switch: "idf-1", Port: "3", Name: "door-east"}, {MAC: "000000000002", Switch: "idf-1", Port: "4"}}
+
+	passes := []struct {
+		problems map[string]string
+		want     int
+		lines    string
+	}{
+		{map[string]string{"000000000002": "not on idf-1"}, 1, "2026-10-18T09:00:00Z DEVIATION 00:00:00:00:00:02: not on idf-1\n"},
+		{map[string]string{"000000000002": "not on idf-1"}, 1, ""},
+		{map[string]string{"000000000001": "on port 5, expected 3", "000000000002": ""}, 1,
+			"2026-10-18T09:00:00Z DEVIATION door-east (00:00:00:00:00:01): on port 5, expected 3\n" +
+				"2026-10-18T09:00:00Z RECOVERED 00:00:00:00:00:02: on idf-1 port 4\n"},
+	}
+	for i, p := range passes {
+		out.Reset()
+		if n := m.report(list, p.problems); n != p.want {
+			t.Errorf("pass %d: report() = %d deviations, want %d", i+1, n, p.want)
+		}
+		if out.String() != p.lines {
+			t.Errorf("pass %d: output = %q, want %q", i+1, out.String(), p.lines)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/http"
+	"sort"
+)
+
+// webAPISchemaVersions lists the major versions of the JSON API described in
+// openapi.yaml that this server speaks. When a breaking change ships, add the
+// new version here and keep serving the old shape until clients have moved.
+var webAPISchemaVersions = []string{"1"}
+
+// versionInfo is the /api/version response. Clients compare schemaVersions
+// with the version they were written for, and check features before using
+// optional endpoints or request fields.
+type versionInfo struct {
+	Version        string   `json:"version"`
+	Commit         string   `json:"commit"`
+	BuildTime      string   `json:"buildTime"`
+	Features       []string `json:"features"`
+	SchemaVersions []string `json:"schemaVersions"`
+}
+
+// webFeatures lists the optional capabilities of this server. Names are
+// stable; a feature is only removed together with a schema version.
+func webFeatures() []string {
+	features := []string{
+		"alerts",
+		"hostOverrides",
+		"identify",
+		"inventory",
+		"maintenance",
+		"pagination",
+		"qr",
+		"topology",
+		"uiState",
+	}
+	if webTestDataMode {
+		features = append(features, "testData")
+	}
+	if webNotify {
+		features = append(features, "notify")
+	}
+	if webSSO != nil {
+		features = append(features, "sso", "apiTokens")
+	}
+	sort.Strings(features)
+	return features
+}
+
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	writeJSON(w, versionInfo{
+		Version:        Version,
+		Commit:         Commit,
+		BuildTime:      BuildTime,
+		Features:       webFeatures(),
+		SchemaVersions: webAPISchemaVersions,
+	})
+}
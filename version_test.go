@@ -0,0 +1,69 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestHandleVersion(t *testing.T) {
+	prev := webTestDataMode
+	webTestDataMode = true
+	defer func() { webTestDataMode = prev }()
+
+	rec := httptest.NewRecorder()
+	handleVersion(rec, httptest.NewRequest(http.MethodGet, "/api/version", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var got versionInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != Version || got.Commit != Commit {
+		t.Errorf("version/commit = %q/%q, want %q/%q", got.Version, got.Commit, Version, Commit)
+	}
+	if !slices.Contains(got.Features, "testData") || !slices.Contains(got.Features, "pagination") {
+		t.Errorf("features = %v, want testData and pagination", got.Features)
+	}
+	if !slices.IsSorted(got.Features) {
+		t.Errorf("features not sorted: %v", got.Features)
+	}
+}
+
+// The spec's major version must be one the server says it speaks, or the
+// bundled UI would warn on every page load.
+func TestSchemaVersionsMatchOpenAPI(t *testing.T) {
+	var spec struct {
+		Info struct {
+			Version string `yaml:"version"`
+		} `yaml:"info"`
+	}
+	if err := yaml.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatal(err)
+	}
+	major, _, _ := strings.Cut(spec.Info.Version, ".")
+	if !slices.Contains(webAPISchemaVersions, major) {
+		t.Errorf("openapi.yaml version %s is not in webAPISchemaVersions %v", spec.Info.Version, webAPISchemaVersions)
+	}
+}
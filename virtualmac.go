@@ -0,0 +1,71 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+	"Find-Meraki-Ports-With-MAC/pkg/output"
+)
+
+// virtualMACNote returns the annotation for a first-hop redundancy virtual MAC,
+// or "" when mac is an ordinary client address.
+func virtualMACNote(mac string) string {
+	norm, err := macaddr.NormalizeExactMac(mac)
+	if err != nil {
+		return ""
+	}
+	kind, group, ok := macaddr.VirtualMAC(norm)
+	if !ok {
+		return ""
+	}
+	if kind == macaddr.VirtualVRRP {
+		return fmt.Sprintf("VRRP virtual MAC (VRID %d)", group)
+	}
+	return fmt.Sprintf("%s virtual MAC (group %d)", kind, group)
+}
+
+// annotateVirtualMACs labels rows whose MAC is a VRRP/HSRP virtual router address
+// so they are not mistaken for ordinary clients. When mapOwners is set, VRRP rows
+// are also mapped to the network's MX warm-spare pair (one API call per network).
+func annotateVirtualMACs(ctx context.Context, client *meraki.MerakiClient, networks []meraki.Network, rows []output.ResultRow, mapOwners bool) {
+	netIDByName := make(map[string]string, len(networks))
+	for _, n := range networks {
+		netIDByName[n.Name] = n.ID
+	}
+	warmSpare := make(map[string]*meraki.WarmSpare) // networkID → config (nil when unavailable)
+	for i := range rows {
+		note := virtualMACNote(rows[i].MAC)
+		if note == "" {
+			continue
+		}
+		if mapOwners && strings.HasPrefix(note, macaddr.VirtualVRRP) {
+			if netID, ok := netIDByName[rows[i].NetworkName]; ok {
+				if _, cached := warmSpare[netID]; !cached {
+					warmSpare[netID], _ = client.GetApplianceWarmSpare(ctx, netID)
+				}
+				if ws := warmSpare[netID]; ws != nil && ws.Enabled {
+					note += fmt.Sprintf(" — MX warm spare %s/%s", ws.PrimarySerial, ws.SpareSerial)
+				}
+			}
+		}
+		rows[i].Note = note
+	}
+}
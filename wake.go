@@ -0,0 +1,127 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/macaddr"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+// wakePollInterval is how often a started ping is polled; tests shorten it.
+var wakePollInterval = 2 * time.Second
+
+// wakeClient implements --wake: before a search reports "not found", it asks
+// each network's MX to ping the target's last known IP so the device answers
+// and the switches relearn its MAC. ip is used when known; otherwise the IP is
+// looked up from the network clients list by mac. It returns true if any ping
+// was sent, i.e. a second lookup is worth trying. Failures are logged, not
+// fatal.
+func wakeClient(ctx context.Context, client *meraki.MerakiClient, networks []meraki.Network, mac, ip string, maxPoll int, log *logger.Logger) bool {
+	pinged := false
+	for _, net := range networks {
+		devices, err := client.GetDevices(ctx, net.ID)
+		if err != nil {
+			log.Warnf("--wake: listing devices of %s: %v", net.Name, err)
+			continue
+		}
+		var mx *meraki.Device
+		for i, d := range devices {
+			if d.ProductType == "appliance" || strings.HasPrefix(strings.ToUpper(d.Model), "MX") {
+				mx = &devices[i]
+				break
+			}
+		}
+		if mx == nil {
+			log.Debugf("--wake: no MX in %s", net.Name)
+			continue
+		}
+		target := ip
+		if target == "" {
+			target = lastKnownIP(ctx, client, net, mac)
+		}
+		if target == "" {
+			log.Debugf("--wake: no known IP for %s in %s", macaddr.FormatMacColon(mac), net.Name)
+			continue
+		}
+		log.Infof("--wake: pinging %s from %s in %s", target, firstNonEmpty(mx.Name, mx.Serial), net.Name)
+		pingID, err := client.CreatePing(ctx, mx.Serial, target, 5)
+		if err != nil {
+			log.Warnf("--wake: starting ping from %s: %v", mx.Serial, err)
+			continue
+		}
+		pinged = true
+		for i := 0; i < maxPoll; i++ {
+			if meraki.SleepContext(ctx, wakePollInterval) != nil {
+				break
+			}
+			status, received, err := client.GetPing(ctx, mx.Serial, pingID)
+			if err != nil {
+				log.Warnf("--wake: polling ping %s: %v", pingID, err)
+				break
+			}
+			if status == "complete" || status == "failed" {
+				log.Infof("--wake: %s answered %d of 5 pings", target, received)
+				break
+			}
+		}
+	}
+	return pinged
+}
+
+// lastKnownIP returns the most recently seen IP of mac in the network clients
+// list, or "" when the MAC is unknown there.
+func lastKnownIP(ctx context.Context, client *meraki.MerakiClient, network meraki.Network, mac string) string {
+	clients, err := client.GetNetworkClients(ctx, network.ID)
+	if err != nil {
+		return ""
+	}
+	var ip, seen string
+	for _, nc := range clients {
+		norm, err := macaddr.NormalizeExactMac(nc.MAC)
+		if err != nil || norm != mac || nc.IP == "" {
+			continue
+		}
+		if ip == "" || nc.LastSeen > seen {
+			ip, seen = nc.IP, nc.LastSeen
+		}
+	}
+	return ip
+}
+
+// wakeTarget picks what --wake pings: a single --ip, or the normalized MAC of
+// a single exact --mac whose IP is then looked up. Subnets, patterns and lists
+// have no single target.
+func wakeTarget(mac, ip string) (wakeMAC, wakeIP string, ok bool) {
+	if ip != "" {
+		if isSubnet(ip) {
+			return "", "", false
+		}
+		return "", ip, true
+	}
+	if mac == "" {
+		return "", "", false
+	}
+	norm, err := macaddr.NormalizeExactMac(mac)
+	if err != nil {
+		return "", "", false
+	}
+	return norm, "", true
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2025 Kent Behrends
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
+)
+
+func TestWakeClient(t *testing.T) {
+	defer func(d time.Duration) { wakePollInterval = d }(wakePollInterval)
+	wakePollInterval = time.Millisecond
+
+	var pinged []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/networks/N1/devices":
+			_, _ = w.Write([]byte(`[{"serial":"Q2SW-0001","model":"MS120","productType":"switch"},
+				{"serial":"Q2MX-0001","name":"edge","model":"MX68","productType":"appliance"}]`))
+		case "/networks/N2/devices":
+			_, _ = w.Write([]byte(`[{"serial":"Q2SW-0002","model":"MS120","productType":"switch"}]`))
+		case "/networks/N1/clients":
+			_, _ = w.Write([]byte(`[{"mac":"aa:bb:cc:00:00:01","ip":"10.0.0.4","lastSeen":"2026-01-01T00:00:00Z"},
+				{"mac":"aa:bb:cc:00:00:01","ip":"10.0.0.5","lastSeen":"2026-02-01T00:00:00Z"}]`))
+		case "/devices/Q2MX-0001/liveTools/ping":
+			var body struct{ Target string }
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			pinged = append(pinged, body.Target)
+			_, _ = w.Write([]byte(`{"pingId":"p1","status":"new"}`))
+		case "/devices/Q2MX-0001/liveTools/ping/p1":
+			_, _ = w.Write([]byte(`{"pingId":"p1","status":"complete","results":{"received":5}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := meraki.NewClient("key", srv.URL, 1)
+	log := logger.NewWriter(io.Discard, logger.LevelError)
+	networks := []meraki.Network{{ID: "N1", Name: "HQ"}, {ID: "N2", Name: "No MX"}}
+
+	if !wakeClient(context.Background(), client, networks, "aabbcc000001", "", 3, log) {
+		t.Fatal("wakeClient(mac) = false, want a ping from the MX")
+	}
+	if len(pinged) != 1 || pinged[0] != "10.0.0.5" {
+		t.Errorf("pinged %v, want the most recent IP 10.0.0.5", pinged)
+	}
+	if wakeClient(context.Background(), client, networks, "aabbcc000099", "", 3, log) {
+		t.Error("wakeClient(unknown mac) = true, want no ping without a known IP")
+	}
+}
+
+func TestWakeTarget(t *testing.T) {
+	tests := []struct {
+		mac, ip         string
+		wantMAC, wantIP string
+		ok              bool
+	}{
+		{"", "10.0.0.5", "", "10.0.0.5", true},
+		{"", "10.0.0.0/24", "", "", false},
+		{"AA-BB-CC-00-00-01", "", "aabbcc000001", "", true},
+		{"aa:bb:cc:*:*:*", "", "", "", false},
+		{"aa:bb:cc:00:00:01,aa:bb:cc:00:00:02", "", "", "", false},
+		{"", "", "", "", false},
+	}
+	for _, tt := range tests {
+		mac, ip, ok := wakeTarget(tt.mac, tt.ip)
+		if mac != tt.wantMAC || ip != tt.wantIP || ok != tt.ok {
+			t.Errorf("wakeTarget(%q, %q) = %q, %q, %v; want %q, %q, %v", tt.mac, tt.ip, mac, ip, ok, tt.wantMAC, tt.wantIP, tt.ok)
+		}
+	}
+}
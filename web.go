@@ -16,16 +16,26 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"Find-Meraki-Ports-With-MAC/pkg/config"
 	"Find-Meraki-Ports-With-MAC/pkg/logger"
+	"Find-Meraki-Ports-With-MAC/pkg/meraki"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -81,26 +91,20 @@ func newWebLogger() *logger.Logger {
 	return logger.NewWriter(io.MultiWriter(os.Stderr, wsWriter{}), logger.LevelDebug)
 }
 
-func startWebServer(cfg Config, host, port string) {
-	webAPIKey = cfg.APIKey
-	webPresetMAC = cfg.MACAddress
-	webPresetIP = cfg.IPAddress
-	webPresetOrgName = cfg.OrgName
-	webPresetNetwork = cfg.NetworkName
-	log := newWebLogger()
-	log.Infof("Starting web server on %s:%s", host, port)
-
-	r := mux.NewRouter()
-
-	// Static files — served with no-cache so the browser always fetches the
-	// latest JS/CSS after a binary rebuild (development tool on localhost).
-	staticHandler := http.StripPrefix("/static/", http.FileServer(http.Dir("./static/")))
-	r.PathPrefix("/static/").HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		w.Header().Set("Cache-Control", "no-store")
-		staticHandler.ServeHTTP(w, req)
-	})
+// staticETag derives an ETag for a file under root from its size and
+// modification time, or returns "" if the file does not exist. http.FileServer
+// honours the ETag header when answering If-None-Match.
+func staticETag(root, name string) string {
+	fi, err := os.Stat(filepath.Join(root, filepath.FromSlash(path.Clean("/"+name))))
+	if err != nil || fi.IsDir() {
+		return ""
+	}
+	return fmt.Sprintf(`"%x-%x"`, fi.Size(), fi.ModTime().UnixNano())
+}
 
-	// API routes
+// registerAPIRoutes adds the page, API and WebSocket routes to r. Every /api/
+// route must also be described in openapi.yaml.
+func registerAPIRoutes(r *mux.Router) {
 	r.HandleFunc("/", handleHome).Methods("GET")
 	if webTestDataMode {
 		r.HandleFunc("/api/validate-key", handleTestValidateKey).Methods("POST")
@@ -108,37 +112,174 @@ func startWebServer(cfg Config, host, port string) {
 		r.HandleFunc("/api/networks", handleTestGetNetworks).Methods("GET")
 		r.HandleFunc("/api/resolve", handleTestResolve).Methods("POST")
 		r.HandleFunc("/api/manufacturer", handleTestGetManufacturer).Methods("GET")
+		r.HandleFunc("/api/identify", handleTestIdentify).Methods("POST")
 	} else {
 		r.HandleFunc("/api/validate-key", handleValidateKey).Methods("POST")
 		r.HandleFunc("/api/config", handleGetConfig).Methods("GET")
 		r.HandleFunc("/api/networks", handleGetNetworks).Methods("GET")
 		r.HandleFunc("/api/resolve", handleResolve).Methods("POST")
 		r.HandleFunc("/api/manufacturer", handleGetManufacturer).Methods("GET")
+		r.HandleFunc("/api/identify", handleIdentify).Methods("POST")
 	}
 	r.HandleFunc("/topology", handleTopology).Methods("GET")
 	r.HandleFunc("/api/topology", handleGetTopology).Methods("GET")
+	r.HandleFunc("/api/inventory", handleGetInventory).Methods("GET")
+	r.HandleFunc("/api/qr", handleQR).Methods("GET")
+	r.HandleFunc("/api/ui-state", handleUIState).Methods("GET", "PUT")
 	r.HandleFunc("/api/alerts", handleGetAlerts).Methods("GET")
 	r.HandleFunc("/api/logs", handleLogs).Methods("GET")
 	r.HandleFunc("/api/debug/network", handleDebugNetwork).Methods("GET")
+	r.HandleFunc("/api/openapi.yaml", handleOpenAPI).Methods("GET")
+	r.HandleFunc("/api/version", handleVersion).Methods("GET")
+	r.HandleFunc("/api/me", handleMe).Methods("GET")
+	r.HandleFunc("/api/tokens", handleTokens).Methods("GET", "POST")
+	r.HandleFunc("/api/tokens/{id}", handleRevokeToken).Methods("DELETE")
+	r.HandleFunc("/api/maintenance", handleMaintenance).Methods("GET", "PUT")
 
 	// WebSocket for real-time updates
 	r.HandleFunc("/ws/logs", handleWebSocketLogs)
+}
+
+// webOptions are the web server settings that are not part of config.Config.
+type webOptions struct {
+	Host, Port string
+	Allow      []*net.IPNet // --allow-cidr; nil allows every source
+	AccessLog  *accessLog   // --access-log; nil disables it
+	// DrainTimeout is how long SIGINT/SIGTERM waits for running jobs
+	// (--drain-timeout).
+	DrainTimeout time.Duration
+	NoBrowser    bool   // do not open the UI in a browser (Windows service)
+	PublicURL    string // --public-url; "" falls back to the LAN address (see lanBaseURL)
+}
+
+// webStop receives SIGINT and SIGTERM, and the stop request of the Windows
+// service, to shut the web server down.
+var webStop = make(chan os.Signal, 2)
+
+func startWebServer(cfg config.Config, opts webOptions) {
+	host, port := opts.Host, opts.Port
+	webAPIKey = cfg.APIKey
+	webPresetMAC = cfg.MACAddress
+	webPresetIP = cfg.IPAddress
+	webPresetOrgName = cfg.OrgName
+	webPresetNetwork = cfg.NetworkName
+	webNotify = cfg.Notify
+	webMaxResults = cfg.MaxResults
+	webClientConfig = cfg
+	hostname, _ := os.Hostname()
+	webInstanceID = instanceID(hostname, host+":"+port, cfg.APIKey, webTestDataMode)
+	webPublicURL = opts.PublicURL
+	webLANURL = lanBaseURL(host, port, net.InterfaceAddrs)
+	log := newWebLogger()
+	log.Infof("Starting web server on %s:%s", host, port)
+	webHistoryFile = resolveHistoryFile(cfg.HistoryFile)
+	startHistoryPruner(webHistoryFile, historyRetention(cfg.HistoryMaxAge), log)
+
+	r := mux.NewRouter()
+
+	// Static files — served with no-cache plus an ETag, so the browser
+	// revalidates on every load and picks up new JS/CSS after a rebuild, but an
+	// unchanged file costs only a 304 instead of a full download.
+	staticHandler := http.StripPrefix("/static/", http.FileServer(http.Dir("./static/")))
+	r.PathPrefix("/static/").HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		if etag := staticETag("./static/", strings.TrimPrefix(req.URL.Path, "/static/")); etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		staticHandler.ServeHTTP(w, req)
+	})
+
+	registerAPIRoutes(r)
 
 	addr := fmt.Sprintf("%s:%s", host, port)
 	url := fmt.Sprintf("http://%s", addr)
+
+	// With OIDC_ISSUER set, the server refuses to start rather than run open
+	// when the provider cannot be reached.
+	var handler http.Handler = webMaintenance.wrap(r)
+	sso, err := newSSOAuth(context.Background(), os.Getenv, url+"/auth/callback")
+	if err != nil {
+		log.Errorf("Single sign-on: %v", err)
+		os.Exit(1)
+	}
+	if sso != nil {
+		webSSO = sso
+		sso.registerRoutes(r)
+		handler = sso.wrap(handler)
+		if f := os.Getenv("API_TOKENS_FILE"); f != "" {
+			webTokens.path = f
+		}
+		log.Infof("Single sign-on enabled via %s", os.Getenv("OIDC_ISSUER"))
+	}
+	handler = withRequestID(handler)
+	if len(opts.Allow) > 0 {
+		handler = (&sourceAllowList{nets: opts.Allow, now: time.Now}).wrap(handler)
+		names := make([]string, len(opts.Allow))
+		for i, n := range opts.Allow {
+			names[i] = n.String()
+		}
+		log.Infof("Accepting connections only from %s and loopback", strings.Join(names, ", "))
+	}
+	if opts.AccessLog != nil {
+		// Outermost, so requests the allowlist rejects are logged too.
+		handler = opts.AccessLog.wrap(handler)
+	}
 	log.Infof("Web interface available at %s", url)
 	log.Infof("Press Ctrl+C to stop the server")
 
 	// Open browser after a short delay to allow the server to start
+	if !opts.NoBrowser {
+		go func() {
+			time.Sleep(500 * time.Millisecond)
+			openBrowser(url)
+		}()
+	}
+
+	srv := &http.Server{Addr: addr, Handler: handler}
+	stopped := make(chan struct{})
 	go func() {
-		time.Sleep(500 * time.Millisecond)
-		openBrowser(url)
+		defer close(stopped)
+		signal.Notify(webStop, os.Interrupt, syscall.SIGTERM)
+		<-webStop
+		log.Infof("Shutting down: finishing running searches (up to %s; press Ctrl+C again to stop now)", opts.DrainTimeout)
+		go func() {
+			<-webStop
+			os.Exit(1)
+		}()
+		if !drainWebServer(opts.DrainTimeout, opts.AccessLog) {
+			log.Warnf("Drain timeout reached with %d job(s) still running", webMaintenance.status().Running)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
 	}()
 
-	if err := http.ListenAndServe(addr, r); err != nil {
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Errorf("Web server error: %v", err)
 		os.Exit(1)
 	}
+	<-stopped
+	log.Infof("Web server stopped")
+}
+
+// withRequestID gives every web request a correlation ID: the caller's valid
+// X-Request-Id, or a random one. It is echoed in the response and prefixes
+// the X-Request-Id of the Meraki API calls the request makes.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if !meraki.ValidRequestID(id) {
+			id = meraki.NewRequestID()
+		}
+		w.Header().Set("X-Request-Id", id)
+		next.ServeHTTP(w, r.WithContext(meraki.WithRequestID(r.Context(), id)))
+	})
+}
+
+// detachedContext is a background context carrying r's request ID, for API
+// calls that should not be cancelled when the browser disconnects.
+func detachedContext(r *http.Request) context.Context {
+	return meraki.WithRequestID(context.Background(), meraki.RequestIDFrom(r.Context()))
 }
 
 // openBrowser opens the given URL in the default system browser.
@@ -176,6 +317,8 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
       <span class="dot dot-idle" id="statusDot"></span>
       <span id="statusLabel"></span>
     </div>
+    <span class="user-badge hidden" id="userBadge"></span>
+    <a class="sign-out hidden" id="signOut" href="/auth/logout">Sign out</a>
     <span class="version">v` + Version + `</span>
   </div>
 
@@ -228,9 +371,12 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
             <input type="text" id="ipInput" placeholder="192.168.1.100">
           </div>
           <div class="btn-group" style="margin-top:4px">
-            <button class="btn btn-primary" id="resolveBtn">Resolve</button>
+            <button class="btn btn-primary has-tip" id="resolveBtn"
+              data-tip="Quick search from anywhere: Ctrl+K (⌘K)">Resolve</button>
             <button class="btn btn-secondary has-tip" id="topologyBtn"
               data-tip="Click a row in the results table to choose which switch to highlight">Topology</button>
+            <button class="btn btn-secondary has-tip" id="identifyBtn"
+              data-tip="Blink the LEDs of the selected row's switch for 20 seconds">Identify</button>
             <button class="btn btn-ghost btn-sm" id="clearBtn">Clear</button>
           </div>
 
@@ -248,18 +394,33 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
     <div class="main">
 
       <!-- Results -->
-      <div class="card" id="resultsWrap">
+      <div class="card" id="resultsWrap" tabindex="-1">
         <div class="card-header">
           <h3>Results</h3>
           <div class="btn-group hidden" id="exportBtns">
             <button class="btn btn-secondary btn-sm" id="exportCsvBtn">&#8595; CSV</button>
             <button class="btn btn-secondary btn-sm" id="exportJsonBtn">&#8595; JSON</button>
+            <button class="btn btn-secondary btn-sm has-tip" id="qrBtn"
+              data-tip="QR code linking to this search, for handing off to a phone">QR</button>
           </div>
         </div>
         <div class="card-body">
           <div class="results-toolbar">
             <span class="results-count" id="resultsCount"></span>
+            <div class="results-pager hidden" id="resultsPager">
+              <select id="pageSizeSel" title="Rows per page">
+                <option value="50">50 / page</option>
+                <option value="100" selected>100 / page</option>
+                <option value="250">250 / page</option>
+                <option value="500">500 / page</option>
+                <option value="0">All</option>
+              </select>
+              <button class="btn btn-secondary btn-sm" id="pagePrevBtn">&#8249; Prev</button>
+              <span class="results-count" id="pageInfo"></span>
+              <button class="btn btn-secondary btn-sm" id="pageNextBtn">Next &#8250;</button>
+            </div>
           </div>
+          <div id="truncatedNote" class="hidden uplink-note" role="status"></div>
           <div id="uplinkNote" class="hidden uplink-note"></div>
           <div class="table-wrap">
             <table id="resultsTable">
@@ -274,13 +435,15 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
                   <th data-col="hostname" class="sortable">Hostname</th>
                   <th data-col="manufacturer" class="sortable">Manufacturer</th>
                   <th data-col="mode" class="sortable">Mode</th>
+                  <th data-col="confidence" class="sortable" title="0–100: freshness, data source, port role and duplicate sightings">Confidence</th>
                 </tr>
               </thead>
               <tbody id="resultsTbody">
-                <tr><td colspan="9" class="no-results">Select a network and enter a MAC or IP address to begin.</td></tr>
+                <tr><td colspan="10" class="no-results">Select a network and enter a MAC or IP address to begin.</td></tr>
               </tbody>
             </table>
           </div>
+          <div id="explainPanel" class="explain-panel hidden"></div>
         </div>
       </div>
 
@@ -309,6 +472,14 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
 </div><!-- /app -->
 
 <div id="toastContainer"></div>
+
+<!-- Quick search palette (Ctrl+K / ⌘K) -->
+<div id="palette" class="palette hidden" role="dialog" aria-label="Quick search">
+  <div class="palette-box">
+    <input type="text" id="paletteInput" placeholder="Paste a MAC, IP or hostname and press Enter" autocomplete="off" spellcheck="false">
+    <div class="palette-hint" id="paletteHint"></div>
+  </div>
+</div>
 <script src="/static/js/app.js"></script>
 </body>
 </html>`
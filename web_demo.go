@@ -21,6 +21,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"Find-Meraki-Ports-With-MAC/pkg/output"
 )
 
 // ── Demo mode ─────────────────────────────────────────────────────────────────
@@ -53,6 +55,9 @@ func handleTestGetConfig(w http.ResponseWriter, r *http.Request) {
 		"presetOrg":     demoOrg,
 		"presetNetwork": firstNonEmpty(webPresetNetwork, "ALL"),
 		"testData":      true,
+		"instanceId":    webInstanceID,
+		"publicURL":     webPublicURL,
+		"lanURL":        webLANURL,
 	})
 }
 
@@ -68,16 +73,26 @@ func handleTestGetManufacturer(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"manufacturer": vendor})
 }
 
+func handleTestIdentify(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req struct {
+		Serial string `json:"serial"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	wsLogHub.broadcast(fmt.Sprintf("[INFO] Blinking LEDs on %s for 20s (demo)", req.Serial))
+	writeJSON(w, map[string]interface{}{"serial": req.Serial, "seconds": 20})
+}
+
 func handleTestGetNetworks(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+	writeCachedJSON(w, r, map[string]interface{}{
 		"networks": []map[string]string{
 			{"id": "demo-net-1", "name": "HQ Campus"},
 			{"id": "demo-net-2", "name": "Warehouse"},
 			{"id": "demo-net-3", "name": "City Parks"},
 			{"id": "demo-net-4", "name": "Remote Office"},
 		},
-	})
+	}, networksMaxAge)
 }
 
 // testDemoResults returns a realistic set of demo results for a single MAC
@@ -101,23 +116,36 @@ func testDemoResults(mac string) []map[string]interface{} {
 	}
 	const lastSeen = "2026-03-02T14:23:00Z"
 	const vlan = 100
-	return []map[string]interface{}{
+	rows := []map[string]interface{}{
 		// ── HQ Campus layer 1: edge MS355 — device physically plugged in here ─
 		{
-			"orgName":      demoOrg,
-			"networkName":  "HQ Campus",
-			"deviceName":   "sw-hq-access-ms355",
-			"deviceSerial": "Q2HP-XXXX-0001",
-			"port":         "12",
-			"aggrPorts":    nil,
-			"mac":          mac,
-			"ip":           demoIP,
-			"hostname":     demoHostname,
-			"lastSeen":     lastSeen,
-			"manufacturer": demoMfr,
-			"vlan":         vlan,
-			"portMode":     "access",
-			"isUplink":     false,
+			"orgName":          demoOrg,
+			"networkName":      "HQ Campus",
+			"deviceName":       "sw-hq-access-ms355",
+			"deviceSerial":     "Q2HP-XXXX-0001",
+			"port":             "12",
+			"aggrPorts":        nil,
+			"mac":              mac,
+			"ip":               demoIP,
+			"hostname":         demoHostname,
+			"lastSeen":         lastSeen,
+			"leaseExpiry":      "2026-03-03T14:23:00Z",
+			"manufacturer":     demoMfr,
+			"vlan":             vlan,
+			"portMode":         "access",
+			"isUplink":         false,
+			"link":             "up",
+			"speed":            "1 Gbps",
+			"duplex":           "full",
+			"neighbor":         "SEP5C5AC7A1B2C3 (Port 1)",
+			"neighborPlatform": "Cisco IP Phone 8845",
+			"deviceStatus":     "online",
+			"lastReported":     "2026-03-02T14:24:10Z",
+			"model":            "MS355-48X",
+			"firmware":         "switch-16-9",
+			"moved":            "moved since last seen here: was sw-hq-access-ms225/port 7 on Feb 27",
+			"source":           "mac-table",
+			"confidence":       92,
 		},
 		// ── HQ Campus layer 2: distribution MS450 — AGGR uplink to core ───────
 		{
@@ -135,6 +163,8 @@ func testDemoResults(mac string) []map[string]interface{} {
 			"vlan":         vlan,
 			"portMode":     "trunk",
 			"isUplink":     true,
+			"source":       "mac-table",
+			"confidence":   38,
 		},
 		// ── HQ Campus layer 3: C9300 core — uplink toward router ──────────────
 		{
@@ -152,6 +182,8 @@ func testDemoResults(mac string) []map[string]interface{} {
 			"vlan":         vlan,
 			"portMode":     "trunk",
 			"isUplink":     true,
+			"source":       "mac-table",
+			"confidence":   35,
 		},
 		// ── Warehouse: uplink-only hit on border MS355 ─────────────────────────
 		// The device is not physically here; MAC appears in the forwarding table
@@ -171,6 +203,8 @@ func testDemoResults(mac string) []map[string]interface{} {
 			"vlan":         vlan,
 			"portMode":     "trunk",
 			"isUplink":     true,
+			"source":       "mac-table",
+			"confidence":   22,
 		},
 		// ── City Parks: uplink-only hit on C9300 WAN uplink ───────────────────
 		{
@@ -188,8 +222,36 @@ func testDemoResults(mac string) []map[string]interface{} {
 			"vlan":         vlan,
 			"portMode":     "trunk",
 			"isUplink":     true,
+			"source":       "mac-table",
+			"confidence":   22,
 		},
 	}
+	for i, row := range rows {
+		row["explain"] = demoExplain(row, i)
+	}
+	return rows
+}
+
+// demoExplain returns the field provenance shown in the web detail view for a
+// demo row, mirroring what explainRow records for a live MAC table hit.
+func demoExplain(row map[string]interface{}, i int) []output.FieldSource {
+	serial, _ := row["deviceSerial"].(string)
+	port, _ := row["port"].(string)
+	sources := []output.FieldSource{
+		{Field: "Port", Source: srcLiveMacTable, Detail: fmt.Sprintf("job demo-%d at 14:23:07", i+1)},
+		{Field: "VLAN", Source: srcPortConfig, Detail: fmt.Sprintf("GET /devices/%s/switch/ports (port %s)", serial, port)},
+		{Field: "PortMode", Source: srcLiveMacTable},
+		{Field: "IP", Source: srcNetworkClients},
+		{Field: "Hostname", Source: srcReverseDNS},
+		{Field: "LastSeen", Source: srcNetworkClients},
+	}
+	if link, _ := row["link"].(string); link != "" {
+		sources = append(sources, output.FieldSource{Field: "Link", Source: srcPortStatuses, Detail: fmt.Sprintf("GET /devices/%s/switch/ports/statuses", serial)})
+	}
+	if up, _ := row["isUplink"].(bool); up {
+		sources = append(sources, output.FieldSource{Field: "Uplink", Source: srcPortStatuses, Detail: fmt.Sprintf("GET /devices/%s/switch/ports/statuses", serial)})
+	}
+	return sources
 }
 
 func handleTestResolve(w http.ResponseWriter, r *http.Request) {
@@ -238,5 +300,11 @@ func handleTestResolve(w http.ResponseWriter, r *http.Request) {
 	}(mac)
 
 	results := testDemoResults(mac)
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	warnings := []output.Warning{{
+		Kind:    output.WarningStaleData,
+		Message: "live MAC table of sw-remote-ms355-01 in Remote Office was unavailable; its results come from client history and may be out of date",
+		Network: "Remote Office",
+		Device:  "Q2HP-XXXX-0009",
+	}}
+	writeJSON(w, map[string]interface{}{"results": results, "total": len(results), "page": 1, "pageSize": 0, "warnings": warnings})
 }
@@ -16,13 +16,17 @@
 package main
 
 import (
-	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
 	"strings"
+	"time"
 
+	"Find-Meraki-Ports-With-MAC/pkg/config"
 	"Find-Meraki-Ports-With-MAC/pkg/filters"
 	"Find-Meraki-Ports-With-MAC/pkg/meraki"
 	"Find-Meraki-Ports-With-MAC/pkg/output"
@@ -46,30 +50,40 @@ func handleValidateKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create Meraki client with the provided API key
-	client := meraki.NewClient(req.APIKey, "", 0)
-	ctx := context.Background()
+	client := newWebClient(requestAPIKey(req.APIKey), "", newWebLogger())
+	ctx := detachedContext(r)
 
 	// Test the API key by fetching organizations
 	orgs, err := client.GetOrganizations(ctx)
 	if err != nil {
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Invalid API key: %v", err)})
+		msg := fmt.Sprintf("Cannot reach the Meraki Dashboard: %v", err)
+		switch {
+		case errors.Is(err, meraki.ErrAuth):
+			msg = fmt.Sprintf("Invalid API key: %v", err)
+		case errors.Is(err, meraki.ErrRateLimited):
+			msg = "Rate limited by the Meraki Dashboard; try again in a minute"
+		}
+		writeJSON(w, map[string]string{"error": msg})
 		return
 	}
 
 	// Return organizations
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSON(w, map[string]interface{}{
 		"organizations": orgs,
 	})
 }
 
 func handleGetConfig(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{
-		"apiKey":        webAPIKey,
+	writeJSON(w, map[string]string{
+		"apiKey":        browserAPIKey(),
 		"presetMAC":     webPresetMAC,
 		"presetIP":      webPresetIP,
 		"presetOrg":     webPresetOrgName,
 		"presetNetwork": webPresetNetwork,
+		"instanceId":    webInstanceID,
+		"publicURL":     webPublicURL,
+		"lanURL":        webLANURL,
 	})
 }
 
@@ -77,25 +91,25 @@ func handleGetNetworks(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	orgID := r.URL.Query().Get("orgId")
-	apiKey := r.URL.Query().Get("apiKey")
+	apiKey := requestAPIKey(r.URL.Query().Get("apiKey"))
 
 	if orgID == "" || apiKey == "" {
 		http.Error(w, `{"error": "Organization ID and API key are required"}`, http.StatusBadRequest)
 		return
 	}
 
-	client := meraki.NewClient(apiKey, "", 0)
-	ctx := context.Background()
+	client := newWebClient(apiKey, orgID, newWebLogger())
+	ctx := detachedContext(r)
 
 	networks, err := client.GetNetworks(ctx, orgID)
 	if err != nil {
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to get networks: %v", err)})
+		writeJSON(w, map[string]string{"error": fmt.Sprintf("Failed to get networks: %v", err)})
 		return
 	}
 
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+	writeCachedJSON(w, r, map[string]interface{}{
 		"networks": networks,
-	})
+	}, networksMaxAge)
 }
 
 func handleResolve(w http.ResponseWriter, r *http.Request) {
@@ -104,10 +118,16 @@ func handleResolve(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		MAC        string   `json:"mac"`
 		IP         string   `json:"ip"`
+		Hostname   string   `json:"hostname"` // forward-resolved to an IP when mac and ip are empty
 		NetworkID  string   `json:"networkId"`
 		NetworkIDs []string `json:"networkIds"`
 		OrgID      string   `json:"orgId"`
 		APIKey     string   `json:"apiKey"`
+		Page       int      `json:"page"`     // 1-based; ignored when pageSize is 0
+		PageSize   int      `json:"pageSize"` // 0 returns every result
+		// HostOverrides uses the HOST_OVERRIDES format and applies to this
+		// request only, layered over the server's own overrides.
+		HostOverrides json.RawMessage `json:"hostOverrides"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -129,61 +149,255 @@ func handleResolve(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error": "At least one network ID is required"}`, http.StatusBadRequest)
 		return
 	}
+	setAccessSearch(r.Context(), describeSearch(req.MAC, req.IP, req.Hostname, networkIDs))
+
+	// Scope host overrides to this request; the process-wide ones are shared
+	// by every concurrent search and must not be modified here.
+	hostNames := meraki.CurrentHostNames()
+	if len(req.HostOverrides) > 0 {
+		var err error
+		if hostNames, err = hostNames.WithOverrides(string(req.HostOverrides)); err != nil {
+			newWebLogger().Warnf("Rejected resolve request: %v", err)
+			http.Error(w, `{"error": "hostOverrides must be a JSON array of {org, net, ip, hostname}"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	// Searches are not cancelled when the browser disconnects, so only the
+	// host names travel with the context.
+	ctx := meraki.WithHostNames(detachedContext(r), hostNames)
+	warnings := &output.Warnings{}
+	ctx = withRunWarnings(ctx, warnings)
+
+	if req.MAC == "" && req.IP == "" && req.Hostname != "" {
+		ips, err := meraki.LookupHostIPs(meraki.WithHostNames(r.Context(), hostNames), req.Hostname)
+		if err != nil {
+			writeJSON(w, map[string]string{"error": fmt.Sprintf("Cannot resolve hostname %s: %v", req.Hostname, err)})
+			return
+		}
+		req.IP = ips[0]
+	}
 
 	if req.MAC == "" && req.IP == "" {
-		http.Error(w, `{"error": "MAC address or IP address is required"}`, http.StatusBadRequest)
+		http.Error(w, `{"error": "MAC address, IP address or hostname is required"}`, http.StatusBadRequest)
 		return
 	}
 
 	// Resolve across all requested networks and aggregate
+	started := time.Now()
 	var allResults []output.ResultRow
 	for _, netID := range networkIDs {
-		cfg := Config{
-			APIKey:       req.APIKey,
+		cfg := config.Config{
+			APIKey:       requestAPIKey(req.APIKey),
 			OrgID:        req.OrgID,
 			NetworkName:  netID,
 			LogLevel:     "INFO",
 			MacTablePoll: firstNonZeroInt(parseIntEnv("MERAKI_MAC_POLL"), 15),
 		}
-		results, err := resolveDevices(cfg, req.MAC, req.IP)
+		results, err := resolveDevices(ctx, cfg, req.MAC, req.IP)
+		if errors.Is(err, meraki.ErrAuth) {
+			// Every other network would fail the same way; don't report "no results".
+			writeJSON(w, map[string]string{"error": fmt.Sprintf("API key rejected: %v", err)})
+			return
+		}
 		if err != nil {
-			// Skip networks that error (e.g. not a switch network)
+			// Skip networks that error (e.g. not a switch network), but say
+			// so unless the IP is simply not a client there.
+			if !errors.Is(err, meraki.ErrIPNotFound) {
+				warnings.Add(output.Warning{
+					Kind:    output.WarningSkippedNetwork,
+					Message: fmt.Sprintf("network %s was skipped: %v", netID, err),
+					Network: netID,
+				})
+			}
 			continue
 		}
 		allResults = append(allResults, results...)
 	}
+	notifySearchComplete(firstNonEmpty(req.MAC, req.IP), len(allResults), time.Since(started))
+	output.ScoreRows(allResults, time.Now())
+	sortResults(allResults)
+	moved := recordWebSightings(webHistoryFile, allResults, started, newWebLogger())
+	allResults, truncated := output.Truncate(allResults, webMaxResults)
+	if truncated != nil {
+		newWebLogger().Warnf("%s (--max-results %d)", truncated.Warning(), webMaxResults)
+		warnings.Add(truncated.AsWarning())
+	}
 
-	// Convert to web-friendly format
-	webResults := make([]map[string]interface{}, len(allResults))
-	for i, result := range allResults {
-		webResults[i] = map[string]interface{}{
-			"orgName":      result.OrgName,
-			"networkName":  result.NetworkName,
-			"deviceName":   result.SwitchName,
-			"deviceSerial": result.SwitchSerial,
-			"port":         result.Port,
-			"aggrPorts":    result.AggrPorts,
-			"mac":          result.MAC,
-			"ip":           result.IP,
-			"hostname":     result.Hostname,
-			"lastSeen":     result.LastSeen,
-			"manufacturer": getManufacturer(result.MAC),
-			"vlan":         result.VLAN,
-			"portMode":     result.PortMode,
-			"isUplink":     result.IsUplink,
-		}
+	// Convert to web-friendly format, one page at a time for large OUI searches
+	total := len(allResults)
+	pageSize := min(req.PageSize, maxResolvePageSize)
+	start, end, page := pageBounds(total, req.Page, pageSize)
+	webResults := make([]map[string]interface{}, 0, end-start)
+	for i, result := range allResults[start:end] {
+		webResults = append(webResults, map[string]interface{}{
+			"orgName":          result.OrgName,
+			"networkName":      result.NetworkName,
+			"deviceName":       result.SwitchName,
+			"deviceSerial":     result.SwitchSerial,
+			"port":             result.Port,
+			"aggrPorts":        result.AggrPorts,
+			"mac":              result.MAC,
+			"ip":               result.IP,
+			"hostname":         result.Hostname,
+			"dhcpHostname":     result.DHCPHostname,
+			"leaseExpiry":      result.LeaseExpiry,
+			"lastSeen":         result.LastSeen,
+			"manufacturer":     getManufacturer(result.MAC),
+			"vlan":             result.VLAN,
+			"portMode":         result.PortMode,
+			"ssid":             result.SSID,
+			"band":             result.Band,
+			"rssi":             result.RSSI,
+			"isUplink":         result.IsUplink,
+			"link":             result.Link,
+			"speed":            result.Speed,
+			"duplex":           result.Duplex,
+			"neighbor":         result.Neighbor,
+			"neighborPlatform": result.NeighborPlatform,
+			"deviceStatus":     result.DeviceStatus,
+			"lastReported":     result.LastReported,
+			"model":            result.Model,
+			"firmware":         result.Firmware,
+			"note":             firstNonEmpty(result.Note, virtualMACNote(result.MAC)),
+			"moved":            moved[start+i],
+			"source":           result.Source,
+			"confidence":       result.Confidence,
+			"explain":          result.Explain,
+		})
 	}
 
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"results": webResults,
-	})
+	resp := map[string]interface{}{
+		"results":  webResults,
+		"total":    total,
+		"page":     page,
+		"pageSize": max(pageSize, 0),
+		"warnings": append([]output.Warning{}, warnings.List()...),
+	}
+	if truncated != nil {
+		resp["truncated"] = truncated
+		resp["warning"] = truncated.Warning()
+	}
+	writeJSON(w, resp)
+}
+
+// maxResolvePageSize caps pageSize so a client cannot defeat pagination.
+const maxResolvePageSize = 1000
+
+// pageBounds returns the [start, end) slice bounds of the requested 1-based
+// page and the page actually served. A pageSize of 0 or less selects every row;
+// pages past the end are clamped to the last page.
+func pageBounds(total, page, pageSize int) (start, end, served int) {
+	if pageSize <= 0 {
+		return 0, total, 1
+	}
+	pages := max((total+pageSize-1)/pageSize, 1)
+	served = min(max(page, 1), pages)
+	start = (served - 1) * pageSize
+	end = min(start+pageSize, total)
+	return start, end, served
+}
+
+// handleIdentify blinks the LEDs of one device so it can be found in the rack.
+func handleIdentify(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req struct {
+		Serial string `json:"serial"`
+		APIKey string `json:"apiKey"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	apiKey := firstNonEmpty(requestAPIKey(req.APIKey), webAPIKey)
+	if req.Serial == "" || apiKey == "" {
+		http.Error(w, `{"error": "serial and API key are required"}`, http.StatusBadRequest)
+		return
+	}
+	d, err := newWebClient(apiKey, "", newWebLogger()).BlinkLEDs(r.Context(), req.Serial, meraki.DefaultBlinkDuration)
+	if err != nil {
+		writeJSON(w, map[string]string{"error": fmt.Sprintf("Failed to blink %s: %v", req.Serial, err)})
+		return
+	}
+	newWebLogger().Infof("Blinking LEDs on %s for %s", req.Serial, d)
+	writeJSON(w, map[string]interface{}{"serial": req.Serial, "seconds": int(d.Seconds())})
 }
 
 func handleGetManufacturer(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	mac := r.URL.Query().Get("mac")
 	vendor := lookupOUI(mac)
-	_ = json.NewEncoder(w).Encode(map[string]string{"manufacturer": vendor})
+	writeJSON(w, map[string]string{"manufacturer": vendor})
+}
+
+// handleQR renders the "data" query parameter as a PNG QR code.
+// The web UI uses it to encode a deep link to the current search.
+func handleQR(w http.ResponseWriter, r *http.Request) {
+	data := r.URL.Query().Get("data")
+	if data == "" {
+		http.Error(w, "data is required", http.StatusBadRequest)
+		return
+	}
+	png, err := output.QRPNG(data, 320)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	if _, err := w.Write(png); err != nil {
+		newWebLogger().Warnf("Writing QR image: %v", err)
+	}
+}
+
+// writeJSON encodes v as the response body. The status line has already been
+// sent when encoding fails, so the failed write (usually a client that went
+// away mid-response) is logged rather than silently dropped.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		newWebLogger().Warnf("Writing JSON response: %v", err)
+	}
+}
+
+// How long the browser may reuse a response before revalidating it. Network
+// lists and topology change rarely, and refetching them on every interaction is
+// slow over WAN links.
+const (
+	networksMaxAge = 5 * time.Minute
+	topologyMaxAge = time.Minute
+)
+
+// writeCachedJSON writes v like writeJSON, but with a private Cache-Control
+// max-age and an ETag of the body. A request whose If-None-Match already holds
+// that ETag gets 304 Not Modified and no body.
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, v interface{}, maxAge time.Duration) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		newWebLogger().Warnf("Encoding JSON response: %v", err)
+		http.Error(w, `{"error": "Failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if _, err := w.Write(append(body, '\n')); err != nil {
+		newWebLogger().Warnf("Writing JSON response: %v", err)
+	}
+}
+
+// etagMatches reports whether an If-None-Match header value lists etag, using
+// the weak comparison RFC 9110 prescribes for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
 }
 
 // handleTopology serves the D3 force-graph topology page.
@@ -193,7 +407,7 @@ func handleTopology(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 	networkID := firstNonEmpty(q.Get("networkId"), "unknown")
 	orgID := q.Get("orgId")
-	apiKey := firstNonEmpty(q.Get("apiKey"), webAPIKey)
+	apiKey := firstNonEmpty(q.Get("apiKey"), browserAPIKey())
 	highlightSerial := q.Get("highlightSerial")
 	highlightPort := q.Get("highlightPort")
 	highlightName := q.Get("highlightName")
@@ -264,7 +478,7 @@ func handleGetTopology(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	ctx := r.Context()
 	networkID := r.URL.Query().Get("networkId")
-	apiKey := r.URL.Query().Get("apiKey")
+	apiKey := requestAPIKey(r.URL.Query().Get("apiKey"))
 	if apiKey == "" {
 		apiKey = webAPIKey
 	}
@@ -273,7 +487,7 @@ func handleGetTopology(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := meraki.NewClient(apiKey, "", 0)
+	client := newWebClient(apiKey, "", newWebLogger())
 
 	type outNode struct {
 		ID    string `json:"id"`
@@ -341,7 +555,7 @@ func handleGetTopology(w http.ResponseWriter, r *http.Request) {
 		// Fallback: list devices in network as flat nodes (no links)
 		devices, devErr := client.GetDevices(ctx, networkID)
 		if devErr != nil {
-			_ = json.NewEncoder(w).Encode(resp)
+			writeJSON(w, resp)
 			return
 		}
 		for _, d := range devices {
@@ -354,7 +568,7 @@ func handleGetTopology(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	_ = json.NewEncoder(w).Encode(resp)
+	writeCachedJSON(w, r, resp, topologyMaxAge)
 }
 
 func handleGetAlerts(w http.ResponseWriter, r *http.Request) {
@@ -378,7 +592,7 @@ func handleDebugNetwork(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 	networkID := q.Get("networkId")
 	orgID := q.Get("orgId")
-	apiKey := q.Get("apiKey")
+	apiKey := requestAPIKey(q.Get("apiKey"))
 	if apiKey == "" {
 		apiKey = webAPIKey
 	}
@@ -387,7 +601,7 @@ func handleDebugNetwork(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ctx := r.Context()
-	client := meraki.NewClient(apiKey, "", 2)
+	client := newWebClient(apiKey, orgID, newWebLogger())
 
 	out := map[string]interface{}{
 		"networkId": networkID,
@@ -445,5 +659,7 @@ func handleDebugNetwork(w http.ResponseWriter, r *http.Request) {
 
 	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
-	_ = enc.Encode(out)
+	if err := enc.Encode(out); err != nil {
+		newWebLogger().Warnf("Writing topology debug response: %v", err)
+	}
 }